@@ -8,9 +8,16 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router"
 	"github.com/traPtitech/traQ/router/auth"
+	"github.com/traPtitech/traQ/router/scim"
+	v3 "github.com/traPtitech/traQ/router/v3"
+	"github.com/traPtitech/traQ/service/bus"
 	"github.com/traPtitech/traQ/service/counter"
 	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ldap"
+	"github.com/traPtitech/traQ/service/media"
+	"github.com/traPtitech/traQ/service/upload"
 	"github.com/traPtitech/traQ/service/variable"
 	"github.com/traPtitech/traQ/utils/storage"
 	"go.uber.org/zap"
@@ -46,10 +53,35 @@ type Config struct {
 	Imaging struct {
 		// MaxPixels 処理可能な最大画素数 (default: 2560*1600)
 		MaxPixels int `mapstructure:"maxPixels" yaml:"maxPixels"`
+		// MaxAnimationFrames 処理可能なアニメーション画像(APNG, WebP)の最大フレーム数 (default: 300)
+		MaxAnimationFrames int `mapstructure:"maxAnimationFrames" yaml:"maxAnimationFrames"`
 		// Concurrency 処理並列数 (default: 1)
 		Concurrency int `mapstructure:"concurrency" yaml:"concurrency"`
+		// Sanitize アップロードされた画像のメタデータ(EXIF等)除去・向き補正設定
+		Sanitize struct {
+			// JPEG JPEG画像のメタデータを除去するかどうか (default: true)
+			JPEG bool `mapstructure:"jpeg" yaml:"jpeg"`
+			// PNG PNG画像のメタデータを除去するかどうか (default: true)
+			PNG bool `mapstructure:"png" yaml:"png"`
+			// WebP WebP画像のメタデータを除去するかどうか (default: true)
+			WebP bool `mapstructure:"webp" yaml:"webp"`
+			// JPEGQuality 向きの補正でJPEG画像を再エンコードする際の品質 (default: 95)
+			JPEGQuality int `mapstructure:"jpegQuality" yaml:"jpegQuality"`
+			// MaxSize メタデータを除去する画像の最大ファイルサイズ(byte) (default: 52428800)
+			MaxSize int64 `mapstructure:"maxSize" yaml:"maxSize"`
+		} `mapstructure:"sanitize" yaml:"sanitize"`
 	} `mapstructure:"imaging" yaml:"imaging"`
 
+	// Media 動画・音声処理設定
+	Media struct {
+		// FFmpeg ffmpeg実行ファイルパス
+		FFmpeg string `mapstructure:"ffmpeg" yaml:"ffmpeg"`
+		// FFprobe ffprobe実行ファイルパス (空の場合は動画・音声の処理を行いません)
+		FFprobe string `mapstructure:"ffprobe" yaml:"ffprobe"`
+		// Concurrency 処理並列数 (default: 1)
+		Concurrency int `mapstructure:"concurrency" yaml:"concurrency"`
+	} `mapstructure:"media" yaml:"media"`
+
 	// MariaDB データベース接続設定
 	MariaDB struct {
 		// Host ホスト名 (default: 127.0.0.1)
@@ -106,6 +138,31 @@ type Config struct {
 			// CacheDir キャッシュディレクトリ
 			CacheDir string `mapstructure:"cacheDir" yaml:"cacheDir"`
 		} `mapstructure:"swift" yaml:"swift"`
+
+		// Quota ファイル容量制限設定 (byte, 0の場合は無制限)
+		Quota struct {
+			// User ユーザー毎の容量制限 (default: 0)
+			User int64 `mapstructure:"user" yaml:"user"`
+			// Channel チャンネル毎の容量制限 (default: 0)
+			Channel int64 `mapstructure:"channel" yaml:"channel"`
+			// Total インスタンス全体の容量制限 (default: 0)
+			Total int64 `mapstructure:"total" yaml:"total"`
+		} `mapstructure:"quota" yaml:"quota"`
+
+		// Upload 再開可能なファイルアップロード設定
+		Upload struct {
+			// Dir 受信中のデータの一時保存先ディレクトリ (default: OSの一時ディレクトリ/traq-uploads)
+			//
+			// ノード毎のディレクトリです。複数ノードで動作させる場合は、同じアップロードへのリクエストが
+			// 同じノードに届くようにロードバランサーでスティッキールーティングを設定してください。
+			Dir string `mapstructure:"dir" yaml:"dir"`
+			// MaxSize アップロード可能な最大ファイルサイズ(byte) (default: 1073741824)
+			MaxSize int64 `mapstructure:"maxSize" yaml:"maxSize"`
+			// MaxOutstandingSize ユーザー毎の受信中のアップロードの合計サイズの上限(byte) (default: 2147483648)
+			MaxOutstandingSize int64 `mapstructure:"maxOutstandingSize" yaml:"maxOutstandingSize"`
+			// Expiry 最後にデータを受信してから破棄されるまでの期間(秒) (default: 86400)
+			Expiry int `mapstructure:"expiry" yaml:"expiry"`
+		} `mapstructure:"upload" yaml:"upload"`
 	} `mapstructure:"storage" yaml:"storage"`
 
 	// GCP Google Cloud Platform設定
@@ -172,6 +229,67 @@ type Config struct {
 		} `mapstructure:"keys" yaml:"keys"`
 	} `mapstructure:"jwt" yaml:"jwt"`
 
+	// SCIM SCIM 2.0 プロビジョニングAPI設定
+	SCIM struct {
+		// Token 認証用Bearerトークン (空の場合はSCIM無効)
+		Token string `mapstructure:"token" yaml:"token"`
+		// GroupAdminName 作成されるグループの管理者ユーザー名 (default: traq)
+		GroupAdminName string `mapstructure:"groupAdminName" yaml:"groupAdminName"`
+	} `mapstructure:"scim" yaml:"scim"`
+
+	// LDAP LDAP認証・グループ同期設定
+	LDAP struct {
+		// URL LDAPサーバーURL (例: ldap://localhost:389)
+		URL string `mapstructure:"url" yaml:"url"`
+		// BindDN 検索用バインドDN
+		BindDN string `mapstructure:"bindDn" yaml:"bindDn"`
+		// BindPassword 検索用バインドパスワード
+		BindPassword string `mapstructure:"bindPassword" yaml:"bindPassword"`
+		// AllowSignUp traQユーザーが存在しない場合に自動登録するかどうか (default: false)
+		AllowSignUp bool `mapstructure:"allowSignUp" yaml:"allowSignUp"`
+		// User ユーザー検索設定
+		User struct {
+			// BaseDN 検索ベースDN
+			BaseDN string `mapstructure:"baseDn" yaml:"baseDn"`
+			// Filter 検索フィルタ (default: (uid=%s))
+			Filter string `mapstructure:"filter" yaml:"filter"`
+			// NameAttribute ユーザー名属性 (default: uid)
+			NameAttribute string `mapstructure:"nameAttribute" yaml:"nameAttribute"`
+			// DisplayNameAttribute 表示名属性 (default: displayName)
+			DisplayNameAttribute string `mapstructure:"displayNameAttribute" yaml:"displayNameAttribute"`
+		} `mapstructure:"user" yaml:"user"`
+		// Group グループ同期設定
+		Group struct {
+			// BaseDN 検索ベースDN (空の場合はグループ同期無効)
+			BaseDN string `mapstructure:"baseDn" yaml:"baseDn"`
+			// Filter 検索フィルタ (default: (objectClass=groupOfNames))
+			Filter string `mapstructure:"filter" yaml:"filter"`
+			// NameAttribute グループ名属性 (default: cn)
+			NameAttribute string `mapstructure:"nameAttribute" yaml:"nameAttribute"`
+			// MemberAttribute メンバー属性 (default: member)
+			MemberAttribute string `mapstructure:"memberAttribute" yaml:"memberAttribute"`
+			// AdminName 作成されるグループの管理者ユーザー名 (default: traq)
+			AdminName string `mapstructure:"adminName" yaml:"adminName"`
+			// SyncInterval 同期間隔(秒) (default: 3600)
+			SyncInterval int `mapstructure:"syncInterval" yaml:"syncInterval"`
+		} `mapstructure:"group" yaml:"group"`
+	} `mapstructure:"ldap" yaml:"ldap"`
+
+	// EventBus 複数ノードで動作させる場合のノード間イベントバス設定
+	//
+	// WS, SSEの再送バッファはノード間で共有されないため、ロードバランサーでは同じユーザーのコネクションを同じノードに振り分けてください。
+	EventBus struct {
+		// Redis Redis Pub/Sub設定
+		Redis struct {
+			// Addr Redisサーバーのアドレス (host:port, 空の場合は単一ノードで動作)
+			Addr string `mapstructure:"addr" yaml:"addr"`
+			// Password Redisサーバーのパスワード
+			Password string `mapstructure:"password" yaml:"password"`
+		} `mapstructure:"redis" yaml:"redis"`
+		// Channel 使用するPub/Subチャンネル名 (default: traq)
+		Channel string `mapstructure:"channel" yaml:"channel"`
+	} `mapstructure:"eventBus" yaml:"eventBus"`
+
 	// ExternalAuth 外部認証設定
 	ExternalAuth struct {
 		GitHub struct {
@@ -197,6 +315,19 @@ type Config struct {
 			AllowSignUp  bool     `mapstructure:"allowSignUp" yaml:"allowSignUp"`
 			Scopes       []string `mapstructure:"scopes" yaml:"scopes"`
 		} `mapstructure:"oidc" yaml:"oidc"`
+		SAML struct {
+			EntityID       string `mapstructure:"entityId" yaml:"entityId"`
+			IdPMetadataURL string `mapstructure:"idpMetadataUrl" yaml:"idpMetadataUrl"`
+			CertFile       string `mapstructure:"certFile" yaml:"certFile"`
+			KeyFile        string `mapstructure:"keyFile" yaml:"keyFile"`
+			AllowSignUp    bool   `mapstructure:"allowSignUp" yaml:"allowSignUp"`
+			GroupAdminName string `mapstructure:"groupAdminName" yaml:"groupAdminName"`
+			Attributes     struct {
+				Name        string `mapstructure:"name" yaml:"name"`
+				DisplayName string `mapstructure:"displayName" yaml:"displayName"`
+				Groups      string `mapstructure:"groups" yaml:"groups"`
+			} `mapstructure:"attributes" yaml:"attributes"`
+		} `mapstructure:"saml" yaml:"saml"`
 	} `mapstructure:"externalAuth" yaml:"externalAuth"`
 }
 
@@ -210,7 +341,16 @@ func init() {
 	viper.SetDefault("accessLog.enabled", true)
 	viper.SetDefault("imagemagick", "")
 	viper.SetDefault("imaging.maxPixels", 2560*1600)
+	viper.SetDefault("imaging.maxAnimationFrames", 300)
 	viper.SetDefault("imaging.concurrency", 1)
+	viper.SetDefault("imaging.sanitize.jpeg", true)
+	viper.SetDefault("imaging.sanitize.png", true)
+	viper.SetDefault("imaging.sanitize.webp", true)
+	viper.SetDefault("imaging.sanitize.jpegQuality", 95)
+	viper.SetDefault("imaging.sanitize.maxSize", 50<<20)
+	viper.SetDefault("media.ffmpeg", "")
+	viper.SetDefault("media.ffprobe", "")
+	viper.SetDefault("media.concurrency", 1)
 	viper.SetDefault("mariadb.host", "127.0.0.1")
 	viper.SetDefault("mariadb.port", 3306)
 	viper.SetDefault("mariadb.username", "root")
@@ -229,6 +369,13 @@ func init() {
 	viper.SetDefault("storage.swift.authUrl", "")
 	viper.SetDefault("storage.swift.tempUrlKey", "")
 	viper.SetDefault("storage.swift.cacheDir", "")
+	viper.SetDefault("storage.quota.user", 0)
+	viper.SetDefault("storage.quota.channel", 0)
+	viper.SetDefault("storage.quota.total", 0)
+	viper.SetDefault("storage.upload.dir", "")
+	viper.SetDefault("storage.upload.maxSize", 1<<30)
+	viper.SetDefault("storage.upload.maxOutstandingSize", 2<<30)
+	viper.SetDefault("storage.upload.expiry", 24*60*60)
 	viper.SetDefault("gcp.serviceAccount.projectId", "")
 	viper.SetDefault("gcp.serviceAccount.file", "")
 	viper.SetDefault("gcp.stackdriver.profiler.enabled", false)
@@ -240,6 +387,25 @@ func init() {
 	viper.SetDefault("externalAuthentication.authPost.successfulCode", 0)
 	viper.SetDefault("externalAuthentication.authPost.formUserNameKey", "")
 	viper.SetDefault("externalAuthentication.authPost.formPasswordKey", "")
+	viper.SetDefault("scim.token", "")
+	viper.SetDefault("scim.groupAdminName", "traq")
+	viper.SetDefault("ldap.url", "")
+	viper.SetDefault("ldap.bindDn", "")
+	viper.SetDefault("ldap.bindPassword", "")
+	viper.SetDefault("ldap.allowSignUp", false)
+	viper.SetDefault("ldap.user.baseDn", "")
+	viper.SetDefault("ldap.user.filter", "(uid=%s)")
+	viper.SetDefault("ldap.user.nameAttribute", "uid")
+	viper.SetDefault("ldap.user.displayNameAttribute", "displayName")
+	viper.SetDefault("ldap.group.baseDn", "")
+	viper.SetDefault("ldap.group.filter", "(objectClass=groupOfNames)")
+	viper.SetDefault("ldap.group.nameAttribute", "cn")
+	viper.SetDefault("ldap.group.memberAttribute", "member")
+	viper.SetDefault("ldap.group.adminName", "traq")
+	viper.SetDefault("ldap.group.syncInterval", 60*60)
+	viper.SetDefault("eventBus.redis.addr", "")
+	viper.SetDefault("eventBus.redis.password", "")
+	viper.SetDefault("eventBus.channel", "traq")
 	viper.SetDefault("externalAuth.github.clientId", "")
 	viper.SetDefault("externalAuth.github.clientSecret", "")
 	viper.SetDefault("externalAuth.github.allowSignUp", false)
@@ -255,6 +421,15 @@ func init() {
 	viper.SetDefault("externalAuth.oidc.clientSecret", "")
 	viper.SetDefault("externalAuth.oidc.scopes", []string{})
 	viper.SetDefault("externalAuth.oidc.allowSignUp", false)
+	viper.SetDefault("externalAuth.saml.entityId", "")
+	viper.SetDefault("externalAuth.saml.idpMetadataUrl", "")
+	viper.SetDefault("externalAuth.saml.certFile", "")
+	viper.SetDefault("externalAuth.saml.keyFile", "")
+	viper.SetDefault("externalAuth.saml.allowSignUp", false)
+	viper.SetDefault("externalAuth.saml.groupAdminName", "traq")
+	viper.SetDefault("externalAuth.saml.attributes.name", "")
+	viper.SetDefault("externalAuth.saml.attributes.displayName", "")
+	viper.SetDefault("externalAuth.saml.attributes.groups", "")
 	viper.SetDefault("skyway.secretKey", "")
 	viper.SetDefault("jwt.keys.private", "")
 }
@@ -326,6 +501,17 @@ func newFCMClientIfAvailable(repo repository.Repository, logger *zap.Logger, unr
 	return fcm.NewNullClient(), nil
 }
 
+func newEventBus(logger *zap.Logger, c bus.RedisConfig) (*bus.Bus, error) {
+	if len(c.Addr) > 0 {
+		t, err := bus.NewRedisTransport(c, logger)
+		if err != nil {
+			return nil, err
+		}
+		return bus.New(t, logger), nil
+	}
+	return bus.New(bus.NewLocalTransport(), logger), nil
+}
+
 func provideServerOriginString(c *Config) variable.ServerOriginString {
 	return variable.ServerOriginString(c.Origin)
 }
@@ -336,10 +522,68 @@ func provideFirebaseCredentialsFilePathString(c *Config) variable.FirebaseCreden
 
 func provideImageProcessorConfig(c *Config) imaging.Config {
 	return imaging.Config{
-		MaxPixels:        c.Imaging.MaxPixels,
-		Concurrency:      c.Imaging.Concurrency,
+		MaxPixels:          c.Imaging.MaxPixels,
+		MaxAnimationFrames: c.Imaging.MaxAnimationFrames,
+		Concurrency:        c.Imaging.Concurrency,
+		ThumbnailMaxSize:   image.Pt(360, 480),
+		ImageMagickPath:    c.ImageMagick,
+	}
+}
+
+func provideFileSanitizeConfig(c *Config) file.SanitizeConfig {
+	return file.SanitizeConfig{
+		JPEG:            c.Imaging.Sanitize.JPEG,
+		PNG:             c.Imaging.Sanitize.PNG,
+		WebP:            c.Imaging.Sanitize.WebP,
+		JPEGQuality:     c.Imaging.Sanitize.JPEGQuality,
+		MaxSize:         c.Imaging.Sanitize.MaxSize,
+		MaxPixels:       c.Imaging.MaxPixels,
+		ImageMagickPath: c.ImageMagick,
+	}
+}
+
+func provideMediaProcessorConfig(c *Config) media.Config {
+	return media.Config{
+		FFmpegPath:       c.Media.FFmpeg,
+		FFprobePath:      c.Media.FFprobe,
+		Concurrency:      c.Media.Concurrency,
 		ThumbnailMaxSize: image.Pt(360, 480),
-		ImageMagickPath:  c.ImageMagick,
+	}
+}
+
+func provideUploadConfig(c *Config) upload.Config {
+	return upload.Config{
+		Dir:                c.Storage.Upload.Dir,
+		MaxSize:            c.Storage.Upload.MaxSize,
+		MaxOutstandingSize: c.Storage.Upload.MaxOutstandingSize,
+		Expiry:             time.Duration(c.Storage.Upload.Expiry) * time.Second,
+	}
+}
+
+func provideLDAPConfig(c *Config) ldap.Config {
+	return ldap.Config{
+		URL:                    c.LDAP.URL,
+		BindDN:                 c.LDAP.BindDN,
+		BindPassword:           c.LDAP.BindPassword,
+		UserBaseDN:             c.LDAP.User.BaseDN,
+		UserFilter:             c.LDAP.User.Filter,
+		UserNameAttribute:      c.LDAP.User.NameAttribute,
+		DisplayNameAttribute:   c.LDAP.User.DisplayNameAttribute,
+		RegisterUserIfNotFound: c.LDAP.AllowSignUp,
+		GroupBaseDN:            c.LDAP.Group.BaseDN,
+		GroupFilter:            c.LDAP.Group.Filter,
+		GroupNameAttribute:     c.LDAP.Group.NameAttribute,
+		GroupMemberAttribute:   c.LDAP.Group.MemberAttribute,
+		GroupAdminName:         c.LDAP.Group.AdminName,
+		SyncInterval:           time.Duration(c.LDAP.Group.SyncInterval) * time.Second,
+	}
+}
+
+func provideEventBusRedisConfig(c *Config) bus.RedisConfig {
+	return bus.RedisConfig{
+		Addr:     c.EventBus.Redis.Addr,
+		Password: c.EventBus.Redis.Password,
+		Channel:  c.EventBus.Channel,
 	}
 }
 
@@ -381,18 +625,35 @@ func provideAuthTraQProviderConfig(c *Config) auth.TraQProviderConfig {
 	}
 }
 
+func provideAuthSAMLProviderConfig(c *Config) auth.SAMLProviderConfig {
+	return auth.SAMLProviderConfig{
+		Origin:                 c.Origin,
+		EntityID:               c.ExternalAuth.SAML.EntityID,
+		IdPMetadataURL:         c.ExternalAuth.SAML.IdPMetadataURL,
+		CertFile:               c.ExternalAuth.SAML.CertFile,
+		KeyFile:                c.ExternalAuth.SAML.KeyFile,
+		NameAttribute:          c.ExternalAuth.SAML.Attributes.Name,
+		DisplayNameAttribute:   c.ExternalAuth.SAML.Attributes.DisplayName,
+		GroupsAttribute:        c.ExternalAuth.SAML.Attributes.Groups,
+		GroupAdminName:         c.ExternalAuth.SAML.GroupAdminName,
+		RegisterUserIfNotFound: c.ExternalAuth.SAML.AllowSignUp,
+	}
+}
+
 func provideRouterExternalAuthConfig(c *Config) router.ExternalAuthConfig {
 	return router.ExternalAuthConfig{
 		GitHub: provideAuthGithubProviderConfig(c),
 		Google: provideAuthGoogleProviderConfig(c),
 		TraQ:   provideAuthTraQProviderConfig(c),
 		OIDC:   provideAuthOIDCProviderConfig(c),
+		SAML:   provideAuthSAMLProviderConfig(c),
 	}
 }
 
 func provideRouterConfig(c *Config) *router.Config {
 	return &router.Config{
 		Development:      c.DevMode,
+		Origin:           c.Origin,
 		Version:          Version,
 		Revision:         Revision,
 		AccessLogging:    c.AccessLog.Enabled,
@@ -401,5 +662,15 @@ func provideRouterConfig(c *Config) *router.Config {
 		IsRefreshEnabled: c.OAuth2.IsRefreshEnabled,
 		SkyWaySecretKey:  c.SkyWay.SecretKey,
 		ExternalAuth:     provideRouterExternalAuthConfig(c),
+		StorageQuota: v3.StorageQuota{
+			User:    c.Storage.Quota.User,
+			Channel: c.Storage.Quota.Channel,
+			Total:   c.Storage.Quota.Total,
+		},
+		SCIM: scim.Config{
+			Token:          c.SCIM.Token,
+			GroupAdminName: c.SCIM.GroupAdminName,
+			Origin:         c.Origin,
+		},
 	}
 }
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/spf13/cobra"
 	"github.com/traPtitech/traQ/model"
@@ -8,7 +10,9 @@ import (
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
 	"github.com/traPtitech/traQ/utils/gormzap"
+	"github.com/traPtitech/traQ/utils/storage"
 	"go.uber.org/zap"
+	"io"
 )
 
 // fileCommand traQ管理ファイル操作コマンド
@@ -20,6 +24,8 @@ func fileCommand() *cobra.Command {
 
 	cmd.AddCommand(
 		filePruneCommand(),
+		fileUsageCommand(),
+		fileDedupeCommand(),
 	)
 
 	return &cmd
@@ -60,7 +66,7 @@ func filePruneCommand() *cobra.Command {
 				logger.Fatal("failed to initialize repository", zap.Error(err))
 			}
 
-			fm, err := file.InitFileManager(repo, fs, imaging.NewProcessor(provideImageProcessorConfig(c)), logger)
+			fm, err := file.InitFileManager(repo, fs, imaging.NewProcessor(provideImageProcessorConfig(c)), nil, provideFileSanitizeConfig(c), logger)
 			if err != nil {
 				logger.Fatal("failed to initialize file manager", zap.Error(err))
 			}
@@ -113,3 +119,176 @@ func filePruneCommand() *cobra.Command {
 
 	return &cmd
 }
+
+// fileUsageCommand ファイル使用量表示コマンド
+func fileUsageCommand() *cobra.Command {
+	var (
+		limit       int
+		recalculate bool
+	)
+
+	cmd := cobra.Command{
+		Use:   "usage",
+		Short: "show storage usage and top consumers",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Logger
+			logger := getCLILogger()
+			defer logger.Sync()
+
+			// Database
+			db, err := c.getDatabase()
+			if err != nil {
+				logger.Fatal("failed to connect database", zap.Error(err))
+			}
+			db.SetLogger(gormzap.New(logger.Named("gorm")))
+			defer db.Close()
+
+			// Repository チャンネルツリーを作らないので注意
+			repo, err := repository.NewGormRepository(db, hub.New(), logger)
+			if err != nil {
+				logger.Fatal("failed to initialize repository", zap.Error(err))
+			}
+
+			if recalculate {
+				if err := repo.RecalculateFileUsages(); err != nil {
+					logger.Fatal("failed to recalculate file usages", zap.Error(err))
+				}
+				logger.Info("file usages were recalculated")
+			}
+
+			size, count, err := repo.GetTotalFileUsage()
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+			logger.Sugar().Infof("total: %d bytes (%d files)", size, count)
+
+			users, err := repo.GetUserFileUsageRanking(limit)
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+			logger.Sugar().Infof("top %d users:", len(users))
+			for _, u := range users {
+				name := u.UserID.String()
+				if user, err := repo.GetUser(u.UserID, false); err == nil {
+					name = user.GetName()
+				}
+				logger.Sugar().Infof("%s - %d bytes (%d files)", name, u.Size, u.Count)
+			}
+
+			channels, err := repo.GetChannelFileUsageRanking(limit)
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+			logger.Sugar().Infof("top %d channels:", len(channels))
+			for _, ch := range channels {
+				logger.Sugar().Infof("%s - %d bytes (%d files)", ch.ChannelID, ch.Size, ch.Count)
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.IntVar(&limit, "limit", 10, "number of top consumers to show")
+	flags.BoolVar(&recalculate, "recalculate", false, "recalculate usages from file metas before showing")
+
+	return &cmd
+}
+
+// fileDedupeCommand 既存ファイル実体重複排除コマンド
+func fileDedupeCommand() *cobra.Command {
+	var dryRun bool
+
+	cmd := cobra.Command{
+		Use:   "dedupe",
+		Short: "calculate hashes of stored files and merge files which have the same content",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Logger
+			logger := getCLILogger()
+			defer logger.Sync()
+
+			// Database
+			db, err := c.getDatabase()
+			if err != nil {
+				logger.Fatal("failed to connect database", zap.Error(err))
+			}
+			db.SetLogger(gormzap.New(logger.Named("gorm")))
+			defer db.Close()
+
+			// FileStorage
+			fs, err := c.getFileStorage()
+			if err != nil {
+				logger.Fatal("failed to setup file storage", zap.Error(err))
+			}
+
+			// Repository チャンネルツリーを作らないので注意
+			repo, err := repository.NewGormRepository(db, hub.New(), logger)
+			if err != nil {
+				logger.Fatal("failed to initialize repository", zap.Error(err))
+			}
+
+			objects, err := repo.GetUnhashedFileObjects()
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+			logger.Sugar().Infof("%d unhashed file objects were found", len(objects))
+
+			var (
+				merged    int
+				freedSize int64
+				seen      = map[string]bool{}
+			)
+			for _, obj := range objects {
+				hash, err := calcFileObjectHash(fs, obj)
+				if err != nil {
+					logger.Warn("failed to calculate hash", zap.Error(err), zap.Stringer("oid", obj.ID))
+					continue
+				}
+
+				if dryRun {
+					key := obj.Type.String() + ":" + hash
+					if seen[key] {
+						merged++
+						freedSize += obj.Size
+					}
+					seen[key] = true
+					continue
+				}
+
+				result, err := repo.SetFileObjectHash(obj.ID, hash)
+				if err != nil {
+					logger.Fatal(err.Error())
+				}
+				if result.ID != obj.ID {
+					// 統合されたので実体を削除
+					logger.Sugar().Infof("%s was merged into %s", obj.ID, result.ID)
+					if err := fs.DeleteByKey(obj.ID.String(), obj.Type); err != nil {
+						logger.Warn("failed to delete file from storage", zap.Error(err), zap.Stringer("oid", obj.ID))
+					}
+					merged++
+					freedSize += obj.Size
+				}
+			}
+
+			logger.Sugar().Infof("%d file objects were merged (%d bytes)", merged, freedSize)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVar(&dryRun, "dry-run", false, "calculate hashes and count duplicates only (no merge)")
+
+	return &cmd
+}
+
+// calcFileObjectHash ストレージ上のファイル実体のSHA-256ハッシュを計算します
+func calcFileObjectHash(fs storage.FileStorage, obj *model.FileObject) (string, error) {
+	f, err := fs.OpenFileByKey(obj.ID.String(), obj.Type)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
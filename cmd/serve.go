@@ -160,12 +160,18 @@ func (s *Server) Start(address string) error {
 		// TODO 適切なパッケージに移動させる
 		sub := s.Hub.Subscribe(10, event.UserOffline)
 		for ev := range sub.Receiver {
+			if event.IsRemote(ev) {
+				continue
+			}
 			userID := ev.Fields["user_id"].(uuid.UUID)
 			datetime := ev.Fields["datetime"].(time.Time)
 			_ = s.Repo.UpdateUser(userID, repository.UpdateUserArgs{LastOnline: optional.TimeFrom(datetime)})
 		}
 	}()
+	s.SS.EventBus.Start()
 	s.SS.BOT.Start()
+	s.SS.LDAP.Start()
+	s.SS.Upload.Start()
 	return s.Router.Start(address)
 }
 
@@ -173,14 +179,30 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.Go(func() error { return s.Router.Shutdown(ctx) })
 	eg.Go(func() error { return s.SS.WS.Close() })
+	eg.Go(func() error {
+		s.SS.SSE.Dispose()
+		return nil
+	})
 	eg.Go(func() error { return s.SS.BOT.Shutdown(ctx) })
 	eg.Go(func() error {
 		s.SS.FCM.Close()
 		return nil
 	})
+	eg.Go(func() error {
+		s.SS.LDAP.Shutdown()
+		return nil
+	})
+	eg.Go(func() error {
+		s.SS.Upload.Shutdown()
+		return nil
+	})
 	eg.Go(func() error {
 		s.SS.ChannelManager.Wait()
 		return nil
 	})
+	eg.Go(func() error {
+		s.SS.EventBus.Shutdown()
+		return nil
+	})
 	return eg.Wait()
 }
@@ -10,12 +10,18 @@ import (
 	"github.com/traPtitech/traQ/router"
 	"github.com/traPtitech/traQ/service"
 	"github.com/traPtitech/traQ/service/bot"
+	"github.com/traPtitech/traQ/service/bus"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ldap"
+	"github.com/traPtitech/traQ/service/media"
 	"github.com/traPtitech/traQ/service/notification"
 	rbac2 "github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/sse"
+	"github.com/traPtitech/traQ/service/typing"
+	"github.com/traPtitech/traQ/service/upload"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
@@ -26,23 +32,39 @@ import (
 func newServer(hub *hub.Hub, db *gorm.DB, repo repository.Repository, fs storage.FileStorage, logger *zap.Logger, c *Config) (*Server, error) {
 	wire.Build(
 		bot.NewService,
+		bus.NewHubReplicator,
 		channel.InitChannelManager,
+		channel.NewGroupSubscriptionSyncer,
+		channel.NewTreeSyncer,
+		channel.NewReadStateRecorder,
 		file.InitFileManager,
 		counter.NewOnlineCounter,
 		counter.NewUnreadMessageCounter,
 		counter.NewMessageCounter,
 		counter.NewChannelCounter,
+		counter.NewStampUsageCounter,
 		imaging.NewProcessor,
+		ldap.NewService,
+		media.NewProcessor,
 		notification.NewService,
 		rbac2.New,
+		sse.NewStreamer,
+		typing.NewManager,
+		upload.NewManager,
 		viewer.NewManager,
 		webrtcv3.NewManager,
 		ws.NewStreamer,
 		router.Setup,
 		newFCMClientIfAvailable,
+		newEventBus,
 		provideServerOriginString,
 		provideFirebaseCredentialsFilePathString,
 		provideImageProcessorConfig,
+		provideFileSanitizeConfig,
+		provideMediaProcessorConfig,
+		provideLDAPConfig,
+		provideEventBusRedisConfig,
+		provideUploadConfig,
 		provideRouterConfig,
 		wire.Struct(new(service.Services), "*"),
 		wire.Struct(new(Server), "*"),
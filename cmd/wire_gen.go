@@ -12,12 +12,18 @@ import (
 	"github.com/traPtitech/traQ/router"
 	"github.com/traPtitech/traQ/service"
 	"github.com/traPtitech/traQ/service/bot"
+	"github.com/traPtitech/traQ/service/bus"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ldap"
+	"github.com/traPtitech/traQ/service/media"
 	"github.com/traPtitech/traQ/service/notification"
 	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/sse"
+	"github.com/traPtitech/traQ/service/typing"
+	"github.com/traPtitech/traQ/service/upload"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
@@ -38,7 +44,15 @@ func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, fs storag
 		return nil, err
 	}
 	botService := bot.NewService(repo, manager, hub2, logger)
-	onlineCounter := counter.NewOnlineCounter(hub2)
+	treeSyncer := channel.NewTreeSyncer(manager, hub2, logger)
+	readStateRecorder := channel.NewReadStateRecorder(manager, repo, hub2, logger)
+	redisConfig := provideEventBusRedisConfig(c2)
+	busBus, err := newEventBus(logger, redisConfig)
+	if err != nil {
+		return nil, err
+	}
+	hubReplicator := bus.NewHubReplicator(busBus, hub2, logger)
+	onlineCounter := counter.NewOnlineCounter(hub2, busBus)
 	unreadMessageCounter, err := counter.NewUnreadMessageCounter(db, hub2)
 	if err != nil {
 		return nil, err
@@ -51,6 +65,8 @@ func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, fs storag
 	if err != nil {
 		return nil, err
 	}
+	stampUsageCounter := counter.NewStampUsageCounter(repo, hub2, logger)
+	groupSubscriptionSyncer := channel.NewGroupSubscriptionSyncer(repo, hub2, logger)
 	firebaseCredentialsFilePathString := provideFirebaseCredentialsFilePathString(c2)
 	client, err := newFCMClientIfAvailable(repo, logger, unreadMessageCounter, firebaseCredentialsFilePathString)
 	if err != nil {
@@ -58,31 +74,54 @@ func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, fs storag
 	}
 	config := provideImageProcessorConfig(c2)
 	processor := imaging.NewProcessor(config)
-	fileManager, err := file.InitFileManager(repo, fs, processor, logger)
+	mediaConfig := provideMediaProcessorConfig(c2)
+	mediaProcessor := media.NewProcessor(mediaConfig)
+	sanitizeConfig := provideFileSanitizeConfig(c2)
+	fileManager, err := file.InitFileManager(repo, fs, processor, mediaProcessor, sanitizeConfig, logger)
 	if err != nil {
 		return nil, err
 	}
-	viewerManager := viewer.NewManager(hub2)
-	webrtcv3Manager := webrtcv3.NewManager(hub2)
-	streamer := ws.NewStreamer(hub2, viewerManager, webrtcv3Manager, logger)
+	ldapConfig := provideLDAPConfig(c2)
+	ldapService := ldap.NewService(repo, fileManager, logger, ldapConfig)
+	viewerManager := viewer.NewManager(hub2, busBus)
+	webrtcv3Manager := webrtcv3.NewManager(hub2, busBus)
+	typingManager := typing.NewManager(hub2)
+	streamer := ws.NewStreamer(hub2, viewerManager, webrtcv3Manager, manager, typingManager, logger)
+	sseStreamer := sse.NewStreamer(hub2)
 	serverOriginString := provideServerOriginString(c2)
-	notificationService := notification.NewService(repo, manager, fileManager, hub2, logger, client, streamer, viewerManager, serverOriginString)
+	notificationService := notification.NewService(repo, manager, fileManager, hub2, logger, client, streamer, sseStreamer, viewerManager, serverOriginString)
 	rbacRBAC, err := rbac.New(db)
 	if err != nil {
 		return nil, err
 	}
+	uploadConfig := provideUploadConfig(c2)
+	uploadManager, err := upload.NewManager(repo, fileManager, logger, uploadConfig)
+	if err != nil {
+		return nil, err
+	}
 	services := &service.Services{
 		BOT:                  botService,
 		ChannelManager:       manager,
+		ChannelTreeSyncer:    treeSyncer,
+		ChannelReadState:     readStateRecorder,
+		GroupSubscription:    groupSubscriptionSyncer,
+		EventBus:             busBus,
+		HubReplicator:        hubReplicator,
 		OnlineCounter:        onlineCounter,
 		UnreadMessageCounter: unreadMessageCounter,
 		MessageCounter:       messageCounter,
 		ChannelCounter:       channelCounter,
+		StampUsageCounter:    stampUsageCounter,
 		FCM:                  client,
 		FileManager:          fileManager,
 		Imaging:              processor,
+		Media:                mediaProcessor,
+		LDAP:                 ldapService,
 		Notification:         notificationService,
 		RBAC:                 rbacRBAC,
+		SSE:                  sseStreamer,
+		Typing:               typingManager,
+		Upload:               uploadManager,
 		ViewerManager:        viewerManager,
 		WebRTCv3:             webrtcv3Manager,
 		WS:                   streamer,
@@ -0,0 +1,16 @@
+package event
+
+import "github.com/leandro-lugaresi/hub"
+
+// FieldRemote 他のノードから複製されたイベントに付与されるフィールドの名前
+//
+// 複数ノードで動作している場合、イベントは全ノードのHubに複製されます。
+// DBの更新や外部への通知など、クラスタ全体で1回だけ行うべき処理は
+// このフィールドがtrueのイベントに対して行ってはいけません。
+const FieldRemote = "remote"
+
+// IsRemote 他のノードから複製されたイベントかどうかを返します
+func IsRemote(m hub.Message) bool {
+	remote, _ := m.Fields[FieldRemote].(bool)
+	return remote
+}
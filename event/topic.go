@@ -24,6 +24,17 @@ const (
 	//		user_id: uuid.UUID
 	//		datetime: time.Time
 	UserOffline = "user.offline"
+	// UserTyping ユーザーがチャンネルでメッセージを入力中になった
+	// 	Fields:
+	//		user_id: uuid.UUID
+	//		channel_id: uuid.UUID
+	//		expires_at: time.Time
+	UserTyping = "user.typing"
+	// UserTypingStopped ユーザーのチャンネルでのメッセージ入力中状態が解除された
+	// 	Fields:
+	//		user_id: uuid.UUID
+	//		channel_id: uuid.UUID
+	UserTypingStopped = "user.typing_stopped"
 
 	// UserTagAdded ユーザーにタグが追加された
 	// 	Fields:
@@ -51,15 +62,29 @@ const (
 	//		group_id: uuid.UUID
 	UserGroupDeleted = "user_group.deleted"
 	// UserGroupMemberAdded ユーザーがグループに追加された
+	//
+	// 子グループを介して実質的にメンバーになった場合も発生します。
 	// 	Fields:
 	//		group_id: uuid.UUID
 	//		user_id: uuid.UUID
 	UserGroupMemberAdded = "user_group.member.added"
 	// UserGroupMemberRemoved ユーザーがグループから削除された
+	//
+	// 子グループを介して実質的にメンバーでなくなった場合も発生します。
 	// 	Fields:
 	//		group_id: uuid.UUID
 	//		user_id: uuid.UUID
 	UserGroupMemberRemoved = "user_group.member.removed"
+	// UserGroupChildAdded グループに子グループが追加された
+	// 	Fields:
+	//		group_id: uuid.UUID
+	//		child_id: uuid.UUID
+	UserGroupChildAdded = "user_group.child.added"
+	// UserGroupChildRemoved グループから子グループが削除された
+	// 	Fields:
+	//		group_id: uuid.UUID
+	//		child_id: uuid.UUID
+	UserGroupChildRemoved = "user_group.child.removed"
 
 	// MessageCreated メッセージが作成された
 	// 	Fields:
@@ -92,12 +117,14 @@ const (
 	// 		stamp_id: uuid.UUID
 	// 		count: int
 	// 		created_at: time.Time
+	// 		new: bool
 	MessageStamped = "message.stamped"
 	// MessageUnstamped メッセージからスタンプが消された
 	// 	Fields:
 	// 		message_id: uuid.UUID
 	// 		user_id: uuid.UUID
 	// 		stamp_id: uuid.UUID
+	// 		created_at: time.Time
 	MessageUnstamped = "message.unstamped"
 	// MessagePinned メッセージがピンされた
 	// 	Fields:
@@ -138,12 +165,32 @@ const (
 	// 		channel_id: uuid.UUID
 	// 		private: bool
 	ChannelDeleted = "channel.deleted"
+	// ChannelMerged チャンネルが他のチャンネルに統合された
+	// 	Fields:
+	// 		channel_id: uuid.UUID	統合元チャンネルのID
+	// 		to_channel_id: uuid.UUID	統合先チャンネルのID
+	// 		updater_id: uuid.UUID
+	ChannelMerged = "channel.merged"
+	// ChannelMembersChanged プライベートチャンネルのメンバーが変更された
+	// 	Fields:
+	// 		channel_id: uuid.UUID
+	// 		added: []uuid.UUID
+	// 		removed: []uuid.UUID
+	// 		updater_id: uuid.UUID
+	ChannelMembersChanged = "channel.members_changed"
 	// ChannelRead チャンネルのメッセージが既読された
 	//	Fields:
 	//		user_id: uuid.UUID
 	//		channel_id: uuid.UUID
 	//		read_messages_num: int
 	ChannelRead = "channel.read"
+	// ChannelReadStateUpdated ユーザーのチャンネルの既読位置が更新された
+	//	Fields:
+	//		user_id: uuid.UUID
+	//		channel_id: uuid.UUID
+	//		message_id: uuid.UUID
+	//		read_at: time.Time
+	ChannelReadStateUpdated = "channel.read_state_updated"
 	// ChannelStared チャンネルがスターされた
 	// 	Fields:
 	// 		user_id: uuid.UUID
@@ -30,6 +30,22 @@ func Migrations() []*gormigrate.Migration {
 		v18(), // インデックス追加
 		v19(), // httpセッション管理テーブル変更
 		v20(), // パーミッション周りの調整
+		v21(), // ユーザーグループ外部ソース同期
+		v22(), // OAuth2デバイス認可グラント
+		v23(), // スタンプカテゴリー・エイリアス・タグ
+		v24(), // スタンプ使用数集計
+		v25(), // ユーザーグループの入れ子
+		v26(), // グループによるチャンネル購読
+		v27(), // ファイル使用量集計
+		v28(), // ファイル実体の重複排除
+		v29(), // 動画・音声ファイルのメディア情報
+		v30(), // 再開可能なファイルアップロード
+		v31(), // 既読位置の共有とユーザー設定
+		v32(), // チャンネルの統合
+		v33(), // プライベートチャンネルのメンバー編集権限の追加
+		v34(), // グループDM
+		v35(), // チャンネルテンプレート
+		v36(), // プライベートチャンネル名の一意性をチャンネル毎に
 	}
 }
 
@@ -43,26 +59,40 @@ func AllTables() []interface{} {
 		&model.RoleInheritance{},
 		&model.UserRole{},
 		&model.DMChannelMapping{},
+		&model.GroupDMChannelMapping{},
 		&model.ChannelLatestMessage{},
 		&model.BotEventLog{},
 		&model.BotJoinChannel{},
 		&model.Bot{},
 		&model.OAuth2Client{},
 		&model.OAuth2Authorize{},
+		&model.OAuth2DeviceAuthorize{},
 		&model.OAuth2Token{},
 		&model.MessageReport{},
 		&model.WebhookBot{},
 		&model.MessageStamp{},
+		&model.StampAlias{},
+		&model.StampTag{},
+		&model.StampDailyUsage{},
+		&model.StampUserUsage{},
 		&model.Stamp{},
 		&model.UsersTag{},
 		&model.Unread{},
+		&model.ChannelReadState{},
+		&model.ChannelRedirect{},
+		&model.ChannelTemplate{},
 		&model.Star{},
 		&model.Device{},
 		&model.Pin{},
 		&model.FileACLEntry{},
+		&model.UserFileUsage{},
+		&model.ChannelFileUsage{},
 		&model.FileMeta{},
+		&model.FileObject{},
+		&model.FileUpload{},
 		&model.UsersPrivateChannel{},
 		&model.UserSubscribeChannel{},
+		&model.ChannelGroupSubscription{},
 		&model.Tag{},
 		&model.ArchivedMessage{},
 		&model.ClipFolderMessage{},
@@ -70,9 +100,11 @@ func AllTables() []interface{} {
 		&model.StampPalette{},
 		&model.UserGroupAdmin{},
 		&model.UserGroupMember{},
+		&model.UserGroupChild{},
 		&model.UserGroup{},
 		&model.ExternalProviderUser{},
 		&model.UserProfile{},
+		&model.UserSettings{},
 		&model.Channel{},
 		&model.ClipFolder{},
 		&model.User{},
@@ -94,6 +126,7 @@ func AllForeignKeys() [][5]string {
 		{"dm_channel_mappings", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
 		{"dm_channel_mappings", "user1", "users(id)", "CASCADE", "CASCADE"},
 		{"dm_channel_mappings", "user2", "users(id)", "CASCADE", "CASCADE"},
+		{"group_dm_channel_mappings", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
 		{"messages", "user_id", "users(id)", "CASCADE", "CASCADE"},
 		{"messages", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
 		{"users_tags", "user_id", "users(id)", "CASCADE", "CASCADE"},
@@ -111,6 +144,12 @@ func AllForeignKeys() [][5]string {
 		{"messages_stamps", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
 		{"messages_stamps", "user_id", "users(id)", "CASCADE", "CASCADE"},
 		{"stamps", "file_id", "files(id)", "NO ACTION", "CASCADE"},
+		{"stamp_aliases", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
+		{"stamp_tags", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
+		{"stamp_daily_usages", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
+		{"stamp_daily_usages", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"stamp_user_usages", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
+		{"stamp_user_usages", "user_id", "users(id)", "CASCADE", "CASCADE"},
 		{"webhook_bots", "bot_user_id", "users(id)", "CASCADE", "CASCADE"},
 		{"webhook_bots", "creator_id", "users(id)", "CASCADE", "CASCADE"},
 		{"webhook_bots", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
@@ -125,8 +164,23 @@ func AllForeignKeys() [][5]string {
 		{"clip_folder_messages", "folder_id", "clip_folders(id)", "CASCADE", "CASCADE"},
 		{"clip_folder_messages", "message_id", "messages(id)", "CASCADE", "CASCADE"},
 		{"stamp_palettes", "creator_id", "users(id)", "CASCADE", "CASCADE"},
+		{"channel_templates", "creator_id", "users(id)", "CASCADE", "CASCADE"},
 		{"external_provider_users", "user_id", "users(id)", "CASCADE", "CASCADE"},
 		{"user_profiles", "home_channel", "channels(id)", "CASCADE", "CASCADE"},
+		{"user_group_children", "group_id", "user_groups(id)", "CASCADE", "CASCADE"},
+		{"user_group_children", "child_id", "user_groups(id)", "CASCADE", "CASCADE"},
+		{"channel_group_subscriptions", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"channel_group_subscriptions", "group_id", "user_groups(id)", "CASCADE", "CASCADE"},
+		{"user_file_usages", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"channel_file_usages", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"file_uploads", "creator_id", "users(id)", "CASCADE", "CASCADE"},
+		{"file_uploads", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"channel_read_states", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"channel_read_states", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"channel_read_states", "message_id", "messages(id)", "CASCADE", "CASCADE"},
+		{"channel_redirects", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"channel_redirects", "to_channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"user_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
 	}
 }
 
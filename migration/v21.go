@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v21 ユーザーグループ外部ソース同期
+func v21() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "21",
+		Migrate: func(db *gorm.DB) error {
+			return db.AutoMigrate(&v21UserGroup{}).Error
+		},
+	}
+}
+
+type v21UserGroup struct {
+	ID          uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Name        string    `gorm:"type:varchar(30);not null;unique"`
+	Description string    `gorm:"type:text;not null"`
+	Type        string    `gorm:"type:varchar(30);not null;default:''"`
+	Source      string    `gorm:"type:varchar(30);not null;default:''"` // 追加
+	CreatedAt   time.Time `gorm:"precision:6"`
+	UpdatedAt   time.Time `gorm:"precision:6"`
+}
+
+func (v21UserGroup) TableName() string {
+	return "user_groups"
+}
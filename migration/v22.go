@@ -0,0 +1,35 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v22 OAuth2デバイス認可グラント
+func v22() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "22",
+		Migrate: func(db *gorm.DB) error {
+			return db.AutoMigrate(&v22OAuth2DeviceAuthorize{}).Error
+		},
+	}
+}
+
+type v22OAuth2DeviceAuthorize struct {
+	DeviceCode   string     `gorm:"type:varchar(64);primary_key"`
+	UserCode     string     `gorm:"type:varchar(16);not null;unique"`
+	ClientID     string     `gorm:"type:char(36);not null"`
+	UserID       uuid.UUID  `gorm:"type:char(36)"`
+	Scopes       string     `gorm:"type:text"`
+	Status       string     `gorm:"type:varchar(10);not null"`
+	PollInterval int        `gorm:"not null"`
+	ExpiresIn    int        `gorm:"not null"`
+	LastPolledAt *time.Time `gorm:"precision:6"`
+	CreatedAt    time.Time  `gorm:"precision:6"`
+}
+
+func (v22OAuth2DeviceAuthorize) TableName() string {
+	return "oauth2_device_authorizes"
+}
@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v23 スタンプカテゴリー・エイリアス・タグ
+func v23() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "23",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v23Stamp{}, &v23StampAlias{}, &v23StampTag{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"stamp_aliases", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
+				{"stamp_tags", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
+			}
+
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+type v23Stamp struct {
+	ID        uuid.UUID  `gorm:"type:char(36);not null;primary_key"`
+	Name      string     `gorm:"type:varchar(32);not null;unique"`
+	CreatorID uuid.UUID  `gorm:"type:char(36);not null"`
+	FileID    uuid.UUID  `gorm:"type:char(36);not null"`
+	IsUnicode bool       `gorm:"type:boolean;not null;default:false;index"`
+	Category  string     `gorm:"type:varchar(30);not null;default:''"` // 追加
+	CreatedAt time.Time  `gorm:"precision:6"`
+	UpdatedAt time.Time  `gorm:"precision:6"`
+	DeletedAt *time.Time `gorm:"precision:6"`
+}
+
+func (*v23Stamp) TableName() string {
+	return "stamps"
+}
+
+type v23StampAlias struct {
+	Name      string    `gorm:"type:varchar(32);not null;primary_key"`
+	StampID   uuid.UUID `gorm:"type:char(36);not null;index"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v23StampAlias) TableName() string {
+	return "stamp_aliases"
+}
+
+type v23StampTag struct {
+	StampID   uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Tag       string    `gorm:"type:varchar(30);not null;primary_key"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v23StampTag) TableName() string {
+	return "stamp_tags"
+}
@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v24 スタンプ使用数集計
+func v24() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "24",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v24StampDailyUsage{}, &v24StampUserUsage{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"stamp_daily_usages", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
+				{"stamp_daily_usages", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+				{"stamp_user_usages", "stamp_id", "stamps(id)", "CASCADE", "CASCADE"},
+				{"stamp_user_usages", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			// 既存のスタンプから集計値を作成
+			if err := db.Exec("INSERT INTO stamp_daily_usages (date, stamp_id, channel_id, count) " +
+				"SELECT DATE(ms.created_at), ms.stamp_id, m.channel_id, COUNT(*) FROM messages_stamps ms " +
+				"INNER JOIN messages m ON ms.message_id = m.id " +
+				"GROUP BY DATE(ms.created_at), ms.stamp_id, m.channel_id").Error; err != nil {
+				return err
+			}
+			return db.Exec("INSERT INTO stamp_user_usages (stamp_id, user_id, count) " +
+				"SELECT stamp_id, user_id, COUNT(*) FROM messages_stamps GROUP BY stamp_id, user_id").Error
+		},
+	}
+}
+
+type v24StampDailyUsage struct {
+	Date      time.Time `gorm:"type:date;not null;primary_key"`
+	StampID   uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	Count     int       `gorm:"type:int;not null;default:0"`
+}
+
+func (*v24StampDailyUsage) TableName() string {
+	return "stamp_daily_usages"
+}
+
+type v24StampUserUsage struct {
+	StampID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID  uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Count   int       `gorm:"type:int;not null;default:0"`
+}
+
+func (*v24StampUserUsage) TableName() string {
+	return "stamp_user_usages"
+}
@@ -0,0 +1,39 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+)
+
+// v25 ユーザーグループの入れ子
+func v25() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "25",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v25UserGroupChild{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"user_group_children", "group_id", "user_groups(id)", "CASCADE", "CASCADE"},
+				{"user_group_children", "child_id", "user_groups(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v25UserGroupChild struct {
+	GroupID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	ChildID uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+}
+
+func (*v25UserGroupChild) TableName() string {
+	return "user_group_children"
+}
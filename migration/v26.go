@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v26 グループによるチャンネル購読
+func v26() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "26",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v26UserSubscribeChannel{}, &v26ChannelGroupSubscription{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"channel_group_subscriptions", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+				{"channel_group_subscriptions", "group_id", "user_groups(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v26UserSubscribeChannel struct {
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Mark      bool      `gorm:"type:boolean;not null;default:false"`
+	Notify    bool      `gorm:"type:boolean;not null;default:false"`
+	Auto      bool      `gorm:"type:boolean;not null;default:false"` // 追加
+}
+
+func (v26UserSubscribeChannel) TableName() string {
+	return "users_subscribe_channels"
+}
+
+type v26ChannelGroupSubscription struct {
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	GroupID   uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	Mark      bool      `gorm:"type:boolean;not null;default:false"`
+	Notify    bool      `gorm:"type:boolean;not null;default:false"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (v26ChannelGroupSubscription) TableName() string {
+	return "channel_group_subscriptions"
+}
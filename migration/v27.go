@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+)
+
+// v27 ファイル使用量集計
+func v27() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "27",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v27UserFileUsage{}, &v27ChannelFileUsage{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"user_file_usages", "user_id", "users(id)", "CASCADE", "CASCADE"},
+				{"channel_file_usages", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			// 既存のファイルから集計値を作成
+			if err := db.Exec("INSERT INTO user_file_usages (user_id, size, count) " +
+				"SELECT creator_id, SUM(size), COUNT(*) FROM files " +
+				"WHERE deleted_at IS NULL AND creator_id IS NOT NULL GROUP BY creator_id").Error; err != nil {
+				return err
+			}
+			return db.Exec("INSERT INTO channel_file_usages (channel_id, size, count) " +
+				"SELECT channel_id, SUM(size), COUNT(*) FROM files " +
+				"WHERE deleted_at IS NULL AND channel_id IS NOT NULL GROUP BY channel_id").Error
+		},
+	}
+}
+
+type v27UserFileUsage struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Size   int64     `gorm:"type:bigint;not null;default:0"`
+	Count  int64     `gorm:"type:bigint;not null;default:0"`
+}
+
+func (*v27UserFileUsage) TableName() string {
+	return "user_file_usages"
+}
+
+type v27ChannelFileUsage struct {
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Size      int64     `gorm:"type:bigint;not null;default:0"`
+	Count     int64     `gorm:"type:bigint;not null;default:0"`
+}
+
+func (*v27ChannelFileUsage) TableName() string {
+	return "channel_file_usages"
+}
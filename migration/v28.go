@@ -0,0 +1,65 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v28 ファイル実体の重複排除
+func v28() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "28",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v28File{}, &v28FileObject{}).Error; err != nil {
+				return err
+			}
+
+			// 既存のファイルは(削除済みのものも含め)それぞれ自身のIDをキーとする実体を持つ
+			// 参照数には削除済みのファイルを含めない
+			// ハッシュは `traQ file dedupe` コマンドで計算される
+			if err := db.Exec("INSERT INTO file_objects (id, hash, type, size, ref_count, created_at) " +
+				"SELECT id, NULL, type, size, CASE WHEN deleted_at IS NULL THEN 1 ELSE 0 END, created_at FROM files").Error; err != nil {
+				return err
+			}
+			return db.Exec("UPDATE files SET object_id = id").Error
+		},
+	}
+}
+
+type v28File struct {
+	ID              uuid.UUID       `gorm:"type:char(36);not null;primary_key"`
+	Name            string          `gorm:"type:text;not null"`
+	Mime            string          `gorm:"type:text;not null"`
+	Size            int64           `gorm:"type:bigint;not null"`
+	CreatorID       optional.UUID   `gorm:"type:char(36)"`
+	Hash            string          `gorm:"type:char(32);not null"`
+	Type            string          `gorm:"type:varchar(30);not null;default:''"`
+	HasThumbnail    bool            `gorm:"type:boolean;not null;default:false"`
+	ThumbnailMime   optional.String `gorm:"type:text"`
+	ThumbnailWidth  int             `gorm:"type:int;not null;default:0"`
+	ThumbnailHeight int             `gorm:"type:int;not null;default:0"`
+	ChannelID       optional.UUID   `gorm:"type:char(36)"`
+	ObjectID        uuid.UUID       `gorm:"type:char(36);not null;index"` // 追加
+	CreatedAt       time.Time       `gorm:"precision:6"`
+	DeletedAt       *time.Time      `gorm:"precision:6"`
+}
+
+func (v28File) TableName() string {
+	return "files"
+}
+
+type v28FileObject struct {
+	ID        uuid.UUID       `gorm:"type:char(36);not null;primary_key"`
+	Hash      optional.String `gorm:"type:char(64);unique_index:idx_file_objects_hash_type"`
+	Type      string          `gorm:"type:varchar(30);not null;default:'';unique_index:idx_file_objects_hash_type"`
+	Size      int64           `gorm:"type:bigint;not null"`
+	RefCount  int             `gorm:"type:int;not null;default:0"`
+	CreatedAt time.Time       `gorm:"precision:6"`
+}
+
+func (v28FileObject) TableName() string {
+	return "file_objects"
+}
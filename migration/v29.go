@@ -0,0 +1,45 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v29 動画・音声ファイルのメディア情報
+func v29() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "29",
+		Migrate: func(db *gorm.DB) error {
+			return db.AutoMigrate(&v29File{}).Error
+		},
+	}
+}
+
+type v29File struct {
+	ID              uuid.UUID       `gorm:"type:char(36);not null;primary_key"`
+	Name            string          `gorm:"type:text;not null"`
+	Mime            string          `gorm:"type:text;not null"`
+	Size            int64           `gorm:"type:bigint;not null"`
+	CreatorID       optional.UUID   `gorm:"type:char(36)"`
+	Hash            string          `gorm:"type:char(32);not null"`
+	Type            string          `gorm:"type:varchar(30);not null;default:''"`
+	HasThumbnail    bool            `gorm:"type:boolean;not null;default:false"`
+	ThumbnailMime   optional.String `gorm:"type:text"`
+	ThumbnailWidth  int             `gorm:"type:int;not null;default:0"`
+	ThumbnailHeight int             `gorm:"type:int;not null;default:0"`
+	Width           int             `gorm:"type:int;not null;default:0"`          // 追加
+	Height          int             `gorm:"type:int;not null;default:0"`          // 追加
+	DurationMS      int64           `gorm:"type:bigint;not null;default:0"`       // 追加
+	Codec           string          `gorm:"type:varchar(50);not null;default:''"` // 追加
+	ChannelID       optional.UUID   `gorm:"type:char(36)"`
+	ObjectID        uuid.UUID       `gorm:"type:char(36);not null;index"`
+	CreatedAt       time.Time       `gorm:"precision:6"`
+	DeletedAt       *time.Time      `gorm:"precision:6"`
+}
+
+func (v29File) TableName() string {
+	return "files"
+}
@@ -0,0 +1,48 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v30 再開可能なファイルアップロード
+func v30() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "30",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v30FileUpload{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"file_uploads", "creator_id", "users(id)", "CASCADE", "CASCADE"},
+				{"file_uploads", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v30FileUpload struct {
+	ID        uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Name      string    `gorm:"type:text;not null"`
+	Mime      string    `gorm:"type:text;not null"`
+	Size      int64     `gorm:"type:bigint;not null"`
+	Offset    int64     `gorm:"type:bigint;not null;default:0"`
+	CreatorID uuid.UUID `gorm:"type:char(36);not null;index"`
+	ChannelID uuid.UUID `gorm:"type:char(36);not null"`
+	ExpiresAt time.Time `gorm:"precision:6;index"`
+	CreatedAt time.Time `gorm:"precision:6"`
+	UpdatedAt time.Time `gorm:"precision:6"`
+}
+
+func (v30FileUpload) TableName() string {
+	return "file_uploads"
+}
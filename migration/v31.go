@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v31 既読位置の共有とユーザー設定
+func v31() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "31",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v31ChannelReadState{}, &v31UserSettings{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"channel_read_states", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+				{"channel_read_states", "user_id", "users(id)", "CASCADE", "CASCADE"},
+				{"channel_read_states", "message_id", "messages(id)", "CASCADE", "CASCADE"},
+				{"user_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v31ChannelReadState struct {
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	MessageID uuid.UUID `gorm:"type:char(36);not null"`
+	ReadAt    time.Time `gorm:"precision:6"`
+}
+
+func (v31ChannelReadState) TableName() string {
+	return "channel_read_states"
+}
+
+type v31UserSettings struct {
+	UserID       uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	ReadReceipts bool      `gorm:"type:boolean;not null;default:false"`
+	UpdatedAt    time.Time `gorm:"precision:6"`
+}
+
+func (v31UserSettings) TableName() string {
+	return "user_settings"
+}
@@ -0,0 +1,41 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v32 チャンネルの統合
+func v32() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "32",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v32ChannelRedirect{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"channel_redirects", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+				{"channel_redirects", "to_channel_id", "channels(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v32ChannelRedirect struct {
+	ChannelID   uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	ToChannelID uuid.UUID `gorm:"type:char(36);not null;index"`
+	CreatedAt   time.Time `gorm:"precision:6"`
+}
+
+func (v32ChannelRedirect) TableName() string {
+	return "channel_redirects"
+}
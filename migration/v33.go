@@ -0,0 +1,40 @@
+package migration
+
+import (
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+)
+
+// v33 プライベートチャンネルのメンバー編集権限の追加
+func v33() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "33",
+		Migrate: func(db *gorm.DB) error {
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"edit_private_channel_members",
+				},
+				"write": {
+					"edit_private_channel_members",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v33RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v33RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v33RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,38 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+)
+
+// v34 グループDM
+func v34() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "34",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v34GroupDMChannelMapping{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"group_dm_channel_mappings", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v34GroupDMChannelMapping struct {
+	ChannelID  uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	MembersKey string    `gorm:"type:char(64);not null;unique"`
+}
+
+func (v34GroupDMChannelMapping) TableName() string {
+	return "group_dm_channel_mappings"
+}
@@ -0,0 +1,44 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v35 チャンネルテンプレート
+func v35() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "35",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v35ChannelTemplate{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"channel_templates", "creator_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v35ChannelTemplate struct {
+	ID          uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Name        string    `gorm:"type:varchar(30);not null;unique"`
+	Description string    `gorm:"type:text;not null"`
+	Definition  string    `sql:"type:TEXT COLLATE utf8mb4_bin NOT NULL"`
+	CreatorID   uuid.UUID `gorm:"type:char(36);not null"`
+	CreatedAt   time.Time `gorm:"precision:6"`
+	UpdatedAt   time.Time `gorm:"precision:6"`
+}
+
+func (v35ChannelTemplate) TableName() string {
+	return "channel_templates"
+}
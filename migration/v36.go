@@ -0,0 +1,38 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+)
+
+// v36 プライベートチャンネル名の一意性をチャンネル毎に
+func v36() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "36",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v36Channel{}).Error; err != nil {
+				return err
+			}
+			if err := db.Exec("UPDATE `channels` SET `name_scope` = ?", uuid.Nil).Error; err != nil {
+				return err
+			}
+			if err := db.Exec("UPDATE `channels` SET `name_scope` = `id` WHERE `parent_id` = ?", "bbbbbbbb-bbbb-4bbb-bbbb-bbbbbbbbbbbb").Error; err != nil {
+				return err
+			}
+			return db.Table("channels").
+				RemoveIndex("name_parent").
+				AddUniqueIndex("name_parent", "name", "parent_id", "name_scope").
+				Error
+		},
+	}
+}
+
+type v36Channel struct {
+	ID        uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	NameScope uuid.UUID `gorm:"type:char(36);not null"`
+}
+
+func (v36Channel) TableName() string {
+	return "channels"
+}
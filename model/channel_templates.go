@@ -0,0 +1,88 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// ChannelTemplate チャンネルテンプレート構造体
+//
+// Definitionのルートノードは、インスタンス化時に指定した名前のチャンネルになります。
+type ChannelTemplate struct {
+	ID          uuid.UUID           `gorm:"type:char(36);not null;primary_key"`
+	Name        string              `gorm:"type:varchar(30);not null;unique"`
+	Description string              `gorm:"type:text;not null"`
+	Definition  ChannelTemplateNode `sql:"type:TEXT COLLATE utf8mb4_bin NOT NULL"`
+	CreatorID   uuid.UUID           `gorm:"type:char(36);not null"`
+	CreatedAt   time.Time           `gorm:"precision:6"`
+	UpdatedAt   time.Time           `gorm:"precision:6"`
+}
+
+// TableName ChannelTemplate構造体のテーブル名
+func (*ChannelTemplate) TableName() string {
+	return "channel_templates"
+}
+
+// ChannelTemplateNode チャンネルテンプレートの各チャンネルの定義
+type ChannelTemplateNode struct {
+	// Name チャンネル名 (ルートノードの場合は無視されます)
+	Name string `json:"name,omitempty"`
+	// Topic チャンネルトピック
+	Topic string `json:"topic"`
+	// Force 強制通知チャンネルかどうか
+	Force bool `json:"force"`
+	// GroupSubscriptions グループによる購読設定
+	GroupSubscriptions []*ChannelTemplateGroupSubscription `json:"groupSubscriptions"`
+	// Bots 参加させるBOTのUUID
+	Bots []uuid.UUID `json:"bots"`
+	// Children 子チャンネルの定義
+	Children []*ChannelTemplateNode `json:"children"`
+}
+
+// ChannelTemplateGroupSubscription チャンネルテンプレートのグループ購読設定
+type ChannelTemplateGroupSubscription struct {
+	GroupID uuid.UUID             `json:"groupId"`
+	Level   ChannelSubscribeLevel `json:"level"`
+}
+
+// Depth このノードをルートとするチャンネルツリーの深さを返します
+func (n *ChannelTemplateNode) Depth() int {
+	max := 0
+	for _, child := range n.Children {
+		if d := child.Depth(); d > max {
+			max = d
+		}
+	}
+	return max + 1
+}
+
+// Count このノードをルートとするチャンネルツリーのチャンネル数を返します
+func (n *ChannelTemplateNode) Count() int {
+	count := 1
+	for _, child := range n.Children {
+		count += child.Count()
+	}
+	return count
+}
+
+// Value database/sql/driver.Valuer 実装
+func (n ChannelTemplateNode) Value() (driver.Value, error) {
+	return json.MarshalToString(n)
+}
+
+// Scan database/sql.Scanner 実装
+func (n *ChannelTemplateNode) Scan(src interface{}) error {
+	*n = ChannelTemplateNode{}
+	switch s := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(s), n)
+	case []byte:
+		return json.Unmarshal(s, n)
+	default:
+		return errors.New("failed to scan ChannelTemplateNode")
+	}
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestChannelTemplate_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "channel_templates", (&ChannelTemplate{}).TableName())
+}
+
+func TestChannelTemplateNode_Depth(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 1, (&ChannelTemplateNode{}).Depth())
+	assert.Equal(t, 3, (&ChannelTemplateNode{
+		Children: []*ChannelTemplateNode{
+			{Name: "a"},
+			{Name: "b", Children: []*ChannelTemplateNode{{Name: "c"}}},
+		},
+	}).Depth())
+}
+
+func TestChannelTemplateNode_Count(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 1, (&ChannelTemplateNode{}).Count())
+	assert.Equal(t, 4, (&ChannelTemplateNode{
+		Children: []*ChannelTemplateNode{
+			{Name: "a"},
+			{Name: "b", Children: []*ChannelTemplateNode{{Name: "c"}}},
+		},
+	}).Count())
+}
@@ -10,17 +10,27 @@ import (
 const (
 	// DirectMessageChannelRootID ダイレクトメッセージチャンネルの親チャンネルID
 	DirectMessageChannelRootID = "aaaaaaaa-aaaa-4aaa-aaaa-aaaaaaaaaaaa"
+	// PrivateChannelRootID プライベートチャンネルの親チャンネルID
+	PrivateChannelRootID = "bbbbbbbb-bbbb-4bbb-bbbb-bbbbbbbbbbbb"
 	// MaxChannelDepth チャンネルの深さの最大
 	MaxChannelDepth = 5
 )
 
-var dmChannelRootUUID = uuid.Must(uuid.FromString(DirectMessageChannelRootID))
+var (
+	dmChannelRootUUID      = uuid.Must(uuid.FromString(DirectMessageChannelRootID))
+	privateChannelRootUUID = uuid.Must(uuid.FromString(PrivateChannelRootID))
+)
 
 // Channel チャンネルの構造体
 type Channel struct {
-	ID        uuid.UUID  `gorm:"type:char(36);not null;primary_key"`
-	Name      string     `gorm:"type:varchar(20);not null;unique_index:name_parent"`
-	ParentID  uuid.UUID  `gorm:"type:char(36);not null;unique_index:name_parent"`
+	ID       uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Name     string    `gorm:"type:varchar(20);not null;unique_index:name_parent"`
+	ParentID uuid.UUID `gorm:"type:char(36);not null;unique_index:name_parent"`
+	// NameScope チャンネル名が一意である範囲
+	//
+	// プライベートチャンネルの場合はチャンネル自身のID、それ以外の場合はuuid.Nilです。
+	// プライベートチャンネルの名前は他のチャンネルと重複できます。
+	NameScope uuid.UUID  `gorm:"type:char(36);not null;unique_index:name_parent"`
 	Topic     string     `sql:"type:TEXT COLLATE utf8mb4_bin NOT NULL"`
 	IsForced  bool       `gorm:"type:boolean;not null;default:false"`
 	IsPublic  bool       `gorm:"type:boolean;not null;default:false"`
@@ -44,6 +54,11 @@ func (ch *Channel) IsDMChannel() bool {
 	return ch.ParentID == dmChannelRootUUID
 }
 
+// IsPrivateChannel 複数人のメンバーで構成されるプライベートチャンネルかどうかを返します
+func (ch *Channel) IsPrivateChannel() bool {
+	return ch.ParentID == privateChannelRootUUID
+}
+
 // IsArchived アーカイブされているチャンネルかどうか
 func (ch *Channel) IsArchived() bool {
 	return !ch.IsVisible
@@ -60,6 +75,31 @@ func (upc *UsersPrivateChannel) TableName() string {
 	return "users_private_channels"
 }
 
+// ChannelReadState ユーザーのチャンネルの既読位置
+type ChannelReadState struct {
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	MessageID uuid.UUID `gorm:"type:char(36);not null"`
+	ReadAt    time.Time `gorm:"precision:6"`
+}
+
+// TableName テーブル名を指定するメソッド
+func (*ChannelReadState) TableName() string {
+	return "channel_read_states"
+}
+
+// ChannelRedirect 統合されたチャンネルの統合先
+type ChannelRedirect struct {
+	ChannelID   uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	ToChannelID uuid.UUID `gorm:"type:char(36);not null;index"`
+	CreatedAt   time.Time `gorm:"precision:6"`
+}
+
+// TableName テーブル名を指定するメソッド
+func (*ChannelRedirect) TableName() string {
+	return "channel_redirects"
+}
+
 // ChannelSubscribeLevel チャンネル購読レベル
 type ChannelSubscribeLevel int
 
@@ -77,11 +117,15 @@ func (v ChannelSubscribeLevel) Int() int {
 }
 
 // UserSubscribeChannel ユーザー・通知チャンネル対構造体
+//
+// Autoがtrueの場合、グループによる購読設定によって自動で設定されたものです。
+// ユーザー自身が購読レベルを変更するとAutoはfalseになり、以降は自動で変更されません。
 type UserSubscribeChannel struct {
 	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
 	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
 	Mark      bool      `gorm:"type:boolean;not null;default:false"`
 	Notify    bool      `gorm:"type:boolean;not null;default:false"`
+	Auto      bool      `gorm:"type:boolean;not null;default:false"`
 }
 
 // TableName UserNotifiedChannel構造体のテーブル名
@@ -101,6 +145,34 @@ func (usc *UserSubscribeChannel) GetLevel() ChannelSubscribeLevel {
 	}
 }
 
+// ChannelGroupSubscription チャンネルのグループ購読設定構造体
+//
+// 指定したグループのメンバー(子グループのメンバーを含む)は、指定したレベルでチャンネルを購読します。
+type ChannelGroupSubscription struct {
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	GroupID   uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	Mark      bool      `gorm:"type:boolean;not null;default:false"`
+	Notify    bool      `gorm:"type:boolean;not null;default:false"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName ChannelGroupSubscription構造体のテーブル名
+func (*ChannelGroupSubscription) TableName() string {
+	return "channel_group_subscriptions"
+}
+
+// GetLevel 購読レベルを返します
+func (cgs *ChannelGroupSubscription) GetLevel() ChannelSubscribeLevel {
+	switch {
+	case cgs.Notify:
+		return ChannelSubscribeLevelMarkAndNotify
+	case cgs.Mark:
+		return ChannelSubscribeLevelMark
+	default:
+		return ChannelSubscribeLevelNone
+	}
+}
+
 // DMChannelMapping ダイレクトメッセージチャンネルとユーザーのマッピング
 type DMChannelMapping struct {
 	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
@@ -113,6 +185,17 @@ func (*DMChannelMapping) TableName() string {
 	return "dm_channel_mappings"
 }
 
+// GroupDMChannelMapping グループダイレクトメッセージチャンネルとメンバー集合のマッピング
+type GroupDMChannelMapping struct {
+	ChannelID  uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	MembersKey string    `gorm:"type:char(64);not null;unique"`
+}
+
+// TableName GroupDMChannelMapping構造体のテーブル名
+func (*GroupDMChannelMapping) TableName() string {
+	return "group_dm_channel_mappings"
+}
+
 // ChannelEventType チャンネルイベントタイプ
 type ChannelEventType string
 
@@ -171,6 +254,32 @@ const (
 	// 	userId    作成者UUID
 	// 	channelId チャンネルUUID
 	ChannelEventChildCreated = ChannelEventType("ChildCreated")
+	// ChannelEventArchived チャンネルイベント アーカイブ
+	//
+	// 	userId  変更者UUID
+	// 	cascade 親チャンネルのアーカイブに伴うものかどうか
+	ChannelEventArchived = ChannelEventType("Archived")
+	// ChannelEventUnarchived チャンネルイベント アーカイブ解除
+	//
+	// 	userId  変更者UUID
+	// 	cascade 親チャンネルのアーカイブ解除に伴うものかどうか
+	ChannelEventUnarchived = ChannelEventType("Unarchived")
+	// ChannelEventMergedInto チャンネルイベント 他のチャンネルへの統合
+	//
+	// 	userId    統合者UUID
+	// 	channelId 統合先チャンネルUUID
+	ChannelEventMergedInto = ChannelEventType("MergedInto")
+	// ChannelEventChannelMerged チャンネルイベント 他のチャンネルの統合
+	//
+	// 	userId    統合者UUID
+	// 	channelId 統合元チャンネルUUID
+	ChannelEventChannelMerged = ChannelEventType("ChannelMerged")
+	// ChannelEventMembersChanged チャンネルイベント プライベートチャンネルメンバー変更
+	//
+	// 	userId  変更者UUID
+	// 	added   追加されたユーザーのUUIDの配列
+	// 	removed 削除されたユーザーのUUIDの配列
+	ChannelEventMembersChanged = ChannelEventType("MembersChanged")
 )
 
 // ChannelEventDetail チャンネルイベント詳細
@@ -17,6 +17,13 @@ func TestChannel_IsDMChannel(t *testing.T) {
 	assert.True(t, (&Channel{ParentID: dmChannelRootUUID}).IsDMChannel())
 }
 
+func TestChannel_IsPrivateChannel(t *testing.T) {
+	t.Parallel()
+	assert.False(t, (&Channel{ParentID: uuid.Nil}).IsPrivateChannel())
+	assert.False(t, (&Channel{ParentID: dmChannelRootUUID}).IsPrivateChannel())
+	assert.True(t, (&Channel{ParentID: privateChannelRootUUID}).IsPrivateChannel())
+}
+
 func TestUsersPrivateChannel_TableName(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "users_private_channels", (&UsersPrivateChannel{}).TableName())
@@ -28,6 +35,20 @@ func TestUserSubscribeChannel_TableName(t *testing.T) {
 	assert.Equal(t, "users_subscribe_channels", (&UserSubscribeChannel{}).TableName())
 }
 
+func TestChannelGroupSubscription_TableName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "channel_group_subscriptions", (&ChannelGroupSubscription{}).TableName())
+}
+
+func TestChannelGroupSubscription_GetLevel(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, ChannelSubscribeLevelNone, (&ChannelGroupSubscription{}).GetLevel())
+	assert.Equal(t, ChannelSubscribeLevelMark, (&ChannelGroupSubscription{Mark: true}).GetLevel())
+	assert.Equal(t, ChannelSubscribeLevelMarkAndNotify, (&ChannelGroupSubscription{Mark: true, Notify: true}).GetLevel())
+}
+
 func TestDMChannelMapping_TableName(t *testing.T) {
 	t.Parallel()
 
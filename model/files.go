@@ -87,6 +87,10 @@ type File interface {
 	GetThumbnailMIMEType() string
 	GetThumbnailWidth() int
 	GetThumbnailHeight() int
+	GetWidth() int
+	GetHeight() int
+	GetDuration() time.Duration
+	GetCodec() string
 	GetUploadChannelID() optional.UUID
 	GetCreatedAt() time.Time
 
@@ -108,7 +112,12 @@ type FileMeta struct {
 	ThumbnailMime   optional.String `gorm:"type:text"`
 	ThumbnailWidth  int             `gorm:"type:int;not null;default:0"`
 	ThumbnailHeight int             `gorm:"type:int;not null;default:0"`
+	Width           int             `gorm:"type:int;not null;default:0"`
+	Height          int             `gorm:"type:int;not null;default:0"`
+	DurationMS      int64           `gorm:"type:bigint;not null;default:0"`
+	Codec           string          `gorm:"type:varchar(50);not null;default:''"`
 	ChannelID       optional.UUID   `gorm:"type:char(36)"`
+	ObjectID        uuid.UUID       `gorm:"type:char(36);not null;index"`
 	CreatedAt       time.Time       `gorm:"precision:6"`
 	DeletedAt       *time.Time      `gorm:"precision:6"`
 }
@@ -118,6 +127,31 @@ func (f FileMeta) TableName() string {
 	return "files"
 }
 
+// StorageKey ファイル実体のストレージ上のキーを返します
+func (f FileMeta) StorageKey() string {
+	if f.ObjectID == uuid.Nil {
+		return f.ID.String()
+	}
+	return f.ObjectID.String()
+}
+
+// FileObject ファイル実体構造体
+//
+// 同一内容・同一タイプのファイルは1つの実体を共有し、RefCountで参照しているファイルメタ(削除済みのものは除く)の数を管理します。
+type FileObject struct {
+	ID        uuid.UUID       `gorm:"type:char(36);not null;primary_key"`
+	Hash      optional.String `gorm:"type:char(64);unique_index:idx_file_objects_hash_type"`
+	Type      FileType        `gorm:"type:varchar(30);not null;default:'';unique_index:idx_file_objects_hash_type"`
+	Size      int64           `gorm:"type:bigint;not null"`
+	RefCount  int             `gorm:"type:int;not null;default:0"`
+	CreatedAt time.Time       `gorm:"precision:6"`
+}
+
+// TableName ファイル実体テーブル名を取得します
+func (*FileObject) TableName() string {
+	return "file_objects"
+}
+
 // FileACLEntry ファイルアクセスコントロールリストエントリー構造体
 type FileACLEntry struct {
 	FileID uuid.UUID     `gorm:"type:char(36);primary_key;not null"`
@@ -129,3 +163,57 @@ type FileACLEntry struct {
 func (f FileACLEntry) TableName() string {
 	return "files_acl"
 }
+
+// UserFileUsage ユーザー別ファイル使用量構造体
+//
+// ファイルの保存・削除により逐次更新される集計値です。
+type UserFileUsage struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key" json:"userId"`
+	Size   int64     `gorm:"type:bigint;not null;default:0" json:"size"`
+	Count  int64     `gorm:"type:bigint;not null;default:0" json:"count"`
+}
+
+// TableName ユーザー別ファイル使用量テーブル名を取得します
+func (*UserFileUsage) TableName() string {
+	return "user_file_usages"
+}
+
+// ChannelFileUsage チャンネル別ファイル使用量構造体
+//
+// ファイルの保存・削除により逐次更新される集計値です。
+type ChannelFileUsage struct {
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key" json:"channelId"`
+	Size      int64     `gorm:"type:bigint;not null;default:0" json:"size"`
+	Count     int64     `gorm:"type:bigint;not null;default:0" json:"count"`
+}
+
+// TableName チャンネル別ファイル使用量テーブル名を取得します
+func (*ChannelFileUsage) TableName() string {
+	return "channel_file_usages"
+}
+
+// FileUpload 再開可能なファイルアップロード構造体
+//
+// 受信したデータはサーバーのステージング領域に一時保存され、全て受信した後にファイルとして保存されます。
+type FileUpload struct {
+	ID        uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Name      string    `gorm:"type:text;not null"`
+	Mime      string    `gorm:"type:text;not null"`
+	Size      int64     `gorm:"type:bigint;not null"`
+	Offset    int64     `gorm:"type:bigint;not null;default:0"`
+	CreatorID uuid.UUID `gorm:"type:char(36);not null;index"`
+	ChannelID uuid.UUID `gorm:"type:char(36);not null"`
+	ExpiresAt time.Time `gorm:"precision:6;index"`
+	CreatedAt time.Time `gorm:"precision:6"`
+	UpdatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName ファイルアップロードテーブル名を取得します
+func (*FileUpload) TableName() string {
+	return "file_uploads"
+}
+
+// IsCompleted 全てのデータを受信済みかどうか
+func (u *FileUpload) IsCompleted() bool {
+	return u.Offset >= u.Size
+}
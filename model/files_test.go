@@ -3,6 +3,7 @@ package model
 import (
 	"testing"
 
+	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -11,7 +12,42 @@ func TestFile_TableName(t *testing.T) {
 	assert.Equal(t, "files", (&FileMeta{}).TableName())
 }
 
+func TestFileMeta_StorageKey(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.NewV3(uuid.Nil, "file")
+	objectID := uuid.NewV3(uuid.Nil, "object")
+	assert.Equal(t, id.String(), FileMeta{ID: id}.StorageKey())
+	assert.Equal(t, objectID.String(), FileMeta{ID: id, ObjectID: objectID}.StorageKey())
+}
+
+func TestFileObject_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "file_objects", (&FileObject{}).TableName())
+}
+
 func TestFileACLEntry_TableName(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "files_acl", (&FileACLEntry{}).TableName())
 }
+
+func TestUserFileUsage_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user_file_usages", (&UserFileUsage{}).TableName())
+}
+
+func TestChannelFileUsage_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "channel_file_usages", (&ChannelFileUsage{}).TableName())
+}
+
+func TestFileUpload_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "file_uploads", (&FileUpload{}).TableName())
+}
+
+func TestFileUpload_IsCompleted(t *testing.T) {
+	t.Parallel()
+	assert.False(t, (&FileUpload{Size: 10, Offset: 5}).IsCompleted())
+	assert.True(t, (&FileUpload{Size: 10, Offset: 10}).IsCompleted())
+}
@@ -22,6 +22,9 @@ import (
 // /と"は使えません。
 type AccessScope string
 
+// ScopeOpenID OpenID Connectのスコープ
+const ScopeOpenID AccessScope = "openid"
+
 // AccessScopes AccessScopeのセット
 type AccessScopes map[AccessScope]struct{}
 
@@ -109,7 +112,7 @@ func (arr AccessScopes) StringArray() (r []string) {
 // Validate github.com/go-ozzo/ozzo-validation.Validatable 実装
 func (arr AccessScopes) Validate() error {
 	// TODO カスタムスコープに対応
-	return vd.Validate(arr.StringArray(), vd.Each(vd.Required, vd.In("read", "write", "manage_bot")))
+	return vd.Validate(arr.StringArray(), vd.Each(vd.Required, vd.In("read", "write", "manage_bot", string(ScopeOpenID))))
 }
 
 // OAuth2Authorize OAuth2 認可データの構造体
@@ -232,3 +235,41 @@ func (t *OAuth2Token) IsExpired() bool {
 func (t *OAuth2Token) IsRefreshEnabled() bool {
 	return t.RefreshEnabled && len(t.RefreshToken) != 0
 }
+
+const (
+	// DeviceAuthorizePending デバイス認可がユーザーの承認待ち
+	DeviceAuthorizePending = "pending"
+	// DeviceAuthorizeApproved デバイス認可がユーザーに承認された
+	DeviceAuthorizeApproved = "approved"
+	// DeviceAuthorizeDenied デバイス認可がユーザーに拒否された
+	DeviceAuthorizeDenied = "denied"
+)
+
+// OAuth2DeviceAuthorize OAuth2 デバイス認可データの構造体 (RFC 8628)
+type OAuth2DeviceAuthorize struct {
+	DeviceCode   string       `gorm:"type:varchar(64);primary_key"`
+	UserCode     string       `gorm:"type:varchar(16);not null;unique"`
+	ClientID     string       `gorm:"type:char(36);not null"`
+	UserID       uuid.UUID    `gorm:"type:char(36)"`
+	Scopes       AccessScopes `gorm:"type:text"`
+	Status       string       `gorm:"type:varchar(10);not null"`
+	PollInterval int          `gorm:"not null"`
+	ExpiresIn    int          `gorm:"not null"`
+	LastPolledAt *time.Time   `gorm:"precision:6"`
+	CreatedAt    time.Time    `gorm:"precision:6"`
+}
+
+// TableName OAuth2DeviceAuthorizeのテーブル名
+func (*OAuth2DeviceAuthorize) TableName() string {
+	return "oauth2_device_authorizes"
+}
+
+// IsExpired 有効期限が切れているかどうか
+func (data *OAuth2DeviceAuthorize) IsExpired() bool {
+	return data.CreatedAt.Add(time.Duration(data.ExpiresIn) * time.Second).Before(time.Now())
+}
+
+// IsPolledTooFast 前回のポーリングからPollInterval秒経過していないかどうか
+func (data *OAuth2DeviceAuthorize) IsPolledTooFast(now time.Time) bool {
+	return data.LastPolledAt != nil && data.LastPolledAt.Add(time.Duration(data.PollInterval)*time.Second).After(now)
+}
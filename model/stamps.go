@@ -2,6 +2,7 @@ package model
 
 import (
 	"github.com/gofrs/uuid"
+	"strings"
 	"time"
 )
 
@@ -12,6 +13,9 @@ type Stamp struct {
 	CreatorID uuid.UUID  `gorm:"type:char(36);not null"                    json:"creatorId"`
 	FileID    uuid.UUID  `gorm:"type:char(36);not null"                    json:"fileId"`
 	IsUnicode bool       `gorm:"type:boolean;not null;default:false;index" json:"isUnicode"`
+	Category  string     `gorm:"type:varchar(30);not null;default:''"      json:"category"`
+	Aliases   []string   `gorm:"-"                                         json:"aliases"`
+	Tags      []string   `gorm:"-"                                         json:"tags"`
 	CreatedAt time.Time  `gorm:"precision:6"                               json:"createdAt"`
 	UpdatedAt time.Time  `gorm:"precision:6"                               json:"updatedAt"`
 	DeletedAt *time.Time `gorm:"precision:6"                               json:"-"`
@@ -26,3 +30,101 @@ func (*Stamp) TableName() string {
 func (s *Stamp) IsSystemStamp() bool {
 	return s.CreatorID == uuid.Nil && s.ID != uuid.Nil && len(s.Name) > 0
 }
+
+// スタンプ検索の一致度 (小さいほど良く一致している)
+const (
+	StampMatchNone = iota
+	StampMatchExact
+	StampMatchPrefix
+	StampMatchPartial
+	StampMatchTag
+)
+
+// Match スタンプが検索クエリに一致するかどうかを、一致度で返します
+//
+// 名前とエイリアスは完全一致・前方一致・部分一致、タグとカテゴリーは前方一致で判定します。
+// 大文字小文字は区別しません。一致しない場合はStampMatchNoneを返します。
+func (s *Stamp) Match(query string) int {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if len(q) == 0 {
+		return StampMatchNone
+	}
+
+	best := StampMatchNone
+	update := func(m int) {
+		if best == StampMatchNone || m < best {
+			best = m
+		}
+	}
+	for _, name := range append([]string{s.Name}, s.Aliases...) {
+		name = strings.ToLower(name)
+		switch {
+		case name == q:
+			update(StampMatchExact)
+		case strings.HasPrefix(name, q):
+			update(StampMatchPrefix)
+		case strings.Contains(name, q):
+			update(StampMatchPartial)
+		}
+	}
+	for _, tag := range append([]string{s.Category}, s.Tags...) {
+		if len(tag) > 0 && strings.HasPrefix(strings.ToLower(tag), q) {
+			update(StampMatchTag)
+		}
+	}
+	return best
+}
+
+// StampAlias スタンプエイリアス構造体
+type StampAlias struct {
+	Name      string    `gorm:"type:varchar(32);not null;primary_key"`
+	StampID   uuid.UUID `gorm:"type:char(36);not null;index"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName スタンプエイリアステーブル名を取得します
+func (*StampAlias) TableName() string {
+	return "stamp_aliases"
+}
+
+// StampTag スタンプタグ構造体
+type StampTag struct {
+	StampID   uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Tag       string    `gorm:"type:varchar(30);not null;primary_key"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName スタンプタグテーブル名を取得します
+func (*StampTag) TableName() string {
+	return "stamp_tags"
+}
+
+// StampDailyUsage スタンプの日別・チャンネル別使用数構造体
+//
+// スタンプが押された・消されたイベントにより逐次更新される集計値です。
+// 1つのメッセージに1人のユーザーが押したスタンプを1回として数えます。
+type StampDailyUsage struct {
+	Date      time.Time `gorm:"type:date;not null;primary_key"`
+	StampID   uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	Count     int       `gorm:"type:int;not null;default:0"`
+}
+
+// TableName スタンプ日別使用数テーブル名を取得します
+func (*StampDailyUsage) TableName() string {
+	return "stamp_daily_usages"
+}
+
+// StampUserUsage スタンプのユーザー別使用数構造体
+//
+// スタンプが押された・消されたイベントにより逐次更新される集計値です。
+type StampUserUsage struct {
+	StampID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID  uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Count   int       `gorm:"type:int;not null;default:0"`
+}
+
+// TableName スタンプユーザー別使用数テーブル名を取得します
+func (*StampUserUsage) TableName() string {
+	return "stamp_user_usages"
+}
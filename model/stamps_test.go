@@ -9,3 +9,56 @@ func TestStamp_TableName(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "stamps", (&Stamp{}).TableName())
 }
+
+func TestStampAlias_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "stamp_aliases", (&StampAlias{}).TableName())
+}
+
+func TestStampTag_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "stamp_tags", (&StampTag{}).TableName())
+}
+
+func TestStampDailyUsage_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "stamp_daily_usages", (&StampDailyUsage{}).TableName())
+}
+
+func TestStampUserUsage_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "stamp_user_usages", (&StampUserUsage{}).TableName())
+}
+
+func TestStamp_Match(t *testing.T) {
+	t.Parallel()
+
+	s := &Stamp{
+		Name:     "thumbs_up",
+		Category: "Reaction",
+		Aliases:  []string{"good", "like"},
+		Tags:     []string{"hand", "ok"},
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"thumbs_up", StampMatchExact},
+		{"THUMBS_UP", StampMatchExact},
+		{"good", StampMatchExact},
+		{"thumbs", StampMatchPrefix},
+		{"goo", StampMatchPrefix},
+		{"up", StampMatchPartial},
+		{"ik", StampMatchPartial},
+		{"han", StampMatchTag},
+		{"reac", StampMatchTag},
+		{"ok", StampMatchTag},
+		{"bad", StampMatchNone},
+		{"", StampMatchNone},
+		{"  ", StampMatchNone},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, s.Match(tt.query), tt.query)
+	}
+}
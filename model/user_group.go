@@ -11,11 +11,13 @@ type UserGroup struct {
 	Name        string    `gorm:"type:varchar(30);not null;unique"`
 	Description string    `gorm:"type:text;not null"`
 	Type        string    `gorm:"type:varchar(30);not null;default:''"`
+	Source      string    `gorm:"type:varchar(30);not null;default:''"`
 	CreatedAt   time.Time `gorm:"precision:6"`
 	UpdatedAt   time.Time `gorm:"precision:6"`
 
-	Admins  []*UserGroupAdmin  `gorm:"association_autoupdate:false;association_autocreate:false;preload:false;foreignkey:GroupID"`
-	Members []*UserGroupMember `gorm:"association_autoupdate:false;association_autocreate:false;preload:false;foreignkey:GroupID"`
+	Admins   []*UserGroupAdmin  `gorm:"association_autoupdate:false;association_autocreate:false;preload:false;foreignkey:GroupID"`
+	Members  []*UserGroupMember `gorm:"association_autoupdate:false;association_autocreate:false;preload:false;foreignkey:GroupID"`
+	Children []*UserGroupChild  `gorm:"association_autoupdate:false;association_autocreate:false;preload:false;foreignkey:GroupID"`
 }
 
 // TableName UserGroup構造体のテーブル名
@@ -23,6 +25,11 @@ func (*UserGroup) TableName() string {
 	return "user_groups"
 }
 
+// IsReadOnly 外部ソースと同期されている読み取り専用のグループかどうか
+func (ug *UserGroup) IsReadOnly() bool {
+	return len(ug.Source) > 0
+}
+
 func (ug *UserGroup) IsAdmin(uid uuid.UUID) bool {
 	for _, admin := range ug.Admins {
 		if admin.UserID == uid {
@@ -49,6 +56,15 @@ func (ug *UserGroup) AdminIDArray() []uuid.UUID {
 	return result
 }
 
+// ChildIDArray 子グループのIDの配列を返します
+func (ug *UserGroup) ChildIDArray() []uuid.UUID {
+	result := make([]uuid.UUID, len(ug.Children))
+	for i, child := range ug.Children {
+		result[i] = child.ChildID
+	}
+	return result
+}
+
 // UserGroupMember ユーザーグループメンバー構造体
 type UserGroupMember struct {
 	GroupID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
@@ -71,3 +87,16 @@ type UserGroupAdmin struct {
 func (*UserGroupAdmin) TableName() string {
 	return "user_group_admins"
 }
+
+// UserGroupChild ユーザーグループの子グループ構造体
+//
+// 子グループのメンバーは親グループのメンバーとしても扱われます。
+type UserGroupChild struct {
+	GroupID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	ChildID uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+}
+
+// TableName UserGroupChild構造体のテーブル名
+func (*UserGroupChild) TableName() string {
+	return "user_group_children"
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -14,3 +15,24 @@ func TestUserGroupMember_TableName(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "user_group_members", (&UserGroupMember{}).TableName())
 }
+
+func TestUserGroup_IsReadOnly(t *testing.T) {
+	t.Parallel()
+	assert.False(t, (&UserGroup{}).IsReadOnly())
+	assert.True(t, (&UserGroup{Source: "ldap"}).IsReadOnly())
+}
+
+func TestUserGroupChild_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user_group_children", (&UserGroupChild{}).TableName())
+}
+
+func TestUserGroup_ChildIDArray(t *testing.T) {
+	t.Parallel()
+	g := &UserGroup{}
+	assert.Empty(t, g.ChildIDArray())
+
+	id1, id2 := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	g.Children = []*UserGroupChild{{GroupID: g.ID, ChildID: id1}, {GroupID: g.ID, ChildID: id2}}
+	assert.Equal(t, []uuid.UUID{id1, id2}, g.ChildIDArray())
+}
@@ -130,6 +130,18 @@ func (UserProfile) TableName() string {
 	return "user_profiles"
 }
 
+// UserSettings ユーザー設定
+type UserSettings struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// ReadReceipts DM・プライベートチャンネルの既読位置を他のメンバーに公開するかどうか
+	ReadReceipts bool      `gorm:"type:boolean;not null;default:false"`
+	UpdatedAt    time.Time `gorm:"precision:6"`
+}
+
+func (UserSettings) TableName() string {
+	return "user_settings"
+}
+
 type ExternalProviderUser struct {
 	UserID       uuid.UUID `gorm:"type:char(36);not null;primary_key"`
 	ProviderName string    `gorm:"type:varchar(30);not null;primary_key"`
@@ -70,8 +70,21 @@ type ChannelRepository interface {
 	GetPublicChannels() ([]*model.Channel, error)
 	// CreateChannel チャンネルを作成します
 	//
-	// dmがtrueの場合、privateMembersに1人または2人のユーザーが入っている必要があります。
+	// dmがtrueの場合、privateMembersに1人以上のユーザーが入っている必要があります。
+	// 3人以上の場合はグループDMチャンネルとして作成され、同じメンバー集合のグループDMチャンネルが既に存在する場合はErrAlreadyExistsを返します。
+	// 同じ親チャンネルの下に同名のチャンネルが既に存在する場合、ErrAlreadyExistsを返します。
+	// ただし、プライベートチャンネルの名前は他のチャンネルと重複できます。
 	CreateChannel(ch model.Channel, privateMembers set.UUID, dm bool) (*model.Channel, error)
+	// CreateChannelTree チャンネルテンプレートの定義に従い、公開チャンネルのツリーを1つのトランザクションで作成します
+	//
+	// 定義のルートノードはparentIDのチャンネルの下にnameという名前で作成されます。
+	// 成功した場合、作成したチャンネルを親が子より先になる順で返します。
+	// 存在しない親チャンネルを指定した場合、ErrNotFoundを返します。
+	// 同じ親チャンネルの下に同名のチャンネルが既に存在する場合、ErrAlreadyExistsを返します。
+	// 定義に存在しないグループ・BOTが含まれている場合、ArgumentErrorを返します。
+	// creatorIDにuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	CreateChannelTree(parentID uuid.UUID, name string, definition model.ChannelTemplateNode, creatorID uuid.UUID) ([]*model.Channel, error)
 	// UpdateChannel 指定したチャンネルの情報を変更します
 	//
 	// 存在しないチャンネルを指定した場合、ErrNotFoundを返します。
@@ -86,13 +99,49 @@ type ChannelRepository interface {
 	GetDirectMessageChannel(user1, user2 uuid.UUID) (*model.Channel, error)
 	// GetDirectMessageChannelMapping 指定したユーザーのDMチャンネルのマッピングを取得します
 	GetDirectMessageChannelMapping(userID uuid.UUID) ([]*model.DMChannelMapping, error)
+	// GetGroupDirectMessageChannel 指定したメンバー集合のグループDMチャンネルを取得します
+	//
+	// 存在しなかった場合、ErrNotFoundを返します。
+	GetGroupDirectMessageChannel(members set.UUID) (*model.Channel, error)
+	// GetGroupDirectMessageChannelMapping 指定したユーザーが参加しているグループDMチャンネルとそのメンバーのマッピングを取得します
+	//
+	// DBによるエラーを返すことがあります。
+	GetGroupDirectMessageChannelMapping(userID uuid.UUID) (map[uuid.UUID][]uuid.UUID, error)
+	// ConvertGroupDirectMessageChannel 指定したグループDMチャンネルをプライベートチャンネルに変換します
+	//
+	// メッセージ・メンバーはそのまま引き継がれ、updaterIDのユーザーがチャンネルの作成者になります。
+	// 存在しないチャンネル・グループDMでないチャンネルを指定した場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	ConvertGroupDirectMessageChannel(channelID uuid.UUID, name string, updaterID uuid.UUID) (*model.Channel, error)
 	// GetPrivateChannelMemberIDs 指定したプライベートチャンネルのメンバーのUUIDを取得します
 	GetPrivateChannelMemberIDs(channelID uuid.UUID) ([]uuid.UUID, error)
 	// ChangeChannelSubscription ユーザーのチャンネルの購読を変更します
 	//
+	// 変更された購読はユーザー自身による設定として扱われ、以降グループによる購読設定で自動的に変更されなくなります。
 	// channelIDにuuid.Nilを指定した場合、ErrNilIDを返します。
 	// 存在しないユーザーを指定した場合は無視されます。
 	ChangeChannelSubscription(channelID uuid.UUID, args ChangeChannelSubscriptionArgs) (on []uuid.UUID, off []uuid.UUID, err error)
+	// SetChannelGroupSubscription 指定したグループのメンバーによるチャンネルの購読レベルを設定します
+	//
+	// グループのメンバー(子グループのメンバーを含む)の購読は、ユーザー自身が変更していない限り自動で設定されます。
+	// levelにChannelSubscribeLevelNoneを指定した場合、設定を削除します。
+	// 通知が有効になったユーザーと無効になったユーザーのIDを返します。
+	// 存在しないチャンネル・グループを指定した場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	SetChannelGroupSubscription(channelID, groupID uuid.UUID, level model.ChannelSubscribeLevel) (on []uuid.UUID, off []uuid.UUID, err error)
+	// GetChannelGroupSubscriptions 指定したチャンネルのグループによる購読設定を取得します
+	//
+	// 成功した場合、作成日時の昇順で返します。
+	// DBによるエラーを返すことがあります。
+	GetChannelGroupSubscriptions(channelID uuid.UUID) ([]*model.ChannelGroupSubscription, error)
+	// SyncGroupChannelSubscriptions 指定したグループによるチャンネルの購読を、指定したユーザーの現在の所属に合わせて更新します
+	//
+	// ユーザー自身が購読レベルを変更したチャンネルは変更されません。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	SyncGroupChannelSubscriptions(userID, groupID uuid.UUID) error
 	// GetChannelSubscriptions 指定したクエリに基づいてチャンネル購読情報を取得します
 	GetChannelSubscriptions(query ChannelSubscriptionQuery) ([]*model.UserSubscribeChannel, error)
 	// GetChannelEvents 指定したクエリでチャンネルイベントを取得します
@@ -106,4 +155,50 @@ type ChannelRepository interface {
 	GetChannelStats(channelID uuid.UUID) (*ChannelStats, error)
 	// RecordChannelEvent チャンネルイベントを記録します
 	RecordChannelEvent(channelID uuid.UUID, eventType model.ChannelEventType, detail model.ChannelEventDetail, datetime time.Time) error
+	// UpdateChannelReadState 指定したチャンネルの最新のメッセージを、指定したユーザーの既読位置として記録します
+	//
+	// 成功した場合、記録した既読位置とnilを返します。
+	// チャンネルにメッセージが存在しない場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateChannelReadState(userID, channelID uuid.UUID, readAt time.Time) (*model.ChannelReadState, error)
+	// GetChannelReadStates 指定したチャンネルの既読位置を取得します
+	//
+	// 既読位置の公開を許可しているユーザーの既読位置のみを返します。
+	// DBによるエラーを返すことがあります。
+	GetChannelReadStates(channelID uuid.UUID) ([]*model.ChannelReadState, error)
+	// MergeChannel 指定したチャンネルを別のチャンネルに統合します
+	//
+	// srcIDのチャンネルのメッセージ(ピン留め・未読を含む)・ファイル・購読設定・スター・BOT参加情報をdstIDのチャンネルに移動し、
+	// srcIDのチャンネルをアーカイブしてdstIDのチャンネルへのリダイレクトを記録します。
+	// 移動先に同じユーザーの購読設定・スターが既に存在する場合は、移動先のものが優先されます。
+	// 存在しないチャンネルを指定した場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	MergeChannel(srcID, dstID, updaterID uuid.UUID) error
+	// GetChannelRedirect 指定したチャンネルの統合先を取得します
+	//
+	// 統合されていないチャンネルを指定した場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetChannelRedirect(channelID uuid.UUID) (*model.ChannelRedirect, error)
+	// GetPrivateChannels 指定したユーザーがメンバーであるプライベートチャンネル(DMを除く)を全て取得します
+	//
+	// DBによるエラーを返すことがあります。
+	GetPrivateChannels(userID uuid.UUID) ([]*model.Channel, error)
+	// AddPrivateChannelMembers 指定したプライベートチャンネルにメンバーを追加します
+	//
+	// 成功した場合、新たに追加されたユーザーのUUIDを返します。既にメンバーであるユーザーは無視されます。
+	// 存在しないチャンネル・公開チャンネルを指定した場合、ErrNotFoundを返します。
+	// channelIDにuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	AddPrivateChannelMembers(channelID uuid.UUID, userIDs set.UUID, updaterID uuid.UUID) ([]uuid.UUID, error)
+	// RemovePrivateChannelMembers 指定したプライベートチャンネルからメンバーを削除します
+	//
+	// 成功した場合、実際に削除されたユーザーのUUIDを返します。メンバーでないユーザーは無視されます。
+	// 削除されたユーザーのチャンネルの購読設定・スターも削除されます。
+	// プライベートチャンネルの作成者(オーナー)が削除され、他のメンバーが残っている場合は、残りのメンバーの1人が新たな作成者になります。
+	// 存在しないチャンネル・公開チャンネルを指定した場合、ErrNotFoundを返します。
+	// channelIDにuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	RemovePrivateChannelMembers(channelID uuid.UUID, userIDs set.UUID, updaterID uuid.UUID) ([]uuid.UUID, error)
 }
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/gofrs/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/leandro-lugaresi/hub"
@@ -10,10 +12,14 @@ import (
 	"github.com/traPtitech/traQ/utils/gormutil"
 	"github.com/traPtitech/traQ/utils/set"
 	"go.uber.org/zap"
+	"sort"
 	"time"
 )
 
-var dmChannelRootUUID = uuid.Must(uuid.FromString(model.DirectMessageChannelRootID))
+var (
+	dmChannelRootUUID      = uuid.Must(uuid.FromString(model.DirectMessageChannelRootID))
+	privateChannelRootUUID = uuid.Must(uuid.FromString(model.PrivateChannelRootID))
+)
 
 // CreateChannel implements ChannelRepository interface.
 func (repo *GormRepository) CreateChannel(ch model.Channel, privateMembers set.UUID, dm bool) (*model.Channel, error) {
@@ -22,6 +28,11 @@ func (repo *GormRepository) CreateChannel(ch model.Channel, privateMembers set.U
 	ch.ID = uuid.Must(uuid.NewV4())
 	ch.IsPublic = true
 	ch.DeletedAt = nil
+	ch.NameScope = uuid.Nil
+	if ch.ParentID == privateChannelRootUUID {
+		// プライベートチャンネルの名前は重複できる
+		ch.NameScope = ch.ID
+	}
 
 	if len(privateMembers) > 0 {
 		ch.IsPublic = false
@@ -39,35 +50,45 @@ func (repo *GormRepository) CreateChannel(ch model.Channel, privateMembers set.U
 		ch.IsPublic = false
 		ch.IsForced = false
 
-		m := &model.DMChannelMapping{
-			ChannelID: ch.ID,
-			User1:     uuid.UUID{},
-			User2:     uuid.UUID{},
-		}
-		if l := len(privateMembers); l == 1 {
-			users := privateMembers.Array()
-			m.User1 = users[0]
-			m.User2 = users[0]
-		} else if l == 2 {
-			users := privateMembers.Array()
-			// user1 <= user2 になるように入れかえ
-			if bytes.Compare(users[0].Bytes(), users[1].Bytes()) == 1 {
-				t := users[0]
-				users[0] = users[1]
-				users[1] = t
-			}
-
-			m.User1 = users[0]
-			m.User2 = users[1]
+		if l := len(privateMembers); l == 0 {
+			return nil, ArgError("privateMembers", "length must be 1 or more")
+		} else if l > 2 {
+			arr = append(arr, &model.GroupDMChannelMapping{
+				ChannelID:  ch.ID,
+				MembersKey: groupDMMembersKey(privateMembers),
+			})
 		} else {
-			return nil, ArgError("privateMembers", "length must be 1 or 2")
+			m := &model.DMChannelMapping{
+				ChannelID: ch.ID,
+				User1:     uuid.UUID{},
+				User2:     uuid.UUID{},
+			}
+			if l == 1 {
+				users := privateMembers.Array()
+				m.User1 = users[0]
+				m.User2 = users[0]
+			} else {
+				users := privateMembers.Array()
+				// user1 <= user2 になるように入れかえ
+				if bytes.Compare(users[0].Bytes(), users[1].Bytes()) == 1 {
+					t := users[0]
+					users[0] = users[1]
+					users[1] = t
+				}
+
+				m.User1 = users[0]
+				m.User2 = users[1]
+			}
+			arr = append(arr, m)
 		}
-		arr = append(arr, m)
 	}
 
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
 		for _, v := range arr {
 			if err := tx.Create(v).Error; err != nil {
+				if gormutil.IsMySQLDuplicatedRecordErr(err) {
+					return ErrAlreadyExists
+				}
 				return err
 			}
 		}
@@ -87,6 +108,156 @@ func (repo *GormRepository) CreateChannel(ch model.Channel, privateMembers set.U
 	return &ch, nil
 }
 
+// CreateChannelTree implements ChannelRepository interface.
+func (repo *GormRepository) CreateChannelTree(parentID uuid.UUID, name string, definition model.ChannelTemplateNode, creatorID uuid.UUID) ([]*model.Channel, error) {
+	if creatorID == uuid.Nil {
+		return nil, ErrNilID
+	}
+
+	var (
+		channels          []*model.Channel
+		botJoins          []*model.BotJoinChannel
+		subscribersChange []uuid.UUID
+	)
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if parentID != uuid.Nil {
+			if exists, err := gormutil.RecordExists(tx, &model.Channel{ID: parentID}); err != nil {
+				return err
+			} else if !exists {
+				return ErrNotFound
+			}
+		}
+
+		groupMembers := map[uuid.UUID]map[uuid.UUID]struct{}{}
+		existingBots := map[uuid.UUID]struct{}{}
+
+		var create func(node *model.ChannelTemplateNode, name string, parentID uuid.UUID) error
+		create = func(node *model.ChannelTemplateNode, name string, parentID uuid.UUID) error {
+			ch := &model.Channel{
+				ID:        uuid.Must(uuid.NewV4()),
+				Name:      name,
+				ParentID:  parentID,
+				Topic:     node.Topic,
+				IsForced:  node.Force,
+				IsPublic:  true,
+				IsVisible: true,
+				CreatorID: creatorID,
+				UpdaterID: creatorID,
+			}
+			if err := tx.Create(ch).Error; err != nil {
+				if gormutil.IsMySQLDuplicatedRecordErr(err) {
+					return ErrAlreadyExists
+				}
+				return err
+			}
+			channels = append(channels, ch)
+
+			// グループによる購読設定
+			subscribersChanged := false
+			for _, s := range node.GroupSubscriptions {
+				if s.Level == model.ChannelSubscribeLevelNone {
+					continue
+				}
+				members, ok := groupMembers[s.GroupID]
+				if !ok {
+					if exists, err := gormutil.RecordExists(tx, &model.UserGroup{ID: s.GroupID}); err != nil {
+						return err
+					} else if !exists {
+						return ArgError("definition", "group not found")
+					}
+					snapshot, err := getUserGroupMemberSnapshot(tx, []uuid.UUID{s.GroupID})
+					if err != nil {
+						return err
+					}
+					members = snapshot[s.GroupID]
+					groupMembers[s.GroupID] = members
+				}
+
+				if err := tx.Create(&model.ChannelGroupSubscription{
+					ChannelID: ch.ID,
+					GroupID:   s.GroupID,
+					Mark:      true,
+					Notify:    s.Level == model.ChannelSubscribeLevelMarkAndNotify,
+				}).Error; err != nil {
+					if gormutil.IsMySQLDuplicatedRecordErr(err) {
+						return ArgError("definition", "duplicated group subscription")
+					}
+					return err
+				}
+				on, _, err := syncAutoChannelSubscriptions(tx, ch.ID, members)
+				if err != nil {
+					return err
+				}
+				subscribersChanged = subscribersChanged || len(on) > 0
+			}
+			if subscribersChanged {
+				subscribersChange = append(subscribersChange, ch.ID)
+			}
+
+			// BOTの参加
+			for _, botID := range node.Bots {
+				if _, ok := existingBots[botID]; !ok {
+					if exists, err := gormutil.RecordExists(tx, &model.Bot{ID: botID}); err != nil {
+						return err
+					} else if !exists {
+						return ArgError("definition", "bot not found")
+					}
+					existingBots[botID] = struct{}{}
+				}
+
+				b := &model.BotJoinChannel{ChannelID: ch.ID, BotID: botID}
+				if err := tx.Create(b).Error; err != nil {
+					if gormutil.IsMySQLDuplicatedRecordErr(err) {
+						return ArgError("definition", "duplicated bot")
+					}
+					return err
+				}
+				botJoins = append(botJoins, b)
+			}
+
+			for _, child := range node.Children {
+				if err := create(child, child.Name, ch.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return create(&definition, name, parentID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ch := range channels {
+		repo.hub.Publish(hub.Message{
+			Name: event.ChannelCreated,
+			Fields: hub.Fields{
+				"channel_id": ch.ID,
+				"channel":    ch,
+				"private":    false,
+			},
+		})
+	}
+	for _, channelID := range subscribersChange {
+		repo.hub.Publish(hub.Message{
+			Name: event.ChannelSubscribersChanged,
+			Fields: hub.Fields{
+				"channel_id": channelID,
+			},
+		})
+	}
+	for _, b := range botJoins {
+		repo.hub.Publish(hub.Message{
+			Name: event.BotJoined,
+			Fields: hub.Fields{
+				"bot_id":     b.BotID,
+				"channel_id": b.ChannelID,
+			},
+		})
+	}
+	return channels, nil
+}
+
 // UpdateChannel implements ChannelRepository interface.
 func (repo *GormRepository) UpdateChannel(channelID uuid.UUID, args UpdateChannelArgs) (*model.Channel, error) {
 	if channelID == uuid.Nil {
@@ -202,6 +373,103 @@ func (repo *GormRepository) GetDirectMessageChannelMapping(userID uuid.UUID) (ma
 		Error
 }
 
+// GetGroupDirectMessageChannel implements ChannelRepository interface.
+func (repo *GormRepository) GetGroupDirectMessageChannel(members set.UUID) (*model.Channel, error) {
+	if len(members) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var ch model.Channel
+	err := repo.db.
+		Where("id = (SELECT channel_id FROM group_dm_channel_mappings WHERE members_key = ?)", groupDMMembersKey(members)).
+		First(&ch).
+		Error
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return &ch, nil
+}
+
+// GetGroupDirectMessageChannelMapping implements ChannelRepository interface.
+func (repo *GormRepository) GetGroupDirectMessageChannelMapping(userID uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	mapping := map[uuid.UUID][]uuid.UUID{}
+	if userID == uuid.Nil {
+		return mapping, nil
+	}
+
+	var rows []struct {
+		ChannelID uuid.UUID
+		UserID    uuid.UUID
+	}
+	err := repo.db.
+		Table("group_dm_channel_mappings m").
+		Select("m.channel_id, upc.user_id").
+		Joins("INNER JOIN users_private_channels upc ON upc.channel_id = m.channel_id").
+		Where("m.channel_id IN (SELECT channel_id FROM users_private_channels WHERE user_id = ?)", userID).
+		Scan(&rows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		mapping[row.ChannelID] = append(mapping[row.ChannelID], row.UserID)
+	}
+	return mapping, nil
+}
+
+// ConvertGroupDirectMessageChannel implements ChannelRepository interface.
+func (repo *GormRepository) ConvertGroupDirectMessageChannel(channelID uuid.UUID, name string, updaterID uuid.UUID) (*model.Channel, error) {
+	if channelID == uuid.Nil || updaterID == uuid.Nil {
+		return nil, ErrNilID
+	}
+
+	var ch model.Channel
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.GroupDMChannelMapping{ChannelID: channelID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		data := map[string]interface{}{
+			"name":       name,
+			"parent_id":  privateChannelRootUUID,
+			"name_scope": channelID,
+			"creator_id": updaterID,
+			"updater_id": updaterID,
+		}
+		if err := tx.Model(&model.Channel{ID: channelID}).Updates(data).Error; err != nil {
+			return err
+		}
+		return tx.First(&ch, &model.Channel{ID: channelID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repo.hub.Publish(hub.Message{
+		Name: event.ChannelUpdated,
+		Fields: hub.Fields{
+			"channel_id": channelID,
+			"private":    true,
+		},
+	})
+	return &ch, nil
+}
+
+// groupDMMembersKey グループDMのメンバー集合を一意に表すキーを返します
+func groupDMMembersKey(members set.UUID) string {
+	ids := members.Array()
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i].Bytes(), ids[j].Bytes()) < 0 })
+	h := sha256.New()
+	for _, id := range ids {
+		h.Write(id.Bytes())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // GetPrivateChannelMemberIDs implements ChannelRepository interface.
 func (repo *GormRepository) GetPrivateChannelMemberIDs(channelID uuid.UUID) (users []uuid.UUID, err error) {
 	users = make([]uuid.UUID, 0)
@@ -230,12 +498,20 @@ func (repo *GormRepository) ChangeChannelSubscription(channelID uuid.UUID, args
 			return err
 		}
 		current := make(map[uuid.UUID]model.ChannelSubscribeLevel, len(_current))
+		auto := make(map[uuid.UUID]bool, len(_current))
 		for _, s := range _current {
 			current[s.UserID] = s.GetLevel()
+			auto[s.UserID] = s.Auto
 		}
 
 		for uid, level := range args.Subscription {
 			if cl := current[uid]; cl == level {
+				if auto[uid] {
+					// グループによる自動設定からユーザー自身による設定に切り替える
+					if err := tx.Model(model.UserSubscribeChannel{}).Where(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Update("auto", false).Error; err != nil {
+						return err
+					}
+				}
 				continue // 既に同じ設定がされているのでスキップ
 			}
 
@@ -251,8 +527,15 @@ func (repo *GormRepository) ChangeChannelSubscription(channelID uuid.UUID, args
 					}
 				}
 
-				if err := tx.Delete(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Error; err != nil {
-					return err
+				if auto[uid] {
+					// グループによって再び自動で購読されないように、購読しない設定として残す
+					if err := tx.Model(model.UserSubscribeChannel{}).Where(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Updates(map[string]bool{"mark": false, "notify": false, "auto": false}).Error; err != nil {
+						return err
+					}
+				} else {
+					if err := tx.Delete(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Error; err != nil {
+						return err
+					}
 				}
 				if current[uid] == model.ChannelSubscribeLevelMarkAndNotify {
 					off = append(off, uid)
@@ -260,7 +543,7 @@ func (repo *GormRepository) ChangeChannelSubscription(channelID uuid.UUID, args
 
 			case model.ChannelSubscribeLevelMark:
 				if _, ok := current[uid]; ok {
-					if err := tx.Model(model.UserSubscribeChannel{}).Where(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Updates(map[string]bool{"mark": true, "notify": false}).Error; err != nil {
+					if err := tx.Model(model.UserSubscribeChannel{}).Where(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Updates(map[string]bool{"mark": true, "notify": false, "auto": false}).Error; err != nil {
 						return err
 					}
 				} else {
@@ -274,7 +557,7 @@ func (repo *GormRepository) ChangeChannelSubscription(channelID uuid.UUID, args
 
 			case model.ChannelSubscribeLevelMarkAndNotify:
 				if _, ok := current[uid]; ok {
-					if err := tx.Model(model.UserSubscribeChannel{}).Where(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Updates(map[string]bool{"mark": true, "notify": true}).Error; err != nil {
+					if err := tx.Model(model.UserSubscribeChannel{}).Where(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Updates(map[string]bool{"mark": true, "notify": true, "auto": false}).Error; err != nil {
 						return err
 					}
 				} else {
@@ -305,6 +588,211 @@ func (repo *GormRepository) ChangeChannelSubscription(channelID uuid.UUID, args
 	return on, off, nil
 }
 
+// SetChannelGroupSubscription implements ChannelRepository interface.
+func (repo *GormRepository) SetChannelGroupSubscription(channelID, groupID uuid.UUID, level model.ChannelSubscribeLevel) (on []uuid.UUID, off []uuid.UUID, err error) {
+	if channelID == uuid.Nil || groupID == uuid.Nil {
+		return nil, nil, ErrNilID
+	}
+
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		if exists, err := gormutil.RecordExists(tx, &model.Channel{ID: channelID}); err != nil {
+			return err
+		} else if !exists {
+			return ErrNotFound
+		}
+		if exists, err := gormutil.RecordExists(tx, &model.UserGroup{ID: groupID}); err != nil {
+			return err
+		} else if !exists {
+			return ErrNotFound
+		}
+
+		s := &model.ChannelGroupSubscription{ChannelID: channelID, GroupID: groupID}
+		if level == model.ChannelSubscribeLevelNone {
+			if err := tx.Delete(s).Error; err != nil {
+				return err
+			}
+		} else {
+			exists, err := gormutil.RecordExists(tx, s)
+			if err != nil {
+				return err
+			}
+			mark, notify := true, level == model.ChannelSubscribeLevelMarkAndNotify
+			if exists {
+				if err := tx.Model(&model.ChannelGroupSubscription{}).Where(s).Updates(map[string]bool{"mark": mark, "notify": notify}).Error; err != nil {
+					return err
+				}
+			} else {
+				s.Mark, s.Notify = mark, notify
+				if err := tx.Create(s).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		members, err := getUserGroupMemberSnapshot(tx, []uuid.UUID{groupID})
+		if err != nil {
+			return err
+		}
+		on, off, err = syncAutoChannelSubscriptions(tx, channelID, members[groupID])
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(on) > 0 || len(off) > 0 {
+		repo.hub.Publish(hub.Message{
+			Name: event.ChannelSubscribersChanged,
+			Fields: hub.Fields{
+				"channel_id": channelID,
+			},
+		})
+	}
+	return on, off, nil
+}
+
+// GetChannelGroupSubscriptions implements ChannelRepository interface.
+func (repo *GormRepository) GetChannelGroupSubscriptions(channelID uuid.UUID) ([]*model.ChannelGroupSubscription, error) {
+	result := make([]*model.ChannelGroupSubscription, 0)
+	if channelID == uuid.Nil {
+		return result, nil
+	}
+	return result, repo.db.
+		Where(&model.ChannelGroupSubscription{ChannelID: channelID}).
+		Order("created_at").
+		Find(&result).
+		Error
+}
+
+// SyncGroupChannelSubscriptions implements ChannelRepository interface.
+func (repo *GormRepository) SyncGroupChannelSubscriptions(userID, groupID uuid.UUID) error {
+	if userID == uuid.Nil || groupID == uuid.Nil {
+		return ErrNilID
+	}
+
+	changed := make([]uuid.UUID, 0)
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var channelIDs []uuid.UUID
+		if err := tx.
+			Model(&model.ChannelGroupSubscription{}).
+			Where(&model.ChannelGroupSubscription{GroupID: groupID}).
+			Pluck("channel_id", &channelIDs).
+			Error; err != nil {
+			return err
+		}
+
+		users := map[uuid.UUID]struct{}{userID: {}}
+		for _, channelID := range channelIDs {
+			on, off, err := syncAutoChannelSubscriptions(tx, channelID, users)
+			if err != nil {
+				return err
+			}
+			if len(on) > 0 || len(off) > 0 {
+				changed = append(changed, channelID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, channelID := range changed {
+		repo.hub.Publish(hub.Message{
+			Name: event.ChannelSubscribersChanged,
+			Fields: hub.Fields{
+				"channel_id": channelID,
+			},
+		})
+	}
+	return nil
+}
+
+// syncAutoChannelSubscriptions 指定したユーザーのチャンネルの購読を、グループによる購読設定に合わせて更新します
+//
+// ユーザー自身が設定した購読は変更しません。
+// 通知が有効になったユーザーと無効になったユーザーのIDを返します。
+func syncAutoChannelSubscriptions(tx *gorm.DB, channelID uuid.UUID, userIDs map[uuid.UUID]struct{}) (on []uuid.UUID, off []uuid.UUID, err error) {
+	on = make([]uuid.UUID, 0)
+	off = make([]uuid.UUID, 0)
+	if len(userIDs) == 0 {
+		return on, off, nil
+	}
+
+	var groupSubs []*model.ChannelGroupSubscription
+	if err := tx.Where(&model.ChannelGroupSubscription{ChannelID: channelID}).Find(&groupSubs).Error; err != nil {
+		return nil, nil, err
+	}
+	groupIDs := make([]uuid.UUID, len(groupSubs))
+	for i, gs := range groupSubs {
+		groupIDs[i] = gs.GroupID
+	}
+	members, err := getUserGroupMemberSnapshot(tx, groupIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(userIDs))
+	for id := range userIDs {
+		ids = append(ids, id)
+	}
+	var _current []*model.UserSubscribeChannel
+	if err := tx.Where("channel_id = ? AND user_id IN (?)", channelID, ids).Find(&_current).Error; err != nil {
+		return nil, nil, err
+	}
+	current := make(map[uuid.UUID]*model.UserSubscribeChannel, len(_current))
+	for _, s := range _current {
+		current[s.UserID] = s
+	}
+
+	for _, uid := range ids {
+		s, ok := current[uid]
+		if ok && !s.Auto {
+			continue // ユーザー自身による設定を優先
+		}
+
+		// 所属しているグループの購読設定のうち、最も高いレベルを採用
+		level := model.ChannelSubscribeLevelNone
+		for _, gs := range groupSubs {
+			if _, member := members[gs.GroupID][uid]; member && gs.GetLevel() > level {
+				level = gs.GetLevel()
+			}
+		}
+		cl := model.ChannelSubscribeLevelNone
+		if ok {
+			cl = s.GetLevel()
+		}
+		if cl == level {
+			continue
+		}
+
+		switch {
+		case level == model.ChannelSubscribeLevelNone:
+			if err := tx.Delete(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Error; err != nil {
+				return nil, nil, err
+			}
+		case ok:
+			if err := tx.Model(model.UserSubscribeChannel{}).Where(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Updates(map[string]bool{"mark": true, "notify": level == model.ChannelSubscribeLevelMarkAndNotify}).Error; err != nil {
+				return nil, nil, err
+			}
+		default:
+			if err := tx.Create(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID, Mark: true, Notify: level == model.ChannelSubscribeLevelMarkAndNotify, Auto: true}).Error; err != nil {
+				if gormutil.IsMySQLForeignKeyConstraintFailsError(err) {
+					continue // 存在しないユーザーは無視
+				}
+				return nil, nil, err
+			}
+		}
+
+		if level == model.ChannelSubscribeLevelMarkAndNotify {
+			on = append(on, uid)
+		} else if cl == model.ChannelSubscribeLevelMarkAndNotify {
+			off = append(off, uid)
+		}
+	}
+	return on, off, nil
+}
+
 // GetChannelSubscriptions implements ChannelRepository interface.
 func (repo *GormRepository) GetChannelSubscriptions(query ChannelSubscriptionQuery) ([]*model.UserSubscribeChannel, error) {
 	tx := repo.db
@@ -409,3 +897,293 @@ func (repo *GormRepository) recordChannelEvent(channelID uuid.UUID, eventType mo
 		repo.logger.Warn("Recording channel event failed", zap.Error(err), zap.Stringer("channelID", channelID), zap.Stringer("type", eventType), zap.Any("detail", detail), zap.Time("datetime", datetime))
 	}
 }
+
+// UpdateChannelReadState implements ChannelRepository interface.
+func (repo *GormRepository) UpdateChannelReadState(userID, channelID uuid.UUID, readAt time.Time) (*model.ChannelReadState, error) {
+	if userID == uuid.Nil || channelID == uuid.Nil {
+		return nil, ErrNilID
+	}
+
+	var state model.ChannelReadState
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var latest model.ChannelLatestMessage
+		if err := tx.First(&latest, &model.ChannelLatestMessage{ChannelID: channelID}).Error; err != nil {
+			return convertError(err)
+		}
+
+		state = model.ChannelReadState{
+			ChannelID: channelID,
+			UserID:    userID,
+			MessageID: latest.MessageID,
+			ReadAt:    readAt,
+		}
+		return tx.Exec("INSERT INTO channel_read_states (channel_id, user_id, message_id, read_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE message_id = VALUES(message_id), read_at = VALUES(read_at)",
+			state.ChannelID, state.UserID, state.MessageID, state.ReadAt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	repo.hub.Publish(hub.Message{
+		Name: event.ChannelReadStateUpdated,
+		Fields: hub.Fields{
+			"user_id":    state.UserID,
+			"channel_id": state.ChannelID,
+			"message_id": state.MessageID,
+			"read_at":    state.ReadAt,
+		},
+	})
+	return &state, nil
+}
+
+// GetChannelReadStates implements ChannelRepository interface.
+func (repo *GormRepository) GetChannelReadStates(channelID uuid.UUID) ([]*model.ChannelReadState, error) {
+	states := make([]*model.ChannelReadState, 0)
+	if channelID == uuid.Nil {
+		return states, nil
+	}
+	return states, repo.db.
+		Joins("INNER JOIN user_settings ON user_settings.user_id = channel_read_states.user_id AND user_settings.read_receipts = TRUE").
+		Where("channel_read_states.channel_id = ?", channelID).
+		Order("channel_read_states.read_at").
+		Find(&states).
+		Error
+}
+
+// MergeChannel implements ChannelRepository interface.
+func (repo *GormRepository) MergeChannel(srcID, dstID, updaterID uuid.UUID) error {
+	if srcID == uuid.Nil || dstID == uuid.Nil {
+		return ErrNilID
+	}
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range []uuid.UUID{srcID, dstID} {
+			if ok, err := gormutil.RecordExists(tx, &model.Channel{ID: id}); err != nil {
+				return err
+			} else if !ok {
+				return ErrNotFound
+			}
+		}
+
+		// メッセージ・ファイル (ピン留め・未読はメッセージに付随して移動する)
+		if err := tx.Unscoped().Model(&model.Message{}).Where("channel_id = ?", srcID).UpdateColumn("channel_id", dstID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.FileMeta{}).Where("channel_id = ?", srcID).UpdateColumn("channel_id", dstID).Error; err != nil {
+			return err
+		}
+		var usage model.ChannelFileUsage
+		if err := tx.First(&usage, &model.ChannelFileUsage{ChannelID: srcID}).Error; err == nil {
+			if err := tx.Exec("INSERT INTO channel_file_usages (channel_id, size, count) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE size = size + ?, count = count + ?",
+				dstID, usage.Size, usage.Count, usage.Size, usage.Count).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&model.ChannelFileUsage{}, &model.ChannelFileUsage{ChannelID: srcID}).Error; err != nil {
+				return err
+			}
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		// 購読設定・スター・BOT参加 (移動先に既に存在するものを優先)
+		moves := []string{
+			"INSERT IGNORE INTO users_subscribe_channels (user_id, channel_id, mark, notify, auto) SELECT user_id, ?, mark, notify, auto FROM users_subscribe_channels WHERE channel_id = ?",
+			"INSERT IGNORE INTO channel_group_subscriptions (channel_id, group_id, mark, notify, created_at) SELECT ?, group_id, mark, notify, created_at FROM channel_group_subscriptions WHERE channel_id = ?",
+			"INSERT IGNORE INTO stars (user_id, channel_id) SELECT user_id, ? FROM stars WHERE channel_id = ?",
+			"INSERT IGNORE INTO bot_join_channels (channel_id, bot_id) SELECT ?, bot_id FROM bot_join_channels WHERE channel_id = ?",
+		}
+		for _, q := range moves {
+			if err := tx.Exec(q, dstID, srcID).Error; err != nil {
+				return err
+			}
+		}
+		for _, m := range []interface{}{&model.UserSubscribeChannel{}, &model.ChannelGroupSubscription{}, &model.Star{}, &model.BotJoinChannel{}, &model.ChannelReadState{}, &model.ChannelLatestMessage{}} {
+			if err := tx.Where("channel_id = ?", srcID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+
+		// Webhookの投稿先・ホームチャンネル
+		if err := tx.Model(&model.WebhookBot{}).Where("channel_id = ?", srcID).UpdateColumn("channel_id", dstID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.UserProfile{}).Where("home_channel = ?", srcID).UpdateColumn("home_channel", dstID).Error; err != nil {
+			return err
+		}
+
+		// 最新メッセージ
+		var latest model.Message
+		if err := tx.Where("channel_id = ?", dstID).Order("created_at DESC").First(&latest).Error; err == nil {
+			if err := tx.Exec("INSERT INTO channel_latest_messages (channel_id, message_id, date_time) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE message_id = VALUES(message_id), date_time = VALUES(date_time)",
+				dstID, latest.ID, latest.CreatedAt).Error; err != nil {
+				return err
+			}
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		// リダイレクト (統合元へのリダイレクトも統合先に付け替える)
+		if err := tx.Delete(&model.ChannelRedirect{}, &model.ChannelRedirect{ChannelID: dstID}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.ChannelRedirect{}).Where("to_channel_id = ?", srcID).UpdateColumn("to_channel_id", dstID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("INSERT INTO channel_redirects (channel_id, to_channel_id, created_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE to_channel_id = VALUES(to_channel_id), created_at = VALUES(created_at)",
+			srcID, dstID, time.Now()).Error; err != nil {
+			return err
+		}
+
+		// 統合元はアーカイブ
+		return tx.Model(&model.Channel{ID: srcID}).Updates(map[string]interface{}{
+			"is_visible": false,
+			"updater_id": updaterID,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	repo.hub.Publish(hub.Message{
+		Name: event.ChannelMerged,
+		Fields: hub.Fields{
+			"channel_id":    srcID,
+			"to_channel_id": dstID,
+			"updater_id":    updaterID,
+		},
+	})
+	return nil
+}
+
+// GetChannelRedirect implements ChannelRepository interface.
+func (repo *GormRepository) GetChannelRedirect(channelID uuid.UUID) (*model.ChannelRedirect, error) {
+	if channelID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var r model.ChannelRedirect
+	if err := repo.db.First(&r, &model.ChannelRedirect{ChannelID: channelID}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &r, nil
+}
+
+// GetPrivateChannels implements ChannelRepository interface.
+func (repo *GormRepository) GetPrivateChannels(userID uuid.UUID) (channels []*model.Channel, err error) {
+	channels = make([]*model.Channel, 0)
+	if userID == uuid.Nil {
+		return channels, nil
+	}
+	return channels, repo.db.
+		Joins("INNER JOIN users_private_channels ON users_private_channels.channel_id = channels.id").
+		Where("users_private_channels.user_id = ? AND channels.parent_id = ?", userID, privateChannelRootUUID).
+		Find(&channels).
+		Error
+}
+
+// AddPrivateChannelMembers implements ChannelRepository interface.
+func (repo *GormRepository) AddPrivateChannelMembers(channelID uuid.UUID, userIDs set.UUID, updaterID uuid.UUID) ([]uuid.UUID, error) {
+	if channelID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	added := make([]uuid.UUID, 0)
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var ch model.Channel
+		if err := tx.First(&ch, &model.Channel{ID: channelID}).Error; err != nil {
+			return convertError(err)
+		}
+		if ch.IsPublic {
+			return ErrNotFound
+		}
+
+		var current []uuid.UUID
+		if err := tx.Model(&model.UsersPrivateChannel{}).Where(&model.UsersPrivateChannel{ChannelID: channelID}).Pluck("user_id", &current).Error; err != nil {
+			return err
+		}
+		members := set.UUIDSetFromArray(current)
+		for uid := range userIDs {
+			if members.Contains(uid) {
+				continue
+			}
+			if err := tx.Create(&model.UsersPrivateChannel{UserID: uid, ChannelID: channelID}).Error; err != nil {
+				return err
+			}
+			added = append(added, uid)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(added) > 0 {
+		repo.hub.Publish(hub.Message{
+			Name: event.ChannelMembersChanged,
+			Fields: hub.Fields{
+				"channel_id": channelID,
+				"added":      added,
+				"removed":    []uuid.UUID{},
+				"updater_id": updaterID,
+			},
+		})
+	}
+	return added, nil
+}
+
+// RemovePrivateChannelMembers implements ChannelRepository interface.
+func (repo *GormRepository) RemovePrivateChannelMembers(channelID uuid.UUID, userIDs set.UUID, updaterID uuid.UUID) ([]uuid.UUID, error) {
+	if channelID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	removed := make([]uuid.UUID, 0)
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var ch model.Channel
+		if err := tx.First(&ch, &model.Channel{ID: channelID}).Error; err != nil {
+			return convertError(err)
+		}
+		if ch.IsPublic {
+			return ErrNotFound
+		}
+
+		for uid := range userIDs {
+			result := tx.Delete(&model.UsersPrivateChannel{UserID: uid, ChannelID: channelID})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			if err := tx.Delete(&model.UserSubscribeChannel{UserID: uid, ChannelID: channelID}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&model.Star{UserID: uid, ChannelID: channelID}).Error; err != nil {
+				return err
+			}
+			removed = append(removed, uid)
+		}
+
+		// オーナーが居なくなった場合は残りのメンバーに引き継ぐ
+		if ch.IsPrivateChannel() && userIDs.Contains(ch.CreatorID) {
+			var rest []uuid.UUID
+			if err := tx.Model(&model.UsersPrivateChannel{}).Where(&model.UsersPrivateChannel{ChannelID: channelID}).Order("user_id").Limit(1).Pluck("user_id", &rest).Error; err != nil {
+				return err
+			}
+			if len(rest) > 0 {
+				return tx.Model(&model.Channel{ID: channelID}).Update("creator_id", rest[0]).Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(removed) > 0 {
+		repo.hub.Publish(hub.Message{
+			Name: event.ChannelMembersChanged,
+			Fields: hub.Fields{
+				"channel_id": channelID,
+				"added":      []uuid.UUID{},
+				"removed":    removed,
+				"updater_id": updaterID,
+			},
+		})
+	}
+	return removed, nil
+}
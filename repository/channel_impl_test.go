@@ -5,9 +5,13 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/set"
 	"testing"
+	"time"
 )
 
 func TestGormRepository_UpdateChannel(t *testing.T) {
@@ -127,6 +131,82 @@ func TestGormRepository_ChangeChannelSubscription(t *testing.T) {
 	})
 }
 
+func TestGormRepository_SetChannelGroupSubscription(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user := setupWithUser(t, common)
+
+	getLevel := func(t *testing.T, channelID, userID uuid.UUID) model.ChannelSubscribeLevel {
+		t.Helper()
+		subs, err := repo.GetChannelSubscriptions(ChannelSubscriptionQuery{}.SetChannel(channelID).SetUser(userID))
+		require.NoError(t, err)
+		if len(subs) == 0 {
+			return model.ChannelSubscribeLevelNone
+		}
+		return subs[0].GetLevel()
+	}
+
+	t.Run("Nil ID", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := repo.SetChannelGroupSubscription(uuid.Nil, uuid.Nil, model.ChannelSubscribeLevelMark)
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		t.Parallel()
+		ch := mustMakeChannel(t, repo, rand)
+
+		_, _, err := repo.SetChannelGroupSubscription(ch.ID, uuid.Must(uuid.NewV4()), model.ChannelSubscribeLevelMark)
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assert.New(t), require.New(t)
+		ch := mustMakeChannel(t, repo, rand)
+		user1 := mustMakeUser(t, repo, rand)
+		user2 := mustMakeUser(t, repo, rand)
+		g := mustMakeUserGroup(t, repo, rand, user.GetID())
+		mustAddUserToGroup(t, repo, user1.GetID(), g.ID)
+
+		on, off, err := repo.SetChannelGroupSubscription(ch.ID, g.ID, model.ChannelSubscribeLevelMarkAndNotify)
+		require.NoError(err)
+		assert.ElementsMatch([]uuid.UUID{user1.GetID()}, on)
+		assert.Empty(off)
+		assert.Equal(model.ChannelSubscribeLevelMarkAndNotify, getLevel(t, ch.ID, user1.GetID()))
+
+		subs, err := repo.GetChannelGroupSubscriptions(ch.ID)
+		require.NoError(err)
+		if assert.Len(subs, 1) {
+			assert.Equal(g.ID, subs[0].GroupID)
+			assert.Equal(model.ChannelSubscribeLevelMarkAndNotify, subs[0].GetLevel())
+		}
+
+		// メンバーの追加・削除に追従
+		mustAddUserToGroup(t, repo, user2.GetID(), g.ID)
+		require.NoError(repo.SyncGroupChannelSubscriptions(user2.GetID(), g.ID))
+		assert.Equal(model.ChannelSubscribeLevelMarkAndNotify, getLevel(t, ch.ID, user2.GetID()))
+		require.NoError(repo.RemoveUserFromGroup(user2.GetID(), g.ID))
+		require.NoError(repo.SyncGroupChannelSubscriptions(user2.GetID(), g.ID))
+		assert.Equal(model.ChannelSubscribeLevelNone, getLevel(t, ch.ID, user2.GetID()))
+
+		// ユーザー自身による設定が優先される
+		_, _, err = repo.ChangeChannelSubscription(ch.ID, ChangeChannelSubscriptionArgs{Subscription: map[uuid.UUID]model.ChannelSubscribeLevel{user1.GetID(): model.ChannelSubscribeLevelNone}})
+		require.NoError(err)
+		_, _, err = repo.SetChannelGroupSubscription(ch.ID, g.ID, model.ChannelSubscribeLevelMark)
+		require.NoError(err)
+		require.NoError(repo.SyncGroupChannelSubscriptions(user1.GetID(), g.ID))
+		assert.Equal(model.ChannelSubscribeLevelNone, getLevel(t, ch.ID, user1.GetID()))
+
+		// 設定の削除
+		_, _, err = repo.SetChannelGroupSubscription(ch.ID, g.ID, model.ChannelSubscribeLevelNone)
+		require.NoError(err)
+		subs, err = repo.GetChannelGroupSubscriptions(ch.ID)
+		require.NoError(err)
+		assert.Empty(subs)
+	})
+}
+
 func TestGormRepository_GetChannelStats(t *testing.T) {
 	t.Parallel()
 	repo, _, _, user, channel := setupWithUserAndChannel(t, common)
@@ -160,3 +240,402 @@ func TestGormRepository_GetChannelStats(t *testing.T) {
 		}
 	})
 }
+
+func TestGormRepository_UpdateChannelReadState(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.UpdateChannelReadState(uuid.Nil, uuid.Nil, time.Now())
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("no messages", func(t *testing.T) {
+		t.Parallel()
+
+		ch := mustMakeChannel(t, repo, rand)
+		_, err := repo.UpdateChannelReadState(user.GetID(), ch.ID, time.Now())
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assertAndRequire(t)
+
+		ch := mustMakeChannel(t, repo, rand)
+		mustMakeMessage(t, repo, user.GetID(), ch.ID)
+		state, err := repo.UpdateChannelReadState(user.GetID(), ch.ID, time.Now())
+		require.NoError(err)
+		m := mustMakeMessage(t, repo, user.GetID(), ch.ID)
+		state2, err := repo.UpdateChannelReadState(user.GetID(), ch.ID, time.Now())
+		require.NoError(err)
+		assert.NotEqual(state.MessageID, state2.MessageID)
+		assert.Equal(m.ID, state2.MessageID)
+	})
+}
+
+func TestGormRepository_GetChannelReadStates(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	ch := mustMakeChannel(t, repo, rand)
+	user1 := mustMakeUser(t, repo, rand)
+	user2 := mustMakeUser(t, repo, rand)
+	m := mustMakeMessage(t, repo, user1.GetID(), ch.ID)
+
+	for _, u := range []model.UserInfo{user1, user2} {
+		_, err := repo.UpdateChannelReadState(u.GetID(), ch.ID, time.Now())
+		require.NoError(err)
+	}
+	// user2は既読位置を公開していない
+	require.NoError(repo.UpdateUserSettings(user1.GetID(), UpdateUserSettingsArgs{ReadReceipts: optional.BoolFrom(true)}))
+
+	states, err := repo.GetChannelReadStates(ch.ID)
+	if assert.NoError(err) && assert.Len(states, 1) {
+		assert.Equal(user1.GetID(), states[0].UserID)
+		assert.Equal(m.ID, states[0].MessageID)
+	}
+}
+
+func TestGormRepository_GroupDirectMessageChannel(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	u1 := mustMakeUser(t, repo, rand)
+	u2 := mustMakeUser(t, repo, rand)
+	u3 := mustMakeUser(t, repo, rand)
+	members := set.UUIDSetFromArray([]uuid.UUID{u1.GetID(), u2.GetID(), u3.GetID()})
+
+	_, err := repo.GetGroupDirectMessageChannel(members)
+	assert.EqualError(t, err, ErrNotFound.Error())
+
+	ch, err := repo.CreateChannel(model.Channel{Name: "dm_" + random.AlphaNumeric(17), IsVisible: true}, members, true)
+	require.NoError(t, err)
+	assert.True(t, ch.IsDMChannel())
+
+	_, err = repo.CreateChannel(model.Channel{Name: "dm_" + random.AlphaNumeric(17), IsVisible: true}, members, true)
+	assert.EqualError(t, err, ErrAlreadyExists.Error())
+
+	if got, err := repo.GetGroupDirectMessageChannel(members); assert.NoError(t, err) {
+		assert.Equal(t, ch.ID, got.ID)
+	}
+	if mapping, err := repo.GetGroupDirectMessageChannelMapping(u2.GetID()); assert.NoError(t, err) {
+		assert.ElementsMatch(t, members.Array(), mapping[ch.ID])
+	}
+
+	converted, err := repo.ConvertGroupDirectMessageChannel(ch.ID, "group_"+random.AlphaNumeric(10), u1.GetID())
+	if assert.NoError(t, err) {
+		assert.True(t, converted.IsPrivateChannel())
+		assert.Equal(t, u1.GetID(), converted.CreatorID)
+	}
+	_, err = repo.GetGroupDirectMessageChannel(members)
+	assert.EqualError(t, err, ErrNotFound.Error())
+	_, err = repo.ConvertGroupDirectMessageChannel(ch.ID, "group_"+random.AlphaNumeric(10), u1.GetID())
+	assert.EqualError(t, err, ErrNotFound.Error())
+}
+
+func TestGormRepository_PrivateChannel(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	u1 := mustMakeUser(t, repo, rand)
+	u2 := mustMakeUser(t, repo, rand)
+	u3 := mustMakeUser(t, repo, rand)
+	parentID := uuid.Must(uuid.FromString(model.PrivateChannelRootID))
+	name := random.AlphaNumeric(20)
+
+	ch1, err := repo.CreateChannel(model.Channel{Name: name, ParentID: parentID, CreatorID: u1.GetID()}, set.UUIDSetFromArray([]uuid.UUID{u1.GetID(), u2.GetID()}), false)
+	require.NoError(t, err)
+	assert.True(t, ch1.IsPrivateChannel())
+	assert.Equal(t, ch1.ID, ch1.NameScope)
+
+	// 名前が同じでも別のプライベートチャンネルを作成できる
+	ch2, err := repo.CreateChannel(model.Channel{Name: name, ParentID: parentID, CreatorID: u3.GetID()}, set.UUIDSetFromArray([]uuid.UUID{u3.GetID()}), false)
+	require.NoError(t, err)
+	assert.NotEqual(t, ch1.ID, ch2.ID)
+
+	// 作成者が退出すると残りのメンバーに引き継がれる
+	removed, err := repo.RemovePrivateChannelMembers(ch1.ID, set.UUIDSetFromArray([]uuid.UUID{u1.GetID()}), u1.GetID())
+	if assert.NoError(t, err) {
+		assert.ElementsMatch(t, []uuid.UUID{u1.GetID()}, removed)
+	}
+	if got, err := repo.GetChannel(ch1.ID); assert.NoError(t, err) {
+		assert.Equal(t, u2.GetID(), got.CreatorID)
+	}
+
+	// 最後のメンバーが退出した場合は変わらない
+	_, err = repo.RemovePrivateChannelMembers(ch2.ID, set.UUIDSetFromArray([]uuid.UUID{u3.GetID()}), u3.GetID())
+	assert.NoError(t, err)
+	if got, err := repo.GetChannel(ch2.ID); assert.NoError(t, err) {
+		assert.Equal(t, u3.GetID(), got.CreatorID)
+	}
+}
+
+func TestGormRepository_MergeChannel(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.MergeChannel(uuid.Nil, uuid.Nil, uuid.Nil), ErrNilID.Error())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		ch := mustMakeChannel(t, repo, rand)
+		assert.EqualError(t, repo.MergeChannel(ch.ID, uuid.Must(uuid.NewV4()), uuid.Nil), ErrNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assertAndRequire(t)
+		db := getDB(repo)
+
+		user1 := mustMakeUser(t, repo, rand)
+		user2 := mustMakeUser(t, repo, rand)
+		src := mustMakeChannel(t, repo, rand)
+		dst := mustMakeChannel(t, repo, rand)
+		old := mustMakeChannel(t, repo, rand)
+
+		// メッセージ・ファイル
+		dstMessage := mustMakeMessage(t, repo, user1.GetID(), dst.ID)
+		srcMessage := mustMakeMessage(t, repo, user1.GetID(), src.ID)
+		f := mustMakeDummyFile(t, repo)
+		require.NoError(db.Model(&model.FileMeta{}).Where("id = ?", f.ID).UpdateColumn("channel_id", src.ID).Error)
+		require.NoError(db.Create(&model.ChannelFileUsage{ChannelID: src.ID, Size: 10, Count: 1}).Error)
+		require.NoError(db.Create(&model.ChannelFileUsage{ChannelID: dst.ID, Size: 20, Count: 2}).Error)
+
+		// 購読設定 (user2は移動先の設定を優先)
+		_, _, err := repo.ChangeChannelSubscription(src.ID, ChangeChannelSubscriptionArgs{Subscription: map[uuid.UUID]model.ChannelSubscribeLevel{
+			user1.GetID(): model.ChannelSubscribeLevelMarkAndNotify,
+			user2.GetID(): model.ChannelSubscribeLevelMarkAndNotify,
+		}})
+		require.NoError(err)
+		_, _, err = repo.ChangeChannelSubscription(dst.ID, ChangeChannelSubscriptionArgs{Subscription: map[uuid.UUID]model.ChannelSubscribeLevel{
+			user2.GetID(): model.ChannelSubscribeLevelMark,
+		}})
+		require.NoError(err)
+
+		// スター・BOT参加・Webhook・ホームチャンネル
+		require.NoError(repo.AddStar(user1.GetID(), src.ID))
+		bot, err := repo.CreateBot(random.AlphaNumeric(20), "bot", "", mustMakeDummyFile(t, repo).ID, user1.GetID(), "https://example.com")
+		require.NoError(err)
+		require.NoError(repo.AddBotToChannel(bot.ID, src.ID))
+		w := mustMakeWebhook(t, repo, rand, src.ID, user1.GetID(), "")
+		require.NoError(repo.UpdateUser(user1.GetID(), UpdateUserArgs{HomeChannel: optional.UUIDFrom(src.ID)}))
+
+		// oldは既にsrcに統合されている
+		require.NoError(repo.MergeChannel(old.ID, src.ID, user1.GetID()))
+
+		require.NoError(repo.MergeChannel(src.ID, dst.ID, user2.GetID()))
+
+		m, err := repo.GetMessageByID(srcMessage.ID)
+		if assert.NoError(err) {
+			assert.Equal(dst.ID, m.ChannelID)
+		}
+		meta, err := repo.GetFileMeta(f.ID)
+		if assert.NoError(err) {
+			assert.Equal(optional.UUIDFrom(dst.ID), meta.ChannelID)
+		}
+		usage, err := repo.GetChannelFileUsage(dst.ID)
+		if assert.NoError(err) {
+			assert.EqualValues(30, usage.Size)
+			assert.EqualValues(3, usage.Count)
+		}
+		assert.Equal(0, count(t, db.Model(&model.ChannelFileUsage{}).Where("channel_id = ?", src.ID)))
+
+		subs, err := repo.GetChannelSubscriptions(ChannelSubscriptionQuery{}.SetChannel(dst.ID))
+		if assert.NoError(err) {
+			levels := map[uuid.UUID]model.ChannelSubscribeLevel{}
+			for _, s := range subs {
+				levels[s.UserID] = s.GetLevel()
+			}
+			assert.Equal(map[uuid.UUID]model.ChannelSubscribeLevel{
+				user1.GetID(): model.ChannelSubscribeLevelMarkAndNotify,
+				user2.GetID(): model.ChannelSubscribeLevelMark,
+			}, levels)
+		}
+		assert.Equal(0, count(t, db.Model(&model.UserSubscribeChannel{}).Where("channel_id = ?", src.ID)))
+
+		stars, err := repo.GetStaredChannels(user1.GetID())
+		if assert.NoError(err) {
+			assert.ElementsMatch([]uuid.UUID{dst.ID}, stars)
+		}
+		assert.Equal(1, count(t, db.Model(&model.BotJoinChannel{}).Where("bot_id = ? AND channel_id = ?", bot.ID, dst.ID)))
+		assert.Equal(0, count(t, db.Model(&model.BotJoinChannel{}).Where("channel_id = ?", src.ID)))
+
+		w2, err := repo.GetWebhook(w.GetID())
+		if assert.NoError(err) {
+			assert.Equal(dst.ID, w2.GetChannelID())
+		}
+		u, err := repo.GetUser(user1.GetID(), true)
+		if assert.NoError(err) {
+			assert.Equal(optional.UUIDFrom(dst.ID), u.GetHomeChannel())
+		}
+
+		// 最新メッセージは統合先と統合元のうち新しいもの
+		var latest model.ChannelLatestMessage
+		if assert.NoError(db.First(&latest, &model.ChannelLatestMessage{ChannelID: dst.ID}).Error) {
+			assert.Equal(srcMessage.ID, latest.MessageID)
+			assert.NotEqual(dstMessage.ID, latest.MessageID)
+		}
+		assert.Equal(0, count(t, db.Model(&model.ChannelLatestMessage{}).Where("channel_id = ?", src.ID)))
+
+		// 統合元へのリダイレクトは統合先に付け替えられる
+		for _, id := range []uuid.UUID{src.ID, old.ID} {
+			r, err := repo.GetChannelRedirect(id)
+			if assert.NoError(err) {
+				assert.Equal(dst.ID, r.ToChannelID)
+			}
+		}
+		_, err = repo.GetChannelRedirect(dst.ID)
+		assert.EqualError(err, ErrNotFound.Error())
+
+		ch, err := repo.GetChannel(src.ID)
+		if assert.NoError(err) {
+			assert.False(ch.IsVisible)
+			assert.Equal(user2.GetID(), ch.UpdaterID)
+		}
+	})
+}
+
+func TestGormRepository_CreateChannelTree(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+	user := mustMakeUser(t, repo, rand)
+
+	t.Run("nil creator", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.CreateChannelTree(uuid.Nil, random.AlphaNumeric(20), model.ChannelTemplateNode{}, uuid.Nil)
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("parent not found", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.CreateChannelTree(uuid.Must(uuid.NewV4()), random.AlphaNumeric(20), model.ChannelTemplateNode{}, user.GetID())
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+
+	t.Run("duplicated name", func(t *testing.T) {
+		t.Parallel()
+
+		parent := mustMakeChannel(t, repo, rand)
+		name := random.AlphaNumeric(20)
+		_, err := repo.CreateChannel(model.Channel{Name: name, ParentID: parent.ID, IsPublic: true, IsVisible: true}, nil, false)
+		require.NoError(t, err)
+
+		_, err = repo.CreateChannelTree(parent.ID, name, model.ChannelTemplateNode{}, user.GetID())
+		assert.EqualError(t, err, ErrAlreadyExists.Error())
+	})
+
+	t.Run("duplicated child names", func(t *testing.T) {
+		t.Parallel()
+
+		name := random.AlphaNumeric(20)
+		_, err := repo.CreateChannelTree(uuid.Nil, name, model.ChannelTemplateNode{
+			Children: []*model.ChannelTemplateNode{{Name: "a"}, {Name: "a"}},
+		}, user.GetID())
+		assert.EqualError(t, err, ErrAlreadyExists.Error())
+		assert.Equal(t, 0, count(t, getDB(repo).Model(&model.Channel{}).Where("name = ?", name)))
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		t.Parallel()
+
+		name := random.AlphaNumeric(20)
+		_, err := repo.CreateChannelTree(uuid.Nil, name, model.ChannelTemplateNode{
+			Children: []*model.ChannelTemplateNode{{
+				Name:               "a",
+				GroupSubscriptions: []*model.ChannelTemplateGroupSubscription{{GroupID: uuid.Must(uuid.NewV4()), Level: model.ChannelSubscribeLevelMark}},
+			}},
+		}, user.GetID())
+		assert.IsType(t, &ArgumentError{}, err)
+		// ルートチャンネルも作成されない
+		assert.Equal(t, 0, count(t, getDB(repo).Model(&model.Channel{}).Where("name = ?", name)))
+	})
+
+	t.Run("bot not found", func(t *testing.T) {
+		t.Parallel()
+
+		name := random.AlphaNumeric(20)
+		_, err := repo.CreateChannelTree(uuid.Nil, name, model.ChannelTemplateNode{
+			Children: []*model.ChannelTemplateNode{{Name: "a", Bots: []uuid.UUID{uuid.Must(uuid.NewV4())}}},
+		}, user.GetID())
+		assert.IsType(t, &ArgumentError{}, err)
+		assert.Equal(t, 0, count(t, getDB(repo).Model(&model.Channel{}).Where("name = ?", name)))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assertAndRequire(t)
+
+		parent := mustMakeChannel(t, repo, rand)
+		member := mustMakeUser(t, repo, rand)
+		group := mustMakeUserGroup(t, repo, rand, user.GetID())
+		mustAddUserToGroup(t, repo, member.GetID(), group.ID)
+		bot, err := repo.CreateBot(random.AlphaNumeric(20), "bot", "", mustMakeDummyFile(t, repo).ID, user.GetID(), "https://example.com")
+		require.NoError(err)
+
+		sub := repo.(*GormRepository).hub.Subscribe(1000, event.ChannelCreated, event.ChannelSubscribersChanged, event.BotJoined)
+		defer repo.(*GormRepository).hub.Unsubscribe(sub)
+
+		channels, err := repo.CreateChannelTree(parent.ID, "team", model.ChannelTemplateNode{
+			Topic:              "topic",
+			GroupSubscriptions: []*model.ChannelTemplateGroupSubscription{{GroupID: group.ID, Level: model.ChannelSubscribeLevelMarkAndNotify}},
+			Bots:               []uuid.UUID{bot.ID},
+			Children: []*model.ChannelTemplateNode{
+				{Name: "a", Force: true, Children: []*model.ChannelTemplateNode{{Name: "b"}}},
+			},
+		}, user.GetID())
+		require.NoError(err)
+		require.Len(channels, 3)
+
+		root, a, b := channels[0], channels[1], channels[2]
+		assert.Equal("team", root.Name)
+		assert.Equal(parent.ID, root.ParentID)
+		assert.Equal("topic", root.Topic)
+		assert.Equal("a", a.Name)
+		assert.Equal(root.ID, a.ParentID)
+		assert.True(a.IsForced)
+		assert.Equal("b", b.Name)
+		assert.Equal(a.ID, b.ParentID)
+		for _, ch := range channels {
+			assert.True(ch.IsPublic)
+			assert.Equal(user.GetID(), ch.CreatorID)
+		}
+
+		subs, err := repo.GetChannelSubscriptions(ChannelSubscriptionQuery{}.SetUser(member.GetID()).SetChannel(root.ID))
+		if assert.NoError(err) && assert.Len(subs, 1) {
+			assert.Equal(model.ChannelSubscribeLevelMarkAndNotify, subs[0].GetLevel())
+		}
+		assert.Equal(1, count(t, getDB(repo).Model(&model.BotJoinChannel{}).Where("bot_id = ? AND channel_id = ?", bot.ID, root.ID)))
+
+		// 作成したチャンネルのイベントのみを集める
+		ids := map[uuid.UUID]bool{root.ID: true, a.ID: true, b.ID: true}
+		received := map[string][]uuid.UUID{}
+		timeout := time.After(time.Second)
+	L:
+		for {
+			select {
+			case ev := <-sub.Receiver:
+				cid := ev.Fields["channel_id"].(uuid.UUID)
+				if ids[cid] {
+					received[ev.Name] = append(received[ev.Name], cid)
+				}
+			case <-timeout:
+				break L
+			}
+		}
+		assert.Equal([]uuid.UUID{root.ID, a.ID, b.ID}, received[event.ChannelCreated])
+		assert.Equal([]uuid.UUID{root.ID}, received[event.ChannelSubscribersChanged])
+		assert.Equal([]uuid.UUID{root.ID}, received[event.BotJoined])
+	})
+}
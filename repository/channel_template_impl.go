@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+)
+
+// CreateChannelTemplate implements ChannelTemplateRepository interface.
+func (repo *GormRepository) CreateChannelTemplate(name, description string, definition model.ChannelTemplateNode, creatorID uuid.UUID) (*model.ChannelTemplate, error) {
+	if creatorID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	t := &model.ChannelTemplate{
+		ID:          uuid.Must(uuid.NewV4()),
+		Name:        name,
+		Description: description,
+		Definition:  definition,
+		CreatorID:   creatorID,
+	}
+	if err := repo.db.Create(t).Error; err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// UpdateChannelTemplate implements ChannelTemplateRepository interface.
+func (repo *GormRepository) UpdateChannelTemplate(id uuid.UUID, args UpdateChannelTemplateArgs) error {
+	if id == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var t model.ChannelTemplate
+		if err := tx.First(&t, &model.ChannelTemplate{ID: id}).Error; err != nil {
+			return convertError(err)
+		}
+
+		changes := map[string]interface{}{}
+		if args.Name.Valid {
+			changes["name"] = args.Name.String
+		}
+		if args.Description.Valid {
+			changes["description"] = args.Description.String
+		}
+		if args.Definition != nil {
+			changes["definition"] = *args.Definition
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := tx.Model(&t).Updates(changes).Error; err != nil {
+			if gormutil.IsMySQLDuplicatedRecordErr(err) {
+				return ErrAlreadyExists
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// GetChannelTemplate implements ChannelTemplateRepository interface.
+func (repo *GormRepository) GetChannelTemplate(id uuid.UUID) (*model.ChannelTemplate, error) {
+	if id == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var t model.ChannelTemplate
+	if err := repo.db.Take(&t, &model.ChannelTemplate{ID: id}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &t, nil
+}
+
+// GetChannelTemplates implements ChannelTemplateRepository interface.
+func (repo *GormRepository) GetChannelTemplates() ([]*model.ChannelTemplate, error) {
+	templates := make([]*model.ChannelTemplate, 0)
+	return templates, repo.db.Order("name").Find(&templates).Error
+}
+
+// DeleteChannelTemplate implements ChannelTemplateRepository interface.
+func (repo *GormRepository) DeleteChannelTemplate(id uuid.UUID) error {
+	if id == uuid.Nil {
+		return ErrNilID
+	}
+	result := repo.db.Delete(&model.ChannelTemplate{ID: id})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/optional"
+	"time"
 )
 
 // FilesQuery GetFiles用クエリ
@@ -20,11 +21,146 @@ type FilesQuery struct {
 	Type       model.FileType
 }
 
+// UpdateFileMediaArgs ファイルメディア情報更新引数
+type UpdateFileMediaArgs struct {
+	Width    int
+	Height   int
+	Duration time.Duration
+	Codec    string
+	// ThumbnailMime サムネイル画像のMIMEタイプ (Validでない場合はサムネイル情報を変更しません)
+	ThumbnailMime   optional.String
+	ThumbnailWidth  int
+	ThumbnailHeight int
+}
+
 // FileRepository ファイルリポジトリ
 type FileRepository interface {
 	GetFileMetas(q FilesQuery) (result []*model.FileMeta, more bool, err error)
 	GetFileMeta(fileID uuid.UUID) (*model.FileMeta, error)
+	// SaveFileMeta ファイルメタを保存します
+	//
+	// meta.ObjectIDが指定されている場合、そのファイル実体の参照数を1増やします。
+	// 成功した場合、nilを返します。
+	// meta.ObjectIDのファイル実体が存在しない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
 	SaveFileMeta(meta *model.FileMeta, acl []*model.FileACLEntry) error
+	// DeleteFileMeta ファイルメタを削除します
+	//
+	// ファイル実体の参照数を1減らし、参照数が0になった場合はファイル実体のレコードも削除します。
+	// 成功した場合、nilを返します。
+	// DBによるエラーを返すことがあります。
 	DeleteFileMeta(fileID uuid.UUID) error
+	// UpdateFileMediaInfo 動画・音声ファイルのメディア情報を更新します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しないファイルを指定した場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateFileMediaInfo(fileID uuid.UUID, args UpdateFileMediaArgs) error
 	IsFileAccessible(fileID, userID uuid.UUID) (bool, error)
+	// GetUserFileUsage 指定したユーザーのファイル使用量を取得します
+	//
+	// 成功した場合、使用量とnilを返します。ファイルが存在しない場合は0の使用量を返します。
+	// DBによるエラーを返すことがあります。
+	GetUserFileUsage(userID uuid.UUID) (*model.UserFileUsage, error)
+	// GetChannelFileUsage 指定したチャンネルのファイル使用量を取得します
+	//
+	// 成功した場合、使用量とnilを返します。ファイルが存在しない場合は0の使用量を返します。
+	// DBによるエラーを返すことがあります。
+	GetChannelFileUsage(channelID uuid.UUID) (*model.ChannelFileUsage, error)
+	// GetTotalFileUsage インスタンス全体のファイル使用量を取得します
+	//
+	// 成功した場合、合計サイズ(byte)とファイル数とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetTotalFileUsage() (size int64, count int64, err error)
+	// GetUserFileUsageRanking ファイル使用量の多いユーザーを取得します
+	//
+	// 成功した場合、使用量の降順に並べた配列とnilを返します。
+	// limitが0以下の場合は全件を返します。
+	// DBによるエラーを返すことがあります。
+	GetUserFileUsageRanking(limit int) ([]*model.UserFileUsage, error)
+	// GetChannelFileUsageRanking ファイル使用量の多いチャンネルを取得します
+	//
+	// 成功した場合、使用量の降順に並べた配列とnilを返します。
+	// limitが0以下の場合は全件を返します。
+	// DBによるエラーを返すことがあります。
+	GetChannelFileUsageRanking(limit int) ([]*model.ChannelFileUsage, error)
+	// RecalculateFileUsages ファイル使用量の集計値を全て再計算します
+	//
+	// 成功した場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	RecalculateFileUsages() error
+	// GetFileObject 指定したファイル実体を取得します
+	//
+	// 成功した場合、ファイル実体とnilを返します。
+	// 存在しない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetFileObject(objectID uuid.UUID) (*model.FileObject, error)
+	// GetFileObjectByHash 指定したタイプ・ハッシュのファイル実体を取得します
+	//
+	// 成功した場合、ファイル実体とnilを返します。
+	// 存在しない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetFileObjectByHash(fileType model.FileType, hash string) (*model.FileObject, error)
+	// CreateFileObject 参照数0のファイル実体を作成します
+	//
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 同一タイプ・ハッシュのファイル実体が既に存在する場合、ErrAlreadyExistsを返します。
+	// DBによるエラーを返すことがあります。
+	CreateFileObject(object *model.FileObject) error
+	// DeleteFileObject 参照されていないファイル実体を削除します
+	//
+	// 成功した、或いは既に存在しない場合、nilを返します。
+	// 参照されているファイル実体は削除されません。
+	// DBによるエラーを返すことがあります。
+	DeleteFileObject(objectID uuid.UUID) error
+	// GetUnhashedFileObjects ハッシュが計算されていないファイル実体を全て取得します
+	//
+	// 成功した場合、ファイル実体の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetUnhashedFileObjects() ([]*model.FileObject, error)
+	// SetFileObjectHash ファイル実体のハッシュを設定します
+	//
+	// 同一タイプ・ハッシュのファイル実体が既に存在する場合、指定したファイル実体を参照しているファイルメタを
+	// 既存のファイル実体に付け替えた上で、指定したファイル実体のレコードを削除します。
+	// 成功した場合、ファイルの内容を保持しているファイル実体とnilを返します。
+	// 存在しないファイル実体を指定した場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	SetFileObjectHash(objectID uuid.UUID, hash string) (*model.FileObject, error)
+	// CreateFileUpload ファイルアップロードを作成します
+	//
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	CreateFileUpload(upload *model.FileUpload) error
+	// GetFileUpload 指定したファイルアップロードを取得します
+	//
+	// 成功した場合、ファイルアップロードとnilを返します。
+	// 存在しない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetFileUpload(uploadID uuid.UUID) (*model.FileUpload, error)
+	// UpdateFileUploadOffset ファイルアップロードの受信済みバイト数と有効期限を更新します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しないファイルアップロードを指定した場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateFileUploadOffset(uploadID uuid.UUID, offset int64, expiresAt time.Time) error
+	// DeleteFileUpload ファイルアップロードを削除します
+	//
+	// 成功した、或いは既に存在しない場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteFileUpload(uploadID uuid.UUID) error
+	// GetExpiredFileUploads 有効期限がnowより前のファイルアップロードを全て取得します
+	//
+	// 成功した場合、ファイルアップロードの配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetExpiredFileUploads(now time.Time) ([]*model.FileUpload, error)
+	// GetUserFileUploadsSize 指定したユーザーの、有効期限がnow以降のファイルアップロードのファイルサイズの合計を取得します
+	//
+	// 成功した場合、合計サイズとnilを返します。ファイルアップロードが存在しない場合は0を返します。
+	// DBによるエラーを返すことがあります。
+	GetUserFileUploadsSize(userID uuid.UUID, now time.Time) (int64, error)
 }
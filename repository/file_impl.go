@@ -4,6 +4,9 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
 )
 
 // GetFileMetas implements FileRepository interface.
@@ -68,6 +71,15 @@ func (repo *GormRepository) SaveFileMeta(meta *model.FileMeta, acl []*model.File
 		return ErrNilID
 	}
 	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if meta.ObjectID != uuid.Nil {
+			result := tx.Model(&model.FileObject{ID: meta.ObjectID}).UpdateColumn("ref_count", gorm.Expr("ref_count + 1"))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrNotFound
+			}
+		}
 		if err := tx.Create(meta).Error; err != nil {
 			return err
 		}
@@ -77,7 +89,7 @@ func (repo *GormRepository) SaveFileMeta(meta *model.FileMeta, acl []*model.File
 				return err
 			}
 		}
-		return nil
+		return updateFileUsage(tx, meta, 1)
 	})
 }
 
@@ -98,7 +110,58 @@ func (repo *GormRepository) DeleteFileMeta(fileID uuid.UUID) error {
 	if fileID == uuid.Nil {
 		return ErrNilID
 	}
-	return repo.db.Delete(&model.FileMeta{ID: fileID}).Error
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var meta model.FileMeta
+		if err := tx.First(&meta, &model.FileMeta{ID: fileID}).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil
+			}
+			return err
+		}
+		result := tx.Delete(&model.FileMeta{ID: fileID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if meta.ObjectID != uuid.Nil {
+			if err := tx.Model(&model.FileObject{ID: meta.ObjectID}).UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id = ? AND ref_count <= 0", meta.ObjectID).Delete(&model.FileObject{}).Error; err != nil {
+				return err
+			}
+		}
+		return updateFileUsage(tx, &meta, -1)
+	})
+}
+
+// UpdateFileMediaInfo implements FileRepository interface.
+func (repo *GormRepository) UpdateFileMediaInfo(fileID uuid.UUID, args UpdateFileMediaArgs) error {
+	if fileID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var meta model.FileMeta
+		if err := tx.First(&meta, &model.FileMeta{ID: fileID}).Error; err != nil {
+			return convertError(err)
+		}
+
+		changes := map[string]interface{}{
+			"width":       args.Width,
+			"height":      args.Height,
+			"duration_ms": args.Duration.Milliseconds(),
+			"codec":       args.Codec,
+		}
+		if args.ThumbnailMime.Valid {
+			changes["has_thumbnail"] = true
+			changes["thumbnail_mime"] = args.ThumbnailMime
+			changes["thumbnail_width"] = args.ThumbnailWidth
+			changes["thumbnail_height"] = args.ThumbnailHeight
+		}
+		return tx.Model(&meta).Updates(changes).Error
+	})
 }
 
 // IsFileAccessible implements FileRepository interface.
@@ -118,3 +181,268 @@ func (repo *GormRepository) IsFileAccessible(fileID, userID uuid.UUID) (bool, er
 	}
 	return result.Allow > 0 && result.Deny == 0, nil
 }
+
+// GetUserFileUsage implements FileRepository interface.
+func (repo *GormRepository) GetUserFileUsage(userID uuid.UUID) (*model.UserFileUsage, error) {
+	usage := &model.UserFileUsage{UserID: userID}
+	if userID == uuid.Nil {
+		return usage, nil
+	}
+	if err := repo.db.Where(&model.UserFileUsage{UserID: userID}).Take(usage).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// GetChannelFileUsage implements FileRepository interface.
+func (repo *GormRepository) GetChannelFileUsage(channelID uuid.UUID) (*model.ChannelFileUsage, error) {
+	usage := &model.ChannelFileUsage{ChannelID: channelID}
+	if channelID == uuid.Nil {
+		return usage, nil
+	}
+	if err := repo.db.Where(&model.ChannelFileUsage{ChannelID: channelID}).Take(usage).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// GetTotalFileUsage implements FileRepository interface.
+func (repo *GormRepository) GetTotalFileUsage() (size int64, count int64, err error) {
+	var total struct {
+		Size  int64
+		Count int64
+	}
+	err = repo.db.
+		Model(&model.FileMeta{}).
+		Select("COALESCE(SUM(size), 0) AS size, COUNT(*) AS count").
+		Scan(&total).
+		Error
+	return total.Size, total.Count, err
+}
+
+// GetUserFileUsageRanking implements FileRepository interface.
+func (repo *GormRepository) GetUserFileUsageRanking(limit int) ([]*model.UserFileUsage, error) {
+	usages := make([]*model.UserFileUsage, 0)
+	err := repo.db.
+		Where("size > 0").
+		Order("size DESC, user_id").
+		Scopes(gormutil.LimitAndOffset(limit, 0)).
+		Find(&usages).
+		Error
+	return usages, err
+}
+
+// GetChannelFileUsageRanking implements FileRepository interface.
+func (repo *GormRepository) GetChannelFileUsageRanking(limit int) ([]*model.ChannelFileUsage, error) {
+	usages := make([]*model.ChannelFileUsage, 0)
+	err := repo.db.
+		Where("size > 0").
+		Order("size DESC, channel_id").
+		Scopes(gormutil.LimitAndOffset(limit, 0)).
+		Find(&usages).
+		Error
+	return usages, err
+}
+
+// RecalculateFileUsages implements FileRepository interface.
+func (repo *GormRepository) RecalculateFileUsages() error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.UserFileUsage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.ChannelFileUsage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("INSERT INTO user_file_usages (user_id, size, count) " +
+			"SELECT creator_id, SUM(size), COUNT(*) FROM files " +
+			"WHERE deleted_at IS NULL AND creator_id IS NOT NULL GROUP BY creator_id").Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO channel_file_usages (channel_id, size, count) " +
+			"SELECT channel_id, SUM(size), COUNT(*) FROM files " +
+			"WHERE deleted_at IS NULL AND channel_id IS NOT NULL GROUP BY channel_id").Error
+	})
+}
+
+// GetFileObject implements FileRepository interface.
+func (repo *GormRepository) GetFileObject(objectID uuid.UUID) (*model.FileObject, error) {
+	if objectID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var obj model.FileObject
+	if err := repo.db.Where(&model.FileObject{ID: objectID}).Take(&obj).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &obj, nil
+}
+
+// GetFileObjectByHash implements FileRepository interface.
+func (repo *GormRepository) GetFileObjectByHash(fileType model.FileType, hash string) (*model.FileObject, error) {
+	if len(hash) == 0 {
+		return nil, ErrNotFound
+	}
+	var obj model.FileObject
+	if err := repo.db.Where("hash = ? AND type = ?", hash, fileType).Take(&obj).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &obj, nil
+}
+
+// CreateFileObject implements FileRepository interface.
+func (repo *GormRepository) CreateFileObject(object *model.FileObject) error {
+	if object == nil || object.ID == uuid.Nil {
+		return ErrNilID
+	}
+	object.RefCount = 0
+	if err := repo.db.Create(object).Error; err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteFileObject implements FileRepository interface.
+func (repo *GormRepository) DeleteFileObject(objectID uuid.UUID) error {
+	if objectID == uuid.Nil {
+		return nil
+	}
+	return repo.db.Where("id = ? AND ref_count <= 0", objectID).Delete(&model.FileObject{}).Error
+}
+
+// GetUnhashedFileObjects implements FileRepository interface.
+func (repo *GormRepository) GetUnhashedFileObjects() ([]*model.FileObject, error) {
+	objects := make([]*model.FileObject, 0)
+	err := repo.db.Where("hash IS NULL").Order("created_at").Find(&objects).Error
+	return objects, err
+}
+
+// SetFileObjectHash implements FileRepository interface.
+func (repo *GormRepository) SetFileObjectHash(objectID uuid.UUID, hash string) (*model.FileObject, error) {
+	if objectID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var result model.FileObject
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var obj model.FileObject
+		if err := tx.Where(&model.FileObject{ID: objectID}).Take(&obj).Error; err != nil {
+			return convertError(err)
+		}
+
+		var dup model.FileObject
+		if err := tx.Where("hash = ? AND type = ? AND id <> ?", hash, obj.Type, obj.ID).Take(&dup).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			// 重複無し
+			obj.Hash = optional.StringFrom(hash)
+			result = obj
+			return tx.Model(&model.FileObject{ID: obj.ID}).Update("hash", hash).Error
+		}
+
+		// 既存のファイル実体に統合
+		if err := tx.Model(&model.FileMeta{}).Unscoped().Where("object_id = ?", obj.ID).Update("object_id", dup.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.FileObject{ID: dup.ID}).UpdateColumn("ref_count", gorm.Expr("ref_count + ?", obj.RefCount)).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.FileObject{ID: obj.ID}).Error; err != nil {
+			return err
+		}
+		dup.RefCount += obj.RefCount
+		result = dup
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// updateFileUsage ファイルのアップロード者・チャンネルの使用量を更新します
+func updateFileUsage(tx *gorm.DB, meta *model.FileMeta, delta int64) error {
+	size := meta.Size * delta
+	if meta.CreatorID.Valid && meta.CreatorID.UUID != uuid.Nil {
+		if err := tx.Exec("INSERT INTO user_file_usages (user_id, size, count) VALUES (?, GREATEST(?, 0), GREATEST(?, 0)) ON DUPLICATE KEY UPDATE size = GREATEST(size + ?, 0), count = GREATEST(count + ?, 0)",
+			meta.CreatorID.UUID, size, delta, size, delta).Error; err != nil {
+			return err
+		}
+	}
+	if meta.ChannelID.Valid && meta.ChannelID.UUID != uuid.Nil {
+		if err := tx.Exec("INSERT INTO channel_file_usages (channel_id, size, count) VALUES (?, GREATEST(?, 0), GREATEST(?, 0)) ON DUPLICATE KEY UPDATE size = GREATEST(size + ?, 0), count = GREATEST(count + ?, 0)",
+			meta.ChannelID.UUID, size, delta, size, delta).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateFileUpload implements FileRepository interface.
+func (repo *GormRepository) CreateFileUpload(upload *model.FileUpload) error {
+	if upload.ID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Create(upload).Error
+}
+
+// GetFileUpload implements FileRepository interface.
+func (repo *GormRepository) GetFileUpload(uploadID uuid.UUID) (*model.FileUpload, error) {
+	if uploadID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var upload model.FileUpload
+	if err := repo.db.First(&upload, &model.FileUpload{ID: uploadID}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &upload, nil
+}
+
+// UpdateFileUploadOffset implements FileRepository interface.
+func (repo *GormRepository) UpdateFileUploadOffset(uploadID uuid.UUID, offset int64, expiresAt time.Time) error {
+	if uploadID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var upload model.FileUpload
+		if err := tx.First(&upload, &model.FileUpload{ID: uploadID}).Error; err != nil {
+			return convertError(err)
+		}
+		return tx.Model(&upload).Updates(map[string]interface{}{
+			"offset":     offset,
+			"expires_at": expiresAt,
+		}).Error
+	})
+}
+
+// DeleteFileUpload implements FileRepository interface.
+func (repo *GormRepository) DeleteFileUpload(uploadID uuid.UUID) error {
+	if uploadID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Delete(&model.FileUpload{ID: uploadID}).Error
+}
+
+// GetExpiredFileUploads implements FileRepository interface.
+func (repo *GormRepository) GetExpiredFileUploads(now time.Time) ([]*model.FileUpload, error) {
+	uploads := make([]*model.FileUpload, 0)
+	return uploads, repo.db.Where("expires_at < ?", now).Find(&uploads).Error
+}
+
+// GetUserFileUploadsSize implements FileRepository interface.
+func (repo *GormRepository) GetUserFileUploadsSize(userID uuid.UUID, now time.Time) (int64, error) {
+	if userID == uuid.Nil {
+		return 0, nil
+	}
+	var total struct {
+		Size int64
+	}
+	err := repo.db.
+		Model(&model.FileUpload{}).
+		Select("COALESCE(SUM(size), 0) AS size").
+		Where("creator_id = ? AND expires_at >= ?", userID, now).
+		Scan(&total).
+		Error
+	return total.Size, err
+}
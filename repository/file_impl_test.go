@@ -6,7 +6,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/random"
 	"testing"
+	"time"
 )
 
 func TestGormRepository_SaveFileMeta(t *testing.T) {
@@ -99,6 +101,53 @@ func TestGormRepository_DeleteFileMeta(t *testing.T) {
 	})
 }
 
+func TestGormRepository_UpdateFileMediaInfo(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		err := repo.UpdateFileMediaInfo(uuid.Nil, UpdateFileMediaArgs{})
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		err := repo.UpdateFileMediaInfo(uuid.NewV3(uuid.Nil, "not exists"), UpdateFileMediaArgs{})
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assertAndRequire(t)
+		f := mustMakeDummyFile(t, repo)
+
+		err := repo.UpdateFileMediaInfo(f.ID, UpdateFileMediaArgs{
+			Width:           1920,
+			Height:          1080,
+			Duration:        1500 * time.Millisecond,
+			Codec:           "h264",
+			ThumbnailMime:   optional.StringFrom("image/png"),
+			ThumbnailWidth:  360,
+			ThumbnailHeight: 203,
+		})
+		require.NoError(err)
+
+		meta, err := repo.GetFileMeta(f.ID)
+		require.NoError(err)
+		assert.Equal(1920, meta.Width)
+		assert.Equal(1080, meta.Height)
+		assert.EqualValues(1500, meta.DurationMS)
+		assert.Equal("h264", meta.Codec)
+		assert.True(meta.HasThumbnail)
+		assert.Equal("image/png", meta.ThumbnailMime.String)
+		assert.Equal(360, meta.ThumbnailWidth)
+		assert.Equal(203, meta.ThumbnailHeight)
+	})
+}
+
 func TestGormRepository_IsFileAccessible(t *testing.T) {
 	t.Parallel()
 	repo, _, _, user := setupWithUser(t, common)
@@ -288,3 +337,224 @@ func TestGormRepository_IsFileAccessible(t *testing.T) {
 		})
 	})
 }
+
+func TestGormRepository_FileUsage(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, ex3)
+
+	user := mustMakeUser(t, repo, rand)
+	ch := mustMakeChannel(t, repo, rand)
+
+	saveFile := func(size int64) *model.FileMeta {
+		meta := &model.FileMeta{
+			ID:        uuid.Must(uuid.NewV4()),
+			Name:      "dummy",
+			Mime:      "application/octet-stream",
+			Size:      size,
+			CreatorID: optional.UUIDFrom(user.GetID()),
+			ChannelID: optional.UUIDFrom(ch.ID),
+			Hash:      "d41d8cd98f00b204e9800998ecf8427e",
+		}
+		require.NoError(repo.SaveFileMeta(meta, nil))
+		return meta
+	}
+
+	f1 := saveFile(100)
+	saveFile(50)
+
+	u, err := repo.GetUserFileUsage(user.GetID())
+	require.NoError(err)
+	assert.EqualValues(150, u.Size)
+	assert.EqualValues(2, u.Count)
+
+	c, err := repo.GetChannelFileUsage(ch.ID)
+	require.NoError(err)
+	assert.EqualValues(150, c.Size)
+	assert.EqualValues(2, c.Count)
+
+	require.NoError(repo.DeleteFileMeta(f1.ID))
+	require.NoError(repo.DeleteFileMeta(f1.ID)) // 二重に減算されない
+
+	u, err = repo.GetUserFileUsage(user.GetID())
+	require.NoError(err)
+	assert.EqualValues(50, u.Size)
+	assert.EqualValues(1, u.Count)
+
+	c, err = repo.GetChannelFileUsage(ch.ID)
+	require.NoError(err)
+	assert.EqualValues(50, c.Size)
+	assert.EqualValues(1, c.Count)
+
+	size, count, err := repo.GetTotalFileUsage()
+	require.NoError(err)
+	assert.EqualValues(50, size)
+	assert.EqualValues(1, count)
+
+	users, err := repo.GetUserFileUsageRanking(10)
+	require.NoError(err)
+	if assert.Len(users, 1) {
+		assert.Equal(user.GetID(), users[0].UserID)
+	}
+
+	channels, err := repo.GetChannelFileUsageRanking(10)
+	require.NoError(err)
+	if assert.Len(channels, 1) {
+		assert.Equal(ch.ID, channels[0].ChannelID)
+	}
+
+	require.NoError(repo.RecalculateFileUsages())
+	u, err = repo.GetUserFileUsage(user.GetID())
+	require.NoError(err)
+	assert.EqualValues(50, u.Size)
+	assert.EqualValues(1, u.Count)
+
+	u, err = repo.GetUserFileUsage(uuid.Must(uuid.NewV4()))
+	require.NoError(err)
+	assert.EqualValues(0, u.Size)
+}
+
+func TestGormRepository_FileObject(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	hash := random.AlphaNumeric(64)
+	obj := &model.FileObject{
+		ID:   uuid.Must(uuid.NewV4()),
+		Hash: optional.StringFrom(hash),
+		Type: model.FileTypeUserFile,
+		Size: 10,
+	}
+	require.NoError(repo.CreateFileObject(obj))
+	assert.Equal(ErrAlreadyExists, repo.CreateFileObject(&model.FileObject{ID: uuid.Must(uuid.NewV4()), Hash: optional.StringFrom(hash), Type: model.FileTypeUserFile}))
+	assert.NoError(repo.CreateFileObject(&model.FileObject{ID: uuid.Must(uuid.NewV4()), Hash: optional.StringFrom(hash), Type: model.FileTypeStamp}))
+
+	found, err := repo.GetFileObjectByHash(model.FileTypeUserFile, hash)
+	require.NoError(err)
+	assert.Equal(obj.ID, found.ID)
+
+	saveFile := func(objectID uuid.UUID) (*model.FileMeta, error) {
+		meta := &model.FileMeta{
+			ID:       uuid.Must(uuid.NewV4()),
+			Name:     "dummy",
+			Mime:     "application/octet-stream",
+			Size:     10,
+			Hash:     "d41d8cd98f00b204e9800998ecf8427e",
+			ObjectID: objectID,
+		}
+		return meta, repo.SaveFileMeta(meta, nil)
+	}
+
+	_, err = saveFile(uuid.Must(uuid.NewV4()))
+	assert.Equal(ErrNotFound, err)
+
+	f1, err := saveFile(obj.ID)
+	require.NoError(err)
+	f2, err := saveFile(obj.ID)
+	require.NoError(err)
+
+	o, err := repo.GetFileObject(obj.ID)
+	require.NoError(err)
+	assert.Equal(2, o.RefCount)
+
+	// 参照されている実体は削除されない
+	require.NoError(repo.DeleteFileObject(obj.ID))
+	_, err = repo.GetFileObject(obj.ID)
+	assert.NoError(err)
+
+	require.NoError(repo.DeleteFileMeta(f1.ID))
+	o, err = repo.GetFileObject(obj.ID)
+	require.NoError(err)
+	assert.Equal(1, o.RefCount)
+
+	require.NoError(repo.DeleteFileMeta(f2.ID))
+	_, err = repo.GetFileObject(obj.ID)
+	assert.Equal(ErrNotFound, err)
+}
+
+func TestGormRepository_SetFileObjectHash(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	hash := random.AlphaNumeric(64)
+	o1 := &model.FileObject{ID: uuid.Must(uuid.NewV4()), Type: model.FileTypeUserFile, Size: 10}
+	o2 := &model.FileObject{ID: uuid.Must(uuid.NewV4()), Type: model.FileTypeUserFile, Size: 10}
+	require.NoError(repo.CreateFileObject(o1))
+	require.NoError(repo.CreateFileObject(o2))
+
+	f := &model.FileMeta{
+		ID:       uuid.Must(uuid.NewV4()),
+		Name:     "dummy",
+		Mime:     "application/octet-stream",
+		Size:     10,
+		Hash:     "d41d8cd98f00b204e9800998ecf8427e",
+		ObjectID: o2.ID,
+	}
+	require.NoError(repo.SaveFileMeta(f, nil))
+
+	_, err := repo.SetFileObjectHash(uuid.Must(uuid.NewV4()), hash)
+	assert.Equal(ErrNotFound, err)
+
+	result, err := repo.SetFileObjectHash(o1.ID, hash)
+	require.NoError(err)
+	assert.Equal(o1.ID, result.ID)
+
+	// o2はo1に統合される
+	result, err = repo.SetFileObjectHash(o2.ID, hash)
+	require.NoError(err)
+	assert.Equal(o1.ID, result.ID)
+	assert.Equal(1, result.RefCount)
+
+	_, err = repo.GetFileObject(o2.ID)
+	assert.Equal(ErrNotFound, err)
+	meta, err := repo.GetFileMeta(f.ID)
+	require.NoError(err)
+	assert.Equal(o1.ID, meta.ObjectID)
+}
+
+func TestGormRepository_FileUpload(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user, channel := setupWithUserAndChannel(t, common)
+
+	assert.EqualError(repo.CreateFileUpload(&model.FileUpload{}), ErrNilID.Error())
+	_, err := repo.GetFileUpload(uuid.Nil)
+	assert.EqualError(err, ErrNotFound.Error())
+
+	now := time.Now()
+	upload := &model.FileUpload{
+		ID:        uuid.Must(uuid.NewV4()),
+		Name:      "test.txt",
+		Mime:      "text/plain",
+		Size:      10,
+		CreatorID: user.GetID(),
+		ChannelID: channel.ID,
+		ExpiresAt: now.Add(-time.Minute),
+	}
+	require.NoError(repo.CreateFileUpload(upload))
+
+	expired, err := repo.GetExpiredFileUploads(now)
+	require.NoError(err)
+	ids := make([]uuid.UUID, 0, len(expired))
+	for _, u := range expired {
+		ids = append(ids, u.ID)
+	}
+	assert.Contains(ids, upload.ID)
+
+	require.NoError(repo.UpdateFileUploadOffset(upload.ID, 5, now.Add(time.Hour)))
+	u, err := repo.GetFileUpload(upload.ID)
+	require.NoError(err)
+	assert.EqualValues(5, u.Offset)
+	assert.False(u.IsCompleted())
+
+	assert.EqualError(repo.UpdateFileUploadOffset(uuid.NewV3(uuid.Nil, "not exists"), 1, now), ErrNotFound.Error())
+
+	if size, err := repo.GetUserFileUploadsSize(user.GetID(), now); assert.NoError(err) {
+		assert.EqualValues(10, size)
+	}
+	if size, err := repo.GetUserFileUploadsSize(user.GetID(), now.Add(2*time.Hour)); assert.NoError(err) {
+		assert.EqualValues(0, size)
+	}
+
+	require.NoError(repo.DeleteFileUpload(upload.ID))
+	_, err = repo.GetFileUpload(upload.ID)
+	assert.EqualError(err, ErrNotFound.Error())
+}
@@ -222,7 +222,7 @@ func (repo *GormRepository) GetMessages(query MessagesQuery) (messages []*model.
 		tx = tx.Where("messages.user_id = ?", query.User)
 	}
 	if query.ChannelsSubscribedByUser != uuid.Nil {
-		tx = tx.Where("channels.is_forced = TRUE OR channels.id IN (SELECT s.channel_id FROM users_subscribe_channels s WHERE s.user_id = ?)", query.ChannelsSubscribedByUser)
+		tx = tx.Where("channels.is_forced = TRUE OR channels.id IN (SELECT s.channel_id FROM users_subscribe_channels s WHERE s.user_id = ? AND s.mark = TRUE)", query.ChannelsSubscribedByUser)
 	}
 
 	if query.Inclusive {
@@ -340,7 +340,7 @@ func (repo *GormRepository) GetChannelLatestMessagesByUserID(userID uuid.UUID, l
 	var query string
 	switch {
 	case subscribeOnly:
-		query = `SELECT m.id, m.user_id, m.channel_id, m.text, m.created_at, m.updated_at, m.deleted_at FROM channel_latest_messages clm INNER JOIN messages m ON clm.message_id = m.id INNER JOIN channels c ON clm.channel_id = c.id WHERE c.deleted_at IS NULL AND c.is_public = TRUE AND m.deleted_at IS NULL AND (c.is_forced = TRUE OR c.id IN (SELECT s.channel_id FROM users_subscribe_channels s WHERE s.user_id = 'USER_ID')) ORDER BY clm.date_time DESC`
+		query = `SELECT m.id, m.user_id, m.channel_id, m.text, m.created_at, m.updated_at, m.deleted_at FROM channel_latest_messages clm INNER JOIN messages m ON clm.message_id = m.id INNER JOIN channels c ON clm.channel_id = c.id WHERE c.deleted_at IS NULL AND c.is_public = TRUE AND m.deleted_at IS NULL AND (c.is_forced = TRUE OR c.id IN (SELECT s.channel_id FROM users_subscribe_channels s WHERE s.user_id = 'USER_ID' AND s.mark = TRUE)) ORDER BY clm.date_time DESC`
 		query = strings.Replace(query, "USER_ID", userID.String(), -1)
 	default:
 		query = `SELECT m.id, m.user_id, m.channel_id, m.text, m.created_at, m.updated_at, m.deleted_at FROM channel_latest_messages clm INNER JOIN messages m ON clm.message_id = m.id INNER JOIN channels c ON clm.channel_id = c.id WHERE c.deleted_at IS NULL AND c.is_public = TRUE AND m.deleted_at IS NULL ORDER BY clm.date_time DESC`
@@ -374,12 +374,11 @@ func (repo *GormRepository) AddStampToMessage(messageID, stampID, userID uuid.UU
 		return nil, ErrNilID
 	}
 
-	err = repo.db.
+	result := repo.db.
 		Set("gorm:insert_option", fmt.Sprintf("ON DUPLICATE KEY UPDATE count = count + %d, updated_at = now()", count)).
-		Create(&model.MessageStamp{MessageID: messageID, StampID: stampID, UserID: userID, Count: count}).
-		Error
-	if err != nil {
-		return nil, err
+		Create(&model.MessageStamp{MessageID: messageID, StampID: stampID, UserID: userID, Count: count})
+	if result.Error != nil {
+		return nil, result.Error
 	}
 
 	// 楽観的に取得し直す。
@@ -395,6 +394,7 @@ func (repo *GormRepository) AddStampToMessage(messageID, stampID, userID uuid.UU
 			"user_id":    userID,
 			"count":      ms.Count,
 			"created_at": ms.CreatedAt,
+			"new":        result.RowsAffected == 1, // ON DUPLICATE KEY UPDATEで更新された場合は2
 		},
 	})
 	return ms, nil
@@ -405,6 +405,13 @@ func (repo *GormRepository) RemoveStampFromMessage(messageID, stampID, userID uu
 	if messageID == uuid.Nil || stampID == uuid.Nil || userID == uuid.Nil {
 		return ErrNilID
 	}
+	var ms model.MessageStamp
+	if err := repo.db.Take(&ms, &model.MessageStamp{MessageID: messageID, StampID: stampID, UserID: userID}).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
 	result := repo.db.Delete(&model.MessageStamp{MessageID: messageID, StampID: stampID, UserID: userID})
 	if result.Error != nil {
 		return result.Error
@@ -416,6 +423,7 @@ func (repo *GormRepository) RemoveStampFromMessage(messageID, stampID, userID uu
 				"message_id": messageID,
 				"stamp_id":   stampID,
 				"user_id":    userID,
+				"created_at": ms.CreatedAt,
 			},
 		})
 	}
@@ -67,6 +67,21 @@ func (mr *MockChannelRepositoryMockRecorder) CreateChannel(ch, privateMembers, d
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateChannel", reflect.TypeOf((*MockChannelRepository)(nil).CreateChannel), ch, privateMembers, dm)
 }
 
+// CreateChannelTree mocks base method
+func (m *MockChannelRepository) CreateChannelTree(parentID uuid.UUID, name string, definition model.ChannelTemplateNode, creatorID uuid.UUID) ([]*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateChannelTree", parentID, name, definition, creatorID)
+	ret0, _ := ret[0].([]*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateChannelTree indicates an expected call of CreateChannelTree
+func (mr *MockChannelRepositoryMockRecorder) CreateChannelTree(parentID, name, definition, creatorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateChannelTree", reflect.TypeOf((*MockChannelRepository)(nil).CreateChannelTree), parentID, name, definition, creatorID)
+}
+
 // UpdateChannel mocks base method
 func (m *MockChannelRepository) UpdateChannel(channelID uuid.UUID, args repository.UpdateChannelArgs) (*model.Channel, error) {
 	m.ctrl.T.Helper()
@@ -127,6 +142,51 @@ func (mr *MockChannelRepositoryMockRecorder) GetDirectMessageChannelMapping(user
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDirectMessageChannelMapping", reflect.TypeOf((*MockChannelRepository)(nil).GetDirectMessageChannelMapping), userID)
 }
 
+// GetGroupDirectMessageChannel mocks base method
+func (m *MockChannelRepository) GetGroupDirectMessageChannel(members set.UUID) (*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroupDirectMessageChannel", members)
+	ret0, _ := ret[0].(*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroupDirectMessageChannel indicates an expected call of GetGroupDirectMessageChannel
+func (mr *MockChannelRepositoryMockRecorder) GetGroupDirectMessageChannel(members interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupDirectMessageChannel", reflect.TypeOf((*MockChannelRepository)(nil).GetGroupDirectMessageChannel), members)
+}
+
+// GetGroupDirectMessageChannelMapping mocks base method
+func (m *MockChannelRepository) GetGroupDirectMessageChannelMapping(userID uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroupDirectMessageChannelMapping", userID)
+	ret0, _ := ret[0].(map[uuid.UUID][]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroupDirectMessageChannelMapping indicates an expected call of GetGroupDirectMessageChannelMapping
+func (mr *MockChannelRepositoryMockRecorder) GetGroupDirectMessageChannelMapping(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupDirectMessageChannelMapping", reflect.TypeOf((*MockChannelRepository)(nil).GetGroupDirectMessageChannelMapping), userID)
+}
+
+// ConvertGroupDirectMessageChannel mocks base method
+func (m *MockChannelRepository) ConvertGroupDirectMessageChannel(channelID uuid.UUID, name string, updaterID uuid.UUID) (*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConvertGroupDirectMessageChannel", channelID, name, updaterID)
+	ret0, _ := ret[0].(*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConvertGroupDirectMessageChannel indicates an expected call of ConvertGroupDirectMessageChannel
+func (mr *MockChannelRepositoryMockRecorder) ConvertGroupDirectMessageChannel(channelID, name, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertGroupDirectMessageChannel", reflect.TypeOf((*MockChannelRepository)(nil).ConvertGroupDirectMessageChannel), channelID, name, updaterID)
+}
+
 // GetPrivateChannelMemberIDs mocks base method
 func (m *MockChannelRepository) GetPrivateChannelMemberIDs(channelID uuid.UUID) ([]uuid.UUID, error) {
 	m.ctrl.T.Helper()
@@ -158,6 +218,51 @@ func (mr *MockChannelRepositoryMockRecorder) ChangeChannelSubscription(channelID
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeChannelSubscription", reflect.TypeOf((*MockChannelRepository)(nil).ChangeChannelSubscription), channelID, args)
 }
 
+// SetChannelGroupSubscription mocks base method
+func (m *MockChannelRepository) SetChannelGroupSubscription(channelID, groupID uuid.UUID, level model.ChannelSubscribeLevel) ([]uuid.UUID, []uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetChannelGroupSubscription", channelID, groupID, level)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].([]uuid.UUID)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SetChannelGroupSubscription indicates an expected call of SetChannelGroupSubscription
+func (mr *MockChannelRepositoryMockRecorder) SetChannelGroupSubscription(channelID, groupID, level interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetChannelGroupSubscription", reflect.TypeOf((*MockChannelRepository)(nil).SetChannelGroupSubscription), channelID, groupID, level)
+}
+
+// GetChannelGroupSubscriptions mocks base method
+func (m *MockChannelRepository) GetChannelGroupSubscriptions(channelID uuid.UUID) ([]*model.ChannelGroupSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChannelGroupSubscriptions", channelID)
+	ret0, _ := ret[0].([]*model.ChannelGroupSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChannelGroupSubscriptions indicates an expected call of GetChannelGroupSubscriptions
+func (mr *MockChannelRepositoryMockRecorder) GetChannelGroupSubscriptions(channelID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChannelGroupSubscriptions", reflect.TypeOf((*MockChannelRepository)(nil).GetChannelGroupSubscriptions), channelID)
+}
+
+// SyncGroupChannelSubscriptions mocks base method
+func (m *MockChannelRepository) SyncGroupChannelSubscriptions(userID, groupID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncGroupChannelSubscriptions", userID, groupID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SyncGroupChannelSubscriptions indicates an expected call of SyncGroupChannelSubscriptions
+func (mr *MockChannelRepositoryMockRecorder) SyncGroupChannelSubscriptions(userID, groupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncGroupChannelSubscriptions", reflect.TypeOf((*MockChannelRepository)(nil).SyncGroupChannelSubscriptions), userID, groupID)
+}
+
 // GetChannelSubscriptions mocks base method
 func (m *MockChannelRepository) GetChannelSubscriptions(query repository.ChannelSubscriptionQuery) ([]*model.UserSubscribeChannel, error) {
 	m.ctrl.T.Helper()
@@ -217,3 +322,107 @@ func (mr *MockChannelRepositoryMockRecorder) RecordChannelEvent(channelID, event
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordChannelEvent", reflect.TypeOf((*MockChannelRepository)(nil).RecordChannelEvent), channelID, eventType, detail, datetime)
 }
+
+// UpdateChannelReadState mocks base method
+func (m *MockChannelRepository) UpdateChannelReadState(userID uuid.UUID, channelID uuid.UUID, readAt time.Time) (*model.ChannelReadState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateChannelReadState", userID, channelID, readAt)
+	ret0, _ := ret[0].(*model.ChannelReadState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateChannelReadState indicates an expected call of UpdateChannelReadState
+func (mr *MockChannelRepositoryMockRecorder) UpdateChannelReadState(userID, channelID, readAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateChannelReadState", reflect.TypeOf((*MockChannelRepository)(nil).UpdateChannelReadState), userID, channelID, readAt)
+}
+
+// GetChannelReadStates mocks base method
+func (m *MockChannelRepository) GetChannelReadStates(channelID uuid.UUID) ([]*model.ChannelReadState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChannelReadStates", channelID)
+	ret0, _ := ret[0].([]*model.ChannelReadState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChannelReadStates indicates an expected call of GetChannelReadStates
+func (mr *MockChannelRepositoryMockRecorder) GetChannelReadStates(channelID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChannelReadStates", reflect.TypeOf((*MockChannelRepository)(nil).GetChannelReadStates), channelID)
+}
+
+// MergeChannel mocks base method
+func (m *MockChannelRepository) MergeChannel(srcID uuid.UUID, dstID uuid.UUID, updaterID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeChannel", srcID, dstID, updaterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MergeChannel indicates an expected call of MergeChannel
+func (mr *MockChannelRepositoryMockRecorder) MergeChannel(srcID, dstID, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeChannel", reflect.TypeOf((*MockChannelRepository)(nil).MergeChannel), srcID, dstID, updaterID)
+}
+
+// GetChannelRedirect mocks base method
+func (m *MockChannelRepository) GetChannelRedirect(channelID uuid.UUID) (*model.ChannelRedirect, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChannelRedirect", channelID)
+	ret0, _ := ret[0].(*model.ChannelRedirect)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChannelRedirect indicates an expected call of GetChannelRedirect
+func (mr *MockChannelRepositoryMockRecorder) GetChannelRedirect(channelID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChannelRedirect", reflect.TypeOf((*MockChannelRepository)(nil).GetChannelRedirect), channelID)
+}
+
+// GetPrivateChannels mocks base method
+func (m *MockChannelRepository) GetPrivateChannels(userID uuid.UUID) ([]*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrivateChannels", userID)
+	ret0, _ := ret[0].([]*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrivateChannels indicates an expected call of GetPrivateChannels
+func (mr *MockChannelRepositoryMockRecorder) GetPrivateChannels(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivateChannels", reflect.TypeOf((*MockChannelRepository)(nil).GetPrivateChannels), userID)
+}
+
+// AddPrivateChannelMembers mocks base method
+func (m *MockChannelRepository) AddPrivateChannelMembers(channelID uuid.UUID, userIDs set.UUID, updaterID uuid.UUID) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPrivateChannelMembers", channelID, userIDs, updaterID)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddPrivateChannelMembers indicates an expected call of AddPrivateChannelMembers
+func (mr *MockChannelRepositoryMockRecorder) AddPrivateChannelMembers(channelID, userIDs, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPrivateChannelMembers", reflect.TypeOf((*MockChannelRepository)(nil).AddPrivateChannelMembers), channelID, userIDs, updaterID)
+}
+
+// RemovePrivateChannelMembers mocks base method
+func (m *MockChannelRepository) RemovePrivateChannelMembers(channelID uuid.UUID, userIDs set.UUID, updaterID uuid.UUID) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePrivateChannelMembers", channelID, userIDs, updaterID)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemovePrivateChannelMembers indicates an expected call of RemovePrivateChannelMembers
+func (mr *MockChannelRepositoryMockRecorder) RemovePrivateChannelMembers(channelID, userIDs, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePrivateChannelMembers", reflect.TypeOf((*MockChannelRepository)(nil).RemovePrivateChannelMembers), channelID, userIDs, updaterID)
+}
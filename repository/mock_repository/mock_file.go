@@ -10,6 +10,7 @@ import (
 	model "github.com/traPtitech/traQ/model"
 	repository "github.com/traPtitech/traQ/repository"
 	reflect "reflect"
+	time "time"
 )
 
 // MockFileRepository is a mock of FileRepository interface
@@ -94,6 +95,20 @@ func (mr *MockFileRepositoryMockRecorder) DeleteFileMeta(fileID interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFileMeta", reflect.TypeOf((*MockFileRepository)(nil).DeleteFileMeta), fileID)
 }
 
+// UpdateFileMediaInfo mocks base method
+func (m *MockFileRepository) UpdateFileMediaInfo(fileID uuid.UUID, args repository.UpdateFileMediaArgs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFileMediaInfo", fileID, args)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFileMediaInfo indicates an expected call of UpdateFileMediaInfo
+func (mr *MockFileRepositoryMockRecorder) UpdateFileMediaInfo(fileID, args interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFileMediaInfo", reflect.TypeOf((*MockFileRepository)(nil).UpdateFileMediaInfo), fileID, args)
+}
+
 // IsFileAccessible mocks base method
 func (m *MockFileRepository) IsFileAccessible(fileID, userID uuid.UUID) (bool, error) {
 	m.ctrl.T.Helper()
@@ -108,3 +123,268 @@ func (mr *MockFileRepositoryMockRecorder) IsFileAccessible(fileID, userID interf
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsFileAccessible", reflect.TypeOf((*MockFileRepository)(nil).IsFileAccessible), fileID, userID)
 }
+
+// GetUserFileUsage mocks base method
+func (m *MockFileRepository) GetUserFileUsage(userID uuid.UUID) (*model.UserFileUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserFileUsage", userID)
+	ret0, _ := ret[0].(*model.UserFileUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserFileUsage indicates an expected call of GetUserFileUsage
+func (mr *MockFileRepositoryMockRecorder) GetUserFileUsage(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserFileUsage", reflect.TypeOf((*MockFileRepository)(nil).GetUserFileUsage), userID)
+}
+
+// GetChannelFileUsage mocks base method
+func (m *MockFileRepository) GetChannelFileUsage(channelID uuid.UUID) (*model.ChannelFileUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChannelFileUsage", channelID)
+	ret0, _ := ret[0].(*model.ChannelFileUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChannelFileUsage indicates an expected call of GetChannelFileUsage
+func (mr *MockFileRepositoryMockRecorder) GetChannelFileUsage(channelID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChannelFileUsage", reflect.TypeOf((*MockFileRepository)(nil).GetChannelFileUsage), channelID)
+}
+
+// GetTotalFileUsage mocks base method
+func (m *MockFileRepository) GetTotalFileUsage() (int64, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalFileUsage")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetTotalFileUsage indicates an expected call of GetTotalFileUsage
+func (mr *MockFileRepositoryMockRecorder) GetTotalFileUsage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalFileUsage", reflect.TypeOf((*MockFileRepository)(nil).GetTotalFileUsage))
+}
+
+// GetUserFileUsageRanking mocks base method
+func (m *MockFileRepository) GetUserFileUsageRanking(limit int) ([]*model.UserFileUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserFileUsageRanking", limit)
+	ret0, _ := ret[0].([]*model.UserFileUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserFileUsageRanking indicates an expected call of GetUserFileUsageRanking
+func (mr *MockFileRepositoryMockRecorder) GetUserFileUsageRanking(limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserFileUsageRanking", reflect.TypeOf((*MockFileRepository)(nil).GetUserFileUsageRanking), limit)
+}
+
+// GetChannelFileUsageRanking mocks base method
+func (m *MockFileRepository) GetChannelFileUsageRanking(limit int) ([]*model.ChannelFileUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChannelFileUsageRanking", limit)
+	ret0, _ := ret[0].([]*model.ChannelFileUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChannelFileUsageRanking indicates an expected call of GetChannelFileUsageRanking
+func (mr *MockFileRepositoryMockRecorder) GetChannelFileUsageRanking(limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChannelFileUsageRanking", reflect.TypeOf((*MockFileRepository)(nil).GetChannelFileUsageRanking), limit)
+}
+
+// RecalculateFileUsages mocks base method
+func (m *MockFileRepository) RecalculateFileUsages() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecalculateFileUsages")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecalculateFileUsages indicates an expected call of RecalculateFileUsages
+func (mr *MockFileRepositoryMockRecorder) RecalculateFileUsages() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecalculateFileUsages", reflect.TypeOf((*MockFileRepository)(nil).RecalculateFileUsages))
+}
+
+// GetFileObject mocks base method
+func (m *MockFileRepository) GetFileObject(objectID uuid.UUID) (*model.FileObject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFileObject", objectID)
+	ret0, _ := ret[0].(*model.FileObject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFileObject indicates an expected call of GetFileObject
+func (mr *MockFileRepositoryMockRecorder) GetFileObject(objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileObject", reflect.TypeOf((*MockFileRepository)(nil).GetFileObject), objectID)
+}
+
+// GetFileObjectByHash mocks base method
+func (m *MockFileRepository) GetFileObjectByHash(fileType model.FileType, hash string) (*model.FileObject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFileObjectByHash", fileType, hash)
+	ret0, _ := ret[0].(*model.FileObject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFileObjectByHash indicates an expected call of GetFileObjectByHash
+func (mr *MockFileRepositoryMockRecorder) GetFileObjectByHash(fileType, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileObjectByHash", reflect.TypeOf((*MockFileRepository)(nil).GetFileObjectByHash), fileType, hash)
+}
+
+// CreateFileObject mocks base method
+func (m *MockFileRepository) CreateFileObject(object *model.FileObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFileObject", object)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFileObject indicates an expected call of CreateFileObject
+func (mr *MockFileRepositoryMockRecorder) CreateFileObject(object interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFileObject", reflect.TypeOf((*MockFileRepository)(nil).CreateFileObject), object)
+}
+
+// DeleteFileObject mocks base method
+func (m *MockFileRepository) DeleteFileObject(objectID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFileObject", objectID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFileObject indicates an expected call of DeleteFileObject
+func (mr *MockFileRepositoryMockRecorder) DeleteFileObject(objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFileObject", reflect.TypeOf((*MockFileRepository)(nil).DeleteFileObject), objectID)
+}
+
+// GetUnhashedFileObjects mocks base method
+func (m *MockFileRepository) GetUnhashedFileObjects() ([]*model.FileObject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUnhashedFileObjects")
+	ret0, _ := ret[0].([]*model.FileObject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUnhashedFileObjects indicates an expected call of GetUnhashedFileObjects
+func (mr *MockFileRepositoryMockRecorder) GetUnhashedFileObjects() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnhashedFileObjects", reflect.TypeOf((*MockFileRepository)(nil).GetUnhashedFileObjects))
+}
+
+// SetFileObjectHash mocks base method
+func (m *MockFileRepository) SetFileObjectHash(objectID uuid.UUID, hash string) (*model.FileObject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFileObjectHash", objectID, hash)
+	ret0, _ := ret[0].(*model.FileObject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetFileObjectHash indicates an expected call of SetFileObjectHash
+func (mr *MockFileRepositoryMockRecorder) SetFileObjectHash(objectID, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFileObjectHash", reflect.TypeOf((*MockFileRepository)(nil).SetFileObjectHash), objectID, hash)
+}
+
+// CreateFileUpload mocks base method
+func (m *MockFileRepository) CreateFileUpload(upload *model.FileUpload) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFileUpload", upload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFileUpload indicates an expected call of CreateFileUpload
+func (mr *MockFileRepositoryMockRecorder) CreateFileUpload(upload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFileUpload", reflect.TypeOf((*MockFileRepository)(nil).CreateFileUpload), upload)
+}
+
+// GetFileUpload mocks base method
+func (m *MockFileRepository) GetFileUpload(uploadID uuid.UUID) (*model.FileUpload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFileUpload", uploadID)
+	ret0, _ := ret[0].(*model.FileUpload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFileUpload indicates an expected call of GetFileUpload
+func (mr *MockFileRepositoryMockRecorder) GetFileUpload(uploadID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileUpload", reflect.TypeOf((*MockFileRepository)(nil).GetFileUpload), uploadID)
+}
+
+// UpdateFileUploadOffset mocks base method
+func (m *MockFileRepository) UpdateFileUploadOffset(uploadID uuid.UUID, offset int64, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFileUploadOffset", uploadID, offset, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFileUploadOffset indicates an expected call of UpdateFileUploadOffset
+func (mr *MockFileRepositoryMockRecorder) UpdateFileUploadOffset(uploadID, offset, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFileUploadOffset", reflect.TypeOf((*MockFileRepository)(nil).UpdateFileUploadOffset), uploadID, offset, expiresAt)
+}
+
+// DeleteFileUpload mocks base method
+func (m *MockFileRepository) DeleteFileUpload(uploadID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFileUpload", uploadID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFileUpload indicates an expected call of DeleteFileUpload
+func (mr *MockFileRepositoryMockRecorder) DeleteFileUpload(uploadID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFileUpload", reflect.TypeOf((*MockFileRepository)(nil).DeleteFileUpload), uploadID)
+}
+
+// GetExpiredFileUploads mocks base method
+func (m *MockFileRepository) GetExpiredFileUploads(now time.Time) ([]*model.FileUpload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiredFileUploads", now)
+	ret0, _ := ret[0].([]*model.FileUpload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiredFileUploads indicates an expected call of GetExpiredFileUploads
+func (mr *MockFileRepositoryMockRecorder) GetExpiredFileUploads(now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredFileUploads", reflect.TypeOf((*MockFileRepository)(nil).GetExpiredFileUploads), now)
+}
+
+// GetUserFileUploadsSize mocks base method
+func (m *MockFileRepository) GetUserFileUploadsSize(userID uuid.UUID, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserFileUploadsSize", userID, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserFileUploadsSize indicates an expected call of GetUserFileUploadsSize
+func (mr *MockFileRepositoryMockRecorder) GetUserFileUploadsSize(userID, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserFileUploadsSize", reflect.TypeOf((*MockFileRepository)(nil).GetUserFileUploadsSize), userID, now)
+}
@@ -196,3 +196,32 @@ func (mr *MockUserRepositoryMockRecorder) UnlinkExternalUserAccount(userID, prov
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlinkExternalUserAccount", reflect.TypeOf((*MockUserRepository)(nil).UnlinkExternalUserAccount), userID, providerName)
 }
+
+// GetUserSettings mocks base method
+func (m *MockUserRepository) GetUserSettings(userID uuid.UUID) (*model.UserSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSettings", userID)
+	ret0, _ := ret[0].(*model.UserSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSettings indicates an expected call of GetUserSettings
+func (mr *MockUserRepositoryMockRecorder) GetUserSettings(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSettings", reflect.TypeOf((*MockUserRepository)(nil).GetUserSettings), userID)
+}
+
+// UpdateUserSettings mocks base method
+func (m *MockUserRepository) UpdateUserSettings(userID uuid.UUID, args repository.UpdateUserSettingsArgs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserSettings", userID, args)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserSettings indicates an expected call of UpdateUserSettings
+func (mr *MockUserRepositoryMockRecorder) UpdateUserSettings(userID, args interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserSettings", reflect.TypeOf((*MockUserRepository)(nil).UpdateUserSettings), userID, args)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/optional"
+	"time"
 )
 
 type UpdateClientArgs struct {
@@ -72,6 +73,40 @@ type OAuth2Repository interface {
 	// 成功した、或いは既に存在しない場合、nilを返します。
 	// DBによるエラーを返すことがあります。
 	DeleteAuthorize(code string) error
+	// SaveDeviceAuthorize デバイス認可データを保存します
+	//
+	// 成功した場合、nilを返します。
+	// ユーザーコードが既に存在する場合、ErrAlreadyExistsを返します。
+	// DBによるエラーを返すことがあります。
+	SaveDeviceAuthorize(data *model.OAuth2DeviceAuthorize) error
+	// GetDeviceAuthorize 指定したデバイスコードのデバイス認可データを取得します
+	//
+	// 成功した場合、デバイス認可データとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetDeviceAuthorize(deviceCode string) (*model.OAuth2DeviceAuthorize, error)
+	// GetDeviceAuthorizeByUserCode 指定したユーザーコードのデバイス認可データを取得します
+	//
+	// 成功した場合、デバイス認可データとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetDeviceAuthorizeByUserCode(userCode string) (*model.OAuth2DeviceAuthorize, error)
+	// DecideDeviceAuthorize 承認待ちのデバイス認可データを承認、或いは拒否します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しない、或いは承認待ちでない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DecideDeviceAuthorize(deviceCode string, userID uuid.UUID, approved bool) error
+	// UpdateDeviceAuthorizePolling デバイス認可データのポーリング日時とポーリング間隔を更新します
+	//
+	// 成功した、或いは存在しない場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateDeviceAuthorizePolling(deviceCode string, polledAt time.Time, interval int) error
+	// DeleteDeviceAuthorize 指定したデバイスコードのデバイス認可データを削除します
+	//
+	// 成功した、或いは既に存在しない場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteDeviceAuthorize(deviceCode string) error
 	// IssueToken トークンを発行します
 	//
 	// 成功した場合、トークンとnilを返します。
@@ -4,6 +4,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
 	"github.com/traPtitech/traQ/utils/random"
 	"time"
 )
@@ -98,6 +99,7 @@ func (repo *GormRepository) DeleteClient(id string) error {
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
 		errs := tx.Delete(&model.OAuth2Client{ID: id}).
 			Delete(&model.OAuth2Authorize{}, &model.OAuth2Authorize{ClientID: id}).
+			Delete(&model.OAuth2DeviceAuthorize{}, &model.OAuth2DeviceAuthorize{ClientID: id}).
 			Delete(&model.OAuth2Token{}, &model.OAuth2Token{ClientID: id}).
 			GetErrors()
 		if len(errs) > 0 {
@@ -133,6 +135,83 @@ func (repo *GormRepository) DeleteAuthorize(code string) error {
 	return repo.db.Delete(&model.OAuth2Authorize{Code: code}).Error
 }
 
+// SaveDeviceAuthorize implements OAuth2Repository interface.
+func (repo *GormRepository) SaveDeviceAuthorize(data *model.OAuth2DeviceAuthorize) error {
+	if err := repo.db.Create(data).Error; err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetDeviceAuthorize implements OAuth2Repository interface.
+func (repo *GormRepository) GetDeviceAuthorize(deviceCode string) (*model.OAuth2DeviceAuthorize, error) {
+	if len(deviceCode) == 0 {
+		return nil, ErrNotFound
+	}
+	da := &model.OAuth2DeviceAuthorize{}
+	if err := repo.db.Take(da, &model.OAuth2DeviceAuthorize{DeviceCode: deviceCode}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return da, nil
+}
+
+// GetDeviceAuthorizeByUserCode implements OAuth2Repository interface.
+func (repo *GormRepository) GetDeviceAuthorizeByUserCode(userCode string) (*model.OAuth2DeviceAuthorize, error) {
+	if len(userCode) == 0 {
+		return nil, ErrNotFound
+	}
+	da := &model.OAuth2DeviceAuthorize{}
+	if err := repo.db.Take(da, &model.OAuth2DeviceAuthorize{UserCode: userCode}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return da, nil
+}
+
+// DecideDeviceAuthorize implements OAuth2Repository interface.
+func (repo *GormRepository) DecideDeviceAuthorize(deviceCode string, userID uuid.UUID, approved bool) error {
+	if len(deviceCode) == 0 {
+		return ErrNotFound
+	}
+	status := model.DeviceAuthorizeDenied
+	if approved {
+		status = model.DeviceAuthorizeApproved
+	}
+	result := repo.db.
+		Model(&model.OAuth2DeviceAuthorize{}).
+		Where("device_code = ? AND status = ?", deviceCode, model.DeviceAuthorizePending).
+		Updates(map[string]interface{}{"user_id": userID, "status": status})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateDeviceAuthorizePolling implements OAuth2Repository interface.
+func (repo *GormRepository) UpdateDeviceAuthorizePolling(deviceCode string, polledAt time.Time, interval int) error {
+	if len(deviceCode) == 0 {
+		return ErrNotFound
+	}
+	return repo.db.
+		Model(&model.OAuth2DeviceAuthorize{}).
+		Where(&model.OAuth2DeviceAuthorize{DeviceCode: deviceCode}).
+		Updates(map[string]interface{}{"last_polled_at": polledAt, "poll_interval": interval}).
+		Error
+}
+
+// DeleteDeviceAuthorize implements OAuth2Repository interface.
+func (repo *GormRepository) DeleteDeviceAuthorize(deviceCode string) error {
+	if len(deviceCode) == 0 {
+		return nil
+	}
+	return repo.db.Delete(&model.OAuth2DeviceAuthorize{DeviceCode: deviceCode}).Error
+}
+
 // IssueToken implements OAuth2Repository interface.
 func (repo *GormRepository) IssueToken(client *model.OAuth2Client, userID uuid.UUID, redirectURI string, scope model.AccessScopes, expire int, refresh bool) (*model.OAuth2Token, error) {
 	newToken := &model.OAuth2Token{
@@ -11,6 +11,7 @@ type Repository interface {
 	UserGroupRepository
 	TagRepository
 	ChannelRepository
+	ChannelTemplateRepository
 	MessageRepository
 	MessageReportRepository
 	StampRepository
@@ -3,6 +3,7 @@ package repository
 import (
 	"github.com/jinzhu/gorm"
 	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/migration"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/service/rbac/role"
@@ -29,7 +30,11 @@ func (repo *GormRepository) Sync() (init bool, err error) {
 	if err := repo.db.Find(&stamps).Error; err != nil {
 		return false, err
 	}
+	if err := loadStampAttributes(repo.db, stamps); err != nil {
+		return false, err
+	}
 	repo.stamps = makeStampRepository(stamps)
+	go repo.syncStampCache(repo.hub.Subscribe(10, event.StampCreated, event.StampUpdated, event.StampDeleted))
 
 	// 管理者ユーザーの確認
 	if exists, err := gormutil.RecordExists(repo.db, &model.User{Role: role.Admin}); err != nil {
@@ -13,6 +13,7 @@ type CreateStampArgs struct {
 	FileID    uuid.UUID
 	CreatorID uuid.UUID
 	IsUnicode bool
+	Category  string
 }
 
 // UpdateStampArgs スタンプ情報更新引数
@@ -20,6 +21,11 @@ type UpdateStampArgs struct {
 	Name      optional.String
 	FileID    optional.UUID
 	CreatorID optional.UUID
+	Category  optional.String
+	// Aliases エイリアス (nilの場合は変更しません)
+	Aliases []string
+	// Tags タグ (nilの場合は変更しません)
+	Tags []string
 }
 
 // UserStampHistory スタンプ履歴構造体
@@ -28,13 +34,33 @@ type UserStampHistory struct {
 	Datetime time.Time `json:"datetime"`
 }
 
+// StampStats スタンプ使用統計構造体
+type StampStats struct {
+	StampID     uuid.UUID          `json:"stampId"`
+	Count       int                `json:"count"`
+	UniqueUsers int                `json:"uniqueUsers"`
+	Daily       []*StampDailyCount `json:"daily"`
+}
+
+// StampDailyCount スタンプの日別使用数構造体
+type StampDailyCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// StampRankingItem スタンプランキング構造体
+type StampRankingItem struct {
+	StampID uuid.UUID `json:"stampId"`
+	Count   int       `json:"count"`
+}
+
 // StampRepository スタンプリポジトリ
 type StampRepository interface {
 	// CreateStamp スタンプを作成します
 	//
 	// 成功した場合、スタンプとnilを返します。
 	// 引数に問題がある場合、ArgumentErrorを返します。
-	// 既にNameが使われている場合、ErrAlreadyExistsを返します。
+	// 既にNameがスタンプ名、或いはエイリアスとして使われている場合、ErrAlreadyExistsを返します。
 	// DBによるエラーを返すことがあります。
 	CreateStamp(args CreateStampArgs) (s *model.Stamp, err error)
 	// UpdateStamp 指定したスタンプの情報を更新します
@@ -43,7 +69,7 @@ type StampRepository interface {
 	// 存在しないスタンプの場合、ErrNotFoundを返します。
 	// idにuuid.Nilを指定した場合、ErrNilIDを返します。
 	// 更新内容に問題がある場合、ArgumentErrorを返します。
-	// 変更後のNameが既に使われている場合、ErrAlreadyExistsを返します。
+	// 変更後のName、或いはエイリアスが既に使われている場合、ErrAlreadyExistsを返します。
 	// DBによるエラーを返すことがあります。
 	UpdateStamp(id uuid.UUID, args UpdateStampArgs) error
 	// GetStamp 指定したIDのスタンプを取得します
@@ -54,6 +80,7 @@ type StampRepository interface {
 	GetStamp(id uuid.UUID) (s *model.Stamp, err error)
 	// GetStampByName 指定したnameのスタンプを取得します
 	//
+	// nameはスタンプ名の他にエイリアスでも構いません。
 	// 成功した場合、スタンプとnilを返します。
 	// 存在しなかった場合、ErrNotFoundを返します。
 	// DBによるエラーを返すことがあります。
@@ -93,4 +120,31 @@ type StampRepository interface {
 	// 存在しないスタンプがあった場合、ArgumentErrorを返します。
 	// DBによるエラーを返すことがあります。
 	ExistStamps(stampIDs []uuid.UUID) (err error)
+	// SearchStamps スタンプを検索します
+	//
+	// スタンプ名・エイリアス・タグ・カテゴリーがqueryに一致するスタンプを、
+	// 一致度、指定したユーザーの直近のスタンプ履歴、全体での使用回数(集計値)の順に並べて最大limit件返します。
+	// limitに0を指定した場合、全て返します。
+	// DBによるエラーを返すことがあります。
+	SearchStamps(query string, userID uuid.UUID, limit int) ([]*model.Stamp, error)
+	// UpdateStampUsage スタンプ使用数の集計値をdeltaだけ増減させます
+	//
+	// dateには集計対象の日時を指定します。UTCの日付単位で集計されます。
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateStampUsage(stampID, channelID, userID uuid.UUID, date time.Time, delta int) error
+	// GetStampStats 指定したスタンプの使用統計を取得します
+	//
+	// 日別使用数はsinceの日付から今日までの分を、古い順に返します。
+	// 成功した場合、使用統計とnilを返します。
+	// 存在しないスタンプを指定した場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetStampStats(stampID uuid.UUID, since time.Time) (*StampStats, error)
+	// GetStampRanking sinceの日付以降に多く使われたスタンプを最大limit件取得します
+	//
+	// channelIDにuuid.Nil以外を指定した場合、そのチャンネルでの使用数のみを集計します。
+	// 成功した場合、使用数の降順の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetStampRanking(since time.Time, channelID uuid.UUID, limit int) ([]*StampRankingItem, error)
 }
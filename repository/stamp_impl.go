@@ -10,12 +10,16 @@ import (
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/gormutil"
 	"github.com/traPtitech/traQ/utils/validator"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 type stampRepository struct {
 	stamps    map[uuid.UUID]*model.Stamp
+	names     map[string]uuid.UUID // スタンプ名・エイリアス -> スタンプID
 	allJSON   []byte
 	json      []byte
 	updatedAt time.Time
@@ -56,12 +60,18 @@ func (r *stampRepository) delete(id uuid.UUID) {
 func (r *stampRepository) regenerateJSON() {
 	arr := make([]*model.Stamp, 0, len(r.stamps))
 	arrAll := make([]*model.Stamp, 0, len(r.stamps))
+	names := make(map[string]uuid.UUID, len(r.stamps))
 	for _, stamp := range r.stamps {
 		arrAll = append(arrAll, stamp)
 		if !stamp.IsUnicode {
 			arr = append(arr, stamp)
 		}
+		names[stamp.Name] = stamp.ID
+		for _, alias := range stamp.Aliases {
+			names[alias] = stamp.ID
+		}
 	}
+	r.names = names
 
 	b, err := jsoniter.ConfigFastest.Marshal(arr)
 	if err != nil {
@@ -76,6 +86,35 @@ func (r *stampRepository) regenerateJSON() {
 	r.allJSON = b
 }
 
+// syncStampCache 他のノードでのスタンプの変更をキャッシュに反映します
+func (repo *GormRepository) syncStampCache(sub hub.Subscription) {
+	for ev := range sub.Receiver {
+		if !event.IsRemote(ev) {
+			continue
+		}
+		id := ev.Fields["stamp_id"].(uuid.UUID)
+		if ev.Name == event.StampDeleted {
+			repo.stamps.Lock()
+			repo.stamps.delete(id)
+			repo.stamps.Unlock()
+			continue
+		}
+
+		var s model.Stamp
+		if err := repo.db.First(&s, &model.Stamp{ID: id}).Error; err != nil {
+			repo.logger.Error("failed to reload stamp", zap.Error(err), zap.Stringer("stampId", id))
+			continue
+		}
+		if err := loadStampAttributes(repo.db, []*model.Stamp{&s}); err != nil {
+			repo.logger.Error("failed to reload stamp", zap.Error(err), zap.Stringer("stampId", id))
+			continue
+		}
+		repo.stamps.Lock()
+		repo.stamps.update(&s)
+		repo.stamps.Unlock()
+	}
+}
+
 func (r *stampRepository) GetStamp(id uuid.UUID) (s *model.Stamp, ok bool) {
 	r.RLock()
 	defer r.RUnlock()
@@ -83,6 +122,27 @@ func (r *stampRepository) GetStamp(id uuid.UUID) (s *model.Stamp, ok bool) {
 	return
 }
 
+func (r *stampRepository) GetStampByName(name string) (s *model.Stamp, ok bool) {
+	r.RLock()
+	defer r.RUnlock()
+	id, ok := r.names[name]
+	if !ok {
+		return nil, false
+	}
+	s, ok = r.stamps[id]
+	return
+}
+
+func (r *stampRepository) GetAll() []*model.Stamp {
+	r.RLock()
+	defer r.RUnlock()
+	arr := make([]*model.Stamp, 0, len(r.stamps))
+	for _, stamp := range r.stamps {
+		arr = append(arr, stamp)
+	}
+	return arr
+}
+
 func (r *stampRepository) CheckIDs(ids []uuid.UUID) bool {
 	r.RLock()
 	defer r.RUnlock()
@@ -102,6 +162,9 @@ func (repo *GormRepository) CreateStamp(args CreateStampArgs) (s *model.Stamp, e
 		FileID:    args.FileID,
 		CreatorID: args.CreatorID, // uuid.Nilを許容する
 		IsUnicode: args.IsUnicode,
+		Category:  args.Category,
+		Aliases:   []string{},
+		Tags:      []string{},
 	}
 
 	if repo.stamps != nil {
@@ -115,11 +178,15 @@ func (repo *GormRepository) CreateStamp(args CreateStampArgs) (s *model.Stamp, e
 			return ArgError("name", "Name must be 1-32 characters of a-zA-Z0-9_-")
 		}
 		// 名前重複チェック
-		if exists, err := gormutil.RecordExists(tx, &model.Stamp{Name: stamp.Name}); err != nil {
+		if exists, err := stampNameExists(tx, stamp.Name, uuid.Nil); err != nil {
 			return err
 		} else if exists {
 			return ErrAlreadyExists
 		}
+		// カテゴリーチェック
+		if err := vd.Validate(stamp.Category, validator.StampCategoryRule...); err != nil {
+			return ArgError("category", "Category must be 0-30 characters")
+		}
 		// ファイル存在チェック
 		if stamp.FileID == uuid.Nil {
 			return ArgError("fileID", "FileID's file is not found")
@@ -163,6 +230,7 @@ func (repo *GormRepository) UpdateStamp(id uuid.UUID, args UpdateStampArgs) erro
 
 	var s model.Stamp
 	changes := map[string]interface{}{}
+	attrChanged := false
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.First(&s, &model.Stamp{ID: id}).Error; err != nil {
 			return convertError(err)
@@ -174,12 +242,17 @@ func (repo *GormRepository) UpdateStamp(id uuid.UUID, args UpdateStampArgs) erro
 			}
 
 			// 重複チェック
-			if exists, err := gormutil.RecordExists(tx, &model.Stamp{Name: args.Name.String}); err != nil {
+			if exists, err := stampNameExists(tx, args.Name.String, s.ID); err != nil {
 				return err
 			} else if exists {
 				return ErrAlreadyExists
 			}
+			// 自身のエイリアスと同じ名前になる場合はエイリアスを削除
+			if err := tx.Where("name = ? AND stamp_id = ?", args.Name.String, s.ID).Delete(&model.StampAlias{}).Error; err != nil {
+				return err
+			}
 			changes["name"] = args.Name.String
+			attrChanged = true
 		}
 		if args.FileID.Valid {
 			// 存在チェック
@@ -197,6 +270,28 @@ func (repo *GormRepository) UpdateStamp(id uuid.UUID, args UpdateStampArgs) erro
 			// uuid.Nilを許容する
 			changes["creator_id"] = args.CreatorID.UUID
 		}
+		if args.Category.Valid && s.Category != args.Category.String {
+			if err := vd.Validate(args.Category.String, validator.StampCategoryRule...); err != nil {
+				return ArgError("args.Category", "Category must be 0-30 characters")
+			}
+			changes["category"] = args.Category.String
+		}
+		if args.Aliases != nil {
+			name := s.Name
+			if args.Name.Valid {
+				name = args.Name.String
+			}
+			if err := updateStampAliases(tx, s.ID, name, args.Aliases); err != nil {
+				return err
+			}
+			attrChanged = true
+		}
+		if args.Tags != nil {
+			if err := updateStampTags(tx, s.ID, args.Tags); err != nil {
+				return err
+			}
+			attrChanged = true
+		}
 
 		if len(changes) > 0 {
 			return tx.Model(&s).Updates(changes).Error
@@ -206,7 +301,10 @@ func (repo *GormRepository) UpdateStamp(id uuid.UUID, args UpdateStampArgs) erro
 	if err != nil {
 		return err
 	}
-	if len(changes) > 0 {
+	if len(changes) > 0 || attrChanged {
+		if err := loadStampAttributes(repo.db, []*model.Stamp{&s}); err != nil {
+			return err
+		}
 		if repo.stamps != nil {
 			repo.stamps.update(&s)
 		}
@@ -237,7 +335,7 @@ func (repo *GormRepository) GetStamp(id uuid.UUID) (s *model.Stamp, err error) {
 	if err := repo.db.First(s, &model.Stamp{ID: id}).Error; err != nil {
 		return nil, convertError(err)
 	}
-	return s, nil
+	return s, loadStampAttributes(repo.db, []*model.Stamp{s})
 }
 
 // GetStampByName implements StampRepository interface.
@@ -245,11 +343,29 @@ func (repo *GormRepository) GetStampByName(name string) (s *model.Stamp, err err
 	if len(name) == 0 {
 		return nil, ErrNotFound
 	}
+
+	if repo.stamps != nil {
+		if s, ok := repo.stamps.GetStampByName(name); ok {
+			return s, nil
+		}
+		return nil, ErrNotFound
+	}
+
 	s = &model.Stamp{}
 	if err := repo.db.First(s, &model.Stamp{Name: name}).Error; err != nil {
-		return nil, convertError(err)
+		if !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+		// エイリアスから検索
+		var alias model.StampAlias
+		if err := repo.db.First(&alias, &model.StampAlias{Name: name}).Error; err != nil {
+			return nil, convertError(err)
+		}
+		if err := repo.db.First(s, &model.Stamp{ID: alias.StampID}).Error; err != nil {
+			return nil, convertError(err)
+		}
 	}
-	return s, nil
+	return s, loadStampAttributes(repo.db, []*model.Stamp{s})
 }
 
 // DeleteStamp implements StampRepository interface.
@@ -263,11 +379,27 @@ func (repo *GormRepository) DeleteStamp(id uuid.UUID) (err error) {
 		defer repo.stamps.Unlock()
 	}
 
-	result := repo.db.Delete(&model.Stamp{ID: id})
-	if result.Error != nil {
-		return result.Error
+	var deleted bool
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.Stamp{ID: id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		deleted = true
+
+		// スタンプは論理削除のため、エイリアスとタグは明示的に削除する
+		if err := tx.Where(&model.StampAlias{StampID: id}).Delete(&model.StampAlias{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(&model.StampTag{StampID: id}).Delete(&model.StampTag{}).Error
+	})
+	if err != nil {
+		return err
 	}
-	if result.RowsAffected > 0 {
+	if deleted {
 		if repo.stamps != nil {
 			repo.stamps.delete(id)
 		}
@@ -289,7 +421,10 @@ func (repo *GormRepository) GetAllStamps(excludeUnicode bool) (stamps []*model.S
 	if excludeUnicode {
 		tx = tx.Where("is_unicode = FALSE")
 	}
-	return stamps, tx.Find(&stamps).Error
+	if err := tx.Find(&stamps).Error; err != nil {
+		return nil, err
+	}
+	return stamps, loadStampAttributes(repo.db, stamps)
 }
 
 // GetStampsJSON implements StampRepository interface.
@@ -362,3 +497,325 @@ func (repo *GormRepository) GetUserStampHistory(userID uuid.UUID, limit int) (h
 		Error
 	return
 }
+
+// stampSearchHistoryLimit スタンプ検索の並べ替えに使うユーザーのスタンプ履歴の件数
+const stampSearchHistoryLimit = 100
+
+// SearchStamps implements StampRepository interface.
+func (repo *GormRepository) SearchStamps(query string, userID uuid.UUID, limit int) ([]*model.Stamp, error) {
+	var all []*model.Stamp
+	if repo.stamps != nil {
+		all = repo.stamps.GetAll()
+	} else {
+		var err error
+		all, err = repo.GetAllStamps(false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := make([]*stampSearchCandidate, 0)
+	ids := make([]uuid.UUID, 0)
+	for _, s := range all {
+		if m := s.Match(query); m != model.StampMatchNone {
+			candidates = append(candidates, &stampSearchCandidate{stamp: s, match: m, recency: -1})
+			ids = append(ids, s.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		return []*model.Stamp{}, nil
+	}
+
+	// ユーザーの直近のスタンプ履歴
+	history, err := repo.GetUserStampHistory(userID, stampSearchHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	recency := make(map[uuid.UUID]int, len(history))
+	for i, h := range history {
+		recency[h.StampID] = i
+	}
+
+	// 全体での使用回数 (ユーザー別使用数の集計値から求める)
+	var counts []struct {
+		StampID uuid.UUID
+		Count   int
+	}
+	if err := repo.db.
+		Model(&model.StampUserUsage{}).
+		Where("stamp_id IN (?)", ids).
+		Group("stamp_id").
+		Select("stamp_id, SUM(count) AS count").
+		Scan(&counts).
+		Error; err != nil {
+		return nil, err
+	}
+	popularity := make(map[uuid.UUID]int, len(counts))
+	for _, c := range counts {
+		popularity[c.StampID] = c.Count
+	}
+
+	for _, c := range candidates {
+		if i, ok := recency[c.stamp.ID]; ok {
+			c.recency = i
+		}
+		c.popularity = popularity[c.stamp.ID]
+	}
+	sortStampSearchCandidates(candidates)
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	result := make([]*model.Stamp, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.stamp
+	}
+	return result, nil
+}
+
+// stampSearchCandidate スタンプ検索の候補
+type stampSearchCandidate struct {
+	stamp *model.Stamp
+	// match 一致度
+	match int
+	// recency ユーザーの使用履歴での順位 (使用したことがない場合は-1)
+	recency int
+	// popularity 全体での使用回数
+	popularity int
+}
+
+// sortStampSearchCandidates スタンプ検索の候補を一致度、ユーザーの使用履歴、全体での使用回数の順に並べ替えます
+func sortStampSearchCandidates(candidates []*stampSearchCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.match != b.match {
+			return a.match < b.match
+		}
+		if (a.recency >= 0) != (b.recency >= 0) {
+			return a.recency >= 0
+		}
+		if a.recency != b.recency {
+			return a.recency < b.recency
+		}
+		if a.popularity != b.popularity {
+			return a.popularity > b.popularity
+		}
+		return a.stamp.Name < b.stamp.Name
+	})
+}
+
+// stampNameExists 名前がexcludeID以外のスタンプの名前、或いはエイリアスとして使われているかどうか
+func stampNameExists(tx *gorm.DB, name string, excludeID uuid.UUID) (bool, error) {
+	if exists, err := gormutil.RecordExists(tx.Where("id <> ?", excludeID), &model.Stamp{Name: name}); err != nil || exists {
+		return exists, err
+	}
+	return gormutil.RecordExists(tx.Where("stamp_id <> ?", excludeID), &model.StampAlias{Name: name})
+}
+
+// updateStampAliases スタンプのエイリアスを置き換えます
+func updateStampAliases(tx *gorm.DB, stampID uuid.UUID, stampName string, aliases []string) error {
+	aliases = uniqueStrings(aliases)
+	for _, alias := range aliases {
+		if err := vd.Validate(alias, validator.StampNameRuleRequired...); err != nil {
+			return ArgError("args.Aliases", "Alias must be 1-32 characters of a-zA-Z0-9_-")
+		}
+		if alias == stampName {
+			return ArgError("args.Aliases", "Alias must be different from the stamp name")
+		}
+		if exists, err := stampNameExists(tx, alias, stampID); err != nil {
+			return err
+		} else if exists {
+			return ErrAlreadyExists
+		}
+	}
+
+	if err := tx.Where(&model.StampAlias{StampID: stampID}).Delete(&model.StampAlias{}).Error; err != nil {
+		return err
+	}
+	for _, alias := range aliases {
+		if err := tx.Create(&model.StampAlias{Name: alias, StampID: stampID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStampTags スタンプのタグを置き換えます
+func updateStampTags(tx *gorm.DB, stampID uuid.UUID, tags []string) error {
+	trimmed := make([]string, len(tags))
+	for i, tag := range tags {
+		trimmed[i] = strings.TrimSpace(tag)
+	}
+	tags = uniqueStrings(trimmed)
+	for _, tag := range tags {
+		if err := vd.Validate(tag, validator.StampTagRuleRequired...); err != nil {
+			return ArgError("args.Tags", "Tag must be 1-30 characters")
+		}
+	}
+
+	if err := tx.Where(&model.StampTag{StampID: stampID}).Delete(&model.StampTag{}).Error; err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if err := tx.Create(&model.StampTag{StampID: stampID, Tag: tag}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadStampAttributes スタンプのエイリアスとタグを読み込みます
+func loadStampAttributes(db *gorm.DB, stamps []*model.Stamp) error {
+	if len(stamps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(stamps))
+	stampMap := make(map[uuid.UUID]*model.Stamp, len(stamps))
+	for i, s := range stamps {
+		ids[i] = s.ID
+		stampMap[s.ID] = s
+		s.Aliases = []string{}
+		s.Tags = []string{}
+	}
+
+	var aliases []*model.StampAlias
+	if err := db.Where("stamp_id IN (?)", ids).Order("name").Find(&aliases).Error; err != nil {
+		return err
+	}
+	for _, a := range aliases {
+		if s, ok := stampMap[a.StampID]; ok {
+			s.Aliases = append(s.Aliases, a.Name)
+		}
+	}
+
+	var tags []*model.StampTag
+	if err := db.Where("stamp_id IN (?)", ids).Order("tag").Find(&tags).Error; err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if s, ok := stampMap[t.StampID]; ok {
+			s.Tags = append(s.Tags, t.Tag)
+		}
+	}
+	return nil
+}
+
+// uniqueStrings 重複を取り除いた文字列の配列を返します
+func uniqueStrings(arr []string) []string {
+	seen := make(map[string]struct{}, len(arr))
+	result := make([]string, 0, len(arr))
+	for _, s := range arr {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		result = append(result, s)
+	}
+	return result
+}
+
+// UpdateStampUsage implements StampRepository interface.
+func (repo *GormRepository) UpdateStampUsage(stampID, channelID, userID uuid.UUID, date time.Time, delta int) error {
+	if stampID == uuid.Nil || channelID == uuid.Nil || userID == uuid.Nil {
+		return ErrNilID
+	}
+	if delta == 0 {
+		return nil
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("INSERT INTO stamp_daily_usages (date, stamp_id, channel_id, count) VALUES (?, ?, ?, GREATEST(?, 0)) ON DUPLICATE KEY UPDATE count = GREATEST(count + ?, 0)",
+			truncateToDate(date), stampID, channelID, delta, delta).Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO stamp_user_usages (stamp_id, user_id, count) VALUES (?, ?, GREATEST(?, 0)) ON DUPLICATE KEY UPDATE count = GREATEST(count + ?, 0)",
+			stampID, userID, delta, delta).Error
+	})
+}
+
+// GetStampStats implements StampRepository interface.
+func (repo *GormRepository) GetStampStats(stampID uuid.UUID, since time.Time) (*StampStats, error) {
+	if ok, err := repo.StampExists(stampID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrNotFound
+	}
+
+	stats := &StampStats{StampID: stampID}
+	var total struct {
+		Count int
+	}
+	if err := repo.db.
+		Model(&model.StampDailyUsage{}).
+		Where("stamp_id = ?", stampID).
+		Select("COALESCE(SUM(count), 0) AS count").
+		Scan(&total).
+		Error; err != nil {
+		return nil, err
+	}
+	stats.Count = total.Count
+
+	uniqueUsers, err := gormutil.Count(repo.db.
+		Model(&model.StampUserUsage{}).
+		Where("stamp_id = ? AND count > 0", stampID))
+	if err != nil {
+		return nil, err
+	}
+	stats.UniqueUsers = uniqueUsers
+
+	var daily []struct {
+		Date  time.Time
+		Count int
+	}
+	since = truncateToDate(since)
+	if err := repo.db.
+		Model(&model.StampDailyUsage{}).
+		Where("stamp_id = ? AND date >= ?", stampID, since).
+		Group("date").
+		Select("date, SUM(count) AS count").
+		Scan(&daily).
+		Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(daily))
+	for _, d := range daily {
+		counts[d.Date.Format("2006-01-02")] = d.Count
+	}
+
+	// 使われなかった日も0として埋める
+	today := truncateToDate(time.Now())
+	stats.Daily = make([]*StampDailyCount, 0)
+	for d := since; !d.After(today); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		stats.Daily = append(stats.Daily, &StampDailyCount{Date: date, Count: counts[date]})
+	}
+	return stats, nil
+}
+
+// GetStampRanking implements StampRepository interface.
+func (repo *GormRepository) GetStampRanking(since time.Time, channelID uuid.UUID, limit int) ([]*StampRankingItem, error) {
+	ranking := make([]*StampRankingItem, 0)
+	tx := repo.db.
+		Table("stamp_daily_usages u").
+		Joins("INNER JOIN stamps s ON u.stamp_id = s.id AND s.deleted_at IS NULL").
+		Where("u.date >= ?", truncateToDate(since))
+	if channelID != uuid.Nil {
+		tx = tx.Where("u.channel_id = ?", channelID)
+	}
+	err := tx.
+		Group("u.stamp_id").
+		Select("u.stamp_id, SUM(u.count) AS count").
+		Having("SUM(u.count) > 0").
+		Order("SUM(u.count) DESC, u.stamp_id").
+		Scopes(gormutil.LimitAndOffset(limit, 0)).
+		Scan(&ranking).
+		Error
+	return ranking, err
+}
+
+// truncateToDate UTCでの日付に切り捨てます
+func truncateToDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -3,9 +3,11 @@ package repository
 import (
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/traPtitech/traQ/utils/optional"
 	random2 "github.com/traPtitech/traQ/utils/random"
 	"testing"
+	"time"
 )
 
 func TestRepositoryImpl_CreateStamp(t *testing.T) {
@@ -131,6 +133,86 @@ func TestRepositoryImpl_UpdateStamp(t *testing.T) {
 	})
 }
 
+func TestRepositoryImpl_UpdateStamp_AliasesAndTags(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common2)
+
+	other := mustMakeStamp(t, repo, rand, uuid.Nil)
+
+	t.Run("invalid alias", func(t *testing.T) {
+		t.Parallel()
+		s := mustMakeStamp(t, repo, rand, uuid.Nil)
+
+		assert.True(t, IsArgError(repo.UpdateStamp(s.ID, UpdateStampArgs{Aliases: []string{"あ"}})))
+	})
+
+	t.Run("alias same as own name", func(t *testing.T) {
+		t.Parallel()
+		s := mustMakeStamp(t, repo, rand, uuid.Nil)
+
+		assert.True(t, IsArgError(repo.UpdateStamp(s.ID, UpdateStampArgs{Aliases: []string{s.Name}})))
+	})
+
+	t.Run("alias conflicts with other stamp name", func(t *testing.T) {
+		t.Parallel()
+		s := mustMakeStamp(t, repo, rand, uuid.Nil)
+
+		assert.EqualError(t, repo.UpdateStamp(s.ID, UpdateStampArgs{Aliases: []string{other.Name}}), ErrAlreadyExists.Error())
+	})
+
+	t.Run("alias conflicts with other stamp alias", func(t *testing.T) {
+		t.Parallel()
+		s1 := mustMakeStamp(t, repo, rand, uuid.Nil)
+		s2 := mustMakeStamp(t, repo, rand, uuid.Nil)
+		alias := random2.AlphaNumeric(20)
+		require.NoError(t, repo.UpdateStamp(s1.ID, UpdateStampArgs{Aliases: []string{alias}}))
+
+		assert.EqualError(t, repo.UpdateStamp(s2.ID, UpdateStampArgs{Aliases: []string{alias}}), ErrAlreadyExists.Error())
+		_, err := repo.CreateStamp(CreateStampArgs{Name: alias, FileID: s2.FileID})
+		assert.EqualError(t, err, ErrAlreadyExists.Error())
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		t.Parallel()
+		s := mustMakeStamp(t, repo, rand, uuid.Nil)
+
+		assert.True(t, IsArgError(repo.UpdateStamp(s.ID, UpdateStampArgs{Tags: []string{" "}})))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assertAndRequire(t)
+		s := mustMakeStamp(t, repo, rand, uuid.Nil)
+		alias1 := random2.AlphaNumeric(20)
+		alias2 := random2.AlphaNumeric(20)
+
+		require.NoError(repo.UpdateStamp(s.ID, UpdateStampArgs{
+			Category: optional.StringFrom("reaction"),
+			Aliases:  []string{alias1, alias2, alias1},
+			Tags:     []string{"hand", " hand ", "ok"},
+		}))
+		a, err := repo.GetStamp(s.ID)
+		require.NoError(err)
+		assert.Equal("reaction", a.Category)
+		assert.ElementsMatch([]string{alias1, alias2}, a.Aliases)
+		assert.ElementsMatch([]string{"hand", "ok"}, a.Tags)
+
+		b, err := repo.GetStampByName(alias2)
+		if assert.NoError(err) {
+			assert.Equal(s.ID, b.ID)
+		}
+
+		// エイリアスを置き換え
+		require.NoError(repo.UpdateStamp(s.ID, UpdateStampArgs{Aliases: []string{alias2}}))
+		_, err = repo.GetStampByName(alias1)
+		assert.EqualError(err, ErrNotFound.Error())
+		a, err = repo.GetStamp(s.ID)
+		require.NoError(err)
+		assert.ElementsMatch([]string{alias2}, a.Aliases)
+		assert.ElementsMatch([]string{"hand", "ok"}, a.Tags)
+	})
+}
+
 func TestRepositoryImpl_GetStamp(t *testing.T) {
 	t.Parallel()
 	repo, _, _ := setup(t, common2)
@@ -302,3 +384,146 @@ func TestRepositoryImpl_GetUserStampHistory(t *testing.T) {
 		}
 	})
 }
+
+func TestRepositoryImpl_SearchStamps(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user, channel := setupWithUserAndChannel(t, common2)
+
+	prefix := random2.AlphaNumeric(10)
+	exact := mustMakeStamp(t, repo, prefix, uuid.Nil)
+	popular := mustMakeStamp(t, repo, prefix+"_popular", uuid.Nil)
+	used := mustMakeStamp(t, repo, prefix+"_used", uuid.Nil)
+	tagged := mustMakeStamp(t, repo, rand, uuid.Nil)
+	require.NoError(t, repo.UpdateStamp(tagged.ID, UpdateStampArgs{Tags: []string{prefix}}))
+	mustMakeStamp(t, repo, rand, uuid.Nil)
+
+	other := mustMakeUser(t, repo, rand)
+	message := mustMakeMessage(t, repo, user.GetID(), channel.ID)
+	mustAddMessageStamp(t, repo, message.ID, used.ID, user.GetID())
+	// 全体での使用回数は集計値から求める
+	require.NoError(t, repo.UpdateStampUsage(popular.ID, channel.ID, other.GetID(), time.Now(), 2))
+	require.NoError(t, repo.UpdateStampUsage(used.ID, channel.ID, user.GetID(), time.Now(), 1))
+	require.NoError(t, repo.UpdateStampUsage(tagged.ID, channel.ID, other.GetID(), time.Now(), 3))
+
+	t.Run("no match", func(t *testing.T) {
+		t.Parallel()
+		stamps, err := repo.SearchStamps(random2.AlphaNumeric(20), user.GetID(), 0)
+		if assert.NoError(t, err) {
+			assert.Empty(t, stamps)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		stamps, err := repo.SearchStamps(prefix, user.GetID(), 0)
+		if assert.NoError(t, err) && assert.Len(t, stamps, 4) {
+			assert.Equal(t, exact.ID, stamps[0].ID)
+			assert.Equal(t, used.ID, stamps[1].ID)
+			assert.Equal(t, popular.ID, stamps[2].ID)
+			assert.Equal(t, tagged.ID, stamps[3].ID)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		t.Parallel()
+		stamps, err := repo.SearchStamps(prefix, user.GetID(), 2)
+		if assert.NoError(t, err) {
+			assert.Len(t, stamps, 2)
+		}
+	})
+}
+
+func TestRepositoryImpl_UpdateStampUsage(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user, channel := setupWithUserAndChannel(t, common2)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.UpdateStampUsage(uuid.Nil, channel.ID, user.GetID(), time.Now(), 1), ErrNilID.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assertAndRequire(t)
+		s := mustMakeStamp(t, repo, rand, uuid.Nil)
+		other := mustMakeUser(t, repo, rand)
+		now := time.Now()
+
+		require.NoError(repo.UpdateStampUsage(s.ID, channel.ID, user.GetID(), now, 1))
+		require.NoError(repo.UpdateStampUsage(s.ID, channel.ID, user.GetID(), now.AddDate(0, 0, -1), 1))
+		require.NoError(repo.UpdateStampUsage(s.ID, channel.ID, other.GetID(), now, 1))
+		require.NoError(repo.UpdateStampUsage(s.ID, channel.ID, other.GetID(), now, -1))
+		require.NoError(repo.UpdateStampUsage(s.ID, channel.ID, other.GetID(), now, -1)) // 0未満にはならない
+
+		stats, err := repo.GetStampStats(s.ID, now.AddDate(0, 0, -2))
+		require.NoError(err)
+		assert.Equal(2, stats.Count)
+		assert.Equal(1, stats.UniqueUsers)
+		if assert.Len(stats.Daily, 3) {
+			assert.Equal(0, stats.Daily[0].Count)
+			assert.Equal(1, stats.Daily[1].Count)
+			assert.Equal(1, stats.Daily[2].Count)
+			assert.Equal(now.UTC().Format("2006-01-02"), stats.Daily[2].Date)
+		}
+	})
+}
+
+func TestRepositoryImpl_GetStampStats(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common2)
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.GetStampStats(uuid.Must(uuid.NewV4()), time.Now())
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+
+	t.Run("no usage", func(t *testing.T) {
+		t.Parallel()
+		s := mustMakeStamp(t, repo, rand, uuid.Nil)
+
+		stats, err := repo.GetStampStats(s.ID, time.Now().AddDate(0, 0, -6))
+		if assert.NoError(t, err) {
+			assert.Equal(t, s.ID, stats.StampID)
+			assert.Equal(t, 0, stats.Count)
+			assert.Equal(t, 0, stats.UniqueUsers)
+			assert.Len(t, stats.Daily, 7)
+		}
+	})
+}
+
+func TestRepositoryImpl_GetStampRanking(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user, channel := setupWithUserAndChannel(t, common2)
+
+	s1 := mustMakeStamp(t, repo, rand, uuid.Nil)
+	s2 := mustMakeStamp(t, repo, rand, uuid.Nil)
+	s3 := mustMakeStamp(t, repo, rand, uuid.Nil)
+	now := time.Now()
+	require.NoError(t, repo.UpdateStampUsage(s1.ID, channel.ID, user.GetID(), now, 1))
+	require.NoError(t, repo.UpdateStampUsage(s2.ID, channel.ID, user.GetID(), now, 3))
+	require.NoError(t, repo.UpdateStampUsage(s3.ID, channel.ID, user.GetID(), now.AddDate(0, 0, -10), 5))
+
+	t.Run("channel", func(t *testing.T) {
+		t.Parallel()
+
+		ranking, err := repo.GetStampRanking(now.AddDate(0, 0, -6), channel.ID, 0)
+		if assert.NoError(t, err) && assert.Len(t, ranking, 2) {
+			assert.Equal(t, s2.ID, ranking[0].StampID)
+			assert.Equal(t, 3, ranking[0].Count)
+			assert.Equal(t, s1.ID, ranking[1].StampID)
+			assert.Equal(t, 1, ranking[1].Count)
+		}
+	})
+
+	t.Run("wider window", func(t *testing.T) {
+		t.Parallel()
+
+		ranking, err := repo.GetStampRanking(now.AddDate(0, 0, -30), channel.ID, 1)
+		if assert.NoError(t, err) && assert.Len(t, ranking, 1) {
+			assert.Equal(t, s3.ID, ranking[0].StampID)
+		}
+	})
+}
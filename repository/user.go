@@ -33,6 +33,11 @@ type UpdateUserArgs struct {
 	Password    optional.String
 }
 
+// UpdateUserSettingsArgs ユーザー設定更新引数
+type UpdateUserSettingsArgs struct {
+	ReadReceipts optional.Bool
+}
+
 // LinkExternalUserAccountArgs 外部アカウント関連付け引数
 type LinkExternalUserAccountArgs struct {
 	ProviderName string
@@ -69,7 +74,7 @@ func (q UsersQuery) CMemberOf(channelID uuid.UUID) UsersQuery {
 	return q
 }
 
-// GMemberOf groupIDグループのメンバーである
+// GMemberOf groupIDグループのメンバーである (子グループのメンバーを含む)
 func (q UsersQuery) GMemberOf(groupID uuid.UUID) UsersQuery {
 	q.IsGMemberOf = optional.UUIDFrom(groupID)
 	return q
@@ -163,4 +168,17 @@ type UserRepository interface {
 	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
 	// DBによるエラーを返すことがあります。
 	UnlinkExternalUserAccount(userID uuid.UUID, providerName string) error
+	// GetUserSettings 指定したユーザーの設定を取得します
+	//
+	// 成功した場合、ユーザー設定とnilを返します。設定を変更していないユーザーの場合は初期設定を返します。
+	// 引数にuuid.Nilを指定した場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetUserSettings(userID uuid.UUID) (*model.UserSettings, error)
+	// UpdateUserSettings 指定したユーザーの設定を更新します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しないユーザーの場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateUserSettings(userID uuid.UUID, args UpdateUserSettingsArgs) error
 }
@@ -11,6 +11,7 @@ type UpdateUserGroupNameArgs struct {
 	Name        optional.String
 	Description optional.String
 	Type        optional.String
+	Source      optional.String
 }
 
 // UserGroupRepository ユーザーグループリポジトリ
@@ -22,6 +23,13 @@ type UserGroupRepository interface {
 	// 既にNameが使われている場合、ErrAlreadyExistsを返します。
 	// DBによるエラーを返すことがあります。
 	CreateUserGroup(name, description, gType string, adminID uuid.UUID) (*model.UserGroup, error)
+	// CreateExternalUserGroup 外部ソースと同期されるユーザーグループを作成します
+	//
+	// 成功した場合、ユーザーグループとnilを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// 既にNameが使われている場合、ErrAlreadyExistsを返します。
+	// DBによるエラーを返すことがあります。
+	CreateExternalUserGroup(name, source string, adminID uuid.UUID) (*model.UserGroup, error)
 	// UpdateUserGroup 指定したユーザーグループを更新します
 	//
 	// 成功した場合、nilを返します。
@@ -52,6 +60,7 @@ type UserGroupRepository interface {
 	GetUserGroupByName(name string) (*model.UserGroup, error)
 	// GetUserBelongingGroupIDs 指定したユーザーが所属しているグループのUUIDを取得します
 	//
+	// 子グループを介して所属しているグループも含みます。
 	// 成功した場合、ユーザーグループのUUIDの配列とnilを返します。
 	// 存在しないユーザーを指定した場合は空配列とnilを返します。
 	// DBによるエラーを返すことがあります。
@@ -90,4 +99,20 @@ type UserGroupRepository interface {
 	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
 	// DBによるエラーを返すことがあります。
 	RemoveUserFromGroupAdmin(userID, groupID uuid.UUID) error
+	// AddChildToGroup 指定したグループの子グループに指定したグループを追加します
+	//
+	// 子グループのメンバーは、親グループのメンバーとしても扱われます。
+	// 成功した、或いは既に追加されている場合、nilを返します。
+	// 存在しないグループの場合、ErrNotFoundを返します。
+	// 追加によってグループが循環する場合、ArgumentErrorを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	AddChildToGroup(childID, groupID uuid.UUID) error
+	// RemoveChildFromGroup 指定したグループの子グループから指定したグループを削除します
+	//
+	// 成功した、或いは既に居ない場合、nilを返します。
+	// 存在しないグループの場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	RemoveChildFromGroup(childID, groupID uuid.UUID) error
 }
@@ -13,12 +13,27 @@ import (
 
 // CreateUserGroup implements UserGroupRepository interface.
 func (repo *GormRepository) CreateUserGroup(name, description, gType string, adminID uuid.UUID) (*model.UserGroup, error) {
-	g := &model.UserGroup{
+	return repo.createUserGroup(&model.UserGroup{
 		ID:          uuid.Must(uuid.NewV4()),
 		Name:        name,
 		Description: description,
 		Type:        gType,
+	}, adminID)
+}
+
+// CreateExternalUserGroup implements UserGroupRepository interface.
+func (repo *GormRepository) CreateExternalUserGroup(name, source string, adminID uuid.UUID) (*model.UserGroup, error) {
+	if len(source) == 0 || utf8.RuneCountInString(source) > 30 {
+		return nil, ArgError("source", "Source must be non-empty and shorter than 31 characters")
 	}
+	return repo.createUserGroup(&model.UserGroup{
+		ID:     uuid.Must(uuid.NewV4()),
+		Name:   name,
+		Source: source,
+	}, adminID)
+}
+
+func (repo *GormRepository) createUserGroup(g *model.UserGroup, adminID uuid.UUID) (*model.UserGroup, error) {
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
 		// 名前チェック
 		if len(g.Name) == 0 || utf8.RuneCountInString(g.Name) > 30 {
@@ -86,6 +101,12 @@ func (repo *GormRepository) UpdateUserGroup(id uuid.UUID, args UpdateUserGroupNa
 			}
 			changes["type"] = args.Type.String
 		}
+		if args.Source.Valid {
+			if utf8.RuneCountInString(args.Source.String) > 30 {
+				return ArgError("args.Source", "Source must be shorter than 31 characters")
+			}
+			changes["source"] = args.Source.String
+		}
 
 		if len(changes) > 0 {
 			return tx.Model(&g).Updates(changes).Error
@@ -100,13 +121,43 @@ func (repo *GormRepository) DeleteUserGroup(id uuid.UUID) error {
 	if id == uuid.Nil {
 		return ErrNilID
 	}
+	var events []hub.Message
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		// 親グループの実質的なメンバーが変化する
+		ancestors, err := traverseUserGroups(tx, false, id)
+		if err != nil {
+			return err
+		}
+		before, err := getUserGroupMemberSnapshot(tx, ancestors)
+		if err != nil {
+			return err
+		}
+		members := before[id]
+		delete(before, id)
+		ancestors = ancestors[1:]
+
+		// グループによるチャンネル購読を解除する
+		var subscribedChannelIDs []uuid.UUID
+		if err := tx.
+			Model(&model.ChannelGroupSubscription{}).
+			Where(&model.ChannelGroupSubscription{GroupID: id}).
+			Pluck("channel_id", &subscribedChannelIDs).
+			Error; err != nil {
+			return err
+		}
+		if err := tx.Where(&model.ChannelGroupSubscription{GroupID: id}).Delete(&model.ChannelGroupSubscription{}).Error; err != nil {
+			return err
+		}
+
 		if err := tx.Where(&model.UserGroupMember{GroupID: id}).Delete(&model.UserGroupMember{}).Error; err != nil {
 			return err
 		}
 		if err := tx.Where(&model.UserGroupAdmin{GroupID: id}).Delete(&model.UserGroupAdmin{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Where("group_id = ? OR child_id = ?", id, id).Delete(&model.UserGroupChild{}).Error; err != nil {
+			return err
+		}
 		result := tx.Delete(&model.UserGroup{ID: id})
 		if result.Error != nil {
 			return result.Error
@@ -114,11 +165,35 @@ func (repo *GormRepository) DeleteUserGroup(id uuid.UUID) error {
 		if result.RowsAffected == 0 {
 			return ErrNotFound
 		}
+
+		after, err := getUserGroupMemberSnapshot(tx, ancestors)
+		if err != nil {
+			return err
+		}
+		events = diffUserGroupMemberSnapshot(before, after)
+
+		for _, channelID := range subscribedChannelIDs {
+			on, off, err := syncAutoChannelSubscriptions(tx, channelID, members)
+			if err != nil {
+				return err
+			}
+			if len(on) > 0 || len(off) > 0 {
+				events = append(events, hub.Message{
+					Name: event.ChannelSubscribersChanged,
+					Fields: hub.Fields{
+						"channel_id": channelID,
+					},
+				})
+			}
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
+	for _, e := range events {
+		repo.hub.Publish(e)
+	}
 	repo.hub.Publish(hub.Message{
 		Name: event.UserGroupDeleted,
 		Fields: hub.Fields{
@@ -154,16 +229,10 @@ func (repo *GormRepository) GetUserGroupByName(name string) (*model.UserGroup, e
 
 // GetUserBelongingGroupIDs implements UserGroupRepository interface.
 func (repo *GormRepository) GetUserBelongingGroupIDs(userID uuid.UUID) ([]uuid.UUID, error) {
-	groups := make([]uuid.UUID, 0)
 	if userID == uuid.Nil {
-		return groups, nil
+		return make([]uuid.UUID, 0), nil
 	}
-	err := repo.db.
-		Model(&model.UserGroupMember{}).
-		Where(&model.UserGroupMember{UserID: userID}).
-		Pluck("group_id", &groups).
-		Error
-	return groups, err
+	return getUserBelongingGroupIDs(repo.db, userID)
 }
 
 // GetAllUserGroups implements UserGroupRepository interface.
@@ -178,7 +247,7 @@ func (repo *GormRepository) AddUserToGroup(userID, groupID uuid.UUID, role strin
 	if userID == uuid.Nil || groupID == uuid.Nil {
 		return ErrNilID
 	}
-	var added bool
+	var events []hub.Message
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
 		var g model.UserGroup
 		if err := tx.Preload("Members").First(&g, &model.UserGroup{ID: groupID}).Error; err != nil {
@@ -190,11 +259,140 @@ func (repo *GormRepository) AddUserToGroup(userID, groupID uuid.UUID, role strin
 				return err
 			}
 		} else {
+			ancestors, err := traverseUserGroups(tx, false, groupID)
+			if err != nil {
+				return err
+			}
+			before, err := getUserGroupMemberSnapshot(tx, ancestors)
+			if err != nil {
+				return err
+			}
 			if err := tx.Create(&model.UserGroupMember{UserID: userID, GroupID: groupID, Role: role}).Error; err != nil {
 				return err
 			}
-			added = true
+			after, err := getUserGroupMemberSnapshot(tx, ancestors)
+			if err != nil {
+				return err
+			}
+
+			// 既に子グループを介してメンバーだった場合も、直接のメンバーが変化したのでイベントを発生させる
+			if _, ok := before[groupID][userID]; ok {
+				delete(before[groupID], userID)
+			}
+			events = diffUserGroupMemberSnapshot(before, after)
+		}
+		return tx.Model(&g).UpdateColumn("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		repo.hub.Publish(e)
+	}
+	return nil
+}
+
+// RemoveUserFromGroup implements UserGroupRepository interface.
+func (repo *GormRepository) RemoveUserFromGroup(userID, groupID uuid.UUID) error {
+	if userID == uuid.Nil || groupID == uuid.Nil {
+		return ErrNilID
+	}
+	var events []hub.Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var g model.UserGroup
+		if err := tx.Scopes(userGroupPreloads).First(&g, &model.UserGroup{ID: groupID}).Error; err != nil {
+			return convertError(err)
+		}
+
+		ancestors, err := traverseUserGroups(tx, false, groupID)
+		if err != nil {
+			return err
+		}
+		before, err := getUserGroupMemberSnapshot(tx, ancestors)
+		if err != nil {
+			return err
+		}
+		result := tx.Delete(&model.UserGroupMember{UserID: userID, GroupID: groupID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		after, err := getUserGroupMemberSnapshot(tx, ancestors)
+		if err != nil {
+			return err
+		}
+
+		// 子グループを介してメンバーであり続ける場合も、直接のメンバーが変化したのでイベントを発生させる
+		if _, ok := after[groupID][userID]; ok {
+			delete(after[groupID], userID)
+		}
+		events = diffUserGroupMemberSnapshot(before, after)
+		return tx.Model(&g).UpdateColumn("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		repo.hub.Publish(e)
+	}
+	return nil
+}
+
+// AddChildToGroup implements UserGroupRepository interface.
+func (repo *GormRepository) AddChildToGroup(childID, groupID uuid.UUID) error {
+	if childID == uuid.Nil || groupID == uuid.Nil {
+		return ErrNilID
+	}
+	var (
+		added  bool
+		events []hub.Message
+	)
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var g model.UserGroup
+		if err := tx.Preload("Children").First(&g, &model.UserGroup{ID: groupID}).Error; err != nil {
+			return convertError(err)
+		}
+		if exists, err := gormutil.RecordExists(tx, &model.UserGroup{ID: childID}); err != nil {
+			return err
+		} else if !exists {
+			return ErrNotFound
+		}
+		for _, c := range g.Children {
+			if c.ChildID == childID {
+				return nil
+			}
+		}
+
+		// 循環チェック
+		descendants, err := traverseUserGroups(tx, true, childID)
+		if err != nil {
+			return err
 		}
+		for _, id := range descendants {
+			if id == groupID {
+				return ArgError("childID", "the group cannot contain itself or its ancestors")
+			}
+		}
+
+		ancestors, err := traverseUserGroups(tx, false, groupID)
+		if err != nil {
+			return err
+		}
+		before, err := getUserGroupMemberSnapshot(tx, ancestors)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&model.UserGroupChild{GroupID: groupID, ChildID: childID}).Error; err != nil {
+			return err
+		}
+		after, err := getUserGroupMemberSnapshot(tx, ancestors)
+		if err != nil {
+			return err
+		}
+		events = diffUserGroupMemberSnapshot(before, after)
+		added = true
 		return tx.Model(&g).UpdateColumn("updated_at", time.Now()).Error
 	})
 	if err != nil {
@@ -202,50 +400,72 @@ func (repo *GormRepository) AddUserToGroup(userID, groupID uuid.UUID, role strin
 	}
 	if added {
 		repo.hub.Publish(hub.Message{
-			Name: event.UserGroupMemberAdded,
+			Name: event.UserGroupChildAdded,
 			Fields: hub.Fields{
 				"group_id": groupID,
-				"user_id":  userID,
+				"child_id": childID,
 			},
 		})
 	}
+	for _, e := range events {
+		repo.hub.Publish(e)
+	}
 	return nil
 }
 
-// RemoveUserFromGroup implements UserGroupRepository interface.
-func (repo *GormRepository) RemoveUserFromGroup(userID, groupID uuid.UUID) error {
-	if userID == uuid.Nil || groupID == uuid.Nil {
+// RemoveChildFromGroup implements UserGroupRepository interface.
+func (repo *GormRepository) RemoveChildFromGroup(childID, groupID uuid.UUID) error {
+	if childID == uuid.Nil || groupID == uuid.Nil {
 		return ErrNilID
 	}
-	var changed bool
+	var (
+		removed bool
+		events  []hub.Message
+	)
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
 		var g model.UserGroup
-		if err := tx.Scopes(userGroupPreloads).First(&g, &model.UserGroup{ID: groupID}).Error; err != nil {
+		if err := tx.First(&g, &model.UserGroup{ID: groupID}).Error; err != nil {
 			return convertError(err)
 		}
 
-		result := tx.Delete(&model.UserGroupMember{UserID: userID, GroupID: groupID})
+		ancestors, err := traverseUserGroups(tx, false, groupID)
+		if err != nil {
+			return err
+		}
+		before, err := getUserGroupMemberSnapshot(tx, ancestors)
+		if err != nil {
+			return err
+		}
+		result := tx.Delete(&model.UserGroupChild{GroupID: groupID, ChildID: childID})
 		if result.Error != nil {
 			return result.Error
 		}
-		if result.RowsAffected > 0 {
-			changed = true
-			return tx.Model(&g).UpdateColumn("updated_at", time.Now()).Error
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		after, err := getUserGroupMemberSnapshot(tx, ancestors)
+		if err != nil {
+			return err
 		}
-		return nil
+		events = diffUserGroupMemberSnapshot(before, after)
+		removed = true
+		return tx.Model(&g).UpdateColumn("updated_at", time.Now()).Error
 	})
 	if err != nil {
 		return err
 	}
-	if changed {
+	if removed {
 		repo.hub.Publish(hub.Message{
-			Name: event.UserGroupMemberRemoved,
+			Name: event.UserGroupChildRemoved,
 			Fields: hub.Fields{
 				"group_id": groupID,
-				"user_id":  userID,
+				"child_id": childID,
 			},
 		})
 	}
+	for _, e := range events {
+		repo.hub.Publish(e)
+	}
 	return nil
 }
 
@@ -301,5 +521,105 @@ func (repo *GormRepository) RemoveUserFromGroupAdmin(userID, groupID uuid.UUID)
 func userGroupPreloads(db *gorm.DB) *gorm.DB {
 	return db.
 		Preload("Admins").
-		Preload("Members")
+		Preload("Members").
+		Preload("Children")
+}
+
+// traverseUserGroups startから辿れる全てのグループのIDを、近い順に返します (start自身を含む)
+//
+// descendがtrueの場合は子グループの方向に、falseの場合は親グループの方向に辿ります。
+func traverseUserGroups(tx *gorm.DB, descend bool, start ...uuid.UUID) ([]uuid.UUID, error) {
+	from, to := "child_id", "group_id"
+	if descend {
+		from, to = "group_id", "child_id"
+	}
+
+	visited := make(map[uuid.UUID]struct{}, len(start))
+	result := make([]uuid.UUID, 0, len(start))
+	frontier := start
+	for len(frontier) > 0 {
+		next := make([]uuid.UUID, 0, len(frontier))
+		for _, id := range frontier {
+			if _, ok := visited[id]; !ok {
+				visited[id] = struct{}{}
+				result = append(result, id)
+				next = append(next, id)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+
+		frontier = nil
+		if err := tx.
+			Model(&model.UserGroupChild{}).
+			Where(from+" IN (?)", next).
+			Pluck(to, &frontier).
+			Error; err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// getUserBelongingGroupIDs 子グループを介したものを含めて、指定したユーザーが所属しているグループのIDを返します
+func getUserBelongingGroupIDs(tx *gorm.DB, userID uuid.UUID) ([]uuid.UUID, error) {
+	direct := make([]uuid.UUID, 0)
+	if err := tx.
+		Model(&model.UserGroupMember{}).
+		Where(&model.UserGroupMember{UserID: userID}).
+		Pluck("group_id", &direct).
+		Error; err != nil {
+		return nil, err
+	}
+	return traverseUserGroups(tx, false, direct...)
+}
+
+// getUserGroupMemberSnapshot 子グループのメンバーを含めて、指定した各グループのメンバーのIDの集合を返します
+func getUserGroupMemberSnapshot(tx *gorm.DB, groupIDs []uuid.UUID) (map[uuid.UUID]map[uuid.UUID]struct{}, error) {
+	snapshot := make(map[uuid.UUID]map[uuid.UUID]struct{}, len(groupIDs))
+	for _, groupID := range groupIDs {
+		descendants, err := traverseUserGroups(tx, true, groupID)
+		if err != nil {
+			return nil, err
+		}
+		var members []uuid.UUID
+		if err := tx.
+			Model(&model.UserGroupMember{}).
+			Where("group_id IN (?)", descendants).
+			Pluck("DISTINCT user_id", &members).
+			Error; err != nil {
+			return nil, err
+		}
+
+		set := make(map[uuid.UUID]struct{}, len(members))
+		for _, id := range members {
+			set[id] = struct{}{}
+		}
+		snapshot[groupID] = set
+	}
+	return snapshot, nil
+}
+
+// diffUserGroupMemberSnapshot 変更前後のグループのメンバーの差分から、メンバーの追加・削除イベントを生成します
+func diffUserGroupMemberSnapshot(before, after map[uuid.UUID]map[uuid.UUID]struct{}) []hub.Message {
+	events := make([]hub.Message, 0)
+	diff := func(name string, a, b map[uuid.UUID]map[uuid.UUID]struct{}) {
+		for groupID, members := range a {
+			for userID := range members {
+				if _, ok := b[groupID][userID]; !ok {
+					events = append(events, hub.Message{
+						Name: name,
+						Fields: hub.Fields{
+							"group_id": groupID,
+							"user_id":  userID,
+						},
+					})
+				}
+			}
+		}
+	}
+	diff(event.UserGroupMemberAdded, after, before)
+	diff(event.UserGroupMemberRemoved, before, after)
+	return events
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/traPtitech/traQ/utils/optional"
 	random2 "github.com/traPtitech/traQ/utils/random"
 	"strings"
@@ -41,6 +42,40 @@ func TestRepositoryImpl_CreateUserGroup(t *testing.T) {
 	})
 }
 
+func TestRepositoryImpl_CreateExternalUserGroup(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user := setupWithUser(t, common3)
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		g, err := repo.CreateExternalUserGroup(random2.AlphaNumeric(20), "ldap", user.GetID())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "ldap", g.Source)
+			assert.True(t, g.IsAdmin(user.GetID()))
+
+			g2, err := repo.GetUserGroup(g.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "ldap", g2.Source)
+			}
+		}
+	})
+
+	t.Run("empty source", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.CreateExternalUserGroup(random2.AlphaNumeric(20), "", user.GetID())
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.CreateExternalUserGroup(strings.Repeat("a", 31), "ldap", user.GetID())
+		assert.Error(t, err)
+	})
+}
+
 func TestRepositoryImpl_UpdateUserGroup(t *testing.T) {
 	t.Parallel()
 	repo, _, _, user := setupWithUser(t, common3)
@@ -290,3 +325,117 @@ func TestRepositoryImpl_RemoveUserFromGroup(t *testing.T) {
 		assert.NoError(t, repo.RemoveUserFromGroup(user.GetID(), g.ID))
 	})
 }
+
+func TestRepositoryImpl_AddChildToGroup(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user := setupWithUser(t, common3)
+
+	g1 := mustMakeUserGroup(t, repo, rand, user.GetID())
+	g2 := mustMakeUserGroup(t, repo, rand, user.GetID())
+	g3 := mustMakeUserGroup(t, repo, rand, user.GetID())
+	require.NoError(t, repo.AddChildToGroup(g2.ID, g1.ID))
+	require.NoError(t, repo.AddChildToGroup(g3.ID, g2.ID))
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.AddChildToGroup(uuid.Nil, g1.ID), ErrNilID.Error())
+		assert.EqualError(t, repo.AddChildToGroup(g1.ID, uuid.Nil), ErrNilID.Error())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.AddChildToGroup(uuid.Must(uuid.NewV4()), g1.ID), ErrNotFound.Error())
+		assert.EqualError(t, repo.AddChildToGroup(g1.ID, uuid.Must(uuid.NewV4())), ErrNotFound.Error())
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		t.Parallel()
+
+		assert.True(t, IsArgError(repo.AddChildToGroup(g1.ID, g1.ID)))
+		assert.True(t, IsArgError(repo.AddChildToGroup(g1.ID, g2.ID)))
+		assert.True(t, IsArgError(repo.AddChildToGroup(g1.ID, g3.ID)))
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NoError(t, repo.AddChildToGroup(g2.ID, g1.ID))
+	})
+}
+
+func TestRepositoryImpl_RemoveChildFromGroup(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user := setupWithUser(t, common3)
+
+	g1 := mustMakeUserGroup(t, repo, rand, user.GetID())
+	g2 := mustMakeUserGroup(t, repo, rand, user.GetID())
+	require.NoError(t, repo.AddChildToGroup(g2.ID, g1.ID))
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.RemoveChildFromGroup(uuid.Nil, g1.ID), ErrNilID.Error())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.RemoveChildFromGroup(g2.ID, uuid.Must(uuid.NewV4())), ErrNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NoError(t, repo.RemoveChildFromGroup(g2.ID, g1.ID))
+		assert.NoError(t, repo.RemoveChildFromGroup(g2.ID, g1.ID))
+
+		g, err := repo.GetUserGroup(g1.ID)
+		if assert.NoError(t, err) {
+			assert.Empty(t, g.Children)
+		}
+	})
+}
+
+func TestRepositoryImpl_NestedUserGroupMembers(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user := setupWithUser(t, common3)
+
+	user2 := mustMakeUser(t, repo, rand)
+	grade := mustMakeUserGroup(t, repo, rand, user.GetID())
+	class := mustMakeUserGroup(t, repo, rand, user.GetID())
+	team := mustMakeUserGroup(t, repo, rand, user.GetID())
+	other := mustMakeUserGroup(t, repo, rand, user.GetID())
+	require.NoError(t, repo.AddChildToGroup(class.ID, grade.ID))
+	require.NoError(t, repo.AddChildToGroup(team.ID, class.ID))
+	mustAddUserToGroup(t, repo, user.GetID(), team.ID)
+	mustAddUserToGroup(t, repo, user2.GetID(), class.ID)
+	mustAddUserToGroup(t, repo, user2.GetID(), other.ID)
+
+	t.Run("GetUserBelongingGroupIDs", func(t *testing.T) {
+		t.Parallel()
+
+		gs, err := repo.GetUserBelongingGroupIDs(user.GetID())
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, gs, []uuid.UUID{grade.ID, class.ID, team.ID})
+		}
+		gs, err = repo.GetUserBelongingGroupIDs(user2.GetID())
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, gs, []uuid.UUID{grade.ID, class.ID, other.ID})
+		}
+	})
+
+	t.Run("GMemberOf", func(t *testing.T) {
+		t.Parallel()
+
+		ids, err := repo.GetUserIDs(UsersQuery{}.GMemberOf(grade.ID))
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, ids, []uuid.UUID{user.GetID(), user2.GetID()})
+		}
+		ids, err = repo.GetUserIDs(UsersQuery{}.GMemberOf(team.ID))
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, ids, []uuid.UUID{user.GetID()})
+		}
+	})
+}
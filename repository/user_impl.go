@@ -113,8 +113,12 @@ func getUser(tx *gorm.DB, withProfile bool, where ...interface{}) (model.UserInf
 
 // GetUsers implements UserRepository interface.
 func (repo *GormRepository) GetUsers(query UsersQuery) (users []model.UserInfo, err error) {
+	tx, err := repo.makeGetUsersTx(query)
+	if err != nil {
+		return nil, err
+	}
 	arr := make([]*model.User, 0)
-	if err = repo.makeGetUsersTx(query).Find(&arr).Error; err != nil {
+	if err = tx.Find(&arr).Error; err != nil {
 		return nil, err
 	}
 
@@ -127,12 +131,16 @@ func (repo *GormRepository) GetUsers(query UsersQuery) (users []model.UserInfo,
 
 // GetUserIDs implements UserRepository interface.
 func (repo *GormRepository) GetUserIDs(query UsersQuery) (ids []uuid.UUID, err error) {
+	tx, err := repo.makeGetUsersTx(query)
+	if err != nil {
+		return nil, err
+	}
 	ids = make([]uuid.UUID, 0)
-	err = repo.makeGetUsersTx(query).Pluck("users.id", &ids).Error
+	err = tx.Pluck("users.id", &ids).Error
 	return ids, err
 }
 
-func (repo *GormRepository) makeGetUsersTx(query UsersQuery) *gorm.DB {
+func (repo *GormRepository) makeGetUsersTx(query UsersQuery) (*gorm.DB, error) {
 	tx := repo.db.Table("users")
 
 	if query.IsActive.Valid {
@@ -155,13 +163,18 @@ func (repo *GormRepository) makeGetUsersTx(query UsersQuery) *gorm.DB {
 		tx = tx.Joins("INNER JOIN users_private_channels ON users_private_channels.user_id = users.id AND users_private_channels.channel_id = ?", query.IsCMemberOf.UUID)
 	}
 	if query.IsGMemberOf.Valid {
-		tx = tx.Joins("INNER JOIN user_group_members ON user_group_members.user_id = users.id AND user_group_members.group_id = ?", query.IsGMemberOf.UUID)
+		// 子グループのメンバーも含む
+		groupIDs, err := traverseUserGroups(repo.db, true, query.IsGMemberOf.UUID)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Joins("INNER JOIN (SELECT DISTINCT user_id FROM user_group_members WHERE group_id IN (?)) AS gm ON gm.user_id = users.id", groupIDs)
 	}
 	if query.EnableProfileLoading {
 		tx = tx.Preload("Profile")
 	}
 
-	return tx
+	return tx, nil
 }
 
 // UserExists implements UserRepository interface.
@@ -340,3 +353,46 @@ func (repo *GormRepository) UnlinkExternalUserAccount(userID uuid.UUID, provider
 	}
 	return nil
 }
+
+// GetUserSettings implements UserRepository interface.
+func (repo *GormRepository) GetUserSettings(userID uuid.UUID) (*model.UserSettings, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var settings model.UserSettings
+	if err := repo.db.First(&settings, &model.UserSettings{UserID: userID}).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return &model.UserSettings{UserID: userID}, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateUserSettings implements UserRepository interface.
+func (repo *GormRepository) UpdateUserSettings(userID uuid.UUID, args UpdateUserSettingsArgs) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if ok, err := gormutil.RecordExists(tx, &model.User{ID: userID}); err != nil {
+			return err
+		} else if !ok {
+			return ErrNotFound
+		}
+
+		var settings model.UserSettings
+		if err := tx.FirstOrCreate(&settings, &model.UserSettings{UserID: userID}).Error; err != nil {
+			return err
+		}
+
+		changes := map[string]interface{}{}
+		if args.ReadReceipts.Valid {
+			changes["read_receipts"] = args.ReadReceipts.Bool
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return tx.Model(&settings).Updates(changes).Error
+	})
+}
@@ -247,3 +247,31 @@ func TestRepositoryImpl_UpdateUser(t *testing.T) {
 		})
 	})
 }
+
+func TestRepositoryImpl_UserSettings(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+
+	_, err := repo.GetUserSettings(uuid.Nil)
+	assert.EqualError(err, ErrNotFound.Error())
+	assert.EqualError(repo.UpdateUserSettings(uuid.Nil, UpdateUserSettingsArgs{}), ErrNilID.Error())
+	assert.EqualError(repo.UpdateUserSettings(uuid.Must(uuid.NewV4()), UpdateUserSettingsArgs{}), ErrNotFound.Error())
+
+	// 初期設定
+	settings, err := repo.GetUserSettings(user.GetID())
+	require.NoError(err)
+	assert.False(settings.ReadReceipts)
+
+	require.NoError(repo.UpdateUserSettings(user.GetID(), UpdateUserSettingsArgs{ReadReceipts: optional.BoolFrom(true)}))
+	settings, err = repo.GetUserSettings(user.GetID())
+	require.NoError(err)
+	assert.True(settings.ReadReceipts)
+
+	// 変更しない項目はそのまま
+	require.NoError(repo.UpdateUserSettings(user.GetID(), UpdateUserSettingsArgs{}))
+	settings, err = repo.GetUserSettings(user.GetID())
+	require.NoError(err)
+	assert.True(settings.ReadReceipts)
+}
@@ -29,12 +29,17 @@ const (
 )
 
 type Provider interface {
-	FetchUserInfo(t *oauth2.Token) (UserInfo, error)
 	LoginHandler(c echo.Context) error
 	CallbackHandler(c echo.Context) error
 	L() *zap.Logger
 }
 
+// OAuth2Provider OAuth2ベースの外部認証プロバイダ
+type OAuth2Provider interface {
+	Provider
+	FetchUserInfo(t *oauth2.Token) (UserInfo, error)
+}
+
 type UserInfo interface {
 	GetProviderName() string
 	GetID() string
@@ -84,7 +89,7 @@ func defaultLoginHandler(sessStore session.Store, oac *oauth2.Config) echo.Handl
 	}
 }
 
-func defaultCallbackHandler(p Provider, oac *oauth2.Config, repo repository.Repository, fm file.Manager, sessStore session.Store, allowSignUp bool) echo.HandlerFunc {
+func defaultCallbackHandler(p OAuth2Provider, oac *oauth2.Config, repo repository.Repository, fm file.Manager, sessStore session.Store, allowSignUp bool) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		if len(c.Request().Header.Get(echo.HeaderAuthorization)) > 0 {
 			return herror.BadRequest("Authorization Header must not be set.")
@@ -118,129 +123,157 @@ func defaultCallbackHandler(p Provider, oac *oauth2.Config, repo repository.Repo
 			return c.String(http.StatusForbidden, "You are not permitted to access traQ")
 		}
 
-		sess, err := sessStore.GetSession(c, false)
-		if err != nil {
-			return herror.InternalServerError(err)
+		if _, err := loginOrLinkUser(c, p, tu, repo, fm, sessStore, allowSignUp); err != nil {
+			return err
 		}
-		if sess != nil {
-			if v, err := sess.Get(accountLinkingFlag); err != nil {
-				return herror.InternalServerError(err)
-			} else if v == true {
-				// アカウント関連付けモード
-				if err := sess.Delete(accountLinkingFlag); err != nil {
-					return herror.InternalServerError(err)
-				}
-				if sess.UserID() == uuid.Nil {
-					return herror.Unauthorized("You are not logged in. Please login.")
-				}
-
-				// ユーザーアカウント状態を確認
-				user, err := repo.GetUser(sess.UserID(), false)
-				if err != nil {
-					return herror.InternalServerError(err)
-				}
-				if !user.IsActive() {
-					return herror.Forbidden("this account is currently suspended")
-				}
-
-				// アカウントにリンク
-				if err := repo.LinkExternalUserAccount(user.GetID(), repository.LinkExternalUserAccountArgs{
-					ProviderName: tu.GetProviderName(),
-					ExternalID:   tu.GetID(),
-					Extra:        model.JSON{"externalName": tu.GetRawName()},
-				}); err != nil {
-					switch err {
-					case repository.ErrAlreadyExists:
-						return herror.BadRequest("this account has already been linked")
-					default:
-						return herror.InternalServerError(err)
-					}
-				}
-				p.L().Info("an external user account has been linked to traQ user",
-					zap.Stringer("id", user.GetID()),
-					zap.String("name", user.GetName()),
-					zap.String("providerName", tu.GetProviderName()),
-					zap.String("externalId", tu.GetID()),
-					zap.String("externalName", tu.GetRawName()))
-
-				return c.Redirect(http.StatusFound, "/") // TODO リダイレクト先を設定画面に
+		return c.Redirect(http.StatusFound, "/") // TODO アカウント関連付け時のリダイレクト先を設定画面に
+	}
+}
+
+// loginOrLinkUser 外部ユーザー情報を元に、アカウント関連付けまたはログイン(必要ならユーザー登録)を行います
+//
+// 成功した場合、対象のtraQユーザーとnilを返します。
+// 失敗した場合、レスポンスとして返すべきエラーを返します。
+func loginOrLinkUser(c echo.Context, p Provider, tu UserInfo, repo repository.Repository, fm file.Manager, sessStore session.Store, allowSignUp bool) (model.UserInfo, error) {
+	sess, err := sessStore.GetSession(c, false)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	if sess != nil {
+		if v, err := sess.Get(accountLinkingFlag); err != nil {
+			return nil, herror.InternalServerError(err)
+		} else if v == true {
+			// アカウント関連付けモード
+			if err := sess.Delete(accountLinkingFlag); err != nil {
+				return nil, herror.InternalServerError(err)
+			}
+			if sess.UserID() == uuid.Nil {
+				return nil, herror.Unauthorized("You are not logged in. Please login.")
 			}
+			return linkExternalUser(p, tu, repo, sess.UserID())
 		}
+	}
+	return loginExternalUser(c, p, tu, repo, fm, sessStore, allowSignUp)
+}
 
-		// ログインモード
+// linkExternalUser 外部ユーザー情報を指定したtraQユーザーに関連付けます
+//
+// 成功した場合、対象のtraQユーザーとnilを返します。
+// 失敗した場合、レスポンスとして返すべきエラーを返します。
+func linkExternalUser(p Provider, tu UserInfo, repo repository.Repository, userID uuid.UUID) (model.UserInfo, error) {
+	// ユーザーアカウント状態を確認
+	user, err := repo.GetUser(userID, false)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	if !user.IsActive() {
+		return nil, herror.Forbidden("this account is currently suspended")
+	}
 
-		// ログインしていないことを確認
-		if sess != nil && sess.UserID() != uuid.Nil {
-			return herror.BadRequest("You have already logged in. Please logout once.")
+	// アカウントにリンク
+	if err := repo.LinkExternalUserAccount(user.GetID(), repository.LinkExternalUserAccountArgs{
+		ProviderName: tu.GetProviderName(),
+		ExternalID:   tu.GetID(),
+		Extra:        model.JSON{"externalName": tu.GetRawName()},
+	}); err != nil {
+		switch err {
+		case repository.ErrAlreadyExists:
+			return nil, herror.BadRequest("this account has already been linked")
+		default:
+			return nil, herror.InternalServerError(err)
 		}
+	}
+	p.L().Info("an external user account has been linked to traQ user",
+		zap.Stringer("id", user.GetID()),
+		zap.String("name", user.GetName()),
+		zap.String("providerName", tu.GetProviderName()),
+		zap.String("externalId", tu.GetID()),
+		zap.String("externalName", tu.GetRawName()))
+
+	return user, nil
+}
 
-		user, err := repo.GetUserByExternalID(tu.GetProviderName(), tu.GetID(), false)
-		if err != nil {
-			if err != repository.ErrNotFound {
-				return herror.InternalServerError(err)
-			}
+// loginExternalUser 外部ユーザー情報を元にログイン(必要ならユーザー登録)を行います
+//
+// 成功した場合、対象のtraQユーザーとnilを返します。
+// 失敗した場合、レスポンスとして返すべきエラーを返します。
+func loginExternalUser(c echo.Context, p Provider, tu UserInfo, repo repository.Repository, fm file.Manager, sessStore session.Store, allowSignUp bool) (model.UserInfo, error) {
+	sess, err := sessStore.GetSession(c, false)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
 
-			if !allowSignUp {
-				return herror.Unauthorized("You are not a member of traQ")
-			}
+	// ログインしていないことを確認
+	if sess != nil && sess.UserID() != uuid.Nil {
+		return nil, herror.BadRequest("You have already logged in. Please logout once.")
+	}
 
-			args := repository.CreateUserArgs{
-				Name:        tu.GetName(),
-				DisplayName: tu.GetDisplayName(),
-				Role:        role.User,
-				ExternalLogin: &model.ExternalProviderUser{
-					ProviderName: tu.GetProviderName(),
-					ExternalID:   tu.GetID(),
-					Extra:        model.JSON{"externalName": tu.GetRawName()},
-				},
-			}
+	user, err := repo.GetUserByExternalID(tu.GetProviderName(), tu.GetID(), false)
+	if err != nil {
+		if err != repository.ErrNotFound {
+			return nil, herror.InternalServerError(err)
+		}
 
-			if b, err := tu.GetProfileImage(); err == nil && b != nil {
-				fid, err := processProfileIcon(fm, b)
-				if err == nil {
-					args.IconFileID = fid
-				}
-			}
-			if args.IconFileID == uuid.Nil {
-				fid, err := file.GenerateIconFile(fm, tu.GetName())
-				if err != nil {
-					return herror.InternalServerError(err)
-				}
+		if !allowSignUp {
+			return nil, herror.Unauthorized("You are not a member of traQ")
+		}
+
+		args := repository.CreateUserArgs{
+			Name:        tu.GetName(),
+			DisplayName: tu.GetDisplayName(),
+			Role:        role.User,
+			ExternalLogin: &model.ExternalProviderUser{
+				ProviderName: tu.GetProviderName(),
+				ExternalID:   tu.GetID(),
+				Extra:        model.JSON{"externalName": tu.GetRawName()},
+			},
+		}
+
+		if b, err := tu.GetProfileImage(); err == nil && b != nil {
+			fid, err := processProfileIcon(fm, b)
+			if err == nil {
 				args.IconFileID = fid
 			}
-
-			user, err = repo.CreateUser(args)
+		}
+		if args.IconFileID == uuid.Nil {
+			fid, err := file.GenerateIconFile(fm, tu.GetName())
 			if err != nil {
-				if err == repository.ErrAlreadyExists {
-					return herror.Conflict("name conflicts") // TODO 名前被りをどうするか
-				}
-				return herror.InternalServerError(err)
+				return nil, herror.InternalServerError(err)
 			}
-			p.L().Info("New user was created by external auth",
-				zap.Stringer("id", user.GetID()),
-				zap.String("name", user.GetName()),
-				zap.String("providerName", tu.GetProviderName()),
-				zap.String("externalId", tu.GetID()),
-				zap.String("externalName", tu.GetRawName()))
-		}
-
-		// ユーザーのアカウント状態の確認
-		if !user.IsActive() {
-			return herror.Forbidden("this account is currently suspended")
+			args.IconFileID = fid
 		}
 
-		if _, err := sessStore.RenewSession(c, user.GetID()); err != nil {
-			return herror.InternalServerError(err)
+		user, err = repo.CreateUser(args)
+		if err != nil {
+			if err == repository.ErrAlreadyExists {
+				return nil, herror.Conflict("name conflicts") // TODO 名前被りをどうするか
+			}
+			return nil, herror.InternalServerError(err)
 		}
-		p.L().Info("User was logged in by external auth",
+		p.L().Info("New user was created by external auth",
 			zap.Stringer("id", user.GetID()),
 			zap.String("name", user.GetName()),
 			zap.String("providerName", tu.GetProviderName()),
 			zap.String("externalId", tu.GetID()),
 			zap.String("externalName", tu.GetRawName()))
+	}
+
+	// ユーザーのアカウント状態の確認
+	if !user.IsActive() {
+		return nil, herror.Forbidden("this account is currently suspended")
+	}
 
-		return c.Redirect(http.StatusFound, "/")
+	if _, err := sessStore.RenewSession(c, user.GetID()); err != nil {
+		return nil, herror.InternalServerError(err)
 	}
+	p.L().Info("User was logged in by external auth",
+		zap.Stringer("id", user.GetID()),
+		zap.String("name", user.GetName()),
+		zap.String("providerName", tu.GetProviderName()),
+		zap.String("externalId", tu.GetID()),
+		zap.String("externalName", tu.GetRawName()))
+
+	return user, nil
 }
 
 func processProfileIcon(m file.Manager, src []byte) (uuid.UUID, error) {
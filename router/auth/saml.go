@@ -0,0 +1,464 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/file"
+	"go.uber.org/zap"
+	"golang.org/x/exp/utf8string"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SAMLProviderName       = "saml"
+	samlRequestErrorFormat = "saml request error: %w"
+	samlCookieName         = "traq_ext_auth_saml_cookie"
+	// SAMLGroupSource SAMLアサーションと同期されたユーザーグループのソース名
+	SAMLGroupSource = "saml"
+)
+
+var samlNameInvalidCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+type SAMLProvider struct {
+	config    SAMLProviderConfig
+	repo      repository.Repository
+	fm        file.Manager
+	logger    *zap.Logger
+	sessStore session.Store
+	sp        *saml.ServiceProvider
+	// stateKey SAML Cookieの署名鍵
+	stateKey []byte
+}
+
+type SAMLProviderConfig struct {
+	// Origin traQのオリジン
+	Origin string
+	// EntityID SPのEntityID (空の場合はメタデータURL)
+	EntityID string
+	// IdPMetadataURL IdPのメタデータURL
+	IdPMetadataURL string
+	// CertFile SPの証明書ファイル(PEM)
+	CertFile string
+	// KeyFile SPのRSA秘密鍵ファイル(PEM)
+	KeyFile string
+	// NameAttribute traQユーザー名にマッピングする属性名 (空の場合はNameID)
+	NameAttribute string
+	// DisplayNameAttribute traQ表示名にマッピングする属性名
+	DisplayNameAttribute string
+	// GroupsAttribute traQユーザーグループにマッピングする属性名
+	GroupsAttribute string
+	// GroupAdminName 作成されるユーザーグループの管理者ユーザー名 (空の場合はtraq)
+	GroupAdminName string
+	// RegisterUserIfNotFound ユーザーが見つからなかった場合に登録するかどうか
+	RegisterUserIfNotFound bool
+}
+
+func (c SAMLProviderConfig) Valid() bool {
+	return len(c.Origin) > 0 && len(c.IdPMetadataURL) > 0 && len(c.CertFile) > 0 && len(c.KeyFile) > 0
+}
+
+// samlState SAML認証リクエストの状態
+//
+// IdPからのPOSTにはtraQのセッションCookieが付かないため、
+// アカウント関連付けの対象ユーザーはAuthnRequestのIDと共に署名付きでSAML Cookieに保存します
+type samlState struct {
+	requestID string
+	// linkUserID アカウント関連付けの対象ユーザーのID (ログインモードの場合はuuid.Nil)
+	linkUserID uuid.UUID
+	expiresAt  time.Time
+}
+
+type samlUserInfo struct {
+	nameID      string
+	name        string
+	displayName string
+	groups      []string
+}
+
+func (u *samlUserInfo) GetProviderName() string {
+	return SAMLProviderName
+}
+
+func (u *samlUserInfo) GetID() string {
+	return u.nameID
+}
+
+func (u *samlUserInfo) GetRawName() string {
+	return u.name
+}
+
+func (u *samlUserInfo) GetName() string {
+	s := strings.Split(u.name, "@")[0]
+	s = samlNameInvalidCharsRegex.ReplaceAllLiteralString(s, "_")
+	if us := utf8string.NewString(s); us.RuneCount() > 32 {
+		s = us.Slice(0, 32)
+	}
+	return s
+}
+
+func (u *samlUserInfo) GetDisplayName() string {
+	if s := utf8string.NewString(u.displayName); s.RuneCount() > 64 {
+		return s.Slice(0, 64)
+	}
+	return u.displayName
+}
+
+func (u *samlUserInfo) GetProfileImage() ([]byte, error) {
+	return nil, nil
+}
+
+func (u *samlUserInfo) IsLoginAllowedUser() bool {
+	return true // TODO
+}
+
+func NewSAMLProvider(repo repository.Repository, fm file.Manager, logger *zap.Logger, sessStore session.Store, config SAMLProviderConfig) (*SAMLProvider, error) {
+	keyPair, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saml key pair: %w", err)
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse saml certificate: %w", err)
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("saml private key must be a RSA key")
+	}
+
+	idpMetadataURL, err := url.Parse(config.IdPMetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid idp metadata url: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	idpMetadata, err := samlsp.FetchMetadata(ctx, http.DefaultClient, *idpMetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch idp metadata: %w", err)
+	}
+
+	origin, err := url.Parse(config.Origin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid origin: %w", err)
+	}
+
+	// SAML Cookieの署名鍵はSPの秘密鍵から導出する (全てのノードで同じ鍵になる)
+	stateKey := sha256.Sum256(append([]byte("traq-saml-state:"), x509.MarshalPKCS1PrivateKey(key)...))
+
+	return &SAMLProvider{
+		repo:      repo,
+		fm:        fm,
+		config:    config,
+		logger:    logger,
+		sessStore: sessStore,
+		stateKey:  stateKey[:],
+		sp: &saml.ServiceProvider{
+			EntityID:          config.EntityID,
+			Key:               key,
+			Certificate:       cert,
+			MetadataURL:       *origin.ResolveReference(&url.URL{Path: "/api/auth/saml/metadata"}),
+			AcsURL:            *origin.ResolveReference(&url.URL{Path: "/api/auth/saml/callback"}),
+			IDPMetadata:       idpMetadata,
+			AuthnNameIDFormat: saml.PersistentNameIDFormat,
+			SignatureMethod:   dsig.RSASHA256SignatureMethod,
+		},
+	}, nil
+}
+
+// MetadataHandler SPメタデータを返します
+func (p *SAMLProvider) MetadataHandler(c echo.Context) error {
+	b, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.Blob(http.StatusOK, "application/samlmetadata+xml", b)
+}
+
+func (p *SAMLProvider) LoginHandler(c echo.Context) error {
+	if len(c.Request().Header.Get(echo.HeaderAuthorization)) > 0 {
+		return herror.BadRequest("Authorization Header must not be set.")
+	}
+
+	sess, err := p.sessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	var linkUserID uuid.UUID
+	if isTrue(c.QueryParam("link")) {
+		// アカウント関連付けモード
+		if sess == nil || sess.UserID() == uuid.Nil {
+			return herror.Unauthorized("You are not logged in. Please login.")
+		}
+		linkUserID = sess.UserID()
+	} else {
+		// ログインモード
+		if sess != nil && sess.UserID() != uuid.Nil {
+			return herror.BadRequest("You have already logged in. Please logout once.")
+		}
+	}
+
+	// 署名付きAuthnRequestはHTTP-POSTバインディングを優先
+	binding := saml.HTTPPostBinding
+	location := p.sp.GetSSOBindingLocation(binding)
+	if len(location) == 0 {
+		binding = saml.HTTPRedirectBinding
+		location = p.sp.GetSSOBindingLocation(binding)
+	}
+	if len(location) == 0 {
+		return herror.InternalServerError(errors.New("idp has no available sso binding"))
+	}
+	req, err := p.sp.MakeAuthenticationRequest(location)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	// IdPからのPOSTはクロスサイトなのでSameSite=Noneにする必要がある
+	expiresAt := time.Now().Add(cookieMaxAge * time.Second)
+	c.SetCookie(&http.Cookie{
+		Name:     samlCookieName,
+		Value:    p.encodeState(samlState{requestID: req.ID, linkUserID: linkUserID, expiresAt: expiresAt}),
+		Path:     "/api/auth/saml",
+		Expires:  expiresAt,
+		MaxAge:   cookieMaxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(p.config.Origin, "https://"),
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	if binding == saml.HTTPRedirectBinding {
+		return c.Redirect(http.StatusFound, req.Redirect("").String())
+	}
+	return c.HTMLBlob(http.StatusOK, append([]byte("<!DOCTYPE html><html><body>"), append(req.Post(""), []byte("</body></html>")...)...))
+}
+
+func (p *SAMLProvider) CallbackHandler(c echo.Context) error {
+	cookie, err := c.Cookie(samlCookieName)
+	if err != nil {
+		return herror.BadRequest("missing cookie")
+	}
+	c.SetCookie(&http.Cookie{
+		Name:   samlCookieName,
+		Path:   "/api/auth/saml",
+		MaxAge: -1,
+	})
+
+	state, err := p.decodeState(cookie.Value)
+	if err != nil {
+		return herror.BadRequest("invalid cookie")
+	}
+
+	if err := c.Request().ParseForm(); err != nil {
+		return herror.BadRequest(err)
+	}
+	assertion, err := p.sp.ParseResponse(c.Request(), []string{state.requestID})
+	if err != nil {
+		if ire, ok := err.(*saml.InvalidResponseError); ok {
+			p.L().Info("invalid saml response", zap.Error(ire.PrivateErr))
+		}
+		return herror.BadRequest("invalid saml response")
+	}
+
+	tu, err := p.extractUserInfo(assertion)
+	if err != nil {
+		return herror.BadRequest(err)
+	}
+
+	if !tu.IsLoginAllowedUser() {
+		return c.String(http.StatusForbidden, "You are not permitted to access traQ")
+	}
+
+	var user model.UserInfo
+	if state.linkUserID != uuid.Nil {
+		// アカウント関連付けモード
+		user, err = linkExternalUser(p, tu, p.repo, state.linkUserID)
+	} else {
+		// ログインモード
+		user, err = loginExternalUser(c, p, tu, p.repo, p.fm, p.sessStore, p.config.RegisterUserIfNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(p.config.GroupsAttribute) > 0 {
+		if err := p.syncUserGroups(user.GetID(), tu.groups); err != nil {
+			p.L().Warn("failed to sync user groups by saml assertion", zap.Stringer("id", user.GetID()), zap.Error(err))
+		}
+	}
+
+	return c.Redirect(http.StatusFound, "/")
+}
+
+func (p *SAMLProvider) extractUserInfo(assertion *saml.Assertion) (*samlUserInfo, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || len(assertion.Subject.NameID.Value) == 0 {
+		return nil, fmt.Errorf(samlRequestErrorFormat, errors.New("missing NameID"))
+	}
+
+	ui := &samlUserInfo{nameID: assertion.Subject.NameID.Value}
+	ui.name = ui.nameID
+	if v := samlAttributeValues(assertion, p.config.NameAttribute); len(v) > 0 {
+		ui.name = v[0]
+	}
+	ui.displayName = ui.name
+	if v := samlAttributeValues(assertion, p.config.DisplayNameAttribute); len(v) > 0 {
+		ui.displayName = v[0]
+	}
+	ui.groups = samlAttributeValues(assertion, p.config.GroupsAttribute)
+	return ui, nil
+}
+
+// syncUserGroups アサーションのグループ属性に合わせて、SAMLで作成されたユーザーグループのメンバーを同期します
+//
+// 同名のSAML以外のユーザーグループは同期の対象外です
+func (p *SAMLProvider) syncUserGroups(userID uuid.UUID, names []string) error {
+	desired := make(map[string]bool, len(names))
+	for _, name := range names {
+		desired[name] = true
+	}
+
+	groups, err := p.repo.GetAllUserGroups()
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		exists[g.Name] = true
+		if g.Source != SAMLGroupSource {
+			if desired[g.Name] {
+				p.L().Warn("saml group was skipped: a non-saml group with the same name exists", zap.String("name", g.Name))
+			}
+			continue
+		}
+
+		switch member := g.IsMember(userID); {
+		case desired[g.Name] && !member:
+			if err := p.repo.AddUserToGroup(userID, g.ID, ""); err != nil {
+				return err
+			}
+		case !desired[g.Name] && member:
+			if err := p.repo.RemoveUserFromGroup(userID, g.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	var admin model.UserInfo
+	for _, name := range names {
+		if exists[name] {
+			continue
+		}
+		exists[name] = true
+
+		if admin == nil {
+			admin, err = p.repo.GetUserByName(p.groupAdminName(), false)
+			if err != nil {
+				return fmt.Errorf("failed to get group admin user: %w", err)
+			}
+		}
+		g, err := p.repo.CreateExternalUserGroup(name, SAMLGroupSource, admin.GetID())
+		if err != nil {
+			p.L().Warn("failed to create saml group", zap.String("name", name), zap.Error(err))
+			continue
+		}
+		p.L().Info("saml group was created", zap.String("name", name), zap.Stringer("id", g.ID))
+		if err := p.repo.AddUserToGroup(userID, g.ID, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *SAMLProvider) groupAdminName() string {
+	if len(p.config.GroupAdminName) == 0 {
+		return "traq"
+	}
+	return p.config.GroupAdminName
+}
+
+// encodeState SAML Cookieの値を生成します
+func (p *SAMLProvider) encodeState(s samlState) string {
+	payload := strings.Join([]string{s.requestID, s.linkUserID.String(), strconv.FormatInt(s.expiresAt.Unix(), 10)}, ".")
+	return payload + "." + base64.RawURLEncoding.EncodeToString(p.signState(payload))
+}
+
+// decodeState SAML Cookieの値の署名と有効期限を検証し、状態を取り出します
+func (p *SAMLProvider) decodeState(v string) (samlState, error) {
+	i := strings.LastIndex(v, ".")
+	if i < 0 {
+		return samlState{}, errors.New("malformed saml state")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(v[i+1:])
+	if err != nil || !hmac.Equal(sig, p.signState(v[:i])) {
+		return samlState{}, errors.New("invalid saml state signature")
+	}
+
+	parts := strings.Split(v[:i], ".")
+	if len(parts) != 3 {
+		return samlState{}, errors.New("malformed saml state")
+	}
+	linkUserID, err := uuid.FromString(parts[1])
+	if err != nil {
+		return samlState{}, err
+	}
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return samlState{}, err
+	}
+	s := samlState{requestID: parts[0], linkUserID: linkUserID, expiresAt: time.Unix(exp, 0)}
+	if time.Now().After(s.expiresAt) {
+		return samlState{}, errors.New("saml state has expired")
+	}
+	return s, nil
+}
+
+func (p *SAMLProvider) signState(payload string) []byte {
+	mac := hmac.New(sha256.New, p.stateKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func (p *SAMLProvider) L() *zap.Logger {
+	return p.logger
+}
+
+// samlAttributeValues アサーションから指定した名前(Name or FriendlyName)の属性値を全て取り出します
+func samlAttributeValues(assertion *saml.Assertion, name string) []string {
+	if len(name) == 0 {
+		return nil
+	}
+	var result []string
+	for _, stmt := range assertion.AttributeStatements {
+		for _, attr := range stmt.Attributes {
+			if attr.Name != name && attr.FriendlyName != name {
+				continue
+			}
+			for _, v := range attr.Values {
+				if len(v.Value) > 0 {
+					result = append(result, v.Value)
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,595 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"github.com/beevik/etree"
+	"github.com/crewjam/saml"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/file"
+	"go.uber.org/zap"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type samlTestRepository struct {
+	repository.Repository
+	users    map[uuid.UUID]*model.User
+	external map[string]uuid.UUID
+	groups   []*model.UserGroup
+}
+
+func (r *samlTestRepository) GetUser(id uuid.UUID, _ bool) (model.UserInfo, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *samlTestRepository) GetUserByName(name string, _ bool) (model.UserInfo, error) {
+	for _, u := range r.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *samlTestRepository) GetUserByExternalID(providerName, externalID string, _ bool) (model.UserInfo, error) {
+	id, ok := r.external[providerName+":"+externalID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return r.GetUser(id, false)
+}
+
+func (r *samlTestRepository) CreateUser(args repository.CreateUserArgs) (model.UserInfo, error) {
+	if _, err := r.GetUserByName(args.Name, false); err == nil {
+		return nil, repository.ErrAlreadyExists
+	}
+	u := &model.User{
+		ID:          uuid.Must(uuid.NewV4()),
+		Name:        args.Name,
+		DisplayName: args.DisplayName,
+		Icon:        args.IconFileID,
+		Status:      model.UserAccountStatusActive,
+		Role:        args.Role,
+	}
+	r.users[u.ID] = u
+	if args.ExternalLogin != nil {
+		r.external[args.ExternalLogin.ProviderName+":"+args.ExternalLogin.ExternalID] = u.ID
+	}
+	return u, nil
+}
+
+func (r *samlTestRepository) LinkExternalUserAccount(userID uuid.UUID, args repository.LinkExternalUserAccountArgs) error {
+	key := args.ProviderName + ":" + args.ExternalID
+	if _, ok := r.external[key]; ok {
+		return repository.ErrAlreadyExists
+	}
+	r.external[key] = userID
+	return nil
+}
+
+func (r *samlTestRepository) GetAllUserGroups() ([]*model.UserGroup, error) {
+	return r.groups, nil
+}
+
+func (r *samlTestRepository) CreateExternalUserGroup(name, source string, adminID uuid.UUID) (*model.UserGroup, error) {
+	g := &model.UserGroup{
+		ID:      uuid.Must(uuid.NewV4()),
+		Name:    name,
+		Source:  source,
+		Admins:  []*model.UserGroupAdmin{{UserID: adminID}},
+		Members: make([]*model.UserGroupMember, 0),
+	}
+	g.Admins[0].GroupID = g.ID
+	r.groups = append(r.groups, g)
+	return g, nil
+}
+
+func (r *samlTestRepository) AddUserToGroup(userID, groupID uuid.UUID, role string) error {
+	for _, g := range r.groups {
+		if g.ID == groupID {
+			g.Members = append(g.Members, &model.UserGroupMember{GroupID: groupID, UserID: userID, Role: role})
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *samlTestRepository) RemoveUserFromGroup(userID, groupID uuid.UUID) error {
+	for _, g := range r.groups {
+		if g.ID != groupID {
+			continue
+		}
+		for i, m := range g.Members {
+			if m.UserID == userID {
+				g.Members = append(g.Members[:i], g.Members[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *samlTestRepository) groupMembers(name string) []uuid.UUID {
+	result := make([]uuid.UUID, 0)
+	for _, g := range r.groups {
+		if g.Name == name {
+			for _, m := range g.Members {
+				result = append(result, m.UserID)
+			}
+		}
+	}
+	return result
+}
+
+type samlTestFile struct {
+	model.File
+	id uuid.UUID
+}
+
+func (f *samlTestFile) GetID() uuid.UUID {
+	return f.id
+}
+
+type samlTestFileManager struct {
+	file.Manager
+}
+
+func (m *samlTestFileManager) Save(file.SaveArgs) (model.File, error) {
+	return &samlTestFile{id: uuid.Must(uuid.NewV4())}, nil
+}
+
+type samlTestEnv struct {
+	p         *SAMLProvider
+	idp       *saml.IdentityProvider
+	repo      *samlTestRepository
+	sessStore session.Store
+	e         *echo.Echo
+}
+
+func newTestCertificate(t *testing.T, cn string) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return key, cert
+}
+
+func mustParseURL(t *testing.T, s string) url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	require.NoError(t, err)
+	return *u
+}
+
+func setupSAML(t *testing.T, config SAMLProviderConfig) *samlTestEnv {
+	t.Helper()
+
+	idpKey, idpCert := newTestCertificate(t, "idp.example.com")
+	idp := &saml.IdentityProvider{
+		Key:             idpKey,
+		Certificate:     idpCert,
+		MetadataURL:     mustParseURL(t, "https://idp.example.com/metadata"),
+		SSOURL:          mustParseURL(t, "https://idp.example.com/sso"),
+		SignatureMethod: dsig.RSASHA256SignatureMethod,
+	}
+
+	spKey, spCert := newTestCertificate(t, "traq.example.com")
+	repo := &samlTestRepository{
+		users:    map[uuid.UUID]*model.User{},
+		external: map[string]uuid.UUID{},
+	}
+	sessStore := session.NewMemorySessionStore()
+	p := &SAMLProvider{
+		config:    config,
+		repo:      repo,
+		fm:        &samlTestFileManager{},
+		logger:    zap.NewNop(),
+		sessStore: sessStore,
+		stateKey:  []byte("test-state-key"),
+		sp: &saml.ServiceProvider{
+			Key:               spKey,
+			Certificate:       spCert,
+			MetadataURL:       mustParseURL(t, "https://traq.example.com/api/auth/saml/metadata"),
+			AcsURL:            mustParseURL(t, "https://traq.example.com/api/auth/saml/callback"),
+			IDPMetadata:       idp.Metadata(),
+			AuthnNameIDFormat: saml.PersistentNameIDFormat,
+			SignatureMethod:   dsig.RSASHA256SignatureMethod,
+		},
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = extension.ErrorHandler(zap.NewNop())
+	e.GET("/api/auth/saml", p.LoginHandler)
+	e.POST("/api/auth/saml/callback", p.CallbackHandler)
+	return &samlTestEnv{p: p, idp: idp, repo: repo, sessStore: sessStore, e: e}
+}
+
+func (env *samlTestEnv) createUser(t *testing.T, name string) *model.User {
+	t.Helper()
+	u, err := env.repo.CreateUser(repository.CreateUserArgs{Name: name, DisplayName: name})
+	require.NoError(t, err)
+	return u.(*model.User)
+}
+
+// response 指定したAuthnRequestに対するIdPの署名付きSAMLレスポンスを生成します
+func (env *samlTestEnv) response(t *testing.T, requestID string, s *saml.Session) string {
+	t.Helper()
+	spMetadata := env.p.sp.Metadata()
+	req := &saml.IdpAuthnRequest{
+		IDP:                     env.idp,
+		HTTPRequest:             httptest.NewRequest(http.MethodPost, "/sso", nil),
+		Request:                 saml.AuthnRequest{ID: requestID, IssueInstant: saml.TimeNow()},
+		ServiceProviderMetadata: spMetadata,
+		SPSSODescriptor:         &spMetadata.SPSSODescriptors[0],
+		ACSEndpoint:             &saml.IndexedEndpoint{Binding: saml.HTTPPostBinding, Location: env.p.sp.AcsURL.String()},
+		Now:                     saml.TimeNow(),
+	}
+	require.NoError(t, saml.DefaultAssertionMaker{}.MakeAssertion(req, s))
+	require.NoError(t, req.MakeResponse())
+
+	doc := etree.NewDocument()
+	doc.SetRoot(req.ResponseEl)
+	b, err := doc.WriteToBytes()
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// callback SAMLレスポンスをコールバックにPOSTします
+func (env *samlTestEnv) callback(cookie string, samlResponse string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	form.Set("SAMLResponse", samlResponse)
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/saml/callback", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	if len(cookie) > 0 {
+		req.AddCookie(&http.Cookie{Name: samlCookieName, Value: cookie})
+	}
+	rec := httptest.NewRecorder()
+	env.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func findCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == name && c.MaxAge >= 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSAMLProvider_extractUserInfo(t *testing.T) {
+	t.Parallel()
+
+	p := &SAMLProvider{config: SAMLProviderConfig{
+		NameAttribute:        "uid",
+		DisplayNameAttribute: "urn:oid:2.5.4.3",
+		GroupsAttribute:      "groups",
+	}}
+	attr := func(name, friendlyName string, values ...string) saml.Attribute {
+		a := saml.Attribute{Name: name, FriendlyName: friendlyName}
+		for _, v := range values {
+			a.Values = append(a.Values, saml.AttributeValue{Value: v})
+		}
+		return a
+	}
+
+	t.Run("attributes", func(t *testing.T) {
+		t.Parallel()
+		ui, err := p.extractUserInfo(&saml.Assertion{
+			Subject: &saml.Subject{NameID: &saml.NameID{Value: "nameid"}},
+			AttributeStatements: []saml.AttributeStatement{
+				{Attributes: []saml.Attribute{
+					// FriendlyNameでも一致する
+					attr("urn:oid:0.9.2342.19200300.100.1.1", "uid", "user@example.com"),
+					attr("urn:oid:2.5.4.3", "cn", "ユーザー"),
+					attr("groups", "", "a", "", "b"),
+				}},
+				{Attributes: []saml.Attribute{attr("groups", "", "c")}},
+			},
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "nameid", ui.GetID())
+			assert.Equal(t, "user@example.com", ui.GetRawName())
+			assert.Equal(t, "user", ui.GetName())
+			assert.Equal(t, "ユーザー", ui.GetDisplayName())
+			assert.Equal(t, []string{"a", "b", "c"}, ui.groups)
+			assert.Equal(t, SAMLProviderName, ui.GetProviderName())
+		}
+	})
+
+	t.Run("without attributes", func(t *testing.T) {
+		t.Parallel()
+		ui, err := p.extractUserInfo(&saml.Assertion{
+			Subject: &saml.Subject{NameID: &saml.NameID{Value: "name.id+" + strings.Repeat("a", 40)}},
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "name.id+"+strings.Repeat("a", 40), ui.GetRawName())
+			assert.Equal(t, "name_id_"+strings.Repeat("a", 24), ui.GetName())
+			assert.Equal(t, ui.GetRawName(), ui.GetDisplayName())
+			assert.Empty(t, ui.groups)
+		}
+	})
+
+	t.Run("missing NameID", func(t *testing.T) {
+		t.Parallel()
+		for _, subject := range []*saml.Subject{nil, {}, {NameID: &saml.NameID{}}} {
+			_, err := p.extractUserInfo(&saml.Assertion{Subject: subject})
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestSAMLProvider_State(t *testing.T) {
+	t.Parallel()
+
+	p := &SAMLProvider{stateKey: []byte("test-state-key")}
+	userID := uuid.Must(uuid.NewV4())
+	s := samlState{requestID: "id-test", linkUserID: userID, expiresAt: time.Now().Add(time.Minute)}
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		decoded, err := p.decodeState(p.encodeState(s))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "id-test", decoded.requestID)
+			assert.Equal(t, userID, decoded.linkUserID)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		t.Parallel()
+		v := p.encodeState(s)
+		v = strings.Replace(v, userID.String(), uuid.Must(uuid.NewV4()).String(), 1)
+		_, err := p.decodeState(v)
+		assert.Error(t, err)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		t.Parallel()
+		other := &SAMLProvider{stateKey: []byte("other-state-key")}
+		_, err := p.decodeState(other.encodeState(s))
+		assert.Error(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+		expired := s
+		expired.expiresAt = time.Now().Add(-time.Second)
+		_, err := p.decodeState(p.encodeState(expired))
+		assert.Error(t, err)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		t.Parallel()
+		for _, v := range []string{"", "id-test", "id-test.sig"} {
+			_, err := p.decodeState(v)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestSAMLProvider_LoginHandler(t *testing.T) {
+	t.Parallel()
+
+	env := setupSAML(t, SAMLProviderConfig{Origin: "https://traq.example.com"})
+	user := env.createUser(t, "user")
+	sess, err := env.sessStore.IssueSession(user.GetID(), nil)
+	require.NoError(t, err)
+
+	login := func(query string, sessionToken string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/saml"+query, nil)
+		if len(sessionToken) > 0 {
+			req.AddCookie(&http.Cookie{Name: session.CookieName, Value: sessionToken})
+		}
+		rec := httptest.NewRecorder()
+		env.e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("login", func(t *testing.T) {
+		t.Parallel()
+		rec := login("", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		if c := findCookie(rec, samlCookieName); assert.NotNil(t, c) {
+			assert.Equal(t, http.SameSiteNoneMode, c.SameSite)
+			s, err := env.p.decodeState(c.Value)
+			if assert.NoError(t, err) {
+				assert.Contains(t, rec.Body.String(), "SAMLRequest")
+				assert.Equal(t, uuid.Nil, s.linkUserID)
+			}
+		}
+	})
+
+	t.Run("already logged in", func(t *testing.T) {
+		t.Parallel()
+		rec := login("", sess.Token())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("link without login", func(t *testing.T) {
+		t.Parallel()
+		rec := login("?link=1", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("link", func(t *testing.T) {
+		t.Parallel()
+		rec := login("?link=1", sess.Token())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		if c := findCookie(rec, samlCookieName); assert.NotNil(t, c) {
+			s, err := env.p.decodeState(c.Value)
+			if assert.NoError(t, err) {
+				assert.Equal(t, user.GetID(), s.linkUserID)
+			}
+		}
+	})
+}
+
+func TestSAMLProvider_CallbackHandler(t *testing.T) {
+	t.Parallel()
+
+	config := SAMLProviderConfig{
+		Origin:               "https://traq.example.com",
+		NameAttribute:        "uid",
+		DisplayNameAttribute: "cn",
+		GroupsAttribute:      "eduPersonAffiliation",
+	}
+	state := func(env *samlTestEnv, requestID string, linkUserID uuid.UUID) string {
+		return env.p.encodeState(samlState{requestID: requestID, linkUserID: linkUserID, expiresAt: time.Now().Add(time.Minute)})
+	}
+	assertion := func(nameID string) *saml.Session {
+		return &saml.Session{
+			CreateTime:     saml.TimeNow(),
+			NameID:         nameID,
+			UserName:       "saml-user",
+			UserCommonName: "SAML User",
+			Groups:         []string{"engineers", "admins"},
+		}
+	}
+
+	t.Run("missing cookie", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		rec := env.callback("", env.response(t, "id-1", assertion("nameid")))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("invalid cookie", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		rec := env.callback("id-1", env.response(t, "id-1", assertion("nameid")))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("request id mismatch", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		rec := env.callback(state(env, "id-1", uuid.Nil), env.response(t, "id-2", assertion("nameid")))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unsigned by idp", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		other := setupSAML(t, config)
+		rec := env.callback(state(env, "id-1", uuid.Nil), other.response(t, "id-1", assertion("nameid")))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("missing NameID", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		rec := env.callback(state(env, "id-1", uuid.Nil), env.response(t, "id-1", assertion("")))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, env.repo.external)
+	})
+
+	t.Run("login", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		env.createUser(t, "traq")
+		user := env.createUser(t, "user")
+		env.repo.external[SAMLProviderName+":nameid"] = user.GetID()
+
+		rec := env.callback(state(env, "id-1", uuid.Nil), env.response(t, "id-1", assertion("nameid")))
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.NotNil(t, findCookie(rec, session.CookieName))
+		assert.Len(t, env.repo.users, 2)
+		assert.Equal(t, []uuid.UUID{user.GetID()}, env.repo.groupMembers("engineers"))
+	})
+
+	t.Run("login without sign up", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		rec := env.callback(state(env, "id-1", uuid.Nil), env.response(t, "id-1", assertion("nameid")))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, env.repo.users)
+	})
+
+	t.Run("link", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		user := env.createUser(t, "user")
+
+		// IdPからのPOSTにはtraQのセッションCookieが付かない
+		rec := env.callback(state(env, "id-1", user.GetID()), env.response(t, "id-1", assertion("nameid")))
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Nil(t, findCookie(rec, session.CookieName))
+		assert.Equal(t, user.GetID(), env.repo.external[SAMLProviderName+":nameid"])
+		assert.Len(t, env.repo.users, 1)
+	})
+
+	t.Run("link already linked", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAML(t, config)
+		user := env.createUser(t, "user")
+		other := env.createUser(t, "other")
+		env.repo.external[SAMLProviderName+":nameid"] = other.GetID()
+
+		rec := env.callback(state(env, "id-1", user.GetID()), env.response(t, "id-1", assertion("nameid")))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, other.GetID(), env.repo.external[SAMLProviderName+":nameid"])
+	})
+
+	t.Run("sign up", func(t *testing.T) {
+		t.Parallel()
+		c := config
+		c.RegisterUserIfNotFound = true
+		env := setupSAML(t, c)
+		admin := env.createUser(t, "traq")
+		other := env.createUser(t, "other")
+		// 同名のSAML以外のグループには追加しない
+		admins, err := env.repo.CreateExternalUserGroup("admins", "", admin.GetID())
+		require.NoError(t, err)
+		// アサーションに含まれないSAMLグループからは削除する
+		old, err := env.repo.CreateExternalUserGroup("old", SAMLGroupSource, admin.GetID())
+		require.NoError(t, err)
+
+		rec := env.callback(state(env, "id-1", uuid.Nil), env.response(t, "id-1", assertion("nameid")))
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.NotNil(t, findCookie(rec, session.CookieName))
+
+		u, err := env.repo.GetUserByExternalID(SAMLProviderName, "nameid", false)
+		require.NoError(t, err)
+		assert.Equal(t, "saml-user", u.GetName())
+		assert.Equal(t, "SAML User", u.GetResponseDisplayName())
+		assert.Equal(t, []uuid.UUID{u.GetID()}, env.repo.groupMembers("engineers"))
+		assert.Empty(t, admins.Members)
+
+		require.NoError(t, env.repo.AddUserToGroup(other.GetID(), old.ID, ""))
+		require.NoError(t, env.repo.AddUserToGroup(u.GetID(), old.ID, ""))
+		rec = env.callback(state(env, "id-2", uuid.Nil), env.response(t, "id-2", &saml.Session{CreateTime: saml.TimeNow(), NameID: "nameid"}))
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Empty(t, env.repo.groupMembers("engineers"))
+		assert.Equal(t, []uuid.UUID{other.GetID()}, env.repo.groupMembers("old"))
+	})
+}
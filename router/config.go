@@ -3,6 +3,7 @@ package router
 import (
 	"github.com/traPtitech/traQ/router/auth"
 	"github.com/traPtitech/traQ/router/oauth2"
+	"github.com/traPtitech/traQ/router/scim"
 	v3 "github.com/traPtitech/traQ/router/v3"
 )
 
@@ -10,6 +11,8 @@ import (
 type Config struct {
 	// 開発モードかどうか
 	Development bool
+	// Origin サーバーオリジン
+	Origin string
 	// Version サーバーバージョン
 	Version string
 	// Revision サーバーリビジョン
@@ -26,6 +29,10 @@ type Config struct {
 	SkyWaySecretKey string
 	// ExternalAuth 外部認証設定
 	ExternalAuth ExternalAuthConfig
+	// SCIM SCIM 2.0 プロビジョニングAPI設定
+	SCIM scim.Config
+	// StorageQuota ファイル容量制限設定
+	StorageQuota v3.StorageQuota
 }
 
 // ExternalAuth 外部認証設定
@@ -38,6 +45,8 @@ type ExternalAuthConfig struct {
 	TraQ auth.TraQProviderConfig
 	// OIDC OpenID Connect
 	OIDC auth.OIDCProviderConfig
+	// SAML SAML 2.0
+	SAML auth.SAMLProviderConfig
 }
 
 func (c ExternalAuthConfig) ValidProviders() map[string]bool {
@@ -54,6 +63,9 @@ func (c ExternalAuthConfig) ValidProviders() map[string]bool {
 	if c.OIDC.Valid() {
 		res[auth.OIDCProviderName] = true
 	}
+	if c.SAML.Valid() {
+		res[auth.SAMLProviderName] = true
+	}
 	return res
 }
 
@@ -61,14 +73,20 @@ func provideOAuth2Config(c *Config) oauth2.Config {
 	return oauth2.Config{
 		AccessTokenExp:   c.AccessTokenExp,
 		IsRefreshEnabled: c.IsRefreshEnabled,
+		Origin:           c.Origin,
 	}
 }
 
+func provideSCIMConfig(c *Config) scim.Config {
+	return c.SCIM
+}
+
 func provideV3Config(c *Config) v3.Config {
 	return v3.Config{
 		Version:                         c.Version,
 		Revision:                        c.Revision,
 		SkyWaySecretKey:                 c.SkyWaySecretKey,
 		EnabledExternalAccountProviders: c.ExternalAuth.ValidProviders(),
+		StorageQuota:                    c.StorageQuota,
 	}
 }
@@ -13,6 +13,7 @@ const (
 	KeyParamWebhook       = "paramWebhook"
 	KeyParamMessage       = "paramMessage"
 	KeyParamChannel       = "paramChannel"
+	KeyParamTemplate      = "paramTemplate"
 	KeyParamFile          = "paramFile"
 	KeyParamClipFolder    = "paramClipFolder"
 	KeyRepo               = "_repo"
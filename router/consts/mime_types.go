@@ -2,7 +2,11 @@ package consts
 
 const (
 	MimeImagePNG  = "image/png"
+	MimeImageAPNG = "image/apng"
 	MimeImageJPEG = "image/jpeg"
 	MimeImageGIF  = "image/gif"
+	MimeImageWebP = "image/webp"
 	MimeImageSVG  = "image/svg+xml"
+
+	MimeOffsetOctetStream = "application/offset+octet-stream"
 )
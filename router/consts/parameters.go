@@ -5,15 +5,18 @@ const (
 	ParamPinID          = "pinID"
 	ParamUserID         = "userID"
 	ParamGroupID        = "groupID"
+	ParamChildGroupID   = "childID"
 	ParamTagID          = "tagID"
 	ParamStampID        = "stampID"
 	ParamStampPaletteID = "paletteID"
 	ParamMessageID      = "messageID"
 	ParamReferenceID    = "referenceID"
 	ParamFileID         = "fileID"
+	ParamUploadID       = "uploadID"
 	ParamWebhookID      = "webhookID"
 	ParamTokenID        = "tokenID"
 	ParamBotID          = "botID"
 	ParamClientID       = "clientID"
 	ParamClipFolderID   = "folderID"
+	ParamTemplateID     = "templateID"
 )
@@ -57,6 +57,16 @@ func AdminOnly(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// BlockOAuth2Token OAuth2トークンによるリクエストを制限し、セッション認証のみを通すミドルウェア
+func BlockOAuth2Token(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, ok := c.Get(consts.KeyOAuth2AccessScopes).(model.AccessScopes); ok {
+			return herror.Forbidden("this API is not available with OAuth2 token")
+		}
+		return next(c)
+	}
+}
+
 // BlockBot Botのリクエストを制限するミドルウェア
 func BlockBot(repo repository.Repository) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -111,7 +121,7 @@ func CheckWebhookAccessPerm(rbac rbac.RBAC, repo repository.Repository) echo.Mid
 }
 
 // CheckFileAccessPerm Fileアクセス権限を確認するミドルウェア
-func CheckFileAccessPerm(rbac rbac.RBAC, fm file.Manager) echo.MiddlewareFunc {
+func CheckFileAccessPerm(rbac rbac.RBAC, fm file.Manager, cm channel.Manager) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			file := c.Get(consts.KeyParamFile).(model.File)
@@ -122,6 +132,16 @@ func CheckFileAccessPerm(rbac rbac.RBAC, fm file.Manager) echo.MiddlewareFunc {
 				return next(c)
 			}
 
+			// 非公開チャンネルのファイルは現在のチャンネルメンバーのみアクセス可能
+			if cid := file.GetUploadChannelID(); cid.Valid && !cm.IsPublicChannel(cid.UUID) {
+				if ok, err := cm.IsChannelAccessibleToUser(userID, cid.UUID); err != nil {
+					return herror.InternalServerError(err)
+				} else if !ok {
+					return herror.Forbidden()
+				}
+				return next(c)
+			}
+
 			// アクセス権確認
 			if ok, err := fm.Accessible(file.GetID(), userID); err != nil {
 				return herror.InternalServerError(err)
@@ -189,6 +209,25 @@ func CheckChannelAccessPerm(rbac rbac.RBAC, cm channel.Manager) echo.MiddlewareF
 	}
 }
 
+// BlockArchivedMessageChannel アーカイブされたチャンネルのメッセージへの変更を制限するミドルウェア
+func BlockArchivedMessageChannel(cm channel.Manager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			m := c.Get(consts.KeyParamMessage).(*model.Message)
+
+			ch, err := cm.GetChannel(m.ChannelID)
+			if err != nil {
+				return herror.InternalServerError(err)
+			}
+			if ch.IsArchived() {
+				return herror.BadRequest(fmt.Sprintf("channel #%s has been archived", cm.PublicChannelTree().GetChannelPath(ch.ID)))
+			}
+
+			return next(c)
+		}
+	}
+}
+
 // CheckUserGroupAdminPerm UserGroup管理者権限を確認するミドルウェア
 func CheckUserGroupAdminPerm(rbac rbac.RBAC, repo repository.Repository) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -205,6 +244,19 @@ func CheckUserGroupAdminPerm(rbac rbac.RBAC, repo repository.Repository) echo.Mi
 	}
 }
 
+// BlockReadOnlyUserGroup 外部ソースと同期されているUserGroupへの変更を制限するミドルウェア
+func BlockReadOnlyUserGroup() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			g := c.Get(consts.KeyParamGroup).(*model.UserGroup)
+			if g.IsReadOnly() {
+				return herror.Forbidden("this group is managed by an external source")
+			}
+			return next(c)
+		}
+	}
+}
+
 // CheckClipFolderAccessPerm ClipFolderアクセス権限を確認するミドルウェア
 func CheckClipFolderAccessPerm(rbac rbac.RBAC, repo repository.Repository) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -123,6 +123,13 @@ func (pr *ParamRetriever) ChannelID() echo.MiddlewareFunc {
 	})
 }
 
+// ChannelTemplateID リクエストURLの`templateID`パラメータからChannelTemplateを取り出す
+func (pr *ParamRetriever) ChannelTemplateID() echo.MiddlewareFunc {
+	return pr.byUUID(consts.ParamTemplateID, consts.KeyParamTemplate, func(c echo.Context, v uuid.UUID) (interface{}, error) {
+		return pr.repo.GetChannelTemplate(v)
+	})
+}
+
 // FileID リクエストURLの`fileID`パラメータからFileを取り出す
 func (pr *ParamRetriever) FileID() echo.MiddlewareFunc {
 	return pr.byUUID(consts.ParamFileID, consts.KeyParamFile, func(c echo.Context, v uuid.UUID) (interface{}, error) {
@@ -0,0 +1,350 @@
+package oauth2
+
+import (
+	crand "crypto/rand"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/random"
+	"go.uber.org/zap"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// userCodeCharset ユーザーコードに使用する文字 (読み間違えやすい母音と数字を除く)
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	// userCodeLength ユーザーコードの長さ (区切り文字を除く)
+	userCodeLength = 8
+)
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type deviceAuthorizationHandlerRequest struct {
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// DeviceAuthorizationEndpointHandler デバイス認可エンドポイントのハンドラ
+func (h *Handler) DeviceAuthorizationEndpointHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req deviceAuthorizationHandlerRequest
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	id, pw, ok := c.Request().BasicAuth()
+	if !ok { // Request Body
+		if len(req.ClientID) == 0 {
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		}
+		id = req.ClientID
+		pw = req.ClientSecret
+	}
+
+	// クライアント確認
+	client, err := h.Repo.GetClient(id)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	if client.Confidential && client.Secret != pw {
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+	}
+
+	// 要求スコープ確認
+	reqScopes, err := h.splitAndValidateScope(req.Scope)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidScope})
+	}
+	validScopes := client.GetAvailableScopes(reqScopes)
+	if len(reqScopes) == 0 {
+		validScopes = client.Scopes
+	} else if len(validScopes) == 0 {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidScope})
+	}
+
+	// ユーザーコードの衝突時は数回まで再生成する
+	for i := 0; i < 3; i++ {
+		data := &model.OAuth2DeviceAuthorize{
+			DeviceCode:   random.SecureAlphaNumeric(64),
+			UserCode:     generateUserCode(),
+			ClientID:     client.ID,
+			Scopes:       validScopes,
+			Status:       model.DeviceAuthorizePending,
+			PollInterval: deviceCodeInterval,
+			ExpiresIn:    deviceCodeExp,
+			CreatedAt:    time.Now(),
+		}
+		if err := h.Repo.SaveDeviceAuthorize(data); err != nil {
+			if err == repository.ErrAlreadyExists {
+				continue
+			}
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+
+		verificationURI := h.Origin + "/device"
+		return c.JSON(http.StatusOK, &deviceAuthorizationResponse{
+			DeviceCode:              data.DeviceCode,
+			UserCode:                data.UserCode,
+			VerificationURI:         verificationURI,
+			VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {data.UserCode}}.Encode(),
+			ExpiresIn:               data.ExpiresIn,
+			Interval:                data.PollInterval,
+		})
+	}
+	h.L(c).Error("failed to generate unique user code")
+	return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+}
+
+type deviceVerificationResponse struct {
+	ClientID  string   `json:"clientId"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expiresAt"`
+}
+
+// DeviceVerificationHandler デバイス認可のユーザーコード確認のハンドラ
+func (h *Handler) DeviceVerificationHandler(c echo.Context) error {
+	data, err := h.getPendingDeviceAuthorize(c.QueryParam("user_code"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &deviceVerificationResponse{
+		ClientID:  data.ClientID,
+		Scopes:    data.Scopes.StringArray(),
+		ExpiresAt: data.CreatedAt.Add(time.Duration(data.ExpiresIn) * time.Second).Format(time.RFC3339),
+	})
+}
+
+type deviceDecideHandlerRequest struct {
+	UserCode string `form:"user_code" json:"userCode"`
+	Submit   string `form:"submit"    json:"submit"`
+}
+
+func (r deviceDecideHandlerRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.UserCode, vd.Required),
+		vd.Field(&r.Submit, vd.Required),
+	)
+}
+
+// DeviceDecideHandler デバイス認可の承認フォームのハンドラ
+func (h *Handler) DeviceDecideHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req deviceDecideHandlerRequest
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	data, err := h.getPendingDeviceAuthorize(req.UserCode)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get(consts.KeyUser).(model.UserInfo)
+	if err := h.Repo.DecideDeviceAuthorize(data.DeviceCode, user.GetID(), req.Submit == "approve"); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.BadRequest("invalid user code")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getPendingDeviceAuthorize ユーザーコードから承認待ちのデバイス認可データを取得します
+func (h *Handler) getPendingDeviceAuthorize(userCode string) (*model.OAuth2DeviceAuthorize, error) {
+	data, err := h.Repo.GetDeviceAuthorizeByUserCode(normalizeUserCode(userCode))
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, herror.BadRequest("invalid user code")
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	if data.IsExpired() || data.Status != model.DeviceAuthorizePending {
+		return nil, herror.BadRequest("invalid user code")
+	}
+	return data, nil
+}
+
+type tokenEndpointDeviceCodeHandlerRequest struct {
+	DeviceCode   string `form:"device_code"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+func (r tokenEndpointDeviceCodeHandlerRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.DeviceCode, vd.Required),
+	)
+}
+
+func (h *Handler) tokenEndpointDeviceCodeHandler(c echo.Context) error {
+	var req tokenEndpointDeviceCodeHandlerRequest
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	// デバイスコード確認
+	data, err := h.Repo.GetDeviceAuthorize(req.DeviceCode)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+
+	// クライアント確認
+	client, err := h.Repo.GetClient(data.ClientID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	id, pw, ok := c.Request().BasicAuth()
+	if !ok { // Request Body
+		if len(req.ClientID) == 0 {
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		}
+		id = req.ClientID
+		pw = req.ClientSecret
+	}
+	if client.ID != id || (client.Confidential && client.Secret != pw) {
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+	}
+
+	if data.IsExpired() {
+		if err := h.Repo.DeleteDeviceAuthorize(data.DeviceCode); err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errExpiredToken})
+	}
+
+	switch data.Status {
+	case model.DeviceAuthorizePending:
+		// ポーリング間隔確認
+		now := time.Now()
+		interval := data.PollInterval
+		if data.IsPolledTooFast(now) {
+			interval += deviceCodeInterval
+		}
+		if err := h.Repo.UpdateDeviceAuthorizePolling(data.DeviceCode, now, interval); err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		if interval != data.PollInterval {
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errSlowDown})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errAuthorizationPending})
+
+	case model.DeviceAuthorizeApproved:
+		// デバイスコードは２回使えない
+		if err := h.Repo.DeleteDeviceAuthorize(data.DeviceCode); err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+
+		// トークン発行
+		newToken, err := h.Repo.IssueToken(client, data.UserID, client.RedirectURI, data.Scopes, h.AccessTokenExp, h.IsRefreshEnabled)
+		if err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+
+		res := &tokenResponse{
+			TokenType:   authScheme,
+			AccessToken: newToken.AccessToken,
+			ExpiresIn:   newToken.ExpiresIn,
+			Scope:       newToken.Scopes.String(),
+		}
+		if newToken.IsRefreshEnabled() {
+			res.RefreshToken = newToken.RefreshToken
+		}
+		if newToken.Scopes.Contains(model.ScopeOpenID) {
+			res.IDToken, err = h.issueIDTokenFor(client.ID, newToken.UserID, "")
+			if err != nil {
+				h.L(c).Error(err.Error(), zap.Error(err))
+				return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+			}
+		}
+		return c.JSON(http.StatusOK, res)
+
+	default: // 拒否
+		if err := h.Repo.DeleteDeviceAuthorize(data.DeviceCode); err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errAccessDenied})
+	}
+}
+
+// generateUserCode "XXXX-XXXX"形式のユーザーコードを生成します
+func generateUserCode() string {
+	max := big.NewInt(int64(len(userCodeCharset)))
+	b := make([]byte, 0, userCodeLength+1)
+	for i := 0; i < userCodeLength; i++ {
+		if i == userCodeLength/2 {
+			b = append(b, '-')
+		}
+		n, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b = append(b, userCodeCharset[n.Int64()])
+	}
+	return string(b)
+}
+
+// normalizeUserCode ユーザーが入力したユーザーコードを"XXXX-XXXX"形式に正規化します
+//
+// 大文字小文字の違いと区切り文字、空白は無視します。
+func normalizeUserCode(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		if 'A' <= r && r <= 'Z' {
+			sb.WriteRune(r)
+		}
+	}
+	code := sb.String()
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
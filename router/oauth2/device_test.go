@@ -0,0 +1,373 @@
+package oauth2
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	random2 "github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestGenerateUserCode(t *testing.T) {
+	t.Parallel()
+
+	re := regexp.MustCompile(`^[` + userCodeCharset + `]{4}-[` + userCodeCharset + `]{4}$`)
+	for i := 0; i < 100; i++ {
+		code := generateUserCode()
+		assert.Regexp(t, re, code)
+		assert.Equal(t, code, normalizeUserCode(code))
+	}
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"BCDF-GHJK", "BCDF-GHJK"},
+		{"bcdf-ghjk", "BCDF-GHJK"},
+		{"BCDFGHJK", "BCDF-GHJK"},
+		{" bcdf ghjk ", "BCDF-GHJK"},
+		{"BCD", "BCD"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, normalizeUserCode(tt.in), tt.in)
+	}
+}
+
+func TestHandlers_DeviceAuthorizationEndpointHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read", "write")
+	client := &model.OAuth2Client{
+		ID:           random2.AlphaNumeric(36),
+		Name:         "test client",
+		Confidential: false,
+		CreatorID:    uuid.Must(uuid.NewV4()),
+		Secret:       random2.AlphaNumeric(36),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.POST("/oauth2/device").
+			WithFormField("client_id", client.ID).
+			WithFormField("scope", "read").
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		obj.Value("device_code").String().NotEmpty()
+		userCode := obj.Value("user_code").String().Raw()
+		obj.Value("verification_uri").String().Equal("http://example.com/device")
+		obj.Value("verification_uri_complete").String().Equal("http://example.com/device?user_code=" + userCode)
+		obj.Value("expires_in").Number().Equal(deviceCodeExp)
+		obj.Value("interval").Number().Equal(deviceCodeInterval)
+
+		data, err := env.Repository.GetDeviceAuthorizeByUserCode(userCode)
+		if assert.NoError(t, err) {
+			assert.Equal(t, client.ID, data.ClientID)
+			assert.Equal(t, model.DeviceAuthorizePending, data.Status)
+			assert.True(t, data.Scopes.Contains("read"))
+			assert.False(t, data.Scopes.Contains("write"))
+		}
+	})
+
+	t.Run("Unknown client", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/device").
+			WithFormField("client_id", random2.AlphaNumeric(36)).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Object().
+			Value("error").String().Equal(errInvalidClient)
+	})
+
+	t.Run("Invalid scope", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/device").
+			WithFormField("client_id", client.ID).
+			WithFormField("scope", "manage_bot").
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Object().
+			Value("error").String().Equal(errInvalidScope)
+	})
+}
+
+func TestHandlers_DeviceDecideHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+	s := env.S(t, user.GetID())
+
+	t.Run("Approve", func(t *testing.T) {
+		t.Parallel()
+		data := env.MakeDeviceAuthorizeData(t, random2.AlphaNumeric(36))
+
+		e := env.R(t)
+		e.GET("/oauth2/device/verify").
+			WithCookie(session.CookieName, s).
+			WithQuery("user_code", data.UserCode).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("clientId").String().Equal(data.ClientID)
+
+		e.POST("/oauth2/device/decide").
+			WithCookie(session.CookieName, s).
+			WithFormField("user_code", data.UserCode).
+			WithFormField("submit", "approve").
+			Expect().
+			Status(http.StatusNoContent)
+
+		d, err := env.Repository.GetDeviceAuthorize(data.DeviceCode)
+		if assert.NoError(t, err) {
+			assert.Equal(t, model.DeviceAuthorizeApproved, d.Status)
+			assert.Equal(t, user.GetID(), d.UserID)
+		}
+
+		// 2回目は失敗
+		e.POST("/oauth2/device/decide").
+			WithCookie(session.CookieName, s).
+			WithFormField("user_code", data.UserCode).
+			WithFormField("submit", "approve").
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		t.Parallel()
+		data := env.MakeDeviceAuthorizeData(t, random2.AlphaNumeric(36))
+
+		e := env.R(t)
+		e.POST("/oauth2/device/decide").
+			WithCookie(session.CookieName, s).
+			WithFormField("user_code", data.UserCode).
+			WithFormField("submit", "deny").
+			Expect().
+			Status(http.StatusNoContent)
+
+		d, err := env.Repository.GetDeviceAuthorize(data.DeviceCode)
+		if assert.NoError(t, err) {
+			assert.Equal(t, model.DeviceAuthorizeDenied, d.Status)
+		}
+	})
+
+	t.Run("Unknown user code", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/device/decide").
+			WithCookie(session.CookieName, s).
+			WithFormField("user_code", "BCDF-GHJK").
+			WithFormField("submit", "approve").
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("Not logged in", func(t *testing.T) {
+		t.Parallel()
+		data := env.MakeDeviceAuthorizeData(t, random2.AlphaNumeric(36))
+
+		e := env.R(t)
+		e.POST("/oauth2/device/decide").
+			WithFormField("user_code", data.UserCode).
+			WithFormField("submit", "approve").
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("OAuth2 token", func(t *testing.T) {
+		t.Parallel()
+		data := env.MakeDeviceAuthorizeData(t, random2.AlphaNumeric(36))
+		scopes := model.AccessScopes{}
+		scopes.Add("read")
+		token, err := env.Repository.IssueToken(nil, user.GetID(), "", scopes, 1000, false)
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.GET("/oauth2/device/verify").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			WithQuery("user_code", data.UserCode).
+			Expect().
+			Status(http.StatusForbidden)
+
+		e.POST("/oauth2/device/decide").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			WithFormField("user_code", data.UserCode).
+			WithFormField("submit", "approve").
+			Expect().
+			Status(http.StatusForbidden)
+
+		d, err := env.Repository.GetDeviceAuthorize(data.DeviceCode)
+		if assert.NoError(t, err) {
+			assert.Equal(t, model.DeviceAuthorizePending, d.Status)
+		}
+	})
+}
+
+func TestHandlers_TokenEndpointDeviceCodeHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db2)
+	user := env.CreateUser(t, rand)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	client := &model.OAuth2Client{
+		ID:           random2.AlphaNumeric(36),
+		Name:         "test client",
+		Confidential: false,
+		CreatorID:    uuid.Must(uuid.NewV4()),
+		Secret:       random2.AlphaNumeric(36),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	t.Run("Pending and slow down", func(t *testing.T) {
+		t.Parallel()
+		data := env.MakeDeviceAuthorizeData(t, client.ID)
+
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", data.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Object().
+			Value("error").String().Equal(errAuthorizationPending)
+
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", data.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Object().
+			Value("error").String().Equal(errSlowDown)
+
+		d, err := env.Repository.GetDeviceAuthorize(data.DeviceCode)
+		if assert.NoError(t, err) {
+			assert.Equal(t, deviceCodeInterval*2, d.PollInterval)
+		}
+	})
+
+	t.Run("Approved", func(t *testing.T) {
+		t.Parallel()
+		data := env.MakeDeviceAuthorizeData(t, client.ID)
+		require.NoError(t, env.Repository.DecideDeviceAuthorize(data.DeviceCode, user.GetID(), true))
+
+		e := env.R(t)
+		res := e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", data.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect()
+
+		res.Status(http.StatusOK)
+		res.Header("Cache-Control").Equal("no-store")
+		obj := res.JSON().Object()
+		obj.Value("access_token").String().NotEmpty()
+		obj.Value("token_type").String().Equal(authScheme)
+		obj.Value("scope").String().Equal("read")
+
+		_, err := env.Repository.GetDeviceAuthorize(data.DeviceCode)
+		assert.EqualError(t, err, repository.ErrNotFound.Error())
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		t.Parallel()
+		data := env.MakeDeviceAuthorizeData(t, client.ID)
+		require.NoError(t, env.Repository.DecideDeviceAuthorize(data.DeviceCode, user.GetID(), false))
+
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", data.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Object().
+			Value("error").String().Equal(errAccessDenied)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		t.Parallel()
+		data := &model.OAuth2DeviceAuthorize{
+			DeviceCode:   random2.AlphaNumeric(64),
+			UserCode:     generateUserCode(),
+			ClientID:     client.ID,
+			Scopes:       scopes,
+			Status:       model.DeviceAuthorizePending,
+			PollInterval: deviceCodeInterval,
+			ExpiresIn:    1,
+			CreatedAt:    time.Now().Add(-time.Minute),
+		}
+		require.NoError(t, env.Repository.SaveDeviceAuthorize(data))
+
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", data.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Object().
+			Value("error").String().Equal(errExpiredToken)
+	})
+
+	t.Run("Different client", func(t *testing.T) {
+		t.Parallel()
+		data := env.MakeDeviceAuthorizeData(t, client.ID)
+
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", data.DeviceCode).
+			WithFormField("client_id", random2.AlphaNumeric(36)).
+			Expect().
+			Status(http.StatusUnauthorized).
+			JSON().
+			Object().
+			Value("error").String().Equal(errInvalidClient)
+	})
+
+	t.Run("Unknown device code", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", random2.AlphaNumeric(64)).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Object().
+			Value("error").String().Equal(errInvalidGrant)
+	})
+}
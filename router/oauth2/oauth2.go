@@ -17,6 +17,7 @@ const (
 	grantTypePassword          = "password"
 	grantTypeClientCredentials = "client_credentials"
 	grantTypeRefreshToken      = "refresh_token"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
 
 	errInvalidRequest          = "invalid_request"
 	errUnauthorizedClient      = "unauthorized_client"
@@ -29,11 +30,16 @@ const (
 	errUnsupportedGrantType    = "unsupported_grant_type"
 	errLoginRequired           = "login_required"
 	errConsentRequired         = "consent_required"
+	errAuthorizationPending    = "authorization_pending"
+	errSlowDown                = "slow_down"
+	errExpiredToken            = "expired_token"
 
 	oauth2ContextSession = "oauth2_context"
 	authScheme           = "Bearer"
 
 	authorizationCodeExp = 60 * 5
+	deviceCodeExp        = 60 * 10
+	deviceCodeInterval   = 5
 )
 
 type Handler struct {
@@ -49,6 +55,8 @@ type Config struct {
 	AccessTokenExp int
 	// IsRefreshEnabled リフレッシュトークンを発行するかどうか
 	IsRefreshEnabled bool
+	// Origin サーバーオリジン (IDトークンのissuer)
+	Origin string
 }
 
 func (h *Handler) Setup(e *echo.Group) {
@@ -57,6 +65,12 @@ func (h *Handler) Setup(e *echo.Group) {
 	e.POST("/authorize", h.AuthorizationEndpointHandler)
 	e.POST("/token", h.TokenEndpointHandler)
 	e.POST("/revoke", h.RevokeTokenEndpointHandler)
+	e.POST("/device", h.DeviceAuthorizationEndpointHandler)
+	e.GET("/device/verify", h.DeviceVerificationHandler, middlewares.UserAuthenticate(h.Repo, h.SessStore), middlewares.BlockOAuth2Token, middlewares.BlockBot(h.Repo))
+	e.POST("/device/decide", h.DeviceDecideHandler, middlewares.UserAuthenticate(h.Repo, h.SessStore), middlewares.BlockOAuth2Token, middlewares.BlockBot(h.Repo))
+	e.GET("/jwks", h.JWKSHandler)
+	e.GET("/userinfo", h.UserInfoHandler)
+	e.POST("/userinfo", h.UserInfoHandler)
 }
 
 // splitAndValidateScope スペース区切りのスコープ文字列を分解し、検証します
@@ -16,6 +16,7 @@ import (
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/rbac/role"
 	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/jwt"
 	"github.com/traPtitech/traQ/utils/random"
 	"go.uber.org/zap"
 	"net/http"
@@ -47,6 +48,11 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
+	privRaw, _ := random.GenerateECDSAKey()
+	if err := jwt.SetupSigner(privRaw); err != nil {
+		panic(err)
+	}
+
 	for _, key := range dbs {
 		env := &Env{}
 
@@ -89,9 +95,11 @@ func TestMain(m *testing.M) {
 			Config: Config{
 				AccessTokenExp:   1000,
 				IsRefreshEnabled: true,
+				Origin:           "http://example.com",
 			},
 		}
 		config.Setup(e.Group("/oauth2"))
+		e.GET("/.well-known/openid-configuration", config.OIDCDiscoveryHandler)
 		env.Server = httptest.NewServer(e)
 
 		envs[key] = env
@@ -192,6 +200,24 @@ func (env *Env) MakeAuthorizeData(t *testing.T, clientID string, userID uuid.UUI
 	return authorize
 }
 
+func (env *Env) MakeDeviceAuthorizeData(t *testing.T, clientID string) *model.OAuth2DeviceAuthorize {
+	t.Helper()
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	data := &model.OAuth2DeviceAuthorize{
+		DeviceCode:   random.AlphaNumeric(64),
+		UserCode:     generateUserCode(),
+		ClientID:     clientID,
+		Scopes:       scopes,
+		Status:       model.DeviceAuthorizePending,
+		PollInterval: deviceCodeInterval,
+		ExpiresIn:    1000,
+		CreatedAt:    time.Now(),
+	}
+	require.NoError(t, env.Repository.SaveDeviceAuthorize(data))
+	return data
+}
+
 func getEnvOrDefault(env string, def string) string {
 	s := os.Getenv(env)
 	if len(s) == 0 {
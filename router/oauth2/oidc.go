@@ -0,0 +1,159 @@
+package oauth2
+
+import (
+	jwt2 "github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/jwt"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// oidcDiscovery OpenID Provider Metadata
+type oidcDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	JWKsURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	ResponseModesSupported            []string `json:"response_modes_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// idTokenClaims IDトークンのクレーム
+type idTokenClaims struct {
+	jwt2.StandardClaims
+	Nonce             string `json:"nonce,omitempty"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Picture           string `json:"picture"`
+}
+
+// userInfoResponse UserInfoエンドポイントのレスポンス
+type userInfoResponse struct {
+	Sub               string `json:"sub"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Picture           string `json:"picture"`
+}
+
+// OIDCDiscoveryHandler OpenID Connect Discoveryのハンドラ
+func (h *Handler) OIDCDiscoveryHandler(c echo.Context) error {
+	endpoint := h.Origin + "/api/v3/oauth2"
+	return c.JSON(http.StatusOK, &oidcDiscovery{
+		Issuer:                            h.Origin,
+		AuthorizationEndpoint:             endpoint + "/authorize",
+		TokenEndpoint:                     endpoint + "/token",
+		UserInfoEndpoint:                  endpoint + "/userinfo",
+		RevocationEndpoint:                endpoint + "/revoke",
+		JWKsURI:                           endpoint + "/jwks",
+		ScopesSupported:                   []string{string(model.ScopeOpenID), "read", "write", "manage_bot"},
+		ResponseTypesSupported:            []string{"code"},
+		ResponseModesSupported:            []string{"query"},
+		GrantTypesSupported:               []string{grantTypeAuthorizationCode, grantTypePassword, grantTypeClientCredentials, grantTypeRefreshToken, grantTypeDeviceCode},
+		DeviceAuthorizationEndpoint:       endpoint + "/device",
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jwt2.SigningMethodES256.Alg()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "name", "preferred_username", "picture"},
+		CodeChallengeMethodsSupported:     []string{"plain", "S256"},
+	})
+}
+
+// JWKSHandler JWK Setエンドポイントのハンドラ
+func (h *Handler) JWKSHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"keys": []jwt.JWK{jwt.PublicJWK()}})
+}
+
+// UserInfoHandler UserInfoエンドポイントのハンドラ
+func (h *Handler) UserInfoHandler(c echo.Context) error {
+	ah := c.Request().Header.Get(echo.HeaderAuthorization)
+	l := len(authScheme)
+	if !(len(ah) > l+1 && ah[:l] == authScheme) {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme)
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	token, err := h.Repo.GetTokenByAccess(ah[l+1:])
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` error="invalid_token"`)
+			return c.NoContent(http.StatusUnauthorized)
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	if token.IsExpired() || token.UserID == uuid.Nil {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` error="invalid_token"`)
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	if !token.Scopes.Contains(model.ScopeOpenID) {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` error="insufficient_scope", scope="openid"`)
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	user, err := h.Repo.GetUser(token.UserID, false)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` error="invalid_token"`)
+			return c.NoContent(http.StatusUnauthorized)
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+
+	return c.JSON(http.StatusOK, &userInfoResponse{
+		Sub:               user.GetID().String(),
+		Name:              user.GetResponseDisplayName(),
+		PreferredUsername: user.GetName(),
+		Picture:           h.iconURL(user),
+	})
+}
+
+// issueIDTokenFor 指定したユーザーのIDトークンを発行します
+func (h *Handler) issueIDTokenFor(clientID string, userID uuid.UUID, nonce string) (string, error) {
+	user, err := h.Repo.GetUser(userID, false)
+	if err != nil {
+		return "", err
+	}
+	return h.issueIDToken(clientID, user, nonce)
+}
+
+// issueIDToken IDトークンを発行します
+func (h *Handler) issueIDToken(clientID string, user model.UserInfo, nonce string) (string, error) {
+	now := time.Now()
+	return jwt.Sign(&idTokenClaims{
+		StandardClaims: jwt2.StandardClaims{
+			Issuer:    h.Origin,
+			Subject:   user.GetID().String(),
+			Audience:  clientID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Duration(h.AccessTokenExp) * time.Second).Unix(),
+		},
+		Nonce:             nonce,
+		Name:              user.GetResponseDisplayName(),
+		PreferredUsername: user.GetName(),
+		Picture:           h.iconURL(user),
+	})
+}
+
+// iconURL ユーザーのアイコン画像のURLを返します
+func (h *Handler) iconURL(user model.UserInfo) string {
+	return h.Origin + "/api/v3/public/icon/" + user.GetName()
+}
@@ -0,0 +1,167 @@
+package oauth2
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/jwt"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandlers_OIDCDiscoveryHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	e := env.R(t)
+	obj := e.GET("/.well-known/openid-configuration").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	obj.Value("issuer").String().Equal("http://example.com")
+	obj.Value("authorization_endpoint").String().Equal("http://example.com/api/v3/oauth2/authorize")
+	obj.Value("token_endpoint").String().Equal("http://example.com/api/v3/oauth2/token")
+	obj.Value("userinfo_endpoint").String().Equal("http://example.com/api/v3/oauth2/userinfo")
+	obj.Value("jwks_uri").String().Equal("http://example.com/api/v3/oauth2/jwks")
+	obj.Value("scopes_supported").Array().Contains("openid")
+	obj.Value("id_token_signing_alg_values_supported").Array().ContainsOnly("ES256")
+}
+
+func TestHandlers_JWKSHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	e := env.R(t)
+	obj := e.GET("/oauth2/jwks").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	keys := obj.Value("keys").Array()
+	keys.Length().Equal(1)
+	key := keys.First().Object()
+	key.Value("kty").String().Equal("EC")
+	key.Value("crv").String().Equal("P-256")
+	key.Value("alg").String().Equal("ES256")
+	key.Value("kid").String().Equal(jwt.PublicJWK().Kid)
+}
+
+func TestHandlers_UserInfoHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	scopesOpenID := model.AccessScopes{}
+	scopesOpenID.Add("read", model.ScopeOpenID)
+	scopesRead := model.AccessScopes{}
+	scopesRead.Add("read")
+
+	t.Run("No Authorization", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/userinfo").
+			Expect().
+			Status(http.StatusUnauthorized).
+			Header("WWW-Authenticate").Equal(authScheme)
+	})
+
+	t.Run("Invalid Token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+random.AlphaNumeric(36)).
+			Expect().
+			Status(http.StatusUnauthorized).
+			Header("WWW-Authenticate").Contains("invalid_token")
+	})
+
+	t.Run("Insufficient Scope", func(t *testing.T) {
+		t.Parallel()
+		token, err := env.Repository.IssueToken(nil, user.GetID(), "", scopesRead, 1000, false)
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusForbidden).
+			Header("WWW-Authenticate").Contains("insufficient_scope")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		token, err := env.Repository.IssueToken(nil, user.GetID(), "", scopesOpenID, 1000, false)
+		require.NoError(t, err)
+
+		e := env.R(t)
+		obj := e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		obj.Value("sub").String().Equal(user.GetID().String())
+		obj.Value("name").String().Equal(user.GetResponseDisplayName())
+		obj.Value("preferred_username").String().Equal(user.GetName())
+		obj.Value("picture").String().Equal("http://example.com/api/v3/public/icon/" + user.GetName())
+	})
+}
+
+func TestHandlers_TokenEndpointAuthorizationCodeHandler_IDToken(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db2)
+	user := env.CreateUser(t, rand)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read", model.ScopeOpenID)
+	client := &model.OAuth2Client{
+		ID:           random.AlphaNumeric(36),
+		Name:         "test client",
+		Confidential: false,
+		CreatorID:    uuid.Must(uuid.NewV4()),
+		Secret:       random.AlphaNumeric(36),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	authorize := &model.OAuth2Authorize{
+		Code:           random.AlphaNumeric(36),
+		ClientID:       client.ID,
+		UserID:         user.GetID(),
+		CreatedAt:      time.Now(),
+		ExpiresIn:      1000,
+		RedirectURI:    "http://example.com",
+		Scopes:         scopes,
+		OriginalScopes: scopes,
+		Nonce:          "nonce",
+	}
+	require.NoError(t, env.Repository.SaveAuthorize(authorize))
+
+	e := env.R(t)
+	obj := e.POST("/oauth2/token").
+		WithFormField("grant_type", grantTypeAuthorizationCode).
+		WithFormField("code", authorize.Code).
+		WithFormField("redirect_uri", "http://example.com").
+		WithFormField("client_id", client.ID).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	var claims idTokenClaims
+	if assert.NoError(t, jwt.Verify(obj.Value("id_token").String().Raw(), &claims)) {
+		assert.Equal(t, "http://example.com", claims.Issuer)
+		assert.Equal(t, user.GetID().String(), claims.Subject)
+		assert.Equal(t, client.ID, claims.Audience)
+		assert.Equal(t, "nonce", claims.Nonce)
+		assert.Equal(t, user.GetName(), claims.PreferredUsername)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/extension"
 	"go.uber.org/zap"
@@ -22,6 +23,7 @@ type tokenResponse struct {
 	ExpiresIn    int    `json:"expires_in,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
 }
 
 // TokenEndpointHandler トークンエンドポイントのハンドラ
@@ -38,6 +40,8 @@ func (h *Handler) TokenEndpointHandler(c echo.Context) error {
 		return h.tokenEndpointClientCredentialsHandler(c)
 	case grantTypeRefreshToken:
 		return h.tokenEndpointRefreshTokenHandler(c)
+	case grantTypeDeviceCode:
+		return h.tokenEndpointDeviceCodeHandler(c)
 	default:
 		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errUnsupportedGrantType})
 	}
@@ -134,6 +138,13 @@ func (h *Handler) tokenEndpointAuthorizationCodeHandler(c echo.Context) error {
 	if newToken.IsRefreshEnabled() {
 		res.RefreshToken = newToken.RefreshToken
 	}
+	if newToken.Scopes.Contains(model.ScopeOpenID) {
+		res.IDToken, err = h.issueIDTokenFor(client.ID, newToken.UserID, code.Nonce)
+		if err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
 	return c.JSON(http.StatusOK, res)
 }
 
@@ -387,5 +398,12 @@ func (h *Handler) tokenEndpointRefreshTokenHandler(c echo.Context) error {
 	if newToken.IsRefreshEnabled() {
 		res.RefreshToken = newToken.RefreshToken
 	}
+	if newToken.Scopes.Contains(model.ScopeOpenID) {
+		res.IDToken, err = h.issueIDTokenFor(client.ID, newToken.UserID, "")
+		if err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
 	return c.JSON(http.StatusOK, res)
 }
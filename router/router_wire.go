@@ -8,6 +8,7 @@ import (
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/oauth2"
+	"github.com/traPtitech/traQ/router/scim"
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/router/utils"
 	v1 "github.com/traPtitech/traQ/router/v1"
@@ -25,10 +26,12 @@ func newRouter(hub *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servic
 		message.NewReplacer,
 		provideOAuth2Config,
 		provideV3Config,
+		provideSCIMConfig,
 		session.NewGormStore,
 		wire.Struct(new(v1.Handlers), "*"),
 		wire.Struct(new(v3.Handlers), "*"),
 		wire.Struct(new(oauth2.Handler), "*"),
+		wire.Struct(new(scim.Handler), "*"),
 		wire.Struct(new(Router), "*"),
 	)
 	return nil
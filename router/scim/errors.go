@@ -0,0 +1,44 @@
+package scim
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+const (
+	scimTypeInvalidFilter = "invalidFilter"
+	scimTypeUniqueness    = "uniqueness"
+	scimTypeMutability    = "mutability"
+	scimTypeInvalidSyntax = "invalidSyntax"
+	scimTypeInvalidPath   = "invalidPath"
+	scimTypeInvalidValue  = "invalidValue"
+	scimTypeNoTarget      = "noTarget"
+)
+
+type scimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// errorResponse SCIMエラーレスポンスを返します
+func errorResponse(c echo.Context, status int, scimType, detail string) error {
+	return response(c, status, &scimError{
+		Schemas:  []string{schemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func notFound(c echo.Context) error {
+	return errorResponse(c, http.StatusNotFound, "", "resource not found")
+}
+
+func (h *Handler) internalServerError(c echo.Context, err error) error {
+	h.L(c).Error(err.Error(), zap.Error(err))
+	return errorResponse(c, http.StatusInternalServerError, "", http.StatusText(http.StatusInternalServerError))
+}
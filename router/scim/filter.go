@@ -0,0 +1,49 @@
+package scim
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// filterRegex `attr eq "value"` 形式のフィルタ
+var filterRegex = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9._:-]*)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+var errUnsupportedFilter = errors.New("unsupported filter")
+
+// eqFilter `attr eq "value"` 形式のフィルタ
+type eqFilter struct {
+	// Attr 属性名 (小文字)
+	Attr string
+	// Value 値
+	Value string
+}
+
+// parseFilter filterクエリを解釈します
+//
+// `attr eq "value"` 形式のみ対応しています。空文字の場合はnilを返します。
+// allowedAttrsに含まれない属性の場合はerrUnsupportedFilterを返します。
+func parseFilter(s string, allowedAttrs ...string) (*eqFilter, error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return nil, nil
+	}
+	m := filterRegex.FindStringSubmatch(s)
+	if m == nil {
+		return nil, errUnsupportedFilter
+	}
+	value, err := strconv.Unquote(m[2])
+	if err != nil {
+		return nil, errUnsupportedFilter
+	}
+
+	attr := strings.ToLower(m[1])
+	attr = strings.TrimPrefix(attr, strings.ToLower(schemaUser)+":")
+	attr = strings.TrimPrefix(attr, strings.ToLower(schemaGroup)+":")
+	for _, a := range allowedAttrs {
+		if attr == strings.ToLower(a) {
+			return &eqFilter{Attr: attr, Value: value}, nil
+		}
+	}
+	return nil, errUnsupportedFilter
+}
@@ -0,0 +1,41 @@
+package scim
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		filter string
+		want   *eqFilter
+		err    bool
+	}{
+		{"empty", "", nil, false},
+		{"spaces", "  ", nil, false},
+		{"userName", `userName eq "alice"`, &eqFilter{Attr: "username", Value: "alice"}, false},
+		{"case insensitive", `USERNAME EQ "alice"`, &eqFilter{Attr: "username", Value: "alice"}, false},
+		{"schema prefix", `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`, &eqFilter{Attr: "username", Value: "alice"}, false},
+		{"escaped quote", `userName eq "a\"b"`, &eqFilter{Attr: "username", Value: `a"b`}, false},
+		{"externalId", `externalId eq "00u1"`, &eqFilter{Attr: "externalid", Value: "00u1"}, false},
+		{"unsupported attr", `displayName eq "alice"`, nil, true},
+		{"unsupported op", `userName co "ali"`, nil, true},
+		{"compound", `userName eq "alice" and active eq true`, nil, true},
+		{"unquoted", `userName eq alice`, nil, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			f, err := parseFilter(tt.filter, "userName", "externalId")
+			if tt.err {
+				assert.Equal(t, errUnsupportedFilter, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, f)
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package scim
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/optional"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultGroupAdminName = "traq"
+
+// groupResource SCIM Groupリソース
+type groupResource struct {
+	Schemas     []string      `json:"schemas"`
+	ID          string        `json:"id"`
+	DisplayName string        `json:"displayName"`
+	Members     []groupMember `json:"members"`
+	Meta        meta          `json:"meta"`
+}
+
+// groupMember SCIM Groupリソースのメンバー
+type groupMember struct {
+	Value string `json:"value"`
+	Ref   string `json:"$ref,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// groupRequest POST, PUT /Groups リクエストボディ
+type groupRequest struct {
+	DisplayName string        `json:"displayName"`
+	Members     []groupMember `json:"members"`
+}
+
+func (r groupRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.DisplayName, vd.Required, vd.RuneLength(1, 30)),
+	)
+}
+
+func (h *Handler) formatGroup(g *model.UserGroup) *groupResource {
+	members := make([]groupMember, len(g.Members))
+	for i, m := range g.Members {
+		members[i] = groupMember{
+			Value: m.UserID.String(),
+			Ref:   h.location("Users", m.UserID.String()),
+			Type:  "User",
+		}
+	}
+	return &groupResource{
+		Schemas:     []string{schemaGroup},
+		ID:          g.ID.String(),
+		DisplayName: g.Name,
+		Members:     members,
+		Meta: meta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: g.UpdatedAt.UTC().Format(time.RFC3339),
+			Location:     h.location("Groups", g.ID.String()),
+		},
+	}
+}
+
+// findGroup パスパラメータのIDのグループを取得します
+//
+// SCIMで作成されたグループのみが対象です。存在しない場合はnilを返します。
+func (h *Handler) findGroup(c echo.Context) (*model.UserGroup, error) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		return nil, nil
+	}
+	g, err := h.Repo.GetUserGroup(id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if g.Source != GroupSource {
+		return nil, nil
+	}
+	return g, nil
+}
+
+func (h *Handler) groupResponse(c echo.Context, code int, groupID uuid.UUID) error {
+	g, err := h.Repo.GetUserGroup(groupID)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	return response(c, code, h.formatGroup(g))
+}
+
+// resolveMembers メンバーのvalue(traQユーザーUUID)を検証してUUIDの集合を返します
+//
+// 存在しないユーザーやBotユーザーが含まれている場合、errInvalidPatchValueを返します。
+func (h *Handler) resolveMembers(members []groupMember) (map[uuid.UUID]bool, error) {
+	result := make(map[uuid.UUID]bool, len(members))
+	for _, m := range members {
+		id, err := uuid.FromString(m.Value)
+		if err != nil {
+			return nil, errInvalidPatchValue
+		}
+		if result[id] {
+			continue
+		}
+		user, err := h.Repo.GetUser(id, false)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return nil, errInvalidPatchValue
+			}
+			return nil, err
+		}
+		if user.IsBot() {
+			return nil, errInvalidPatchValue
+		}
+		result[id] = true
+	}
+	return result, nil
+}
+
+// syncMembers グループのメンバーをdesiredに一致させます
+func (h *Handler) syncMembers(g *model.UserGroup, desired map[uuid.UUID]bool) error {
+	current := make(map[uuid.UUID]bool, len(g.Members))
+	for _, m := range g.Members {
+		current[m.UserID] = true
+	}
+	for id := range desired {
+		if !current[id] {
+			if err := h.Repo.AddUserToGroup(id, g.ID, ""); err != nil {
+				return err
+			}
+		}
+	}
+	for id := range current {
+		if !desired[id] {
+			if err := h.Repo.RemoveUserFromGroup(id, g.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetGroups GET /Groups
+func (h *Handler) GetGroups(c echo.Context) error {
+	f, err := parseFilter(c.QueryParam("filter"), "displayName")
+	if err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidFilter, "only `displayName eq` filter is supported")
+	}
+
+	all, err := h.Repo.GetAllUserGroups()
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	groups := make([]*model.UserGroup, 0, len(all))
+	for _, g := range all {
+		if g.Source != GroupSource {
+			continue
+		}
+		if f != nil && g.Name != f.Value {
+			continue
+		}
+		groups = append(groups, g)
+	}
+
+	startIndex, count := pagination(c)
+	from, to := paginate(len(groups), startIndex, count)
+	resources := make([]*groupResource, 0, to-from)
+	for _, g := range groups[from:to] {
+		resources = append(resources, h.formatGroup(g))
+	}
+	return response(c, http.StatusOK, &listResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: len(groups),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// CreateGroup POST /Groups
+func (h *Handler) CreateGroup(c echo.Context) error {
+	var req groupRequest
+	if err := bind(c, &req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidSyntax, "invalid request body")
+	}
+	if err := req.Validate(); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, err.Error())
+	}
+	members, err := h.resolveMembers(req.Members)
+	if err != nil {
+		if err == errInvalidPatchValue {
+			return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, "invalid member")
+		}
+		return h.internalServerError(c, err)
+	}
+
+	adminName := h.GroupAdminName
+	if len(adminName) == 0 {
+		adminName = defaultGroupAdminName
+	}
+	admin, err := h.Repo.GetUserByName(adminName, false)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+
+	g, err := h.Repo.CreateExternalUserGroup(req.DisplayName, GroupSource, admin.GetID())
+	if err != nil {
+		return h.groupUpdateError(c, err)
+	}
+	if err := h.syncMembers(g, members); err != nil {
+		return h.internalServerError(c, err)
+	}
+	h.L(c).Info("New user group was created by scim", zap.Stringer("id", g.ID), zap.String("name", g.Name))
+
+	return h.groupResponse(c, http.StatusCreated, g.ID)
+}
+
+// GetGroup GET /Groups/:id
+func (h *Handler) GetGroup(c echo.Context) error {
+	g, err := h.findGroup(c)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	if g == nil {
+		return notFound(c)
+	}
+	return response(c, http.StatusOK, h.formatGroup(g))
+}
+
+// ReplaceGroup PUT /Groups/:id
+func (h *Handler) ReplaceGroup(c echo.Context) error {
+	g, err := h.findGroup(c)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	if g == nil {
+		return notFound(c)
+	}
+
+	var req groupRequest
+	if err := bind(c, &req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidSyntax, "invalid request body")
+	}
+	if err := req.Validate(); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, err.Error())
+	}
+	members, err := h.resolveMembers(req.Members)
+	if err != nil {
+		if err == errInvalidPatchValue {
+			return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, "invalid member")
+		}
+		return h.internalServerError(c, err)
+	}
+
+	if err := h.renameGroup(g, req.DisplayName); err != nil {
+		return h.groupUpdateError(c, err)
+	}
+	if err := h.syncMembers(g, members); err != nil {
+		return h.internalServerError(c, err)
+	}
+
+	return h.groupResponse(c, http.StatusOK, g.ID)
+}
+
+// PatchGroup PATCH /Groups/:id
+func (h *Handler) PatchGroup(c echo.Context) error {
+	g, err := h.findGroup(c)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	if g == nil {
+		return notFound(c)
+	}
+
+	var req patchRequest
+	if err := bind(c, &req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidSyntax, "invalid request body")
+	}
+
+	desired := make(map[uuid.UUID]bool, len(g.Members))
+	for _, m := range g.Members {
+		desired[m.UserID] = true
+	}
+	var displayName optional.String
+
+	parseMembers := func(value jsoniter.RawMessage) (map[uuid.UUID]bool, error) {
+		var members []groupMember
+		if err := json.Unmarshal(value, &members); err != nil {
+			return nil, errInvalidPatchValue
+		}
+		return h.resolveMembers(members)
+	}
+	apply := func(op, path string, value jsoniter.RawMessage) error {
+		switch path {
+		case "members":
+			members, err := parseMembers(value)
+			if err != nil {
+				return err
+			}
+			if op == patchOpReplace {
+				desired = members
+			} else {
+				for id := range members {
+					desired[id] = true
+				}
+			}
+		case "displayname":
+			s, err := parseStringValue(value)
+			if err != nil {
+				return err
+			}
+			displayName = optional.StringFrom(s)
+		default:
+			// 未対応の属性は無視する
+		}
+		return nil
+	}
+
+	for _, op := range req.Operations {
+		var err error
+		switch op.op() {
+		case patchOpAdd, patchOpReplace:
+			if path := op.path(); len(path) > 0 {
+				err = apply(op.op(), path, op.Value)
+			} else {
+				// パス指定無しの場合はvalueが属性のオブジェクト
+				var values map[string]jsoniter.RawMessage
+				if err = json.Unmarshal(op.Value, &values); err != nil {
+					return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, "invalid patch value")
+				}
+				for k, v := range values {
+					if err = apply(op.op(), strings.ToLower(k), v); err != nil {
+						break
+					}
+				}
+			}
+		case patchOpRemove:
+			if id, ok := parseMemberPath(op.Path); ok {
+				if uid, e := uuid.FromString(id); e == nil {
+					delete(desired, uid)
+				}
+				break
+			}
+			if op.path() != "members" {
+				return errorResponse(c, http.StatusBadRequest, scimTypeInvalidPath, "unsupported path")
+			}
+			if len(op.Value) == 0 {
+				desired = map[uuid.UUID]bool{}
+				break
+			}
+			var members []groupMember
+			if err = json.Unmarshal(op.Value, &members); err == nil {
+				for _, m := range members {
+					if uid, e := uuid.FromString(m.Value); e == nil {
+						delete(desired, uid)
+					}
+				}
+			} else {
+				err = errInvalidPatchValue
+			}
+		default:
+			return errorResponse(c, http.StatusBadRequest, scimTypeInvalidSyntax, "unsupported patch op")
+		}
+		switch err {
+		case nil:
+		case errInvalidPatchValue:
+			return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, "invalid patch value")
+		default:
+			return h.internalServerError(c, err)
+		}
+	}
+
+	if displayName.Valid {
+		if vd.Validate(displayName.String, vd.Required, vd.RuneLength(1, 30)) != nil {
+			return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, "invalid displayName")
+		}
+		if err := h.renameGroup(g, displayName.String); err != nil {
+			return h.groupUpdateError(c, err)
+		}
+	}
+	if err := h.syncMembers(g, desired); err != nil {
+		return h.internalServerError(c, err)
+	}
+
+	return h.groupResponse(c, http.StatusOK, g.ID)
+}
+
+// renameGroup グループ名を変更します
+func (h *Handler) renameGroup(g *model.UserGroup, name string) error {
+	if g.Name == name {
+		return nil
+	}
+	return h.Repo.UpdateUserGroup(g.ID, repository.UpdateUserGroupNameArgs{Name: optional.StringFrom(name)})
+}
+
+// groupUpdateError グループの作成・更新時のエラーをレスポンスに変換します
+func (h *Handler) groupUpdateError(c echo.Context, err error) error {
+	switch {
+	case err == repository.ErrAlreadyExists:
+		return errorResponse(c, http.StatusConflict, scimTypeUniqueness, "displayName conflicts")
+	case repository.IsArgError(err):
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, err.Error())
+	default:
+		return h.internalServerError(c, err)
+	}
+}
+
+// DeleteGroup DELETE /Groups/:id
+func (h *Handler) DeleteGroup(c echo.Context) error {
+	g, err := h.findGroup(c)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	if g == nil {
+		return notFound(c)
+	}
+
+	if err := h.Repo.DeleteUserGroup(g.ID); err != nil {
+		return h.internalServerError(c, err)
+	}
+	h.L(c).Info("user group was deleted by scim", zap.Stringer("id", g.ID), zap.String("name", g.Name))
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,267 @@
+package scim
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandler_GetGroups(t *testing.T) {
+	t.Parallel()
+	path := "/scim/v2/Groups"
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+	g := env.CreateGroup(t, rand, user.GetID())
+
+	t.Run("invalid filter", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithQuery("filter", `userName eq "a"`).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("filter by displayName", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithQuery("filter", `displayName eq "`+g.Name+`"`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSON).
+			Object()
+
+		obj.Value("totalResults").Number().Equal(1)
+		res := obj.Value("Resources").Array()
+		res.Length().Equal(1)
+		res.First().Object().Value("id").String().Equal(g.ID.String())
+		members := res.First().Object().Value("members").Array()
+		members.Length().Equal(1)
+		members.First().Object().Value("value").String().Equal(user.GetID().String())
+	})
+
+	t.Run("groups not created by scim", func(t *testing.T) {
+		t.Parallel()
+		name := random.AlphaNumeric(20)
+		_, err := env.Repository.CreateUserGroup(name, "", "", user.GetID())
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.GET(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithQuery("filter", `displayName eq "`+name+`"`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSON).
+			Object().
+			Value("totalResults").Number().Equal(0)
+	})
+}
+
+func TestHandler_CreateGroup(t *testing.T) {
+	t.Parallel()
+	path := "/scim/v2/Groups"
+	env := Setup(t, common)
+
+	t.Run("unauthorized", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithJSON(echo.Map{"displayName": random.AlphaNumeric(20)}).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("invalid displayName", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{"displayName": strings.Repeat("a", 31)}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON(scimJSON).
+			Object().
+			Value("scimType").String().Equal(scimTypeInvalidValue)
+	})
+
+	t.Run("invalid member", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{
+				"displayName": random.AlphaNumeric(20),
+				"members":     []echo.Map{{"value": uuid.Must(uuid.NewV4()).String()}},
+			}).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("displayName conflicts", func(t *testing.T) {
+		t.Parallel()
+		g := env.CreateGroup(t, rand)
+
+		e := env.R(t)
+		e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{"displayName": g.Name}).
+			Expect().
+			Status(http.StatusConflict).
+			JSON(scimJSON).
+			Object().
+			Value("scimType").String().Equal(scimTypeUniqueness)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		name := random.AlphaNumeric(20)
+
+		e := env.R(t)
+		obj := e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{
+				"displayName": name,
+				"members":     []echo.Map{{"value": user.GetID().String()}},
+			}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON(scimJSON).
+			Object()
+
+		obj.Value("displayName").String().Equal(name)
+		obj.Value("members").Array().Length().Equal(1)
+
+		id := uuid.FromStringOrNil(obj.Value("id").String().Raw())
+		g, err := env.Repository.GetUserGroup(id)
+		require.NoError(t, err)
+		assert.Equal(t, name, g.Name)
+		assert.Equal(t, GroupSource, g.Source)
+		admin, err := env.Repository.GetUserByName(defaultGroupAdminName, false)
+		require.NoError(t, err)
+		assert.True(t, g.IsAdmin(admin.GetID()))
+		assert.True(t, g.IsMember(user.GetID()))
+	})
+}
+
+func TestHandler_GetGroup(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/Groups/{id}", uuid.Must(uuid.NewV4())).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("group not created by scim", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		g, err := env.Repository.CreateUserGroup(random.AlphaNumeric(20), "", "", user.GetID())
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.GET("/scim/v2/Groups/{id}", g.ID).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		g := env.CreateGroup(t, rand)
+
+		e := env.R(t)
+		obj := e.GET("/scim/v2/Groups/{id}", g.ID).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSON).
+			Object()
+
+		obj.Value("id").String().Equal(g.ID.String())
+		obj.Value("displayName").String().Equal(g.Name)
+	})
+}
+
+func TestHandler_PatchGroup(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+
+	t.Run("members", func(t *testing.T) {
+		t.Parallel()
+		user1 := env.CreateUser(t, rand)
+		user2 := env.CreateUser(t, rand)
+		g := env.CreateGroup(t, rand, user1.GetID())
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Groups/{id}", g.ID).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{
+				"schemas": []string{schemaPatchOp},
+				"Operations": []echo.Map{
+					{"op": "add", "path": "members", "value": []echo.Map{{"value": user2.GetID().String()}}},
+					{"op": "remove", "path": `members[value eq "` + user1.GetID().String() + `"]`},
+				},
+			}).
+			Expect().
+			Status(http.StatusOK)
+
+		g, err := env.Repository.GetUserGroup(g.ID)
+		require.NoError(t, err)
+		assert.False(t, g.IsMember(user1.GetID()))
+		assert.True(t, g.IsMember(user2.GetID()))
+	})
+
+	t.Run("displayName", func(t *testing.T) {
+		t.Parallel()
+		g := env.CreateGroup(t, rand)
+		name := random.AlphaNumeric(20)
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Groups/{id}", g.ID).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{
+				"schemas":    []string{schemaPatchOp},
+				"Operations": []echo.Map{{"op": "replace", "path": "displayName", "value": name}},
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSON).
+			Object().
+			Value("displayName").String().Equal(name)
+	})
+}
+
+func TestHandler_DeleteGroup(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	g := env.CreateGroup(t, rand)
+
+	e := env.R(t)
+	e.DELETE("/scim/v2/Groups/{id}", g.ID).
+		WithHeader(echo.HeaderAuthorization, authorization).
+		Expect().
+		Status(http.StatusNoContent)
+
+	_, err := env.Repository.GetUserGroup(g.ID)
+	assert.EqualError(t, err, repository.ErrNotFound.Error())
+
+	e.DELETE("/scim/v2/Groups/{id}", g.ID).
+		WithHeader(echo.HeaderAuthorization, authorization).
+		Expect().
+		Status(http.StatusNotFound)
+}
@@ -0,0 +1,90 @@
+package scim
+
+import (
+	"errors"
+	jsoniter "github.com/json-iterator/go"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchOpAdd     = "add"
+	patchOpReplace = "replace"
+	patchOpRemove  = "remove"
+)
+
+// memberPathRegex `members[value eq "id"]` 形式のパス
+var memberPathRegex = regexp.MustCompile(`(?i)^\s*members\s*\[\s*value\s+eq\s+("(?:[^"\\]|\\.)*")\s*\]\s*$`)
+
+var (
+	errInvalidPatchValue = errors.New("invalid patch value")
+	errUserNameImmutable = errors.New("userName cannot be changed")
+)
+
+// patchRequest PATCHリクエストボディ
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+// patchOperation PATCH操作
+type patchOperation struct {
+	Op    string              `json:"op"`
+	Path  string              `json:"path"`
+	Value jsoniter.RawMessage `json:"value"`
+}
+
+// op 小文字に正規化した操作名を返します
+func (o *patchOperation) op() string {
+	return strings.ToLower(o.Op)
+}
+
+// path 小文字に正規化したパスを返します (スキーマURNのプレフィックスは取り除きます)
+func (o *patchOperation) path() string {
+	p := strings.ToLower(strings.TrimSpace(o.Path))
+	p = strings.TrimPrefix(p, strings.ToLower(schemaUser)+":")
+	p = strings.TrimPrefix(p, strings.ToLower(schemaGroup)+":")
+	return p
+}
+
+// parseBoolValue 真偽値を解釈します
+//
+// 一部のIdPは真偽値を文字列("True", "False")で送ってくるため、文字列も受け付けます。
+func parseBoolValue(raw jsoniter.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return false, errInvalidPatchValue
+	}
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return false, errInvalidPatchValue
+	}
+	return b, nil
+}
+
+// parseStringValue 文字列を解釈します
+func parseStringValue(raw jsoniter.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", errInvalidPatchValue
+	}
+	return s, nil
+}
+
+// parseMemberPath `members[value eq "id"]` 形式のパスからメンバーのIDを取り出します
+func parseMemberPath(path string) (string, bool) {
+	m := memberPathRegex.FindStringSubmatch(path)
+	if m == nil {
+		return "", false
+	}
+	v, err := strconv.Unquote(m[1])
+	if err != nil {
+		return "", false
+	}
+	return v, true
+}
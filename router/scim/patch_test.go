@@ -0,0 +1,64 @@
+package scim
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseBoolValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		raw  string
+		want bool
+		err  bool
+	}{
+		{`true`, true, false},
+		{`false`, false, false},
+		{`"True"`, true, false},
+		{`"False"`, false, false},
+		{`"yes"`, false, true},
+		{`1`, false, true},
+		{`{}`, false, true},
+	}
+	for _, tt := range tests {
+		b, err := parseBoolValue(jsoniter.RawMessage(tt.raw))
+		if tt.err {
+			assert.Equal(t, errInvalidPatchValue, err, tt.raw)
+		} else if assert.NoError(t, err, tt.raw) {
+			assert.Equal(t, tt.want, b, tt.raw)
+		}
+	}
+}
+
+func TestParseMemberPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{`members[value eq "2c8e4b3a-0d5f-4b4e-9a1c-7f4f0e3e9d10"]`, "2c8e4b3a-0d5f-4b4e-9a1c-7f4f0e3e9d10", true},
+		{`Members[Value EQ "abc"]`, "abc", true},
+		{` members [ value eq "abc" ] `, "abc", true},
+		{`members`, "", false},
+		{`members[display eq "abc"]`, "", false},
+		{`members[value eq abc]`, "", false},
+	}
+	for _, tt := range tests {
+		v, ok := parseMemberPath(tt.path)
+		assert.Equal(t, tt.ok, ok, tt.path)
+		assert.Equal(t, tt.want, v, tt.path)
+	}
+}
+
+func TestPatchOperation_path(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "active", (&patchOperation{Path: "active"}).path())
+	assert.Equal(t, "displayname", (&patchOperation{Path: " displayName "}).path())
+	assert.Equal(t, "username", (&patchOperation{Path: "urn:ietf:params:scim:schemas:core:2.0:User:userName"}).path())
+	assert.Equal(t, "", (&patchOperation{}).path())
+}
@@ -0,0 +1,195 @@
+package scim
+
+import (
+	"fmt"
+	"github.com/gavv/httpexpect/v2"
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/migration"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/storage"
+	"go.uber.org/zap"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+const (
+	dbPrefix  = "traq-test-router-scim-"
+	common    = "common"
+	rand      = "random"
+	testToken = "scim-test-token"
+)
+
+var envs = map[string]*Env{}
+
+func TestMain(m *testing.M) {
+	user := getEnvOrDefault("MARIADB_USERNAME", "root")
+	pass := getEnvOrDefault("MARIADB_PASSWORD", "password")
+	host := getEnvOrDefault("MARIADB_HOSTNAME", "127.0.0.1")
+	port := getEnvOrDefault("MARIADB_PORT", "3306")
+	dbs := []string{
+		common,
+	}
+	if err := migration.CreateDatabasesIfNotExists("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=utf8mb4&parseTime=true", user, pass, host, port), dbPrefix, dbs...); err != nil {
+		panic(err)
+	}
+
+	for _, key := range dbs {
+		env := &Env{}
+
+		// テスト用データベース接続
+		db, err := gorm.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=true", user, pass, host, port, fmt.Sprintf("%s%s", dbPrefix, key)))
+		if err != nil {
+			panic(err)
+		}
+		db.DB().SetMaxOpenConns(20)
+		if err := migration.DropAll(db); err != nil {
+			panic(err)
+		}
+
+		env.DB = db
+		env.Hub = hub.New()
+
+		// テスト用リポジトリ作成
+		repo, err := repository.NewGormRepository(db, env.Hub, zap.NewNop())
+		if err != nil {
+			panic(err)
+		}
+		if _, err := repo.Sync(); err != nil {
+			panic(err)
+		}
+		env.Repository = repo
+		fm, err := file.InitFileManager(repo, storage.NewInMemoryFileStorage(), imaging.NewProcessor(imaging.Config{
+			MaxPixels:        1000 * 1000,
+			Concurrency:      1,
+			ThumbnailMaxSize: image.Pt(360, 480),
+		}), nil, file.SanitizeConfig{}, zap.NewNop())
+		if err != nil {
+			panic(err)
+		}
+
+		// テスト用サーバー作成
+		e := echo.New()
+		e.HideBanner = true
+		e.HidePort = true
+		e.HTTPErrorHandler = extension.ErrorHandler(zap.NewNop())
+
+		handler := &Handler{
+			Repo:        repo,
+			Logger:      zap.NewNop(),
+			FileManager: fm,
+			Config: Config{
+				Token:  testToken,
+				Origin: "http://example.com",
+			},
+		}
+		handler.Setup(e.Group("/scim/v2"))
+		env.Server = httptest.NewServer(e)
+
+		envs[key] = env
+	}
+
+	// テスト実行
+	code := m.Run()
+
+	// 後始末
+	for _, env := range envs {
+		env.Server.Close()
+		env.DB.Close()
+		env.Hub.Close()
+	}
+	os.Exit(code)
+}
+
+type Env struct {
+	Server     *httptest.Server
+	DB         *gorm.DB
+	Repository repository.Repository
+	Hub        *hub.Hub
+}
+
+// Setup テストセットアップ
+func Setup(t *testing.T, server string) *Env {
+	t.Helper()
+	env, ok := envs[server]
+	if !ok {
+		t.FailNow()
+	}
+	return env
+}
+
+// R リクエストテスターを作成
+func (env *Env) R(t *testing.T) *httpexpect.Expect {
+	t.Helper()
+	return httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  env.Server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+		Printers: []httpexpect.Printer{
+			httpexpect.NewCurlPrinter(t),
+			httpexpect.NewDebugPrinter(t, true),
+		},
+		Client: &http.Client{
+			Jar:     nil, // クッキーは保持しない
+			Timeout: time.Second * 30,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse // リダイレクトを自動処理しない
+			},
+		},
+	})
+}
+
+// CreateUser ユーザーを必ず作成します
+func (env *Env) CreateUser(t *testing.T, userName string) model.UserInfo {
+	t.Helper()
+	if userName == rand {
+		userName = random.AlphaNumeric(32)
+	}
+	u, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: userName, Password: "testtesttesttest", Role: role.User, IconFileID: uuid.Must(uuid.NewV4())})
+	require.NoError(t, err)
+	return u
+}
+
+// CreateGroup SCIMで作成されたユーザーグループを必ず作成します
+func (env *Env) CreateGroup(t *testing.T, name string, members ...uuid.UUID) *model.UserGroup {
+	t.Helper()
+	if name == rand {
+		name = random.AlphaNumeric(20)
+	}
+	admin, err := env.Repository.GetUserByName(defaultGroupAdminName, false)
+	require.NoError(t, err)
+	g, err := env.Repository.CreateExternalUserGroup(name, GroupSource, admin.GetID())
+	require.NoError(t, err)
+	for _, id := range members {
+		require.NoError(t, env.Repository.AddUserToGroup(id, g.ID, ""))
+	}
+	return g
+}
+
+// scimJSON SCIMレスポンスのContent-Type
+var scimJSON = httpexpect.ContentOpts{MediaType: mimeSCIM}
+
+// authorization テスト用トークンのAuthorizationヘッダー
+const authorization = authScheme + " " + testToken
+
+func getEnvOrDefault(env string, def string) string {
+	s := os.Getenv(env)
+	if len(s) == 0 {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,210 @@
+package scim
+
+import (
+	"crypto/subtle"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/service/file"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProviderName SCIMのexternalIdを保存する外部ログインアカウントのプロバイダ名
+	ProviderName = "scim"
+	// GroupSource SCIMで作成されたユーザーグループのソース名
+	GroupSource = "scim"
+
+	mimeSCIM   = "application/scim+json"
+	authScheme = "Bearer"
+
+	schemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	schemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	schemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	schemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	defaultListCount = 100
+	maxListCount     = 1000
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// Handler SCIM 2.0 プロビジョニングAPIハンドラ
+type Handler struct {
+	Repo        repository.Repository
+	Logger      *zap.Logger
+	FileManager file.Manager
+	Config
+}
+
+// Config SCIM設定
+type Config struct {
+	// Token 認証用Bearerトークン
+	Token string
+	// GroupAdminName SCIMで作成されるグループの管理者にするtraQユーザー名
+	GroupAdminName string
+	// Origin traQのオリジン (リソースのlocationに使用)
+	Origin string
+}
+
+// Valid SCIMが有効かどうか
+func (c Config) Valid() bool {
+	return len(c.Token) > 0
+}
+
+// Setup SCIMのルーティングを行います
+func (h *Handler) Setup(e *echo.Group) {
+	e.Use(h.authenticate)
+	e.GET("/ServiceProviderConfig", h.GetServiceProviderConfig)
+
+	e.GET("/Users", h.GetUsers)
+	e.POST("/Users", h.CreateUser)
+	e.GET("/Users/:id", h.GetUser)
+	e.PUT("/Users/:id", h.ReplaceUser)
+	e.PATCH("/Users/:id", h.PatchUser)
+	e.DELETE("/Users/:id", h.DeleteUser)
+
+	e.GET("/Groups", h.GetGroups)
+	e.POST("/Groups", h.CreateGroup)
+	e.GET("/Groups/:id", h.GetGroup)
+	e.PUT("/Groups/:id", h.ReplaceGroup)
+	e.PATCH("/Groups/:id", h.PatchGroup)
+	e.DELETE("/Groups/:id", h.DeleteGroup)
+}
+
+// authenticate Bearerトークンを検証するミドルウェア
+func (h *Handler) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ah := c.Request().Header.Get(echo.HeaderAuthorization)
+		if len(ah) <= len(authScheme)+1 || !strings.EqualFold(ah[:len(authScheme)], authScheme) {
+			return errorResponse(c, http.StatusUnauthorized, "", "missing bearer token")
+		}
+		token := strings.TrimSpace(ah[len(authScheme)+1:])
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+			return errorResponse(c, http.StatusUnauthorized, "", "invalid bearer token")
+		}
+		return next(c)
+	}
+}
+
+type serviceProviderConfig struct {
+	Schemas          []string      `json:"schemas"`
+	Patch            supported     `json:"patch"`
+	Bulk             bulk          `json:"bulk"`
+	Filter           filterSupport `json:"filter"`
+	ChangePassword   supported     `json:"changePassword"`
+	Sort             supported     `json:"sort"`
+	ETag             supported     `json:"etag"`
+	AuthSchemes      []scheme      `json:"authenticationSchemes"`
+	DocumentationURI string        `json:"documentationUri,omitempty"`
+}
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type bulk struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type filterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type scheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// GetServiceProviderConfig GET /ServiceProviderConfig
+func (h *Handler) GetServiceProviderConfig(c echo.Context) error {
+	return response(c, http.StatusOK, &serviceProviderConfig{
+		Schemas:        []string{schemaServiceProviderConfig},
+		Patch:          supported{Supported: true},
+		Filter:         filterSupport{Supported: true, MaxResults: maxListCount},
+		ChangePassword: supported{Supported: true},
+		AuthSchemes: []scheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication scheme using the OAuth Bearer Token Standard",
+		}},
+	})
+}
+
+type meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	Location     string `json:"location"`
+}
+
+type listResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// pagination startIndex(1始まり)とcountクエリを解釈します
+func pagination(c echo.Context) (startIndex, count int) {
+	startIndex, count = 1, defaultListCount
+	if v, err := strconv.Atoi(c.QueryParam("startIndex")); err == nil && v > 1 {
+		startIndex = v
+	}
+	if v, err := strconv.Atoi(c.QueryParam("count")); err == nil && v >= 0 {
+		count = v
+	}
+	if count > maxListCount {
+		count = maxListCount
+	}
+	return
+}
+
+// paginate 全件数totalのうち、返すべき範囲[from, to)を返します
+func paginate(total, startIndex, count int) (from, to int) {
+	from = startIndex - 1
+	if from > total {
+		from = total
+	}
+	to = from + count
+	if to > total {
+		to = total
+	}
+	return
+}
+
+func (h *Handler) location(resourceType, id string) string {
+	return strings.TrimSuffix(h.Origin, "/") + "/api/scim/v2/" + resourceType + "/" + id
+}
+
+// bind リクエストボディをデシリアライズします
+//
+// application/scim+jsonはechoのBinderが対応していないため自前でデコードします。
+func bind(c echo.Context, i interface{}) error {
+	return json.NewDecoder(c.Request().Body).Decode(i)
+}
+
+// response SCIMレスポンスを返します
+func response(c echo.Context, code int, i interface{}) error {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return c.Blob(code, mimeSCIM, b)
+}
+
+// L ロガーを返します
+func (h *Handler) L(c echo.Context) *zap.Logger {
+	return h.Logger.With(zap.String("requestId", extension.GetRequestID(c)))
+}
@@ -0,0 +1,480 @@
+package scim
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/validator"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// userResource SCIM Userリソース
+type userResource struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	DisplayName string   `json:"displayName"`
+	Active      bool     `json:"active"`
+	Meta        meta     `json:"meta"`
+}
+
+// userRequest POST, PUT /Users リクエストボディ
+type userRequest struct {
+	ExternalID  *string `json:"externalId"`
+	UserName    string  `json:"userName"`
+	DisplayName *string `json:"displayName"`
+	Name        *struct {
+		Formatted  string `json:"formatted"`
+		FamilyName string `json:"familyName"`
+		GivenName  string `json:"givenName"`
+	} `json:"name"`
+	Active   *bool   `json:"active"`
+	Password *string `json:"password"`
+}
+
+func (r userRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.UserName, validator.UserNameRuleRequired...),
+		vd.Field(&r.Password, validator.PasswordRule...),
+	)
+}
+
+// displayName 表示名を返します
+//
+// displayNameが無い場合はnameから組み立てます。
+func (r userRequest) displayName() string {
+	var s string
+	switch {
+	case r.DisplayName != nil:
+		s = *r.DisplayName
+	case r.Name != nil && len(r.Name.Formatted) > 0:
+		s = r.Name.Formatted
+	case r.Name != nil:
+		s = strings.TrimSpace(r.Name.GivenName + " " + r.Name.FamilyName)
+	}
+	if rs := []rune(s); len(rs) > 64 {
+		s = string(rs[:64])
+	}
+	return s
+}
+
+func (h *Handler) formatUser(user model.UserInfo) (*userResource, error) {
+	externalID, err := h.getExternalID(user.GetID())
+	if err != nil {
+		return nil, err
+	}
+	return &userResource{
+		Schemas:     []string{schemaUser},
+		ID:          user.GetID().String(),
+		ExternalID:  externalID,
+		UserName:    user.GetName(),
+		DisplayName: user.GetDisplayName(),
+		Active:      user.GetState() == model.UserAccountStatusActive,
+		Meta: meta{
+			ResourceType: "User",
+			Created:      user.GetCreatedAt().UTC().Format(time.RFC3339),
+			LastModified: user.GetUpdatedAt().UTC().Format(time.RFC3339),
+			Location:     h.location("Users", user.GetID().String()),
+		},
+	}, nil
+}
+
+// getExternalID ユーザーに関連付けられているSCIMのexternalIdを取得します
+func (h *Handler) getExternalID(userID uuid.UUID) (string, error) {
+	accounts, err := h.Repo.GetLinkedExternalUserAccounts(userID)
+	if err != nil {
+		return "", err
+	}
+	for _, a := range accounts {
+		if a.ProviderName == ProviderName {
+			return a.ExternalID, nil
+		}
+	}
+	return "", nil
+}
+
+// setExternalID ユーザーに関連付けるSCIMのexternalIdを変更します
+func (h *Handler) setExternalID(userID uuid.UUID, externalID string) error {
+	current, err := h.getExternalID(userID)
+	if err != nil {
+		return err
+	}
+	if current == externalID {
+		return nil
+	}
+	if len(current) > 0 {
+		if err := h.Repo.UnlinkExternalUserAccount(userID, ProviderName); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+	}
+	if len(externalID) > 0 {
+		return h.Repo.LinkExternalUserAccount(userID, repository.LinkExternalUserAccountArgs{
+			ProviderName: ProviderName,
+			ExternalID:   externalID,
+			Extra:        model.JSON{},
+		})
+	}
+	return nil
+}
+
+// findUser パスパラメータのIDのユーザーを取得します
+//
+// Botユーザーは対象外です。存在しない場合はnilを返します。
+func (h *Handler) findUser(c echo.Context) (model.UserInfo, error) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		return nil, nil
+	}
+	user, err := h.Repo.GetUser(id, false)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if user.IsBot() {
+		return nil, nil
+	}
+	return user, nil
+}
+
+func (h *Handler) userResponse(c echo.Context, code int, userID uuid.UUID) error {
+	user, err := h.Repo.GetUser(userID, false)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	res, err := h.formatUser(user)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	return response(c, code, res)
+}
+
+func accountState(active bool) model.UserAccountStatus {
+	if active {
+		return model.UserAccountStatusActive
+	}
+	return model.UserAccountStatusDeactivated
+}
+
+// GetUsers GET /Users
+func (h *Handler) GetUsers(c echo.Context) error {
+	f, err := parseFilter(c.QueryParam("filter"), "userName", "externalId")
+	if err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidFilter, "only `userName eq` and `externalId eq` filters are supported")
+	}
+
+	var users []model.UserInfo
+	switch {
+	case f == nil:
+		users, err = h.Repo.GetUsers(repository.UsersQuery{}.NotBot())
+	case f.Attr == "username":
+		var u model.UserInfo
+		u, err = h.Repo.GetUserByName(f.Value, false)
+		if err == nil && !u.IsBot() {
+			users = append(users, u)
+		}
+	case f.Attr == "externalid":
+		var u model.UserInfo
+		u, err = h.Repo.GetUserByExternalID(ProviderName, f.Value, false)
+		if err == nil && !u.IsBot() {
+			users = append(users, u)
+		}
+	}
+	if err != nil && err != repository.ErrNotFound {
+		return h.internalServerError(c, err)
+	}
+
+	startIndex, count := pagination(c)
+	from, to := paginate(len(users), startIndex, count)
+	resources := make([]*userResource, 0, to-from)
+	for _, u := range users[from:to] {
+		res, err := h.formatUser(u)
+		if err != nil {
+			return h.internalServerError(c, err)
+		}
+		resources = append(resources, res)
+	}
+	return response(c, http.StatusOK, &listResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: len(users),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// CreateUser POST /Users
+func (h *Handler) CreateUser(c echo.Context) error {
+	var req userRequest
+	if err := bind(c, &req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidSyntax, "invalid request body")
+	}
+	if err := req.Validate(); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, err.Error())
+	}
+
+	if req.ExternalID != nil && len(*req.ExternalID) > 0 {
+		if _, err := h.Repo.GetUserByExternalID(ProviderName, *req.ExternalID, false); err == nil {
+			return errorResponse(c, http.StatusConflict, scimTypeUniqueness, "externalId conflicts")
+		} else if err != repository.ErrNotFound {
+			return h.internalServerError(c, err)
+		}
+	}
+
+	iconFileID, err := file.GenerateIconFile(h.FileManager, req.UserName)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+
+	args := repository.CreateUserArgs{
+		Name:        req.UserName,
+		DisplayName: req.displayName(),
+		Role:        role.User,
+		IconFileID:  iconFileID,
+	}
+	if req.Password != nil {
+		args.Password = *req.Password
+	}
+	if req.ExternalID != nil && len(*req.ExternalID) > 0 {
+		args.ExternalLogin = &model.ExternalProviderUser{
+			ProviderName: ProviderName,
+			ExternalID:   *req.ExternalID,
+			Extra:        model.JSON{},
+		}
+	}
+	user, err := h.Repo.CreateUser(args)
+	if err != nil {
+		switch err {
+		case repository.ErrAlreadyExists:
+			return errorResponse(c, http.StatusConflict, scimTypeUniqueness, "userName conflicts")
+		default:
+			return h.internalServerError(c, err)
+		}
+	}
+	h.L(c).Info("New user was created by scim", zap.Stringer("id", user.GetID()), zap.String("name", user.GetName()))
+
+	if req.Active != nil && !*req.Active {
+		var args repository.UpdateUserArgs
+		args.UserState.Valid = true
+		args.UserState.State = model.UserAccountStatusDeactivated
+		if err := h.Repo.UpdateUser(user.GetID(), args); err != nil {
+			return h.internalServerError(c, err)
+		}
+	}
+
+	return h.userResponse(c, http.StatusCreated, user.GetID())
+}
+
+// GetUser GET /Users/:id
+func (h *Handler) GetUser(c echo.Context) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	if user == nil {
+		return notFound(c)
+	}
+
+	res, err := h.formatUser(user)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	return response(c, http.StatusOK, res)
+}
+
+// ReplaceUser PUT /Users/:id
+func (h *Handler) ReplaceUser(c echo.Context) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	if user == nil {
+		return notFound(c)
+	}
+
+	var req userRequest
+	if err := bind(c, &req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidSyntax, "invalid request body")
+	}
+	if err := req.Validate(); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, err.Error())
+	}
+	if req.UserName != user.GetName() {
+		return errorResponse(c, http.StatusBadRequest, scimTypeMutability, "userName cannot be changed")
+	}
+
+	args := repository.UpdateUserArgs{
+		DisplayName: optional.StringFrom(req.displayName()),
+	}
+	if req.Active != nil {
+		args.UserState.Valid = true
+		args.UserState.State = accountState(*req.Active)
+	}
+	if req.Password != nil {
+		args.Password = optional.StringFrom(*req.Password)
+	}
+	if err := h.Repo.UpdateUser(user.GetID(), args); err != nil {
+		return h.internalServerError(c, err)
+	}
+
+	var externalID string
+	if req.ExternalID != nil {
+		externalID = *req.ExternalID
+	}
+	if err := h.setExternalID(user.GetID(), externalID); err != nil {
+		if err == repository.ErrAlreadyExists {
+			return errorResponse(c, http.StatusConflict, scimTypeUniqueness, "externalId conflicts")
+		}
+		return h.internalServerError(c, err)
+	}
+
+	return h.userResponse(c, http.StatusOK, user.GetID())
+}
+
+// PatchUser PATCH /Users/:id
+func (h *Handler) PatchUser(c echo.Context) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	if user == nil {
+		return notFound(c)
+	}
+
+	var req patchRequest
+	if err := bind(c, &req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, scimTypeInvalidSyntax, "invalid request body")
+	}
+
+	var (
+		args       repository.UpdateUserArgs
+		externalID optional.String
+	)
+	apply := func(path string, value jsoniter.RawMessage) error {
+		var err error
+		switch path {
+		case "active":
+			var active bool
+			if active, err = parseBoolValue(value); err == nil {
+				args.UserState.Valid = true
+				args.UserState.State = accountState(active)
+			}
+		case "displayname":
+			var s string
+			if s, err = parseStringValue(value); err == nil {
+				if vd.Validate(s, vd.RuneLength(0, 64)) != nil {
+					return errInvalidPatchValue
+				}
+				args.DisplayName = optional.StringFrom(s)
+			}
+		case "password":
+			var s string
+			if s, err = parseStringValue(value); err == nil {
+				if vd.Validate(s, validator.PasswordRuleRequired...) != nil {
+					return errInvalidPatchValue
+				}
+				args.Password = optional.StringFrom(s)
+			}
+		case "externalid":
+			var s string
+			if s, err = parseStringValue(value); err == nil {
+				externalID = optional.StringFrom(s)
+			}
+		case "username":
+			var s string
+			if s, err = parseStringValue(value); err == nil && s != user.GetName() {
+				return errUserNameImmutable
+			}
+		default:
+			// 未対応の属性は無視する
+		}
+		return err
+	}
+
+	for _, op := range req.Operations {
+		var err error
+		switch op.op() {
+		case patchOpAdd, patchOpReplace:
+			if path := op.path(); len(path) > 0 {
+				err = apply(path, op.Value)
+			} else {
+				// パス指定無しの場合はvalueが属性のオブジェクト
+				var values map[string]jsoniter.RawMessage
+				if err = json.Unmarshal(op.Value, &values); err != nil {
+					return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, "invalid patch value")
+				}
+				for k, v := range values {
+					if err = apply(strings.ToLower(k), v); err != nil {
+						break
+					}
+				}
+			}
+		case patchOpRemove:
+			switch op.path() {
+			case "externalid":
+				externalID = optional.StringFrom("")
+			case "displayname":
+				args.DisplayName = optional.StringFrom("")
+			default:
+				return errorResponse(c, http.StatusBadRequest, scimTypeMutability, "the attribute cannot be removed")
+			}
+		default:
+			return errorResponse(c, http.StatusBadRequest, scimTypeInvalidSyntax, "unsupported patch op")
+		}
+		switch err {
+		case nil:
+		case errUserNameImmutable:
+			return errorResponse(c, http.StatusBadRequest, scimTypeMutability, "userName cannot be changed")
+		default:
+			return errorResponse(c, http.StatusBadRequest, scimTypeInvalidValue, err.Error())
+		}
+	}
+
+	if err := h.Repo.UpdateUser(user.GetID(), args); err != nil {
+		return h.internalServerError(c, err)
+	}
+	if externalID.Valid {
+		if err := h.setExternalID(user.GetID(), externalID.String); err != nil {
+			if err == repository.ErrAlreadyExists {
+				return errorResponse(c, http.StatusConflict, scimTypeUniqueness, "externalId conflicts")
+			}
+			return h.internalServerError(c, err)
+		}
+	}
+
+	return h.userResponse(c, http.StatusOK, user.GetID())
+}
+
+// DeleteUser DELETE /Users/:id
+//
+// traQではユーザーを削除できないため、アカウントを凍結します。
+func (h *Handler) DeleteUser(c echo.Context) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return h.internalServerError(c, err)
+	}
+	if user == nil {
+		return notFound(c)
+	}
+
+	var args repository.UpdateUserArgs
+	args.UserState.Valid = true
+	args.UserState.State = model.UserAccountStatusDeactivated
+	if err := h.Repo.UpdateUser(user.GetID(), args); err != nil {
+		return h.internalServerError(c, err)
+	}
+	h.L(c).Info("user was deactivated by scim", zap.Stringer("id", user.GetID()), zap.String("name", user.GetName()))
+
+	return c.NoContent(http.StatusNoContent)
+}
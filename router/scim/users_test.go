@@ -0,0 +1,298 @@
+package scim
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_Authenticate(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+
+	t.Run("no token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/Users").
+			Expect().
+			Status(http.StatusUnauthorized).
+			JSON(scimJSON).
+			Object().
+			Value("status").String().Equal("401")
+	})
+
+	t.Run("invalid scheme", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/Groups").
+			WithHeader(echo.HeaderAuthorization, "Basic "+testToken).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/scim/v2/Users").
+			WithHeader(echo.HeaderAuthorization, authScheme+" wrong-token").
+			WithJSON(echo.Map{"userName": random.AlphaNumeric(20)}).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/ServiceProviderConfig").
+			WithHeader(echo.HeaderAuthorization, authorization).
+			Expect().
+			Status(http.StatusOK)
+	})
+}
+
+func TestHandler_GetUsers(t *testing.T) {
+	t.Parallel()
+	path := "/scim/v2/Users"
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+
+	t.Run("invalid filter", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithQuery("filter", `displayName co "a"`).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("filter by userName", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithQuery("filter", `userName eq "`+user.GetName()+`"`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSON).
+			Object()
+
+		obj.Value("totalResults").Number().Equal(1)
+		res := obj.Value("Resources").Array()
+		res.Length().Equal(1)
+		res.First().Object().Value("id").String().Equal(user.GetID().String())
+		res.First().Object().Value("userName").String().Equal(user.GetName())
+		res.First().Object().Value("active").Boolean().True()
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithQuery("filter", `externalId eq "`+random.AlphaNumeric(20)+`"`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSON).
+			Object().
+			Value("totalResults").Number().Equal(0)
+	})
+}
+
+func TestHandler_CreateUser(t *testing.T) {
+	t.Parallel()
+	path := "/scim/v2/Users"
+	env := Setup(t, common)
+
+	t.Run("invalid userName", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{"userName": "あいうえお"}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON(scimJSON).
+			Object().
+			Value("scimType").String().Equal(scimTypeInvalidValue)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithText("{").
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("userName conflicts", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+
+		e := env.R(t)
+		e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{"userName": user.GetName()}).
+			Expect().
+			Status(http.StatusConflict).
+			JSON(scimJSON).
+			Object().
+			Value("scimType").String().Equal(scimTypeUniqueness)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		name := random.AlphaNumeric(20)
+		externalID := random.AlphaNumeric(20)
+
+		e := env.R(t)
+		obj := e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{
+				"userName":   name,
+				"externalId": externalID,
+				"name":       echo.Map{"givenName": "Taro", "familyName": "Yamada"},
+			}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON(scimJSON).
+			Object()
+
+		obj.Value("userName").String().Equal(name)
+		obj.Value("externalId").String().Equal(externalID)
+		obj.Value("displayName").String().Equal("Taro Yamada")
+		obj.Value("active").Boolean().True()
+
+		user, err := env.Repository.GetUserByExternalID(ProviderName, externalID, false)
+		require.NoError(t, err)
+		assert.Equal(t, name, user.GetName())
+		obj.Value("id").String().Equal(user.GetID().String())
+
+		// 同じexternalIdでは作成できない
+		e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{"userName": random.AlphaNumeric(20), "externalId": externalID}).
+			Expect().
+			Status(http.StatusConflict)
+	})
+
+	t.Run("inactive", func(t *testing.T) {
+		t.Parallel()
+		name := random.AlphaNumeric(20)
+
+		e := env.R(t)
+		e.POST(path).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{"userName": name, "active": false}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON(scimJSON).
+			Object().
+			Value("active").Boolean().False()
+
+		user, err := env.Repository.GetUserByName(name, false)
+		require.NoError(t, err)
+		assert.Equal(t, model.UserAccountStatusDeactivated, user.GetState())
+	})
+}
+
+func TestHandler_GetUser(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/Users/{id}", uuid.Must(uuid.NewV4())).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET("/scim/v2/Users/{id}", user.GetID()).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSON).
+			Object()
+
+		obj.Value("id").String().Equal(user.GetID().String())
+		obj.Value("userName").String().Equal(user.GetName())
+		obj.Value("meta").Object().Value("location").String().Equal("http://example.com/api/scim/v2/Users/" + user.GetID().String())
+	})
+}
+
+func TestHandler_PatchUser(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+
+	t.Run("userName is immutable", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Users/{id}", user.GetID()).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{
+				"schemas":    []string{schemaPatchOp},
+				"Operations": []echo.Map{{"op": "replace", "path": "userName", "value": random.AlphaNumeric(20)}},
+			}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON(scimJSON).
+			Object().
+			Value("scimType").String().Equal(scimTypeMutability)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Users/{id}", user.GetID()).
+			WithHeader(echo.HeaderAuthorization, authorization).
+			WithJSON(echo.Map{
+				"schemas": []string{schemaPatchOp},
+				"Operations": []echo.Map{
+					{"op": "replace", "value": echo.Map{"active": "False", "displayName": "patched"}},
+				},
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSON).
+			Object().
+			Value("displayName").String().Equal("patched")
+
+		u, err := env.Repository.GetUser(user.GetID(), false)
+		require.NoError(t, err)
+		assert.Equal(t, model.UserAccountStatusDeactivated, u.GetState())
+	})
+}
+
+func TestHandler_DeleteUser(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+
+	e := env.R(t)
+	e.DELETE("/scim/v2/Users/{id}", user.GetID()).
+		WithHeader(echo.HeaderAuthorization, authorization).
+		Expect().
+		Status(http.StatusNoContent)
+
+	// 削除ではなく凍結される
+	u, err := env.Repository.GetUser(user.GetID(), false)
+	require.NoError(t, err)
+	assert.Equal(t, model.UserAccountStatusDeactivated, u.GetState())
+}
@@ -53,7 +53,24 @@ func saveUploadImage(p imaging2.Processor, c echo.Context, m file.Manager, name
 		FileType: fType,
 	}
 
-	switch fh.Header.Get(echo.HeaderContentType) {
+	ctype := fh.Header.Get(echo.HeaderContentType)
+	if ctype == consts.MimeImagePNG || ctype == consts.MimeImageAPNG {
+		// APNGかどうか
+		info, err := imaging.ProbeAPNG(src)
+		if err != nil {
+			return uuid.Nil, herror.BadRequest(badImage)
+		}
+		if _, err := src.Seek(0, 0); err != nil {
+			return uuid.Nil, herror.InternalServerError(err)
+		}
+		if info.Frames > 1 {
+			ctype = consts.MimeImageAPNG
+		} else {
+			ctype = consts.MimeImagePNG
+		}
+	}
+
+	switch ctype {
 	case consts.MimeImagePNG, consts.MimeImageJPEG:
 		img, err := p.Fit(src, maxImageSize, maxImageSize)
 		if err != nil {
@@ -105,6 +122,59 @@ func saveUploadImage(p imaging2.Processor, c echo.Context, m file.Manager, name
 		}
 		_, _ = b.Seek(0, 0)
 
+	case consts.MimeImageAPNG:
+		// アニメーションを保ったままリサイズ
+		b, err := p.FitAnimationPNG(src, maxImageSize, maxImageSize)
+		if err != nil {
+			switch err {
+			case imaging2.ErrInvalidImageSrc:
+				return uuid.Nil, herror.BadRequest(badImage)
+			case imaging2.ErrPixelLimitExceeded, imaging2.ErrFrameLimitExceeded:
+				return uuid.Nil, herror.BadRequest(tooLargeImage)
+			default:
+				return uuid.Nil, herror.InternalServerError(err)
+			}
+		}
+
+		args.Src = b
+		args.FileSize = b.Size()
+		args.MimeType = consts.MimeImagePNG // APNG非対応のクライアントでも先頭フレームが表示される
+
+		// サムネイルは先頭フレームの静止画
+		args.Thumbnail, err = p.AnimationThumbnail(b)
+		if err != nil {
+			return uuid.Nil, herror.InternalServerError(err)
+		}
+		_, _ = b.Seek(0, 0)
+
+	case consts.MimeImageWebP:
+		// アニメーションを保ったままリサイズ
+		b, err := p.FitAnimationWebP(src, maxImageSize, maxImageSize)
+		if err != nil {
+			switch err {
+			case imaging.ErrImageMagickUnavailable:
+				// webpは一時的にサポートされていない
+				return uuid.Nil, herror.BadRequest("webp file is temporarily unsupported")
+			case imaging2.ErrInvalidImageSrc, imaging2.ErrTimeout:
+				return uuid.Nil, herror.BadRequest(badImage)
+			case imaging2.ErrPixelLimitExceeded, imaging2.ErrFrameLimitExceeded:
+				return uuid.Nil, herror.BadRequest(tooLargeImage)
+			default:
+				return uuid.Nil, herror.InternalServerError(err)
+			}
+		}
+
+		args.Src = b
+		args.FileSize = b.Size()
+		args.MimeType = consts.MimeImageWebP
+
+		// サムネイルは先頭フレームの静止画
+		args.Thumbnail, err = p.AnimationThumbnail(b)
+		if err != nil {
+			return uuid.Nil, herror.InternalServerError(err)
+		}
+		_, _ = b.Seek(0, 0)
+
 	default:
 		return uuid.Nil, herror.BadRequest(badImage)
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/traPtitech/traQ/service/bot/event"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
 )
 
 type ctxKey int
@@ -105,6 +106,20 @@ var IsActiveHumanUserID = vd.WithContext(func(ctx context.Context, value interfa
 	return nil
 })
 
+// IsActiveHumanUserIDs アカウントが有効な一般ユーザーのUUIDのセットである
+var IsActiveHumanUserIDs = vd.WithContext(func(ctx context.Context, value interface{}) error {
+	ids, ok := value.(set.UUID)
+	if !ok {
+		return errors.New("invalid user ids")
+	}
+	for id := range ids {
+		if err := vd.ValidateWithContext(ctx, id, IsActiveHumanUserID); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
 // IsUserID ユーザーのUUIDである
 var IsUserID = vd.WithContext(func(ctx context.Context, value interface{}) error {
 	const errMessage = "invalid user id"
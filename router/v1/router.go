@@ -18,6 +18,7 @@ import (
 	"github.com/traPtitech/traQ/service/counter"
 	"github.com/traPtitech/traQ/service/file"
 	imaging2 "github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ldap"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/rbac/permission"
 	"github.com/traPtitech/traQ/service/viewer"
@@ -50,6 +51,7 @@ type Handlers struct {
 	SessStore      session.Store
 	ChannelManager channel.Manager
 	FileManager    file.Manager
+	LDAP           ldap.Service
 	Replacer       *message.Replacer
 
 	emojiJSONCache     bytes.Buffer `wire:"-"`
@@ -71,10 +73,12 @@ func (h *Handlers) Setup(e *echo.Group) {
 
 	requiresBotAccessPerm := middlewares.CheckBotAccessPerm(h.RBAC, h.Repo)
 	requiresWebhookAccessPerm := middlewares.CheckWebhookAccessPerm(h.RBAC, h.Repo)
-	requiresFileAccessPerm := middlewares.CheckFileAccessPerm(h.RBAC, h.FileManager)
+	requiresFileAccessPerm := middlewares.CheckFileAccessPerm(h.RBAC, h.FileManager, h.ChannelManager)
 	requiresClientAccessPerm := middlewares.CheckClientAccessPerm(h.RBAC, h.Repo)
 	requiresMessageAccessPerm := middlewares.CheckMessageAccessPerm(h.RBAC, h.ChannelManager)
 	requiresChannelAccessPerm := middlewares.CheckChannelAccessPerm(h.RBAC, h.ChannelManager)
+	blockReadOnlyGroup := middlewares.BlockReadOnlyUserGroup()
+	blockArchivedChannel := middlewares.BlockArchivedMessageChannel(h.ChannelManager)
 
 	gone := func(c echo.Context) error { return herror.HTTPError(http.StatusGone, "this api has been deleted") }
 
@@ -208,8 +212,8 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiMessagesMid.GET("/stamps", h.GetMessageStamps, requires(permission.GetMessage))
 				apiMessagesMidStampsSid := apiMessagesMid.Group("/stamps/:stampID", retrieve.StampID(true))
 				{
-					apiMessagesMidStampsSid.POST("", h.PostMessageStamp, requires(permission.AddMessageStamp))
-					apiMessagesMidStampsSid.DELETE("", h.DeleteMessageStamp, requires(permission.RemoveMessageStamp))
+					apiMessagesMidStampsSid.POST("", h.PostMessageStamp, requires(permission.AddMessageStamp), blockArchivedChannel)
+					apiMessagesMidStampsSid.DELETE("", h.DeleteMessageStamp, requires(permission.RemoveMessageStamp), blockArchivedChannel)
 				}
 			}
 		}
@@ -271,13 +275,13 @@ func (h *Handlers) Setup(e *echo.Group) {
 			apiGroupsGid := apiGroups.Group("/:groupID", retrieve.GroupID())
 			{
 				apiGroupsGid.GET("", h.GetUserGroup, requires(permission.GetUserGroup))
-				apiGroupsGid.PATCH("", h.PatchUserGroup, requires(permission.EditUserGroup))
-				apiGroupsGid.DELETE("", h.DeleteUserGroup, requires(permission.DeleteUserGroup))
+				apiGroupsGid.PATCH("", h.PatchUserGroup, blockReadOnlyGroup, requires(permission.EditUserGroup))
+				apiGroupsGid.DELETE("", h.DeleteUserGroup, blockReadOnlyGroup, requires(permission.DeleteUserGroup))
 				apiGroupsGidMembers := apiGroupsGid.Group("/members")
 				{
 					apiGroupsGidMembers.GET("", h.GetUserGroupMembers, requires(permission.GetUserGroup))
-					apiGroupsGidMembers.POST("", h.PostUserGroupMembers, requires(permission.EditUserGroup))
-					apiGroupsGidMembers.DELETE("/:userID", h.DeleteUserGroupMembers, requires(permission.EditUserGroup))
+					apiGroupsGidMembers.POST("", h.PostUserGroupMembers, blockReadOnlyGroup, requires(permission.EditUserGroup))
+					apiGroupsGidMembers.DELETE("/:userID", h.DeleteUserGroupMembers, blockReadOnlyGroup, requires(permission.EditUserGroup))
 				}
 			}
 		}
@@ -8,10 +8,12 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/bus"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ldap"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/testutils"
@@ -77,7 +79,7 @@ func TestMain(m *testing.M) {
 			ThumbnailMaxSize: image.Pt(360, 480),
 			ImageMagickPath:  "",
 		})
-		env.FileManager, _ = file.InitFileManager(env.Repository, storage.NewInMemoryFileStorage(), env.ImageProcessor, zap.NewNop())
+		env.FileManager, _ = file.InitFileManager(env.Repository, storage.NewInMemoryFileStorage(), env.ImageProcessor, nil, file.SanitizeConfig{}, zap.NewNop())
 
 		e := echo.New()
 		e.HideBanner = true
@@ -85,17 +87,19 @@ func TestMain(m *testing.M) {
 		e.HTTPErrorHandler = extension.ErrorHandler(zap.NewNop())
 		e.Use(extension.Wrap(env.Repository, env.ChannelManager))
 
+		eventBus := bus.New(bus.NewLocalTransport(), zap.NewNop())
 		handlers := &Handlers{
 			RBAC:           env.RBAC,
 			Repo:           env.Repository,
 			Hub:            env.Hub,
 			Logger:         zap.NewNop(),
-			OC:             counter.NewOnlineCounter(env.Hub),
-			VM:             viewer.NewManager(env.Hub),
+			OC:             counter.NewOnlineCounter(env.Hub, eventBus),
+			VM:             viewer.NewManager(env.Hub, eventBus),
 			ChannelManager: env.ChannelManager,
 			FileManager:    env.FileManager,
 			SessStore:      env.SessStore,
 			Imaging:        env.ImageProcessor,
+			LDAP:           ldap.NewNullService(),
 		}
 		handlers.Setup(e.Group("/api"))
 		env.Server = httptest.NewServer(e)
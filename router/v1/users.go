@@ -5,6 +5,7 @@ import (
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/router/utils"
 	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/ldap"
 	jwt2 "github.com/traPtitech/traQ/utils/jwt"
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/validator"
@@ -32,29 +33,50 @@ func (h *Handlers) PostLogin(c echo.Context) error {
 		return err
 	}
 
-	user, err := h.Repo.GetUserByName(req.Name, false)
-	if err != nil {
-		switch err {
-		case repository.ErrNotFound:
-			h.L(c).Info("an api login attempt failed: unknown user", zap.String("username", req.Name))
-			return echo.NewHTTPError(http.StatusUnauthorized, "invalid name")
-		default:
-			return herror.InternalServerError(err)
+	// LDAP認証
+	user, err := h.LDAP.Login(req.Name, req.Pass)
+	switch err {
+	case nil:
+		if !user.IsActive() {
+			h.L(c).Info("an api login attempt failed: suspended user", zap.String("username", req.Name))
+			return herror.Forbidden("this account is currently suspended")
+		}
+		h.L(c).Info("an api login attempt succeeded by ldap", zap.String("username", req.Name))
+	case ldap.ErrInvalidCredentials:
+		h.L(c).Info("an api login attempt failed: wrong ldap password", zap.String("username", req.Name))
+		return herror.Unauthorized("invalid name or password")
+	case ldap.ErrNotRegistered:
+		h.L(c).Info("an api login attempt failed: ldap user is not registered", zap.String("username", req.Name))
+		return herror.Forbidden("You are not a member of traQ")
+	default:
+		if err != ldap.ErrUserNotFound && err != ldap.ErrNotLinked {
+			h.L(c).Warn("ldap login failed. fallback to local login", zap.String("username", req.Name), zap.Error(err))
+		}
+		// ローカル認証
+		user, err = h.Repo.GetUserByName(req.Name, false)
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				h.L(c).Info("an api login attempt failed: unknown user", zap.String("username", req.Name))
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid name")
+			default:
+				return herror.InternalServerError(err)
+			}
 		}
-	}
 
-	// ユーザーのアカウント状態の確認
-	if !user.IsActive() {
-		h.L(c).Info("an api login attempt failed: suspended user", zap.String("username", req.Name))
-		return herror.Forbidden("this account is currently suspended")
-	}
+		// ユーザーのアカウント状態の確認
+		if !user.IsActive() {
+			h.L(c).Info("an api login attempt failed: suspended user", zap.String("username", req.Name))
+			return herror.Forbidden("this account is currently suspended")
+		}
 
-	// パスワード検証
-	if err := user.Authenticate(req.Pass); err != nil {
-		h.L(c).Info("an api login attempt failed: wrong password", zap.String("username", req.Name))
-		return echo.NewHTTPError(http.StatusUnauthorized, err)
+		// パスワード検証
+		if err := user.Authenticate(req.Pass); err != nil {
+			h.L(c).Info("an api login attempt failed: wrong password", zap.String("username", req.Name))
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		}
+		h.L(c).Info("an api login attempt succeeded", zap.String("username", req.Name))
 	}
-	h.L(c).Info("an api login attempt succeeded", zap.String("username", req.Name))
 
 	if _, err := h.SessStore.RenewSession(c, user.GetID()); err != nil {
 		return herror.InternalServerError(err)
@@ -0,0 +1,262 @@
+package v3
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/validator"
+)
+
+// channelTemplateMaxChannels 1つのチャンネルテンプレートに含められる最大チャンネル数
+const channelTemplateMaxChannels = 100
+
+// GetChannelTemplates GET /channel-templates
+func (h *Handlers) GetChannelTemplates(c echo.Context) error {
+	templates, err := h.Repo.GetChannelTemplates()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatChannelTemplates(templates))
+}
+
+// PostChannelTemplateRequest POST /channel-templates リクエストボディ
+type PostChannelTemplateRequest struct {
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Definition  model.ChannelTemplateNode `json:"definition"`
+}
+
+func (r PostChannelTemplateRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, validator.ChannelTemplateNameRuleRequired...),
+		vd.Field(&r.Description, validator.ChannelTemplateDescriptionRule...),
+		vd.Field(&r.Definition, vd.By(validateChannelTemplateDefinition)),
+	)
+}
+
+// CreateChannelTemplate POST /channel-templates
+func (h *Handlers) CreateChannelTemplate(c echo.Context) error {
+	var req PostChannelTemplateRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	normalizeChannelTemplateNode(&req.Definition)
+	t, err := h.Repo.CreateChannelTemplate(req.Name, req.Description, req.Definition, getRequestUserID(c))
+	if err != nil {
+		switch err {
+		case repository.ErrAlreadyExists:
+			return herror.Conflict("channel template name conflicts")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusCreated, formatChannelTemplate(t))
+}
+
+// GetChannelTemplate GET /channel-templates/:templateID
+func (h *Handlers) GetChannelTemplate(c echo.Context) error {
+	return c.JSON(http.StatusOK, formatChannelTemplate(getParamChannelTemplate(c)))
+}
+
+// PatchChannelTemplateRequest PATCH /channel-templates/:templateID リクエストボディ
+type PatchChannelTemplateRequest struct {
+	Name        optional.String            `json:"name"`
+	Description optional.String            `json:"description"`
+	Definition  *model.ChannelTemplateNode `json:"definition"`
+}
+
+func (r PatchChannelTemplateRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, validator.ChannelTemplateNameRule...),
+		vd.Field(&r.Description, validator.ChannelTemplateDescriptionRule...),
+		vd.Field(&r.Definition, vd.By(validateChannelTemplateDefinition)),
+	)
+}
+
+// EditChannelTemplate PATCH /channel-templates/:templateID
+func (h *Handlers) EditChannelTemplate(c echo.Context) error {
+	t := getParamChannelTemplate(c)
+
+	var req PatchChannelTemplateRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if req.Definition != nil {
+		normalizeChannelTemplateNode(req.Definition)
+	}
+	args := repository.UpdateChannelTemplateArgs{
+		Name:        req.Name,
+		Description: req.Description,
+		Definition:  req.Definition,
+	}
+	if err := h.Repo.UpdateChannelTemplate(t.ID, args); err != nil {
+		switch err {
+		case repository.ErrAlreadyExists:
+			return herror.Conflict("channel template name conflicts")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteChannelTemplate DELETE /channel-templates/:templateID
+func (h *Handlers) DeleteChannelTemplate(c echo.Context) error {
+	t := getParamChannelTemplate(c)
+
+	if err := h.Repo.DeleteChannelTemplate(t.ID); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PostChannelTemplateInstantiateRequest POST /channel-templates/:templateID/instantiate リクエストボディ
+type PostChannelTemplateInstantiateRequest struct {
+	Name   string        `json:"name"`
+	Parent optional.UUID `json:"parent"`
+}
+
+func (r PostChannelTemplateInstantiateRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, validator.ChannelNameRuleRequired...),
+	)
+}
+
+// InstantiateChannelTemplate POST /channel-templates/:templateID/instantiate
+func (h *Handlers) InstantiateChannelTemplate(c echo.Context) error {
+	t := getParamChannelTemplate(c)
+
+	var req PostChannelTemplateInstantiateRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	channels, err := h.ChannelManager.InstantiateChannelTemplate(t.Definition, req.Parent.UUID, req.Name, getRequestUserID(c))
+	if err != nil {
+		switch err {
+		case channel.ErrChannelArchived:
+			return herror.BadRequest("parent channel has been archived")
+		case channel.ErrInvalidChannelName:
+			return herror.BadRequest("invalid channel name")
+		case channel.ErrInvalidParentChannel:
+			return herror.BadRequest("invalid parent channel")
+		case channel.ErrTooDeepChannel:
+			return herror.BadRequest("channel depth limit exceeded")
+		case channel.ErrForcedNotification, channel.ErrInvalidTemplate:
+			return herror.BadRequest("invalid channel template")
+		case channel.ErrChannelNameConflicts:
+			return herror.Conflict("channel name conflicts")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusCreated, formatChannels(channels))
+}
+
+// validateChannelTemplateDefinition チャンネルテンプレートの定義を検証します
+func validateChannelTemplateDefinition(value interface{}) error {
+	var node *model.ChannelTemplateNode
+	switch v := value.(type) {
+	case model.ChannelTemplateNode:
+		node = &v
+	case *model.ChannelTemplateNode:
+		if v == nil {
+			return nil
+		}
+		node = v
+	default:
+		return vd.NewInternalError(errors.New("invalid channel template definition"))
+	}
+
+	if node.Count() > channelTemplateMaxChannels {
+		return vd.NewError("validation_too_many_channels", "the number of channels must be no more than "+strconv.Itoa(channelTemplateMaxChannels))
+	}
+	return validateChannelTemplateNode(node, true)
+}
+
+func validateChannelTemplateNode(node *model.ChannelTemplateNode, root bool) error {
+	return vd.ValidateStruct(node,
+		vd.Field(&node.Name, vd.When(!root, validator.ChannelNameRuleRequired...)),
+		vd.Field(&node.Topic, vd.RuneLength(0, 200)),
+		vd.Field(&node.GroupSubscriptions,
+			vd.When(node.Force, vd.Empty.Error("must be empty for forced notification channels")),
+			vd.By(func(interface{}) error {
+				errs := vd.Errors{}
+				groups := map[uuid.UUID]struct{}{}
+				for i, s := range node.GroupSubscriptions {
+					if s == nil {
+						errs[strconv.Itoa(i)] = vd.ErrNotNilRequired
+						continue
+					}
+					if _, ok := groups[s.GroupID]; ok {
+						errs[strconv.Itoa(i)] = vd.NewError("validation_duplicated_group", "duplicated group")
+						continue
+					}
+					groups[s.GroupID] = struct{}{}
+					if err := vd.ValidateStruct(s,
+						vd.Field(&s.GroupID, vd.Required, validator.NotNilUUID),
+						vd.Field(&s.Level, vd.Min(model.ChannelSubscribeLevelMark), vd.Max(model.ChannelSubscribeLevelMarkAndNotify)),
+					); err != nil {
+						errs[strconv.Itoa(i)] = err
+					}
+				}
+				return errs.Filter()
+			}),
+		),
+		vd.Field(&node.Bots, vd.By(func(interface{}) error {
+			errs := vd.Errors{}
+			bots := map[uuid.UUID]struct{}{}
+			for i, id := range node.Bots {
+				if _, ok := bots[id]; ok {
+					errs[strconv.Itoa(i)] = vd.NewError("validation_duplicated_bot", "duplicated bot")
+					continue
+				}
+				bots[id] = struct{}{}
+				if err := vd.Validate(id, validator.NotNilUUID); err != nil {
+					errs[strconv.Itoa(i)] = err
+				}
+			}
+			return errs.Filter()
+		})),
+		vd.Field(&node.Children, vd.By(func(interface{}) error {
+			errs := vd.Errors{}
+			for i, child := range node.Children {
+				if child == nil {
+					errs[strconv.Itoa(i)] = vd.ErrNotNilRequired
+					continue
+				}
+				if err := validateChannelTemplateNode(child, false); err != nil {
+					errs[strconv.Itoa(i)] = err
+				}
+			}
+			return errs.Filter()
+		})),
+	)
+}
+
+// normalizeChannelTemplateNode チャンネルテンプレートの定義のnilの配列を空の配列に置き換えます
+func normalizeChannelTemplateNode(node *model.ChannelTemplateNode) {
+	if node.GroupSubscriptions == nil {
+		node.GroupSubscriptions = make([]*model.ChannelTemplateGroupSubscription, 0)
+	}
+	if node.Bots == nil {
+		node.Bots = make([]uuid.UUID, 0)
+	}
+	if node.Children == nil {
+		node.Children = make([]*model.ChannelTemplateNode, 0)
+	}
+	for _, child := range node.Children {
+		normalizeChannelTemplateNode(child)
+	}
+}
@@ -1,6 +1,7 @@
 package v3
 
 import (
+	"context"
 	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
@@ -8,7 +9,9 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
 	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac/permission"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/set"
@@ -30,6 +33,20 @@ func (h *Handlers) GetChannels(c echo.Context) error {
 			return herror.InternalServerError(err)
 		}
 		res["dm"] = formatDMChannels(mapping)
+
+		groupMapping, err := h.ChannelManager.GetGroupDMChannelMapping(getRequestUserID(c))
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		res["groupDm"] = formatGroupDMChannels(groupMapping)
+	}
+
+	if isTrue(c.QueryParam("include-private")) {
+		channels, err := h.ChannelManager.GetPrivateChannels(getRequestUserID(c))
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		res["private"] = formatChannels(channels)
 	}
 
 	return c.JSON(http.StatusOK, res)
@@ -37,13 +54,17 @@ func (h *Handlers) GetChannels(c echo.Context) error {
 
 // PostChannelRequest POST /channels リクエストボディ
 type PostChannelRequest struct {
-	Name   string        `json:"name"`
-	Parent optional.UUID `json:"parent"`
+	Name    string        `json:"name"`
+	Parent  optional.UUID `json:"parent"`
+	Private bool          `json:"private"`
+	Members set.UUID      `json:"members"`
 }
 
-func (r PostChannelRequest) Validate() error {
-	return vd.ValidateStruct(&r,
+func (r PostChannelRequest) ValidateWithContext(ctx context.Context) error {
+	return vd.ValidateStructWithContext(ctx, &r,
 		vd.Field(&r.Name, validator.ChannelNameRuleRequired...),
+		vd.Field(&r.Parent, vd.When(r.Private, vd.Nil.Error("must be null for private channels"))),
+		vd.Field(&r.Members, vd.When(!r.Private, vd.Empty.Error("must be empty for public channels")), vd.Length(0, 100), utils.IsActiveHumanUserIDs),
 	)
 }
 
@@ -56,7 +77,15 @@ func (h *Handlers) CreateChannels(c echo.Context) error {
 		return err
 	}
 
-	ch, err := h.ChannelManager.CreatePublicChannel(req.Name, req.Parent.UUID, userID)
+	var (
+		ch  *model.Channel
+		err error
+	)
+	if req.Private {
+		ch, err = h.ChannelManager.CreatePrivateChannel(req.Name, req.Members, userID)
+	} else {
+		ch, err = h.ChannelManager.CreatePublicChannel(req.Name, req.Parent.UUID, userID)
+	}
 	if err != nil {
 		switch err {
 		case channel.ErrChannelArchived:
@@ -106,17 +135,163 @@ func (h *Handlers) EditChannel(c echo.Context) error {
 		return err
 	}
 
-	args := repository.UpdateChannelArgs{
-		UpdaterID:          getRequestUserID(c),
-		Name:               req.Name,
-		Visibility:         optional.NewBool(!req.Archived.Bool, req.Archived.Valid),
-		ForcedNotification: req.Force,
-		Parent:             req.Parent,
+	// アーカイブ状態の変更にはアーカイブ権限が必要
+	if req.Archived.Valid && !h.RBAC.IsGranted(getRequestUser(c).GetRole(), permission.ArchiveChannel) {
+		return herror.Forbidden("you are not permitted to archive channels")
+	}
+
+	if req.Name.Valid || req.Force.Valid || req.Parent.Valid {
+		args := repository.UpdateChannelArgs{
+			UpdaterID:          getRequestUserID(c),
+			Name:               req.Name,
+			ForcedNotification: req.Force,
+			Parent:             req.Parent,
+		}
+		if err := h.ChannelManager.UpdateChannel(channelID, args); err != nil {
+			switch err {
+			case channel.ErrInvalidChannelName:
+				return herror.BadRequest("invalid channel name")
+			case channel.ErrInvalidParentChannel:
+				return herror.BadRequest("invalid parent channel")
+			case channel.ErrTooDeepChannel:
+				return herror.BadRequest("channel depth limit exceeded")
+			case channel.ErrChannelNameConflicts:
+				return herror.Conflict("channel name conflicts")
+			default:
+				return herror.InternalServerError(err)
+			}
+		}
+	}
+
+	if req.Archived.Valid {
+		var err error
+		if req.Archived.Bool {
+			err = h.ChannelManager.ArchiveChannel(channelID, false, getRequestUserID(c))
+		} else {
+			err = h.ChannelManager.UnarchiveChannel(channelID, false, getRequestUserID(c))
+		}
+		if err != nil {
+			switch err {
+			case channel.ErrInvalidChannel:
+				return herror.BadRequest("only public channels can be archived")
+			case channel.ErrChannelArchived:
+				return herror.BadRequest("parent channel has been archived")
+			case channel.ErrChannelNotFound:
+				return herror.NotFound()
+			default:
+				return herror.InternalServerError(err)
+			}
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PostArchiveChannelRequest POST /channels/:channelID/archive, POST /channels/:channelID/unarchive リクエストボディ
+type PostArchiveChannelRequest struct {
+	Cascade bool `json:"cascade"`
+}
+
+// ArchiveChannel POST /channels/:channelID/archive
+func (h *Handlers) ArchiveChannel(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	var req PostArchiveChannelRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.ChannelManager.ArchiveChannel(channelID, req.Cascade, getRequestUserID(c)); err != nil {
+		switch err {
+		case channel.ErrInvalidChannel:
+			return herror.BadRequest("only public channels can be archived")
+		case channel.ErrChannelNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
 	}
-	if err := h.ChannelManager.UpdateChannel(channelID, args); err != nil {
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UnarchiveChannel POST /channels/:channelID/unarchive
+func (h *Handlers) UnarchiveChannel(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	var req PostArchiveChannelRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.ChannelManager.UnarchiveChannel(channelID, req.Cascade, getRequestUserID(c)); err != nil {
 		switch err {
-		case channel.ErrInvalidChannelName:
-			return herror.BadRequest("invalid channel name")
+		case channel.ErrInvalidChannel:
+			return herror.BadRequest("only public channels can be unarchived")
+		case channel.ErrChannelArchived:
+			return herror.BadRequest("parent channel has been archived")
+		case channel.ErrChannelNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PostChannelMergeRequest POST /channels/:channelID/merge リクエストボディ
+type PostChannelMergeRequest struct {
+	To uuid.UUID `json:"to"`
+}
+
+func (r PostChannelMergeRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.To, vd.Required, validator.NotNilUUID),
+	)
+}
+
+// MergeChannel POST /channels/:channelID/merge
+func (h *Handlers) MergeChannel(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	var req PostChannelMergeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.ChannelManager.MergeChannel(channelID, req.To, getRequestUserID(c)); err != nil {
+		switch err {
+		case channel.ErrInvalidChannel:
+			return herror.BadRequest("invalid destination channel")
+		case channel.ErrChannelHasChildren:
+			return herror.BadRequest("channels which have children cannot be merged")
+		case channel.ErrChannelArchived:
+			return herror.BadRequest("destination channel has been archived")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PostChannelMoveRequest POST /channels/:channelID/move リクエストボディ
+type PostChannelMoveRequest struct {
+	Parent optional.UUID `json:"parent"`
+	DryRun bool          `json:"dryRun"`
+}
+
+// MoveChannel POST /channels/:channelID/move
+func (h *Handlers) MoveChannel(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	var req PostChannelMoveRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	moves, err := h.ChannelManager.MoveChannel(channelID, req.Parent.UUID, req.DryRun, getRequestUserID(c))
+	if err != nil {
+		switch err {
+		case channel.ErrInvalidChannel:
+			return herror.BadRequest("invalid channel")
 		case channel.ErrInvalidParentChannel:
 			return herror.BadRequest("invalid parent channel")
 		case channel.ErrTooDeepChannel:
@@ -127,6 +302,132 @@ func (h *Handlers) EditChannel(c echo.Context) error {
 			return herror.InternalServerError(err)
 		}
 	}
+	return c.JSON(http.StatusOK, formatChannelMoves(moves))
+}
+
+// GetChannelRedirect GET /channels/:channelID/redirect
+func (h *Handlers) GetChannelRedirect(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	r, err := h.Repo.GetChannelRedirect(channelID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusOK, echo.Map{"channelId": r.ToChannelID})
+}
+
+// GetChannelMembers GET /channels/:channelID/members
+func (h *Handlers) GetChannelMembers(c echo.Context) error {
+	ch := getParamChannel(c)
+	if ch.IsPublic {
+		return herror.BadRequest("public channels have no members")
+	}
+
+	members, err := h.ChannelManager.GetDMChannelMembers(ch.ID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, members)
+}
+
+// PostChannelConvertRequest POST /channels/:channelID/convert リクエストボディ
+type PostChannelConvertRequest struct {
+	Name string `json:"name"`
+}
+
+func (r PostChannelConvertRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, validator.ChannelNameRuleRequired...),
+	)
+}
+
+// ConvertChannel POST /channels/:channelID/convert
+func (h *Handlers) ConvertChannel(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	var req PostChannelConvertRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ch, err := h.ChannelManager.ConvertGroupDMChannel(channelID, req.Name, getRequestUserID(c))
+	if err != nil {
+		switch err {
+		case channel.ErrInvalidChannel:
+			return herror.BadRequest("this channel is not a group dm channel")
+		case channel.ErrInvalidChannelName:
+			return herror.BadRequest("invalid channel name")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusOK, formatChannel(ch, ch.ChildrenID))
+}
+
+// PostChannelMemberRequest POST /channels/:channelID/members リクエストボディ
+type PostChannelMemberRequest struct {
+	ID uuid.UUID `json:"id"`
+}
+
+func (r PostChannelMemberRequest) ValidateWithContext(ctx context.Context) error {
+	return vd.ValidateStructWithContext(ctx, &r,
+		vd.Field(&r.ID, vd.Required, validator.NotNilUUID, utils.IsActiveHumanUserID),
+	)
+}
+
+// AddChannelMember POST /channels/:channelID/members
+func (h *Handlers) AddChannelMember(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	var req PostChannelMemberRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.ChannelManager.AddPrivateChannelMembers(channelID, set.UUIDSetFromArray([]uuid.UUID{req.ID}), getRequestUserID(c)); err != nil {
+		switch err {
+		case channel.ErrInvalidChannel:
+			return herror.BadRequest("not a private channel")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveChannelMember DELETE /channels/:channelID/members/:userID
+func (h *Handlers) RemoveChannelMember(c echo.Context) error {
+	ch := getParamChannel(c)
+	userID := getParamAsUUID(c, consts.ParamUserID)
+
+	// メンバーを削除できるのはチャンネルのオーナー(作成者)と管理権限を持つユーザーのみ
+	user := getRequestUser(c)
+	if ch.CreatorID != user.GetID() && !h.RBAC.IsGranted(user.GetRole(), permission.ManageOthersPrivateChannelMembers) {
+		return herror.Forbidden("only the owner of the channel can remove members")
+	}
+	return h.removeChannelMember(c, ch.ID, userID)
+}
+
+// LeaveChannel POST /channels/:channelID/leave
+func (h *Handlers) LeaveChannel(c echo.Context) error {
+	userID := getRequestUserID(c)
+	return h.removeChannelMember(c, getParamAsUUID(c, consts.ParamChannelID), userID)
+}
+
+func (h *Handlers) removeChannelMember(c echo.Context, channelID, userID uuid.UUID) error {
+	if err := h.ChannelManager.RemovePrivateChannelMembers(channelID, set.UUIDSetFromArray([]uuid.UUID{userID}), getRequestUserID(c)); err != nil {
+		switch err {
+		case channel.ErrInvalidChannel:
+			return herror.BadRequest("not a private channel")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
 	return c.NoContent(http.StatusNoContent)
 }
 
@@ -147,6 +448,29 @@ func (h *Handlers) GetChannelStats(c echo.Context) error {
 	return c.JSON(http.StatusOK, stats)
 }
 
+// GetChannelReadStates GET /channels/:channelID/read-states
+func (h *Handlers) GetChannelReadStates(c echo.Context) error {
+	ch := getParamChannel(c)
+
+	// 既読位置はDM・少人数のプライベートチャンネルでのみ取得できる
+	if ch.IsPublic {
+		return herror.BadRequest("read states are not available for public channels")
+	}
+	members, err := h.ChannelManager.GetDMChannelMembers(ch.ID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if len(members) > channel.ReadStateMaxMembers {
+		return herror.BadRequest("read states are not available for this channel")
+	}
+
+	states, err := h.Repo.GetChannelReadStates(ch.ID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatChannelReadStates(states))
+}
+
 // GetChannelTopic GET /channels/:channelID/topic
 func (h *Handlers) GetChannelTopic(c echo.Context) error {
 	return c.JSON(http.StatusOK, echo.Map{"topic": getParamChannel(c).Topic})
@@ -312,6 +636,68 @@ func (h *Handlers) SetChannelSubscribers(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// GetChannelGroupSubscriptions GET /channels/:channelID/subscribers/groups
+func (h *Handlers) GetChannelGroupSubscriptions(c echo.Context) error {
+	ch := getParamChannel(c)
+
+	// プライベートチャンネル・強制通知チャンネルの設定は取得できない。
+	if !ch.IsPublic || ch.IsForced {
+		return herror.Forbidden()
+	}
+
+	subscriptions, err := h.Repo.GetChannelGroupSubscriptions(ch.ID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	type response struct {
+		GroupID uuid.UUID `json:"groupId"`
+		Level   int       `json:"level"`
+	}
+	result := make([]response, len(subscriptions))
+	for i, subscription := range subscriptions {
+		result[i] = response{GroupID: subscription.GroupID, Level: subscription.GetLevel().Int()}
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// PutChannelGroupSubscriptionRequest PUT /channels/:channelID/subscribers/groups/:groupID リクエストボディ
+type PutChannelGroupSubscriptionRequest struct {
+	Level optional.Int `json:"level"`
+}
+
+func (r PutChannelGroupSubscriptionRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Level, vd.NotNil, vd.Min(0), vd.Max(2)),
+	)
+}
+
+// SetChannelGroupSubscription PUT /channels/:channelID/subscribers/groups/:groupID
+func (h *Handlers) SetChannelGroupSubscription(c echo.Context) error {
+	ch := getParamChannel(c)
+	groupID := getParamAsUUID(c, consts.ParamGroupID)
+
+	var req PutChannelGroupSubscriptionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.ChannelManager.SetChannelGroupSubscription(ch.ID, groupID, model.ChannelSubscribeLevel(req.Level.Int64), getRequestUserID(c)); err != nil {
+		switch err {
+		case channel.ErrInvalidChannel:
+			return herror.Forbidden("the channel's subscriptions is not configurable")
+		case channel.ErrForcedNotification:
+			return herror.Forbidden("the channel's subscriptions is not configurable")
+		case channel.ErrUserGroupNotFound:
+			return herror.NotFound("the group is not found")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
 // PatchChannelSubscribersRequest PATCH /channels/:channelID/subscribers リクエストボディ
 type PatchChannelSubscribersRequest struct {
 	On  set.UUID `json:"on"`
@@ -366,3 +752,39 @@ func (h *Handlers) GetUserDMChannel(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, &DMChannel{ID: ch.ID, UserID: userID})
 }
+
+// PostGroupDMChannelRequest POST /users/me/group-dm-channel リクエストボディ
+type PostGroupDMChannelRequest struct {
+	Members set.UUID `json:"members"`
+}
+
+func (r PostGroupDMChannelRequest) ValidateWithContext(ctx context.Context) error {
+	return vd.ValidateStructWithContext(ctx, &r,
+		vd.Field(&r.Members, vd.Required, vd.Length(2, channel.GroupDMMaxMembers-1), utils.IsActiveHumanUserIDs),
+	)
+}
+
+// PostMyGroupDMChannel POST /users/me/group-dm-channel
+func (h *Handlers) PostMyGroupDMChannel(c echo.Context) error {
+	myID := getRequestUserID(c)
+
+	var req PostGroupDMChannelRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	// グループDMチャンネルを取得
+	ch, err := h.ChannelManager.GetGroupDMChannel(req.Members, myID)
+	if err != nil {
+		switch err {
+		case channel.ErrInvalidGroupMembers:
+			return herror.BadRequest("invalid members")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	members := req.Members.Clone()
+	members.Remove(myID)
+	return c.JSON(http.StatusOK, &GroupDMChannel{ID: ch.ID, Members: members.Array()})
+}
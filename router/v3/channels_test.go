@@ -0,0 +1,195 @@
+package v3
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/set"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandlers_GetChannelReadStates(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/channels/{channelId}/read-states"
+	env := Setup(t, common)
+	user1 := env.CreateUser(t, rand)
+	user2 := env.CreateUser(t, rand)
+	user1Session := env.S(t, user1.GetID())
+
+	dm, err := env.CM.GetDMChannel(user1.GetID(), user2.GetID())
+	require.NoError(t, err)
+	m, err := env.Repository.CreateMessage(user1.GetID(), dm.ID, "test")
+	require.NoError(t, err)
+	for _, id := range []uuid.UUID{user1.GetID(), user2.GetID()} {
+		_, err := env.Repository.UpdateChannelReadState(id, dm.ID, time.Now())
+		require.NoError(t, err)
+	}
+	// user2は既読位置を公開していない
+	require.NoError(t, env.Repository.UpdateUserSettings(user1.GetID(), repository.UpdateUserSettingsArgs{ReadReceipts: optional.BoolFrom(true)}))
+
+	t.Run("NotLoggedIn", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET(path, dm.ID).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("public channel", func(t *testing.T) {
+		t.Parallel()
+		ch, err := env.CM.CreatePublicChannel(random.AlphaNumeric(20), uuid.Nil, user1.GetID())
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.GET(path, ch.ID).
+			WithCookie(session.CookieName, user1Session).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("too many members", func(t *testing.T) {
+		t.Parallel()
+		members := set.UUID{}
+		for i := 0; i < channel.ReadStateMaxMembers; i++ {
+			members.Add(env.CreateUser(t, rand).GetID())
+		}
+		ch, err := env.CM.CreatePrivateChannel(random.AlphaNumeric(20), members, user1.GetID())
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.GET(path, ch.ID).
+			WithCookie(session.CookieName, user1Session).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		arr := e.GET(path, dm.ID).
+			WithCookie(session.CookieName, env.S(t, user2.GetID())).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array()
+
+		arr.Length().Equal(1)
+		obj := arr.First().Object()
+		obj.Value("userId").String().Equal(user1.GetID().String())
+		obj.Value("messageId").String().Equal(m.ID.String())
+	})
+}
+
+func TestHandlers_EditChannel(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/channels/{channelId}"
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+	admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin, IconFileID: uuid.Must(uuid.NewV4())})
+	require.NoError(t, err)
+
+	hasEvent := func(channelID uuid.UUID, eventType model.ChannelEventType) bool {
+		events, _, err := env.Repository.GetChannelEvents(repository.ChannelEventsQuery{Channel: channelID, Limit: 20})
+		require.NoError(t, err)
+		for _, e := range events {
+			if e.EventType == eventType {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("rename", func(t *testing.T) {
+		t.Parallel()
+		ch, err := env.CM.CreatePublicChannel(random.AlphaNumeric(20), uuid.Nil, user.GetID())
+		require.NoError(t, err)
+		name := random.AlphaNumeric(20)
+
+		e := env.R(t)
+		e.PATCH(path, ch.ID).
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			WithJSON(map[string]interface{}{"name": name}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		ch, err = env.CM.GetChannel(ch.ID)
+		require.NoError(t, err)
+		assert.Equal(t, name, ch.Name)
+		assert.False(t, ch.IsArchived())
+	})
+
+	t.Run("archive without permission", func(t *testing.T) {
+		t.Parallel()
+		ch, err := env.CM.CreatePublicChannel(random.AlphaNumeric(20), uuid.Nil, user.GetID())
+		require.NoError(t, err)
+		name := random.AlphaNumeric(20)
+
+		e := env.R(t)
+		e.PATCH(path, ch.ID).
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			WithJSON(map[string]interface{}{"name": name, "archived": true}).
+			Expect().
+			Status(http.StatusForbidden)
+
+		ch, err = env.CM.GetChannel(ch.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, name, ch.Name)
+		assert.False(t, ch.IsArchived())
+	})
+
+	t.Run("archive and unarchive", func(t *testing.T) {
+		t.Parallel()
+		ch, err := env.CM.CreatePublicChannel(random.AlphaNumeric(20), uuid.Nil, user.GetID())
+		require.NoError(t, err)
+		child, err := env.CM.CreatePublicChannel(random.AlphaNumeric(20), ch.ID, user.GetID())
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.PATCH(path, ch.ID).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			WithJSON(map[string]interface{}{"archived": true}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		ch, err = env.CM.GetChannel(ch.ID)
+		require.NoError(t, err)
+		assert.True(t, ch.IsArchived())
+		child, err = env.CM.GetChannel(child.ID)
+		require.NoError(t, err)
+		assert.False(t, child.IsArchived())
+		assert.Eventually(t, func() bool { return hasEvent(ch.ID, model.ChannelEventArchived) }, time.Second, 10*time.Millisecond)
+
+		e.PATCH(path, ch.ID).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			WithJSON(map[string]interface{}{"archived": false}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		ch, err = env.CM.GetChannel(ch.ID)
+		require.NoError(t, err)
+		assert.False(t, ch.IsArchived())
+		assert.Eventually(t, func() bool { return hasEvent(ch.ID, model.ChannelEventUnarchived) }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("archive private channel", func(t *testing.T) {
+		t.Parallel()
+		dm, err := env.CM.GetDMChannel(user.GetID(), admin.GetID())
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.PATCH(path, dm.ID).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			WithJSON(map[string]interface{}{"archived": true}).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,172 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/upload"
+	"github.com/traPtitech/traQ/utils/validator"
+	"net/http"
+	"strconv"
+)
+
+// PostFileUploadRequest POST /files/uploads リクエストボディ
+type PostFileUploadRequest struct {
+	Name      string    `json:"name"`
+	Mime      string    `json:"mime"`
+	Size      int64     `json:"size"`
+	ChannelID uuid.UUID `json:"channelId"`
+}
+
+func (r PostFileUploadRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, vd.Required),
+		vd.Field(&r.Size, vd.Required, vd.Min(int64(1))),
+		vd.Field(&r.ChannelID, validator.NotNilUUID),
+	)
+}
+
+// CreateFileUpload POST /files/uploads
+func (h *Handlers) CreateFileUpload(c echo.Context) error {
+	var req PostFileUploadRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	userID := getRequestUserID(c)
+
+	// チャンネルアクセス権・容量制限確認
+	if _, err := h.getChannelFileACL(userID, req.ChannelID); err != nil {
+		return err
+	}
+	if err := h.checkStorageQuota(userID, req.ChannelID, req.Size, 0); err != nil {
+		return err
+	}
+
+	u, err := h.Upload.Create(upload.CreateArgs{
+		FileName:  req.Name,
+		FileSize:  req.Size,
+		MimeType:  req.Mime,
+		CreatorID: userID,
+		ChannelID: req.ChannelID,
+	})
+	if err != nil {
+		switch err {
+		case upload.ErrTooLarge, upload.ErrOutstandingLimitExceeded:
+			return herror.HTTPError(http.StatusRequestEntityTooLarge, err.Error())
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return writeFileUpload(c, http.StatusCreated, u)
+}
+
+// GetFileUpload GET /files/uploads/:uploadID
+func (h *Handlers) GetFileUpload(c echo.Context) error {
+	u, err := h.getRequestFileUpload(c)
+	if err != nil {
+		return err
+	}
+	return writeFileUpload(c, http.StatusOK, u)
+}
+
+// PatchFileUpload PATCH /files/uploads/:uploadID
+func (h *Handlers) PatchFileUpload(c echo.Context) error {
+	u, err := h.getRequestFileUpload(c)
+	if err != nil {
+		return err
+	}
+	if c.Request().Header.Get(echo.HeaderContentType) != consts.MimeOffsetOctetStream {
+		return herror.HTTPError(http.StatusUnsupportedMediaType, "Content-Type must be "+consts.MimeOffsetOctetStream)
+	}
+	offset, err := strconv.ParseInt(c.Request().Header.Get(consts.HeaderUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		return herror.BadRequest("invalid Upload-Offset header")
+	}
+
+	u, err = h.Upload.Append(u.ID, offset, c.Request().Body)
+	if err != nil {
+		switch err {
+		case upload.ErrNotFound:
+			return herror.NotFound()
+		case upload.ErrOffsetMismatch:
+			return herror.Conflict(err.Error())
+		case upload.ErrSizeExceeded:
+			return herror.HTTPError(http.StatusRequestEntityTooLarge, err.Error())
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return writeFileUpload(c, http.StatusOK, u)
+}
+
+// FinalizeFileUpload POST /files/uploads/:uploadID/finalize
+func (h *Handlers) FinalizeFileUpload(c echo.Context) error {
+	u, err := h.getRequestFileUpload(c)
+	if err != nil {
+		return err
+	}
+	if !u.IsCompleted() {
+		return herror.Conflict("upload is incomplete")
+	}
+
+	// アップロード作成時からチャンネルの状態や使用量が変わっている可能性があるので再度確認
+	acl, err := h.getChannelFileACL(u.CreatorID, u.ChannelID)
+	if err != nil {
+		return err
+	}
+	if err := h.checkStorageQuota(u.CreatorID, u.ChannelID, u.Size, u.Size); err != nil {
+		return err
+	}
+
+	f, err := h.Upload.Finalize(u.ID, acl)
+	if err != nil {
+		switch err {
+		case upload.ErrNotFound:
+			return herror.NotFound()
+		case upload.ErrIncomplete:
+			return herror.Conflict(err.Error())
+		case file.ErrInvalidImage:
+			return herror.BadRequest(err.Error())
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusCreated, formatFileInfo(f))
+}
+
+// AbortFileUpload DELETE /files/uploads/:uploadID
+func (h *Handlers) AbortFileUpload(c echo.Context) error {
+	u, err := h.getRequestFileUpload(c)
+	if err != nil {
+		return err
+	}
+	if err := h.Upload.Abort(u.ID); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getRequestFileUpload URLの:uploadIDに対応するリクエストユーザーのファイルアップロードを取得
+func (h *Handlers) getRequestFileUpload(c echo.Context) (*model.FileUpload, error) {
+	u, err := h.Upload.Get(getParamAsUUID(c, consts.ParamUploadID))
+	if err != nil {
+		if err == upload.ErrNotFound {
+			return nil, herror.NotFound()
+		}
+		return nil, herror.InternalServerError(err)
+	}
+	// 他人のアップロードは存在しないものとして扱う
+	if u.CreatorID != getRequestUserID(c) {
+		return nil, herror.NotFound()
+	}
+	return u, nil
+}
+
+func writeFileUpload(c echo.Context, code int, u *model.FileUpload) error {
+	c.Response().Header().Set(consts.HeaderUploadOffset, strconv.FormatInt(u.Offset, 10))
+	return c.JSON(code, formatFileUpload(u))
+}
@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // GetFilesRequest GET /files 用リクエストクエリ
@@ -103,36 +104,101 @@ func (h *Handlers) PostFile(c echo.Context) error {
 
 	// チャンネルアクセス権確認
 	channelID := uuid.FromStringOrNil(c.FormValue("channelId"))
+	acl, err := h.getChannelFileACL(userID, channelID)
+	if err != nil {
+		return err
+	}
+	args.ACL = acl
+	args.ChannelID = optional.UUIDFrom(channelID)
+
+	// 容量制限確認
+	if err := h.checkStorageQuota(userID, channelID, uploadedFile.Size, 0); err != nil {
+		return err
+	}
+
+	// 保存
+	f, err := h.FileManager.Save(args)
+	if err != nil {
+		switch err {
+		case file.ErrInvalidImage:
+			return herror.BadRequest(err.Error())
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusCreated, formatFileInfo(f))
+}
+
+// getChannelFileACL ユーザーがチャンネルにファイルをアップロードできるかどうかを確認し、ファイルに設定するACLを返します
+//
+// 公開チャンネルの場合はnil(全員に許可)を返します。
+func (h *Handlers) getChannelFileACL(userID, channelID uuid.UUID) (file.ACL, error) {
 	if ok, err := h.ChannelManager.IsChannelAccessibleToUser(userID, channelID); err != nil {
-		return herror.InternalServerError(err)
+		return nil, herror.InternalServerError(err)
 	} else if !ok {
-		return herror.BadRequest("invalid channelId")
+		return nil, herror.BadRequest("invalid channelId")
 	}
 	ch, err := h.ChannelManager.GetChannel(channelID)
 	if err != nil {
-		return herror.InternalServerError(err)
+		return nil, herror.InternalServerError(err)
 	}
 	if ch.IsArchived() {
-		return herror.BadRequest(fmt.Sprintf("channel #%s has been archived", h.ChannelManager.PublicChannelTree().GetChannelPath(ch.ID)))
+		return nil, herror.BadRequest(fmt.Sprintf("channel #%s has been archived", h.ChannelManager.PublicChannelTree().GetChannelPath(ch.ID)))
+	}
+	if ch.IsPublic {
+		return nil, nil
 	}
-	if !ch.IsPublic {
-		// アクセスコントロール設定
-		members, err := h.ChannelManager.GetDMChannelMembers(ch.ID)
+
+	// アクセスコントロール設定
+	members, err := h.ChannelManager.GetDMChannelMembers(ch.ID)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	acl := file.ACL{}
+	for _, v := range members {
+		acl[v] = true
+	}
+	return acl, nil
+}
+
+// checkStorageQuota ファイルを追加で保存しても容量制限を超えないかどうかを確認します
+//
+// ユーザー毎の容量制限には、受信中のファイルアップロードのサイズも含めます。
+// pendingには、sizeのうち受信中のファイルアップロードとして既に数えられている分を指定してください。
+func (h *Handlers) checkStorageQuota(userID, channelID uuid.UUID, size, pending int64) error {
+	q := h.StorageQuota
+	if q.User > 0 {
+		usage, err := h.Repo.GetUserFileUsage(userID)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		outstanding, err := h.Repo.GetUserFileUploadsSize(userID, time.Now())
 		if err != nil {
 			return herror.InternalServerError(err)
 		}
-		for _, v := range members {
-			args.ACLAllow(v)
+		if usage.Size+outstanding-pending+size > q.User {
+			return herror.HTTPError(http.StatusRequestEntityTooLarge, "user storage quota exceeded")
 		}
 	}
-	args.ChannelID = optional.UUIDFrom(channelID)
-
-	// 保存
-	file, err := h.FileManager.Save(args)
-	if err != nil {
-		return herror.InternalServerError(err)
+	if q.Channel > 0 {
+		usage, err := h.Repo.GetChannelFileUsage(channelID)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if usage.Size+size > q.Channel {
+			return herror.HTTPError(http.StatusRequestEntityTooLarge, "channel storage quota exceeded")
+		}
 	}
-	return c.JSON(http.StatusCreated, formatFileInfo(file))
+	if q.Total > 0 {
+		total, _, err := h.Repo.GetTotalFileUsage()
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if total+size > q.Total {
+			return herror.HTTPError(http.StatusRequestEntityTooLarge, "storage quota exceeded")
+		}
+	}
+	return nil
 }
 
 // GetFileMeta GET /files/:fileID/meta
@@ -169,3 +235,62 @@ func (h *Handlers) DeleteFile(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// GetFileUsage GET /files/usage
+func (h *Handlers) GetFileUsage(c echo.Context) error {
+	size, count, err := h.Repo.GetTotalFileUsage()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"size":  size,
+		"count": count,
+		"quota": echo.Map{
+			"user":    h.StorageQuota.User,
+			"channel": h.StorageQuota.Channel,
+			"total":   h.StorageQuota.Total,
+		},
+	})
+}
+
+// GetFileUsageRankingRequest GET /files/usage/users, GET /files/usage/channels 用リクエストクエリ
+type GetFileUsageRankingRequest struct {
+	Limit int `query:"limit"`
+}
+
+func (r *GetFileUsageRankingRequest) Validate() error {
+	if r.Limit == 0 {
+		r.Limit = 20
+	}
+	return vd.ValidateStruct(r,
+		vd.Field(&r.Limit, vd.Min(1), vd.Max(100)),
+	)
+}
+
+// GetUserFileUsageRanking GET /files/usage/users
+func (h *Handlers) GetUserFileUsageRanking(c echo.Context) error {
+	var req GetFileUsageRankingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	usages, err := h.Repo.GetUserFileUsageRanking(req.Limit)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, usages)
+}
+
+// GetChannelFileUsageRanking GET /files/usage/channels
+func (h *Handlers) GetChannelFileUsageRanking(c echo.Context) error {
+	var req GetFileUsageRankingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	usages, err := h.Repo.GetChannelFileUsageRanking(req.Limit)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, usages)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/channel"
 )
 
 type Channel struct {
@@ -30,6 +31,14 @@ func formatChannel(channel *model.Channel, childrenID []uuid.UUID) *Channel {
 	}
 }
 
+func formatChannels(channels []*model.Channel) []*Channel {
+	res := make([]*Channel, len(channels))
+	for i, ch := range channels {
+		res[i] = formatChannel(ch, ch.ChildrenID)
+	}
+	return res
+}
+
 type DMChannel struct {
 	ID     uuid.UUID `json:"id"`
 	UserID uuid.UUID `json:"userId"`
@@ -43,6 +52,19 @@ func formatDMChannels(dmcs map[uuid.UUID]uuid.UUID) []*DMChannel {
 	return res
 }
 
+type GroupDMChannel struct {
+	ID      uuid.UUID   `json:"id"`
+	Members []uuid.UUID `json:"members"`
+}
+
+func formatGroupDMChannels(gdmcs map[uuid.UUID][]uuid.UUID) []*GroupDMChannel {
+	res := make([]*GroupDMChannel, 0, len(gdmcs))
+	for cid, members := range gdmcs {
+		res = append(res, &GroupDMChannel{ID: cid, Members: members})
+	}
+	return res
+}
+
 type UserTag struct {
 	ID        uuid.UUID `json:"tagId"`
 	Tag       string    `json:"tag"`
@@ -349,8 +371,10 @@ type UserGroup struct {
 	Name        string            `json:"name"`
 	Description string            `json:"description"`
 	Type        string            `json:"type"`
+	Source      string            `json:"source"`
 	Members     []UserGroupMember `json:"members"`
 	Admins      []uuid.UUID       `json:"admins"`
+	Children    []uuid.UUID       `json:"children"`
 	CreatedAt   time.Time         `json:"createdAt"`
 	UpdatedAt   time.Time         `json:"updatedAt"`
 }
@@ -361,8 +385,10 @@ func formatUserGroup(g *model.UserGroup) *UserGroup {
 		Name:        g.Name,
 		Description: g.Description,
 		Type:        g.Type,
+		Source:      g.Source,
 		Members:     formatUserGroupMembers(g.Members),
 		Admins:      formatUserGroupAdmins(g.Admins),
+		Children:    g.ChildIDArray(),
 		CreatedAt:   g.CreatedAt,
 		UpdatedAt:   g.UpdatedAt,
 	}
@@ -382,6 +408,13 @@ type FileInfoThumbnail struct {
 	Height int    `json:"height"`
 }
 
+type FileInfoMedia struct {
+	Duration int64  `json:"duration"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Codec    string `json:"codec"`
+}
+
 type FileInfo struct {
 	ID         uuid.UUID          `json:"id"`
 	Name       string             `json:"name"`
@@ -390,6 +423,7 @@ type FileInfo struct {
 	MD5        string             `json:"md5"`
 	CreatedAt  time.Time          `json:"createdAt"`
 	Thumbnail  *FileInfoThumbnail `json:"thumbnail"`
+	Media      *FileInfoMedia     `json:"media"`
 	ChannelID  optional.UUID      `json:"channelId"`
 	UploaderID optional.UUID      `json:"uploaderId"`
 }
@@ -412,6 +446,14 @@ func formatFileInfo(meta model.File) *FileInfo {
 			Height: meta.GetThumbnailHeight(),
 		}
 	}
+	if len(meta.GetCodec()) > 0 {
+		fi.Media = &FileInfoMedia{
+			Duration: meta.GetDuration().Milliseconds(),
+			Width:    meta.GetWidth(),
+			Height:   meta.GetHeight(),
+			Codec:    meta.GetCodec(),
+		}
+	}
 	return fi
 }
 
@@ -423,6 +465,66 @@ func formatFileInfos(metas []model.File) []*FileInfo {
 	return result
 }
 
+type FileUpload struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Mime      string    `json:"mime"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	ChannelID uuid.UUID `json:"channelId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func formatFileUpload(u *model.FileUpload) *FileUpload {
+	return &FileUpload{
+		ID:        u.ID,
+		Name:      u.Name,
+		Mime:      u.Mime,
+		Size:      u.Size,
+		Offset:    u.Offset,
+		ChannelID: u.ChannelID,
+		ExpiresAt: u.ExpiresAt,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+type ChannelReadState struct {
+	UserID    uuid.UUID `json:"userId"`
+	MessageID uuid.UUID `json:"messageId"`
+	ReadAt    time.Time `json:"readAt"`
+}
+
+func formatChannelReadStates(states []*model.ChannelReadState) []*ChannelReadState {
+	res := make([]*ChannelReadState, len(states))
+	for i, s := range states {
+		res[i] = &ChannelReadState{
+			UserID:    s.UserID,
+			MessageID: s.MessageID,
+			ReadAt:    s.ReadAt,
+		}
+	}
+	return res
+}
+
+type ChannelMove struct {
+	ID     uuid.UUID `json:"id"`
+	Before string    `json:"before"`
+	After  string    `json:"after"`
+}
+
+func formatChannelMoves(moves []*channel.ChannelMove) []*ChannelMove {
+	res := make([]*ChannelMove, len(moves))
+	for i, m := range moves {
+		res[i] = &ChannelMove{
+			ID:     m.ID,
+			Before: m.Before,
+			After:  m.After,
+		}
+	}
+	return res
+}
+
 type OAuth2Client struct {
 	ID          string             `json:"id"`
 	Name        string             `json:"name"`
@@ -517,6 +619,36 @@ func formatClipFolderMessages(cfms []*model.ClipFolderMessage) []*ClipFolderMess
 	return res
 }
 
+type ChannelTemplate struct {
+	ID          uuid.UUID                 `json:"id"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Definition  model.ChannelTemplateNode `json:"definition"`
+	CreatorID   uuid.UUID                 `json:"creatorId"`
+	CreatedAt   time.Time                 `json:"createdAt"`
+	UpdatedAt   time.Time                 `json:"updatedAt"`
+}
+
+func formatChannelTemplate(t *model.ChannelTemplate) *ChannelTemplate {
+	return &ChannelTemplate{
+		ID:          t.ID,
+		Name:        t.Name,
+		Description: t.Description,
+		Definition:  t.Definition,
+		CreatorID:   t.CreatorID,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+func formatChannelTemplates(ts []*model.ChannelTemplate) []*ChannelTemplate {
+	res := make([]*ChannelTemplate, len(ts))
+	for i, t := range ts {
+		res[i] = formatChannelTemplate(t)
+	}
+	return res
+}
+
 type StampPalette struct {
 	ID          uuid.UUID   `json:"id"`
 	Name        string      `json:"name"`
@@ -11,8 +11,11 @@ import (
 	"github.com/traPtitech/traQ/service/counter"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ldap"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/sse"
+	"github.com/traPtitech/traQ/service/upload"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
@@ -24,6 +27,7 @@ type Handlers struct {
 	RBAC           rbac.RBAC
 	Repo           repository.Repository
 	WS             *ws.Streamer
+	SSE            *sse.Streamer
 	Hub            *hub.Hub
 	Logger         *zap.Logger
 	OC             *counter.OnlineCounter
@@ -33,6 +37,8 @@ type Handlers struct {
 	SessStore      session.Store
 	ChannelManager channel.Manager
 	FileManager    file.Manager
+	Upload         upload.Manager
+	LDAP           ldap.Service
 	Replacer       *message.Replacer
 	Config
 }
@@ -46,6 +52,19 @@ type Config struct {
 
 	// EnabledExternalAccountLink リンク可能な外部認証アカウントのプロバイダ
 	EnabledExternalAccountProviders map[string]bool
+
+	// StorageQuota ファイル容量制限設定
+	StorageQuota StorageQuota
+}
+
+// StorageQuota ファイル容量制限設定 (byte, 0以下の場合は無制限)
+type StorageQuota struct {
+	// User ユーザー毎の容量制限
+	User int64
+	// Channel チャンネル毎の容量制限
+	Channel int64
+	// Total インスタンス全体の容量制限
+	Total int64
 }
 
 // Setup APIルーティングを行います
@@ -59,12 +78,14 @@ func (h *Handlers) Setup(e *echo.Group) {
 
 	requiresBotAccessPerm := middlewares.CheckBotAccessPerm(h.RBAC, h.Repo)
 	requiresWebhookAccessPerm := middlewares.CheckWebhookAccessPerm(h.RBAC, h.Repo)
-	requiresFileAccessPerm := middlewares.CheckFileAccessPerm(h.RBAC, h.FileManager)
+	requiresFileAccessPerm := middlewares.CheckFileAccessPerm(h.RBAC, h.FileManager, h.ChannelManager)
 	requiresClientAccessPerm := middlewares.CheckClientAccessPerm(h.RBAC, h.Repo)
 	requiresMessageAccessPerm := middlewares.CheckMessageAccessPerm(h.RBAC, h.ChannelManager)
 	requiresChannelAccessPerm := middlewares.CheckChannelAccessPerm(h.RBAC, h.ChannelManager)
 	requiresGroupAdminPerm := middlewares.CheckUserGroupAdminPerm(h.RBAC, h.Repo)
 	requiresClipFolderAccessPerm := middlewares.CheckClipFolderAccessPerm(h.RBAC, h.Repo)
+	blockReadOnlyGroup := middlewares.BlockReadOnlyUserGroup()
+	blockArchivedChannel := middlewares.BlockArchivedMessageChannel(h.ChannelManager)
 
 	api := e.Group("/v3", middlewares.UserAuthenticate(h.Repo, h.SessStore))
 	{
@@ -97,12 +118,15 @@ func (h *Handlers) Setup(e *echo.Group) {
 			{
 				apiUsersMe.GET("", h.GetMe, requires(permission.GetMe))
 				apiUsersMe.PATCH("", h.EditMe, requires(permission.EditMe))
+				apiUsersMe.GET("/settings", h.GetMySettings, requires(permission.GetMe))
+				apiUsersMe.PATCH("/settings", h.EditMySettings, requires(permission.EditMe))
 				apiUsersMe.GET("/stamp-history", h.GetMyStampHistory, requires(permission.GetMyStampHistory))
 				apiUsersMe.GET("/qr-code", h.GetMyQRCode, requires(permission.GetUserQRCode), blockBot)
 				apiUsersMe.GET("/icon", h.GetMyIcon, requires(permission.DownloadFile))
 				apiUsersMe.PUT("/icon", h.ChangeMyIcon, requires(permission.ChangeMyIcon))
 				apiUsersMe.PUT("/password", h.PutMyPassword, requires(permission.ChangeMyPassword), blockBot)
 				apiUsersMe.POST("/fcm-device", h.PostMyFCMDevice, requires(permission.RegisterFCMDevice), blockBot)
+				apiUsersMe.POST("/group-dm-channel", h.PostMyGroupDMChannel, requires(permission.GetChannel), blockBot)
 				apiUsersMeTags := apiUsersMe.Group("/tags")
 				{
 					apiUsersMeTags.GET("", h.GetMyUserTags, requires(permission.GetUserTag))
@@ -144,6 +168,7 @@ func (h *Handlers) Setup(e *echo.Group) {
 					apiUsersMeExAccounts.GET("", h.GetMyExternalAccounts, requires(permission.GetMyExternalAccount))
 					apiUsersMeExAccounts.POST("/link", h.LinkExternalAccount, requires(permission.EditMyExternalAccount))
 					apiUsersMeExAccounts.POST("/unlink", h.UnlinkExternalAccount, requires(permission.EditMyExternalAccount))
+					apiUsersMeExAccounts.POST("/ldap", h.LinkLDAPAccount, requires(permission.EditMyExternalAccount))
 				}
 			}
 		}
@@ -155,16 +180,29 @@ func (h *Handlers) Setup(e *echo.Group) {
 			{
 				apiChannelsCID.GET("", h.GetChannel, requires(permission.GetChannel))
 				apiChannelsCID.PATCH("", h.EditChannel, requires(permission.EditChannel))
+				apiChannelsCID.POST("/archive", h.ArchiveChannel, requires(permission.ArchiveChannel))
+				apiChannelsCID.POST("/unarchive", h.UnarchiveChannel, requires(permission.ArchiveChannel))
+				apiChannelsCID.POST("/merge", h.MergeChannel, requires(permission.EditChannel))
+				apiChannelsCID.POST("/move", h.MoveChannel, requires(permission.ChangeParentChannel))
+				apiChannelsCID.GET("/redirect", h.GetChannelRedirect, requires(permission.GetChannel))
+				apiChannelsCID.GET("/members", h.GetChannelMembers, requires(permission.GetChannel))
+				apiChannelsCID.POST("/members", h.AddChannelMember, requires(permission.EditPrivateChannelMembers))
+				apiChannelsCID.DELETE("/members/:userID", h.RemoveChannelMember, requires(permission.EditPrivateChannelMembers))
+				apiChannelsCID.POST("/leave", h.LeaveChannel, requires(permission.EditPrivateChannelMembers))
+				apiChannelsCID.POST("/convert", h.ConvertChannel, requires(permission.CreateChannel))
 				apiChannelsCID.GET("/messages", h.GetMessages, requires(permission.GetMessage))
 				apiChannelsCID.POST("/messages", h.PostMessage, bodyLimit(100), requires(permission.PostMessage))
 				apiChannelsCID.GET("/stats", h.GetChannelStats, requires(permission.GetChannel))
 				apiChannelsCID.GET("/topic", h.GetChannelTopic, requires(permission.GetChannel))
 				apiChannelsCID.PUT("/topic", h.EditChannelTopic, requires(permission.EditChannelTopic))
 				apiChannelsCID.GET("/viewers", h.GetChannelViewers, requires(permission.GetChannel))
+				apiChannelsCID.GET("/read-states", h.GetChannelReadStates, requires(permission.GetChannel))
 				apiChannelsCID.GET("/pins", h.GetChannelPins, requires(permission.GetMessage))
 				apiChannelsCID.GET("/subscribers", h.GetChannelSubscribers, requires(permission.GetChannelSubscription))
 				apiChannelsCID.PUT("/subscribers", h.SetChannelSubscribers, requires(permission.EditChannelSubscription))
 				apiChannelsCID.PATCH("/subscribers", h.EditChannelSubscribers, requires(permission.EditChannelSubscription))
+				apiChannelsCID.GET("/subscribers/groups", h.GetChannelGroupSubscriptions, requires(permission.GetChannelSubscription))
+				apiChannelsCID.PUT("/subscribers/groups/:groupID", h.SetChannelGroupSubscription, requires(permission.EditChannelSubscription))
 				apiChannelsCID.GET("/bots", h.GetChannelBots, requires(permission.GetChannel))
 				apiChannelsCID.GET("/events", h.GetChannelEvents, requires(permission.GetChannel))
 			}
@@ -177,16 +215,16 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiMessagesMID.PUT("", h.EditMessage, bodyLimit(100), requires(permission.EditMessage))
 				apiMessagesMID.DELETE("", h.DeleteMessage, requires(permission.DeleteMessage))
 				apiMessagesMID.GET("/pin", h.GetPin, requires(permission.GetMessage))
-				apiMessagesMID.POST("/pin", h.CreatePin, requires(permission.CreateMessagePin))
-				apiMessagesMID.DELETE("/pin", h.RemovePin, requires(permission.DeleteMessagePin))
+				apiMessagesMID.POST("/pin", h.CreatePin, requires(permission.CreateMessagePin), blockArchivedChannel)
+				apiMessagesMID.DELETE("/pin", h.RemovePin, requires(permission.DeleteMessagePin), blockArchivedChannel)
 				apiMessagesMID.GET("/clips", h.GetMessageClips, requires(permission.GetClipFolder))
 				apiMessagesMIDStamps := apiMessagesMID.Group("/stamps")
 				{
 					apiMessagesMIDStamps.GET("", h.GetMessageStamps, requires(permission.GetMessage))
 					apiMessagesMIDStampsSID := apiMessagesMIDStamps.Group("/:stampID", retrieve.StampID(true))
 					{
-						apiMessagesMIDStampsSID.POST("", h.AddMessageStamp, requires(permission.AddMessageStamp))
-						apiMessagesMIDStampsSID.DELETE("", h.RemoveMessageStamp, requires(permission.RemoveMessageStamp))
+						apiMessagesMIDStampsSID.POST("", h.AddMessageStamp, requires(permission.AddMessageStamp), blockArchivedChannel)
+						apiMessagesMIDStampsSID.DELETE("", h.RemoveMessageStamp, requires(permission.RemoveMessageStamp), blockArchivedChannel)
 					}
 				}
 			}
@@ -195,6 +233,23 @@ func (h *Handlers) Setup(e *echo.Group) {
 		{
 			apiFiles.GET("", h.GetFiles, requires(permission.DownloadFile))
 			apiFiles.POST("", h.PostFile, bodyLimit(30<<10), requires(permission.UploadFile))
+			apiFilesUploads := apiFiles.Group("/uploads", requires(permission.UploadFile))
+			{
+				apiFilesUploads.POST("", h.CreateFileUpload)
+				apiFilesUploadsUID := apiFilesUploads.Group("/:uploadID")
+				{
+					apiFilesUploadsUID.GET("", h.GetFileUpload)
+					apiFilesUploadsUID.PATCH("", h.PatchFileUpload, bodyLimit(30<<10))
+					apiFilesUploadsUID.DELETE("", h.AbortFileUpload)
+					apiFilesUploadsUID.POST("/finalize", h.FinalizeFileUpload)
+				}
+			}
+			apiFilesUsage := apiFiles.Group("/usage", requires(permission.GetFileUsage))
+			{
+				apiFilesUsage.GET("", h.GetFileUsage)
+				apiFilesUsage.GET("/users", h.GetUserFileUsageRanking)
+				apiFilesUsage.GET("/channels", h.GetChannelFileUsageRanking)
+			}
 			apiFilesFID := apiFiles.Group("/:fileID", retrieve.FileID(), requiresFileAccessPerm)
 			{
 				apiFilesFID.GET("", h.GetFile, requires(permission.DownloadFile))
@@ -214,15 +269,29 @@ func (h *Handlers) Setup(e *echo.Group) {
 		{
 			apiStamps.GET("", h.GetStamps, requires(permission.GetStamp))
 			apiStamps.POST("", h.CreateStamp, requires(permission.CreateStamp))
+			apiStamps.GET("/ranking", h.GetStampRanking, requires(permission.GetStamp))
 			apiStampsSID := apiStamps.Group("/:stampID", retrieve.StampID(false))
 			{
 				apiStampsSID.GET("", h.GetStamp, requires(permission.GetStamp))
 				apiStampsSID.PATCH("", h.EditStamp, requires(permission.EditStamp))
 				apiStampsSID.DELETE("", h.DeleteStamp, requires(permission.DeleteStamp))
+				apiStampsSID.GET("/stats", h.GetStampStats, requires(permission.GetStamp))
 				apiStampsSID.GET("/image", h.GetStampImage, requires(permission.GetStamp, permission.DownloadFile))
 				apiStampsSID.PUT("/image", h.ChangeStampImage, requires(permission.EditStamp))
 			}
 		}
+		apiChannelTemplates := api.Group("/channel-templates", blockBot)
+		{
+			apiChannelTemplates.GET("", h.GetChannelTemplates, requires(permission.GetChannelTemplate))
+			apiChannelTemplates.POST("", h.CreateChannelTemplate, requires(permission.ManageChannelTemplate))
+			apiChannelTemplatesTID := apiChannelTemplates.Group("/:templateID", retrieve.ChannelTemplateID())
+			{
+				apiChannelTemplatesTID.GET("", h.GetChannelTemplate, requires(permission.GetChannelTemplate))
+				apiChannelTemplatesTID.PATCH("", h.EditChannelTemplate, requires(permission.ManageChannelTemplate))
+				apiChannelTemplatesTID.DELETE("", h.DeleteChannelTemplate, requires(permission.ManageChannelTemplate))
+				apiChannelTemplatesTID.POST("/instantiate", h.InstantiateChannelTemplate, requires(permission.ManageChannelTemplate, permission.CreateChannel))
+			}
+		}
 		apiStampPalettes := api.Group("/stamp-palettes", blockBot)
 		{
 			apiStampPalettes.GET("", h.GetStampPalettes, requires(permission.GetStampPalette))
@@ -255,13 +324,13 @@ func (h *Handlers) Setup(e *echo.Group) {
 			apiGroupsGID := apiGroups.Group("/:groupID", retrieve.GroupID())
 			{
 				apiGroupsGID.GET("", h.GetUserGroup, requires(permission.GetUserGroup))
-				apiGroupsGID.PATCH("", h.EditUserGroup, requiresGroupAdminPerm, requires(permission.EditUserGroup))
-				apiGroupsGID.DELETE("", h.DeleteUserGroup, requiresGroupAdminPerm, requires(permission.DeleteUserGroup))
+				apiGroupsGID.PATCH("", h.EditUserGroup, blockReadOnlyGroup, requiresGroupAdminPerm, requires(permission.EditUserGroup))
+				apiGroupsGID.DELETE("", h.DeleteUserGroup, blockReadOnlyGroup, requiresGroupAdminPerm, requires(permission.DeleteUserGroup))
 				apiGroupsGIDMembers := apiGroupsGID.Group("/members")
 				{
 					apiGroupsGIDMembers.GET("", h.GetUserGroupMembers, requires(permission.GetUserGroup))
-					apiGroupsGIDMembers.POST("", h.AddUserGroupMember, requiresGroupAdminPerm, requires(permission.EditUserGroup))
-					apiGroupsGIDMembersUID := apiGroupsGIDMembers.Group("/:userID", requiresGroupAdminPerm)
+					apiGroupsGIDMembers.POST("", h.AddUserGroupMember, blockReadOnlyGroup, requiresGroupAdminPerm, requires(permission.EditUserGroup))
+					apiGroupsGIDMembersUID := apiGroupsGIDMembers.Group("/:userID", blockReadOnlyGroup, requiresGroupAdminPerm)
 					{
 						apiGroupsGIDMembersUID.PATCH("", h.EditUserGroupMember, requires(permission.EditUserGroup))
 						apiGroupsGIDMembersUID.DELETE("", h.RemoveUserGroupMember, requires(permission.EditUserGroup))
@@ -270,12 +339,21 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiGroupsGIDAdmins := apiGroupsGID.Group("/admins")
 				{
 					apiGroupsGIDAdmins.GET("", h.GetUserGroupAdmins, requires(permission.GetUserGroup))
-					apiGroupsGIDAdmins.POST("", h.AddUserGroupAdmin, requiresGroupAdminPerm, requires(permission.EditUserGroup))
-					apiGroupsGIDAdminsUID := apiGroupsGIDAdmins.Group("/:userID", requiresGroupAdminPerm)
+					apiGroupsGIDAdmins.POST("", h.AddUserGroupAdmin, blockReadOnlyGroup, requiresGroupAdminPerm, requires(permission.EditUserGroup))
+					apiGroupsGIDAdminsUID := apiGroupsGIDAdmins.Group("/:userID", blockReadOnlyGroup, requiresGroupAdminPerm)
 					{
 						apiGroupsGIDAdminsUID.DELETE("", h.RemoveUserGroupAdmin, requires(permission.EditUserGroup))
 					}
 				}
+				apiGroupsGIDChildren := apiGroupsGID.Group("/children")
+				{
+					apiGroupsGIDChildren.GET("", h.GetUserGroupChildren, requires(permission.GetUserGroup))
+					apiGroupsGIDChildren.POST("", h.AddUserGroupChild, blockReadOnlyGroup, requiresGroupAdminPerm, requires(permission.EditUserGroup))
+					apiGroupsGIDChildrenCID := apiGroupsGIDChildren.Group("/:childID", blockReadOnlyGroup, requiresGroupAdminPerm)
+					{
+						apiGroupsGIDChildrenCID.DELETE("", h.RemoveUserGroupChild, requires(permission.EditUserGroup))
+					}
+				}
 			}
 		}
 		apiActivity := api.Group("/activity")
@@ -339,6 +417,7 @@ func (h *Handlers) Setup(e *echo.Group) {
 			}
 		}
 		api.GET("/ws", echo.WrapHandler(h.WS), requires(permission.ConnectNotificationStream), blockBot)
+		api.GET("/sse", echo.WrapHandler(h.SSE), requires(permission.ConnectNotificationStream), blockBot)
 	}
 
 	apiNoAuth := e.Group("/v3")
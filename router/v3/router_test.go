@@ -15,6 +15,7 @@ import (
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ldap"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/rbac/role"
 	"github.com/traPtitech/traQ/utils/random"
@@ -76,6 +77,7 @@ func TestMain(m *testing.M) {
 		}
 		env.Repository = repo
 		env.CM, _ = channel.InitChannelManager(repo, zap.NewNop())
+		env.LDAP = &testLDAPService{Service: ldap.NewNullService(), repo: repo, directory: map[string]string{}}
 
 		// テスト用サーバー作成
 		e := echo.New()
@@ -95,6 +97,7 @@ func TestMain(m *testing.M) {
 			SessStore:      env.SessStore,
 			ChannelManager: env.CM,
 			Logger:         zap.NewNop(),
+			LDAP:           env.LDAP,
 			Imaging: imaging.NewProcessor(imaging.Config{
 				MaxPixels:        1000 * 1000,
 				Concurrency:      1,
@@ -131,6 +134,7 @@ type Env struct {
 	CM         channel.Manager
 	Hub        *hub.Hub
 	SessStore  session.Store
+	LDAP       *testLDAPService
 }
 
 // Setup テストセットアップ
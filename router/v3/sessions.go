@@ -8,6 +8,7 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/ldap"
 	"github.com/traPtitech/traQ/utils/validator"
 	"go.uber.org/zap"
 	"net/http"
@@ -36,29 +37,60 @@ func (h *Handlers) Login(c echo.Context) error {
 		return err
 	}
 
-	user, err := h.Repo.GetUserByName(req.Name, false)
-	if err != nil {
+	// LDAP認証
+	user, err := h.LDAP.Login(req.Name, req.Password)
+	switch err {
+	case nil:
+		if !user.IsActive() {
+			h.L(c).Info("an api login attempt failed: suspended user", zap.String("username", req.Name))
+			return herror.Forbidden("this account is currently suspended")
+		}
+		h.L(c).Info("an api login attempt succeeded by ldap", zap.String("username", req.Name))
+	case ldap.ErrNotRegistered:
+		h.L(c).Info("an api login attempt failed: ldap user is not registered", zap.String("username", req.Name))
+		return herror.Forbidden("You are not a member of traQ")
+	default:
 		switch err {
-		case repository.ErrNotFound:
-			h.L(c).Info("an api login attempt failed: unknown user", zap.String("username", req.Name))
-			return echo.NewHTTPError(http.StatusUnauthorized, "invalid name")
+		case ldap.ErrInvalidCredentials:
+			// LDAPアカウントが関連付けられているユーザーはローカル認証を行わない
+			linked, err := h.isLDAPLinkedUser(req.Name)
+			if err != nil {
+				return herror.InternalServerError(err)
+			}
+			if linked {
+				h.L(c).Info("an api login attempt failed: wrong ldap password", zap.String("username", req.Name))
+				return herror.Unauthorized("invalid name or password")
+			}
+		case ldap.ErrUserNotFound, ldap.ErrNotLinked:
+			// LDAPのユーザーとしてログインできない場合はローカル認証を行う
 		default:
-			return herror.InternalServerError(err)
+			h.L(c).Warn("ldap login failed. fallback to local login", zap.String("username", req.Name), zap.Error(err))
+		}
+		// ローカル認証
+		user, err = h.Repo.GetUserByName(req.Name, false)
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				h.L(c).Info("an api login attempt failed: unknown user", zap.String("username", req.Name))
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid name")
+			default:
+				return herror.InternalServerError(err)
+			}
 		}
-	}
 
-	// ユーザーのアカウント状態の確認
-	if !user.IsActive() {
-		h.L(c).Info("an api login attempt failed: suspended user", zap.String("username", req.Name))
-		return herror.Forbidden("this account is currently suspended")
-	}
+		// ユーザーのアカウント状態の確認
+		if !user.IsActive() {
+			h.L(c).Info("an api login attempt failed: suspended user", zap.String("username", req.Name))
+			return herror.Forbidden("this account is currently suspended")
+		}
 
-	// パスワード検証
-	if err := user.Authenticate(req.Password); err != nil {
-		h.L(c).Info("an api login attempt failed: wrong password", zap.String("username", req.Name))
-		return echo.NewHTTPError(http.StatusUnauthorized, err)
+		// パスワード検証
+		if err := user.Authenticate(req.Password); err != nil {
+			h.L(c).Info("an api login attempt failed: wrong password", zap.String("username", req.Name))
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		}
+		h.L(c).Info("an api login attempt succeeded", zap.String("username", req.Name))
 	}
-	h.L(c).Info("an api login attempt succeeded", zap.String("username", req.Name))
 
 	if _, err := h.SessStore.RenewSession(c, user.GetID()); err != nil {
 		return herror.InternalServerError(err)
@@ -70,6 +102,27 @@ func (h *Handlers) Login(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// isLDAPLinkedUser 指定した名前のtraQユーザーにLDAPアカウントが関連付けられているかどうか
+func (h *Handlers) isLDAPLinkedUser(name string) (bool, error) {
+	user, err := h.Repo.GetUserByName(name, false)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	accounts, err := h.Repo.GetLinkedExternalUserAccounts(user.GetID())
+	if err != nil {
+		return false, err
+	}
+	for _, account := range accounts {
+		if account.ProviderName == ldap.ProviderName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Logout POST /logout
 func (h *Handlers) Logout(c echo.Context) error {
 	sess, err := h.SessStore.GetSession(c, false)
@@ -245,6 +298,40 @@ func (h *Handlers) LinkExternalAccount(c echo.Context) error {
 	return c.Redirect(http.StatusFound, "/api/auth/"+req.ProviderName+"?link=1")
 }
 
+// PostLinkLDAPAccountRequest POST /users/me/ex-accounts/ldap リクエストボディ
+type PostLinkLDAPAccountRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func (r PostLinkLDAPAccountRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, vd.Required),
+		vd.Field(&r.Password, vd.Required),
+	)
+}
+
+// LinkLDAPAccount POST /users/me/ex-accounts/ldap
+func (h *Handlers) LinkLDAPAccount(c echo.Context) error {
+	var req PostLinkLDAPAccountRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.LDAP.Link(getRequestUserID(c), req.Name, req.Password); err != nil {
+		switch err {
+		case ldap.ErrUserNotFound, ldap.ErrInvalidCredentials:
+			return herror.BadRequest("invalid name or password")
+		case repository.ErrAlreadyExists:
+			return herror.Conflict("this account has already been linked")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 // PostUnlinkExternalAccount POST /users/me/ex-accounts/unlink リクエストボディ
 type PostUnlinkExternalAccount struct {
 	ProviderName string `json:"providerName"`
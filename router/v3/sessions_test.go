@@ -0,0 +1,118 @@
+package v3
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/ldap"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// testLDAPService テスト用のLDAPサービス
+//
+// directoryに登録されたユーザー名とパスワードで認証し、同名のtraQユーザーを返します
+type testLDAPService struct {
+	ldap.Service
+	repo      repository.Repository
+	directory map[string]string
+	mu        sync.RWMutex
+}
+
+func (s *testLDAPService) Login(name, password string) (model.UserInfo, error) {
+	s.mu.RLock()
+	p, ok := s.directory[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ldap.ErrUserNotFound
+	}
+	if p != password {
+		return nil, ldap.ErrInvalidCredentials
+	}
+	return s.repo.GetUserByName(name, false)
+}
+
+// AddEntry ディレクトリにユーザーを追加します
+func (s *testLDAPService) AddEntry(name, password string) {
+	s.mu.Lock()
+	s.directory[name] = password
+	s.mu.Unlock()
+}
+
+func TestHandlers_Login(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/login"
+	env := Setup(t, common)
+
+	t.Run("local user", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+
+		e := env.R(t)
+		res := e.POST(path).
+			WithJSON(echo.Map{"name": user.GetName(), "password": "testtesttesttest"}).
+			Expect()
+		res.Status(http.StatusNoContent)
+		res.Cookie(session.CookieName).Value().NotEmpty()
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithJSON(echo.Map{"name": "unknown_user", "password": "testtesttesttest"}).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("ldap user", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		env.LDAP.AddEntry(user.GetName(), "ldap-password")
+
+		e := env.R(t)
+		e.POST(path).
+			WithJSON(echo.Map{"name": user.GetName(), "password": "ldap-password"}).
+			Expect().
+			Status(http.StatusNoContent)
+	})
+
+	t.Run("local user with the same name as a directory entry", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		env.LDAP.AddEntry(user.GetName(), "ldap-password")
+
+		e := env.R(t)
+		res := e.POST(path).
+			WithJSON(echo.Map{"name": user.GetName(), "password": "testtesttesttest"}).
+			Expect()
+		res.Status(http.StatusNoContent)
+		res.Cookie(session.CookieName).Value().NotEmpty()
+
+		e.POST(path).
+			WithJSON(echo.Map{"name": user.GetName(), "password": "wrong password"}).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("ldap linked user with wrong ldap password", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		env.LDAP.AddEntry(user.GetName(), "ldap-password")
+		require.NoError(t, env.Repository.LinkExternalUserAccount(user.GetID(), repository.LinkExternalUserAccountArgs{
+			ProviderName: ldap.ProviderName,
+			ExternalID:   user.GetName(),
+			Extra:        model.JSON{},
+		}))
+
+		// LDAPアカウントが関連付けられている場合、ローカルのパスワードではログインできない
+		e := env.R(t)
+		e.POST(path).
+			WithJSON(echo.Map{"name": user.GetName(), "password": "testtesttesttest"}).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+}
@@ -3,6 +3,7 @@ package v3
 import (
 	"context"
 	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
@@ -14,10 +15,15 @@ import (
 	"github.com/traPtitech/traQ/utils/validator"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // GetStamps GET /stamps
 func (h *Handlers) GetStamps(c echo.Context) error {
+	if q := c.QueryParam("q"); len(q) > 0 {
+		return h.searchStamps(c, q)
+	}
+
 	u := c.QueryParam("include-unicode")
 	if len(u) == 0 {
 		u = "1"
@@ -36,6 +42,24 @@ func (h *Handlers) GetStamps(c echo.Context) error {
 	return c.JSONBlob(http.StatusOK, b)
 }
 
+// searchStamps GET /stamps?q=
+func (h *Handlers) searchStamps(c echo.Context, q string) error {
+	limit := 50
+	if l := c.QueryParam("limit"); len(l) > 0 {
+		v, err := strconv.Atoi(l)
+		if err != nil || v < 1 || v > 200 {
+			return herror.BadRequest("invalid limit")
+		}
+		limit = v
+	}
+
+	stamps, err := h.Repo.SearchStamps(q, getRequestUserID(c), limit)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, stamps)
+}
+
 // CreateStamp POST /stamps
 func (h *Handlers) CreateStamp(c echo.Context) error {
 	userID := getRequestUserID(c)
@@ -47,7 +71,7 @@ func (h *Handlers) CreateStamp(c echo.Context) error {
 	}
 
 	// スタンプ作成
-	s, err := h.Repo.CreateStamp(repository.CreateStampArgs{Name: c.FormValue("name"), FileID: fileID, CreatorID: userID})
+	s, err := h.Repo.CreateStamp(repository.CreateStampArgs{Name: c.FormValue("name"), FileID: fileID, CreatorID: userID, Category: c.FormValue("category")})
 	if err != nil {
 		switch {
 		case repository.IsArgError(err):
@@ -70,12 +94,18 @@ func (h *Handlers) GetStamp(c echo.Context) error {
 type PatchStampRequest struct {
 	Name      optional.String `json:"name"`
 	CreatorID optional.UUID   `json:"creatorId"`
+	Category  optional.String `json:"category"`
+	Aliases   []string        `json:"aliases"`
+	Tags      []string        `json:"tags"`
 }
 
 func (r PatchStampRequest) ValidateWithContext(ctx context.Context) error {
 	return vd.ValidateStructWithContext(ctx, &r,
 		vd.Field(&r.Name, validator.StampNameRule...),
 		vd.Field(&r.CreatorID, validator.NotNilUUID, utils.IsActiveHumanUserID),
+		vd.Field(&r.Category, validator.StampCategoryRule...),
+		vd.Field(&r.Aliases, vd.Length(0, 20), vd.Each(validator.StampNameRuleRequired...)),
+		vd.Field(&r.Tags, vd.Length(0, 20), vd.Each(validator.StampTagRuleRequired...)),
 	)
 }
 
@@ -97,6 +127,9 @@ func (h *Handlers) EditStamp(c echo.Context) error {
 	args := repository.UpdateStampArgs{
 		Name:      req.Name,
 		CreatorID: req.CreatorID,
+		Category:  req.Category,
+		Aliases:   req.Aliases,
+		Tags:      req.Tags,
 	}
 
 	// 更新
@@ -105,7 +138,7 @@ func (h *Handlers) EditStamp(c echo.Context) error {
 		case repository.IsArgError(err):
 			return herror.BadRequest(err)
 		case err == repository.ErrAlreadyExists:
-			return herror.Conflict("this name has already been used")
+			return herror.Conflict("this name or alias has already been used")
 		default:
 			return herror.InternalServerError(err)
 		}
@@ -124,6 +157,79 @@ func (h *Handlers) DeleteStamp(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// GetStampStatsRequest GET /stamps/:stampID/stats 用リクエストクエリ
+type GetStampStatsRequest struct {
+	Days int `query:"days"`
+}
+
+func (r *GetStampStatsRequest) Validate() error {
+	if r.Days == 0 {
+		r.Days = 30
+	}
+	return vd.ValidateStruct(r,
+		vd.Field(&r.Days, vd.Min(1), vd.Max(365)),
+	)
+}
+
+// GetStampStats GET /stamps/:stampID/stats
+func (h *Handlers) GetStampStats(c echo.Context) error {
+	stampID := getParamAsUUID(c, consts.ParamStampID)
+
+	var req GetStampStatsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	stats, err := h.Repo.GetStampStats(stampID, time.Now().AddDate(0, 0, -(req.Days-1)))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// GetStampRankingRequest GET /stamps/ranking 用リクエストクエリ
+type GetStampRankingRequest struct {
+	Days      int       `query:"days"`
+	ChannelID uuid.UUID `query:"channelId"`
+	Limit     int       `query:"limit"`
+}
+
+func (r *GetStampRankingRequest) Validate() error {
+	if r.Days == 0 {
+		r.Days = 7
+	}
+	if r.Limit == 0 {
+		r.Limit = 20
+	}
+	return vd.ValidateStruct(r,
+		vd.Field(&r.Days, vd.Min(1), vd.Max(365)),
+		vd.Field(&r.Limit, vd.Min(1), vd.Max(100)),
+	)
+}
+
+// GetStampRanking GET /stamps/ranking
+func (h *Handlers) GetStampRanking(c echo.Context) error {
+	var req GetStampRankingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if req.ChannelID != uuid.Nil {
+		// チャンネルアクセス権確認
+		if ok, err := h.ChannelManager.IsChannelAccessibleToUser(getRequestUserID(c), req.ChannelID); err != nil {
+			return herror.InternalServerError(err)
+		} else if !ok {
+			return herror.BadRequest("invalid channelId")
+		}
+	}
+
+	ranking, err := h.Repo.GetStampRanking(time.Now().AddDate(0, 0, -(req.Days-1)), req.ChannelID, req.Limit)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, ranking)
+}
+
 // GetStampImage GET /stamps/:stampID/image
 func (h *Handlers) GetStampImage(c echo.Context) error {
 	stamp := getParamStamp(c)
@@ -134,7 +134,23 @@ func (h *Handlers) DeleteUserGroup(c echo.Context) error {
 
 // GetUserGroupMembers GET /groups/:groupID/members
 func (h *Handlers) GetUserGroupMembers(c echo.Context) error {
-	return c.JSON(http.StatusOK, formatUserGroupMembers(getParamGroup(c).Members))
+	g := getParamGroup(c)
+	members := formatUserGroupMembers(g.Members)
+	if !isTrue(c.QueryParam("include-subgroups")) {
+		return c.JSON(http.StatusOK, members)
+	}
+
+	// 子グループを介したメンバーも含める
+	ids, err := h.Repo.GetUserIDs(repository.UsersQuery{}.GMemberOf(g.ID))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	for _, id := range ids {
+		if !g.IsMember(id) {
+			members = append(members, UserGroupMember{ID: id})
+		}
+	}
+	return c.JSON(http.StatusOK, members)
 }
 
 // PostUserGroupMemberRequest POST /groups/:groupID/members リクエストボディ
@@ -257,3 +273,54 @@ func (h *Handlers) RemoveUserGroupAdmin(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// GetUserGroupChildren GET /groups/:groupID/children
+func (h *Handlers) GetUserGroupChildren(c echo.Context) error {
+	return c.JSON(http.StatusOK, getParamGroup(c).ChildIDArray())
+}
+
+// PostUserGroupChildRequest POST /groups/:groupID/children リクエストボディ
+type PostUserGroupChildRequest struct {
+	ID uuid.UUID `json:"id"`
+}
+
+func (r PostUserGroupChildRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.ID, vd.Required, validator.NotNilUUID),
+	)
+}
+
+// AddUserGroupChild POST /groups/:groupID/children
+func (h *Handlers) AddUserGroupChild(c echo.Context) error {
+	g := getParamGroup(c)
+
+	var req PostUserGroupChildRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.Repo.AddChildToGroup(req.ID, g.ID); err != nil {
+		switch {
+		case err == repository.ErrNotFound:
+			return herror.BadRequest("the child group is not found")
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveUserGroupChild DELETE /groups/:groupID/children/:childID
+func (h *Handlers) RemoveUserGroupChild(c echo.Context) error {
+	childID := getParamAsUUID(c, consts.ParamChildGroupID)
+	g := getParamGroup(c)
+
+	if err := h.Repo.RemoveChildFromGroup(childID, g.ID); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -154,6 +154,35 @@ func (h *Handlers) EditMe(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// GetMySettings GET /users/me/settings
+func (h *Handlers) GetMySettings(c echo.Context) error {
+	settings, err := h.Repo.GetUserSettings(getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"readReceipts": settings.ReadReceipts,
+	})
+}
+
+// PatchMySettingsRequest PATCH /users/me/settings リクエストボディ
+type PatchMySettingsRequest struct {
+	ReadReceipts optional.Bool `json:"readReceipts"`
+}
+
+// EditMySettings PATCH /users/me/settings
+func (h *Handlers) EditMySettings(c echo.Context) error {
+	var req PatchMySettingsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.Repo.UpdateUserSettings(getRequestUserID(c), repository.UpdateUserSettingsArgs{ReadReceipts: req.ReadReceipts}); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
 // PutMyPasswordRequest PUT /users/me/password リクエストボディ
 type PutMyPasswordRequest struct {
 	Password    string `json:"password"`
@@ -72,6 +72,11 @@ func getParamStamp(c echo.Context) *model.Stamp {
 	return c.Get(consts.KeyParamStamp).(*model.Stamp)
 }
 
+// getParamChannelTemplate URLの:templateIDに対応するChannelTemplateを取得
+func getParamChannelTemplate(c echo.Context) *model.ChannelTemplate {
+	return c.Get(consts.KeyParamTemplate).(*model.ChannelTemplate)
+}
+
 // getParamStampPalette URLの:paletteIDに対応するStampPaletteを取得
 func getParamStampPalette(c echo.Context) *model.StampPalette {
 	return c.Get(consts.KeyParamStampPalette).(*model.StampPalette)
@@ -10,6 +10,7 @@ import (
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/oauth2"
+	"github.com/traPtitech/traQ/router/scim"
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/router/utils"
 	"github.com/traPtitech/traQ/router/v1"
@@ -30,6 +31,7 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 	viewerManager := ss.ViewerManager
 	processor := ss.Imaging
 	fileManager := ss.FileManager
+	ldapService := ss.LDAP
 	replaceMapper := utils.NewReplaceMapper(repo, manager)
 	replacer := message.NewReplacer(replaceMapper)
 	handlers := &v1.Handlers{
@@ -43,15 +45,19 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 		SessStore:      store,
 		ChannelManager: manager,
 		FileManager:    fileManager,
+		LDAP:           ldapService,
 		Replacer:       replacer,
 	}
 	streamer := ss.WS
+	sseStreamer := ss.SSE
 	webrtcv3Manager := ss.WebRTCv3
+	uploadManager := ss.Upload
 	v3Config := provideV3Config(config)
 	v3Handlers := &v3.Handlers{
 		RBAC:           rbac,
 		Repo:           repo,
 		WS:             streamer,
+		SSE:            sseStreamer,
 		Hub:            hub2,
 		Logger:         logger,
 		OC:             onlineCounter,
@@ -61,6 +67,8 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 		SessStore:      store,
 		ChannelManager: manager,
 		FileManager:    fileManager,
+		Upload:         uploadManager,
+		LDAP:           ldapService,
 		Replacer:       replacer,
 		Config:         v3Config,
 	}
@@ -72,12 +80,20 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 		SessStore: store,
 		Config:    oauth2Config,
 	}
+	scimConfig := provideSCIMConfig(config)
+	scimHandler := &scim.Handler{
+		Repo:        repo,
+		Logger:      logger,
+		FileManager: fileManager,
+		Config:      scimConfig,
+	}
 	router := &Router{
 		e:         echo,
 		sessStore: store,
 		v1:        handlers,
 		v3:        v3Handlers,
 		oauth2:    handler,
+		scim:      scimHandler,
 	}
 	return router
 }
@@ -27,6 +27,8 @@ const (
 	TagAdded model.BotEventType = "TAG_ADDED"
 	// TagRemoved タグ削除イベント
 	TagRemoved model.BotEventType = "TAG_REMOVED"
+	// UserTyping メッセージ入力中イベント
+	UserTyping model.BotEventType = "USER_TYPING"
 )
 
 var Types model.BotEventTypes
@@ -46,6 +48,7 @@ func init() {
 		StampCreated,
 		TagAdded,
 		TagRemoved,
+		UserTyping,
 	} {
 		Types[t] = struct{}{}
 	}
@@ -0,0 +1,24 @@
+package payload
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
+
+// UserTyping USER_TYPINGイベントペイロード
+type UserTyping struct {
+	Base
+	User      User      `json:"user"`
+	ChannelID uuid.UUID `json:"channelId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func MakeUserTyping(et time.Time, user model.UserInfo, channelID uuid.UUID, expiresAt time.Time) *UserTyping {
+	return &UserTyping{
+		Base:      MakeBase(et),
+		User:      MakeUser(user),
+		ChannelID: channelID,
+		ExpiresAt: expiresAt,
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/bot/event"
+	"github.com/traPtitech/traQ/service/bot/event/payload"
+	"time"
+)
+
+func UserTyping(ctx Context, datetime time.Time, _ string, fields hub.Fields) error {
+	userID := fields["user_id"].(uuid.UUID)
+	chID := fields["channel_id"].(uuid.UUID)
+	expiresAt := fields["expires_at"].(time.Time)
+
+	ch, err := ctx.CM().GetChannel(chID)
+	if err != nil {
+		return fmt.Errorf("failed to GetChannel: %w", err)
+	}
+
+	var bots []*model.Bot
+	if ch.IsDMChannel() {
+		ids, err := ctx.CM().GetDMChannelMembers(ch.ID)
+		if err != nil {
+			return fmt.Errorf("failed to GetDMChannelMembers: %w", err)
+		}
+
+		for _, id := range ids {
+			if id == userID {
+				continue
+			}
+			bot, err := ctx.GetBotByBotUserID(id)
+			if err != nil {
+				return fmt.Errorf("failed to GetBotByBotUserID: %w", err)
+			}
+			if bot != nil && bot.SubscribeEvents.Contains(event.UserTyping) {
+				bots = append(bots, bot)
+			}
+		}
+	} else {
+		bots, err = ctx.GetChannelBots(chID, event.UserTyping)
+		if err != nil {
+			return fmt.Errorf("failed to GetChannelBots: %w", err)
+		}
+	}
+
+	bots = filterBotUserIDNotEquals(bots, userID)
+	if len(bots) == 0 {
+		return nil
+	}
+
+	user, err := ctx.R().GetUser(userID, false)
+	if err != nil {
+		return fmt.Errorf("failed to GetUser: %w", err)
+	}
+
+	if err := ctx.Multicast(
+		event.UserTyping,
+		payload.MakeUserTyping(datetime, user, chID, expiresAt),
+		bots,
+	); err != nil {
+		return fmt.Errorf("failed to multicast: %w", err)
+	}
+	return nil
+}
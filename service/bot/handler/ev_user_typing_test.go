@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	intevent "github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/bot/event"
+	"github.com/traPtitech/traQ/service/bot/event/payload"
+	"testing"
+	"time"
+)
+
+func TestUserTyping(t *testing.T) {
+	t.Parallel()
+
+	b := &model.Bot{
+		ID:              uuid.NewV3(uuid.Nil, "b"),
+		BotUserID:       uuid.NewV3(uuid.Nil, "bu"),
+		SubscribeEvents: model.BotEventTypesFromArray([]string{event.UserTyping.String()}),
+		State:           model.BotActive,
+	}
+	u := &model.User{
+		ID:   uuid.NewV3(uuid.Nil, "u"),
+		Name: "testman",
+	}
+	ch := &model.Channel{
+		ID:       uuid.NewV3(uuid.Nil, "c"),
+		Name:     "test",
+		IsPublic: true,
+	}
+	dm := &model.Channel{
+		ID:       uuid.NewV3(uuid.Nil, "dm"),
+		ParentID: uuid.Must(uuid.FromString(model.DirectMessageChannelRootID)),
+	}
+
+	t.Run("success (public channel)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		handlerCtx, cm, repo := setup(t, ctrl)
+
+		registerBot(t, handlerCtx, b)
+		registerChannel(cm, ch)
+		registerUser(repo, u)
+
+		handlerCtx.EXPECT().
+			GetChannelBots(ch.ID, event.UserTyping).
+			Return([]*model.Bot{b}, nil).
+			AnyTimes()
+
+		et := time.Now()
+		expiresAt := et.Add(time.Second)
+		expectMulticast(handlerCtx, event.UserTyping, payload.MakeUserTyping(et, u, ch.ID, expiresAt), []*model.Bot{b})
+		assert.NoError(t, UserTyping(handlerCtx, et, intevent.UserTyping, hub.Fields{
+			"user_id":    u.ID,
+			"channel_id": ch.ID,
+			"expires_at": expiresAt,
+		}))
+	})
+
+	t.Run("success (dm channel)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		handlerCtx, cm, repo := setup(t, ctrl)
+
+		registerBot(t, handlerCtx, b)
+		registerChannel(cm, dm)
+		registerUser(repo, u)
+
+		cm.EXPECT().
+			GetDMChannelMembers(dm.ID).
+			Return([]uuid.UUID{u.ID, b.BotUserID}, nil).
+			AnyTimes()
+
+		et := time.Now()
+		expiresAt := et.Add(time.Second)
+		expectMulticast(handlerCtx, event.UserTyping, payload.MakeUserTyping(et, u, dm.ID, expiresAt), []*model.Bot{b})
+		assert.NoError(t, UserTyping(handlerCtx, et, intevent.UserTyping, hub.Fields{
+			"user_id":    u.ID,
+			"channel_id": dm.ID,
+			"expires_at": expiresAt,
+		}))
+	})
+
+	t.Run("success (typing by the bot itself)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		handlerCtx, cm, _ := setup(t, ctrl)
+
+		registerBot(t, handlerCtx, b)
+		registerChannel(cm, ch)
+
+		handlerCtx.EXPECT().
+			GetChannelBots(ch.ID, event.UserTyping).
+			Return([]*model.Bot{b}, nil).
+			AnyTimes()
+
+		assert.NoError(t, UserTyping(handlerCtx, time.Now(), intevent.UserTyping, hub.Fields{
+			"user_id":    b.BotUserID,
+			"channel_id": ch.ID,
+			"expires_at": time.Now(),
+		}))
+	})
+}
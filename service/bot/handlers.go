@@ -20,4 +20,5 @@ var eventHandlerSet = map[string]eventHandler{
 	intevent.StampCreated:        handler.StampCreated,
 	intevent.UserTagAdded:        handler.UserTagAdded,
 	intevent.UserTagRemoved:      handler.UserTagRemoved,
+	intevent.UserTyping:          handler.UserTyping,
 }
@@ -0,0 +1,284 @@
+package bus
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/traPtitech/traQ/utils/random"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrClosed イベントバスは既に閉じられています
+	ErrClosed = errors.New("event bus is closed")
+)
+
+const (
+	kindMessage   = "message"
+	kindSnapshot  = "snapshot"
+	kindHeartbeat = "heartbeat"
+	kindSync      = "sync"
+	kindLeave     = "leave"
+)
+
+// Transport ノード間でメッセージを配送する手段
+type Transport interface {
+	// Publish 全ノードにメッセージを配送します
+	//
+	// 自ノードにも配送されて構いません。
+	Publish(data []byte) error
+	// Receive メッセージの受信を開始します
+	//
+	// handlerは受信したメッセージ毎に、connectedは配送先への(再)接続が完了する度に呼び出されます。
+	Receive(handler func(data []byte), connected func())
+	// Close 配送を終了します
+	Close() error
+}
+
+// Handler 他のノードから届いたメッセージのハンドラ
+type Handler func(node string, data []byte)
+
+// Shared ノード毎に所有され、クラスタ内で共有される状態
+type Shared interface {
+	// Snapshot 自ノードが所有する状態の全体を返します
+	Snapshot() []byte
+	// ApplySnapshot 指定したノードが所有する状態を全体で置き換えます
+	ApplySnapshot(node string, data []byte)
+	// Apply 指定したノードが所有する状態の差分を適用します
+	Apply(node string, data []byte)
+	// Forget クラスタから離脱したノードが所有していた状態を破棄します
+	Forget(node string)
+}
+
+type envelope struct {
+	Node  string `json:"node"`
+	Kind  string `json:"kind"`
+	Topic string `json:"topic,omitempty"`
+	Data  []byte `json:"data,omitempty"`
+}
+
+// Bus ノード間イベントバス
+//
+// 複数ノードで動作させる場合に、イベントやノード毎の状態をノード間で共有します。
+// 自ノードが送信したメッセージは自ノードのハンドラには届きません。
+type Bus struct {
+	t        Transport
+	node     string
+	logger   *zap.Logger
+	handlers map[string][]Handler
+	shared   map[string]Shared
+	peers    map[string]time.Time
+	mu       sync.RWMutex
+
+	heartbeatInterval time.Duration
+	peerTimeout       time.Duration
+
+	started  bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New イベントバスを生成します
+func New(t Transport, logger *zap.Logger) *Bus {
+	return &Bus{
+		t:                 t,
+		node:              random.AlphaNumeric(16),
+		logger:            logger.Named("bus"),
+		handlers:          map[string][]Handler{},
+		shared:            map[string]Shared{},
+		peers:             map[string]time.Time{},
+		heartbeatInterval: 5 * time.Second,
+		peerTimeout:       20 * time.Second,
+		stop:              make(chan struct{}),
+	}
+}
+
+// NodeID 自ノードのIDを返します
+func (b *Bus) NodeID() string {
+	return b.node
+}
+
+// Peers 生存している他のノードのIDを返します
+func (b *Bus) Peers() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	peers := make([]string, 0, len(b.peers))
+	for node := range b.peers {
+		peers = append(peers, node)
+	}
+	return peers
+}
+
+// Publish 他の全ノードの指定したトピックのハンドラにメッセージを送信します
+func (b *Bus) Publish(topic string, data []byte) error {
+	return b.send(kindMessage, topic, data)
+}
+
+// Subscribe 他のノードから届いた指定したトピックのメッセージのハンドラを登録します
+//
+// Startの前に呼び出す必要があります。
+func (b *Bus) Subscribe(topic string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+}
+
+// Share ノード毎に所有される状態を指定したトピックで共有します
+//
+// 状態の差分はPublishで同じトピックに送信してください。
+// 新たなノードを検出した時や再接続した時には、状態の全体が送信されます。
+// Startの前に呼び出す必要があります。
+func (b *Bus) Share(topic string, s Shared) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.shared[topic] = s
+	b.handlers[topic] = append(b.handlers[topic], s.Apply)
+}
+
+// Start ノード間の通信を開始します
+func (b *Bus) Start() {
+	b.mu.Lock()
+	if b.started {
+		b.mu.Unlock()
+		return
+	}
+	b.started = true
+	b.mu.Unlock()
+
+	b.t.Receive(b.receive, func() {
+		// 切断中に失われた差分を補うため、全ノードと状態を交換する
+		b.sendSnapshots()
+		_ = b.send(kindSync, "", nil)
+	})
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		t := time.NewTicker(b.heartbeatInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				_ = b.send(kindHeartbeat, "", nil)
+				b.expirePeers(time.Now())
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+	b.logger.Info("event bus started", zap.String("node", b.node))
+}
+
+// Shutdown ノード間の通信を終了します
+func (b *Bus) Shutdown() {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+		b.wg.Wait()
+		_ = b.send(kindLeave, "", nil)
+		if err := b.t.Close(); err != nil {
+			b.logger.Warn("failed to close transport", zap.Error(err))
+		}
+	})
+}
+
+func (b *Bus) send(kind, topic string, data []byte) error {
+	msg, _ := json.Marshal(&envelope{Node: b.node, Kind: kind, Topic: topic, Data: data})
+	if err := b.t.Publish(msg); err != nil {
+		b.logger.Warn("failed to publish", zap.Error(err), zap.String("kind", kind), zap.String("topic", topic))
+		return err
+	}
+	return nil
+}
+
+func (b *Bus) sendSnapshots() {
+	b.mu.RLock()
+	shared := make(map[string]Shared, len(b.shared))
+	for topic, s := range b.shared {
+		shared[topic] = s
+	}
+	b.mu.RUnlock()
+
+	for topic, s := range shared {
+		_ = b.send(kindSnapshot, topic, s.Snapshot())
+	}
+}
+
+func (b *Bus) receive(data []byte) {
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		b.logger.Warn("received malformed message", zap.Error(err))
+		return
+	}
+	if e.Node == b.node {
+		return
+	}
+
+	if e.Kind == kindLeave {
+		b.forget(e.Node)
+		return
+	}
+	if b.touchPeer(e.Node, time.Now()) {
+		// 新たなノードに自ノードの状態を知らせる
+		b.sendSnapshots()
+	}
+
+	switch e.Kind {
+	case kindMessage:
+		b.mu.RLock()
+		handlers := b.handlers[e.Topic]
+		b.mu.RUnlock()
+		for _, h := range handlers {
+			h(e.Node, e.Data)
+		}
+	case kindSnapshot:
+		b.mu.RLock()
+		s, ok := b.shared[e.Topic]
+		b.mu.RUnlock()
+		if ok {
+			s.ApplySnapshot(e.Node, e.Data)
+		}
+	case kindSync:
+		b.sendSnapshots()
+	}
+}
+
+// touchPeer ノードの生存を記録し、新たなノードだった場合はtrueを返します
+func (b *Bus) touchPeer(node string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.peers[node]
+	b.peers[node] = now
+	return !ok
+}
+
+func (b *Bus) expirePeers(now time.Time) {
+	b.mu.RLock()
+	var expired []string
+	for node, last := range b.peers {
+		if now.Sub(last) > b.peerTimeout {
+			expired = append(expired, node)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, node := range expired {
+		b.logger.Warn("peer timed out", zap.String("node", node))
+		b.forget(node)
+	}
+}
+
+func (b *Bus) forget(node string) {
+	b.mu.Lock()
+	delete(b.peers, node)
+	shared := make([]Shared, 0, len(b.shared))
+	for _, s := range b.shared {
+		shared = append(shared, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range shared {
+		s.Forget(node)
+	}
+}
@@ -0,0 +1,175 @@
+package bus
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memNetwork テスト用のノード間ネットワーク
+type memNetwork struct {
+	mu    sync.Mutex
+	nodes []*memTransport
+}
+
+func (n *memNetwork) join() *memTransport {
+	t := &memTransport{n: n, ch: make(chan []byte, 100)}
+	n.mu.Lock()
+	n.nodes = append(n.nodes, t)
+	n.mu.Unlock()
+	return t
+}
+
+type memTransport struct {
+	n      *memNetwork
+	ch     chan []byte
+	closed bool
+}
+
+func (t *memTransport) Publish(data []byte) error {
+	t.n.mu.Lock()
+	defer t.n.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	for _, node := range t.n.nodes {
+		if !node.closed {
+			node.ch <- data
+		}
+	}
+	return nil
+}
+
+func (t *memTransport) Receive(handler func(data []byte), connected func()) {
+	connected()
+	go func() {
+		for data := range t.ch {
+			handler(data)
+		}
+	}()
+}
+
+func (t *memTransport) Close() error {
+	t.n.mu.Lock()
+	defer t.n.mu.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.ch)
+	}
+	return nil
+}
+
+// memShared テスト用の共有状態 (ノード毎の文字列集合)
+type memShared struct {
+	mu    sync.Mutex
+	local []string
+	nodes map[string][]string
+}
+
+func (s *memShared) Snapshot() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, _ := json.Marshal(s.local)
+	return b
+}
+
+func (s *memShared) ApplySnapshot(node string, data []byte) {
+	var v []string
+	_ = json.Unmarshal(data, &v)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes[node] = v
+}
+
+func (s *memShared) Apply(node string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes[node] = append(s.nodes[node], string(data))
+}
+
+func (s *memShared) Forget(node string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.nodes, node)
+}
+
+func (s *memShared) get(node string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nodes[node]
+}
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+
+	n := &memNetwork{}
+	b1 := New(n.join(), zap.NewNop())
+	b2 := New(n.join(), zap.NewNop())
+	assert.NotEqual(t, b1.NodeID(), b2.NodeID())
+
+	s1 := &memShared{local: []string{"a"}, nodes: map[string][]string{}}
+	s2 := &memShared{nodes: map[string][]string{}}
+	b1.Share("shared", s1)
+	b2.Share("shared", s2)
+
+	var mu sync.Mutex
+	var received []string
+	b1.Subscribe("topic", func(node string, data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, node+":"+string(data))
+	})
+	b2.Subscribe("topic", func(node string, data []byte) {
+		t.Error("must not receive own messages")
+	})
+
+	b1.Start()
+	b2.Start()
+
+	// b1の状態の全体がb2に共有される
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"a"}, s2.get(b1.NodeID()))
+	}, time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{b1.NodeID()}, b2.Peers())
+	assert.ElementsMatch(t, []string{b2.NodeID()}, b1.Peers())
+
+	// 差分
+	assert.NoError(t, b1.Publish("shared", []byte("b")))
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"a", "b"}, s2.get(b1.NodeID()))
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, b2.Publish("topic", []byte("hello")))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return assert.ObjectsAreEqual([]string{b2.NodeID() + ":hello"}, received)
+	}, time.Second, 10*time.Millisecond)
+
+	// 離脱したノードの状態は破棄される
+	b1.Shutdown()
+	assert.Eventually(t, func() bool {
+		return s2.get(b1.NodeID()) == nil && len(b2.Peers()) == 0
+	}, time.Second, 10*time.Millisecond)
+	b2.Shutdown()
+}
+
+func TestBus_expirePeers(t *testing.T) {
+	t.Parallel()
+
+	s := &memShared{nodes: map[string][]string{"dead": {"a"}, "alive": {"b"}}}
+	b := New(NewLocalTransport(), zap.NewNop())
+	b.Share("shared", s)
+
+	now := time.Now()
+	b.touchPeer("dead", now.Add(-time.Minute))
+	b.touchPeer("alive", now)
+	b.expirePeers(now)
+
+	assert.ElementsMatch(t, []string{"alive"}, b.Peers())
+	assert.Nil(t, s.get("dead"))
+	assert.Equal(t, []string{"b"}, s.get("alive"))
+}
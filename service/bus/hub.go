@@ -0,0 +1,127 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/message"
+	"go.uber.org/zap"
+	"time"
+)
+
+const hubTopic = "hub"
+
+// localTopics 他のノードに複製しないイベント
+//
+// ノード毎の接続状態から導出されるイベントは、共有された状態から各ノードで発行されます。
+var localTopics = map[string]bool{
+	event.WSConnected:              true,
+	event.WSDisconnected:           true,
+	event.SSEConnected:             true,
+	event.SSEDisconnected:          true,
+	event.UserOnline:               true,
+	event.UserOffline:              true,
+	event.ChannelViewersChanged:    true,
+	event.UserWebRTCv3StateChanged: true,
+	hub.AlertTopic:                 true,
+}
+
+func init() {
+	for _, v := range []interface{}{
+		uuid.UUID{},
+		[]uuid.UUID{},
+		time.Time{},
+		map[string]string{},
+		&message.ParseResult{},
+		&model.User{},
+		&model.UserGroup{},
+		&model.Message{},
+		[]*model.Unread{},
+		&model.Channel{},
+		&model.Stamp{},
+		&model.StampPalette{},
+		&model.WebhookBot{},
+		&model.Bot{},
+		model.BotState(0),
+		&model.ClipFolder{},
+		&model.ClipFolderMessage{},
+	} {
+		gob.Register(v)
+	}
+}
+
+type hubMessage struct {
+	Name   string
+	Fields map[string]interface{}
+}
+
+// HubReplicator Hubのイベントをノード間で複製します
+//
+// 他のノードから複製されたイベントにはevent.FieldRemoteが付与されます。
+type HubReplicator struct {
+	bus    *Bus
+	hub    *hub.Hub
+	logger *zap.Logger
+}
+
+// NewHubReplicator Hubのイベントの複製を開始します
+func NewHubReplicator(b *Bus, hub *hub.Hub, logger *zap.Logger) *HubReplicator {
+	r := &HubReplicator{
+		bus:    b,
+		hub:    hub,
+		logger: logger.Named("hub_replicator"),
+	}
+	b.Subscribe(hubTopic, r.receive)
+	// ワイルドカードは1階層にしかマッチしないので、階層毎に購読する
+	sub := hub.Subscribe(1000, "*", "*.*", "*.*.*", "*.*.*.*")
+	go func() {
+		for msg := range sub.Receiver {
+			r.send(msg)
+		}
+	}()
+	return r
+}
+
+func (r *HubReplicator) send(msg hub.Message) {
+	if localTopics[msg.Name] || event.IsRemote(msg) {
+		return
+	}
+	data, err := encodeHubMessage(msg)
+	if err != nil {
+		r.logger.Warn("failed to encode event", zap.Error(err), zap.String("topic", msg.Name))
+		return
+	}
+	_ = r.bus.Publish(hubTopic, data)
+}
+
+func (r *HubReplicator) receive(_ string, data []byte) {
+	msg, err := decodeHubMessage(data)
+	if err != nil {
+		r.logger.Warn("failed to decode event", zap.Error(err))
+		return
+	}
+	msg.Fields[event.FieldRemote] = true
+	r.hub.Publish(msg)
+}
+
+func encodeHubMessage(msg hub.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&hubMessage{Name: msg.Name, Fields: msg.Fields}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeHubMessage(data []byte) (hub.Message, error) {
+	var m hubMessage
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&m); err != nil {
+		return hub.Message{}, err
+	}
+	if m.Fields == nil {
+		m.Fields = map[string]interface{}{}
+	}
+	return hub.Message{Name: m.Name, Fields: m.Fields}, nil
+}
@@ -0,0 +1,99 @@
+package bus
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/message"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestEncodeHubMessage(t *testing.T) {
+	t.Parallel()
+
+	m := &model.Message{ID: uuid.Must(uuid.NewV4()), Text: "test", CreatedAt: time.Now().Truncate(time.Second)}
+	msg := hub.Message{
+		Name: event.MessageCreated,
+		Fields: hub.Fields{
+			"message_id":   m.ID,
+			"message":      m,
+			"parse_result": &message.ParseResult{Mentions: []uuid.UUID{uuid.Must(uuid.NewV4())}},
+			"unreads":      []*model.Unread{{MessageID: m.ID}},
+			"noticeable":   true,
+			"count":        3,
+			"topic":        "topic",
+		},
+	}
+	data, err := encodeHubMessage(msg)
+	require.NoError(t, err)
+	decoded, err := decodeHubMessage(data)
+	require.NoError(t, err)
+	assert.Equal(t, msg.Name, decoded.Name)
+	assert.Equal(t, m.ID, decoded.Fields["message_id"])
+	if assert.IsType(t, &model.Message{}, decoded.Fields["message"]) {
+		assert.Equal(t, m.Text, decoded.Fields["message"].(*model.Message).Text)
+		assert.True(t, m.CreatedAt.Equal(decoded.Fields["message"].(*model.Message).CreatedAt))
+	}
+	assert.Equal(t, msg.Fields["parse_result"], decoded.Fields["parse_result"])
+	assert.Equal(t, msg.Fields["unreads"], decoded.Fields["unreads"])
+	assert.Equal(t, true, decoded.Fields["noticeable"])
+	assert.Equal(t, 3, decoded.Fields["count"])
+	assert.Equal(t, "topic", decoded.Fields["topic"])
+
+	_, err = encodeHubMessage(hub.Message{Name: "unknown", Fields: hub.Fields{"f": func() {}}})
+	assert.Error(t, err)
+}
+
+func TestHubReplicator(t *testing.T) {
+	t.Parallel()
+
+	n := &memNetwork{}
+	b1, b2 := New(n.join(), zap.NewNop()), New(n.join(), zap.NewNop())
+	h1, h2 := hub.New(), hub.New()
+	NewHubReplicator(b1, h1, zap.NewNop())
+	NewHubReplicator(b2, h2, zap.NewNop())
+	b1.Start()
+	b2.Start()
+	defer b1.Shutdown()
+	defer b2.Shutdown()
+
+	sub1 := h1.Subscribe(10, event.ChannelCreated, event.WSConnected)
+	sub2 := h2.Subscribe(10, event.ChannelCreated, event.WSConnected)
+	defer h1.Unsubscribe(sub1)
+	defer h2.Unsubscribe(sub2)
+
+	// ノード固有のイベントは複製されない
+	h1.Publish(hub.Message{Name: event.WSConnected, Fields: hub.Fields{"user_id": uuid.Must(uuid.NewV4())}})
+	<-sub1.Receiver
+
+	id := uuid.Must(uuid.NewV4())
+	h1.Publish(hub.Message{Name: event.ChannelCreated, Fields: hub.Fields{"channel_id": id, "channel": &model.Channel{ID: id, Name: "test"}}})
+	<-sub1.Receiver
+
+	select {
+	case msg := <-sub2.Receiver:
+		assert.Equal(t, event.ChannelCreated, msg.Name)
+		assert.True(t, event.IsRemote(msg))
+		assert.Equal(t, id, msg.Fields["channel_id"])
+		assert.Equal(t, "test", msg.Fields["channel"].(*model.Channel).Name)
+	case <-time.After(time.Second):
+		t.Fatal("event not replicated")
+	}
+
+	// 複製されたイベントは送り返されない
+	select {
+	case msg := <-sub1.Receiver:
+		t.Fatalf("unexpected event: %s", msg.Name)
+	case <-time.After(100 * time.Millisecond):
+	}
+	select {
+	case msg := <-sub2.Receiver:
+		t.Fatalf("unexpected event: %s", msg.Name)
+	default:
+	}
+}
@@ -0,0 +1,21 @@
+package bus
+
+// localTransport 単一ノード用のTransport
+type localTransport struct{}
+
+// NewLocalTransport 単一ノードで動作させる場合のTransportを生成します
+//
+// 他のノードが存在しないので、何も配送しません。
+func NewLocalTransport() Transport {
+	return localTransport{}
+}
+
+func (localTransport) Publish([]byte) error {
+	return nil
+}
+
+func (localTransport) Receive(func([]byte), func()) {}
+
+func (localTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,302 @@
+package bus
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	redisDialTimeout  = 5 * time.Second
+	redisWriteTimeout = 5 * time.Second
+	redisMaxBackoff   = 10 * time.Second
+)
+
+// RedisConfig Redis Pub/Subを用いたTransportの設定
+type RedisConfig struct {
+	// Addr Redisサーバーのアドレス (host:port)
+	Addr string
+	// Password Redisサーバーのパスワード
+	Password string
+	// Channel 使用するPub/Subチャンネル名
+	Channel string
+}
+
+// redisTransport Redis Pub/Subを用いたTransport
+type redisTransport struct {
+	c      RedisConfig
+	logger *zap.Logger
+
+	pubMu   sync.Mutex
+	pubConn *redisConn
+
+	subMu   sync.Mutex
+	subConn *redisConn
+
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRedisTransport Redis Pub/Subを用いたTransportを生成します
+func NewRedisTransport(c RedisConfig, logger *zap.Logger) (Transport, error) {
+	if len(c.Addr) == 0 {
+		return nil, errors.New("redis address is required")
+	}
+	if len(c.Channel) == 0 {
+		c.Channel = "traq"
+	}
+	t := &redisTransport{
+		c:      c,
+		logger: logger.Named("bus.redis"),
+		stop:   make(chan struct{}),
+	}
+	// 起動時に接続できるか確認する
+	conn, err := t.dial()
+	if err != nil {
+		return nil, err
+	}
+	t.pubConn = conn
+	return t, nil
+}
+
+func (t *redisTransport) dial() (*redisConn, error) {
+	nc, err := net.DialTimeout("tcp", t.c.Addr, redisDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	if len(t.c.Password) > 0 {
+		if _, err := conn.do("AUTH", []byte(t.c.Password)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (t *redisTransport) Publish(data []byte) error {
+	t.pubMu.Lock()
+	defer t.pubMu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if t.pubConn == nil {
+			if t.pubConn, err = t.dial(); err != nil {
+				continue
+			}
+		}
+		if _, err = t.pubConn.do("PUBLISH", []byte(t.c.Channel), data); err == nil {
+			return nil
+		}
+		// 接続が切れている可能性があるので、接続し直してもう一度試す
+		_ = t.pubConn.Close()
+		t.pubConn = nil
+	}
+	return err
+}
+
+func (t *redisTransport) Receive(handler func(data []byte), connected func()) {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		backoff := 100 * time.Millisecond
+		for {
+			err := t.subscribe(handler, func() {
+				backoff = 100 * time.Millisecond
+				connected()
+			})
+			select {
+			case <-t.stop:
+				return
+			default:
+			}
+			t.logger.Warn("subscription lost, reconnecting", zap.Error(err), zap.Duration("backoff", backoff))
+			select {
+			case <-time.After(backoff):
+			case <-t.stop:
+				return
+			}
+			if backoff *= 2; backoff > redisMaxBackoff {
+				backoff = redisMaxBackoff
+			}
+		}
+	}()
+}
+
+func (t *redisTransport) subscribe(handler func(data []byte), connected func()) error {
+	conn, err := t.dial()
+	if err != nil {
+		return err
+	}
+	t.subMu.Lock()
+	if t.closed {
+		t.subMu.Unlock()
+		_ = conn.Close()
+		return ErrClosed
+	}
+	t.subConn = conn
+	t.subMu.Unlock()
+	defer func() {
+		t.subMu.Lock()
+		t.subConn = nil
+		t.subMu.Unlock()
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.do("SUBSCRIBE", []byte(t.c.Channel)); err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			return err
+		}
+		arr, ok := reply.([]interface{})
+		if !ok || len(arr) != 3 {
+			continue
+		}
+		if kind, _ := arr[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		if payload, ok := arr[2].([]byte); ok {
+			handler(payload)
+		}
+	}
+}
+
+func (t *redisTransport) Close() error {
+	t.pubMu.Lock()
+	t.subMu.Lock()
+	if t.closed {
+		t.subMu.Unlock()
+		t.pubMu.Unlock()
+		return nil
+	}
+	t.closed = true
+	close(t.stop)
+	if t.subConn != nil {
+		_ = t.subConn.Close()
+	}
+	var err error
+	if t.pubConn != nil {
+		err = t.pubConn.Close()
+		t.pubConn = nil
+	}
+	t.subMu.Unlock()
+	t.pubMu.Unlock()
+
+	t.wg.Wait()
+	return err
+}
+
+// redisError Redisサーバーが返したエラー
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisConn RESPで通信するRedisサーバーとの接続
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// do コマンドを送信し、その応答を返します
+func (c *redisConn) do(cmd string, args ...[]byte) (interface{}, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)+1), 10)
+	buf = append(buf, "\r\n"...)
+	buf = appendBulk(buf, []byte(cmd))
+	for _, arg := range args {
+		buf = appendBulk(buf, arg)
+	}
+
+	if err := c.SetWriteDeadline(time.Now().Add(redisWriteTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(redisError); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// read 応答を1つ読み込みます
+//
+// 文字列はすべて[]byte、整数はint64、配列は[]interface{}、エラーはredisErrorで返します。
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply")
+	}
+	body := string(line[1 : len(line)-2])
+
+	switch line[0] {
+	case '+':
+		return []byte(body), nil
+	case '-':
+		return redisError(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]interface{}, n)
+		for i := range arr {
+			if arr[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+}
+
+func appendBulk(buf, b []byte) []byte {
+	buf = append(buf, '$')
+	buf = strconv.AppendInt(buf, int64(len(b)), 10)
+	buf = append(buf, "\r\n"...)
+	buf = append(buf, b...)
+	return append(buf, "\r\n"...)
+}
@@ -0,0 +1,239 @@
+package bus
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis Pub/Subのみに対応したテスト用のRedisサーバー
+type fakeRedis struct {
+	l        net.Listener
+	password string
+
+	mu          sync.Mutex
+	conns       map[net.Conn]bool
+	subscribers map[string]map[net.Conn]bool
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeRedis{
+		l:           l,
+		password:    password,
+		conns:       map[net.Conn]bool{},
+		subscribers: map[string]map[net.Conn]bool{},
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns[conn] = true
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = l.Close()
+		s.dropAll()
+	})
+	return s
+}
+
+func (s *fakeRedis) addr() string {
+	return s.l.Addr().String()
+}
+
+// dropAll 全ての接続を切断します
+func (s *fakeRedis) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+func (s *fakeRedis) subscriberCount(channel string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers[channel])
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		for _, subs := range s.subscribers {
+			delete(subs, conn)
+		}
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	rc := &redisConn{Conn: conn, r: bufio.NewReader(conn)}
+	authed := len(s.password) == 0
+	for {
+		req, err := rc.read()
+		if err != nil {
+			return
+		}
+		args, _ := req.([]interface{})
+		if len(args) == 0 {
+			return
+		}
+		cmd, _ := args[0].([]byte)
+		arg := func(i int) []byte {
+			b, _ := args[i].([]byte)
+			return b
+		}
+
+		s.mu.Lock()
+		switch {
+		case string(cmd) == "AUTH":
+			if len(args) == 2 && string(arg(1)) == s.password {
+				authed = true
+				_, _ = conn.Write([]byte("+OK\r\n"))
+			} else {
+				_, _ = conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+			}
+		case !authed:
+			_, _ = conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+		case string(cmd) == "SUBSCRIBE" && len(args) == 2:
+			ch := string(arg(1))
+			if s.subscribers[ch] == nil {
+				s.subscribers[ch] = map[net.Conn]bool{}
+			}
+			s.subscribers[ch][conn] = true
+			_, _ = conn.Write(appendArray(nil, []byte("subscribe"), []byte(ch), nil))
+		case string(cmd) == "PUBLISH" && len(args) == 3:
+			ch := string(arg(1))
+			for sub := range s.subscribers[ch] {
+				_, _ = sub.Write(appendArray(nil, []byte("message"), []byte(ch), arg(2)))
+			}
+			_, _ = conn.Write([]byte(":" + strconv.Itoa(len(s.subscribers[ch])) + "\r\n"))
+		default:
+			_, _ = conn.Write([]byte("-ERR unknown command\r\n"))
+		}
+		s.mu.Unlock()
+	}
+}
+
+// appendArray 要素がnilの場合は整数の1として配列を書き込みます
+func appendArray(buf []byte, elems ...[]byte) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(elems)), 10)
+	buf = append(buf, "\r\n"...)
+	for _, e := range elems {
+		if e == nil {
+			buf = append(buf, ":1\r\n"...)
+		} else {
+			buf = appendBulk(buf, e)
+		}
+	}
+	return buf
+}
+
+func testRedisTransport(t *testing.T, c RedisConfig, drop func()) {
+	t1, err := NewRedisTransport(c, zap.NewNop())
+	require.NoError(t, err)
+	t2, err := NewRedisTransport(c, zap.NewNop())
+	require.NoError(t, err)
+	defer t1.Close()
+	defer t2.Close()
+
+	received := make(chan []byte, 10)
+	connected := make(chan struct{}, 10)
+	t1.Receive(func([]byte) {}, func() {})
+	t2.Receive(func(data []byte) { received <- data }, func() { connected <- struct{}{} })
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not connected")
+	}
+
+	payload := []byte("binary\r\n\x00payload")
+	require.NoError(t, t1.Publish(payload))
+	select {
+	case data := <-received:
+		assert.Equal(t, payload, data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	if drop == nil {
+		return
+	}
+	// 接続が切れても再接続して配送を続ける
+	drop()
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not reconnected")
+	}
+	require.NoError(t, t1.Publish([]byte("after reconnect")))
+	select {
+	case data := <-received:
+		assert.Equal(t, []byte("after reconnect"), data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received after reconnect")
+	}
+}
+
+func TestRedisTransport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fake server", func(t *testing.T) {
+		t.Parallel()
+
+		s := newFakeRedis(t, "secret")
+		testRedisTransport(t, RedisConfig{Addr: s.addr(), Password: "secret", Channel: "test"}, s.dropAll)
+		assert.Eventually(t, func() bool { return s.subscriberCount("test") == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		t.Parallel()
+
+		s := newFakeRedis(t, "secret")
+		_, err := NewRedisTransport(RedisConfig{Addr: s.addr(), Password: "wrong"}, zap.NewNop())
+		assert.Error(t, err)
+	})
+
+	t.Run("no address", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewRedisTransport(RedisConfig{}, zap.NewNop())
+		assert.Error(t, err)
+	})
+
+	t.Run("publish after close", func(t *testing.T) {
+		t.Parallel()
+
+		s := newFakeRedis(t, "")
+		tr, err := NewRedisTransport(RedisConfig{Addr: s.addr()}, zap.NewNop())
+		require.NoError(t, err)
+		require.NoError(t, tr.Close())
+		assert.Equal(t, ErrClosed, tr.Publish([]byte("a")))
+	})
+
+	t.Run("real server", func(t *testing.T) {
+		t.Parallel()
+
+		addr := os.Getenv("TRAQ_EVENTBUS_REDIS_ADDR")
+		if len(addr) == 0 {
+			t.Skip("TRAQ_EVENTBUS_REDIS_ADDR is not set")
+		}
+		testRedisTransport(t, RedisConfig{Addr: addr, Channel: "traq_test_" + strconv.FormatInt(time.Now().UnixNano(), 10)}, nil)
+	})
+}
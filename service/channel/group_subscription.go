@@ -0,0 +1,41 @@
+package channel
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/repository"
+	"go.uber.org/zap"
+)
+
+// GroupSubscriptionSyncer グループによるチャンネル購読の同期器
+//
+// ユーザーグループのメンバーの追加・削除イベントを受け取り、
+// グループによるチャンネル購読設定に従ってメンバーのチャンネル購読を更新します。
+type GroupSubscriptionSyncer struct {
+	repo   repository.ChannelRepository
+	logger *zap.Logger
+}
+
+// NewGroupSubscriptionSyncer グループによるチャンネル購読の同期器を生成します
+func NewGroupSubscriptionSyncer(repo repository.ChannelRepository, hub *hub.Hub, logger *zap.Logger) *GroupSubscriptionSyncer {
+	syncer := &GroupSubscriptionSyncer{
+		repo:   repo,
+		logger: logger.Named("group_subscription_syncer"),
+	}
+	go func() {
+		for e := range hub.Subscribe(100, event.UserGroupMemberAdded, event.UserGroupMemberRemoved).Receiver {
+			if event.IsRemote(e) {
+				continue
+			}
+			syncer.sync(e.Fields["user_id"].(uuid.UUID), e.Fields["group_id"].(uuid.UUID))
+		}
+	}()
+	return syncer
+}
+
+func (s *GroupSubscriptionSyncer) sync(userID, groupID uuid.UUID) {
+	if err := s.repo.SyncGroupChannelSubscriptions(userID, groupID); err != nil {
+		s.logger.Error("failed to SyncGroupChannelSubscriptions", zap.Error(err), zap.Stringer("userID", userID), zap.Stringer("groupID", groupID))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/set"
 )
 
 var (
@@ -17,19 +18,83 @@ var (
 	ErrChannelArchived      = errors.New("channel archived")
 	ErrForcedNotification   = errors.New("forced notification channel")
 	ErrInvalidChannel       = errors.New("invalid channel")
+	ErrUserGroupNotFound    = errors.New("user group not found")
+	ErrChannelHasChildren   = errors.New("channel has children")
+	ErrInvalidGroupMembers  = errors.New("invalid group dm members")
+	ErrInvalidTemplate      = errors.New("invalid channel template")
 )
 
+// GroupDMMaxMembers グループDMの最大メンバー数
+const GroupDMMaxMembers = 20
+
+// ChannelMove チャンネルの移動によるパスの変化
+type ChannelMove struct {
+	ID     uuid.UUID
+	Before string
+	After  string
+}
+
 type Manager interface {
 	GetChannel(id uuid.UUID) (*model.Channel, error)
 	CreatePublicChannel(name string, parent, creatorID uuid.UUID) (*model.Channel, error)
 	UpdateChannel(id uuid.UUID, args repository.UpdateChannelArgs) error
+	// ArchiveChannel 公開チャンネルをアーカイブします
+	//
+	// cascadeがtrueの場合、子孫チャンネルも全てアーカイブします
+	ArchiveChannel(id uuid.UUID, cascade bool, updaterID uuid.UUID) error
+	// UnarchiveChannel 公開チャンネルのアーカイブを解除します
+	//
+	// cascadeがtrueの場合、子孫チャンネルのアーカイブも全て解除します。
+	// 親チャンネルがアーカイブされている場合は解除できません
+	UnarchiveChannel(id uuid.UUID, cascade bool, updaterID uuid.UUID) error
+	// MergeChannel 公開チャンネルを別の公開チャンネルに統合します
+	//
+	// srcIDのチャンネルのメッセージ・購読設定・スターなどはdstIDのチャンネルに移動し、srcIDのチャンネルはアーカイブされます。
+	// 子チャンネルを持つチャンネルは統合できません
+	MergeChannel(srcID, dstID, updaterID uuid.UUID) error
+	// MoveChannel 公開チャンネルを子孫チャンネルごと指定した親チャンネルの下に移動します
+	//
+	// 移動によってパスが変化するチャンネルの一覧を返します。
+	// dryRunがtrueの場合、検証のみを行い実際には移動しません
+	MoveChannel(id, parent uuid.UUID, dryRun bool, updaterID uuid.UUID) ([]*ChannelMove, error)
+	// InstantiateChannelTemplate チャンネルテンプレートの定義に従い、公開チャンネルのツリーを作成します
+	//
+	// 定義のルートノードはparentのチャンネルの下にnameという名前で作成されます。
+	// 作成したチャンネルを親が子より先になる順で返します
+	InstantiateChannelTemplate(definition model.ChannelTemplateNode, parent uuid.UUID, name string, creatorID uuid.UUID) ([]*model.Channel, error)
 	PublicChannelTree() Tree
+	// ReloadPublicChannelTree 公開チャンネルツリーをDBから再構築します
+	ReloadPublicChannelTree() error
 
 	ChangeChannelSubscriptions(channelID uuid.UUID, subscriptions map[uuid.UUID]model.ChannelSubscribeLevel, keepOffLevel bool, updaterID uuid.UUID) error
+	SetChannelGroupSubscription(channelID, groupID uuid.UUID, level model.ChannelSubscribeLevel, updaterID uuid.UUID) error
 
 	GetDMChannel(user1, user2 uuid.UUID) (*model.Channel, error)
 	GetDMChannelMembers(id uuid.UUID) ([]uuid.UUID, error)
 	GetDMChannelMapping(userID uuid.UUID) (map[uuid.UUID]uuid.UUID, error)
+	// GetGroupDMChannel 指定したユーザーとmembersで構成されるグループDMチャンネルを取得します
+	//
+	// 存在しない場合は作成します。
+	// メンバー数(userIDを含む)は3人以上GroupDMMaxMembers人以下である必要があります
+	GetGroupDMChannel(members set.UUID, userID uuid.UUID) (*model.Channel, error)
+	// GetGroupDMChannelMapping 指定したユーザーが参加しているグループDMチャンネルと、そのユーザー以外のメンバーのマッピングを取得します
+	GetGroupDMChannelMapping(userID uuid.UUID) (map[uuid.UUID][]uuid.UUID, error)
+	// ConvertGroupDMChannel グループDMチャンネルをプライベートチャンネルに変換します
+	//
+	// メッセージ・メンバーは引き継がれ、変換したユーザーがチャンネルの作成者になります
+	ConvertGroupDMChannel(id uuid.UUID, name string, updaterID uuid.UUID) (*model.Channel, error)
+
+	// CreatePrivateChannel 複数人のメンバーで構成されるプライベートチャンネルを作成します
+	//
+	// 作成者は自動的にメンバーになります。
+	// チャンネル名は他のチャンネルと重複できます
+	CreatePrivateChannel(name string, members set.UUID, creatorID uuid.UUID) (*model.Channel, error)
+	// GetPrivateChannels 指定したユーザーがメンバーであるプライベートチャンネルを取得します
+	GetPrivateChannels(userID uuid.UUID) ([]*model.Channel, error)
+	// AddPrivateChannelMembers プライベートチャンネルにメンバーを追加します
+	AddPrivateChannelMembers(id uuid.UUID, members set.UUID, updaterID uuid.UUID) error
+	// RemovePrivateChannelMembers プライベートチャンネルからメンバーを削除します
+	RemovePrivateChannelMembers(id uuid.UUID, members set.UUID, updaterID uuid.UUID) error
 
 	IsChannelAccessibleToUser(userID, channelID uuid.UUID) (bool, error)
 	IsPublicChannel(id uuid.UUID) bool
@@ -404,6 +404,16 @@ func TestManagerImpl_UpdateChannel(t *testing.T) {
 		assert.EqualError(t, err, ErrTooDeepChannel.Error())
 	})
 
+	t.Run("ErrTooDeepChannel (boundary)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		err := cm.UpdateChannel(cABC, repository.UpdateChannelArgs{Parent: optional.UUIDFrom(cEFG)})
+		assert.EqualError(t, err, ErrTooDeepChannel.Error())
+	})
+
 	t.Run("repository error", func(t *testing.T) {
 		t.Parallel()
 		ctrl := gomock.NewController(t)
@@ -554,6 +564,400 @@ func TestManagerImpl_UpdateChannel(t *testing.T) {
 	})
 }
 
+func expectSetChannelArchived(t *testing.T, repo *mock_repository.MockChannelRepository, cm *managerImpl, id uuid.UUID, archived, cascade bool, updaterID uuid.UUID) {
+	t.Helper()
+	ch, err := cm.PublicChannelTree().GetModel(id)
+	require.NoError(t, err)
+	new := *ch
+	new.IsVisible = !archived
+	new.UpdaterID = updaterID
+	new.UpdatedAt = time.Now()
+
+	eventType := model.ChannelEventArchived
+	if !archived {
+		eventType = model.ChannelEventUnarchived
+	}
+	repo.EXPECT().
+		UpdateChannel(id, repository.UpdateChannelArgs{UpdaterID: updaterID, Visibility: optional.BoolFrom(!archived)}).
+		Return(&new, nil).
+		Times(1)
+	repo.EXPECT().
+		RecordChannelEvent(id, eventType, model.ChannelEventDetail{"userId": updaterID, "cascade": cascade}, gomock.Any()).
+		Return(nil).
+		Times(1)
+}
+
+func TestManagerImpl_ArchiveChannel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		repo.EXPECT().
+			GetChannel(cNotFound).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+
+		assert.EqualError(t, cm.ArchiveChannel(cNotFound, false, uuid.Must(uuid.NewV4())), ErrChannelNotFound.Error())
+	})
+
+	t.Run("dm channel", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		id := uuid.Must(uuid.NewV4())
+
+		repo.EXPECT().
+			GetChannel(id).
+			Return(&model.Channel{ID: id, ParentID: dmChannelRootUUID, IsVisible: true}, nil).
+			Times(1)
+
+		assert.EqualError(t, cm.ArchiveChannel(id, false, uuid.Must(uuid.NewV4())), ErrInvalidChannel.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+
+		expectSetChannelArchived(t, repo, cm, cABC, true, false, userID)
+
+		err := cm.ArchiveChannel(cABC, false, userID)
+		cm.P.Wait()
+		if assert.NoError(t, err) {
+			assert.True(t, cm.T.IsArchivedChannel(cABC))
+			assert.False(t, cm.T.IsArchivedChannel(cABCD))
+			assert.False(t, cm.T.IsArchivedChannel(cABCE))
+		}
+	})
+
+	t.Run("success (cascade)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+
+		expectSetChannelArchived(t, repo, cm, cAB, true, false, userID)
+		for _, id := range []uuid.UUID{cABC, cABCD, cABCE, cABF, cABFA} {
+			expectSetChannelArchived(t, repo, cm, id, true, true, userID)
+		}
+
+		err := cm.ArchiveChannel(cAB, true, userID)
+		cm.P.Wait()
+		if assert.NoError(t, err) {
+			for _, id := range []uuid.UUID{cAB, cABC, cABCD, cABCE, cABF, cABFA, cABB, cABBC} {
+				assert.True(t, cm.T.IsArchivedChannel(id))
+			}
+			assert.False(t, cm.T.IsArchivedChannel(cA))
+			assert.False(t, cm.T.IsArchivedChannel(cAD))
+		}
+	})
+}
+
+func TestManagerImpl_UnarchiveChannel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parent archived", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		assert.EqualError(t, cm.UnarchiveChannel(cABBC, false, uuid.Must(uuid.NewV4())), ErrChannelArchived.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+
+		expectSetChannelArchived(t, repo, cm, cABB, false, false, userID)
+
+		err := cm.UnarchiveChannel(cABB, false, userID)
+		cm.P.Wait()
+		if assert.NoError(t, err) {
+			assert.False(t, cm.T.IsArchivedChannel(cABB))
+			assert.True(t, cm.T.IsArchivedChannel(cABBC))
+		}
+	})
+
+	t.Run("success (cascade)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+
+		expectSetChannelArchived(t, repo, cm, cABB, false, false, userID)
+		expectSetChannelArchived(t, repo, cm, cABBC, false, true, userID)
+
+		err := cm.UnarchiveChannel(cABB, true, userID)
+		cm.P.Wait()
+		if assert.NoError(t, err) {
+			assert.False(t, cm.T.IsArchivedChannel(cABB))
+			assert.False(t, cm.T.IsArchivedChannel(cABBC))
+		}
+	})
+}
+
+func TestManagerImpl_MergeChannel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("same channel", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		assert.EqualError(t, cm.MergeChannel(cABCD, cABCD, uuid.Must(uuid.NewV4())), ErrInvalidChannel.Error())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		assert.EqualError(t, cm.MergeChannel(cABCD, cNotFound, uuid.Must(uuid.NewV4())), ErrInvalidChannel.Error())
+	})
+
+	t.Run("has children", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		assert.EqualError(t, cm.MergeChannel(cAB, cAD, uuid.Must(uuid.NewV4())), ErrChannelHasChildren.Error())
+	})
+
+	t.Run("destination archived", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		assert.EqualError(t, cm.MergeChannel(cAD, cABBC, uuid.Must(uuid.NewV4())), ErrChannelArchived.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+
+		ch, err := cm.PublicChannelTree().GetModel(cABCD)
+		require.NoError(t, err)
+		merged := *ch
+		merged.IsVisible = false
+		merged.UpdaterID = userID
+		merged.UpdatedAt = time.Now()
+
+		repo.EXPECT().
+			MergeChannel(cABCD, cAD, userID).
+			Return(nil).
+			Times(1)
+		repo.EXPECT().
+			GetChannel(cABCD).
+			Return(&merged, nil).
+			Times(1)
+		repo.EXPECT().
+			RecordChannelEvent(cABCD, model.ChannelEventMergedInto, model.ChannelEventDetail{"userId": userID, "channelId": cAD}, gomock.Any()).
+			Return(nil).
+			Times(1)
+		repo.EXPECT().
+			RecordChannelEvent(cAD, model.ChannelEventChannelMerged, model.ChannelEventDetail{"userId": userID, "channelId": cABCD}, gomock.Any()).
+			Return(nil).
+			Times(1)
+
+		err = cm.MergeChannel(cABCD, cAD, userID)
+		cm.P.Wait()
+		if assert.NoError(t, err) {
+			assert.True(t, cm.T.IsArchivedChannel(cABCD))
+			assert.False(t, cm.T.IsArchivedChannel(cAD))
+		}
+	})
+}
+
+func TestManagerImpl_MoveChannel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.MoveChannel(cNotFound, cA, false, uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidChannel.Error())
+	})
+
+	t.Run("ErrInvalidParentChannel", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.MoveChannel(cABC, cNotFound, false, uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidParentChannel.Error())
+	})
+
+	t.Run("ErrTooDeepChannel (loop)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.MoveChannel(cAB, cABCD, true, uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrTooDeepChannel.Error())
+	})
+
+	t.Run("ErrTooDeepChannel (limit exceeded)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.MoveChannel(cA, cEF, true, uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrTooDeepChannel.Error())
+	})
+
+	t.Run("depth boundary", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		// 移動後の深さがMaxChannelDepthと等しい場合は移動できる
+		_, err := cm.MoveChannel(cABC, cEF, true, uuid.Must(uuid.NewV4()))
+		assert.NoError(t, err)
+		_, err = cm.MoveChannel(cABC, cEFG, true, uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrTooDeepChannel.Error())
+	})
+
+	t.Run("ErrChannelNameConflicts", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.MoveChannel(cABCD, cA, true, uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrChannelNameConflicts.Error())
+	})
+
+	t.Run("same parent", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		moves, err := cm.MoveChannel(cABC, cAB, false, uuid.Must(uuid.NewV4()))
+		if assert.NoError(t, err) {
+			assert.Empty(t, moves)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		moves, err := cm.MoveChannel(cABC, cAD, true, uuid.Must(uuid.NewV4()))
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, moves, []*ChannelMove{
+				{ID: cABC, Before: "a/b/c", After: "a/d/c"},
+				{ID: cABCD, Before: "a/b/c/d", After: "a/d/c/d"},
+				{ID: cABCE, Before: "a/b/c/e", After: "a/d/c/e"},
+			})
+			assert.Equal(t, "a/b/c", cm.T.GetChannelPath(cABC))
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+
+		ch, err := cm.PublicChannelTree().GetModel(cABC)
+		require.NoError(t, err)
+		moved := *ch
+		moved.ParentID = uuid.Nil
+		moved.UpdaterID = userID
+		moved.UpdatedAt = time.Now()
+
+		repo.EXPECT().
+			UpdateChannel(cABC, repository.UpdateChannelArgs{UpdaterID: userID, Parent: optional.UUIDFrom(uuid.Nil)}).
+			Return(&moved, nil).
+			Times(1)
+		repo.EXPECT().
+			RecordChannelEvent(cABC, model.ChannelEventParentChanged, model.ChannelEventDetail{"userId": userID, "before": cAB, "after": uuid.Nil}, gomock.Any()).
+			Return(nil).
+			Times(1)
+
+		moves, err := cm.MoveChannel(cABC, uuid.Nil, false, userID)
+		cm.P.Wait()
+		if assert.NoError(t, err) {
+			assert.Len(t, moves, 3)
+			assert.Equal(t, "c", cm.T.GetChannelPath(cABC))
+			assert.Equal(t, "c/d", cm.T.GetChannelPath(cABCD))
+			assert.Equal(t, "c/e", cm.T.GetChannelPath(cABCE))
+		}
+	})
+}
+
+func TestManagerImpl_ReloadPublicChannelTree(t *testing.T) {
+	t.Parallel()
+
+	t.Run("repository error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		repo.EXPECT().
+			GetPublicChannels().
+			Return(nil, errors.New("mock error")).
+			Times(1)
+
+		assert.Error(t, cm.ReloadPublicChannelTree())
+		assert.True(t, cm.PublicChannelTree().IsChannelPresent(cA))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		cX := uuid.Must(uuid.NewV4())
+		repo.EXPECT().
+			GetPublicChannels().
+			Return([]*model.Channel{
+				{ID: cX, Name: "x", ParentID: uuid.Nil, IsPublic: true, IsVisible: true},
+			}, nil).
+			Times(1)
+
+		if assert.NoError(t, cm.ReloadPublicChannelTree()) {
+			tree := cm.PublicChannelTree()
+			assert.False(t, tree.IsChannelPresent(cA))
+			assert.True(t, tree.IsChannelPresent(cX))
+			assert.Equal(t, "x", tree.GetChannelPath(cX))
+		}
+	})
+}
+
 func TestManagerImpl_ChangeChannelSubscriptions(t *testing.T) {
 	t.Parallel()
 
@@ -637,11 +1041,81 @@ func TestManagerImpl_ChangeChannelSubscriptions(t *testing.T) {
 					Return(nil).
 					Times(1)
 
-				err := cm.ChangeChannelSubscriptions(c.ID, c.Subscriptions, false, uid1)
-				cm.P.Wait()
-				assert.NoError(t, err)
-			})
-		}
+				err := cm.ChangeChannelSubscriptions(c.ID, c.Subscriptions, false, uid1)
+				cm.P.Wait()
+				assert.NoError(t, err)
+			})
+		}
+	})
+}
+
+func TestManagerImpl_SetChannelGroupSubscription(t *testing.T) {
+	t.Parallel()
+
+	uid1 := uuid.NewV3(uuid.Nil, "u1")
+	uid2 := uuid.NewV3(uuid.Nil, "u2")
+	gid := uuid.NewV3(uuid.Nil, "g1")
+
+	t.Run("ErrInvalidChannel", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		err := cm.SetChannelGroupSubscription(cNotFound, gid, model.ChannelSubscribeLevelMark, uid1)
+		assert.EqualError(t, err, ErrInvalidChannel.Error())
+	})
+
+	t.Run("ErrForcedNotification", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		err := cm.SetChannelGroupSubscription(cE, gid, model.ChannelSubscribeLevelMark, uid1)
+		assert.EqualError(t, err, ErrForcedNotification.Error())
+	})
+
+	t.Run("ErrUserGroupNotFound", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		repo.EXPECT().
+			SetChannelGroupSubscription(cA, gid, model.ChannelSubscribeLevelMark).
+			Return(nil, nil, repository.ErrNotFound).
+			Times(1)
+
+		err := cm.SetChannelGroupSubscription(cA, gid, model.ChannelSubscribeLevelMark, uid1)
+		assert.EqualError(t, err, ErrUserGroupNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		on := []uuid.UUID{uid1}
+		off := []uuid.UUID{uid2}
+		repo.EXPECT().
+			SetChannelGroupSubscription(cAB, gid, model.ChannelSubscribeLevelMarkAndNotify).
+			Return(on, off, nil).
+			Times(1)
+		repo.EXPECT().
+			RecordChannelEvent(cAB, model.ChannelEventSubscribersChanged, model.ChannelEventDetail{
+				"userId":  uid1,
+				"groupId": gid,
+				"on":      on,
+				"off":     off,
+			}, gomock.Any()).
+			Return(nil).
+			Times(1)
+
+		err := cm.SetChannelGroupSubscription(cAB, gid, model.ChannelSubscribeLevelMarkAndNotify, uid1)
+		cm.P.Wait()
+		assert.NoError(t, err)
 	})
 }
 
@@ -884,6 +1358,165 @@ func TestManagerImpl_GetDMChannelMapping(t *testing.T) {
 	})
 }
 
+func TestManagerImpl_CreatePrivateChannel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid name", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.CreatePrivateChannel("あ", set.UUID{}, uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidChannelName.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		creatorID := uuid.Must(uuid.NewV4())
+		memberID := uuid.Must(uuid.NewV4())
+
+		repo.EXPECT().
+			CreateChannel(gomock.Any(), set.UUIDSetFromArray([]uuid.UUID{creatorID, memberID}), false).
+			DoAndReturn(func(ch model.Channel, _ set.UUID, _ bool) (*model.Channel, error) {
+				assert.True(t, ch.IsPrivateChannel())
+				assert.EqualValues(t, "private", ch.Name)
+				ch.ID = uuid.Must(uuid.NewV4())
+				return &ch, nil
+			}).
+			Times(1)
+
+		ch, err := cm.CreatePrivateChannel("private", set.UUIDSetFromArray([]uuid.UUID{memberID}), creatorID)
+		if assert.NoError(t, err) {
+			assert.False(t, cm.T.IsChannelPresent(ch.ID))
+		}
+	})
+}
+
+func TestManagerImpl_AddPrivateChannelMembers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("public channel", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		err := cm.AddPrivateChannelMembers(cA, set.UUIDSetFromArray([]uuid.UUID{uuid.Must(uuid.NewV4())}), uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidChannel.Error())
+	})
+
+	t.Run("dm channel", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		id := uuid.Must(uuid.NewV4())
+
+		repo.EXPECT().
+			GetChannel(id).
+			Return(&model.Channel{ID: id, ParentID: dmChannelRootUUID, IsVisible: true}, nil).
+			Times(1)
+
+		err := cm.AddPrivateChannelMembers(id, set.UUIDSetFromArray([]uuid.UUID{uuid.Must(uuid.NewV4())}), uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidChannel.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		id := uuid.Must(uuid.NewV4())
+		userID := uuid.Must(uuid.NewV4())
+		members := set.UUIDSetFromArray([]uuid.UUID{uuid.Must(uuid.NewV4())})
+
+		repo.EXPECT().
+			GetChannel(id).
+			Return(&model.Channel{ID: id, ParentID: privateChannelRootUUID, IsVisible: true}, nil).
+			Times(1)
+		repo.EXPECT().
+			AddPrivateChannelMembers(id, members, userID).
+			Return(members.Array(), nil).
+			Times(1)
+		repo.EXPECT().
+			RecordChannelEvent(id, model.ChannelEventMembersChanged, model.ChannelEventDetail{"userId": userID, "added": members.Array(), "removed": []uuid.UUID{}}, gomock.Any()).
+			Return(nil).
+			Times(1)
+
+		err := cm.AddPrivateChannelMembers(id, members, userID)
+		cm.P.Wait()
+		assert.NoError(t, err)
+	})
+}
+
+func TestManagerImpl_RemovePrivateChannelMembers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("public channel", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		err := cm.RemovePrivateChannelMembers(cA, set.UUIDSetFromArray([]uuid.UUID{uuid.Must(uuid.NewV4())}), uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidChannel.Error())
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		id := uuid.Must(uuid.NewV4())
+		userID := uuid.Must(uuid.NewV4())
+		members := set.UUIDSetFromArray([]uuid.UUID{uuid.Must(uuid.NewV4())})
+
+		repo.EXPECT().
+			GetChannel(id).
+			Return(&model.Channel{ID: id, ParentID: privateChannelRootUUID, IsVisible: true}, nil).
+			Times(1)
+		repo.EXPECT().
+			RemovePrivateChannelMembers(id, members, userID).
+			Return([]uuid.UUID{}, nil).
+			Times(1)
+
+		err := cm.RemovePrivateChannelMembers(id, members, userID)
+		cm.P.Wait()
+		assert.NoError(t, err)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		id := uuid.Must(uuid.NewV4())
+		userID := uuid.Must(uuid.NewV4())
+		members := set.UUIDSetFromArray([]uuid.UUID{uuid.Must(uuid.NewV4())})
+
+		repo.EXPECT().
+			GetChannel(id).
+			Return(&model.Channel{ID: id, ParentID: privateChannelRootUUID, IsVisible: true}, nil).
+			Times(1)
+		repo.EXPECT().
+			RemovePrivateChannelMembers(id, members, userID).
+			Return(members.Array(), nil).
+			Times(1)
+		repo.EXPECT().
+			RecordChannelEvent(id, model.ChannelEventMembersChanged, model.ChannelEventDetail{"userId": userID, "added": []uuid.UUID{}, "removed": members.Array()}, gomock.Any()).
+			Return(nil).
+			Times(1)
+
+		err := cm.RemovePrivateChannelMembers(id, members, userID)
+		cm.P.Wait()
+		assert.NoError(t, err)
+	})
+}
+
 func TestManagerImpl_IsChannelAccessibleToUser(t *testing.T) {
 	t.Parallel()
 
@@ -973,3 +1606,237 @@ func TestManagerImpl_IsPublicChannel(t *testing.T) {
 	assert.True(t, cm.IsPublicChannel(cA))
 	assert.False(t, cm.IsPublicChannel(cNotFound))
 }
+
+func TestManagerImpl_GetGroupDMChannel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("too few members", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+
+		_, err := cm.GetGroupDMChannel(set.UUIDSetFromArray([]uuid.UUID{userID, uuid.Must(uuid.NewV4())}), userID)
+		assert.EqualError(t, err, ErrInvalidGroupMembers.Error())
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+		others := set.UUIDSetFromArray([]uuid.UUID{uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())})
+		members := others.Clone()
+		members.Add(userID)
+		ch := &model.Channel{ID: uuid.Must(uuid.NewV4()), ParentID: dmChannelRootUUID}
+
+		repo.EXPECT().
+			GetGroupDirectMessageChannel(members).
+			Return(ch, nil).
+			Times(1)
+
+		got, err := cm.GetGroupDMChannel(others, userID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, ch.ID, got.ID)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		userID := uuid.Must(uuid.NewV4())
+		others := set.UUIDSetFromArray([]uuid.UUID{uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())})
+		members := others.Clone()
+		members.Add(userID)
+
+		repo.EXPECT().
+			GetGroupDirectMessageChannel(members).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+		repo.EXPECT().
+			CreateChannel(gomock.Any(), members, true).
+			DoAndReturn(func(ch model.Channel, _ set.UUID, _ bool) (*model.Channel, error) {
+				assert.Equal(t, userID, ch.CreatorID)
+				ch.ID = uuid.Must(uuid.NewV4())
+				ch.ParentID = dmChannelRootUUID
+				return &ch, nil
+			}).
+			Times(1)
+
+		ch, err := cm.GetGroupDMChannel(others, userID)
+		if assert.NoError(t, err) {
+			assert.True(t, ch.IsDMChannel())
+			assert.False(t, cm.T.IsChannelPresent(ch.ID))
+		}
+	})
+}
+
+func TestManagerImpl_ConvertGroupDMChannel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid name", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.ConvertGroupDMChannel(uuid.Must(uuid.NewV4()), "あ", uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidChannelName.Error())
+	})
+
+	t.Run("not group dm", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		repo.EXPECT().
+			ConvertGroupDirectMessageChannel(cA, "private", gomock.Any()).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+
+		_, err := cm.ConvertGroupDMChannel(cA, "private", uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidChannel.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		id := uuid.Must(uuid.NewV4())
+		updaterID := uuid.Must(uuid.NewV4())
+
+		repo.EXPECT().
+			ConvertGroupDirectMessageChannel(id, "private", updaterID).
+			Return(&model.Channel{ID: id, Name: "private", ParentID: privateChannelRootUUID, CreatorID: updaterID}, nil).
+			Times(1)
+
+		ch, err := cm.ConvertGroupDMChannel(id, "private", updaterID)
+		if assert.NoError(t, err) {
+			assert.True(t, ch.IsPrivateChannel())
+			assert.NotNil(t, ch.ChildrenID)
+		}
+	})
+}
+
+func TestManagerImpl_InstantiateChannelTemplate(t *testing.T) {
+	t.Parallel()
+
+	definition := model.ChannelTemplateNode{
+		Topic: "team",
+		Children: []*model.ChannelTemplateNode{
+			{Name: "general", Force: true},
+			{Name: "random"},
+		},
+	}
+
+	t.Run("invalid name", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.InstantiateChannelTemplate(definition, cA, "あ", uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidChannelName.Error())
+	})
+
+	t.Run("duplicated names in template", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		def := model.ChannelTemplateNode{Children: []*model.ChannelTemplateNode{{Name: "dev"}, {Name: "DEV"}}}
+		_, err := cm.InstantiateChannelTemplate(def, cA, "team", uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrChannelNameConflicts.Error())
+	})
+
+	t.Run("forced channel with group subscriptions", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		def := model.ChannelTemplateNode{Children: []*model.ChannelTemplateNode{{
+			Name:               "general",
+			Force:              true,
+			GroupSubscriptions: []*model.ChannelTemplateGroupSubscription{{GroupID: uuid.Must(uuid.NewV4()), Level: model.ChannelSubscribeLevelMark}},
+		}}}
+		_, err := cm.InstantiateChannelTemplate(def, cA, "team", uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrForcedNotification.Error())
+	})
+
+	t.Run("name conflicts", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.InstantiateChannelTemplate(definition, cA, "b", uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrChannelNameConflicts.Error())
+	})
+
+	t.Run("too deep", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		_, err := cm.InstantiateChannelTemplate(definition, cABCD, "team", uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrTooDeepChannel.Error())
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+
+		repo.EXPECT().
+			CreateChannelTree(cA, "team", definition, gomock.Any()).
+			Return(nil, repository.ArgError("definition", "bot not found")).
+			Times(1)
+
+		_, err := cm.InstantiateChannelTemplate(definition, cA, "team", uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrInvalidTemplate.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		creatorID := uuid.Must(uuid.NewV4())
+
+		repo.EXPECT().
+			CreateChannelTree(cA, "team", definition, creatorID).
+			DoAndReturn(func(parentID uuid.UUID, name string, def model.ChannelTemplateNode, creatorID uuid.UUID) ([]*model.Channel, error) {
+				root := &model.Channel{ID: uuid.Must(uuid.NewV4()), Name: name, ParentID: parentID, Topic: def.Topic, IsPublic: true, CreatorID: creatorID}
+				channels := []*model.Channel{root}
+				for _, child := range def.Children {
+					channels = append(channels, &model.Channel{ID: uuid.Must(uuid.NewV4()), Name: child.Name, ParentID: root.ID, IsForced: child.Force, IsPublic: true, CreatorID: creatorID})
+				}
+				return channels, nil
+			}).
+			Times(1)
+		repo.EXPECT().
+			RecordChannelEvent(gomock.Any(), model.ChannelEventChildCreated, gomock.Any(), gomock.Any()).
+			Return(nil).
+			Times(3)
+
+		channels, err := cm.InstantiateChannelTemplate(definition, cA, "team", creatorID)
+		if assert.NoError(t, err) && assert.Len(t, channels, 3) {
+			assert.Len(t, channels[0].ChildrenID, 2)
+			assert.EqualValues(t, "a/team", cm.T.GetChannelPath(channels[0].ID))
+			assert.EqualValues(t, "a/team/general", cm.T.GetChannelPath(channels[1].ID))
+			assert.True(t, cm.T.IsForceChannel(channels[1].ID))
+			assert.EqualValues(t, "a/team/random", cm.T.GetChannelPath(channels[2].ID))
+		}
+		cm.P.Wait()
+	})
+}
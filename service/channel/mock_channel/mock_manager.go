@@ -10,6 +10,7 @@ import (
 	model "github.com/traPtitech/traQ/model"
 	repository "github.com/traPtitech/traQ/repository"
 	channel "github.com/traPtitech/traQ/service/channel"
+	set "github.com/traPtitech/traQ/utils/set"
 	reflect "reflect"
 )
 
@@ -80,6 +81,78 @@ func (mr *MockManagerMockRecorder) UpdateChannel(id, args interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateChannel", reflect.TypeOf((*MockManager)(nil).UpdateChannel), id, args)
 }
 
+// ArchiveChannel mocks base method
+func (m *MockManager) ArchiveChannel(id uuid.UUID, cascade bool, updaterID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveChannel", id, cascade, updaterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ArchiveChannel indicates an expected call of ArchiveChannel
+func (mr *MockManagerMockRecorder) ArchiveChannel(id, cascade, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveChannel", reflect.TypeOf((*MockManager)(nil).ArchiveChannel), id, cascade, updaterID)
+}
+
+// UnarchiveChannel mocks base method
+func (m *MockManager) UnarchiveChannel(id uuid.UUID, cascade bool, updaterID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnarchiveChannel", id, cascade, updaterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnarchiveChannel indicates an expected call of UnarchiveChannel
+func (mr *MockManagerMockRecorder) UnarchiveChannel(id, cascade, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnarchiveChannel", reflect.TypeOf((*MockManager)(nil).UnarchiveChannel), id, cascade, updaterID)
+}
+
+// MergeChannel mocks base method
+func (m *MockManager) MergeChannel(srcID uuid.UUID, dstID uuid.UUID, updaterID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeChannel", srcID, dstID, updaterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MergeChannel indicates an expected call of MergeChannel
+func (mr *MockManagerMockRecorder) MergeChannel(srcID, dstID, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeChannel", reflect.TypeOf((*MockManager)(nil).MergeChannel), srcID, dstID, updaterID)
+}
+
+// MoveChannel mocks base method
+func (m *MockManager) MoveChannel(id uuid.UUID, parent uuid.UUID, dryRun bool, updaterID uuid.UUID) ([]*channel.ChannelMove, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveChannel", id, parent, dryRun, updaterID)
+	ret0, _ := ret[0].([]*channel.ChannelMove)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MoveChannel indicates an expected call of MoveChannel
+func (mr *MockManagerMockRecorder) MoveChannel(id, parent, dryRun, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveChannel", reflect.TypeOf((*MockManager)(nil).MoveChannel), id, parent, dryRun, updaterID)
+}
+
+// InstantiateChannelTemplate mocks base method
+func (m *MockManager) InstantiateChannelTemplate(definition model.ChannelTemplateNode, parent uuid.UUID, name string, creatorID uuid.UUID) ([]*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstantiateChannelTemplate", definition, parent, name, creatorID)
+	ret0, _ := ret[0].([]*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstantiateChannelTemplate indicates an expected call of InstantiateChannelTemplate
+func (mr *MockManagerMockRecorder) InstantiateChannelTemplate(definition, parent, name, creatorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstantiateChannelTemplate", reflect.TypeOf((*MockManager)(nil).InstantiateChannelTemplate), definition, parent, name, creatorID)
+}
+
 // PublicChannelTree mocks base method
 func (m *MockManager) PublicChannelTree() channel.Tree {
 	m.ctrl.T.Helper()
@@ -94,6 +167,20 @@ func (mr *MockManagerMockRecorder) PublicChannelTree() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublicChannelTree", reflect.TypeOf((*MockManager)(nil).PublicChannelTree))
 }
 
+// ReloadPublicChannelTree mocks base method
+func (m *MockManager) ReloadPublicChannelTree() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReloadPublicChannelTree")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReloadPublicChannelTree indicates an expected call of ReloadPublicChannelTree
+func (mr *MockManagerMockRecorder) ReloadPublicChannelTree() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadPublicChannelTree", reflect.TypeOf((*MockManager)(nil).ReloadPublicChannelTree))
+}
+
 // ChangeChannelSubscriptions mocks base method
 func (m *MockManager) ChangeChannelSubscriptions(channelID uuid.UUID, subscriptions map[uuid.UUID]model.ChannelSubscribeLevel, keepOffLevel bool, updaterID uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -108,6 +195,20 @@ func (mr *MockManagerMockRecorder) ChangeChannelSubscriptions(channelID, subscri
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeChannelSubscriptions", reflect.TypeOf((*MockManager)(nil).ChangeChannelSubscriptions), channelID, subscriptions, keepOffLevel, updaterID)
 }
 
+// SetChannelGroupSubscription mocks base method
+func (m *MockManager) SetChannelGroupSubscription(channelID, groupID uuid.UUID, level model.ChannelSubscribeLevel, updaterID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetChannelGroupSubscription", channelID, groupID, level, updaterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetChannelGroupSubscription indicates an expected call of SetChannelGroupSubscription
+func (mr *MockManagerMockRecorder) SetChannelGroupSubscription(channelID, groupID, level, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetChannelGroupSubscription", reflect.TypeOf((*MockManager)(nil).SetChannelGroupSubscription), channelID, groupID, level, updaterID)
+}
+
 // GetDMChannel mocks base method
 func (m *MockManager) GetDMChannel(user1, user2 uuid.UUID) (*model.Channel, error) {
 	m.ctrl.T.Helper()
@@ -153,6 +254,109 @@ func (mr *MockManagerMockRecorder) GetDMChannelMapping(userID interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDMChannelMapping", reflect.TypeOf((*MockManager)(nil).GetDMChannelMapping), userID)
 }
 
+// GetGroupDMChannel mocks base method
+func (m *MockManager) GetGroupDMChannel(members set.UUID, userID uuid.UUID) (*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroupDMChannel", members, userID)
+	ret0, _ := ret[0].(*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroupDMChannel indicates an expected call of GetGroupDMChannel
+func (mr *MockManagerMockRecorder) GetGroupDMChannel(members, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupDMChannel", reflect.TypeOf((*MockManager)(nil).GetGroupDMChannel), members, userID)
+}
+
+// GetGroupDMChannelMapping mocks base method
+func (m *MockManager) GetGroupDMChannelMapping(userID uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroupDMChannelMapping", userID)
+	ret0, _ := ret[0].(map[uuid.UUID][]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroupDMChannelMapping indicates an expected call of GetGroupDMChannelMapping
+func (mr *MockManagerMockRecorder) GetGroupDMChannelMapping(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupDMChannelMapping", reflect.TypeOf((*MockManager)(nil).GetGroupDMChannelMapping), userID)
+}
+
+// ConvertGroupDMChannel mocks base method
+func (m *MockManager) ConvertGroupDMChannel(id uuid.UUID, name string, updaterID uuid.UUID) (*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConvertGroupDMChannel", id, name, updaterID)
+	ret0, _ := ret[0].(*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConvertGroupDMChannel indicates an expected call of ConvertGroupDMChannel
+func (mr *MockManagerMockRecorder) ConvertGroupDMChannel(id, name, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertGroupDMChannel", reflect.TypeOf((*MockManager)(nil).ConvertGroupDMChannel), id, name, updaterID)
+}
+
+// CreatePrivateChannel mocks base method
+func (m *MockManager) CreatePrivateChannel(name string, members set.UUID, creatorID uuid.UUID) (*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePrivateChannel", name, members, creatorID)
+	ret0, _ := ret[0].(*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePrivateChannel indicates an expected call of CreatePrivateChannel
+func (mr *MockManagerMockRecorder) CreatePrivateChannel(name, members, creatorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePrivateChannel", reflect.TypeOf((*MockManager)(nil).CreatePrivateChannel), name, members, creatorID)
+}
+
+// GetPrivateChannels mocks base method
+func (m *MockManager) GetPrivateChannels(userID uuid.UUID) ([]*model.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrivateChannels", userID)
+	ret0, _ := ret[0].([]*model.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrivateChannels indicates an expected call of GetPrivateChannels
+func (mr *MockManagerMockRecorder) GetPrivateChannels(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivateChannels", reflect.TypeOf((*MockManager)(nil).GetPrivateChannels), userID)
+}
+
+// AddPrivateChannelMembers mocks base method
+func (m *MockManager) AddPrivateChannelMembers(id uuid.UUID, members set.UUID, updaterID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPrivateChannelMembers", id, members, updaterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPrivateChannelMembers indicates an expected call of AddPrivateChannelMembers
+func (mr *MockManagerMockRecorder) AddPrivateChannelMembers(id, members, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPrivateChannelMembers", reflect.TypeOf((*MockManager)(nil).AddPrivateChannelMembers), id, members, updaterID)
+}
+
+// RemovePrivateChannelMembers mocks base method
+func (m *MockManager) RemovePrivateChannelMembers(id uuid.UUID, members set.UUID, updaterID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePrivateChannelMembers", id, members, updaterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemovePrivateChannelMembers indicates an expected call of RemovePrivateChannelMembers
+func (mr *MockManagerMockRecorder) RemovePrivateChannelMembers(id, members, updaterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePrivateChannelMembers", reflect.TypeOf((*MockManager)(nil).RemovePrivateChannelMembers), id, members, updaterID)
+}
+
 // IsChannelAccessibleToUser mocks base method
 func (m *MockManager) IsChannelAccessibleToUser(userID, channelID uuid.UUID) (bool, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,67 @@
+package channel
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ReadStateMaxMembers 既読位置を記録するプライベートチャンネルの最大メンバー数
+const ReadStateMaxMembers = 20
+
+// ReadStateRecorder チャンネルの既読位置の記録器
+//
+// チャンネルの既読イベントを受け取り、DM・少人数のプライベートチャンネルでの
+// ユーザーの既読位置を記録します。既読位置を公開していないユーザーの既読位置は記録しません。
+type ReadStateRecorder struct {
+	cm     Manager
+	repo   repository.Repository
+	logger *zap.Logger
+}
+
+// NewReadStateRecorder チャンネルの既読位置の記録器を生成します
+func NewReadStateRecorder(cm Manager, repo repository.Repository, hub *hub.Hub, logger *zap.Logger) *ReadStateRecorder {
+	recorder := &ReadStateRecorder{
+		cm:     cm,
+		repo:   repo,
+		logger: logger.Named("read_state_recorder"),
+	}
+	go func() {
+		for e := range hub.Subscribe(100, event.ChannelRead).Receiver {
+			if event.IsRemote(e) {
+				continue
+			}
+			recorder.record(e.Fields["user_id"].(uuid.UUID), e.Fields["channel_id"].(uuid.UUID))
+		}
+	}()
+	return recorder
+}
+
+func (r *ReadStateRecorder) record(userID, channelID uuid.UUID) {
+	if r.cm.PublicChannelTree().IsChannelPresent(channelID) {
+		return // 公開チャンネルでは記録しない
+	}
+	settings, err := r.repo.GetUserSettings(userID)
+	if err != nil {
+		r.logger.Error("failed to GetUserSettings", zap.Error(err), zap.Stringer("userID", userID))
+		return
+	}
+	if !settings.ReadReceipts {
+		return // 既読位置を公開していない間は記録しない
+	}
+	members, err := r.cm.GetDMChannelMembers(channelID)
+	if err != nil {
+		r.logger.Error("failed to GetDMChannelMembers", zap.Error(err), zap.Stringer("channelID", channelID))
+		return
+	}
+	if len(members) > ReadStateMaxMembers {
+		return
+	}
+
+	if _, err := r.repo.UpdateChannelReadState(userID, channelID, time.Now()); err != nil && err != repository.ErrNotFound {
+		r.logger.Error("failed to UpdateChannelReadState", zap.Error(err), zap.Stringer("userID", userID), zap.Stringer("channelID", channelID))
+	}
+}
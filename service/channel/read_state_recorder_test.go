@@ -0,0 +1,92 @@
+package channel
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type readStateRepository struct {
+	repository.Repository
+	members      map[uuid.UUID][]uuid.UUID
+	readReceipts map[uuid.UUID]bool
+	recorded     []uuid.UUID
+}
+
+func (r *readStateRepository) GetPrivateChannelMemberIDs(channelID uuid.UUID) ([]uuid.UUID, error) {
+	return r.members[channelID], nil
+}
+
+func (r *readStateRepository) GetUserSettings(userID uuid.UUID) (*model.UserSettings, error) {
+	enabled, ok := r.readReceipts[userID]
+	if !ok {
+		return nil, errors.New("error")
+	}
+	return &model.UserSettings{UserID: userID, ReadReceipts: enabled}, nil
+}
+
+func (r *readStateRepository) UpdateChannelReadState(userID, channelID uuid.UUID, readAt time.Time) (*model.ChannelReadState, error) {
+	r.recorded = append(r.recorded, channelID)
+	return &model.ChannelReadState{UserID: userID, ChannelID: channelID, ReadAt: readAt}, nil
+}
+
+func TestReadStateRecorder_record(t *testing.T) {
+	t.Parallel()
+
+	enabled := uuid.NewV3(uuid.Nil, "enabled")
+	disabled := uuid.NewV3(uuid.Nil, "disabled")
+	broken := uuid.NewV3(uuid.Nil, "broken")
+	public := uuid.NewV3(uuid.Nil, "public")
+	dm := uuid.NewV3(uuid.Nil, "dm")
+	large := uuid.NewV3(uuid.Nil, "large")
+
+	largeMembers := make([]uuid.UUID, ReadStateMaxMembers+1)
+	for i := range largeMembers {
+		largeMembers[i] = uuid.Must(uuid.NewV4())
+	}
+
+	tests := []struct {
+		name      string
+		userID    uuid.UUID
+		channelID uuid.UUID
+		recorded  bool
+	}{
+		{name: "dm", userID: enabled, channelID: dm, recorded: true},
+		{name: "public channel", userID: enabled, channelID: public, recorded: false},
+		{name: "too many members", userID: enabled, channelID: large, recorded: false},
+		{name: "read receipts disabled", userID: disabled, channelID: dm, recorded: false},
+		{name: "settings error", userID: broken, channelID: dm, recorded: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := &readStateRepository{
+				members: map[uuid.UUID][]uuid.UUID{
+					dm:    {enabled, disabled, broken},
+					large: largeMembers,
+				},
+				readReceipts: map[uuid.UUID]bool{enabled: true, disabled: false},
+			}
+			cm := &managerImpl{
+				R: repo,
+				L: zap.NewNop(),
+				T: &treeImpl{nodes: map[uuid.UUID]*channelNode{public: {id: public}}},
+			}
+			r := &ReadStateRecorder{cm: cm, repo: repo, logger: zap.NewNop()}
+
+			r.record(tt.userID, tt.channelID)
+			if tt.recorded {
+				assert.Equal(t, []uuid.UUID{tt.channelID}, repo.recorded)
+			} else {
+				assert.Empty(t, repo.recorded)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package channel
+
+import (
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"go.uber.org/zap"
+)
+
+// TreeSyncer 公開チャンネルツリーの同期器
+//
+// 他のノードから複製されたチャンネルの作成・更新・削除・統合イベントを受け取り、
+// 自ノードの公開チャンネルツリーを再構築します。
+type TreeSyncer struct {
+	cm     Manager
+	logger *zap.Logger
+}
+
+// NewTreeSyncer 公開チャンネルツリーの同期器を生成します
+func NewTreeSyncer(cm Manager, hub *hub.Hub, logger *zap.Logger) *TreeSyncer {
+	syncer := &TreeSyncer{
+		cm:     cm,
+		logger: logger.Named("channel_tree_syncer"),
+	}
+	go func() {
+		for e := range hub.Subscribe(100, event.ChannelCreated, event.ChannelUpdated, event.ChannelTopicUpdated, event.ChannelDeleted, event.ChannelMerged).Receiver {
+			if event.IsRemote(e) {
+				syncer.sync()
+			}
+		}
+	}()
+	return syncer
+}
+
+func (s *TreeSyncer) sync() {
+	if err := s.cm.ReloadPublicChannelTree(); err != nil {
+		s.logger.Error("failed to ReloadPublicChannelTree", zap.Error(err))
+	}
+}
@@ -1,33 +1,47 @@
 package counter
 
 import (
+	"encoding/json"
 	"github.com/gofrs/uuid"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/service/bus"
 	"sync"
 	"time"
 )
 
+const onlineBusTopic = "online"
+
 var onlineUsersCounter = promauto.NewGauge(prometheus.GaugeOpts{
 	Namespace: "traq",
 	Name:      "online_users",
 })
 
 // OnlineCounter オンラインユーザーカウンター
+//
+// 複数ノードで動作している場合、いずれかのノードに接続しているユーザーをオンラインとみなします。
 type OnlineCounter struct {
 	hub          *hub.Hub
+	bus          *bus.Bus
 	counters     map[uuid.UUID]*counter
 	countersLock sync.Mutex
 }
 
+type onlineDelta struct {
+	UserID uuid.UUID `json:"user_id"`
+	Online bool      `json:"online"`
+}
+
 // NewOnlineCounter オンラインユーザーカウンターを生成します
-func NewOnlineCounter(hub *hub.Hub) *OnlineCounter {
+func NewOnlineCounter(hub *hub.Hub, b *bus.Bus) *OnlineCounter {
 	oc := &OnlineCounter{
 		hub:      hub,
+		bus:      b,
 		counters: map[uuid.UUID]*counter{},
 	}
+	b.Share(onlineBusTopic, oc)
 	go func() {
 		for e := range hub.Subscribe(8, event.SSEConnected, event.SSEDisconnected, event.WSConnected, event.WSDisconnected).Receiver {
 			switch e.Topic() {
@@ -41,66 +55,92 @@ func NewOnlineCounter(hub *hub.Hub) *OnlineCounter {
 	return oc
 }
 
-// inc 指定したユーザーのカウンタをインクリメントします
-func (oc *OnlineCounter) inc(userID uuid.UUID) (toOnline bool) {
+func (oc *OnlineCounter) getCounter(userID uuid.UUID, create bool) *counter {
 	oc.countersLock.Lock()
+	defer oc.countersLock.Unlock()
 	c, ok := oc.counters[userID]
-	if !ok {
+	if !ok && create {
 		c = &counter{
-			userID: userID,
+			userID:  userID,
+			remotes: map[string]struct{}{},
 		}
 		oc.counters[userID] = c
 	}
-	oc.countersLock.Unlock()
+	return c
+}
 
-	toOnline = c.inc()
+// inc 指定したユーザーのカウンタをインクリメントします
+func (oc *OnlineCounter) inc(userID uuid.UUID) (toOnline bool) {
+	c := oc.getCounter(userID, true)
+	toOnline, localToOnline := c.inc()
+	if localToOnline {
+		oc.publishDelta(userID, true)
+	}
 	if toOnline {
-		onlineUsersCounter.Inc()
-		oc.hub.Publish(hub.Message{
-			Name: event.UserOnline,
-			Fields: hub.Fields{
-				"user_id":  userID,
-				"datetime": c.getLastUpdated(),
-			},
-		})
+		oc.notify(c, true, false)
 	}
 	return
 }
 
 // dec 指定したユーザーのカウンタをデクリメントします
 func (oc *OnlineCounter) dec(userID uuid.UUID) (toOffline bool) {
-	oc.countersLock.Lock()
-	c, ok := oc.counters[userID]
-	if !ok {
-		oc.countersLock.Unlock()
+	c := oc.getCounter(userID, false)
+	if c == nil {
 		return
 	}
-	oc.countersLock.Unlock()
-
-	toOffline = c.dec()
+	toOffline, localToOffline := c.dec()
+	if localToOffline {
+		oc.publishDelta(userID, false)
+	}
 	if toOffline {
-		onlineUsersCounter.Dec()
-		oc.hub.Publish(hub.Message{
-			Name: event.UserOffline,
-			Fields: hub.Fields{
-				"user_id":  userID,
-				"datetime": c.getLastUpdated(),
-			},
-		})
+		oc.notify(c, false, false)
 	}
 	return
 }
 
+// setRemote 他のノードでの指定したユーザーのオンライン状態を設定します
+func (oc *OnlineCounter) setRemote(node string, userID uuid.UUID, online bool) {
+	c := oc.getCounter(userID, online)
+	if c == nil {
+		return
+	}
+	if c.setRemote(node, online) {
+		oc.notify(c, online, true)
+	}
+}
+
+func (oc *OnlineCounter) publishDelta(userID uuid.UUID, online bool) {
+	b, _ := json.Marshal(&onlineDelta{UserID: userID, Online: online})
+	_ = oc.bus.Publish(onlineBusTopic, b)
+}
+
+func (oc *OnlineCounter) notify(c *counter, online bool, remote bool) {
+	name := event.UserOffline
+	if online {
+		name = event.UserOnline
+		onlineUsersCounter.Inc()
+	} else {
+		onlineUsersCounter.Dec()
+	}
+	fields := hub.Fields{
+		"user_id":  c.userID,
+		"datetime": c.getLastUpdated(),
+	}
+	if remote {
+		fields[event.FieldRemote] = true
+	}
+	oc.hub.Publish(hub.Message{
+		Name:   name,
+		Fields: fields,
+	})
+}
+
 // IsOnline 指定したユーザーがオンラインかどうかを取得します
 func (oc *OnlineCounter) IsOnline(userID uuid.UUID) bool {
-	oc.countersLock.Lock()
-	c, ok := oc.counters[userID]
-	if !ok {
-		oc.countersLock.Unlock()
+	c := oc.getCounter(userID, false)
+	if c == nil {
 		return false
 	}
-	oc.countersLock.Unlock()
-
 	return c.isOnline()
 }
 
@@ -117,44 +157,132 @@ func (oc *OnlineCounter) GetOnlineUserIDs() []uuid.UUID {
 	return users
 }
 
+// Snapshot implements bus.Shared interface.
+func (oc *OnlineCounter) Snapshot() []byte {
+	oc.countersLock.Lock()
+	users := make([]uuid.UUID, 0, len(oc.counters))
+	for u, c := range oc.counters {
+		if c.isLocalOnline() {
+			users = append(users, u)
+		}
+	}
+	oc.countersLock.Unlock()
+	b, _ := json.Marshal(users)
+	return b
+}
+
+// ApplySnapshot implements bus.Shared interface.
+func (oc *OnlineCounter) ApplySnapshot(node string, data []byte) {
+	var users []uuid.UUID
+	if err := json.Unmarshal(data, &users); err != nil {
+		return
+	}
+	online := make(map[uuid.UUID]bool, len(users))
+	for _, u := range users {
+		online[u] = true
+	}
+
+	oc.countersLock.Lock()
+	for u, c := range oc.counters {
+		if c.hasRemote(node) && !online[u] {
+			online[u] = false
+		}
+	}
+	oc.countersLock.Unlock()
+
+	for u, v := range online {
+		oc.setRemote(node, u, v)
+	}
+}
+
+// Apply implements bus.Shared interface.
+func (oc *OnlineCounter) Apply(node string, data []byte) {
+	var d onlineDelta
+	if err := json.Unmarshal(data, &d); err != nil {
+		return
+	}
+	oc.setRemote(node, d.UserID, d.Online)
+}
+
+// Forget implements bus.Shared interface.
+func (oc *OnlineCounter) Forget(node string) {
+	oc.ApplySnapshot(node, []byte("[]"))
+}
+
 type counter struct {
 	sync.RWMutex
-	userID      uuid.UUID
-	count       int
+	userID uuid.UUID
+	// count 自ノードでの接続数
+	count int
+	// remotes ユーザーが接続している他のノード
+	remotes     map[string]struct{}
 	lastUpdated time.Time
 }
 
 func (s *counter) isOnline() (r bool) {
+	s.RLock()
+	r = s.count > 0 || len(s.remotes) > 0
+	s.RUnlock()
+	return
+}
+
+func (s *counter) isLocalOnline() (r bool) {
 	s.RLock()
 	r = s.count > 0
 	s.RUnlock()
 	return
 }
 
-func (s *counter) inc() (toOnline bool) {
+func (s *counter) hasRemote(node string) (r bool) {
+	s.RLock()
+	_, r = s.remotes[node]
+	s.RUnlock()
+	return
+}
+
+func (s *counter) inc() (toOnline bool, localToOnline bool) {
 	s.Lock()
 	s.count++
 	s.lastUpdated = time.Now()
 	if s.count == 1 {
-		toOnline = true
+		localToOnline = true
+		toOnline = len(s.remotes) == 0
 	}
 	s.Unlock()
 	return
 }
 
-func (s *counter) dec() (toOffline bool) {
+func (s *counter) dec() (toOffline bool, localToOffline bool) {
 	s.Lock()
 	if s.count > 0 {
 		s.count--
 		s.lastUpdated = time.Now()
 		if s.count == 0 {
-			toOffline = true
+			localToOffline = true
+			toOffline = len(s.remotes) == 0
 		}
 	}
 	s.Unlock()
 	return
 }
 
+func (s *counter) setRemote(node string, online bool) (changed bool) {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.remotes[node]
+	if ok == online {
+		return false
+	}
+	before := s.count > 0 || len(s.remotes) > 0
+	if online {
+		s.remotes[node] = struct{}{}
+	} else {
+		delete(s.remotes, node)
+	}
+	s.lastUpdated = time.Now()
+	return before != (s.count > 0 || len(s.remotes) > 0)
+}
+
 func (s *counter) getLastUpdated() (t time.Time) {
 	s.RLock()
 	t = s.lastUpdated
@@ -0,0 +1,58 @@
+package counter
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// StampUsageCounter スタンプ使用数集計器
+//
+// メッセージにスタンプが押された・消されたイベントを受け取り、
+// リポジトリのスタンプ使用数の集計値を逐次更新します。
+type StampUsageCounter struct {
+	repo   repository.Repository
+	logger *zap.Logger
+}
+
+// NewStampUsageCounter スタンプ使用数集計器を生成します
+func NewStampUsageCounter(repo repository.Repository, hub *hub.Hub, logger *zap.Logger) *StampUsageCounter {
+	counter := &StampUsageCounter{
+		repo:   repo,
+		logger: logger.Named("stamp_usage_counter"),
+	}
+	sub := hub.Subscribe(100, event.MessageStamped, event.MessageUnstamped)
+	go func() {
+		for e := range sub.Receiver {
+			if event.IsRemote(e) {
+				continue
+			}
+			switch e.Topic() {
+			case event.MessageStamped:
+				// 同じユーザーが同じメッセージに押したスタンプは1回として数える
+				if e.Fields["new"].(bool) {
+					counter.update(e.Fields["message_id"].(uuid.UUID), e.Fields["stamp_id"].(uuid.UUID), e.Fields["user_id"].(uuid.UUID), e.Fields["created_at"].(time.Time), 1)
+				}
+			case event.MessageUnstamped:
+				counter.update(e.Fields["message_id"].(uuid.UUID), e.Fields["stamp_id"].(uuid.UUID), e.Fields["user_id"].(uuid.UUID), e.Fields["created_at"].(time.Time), -1)
+			}
+		}
+	}()
+	return counter
+}
+
+func (c *StampUsageCounter) update(messageID, stampID, userID uuid.UUID, date time.Time, delta int) {
+	m, err := c.repo.GetMessageByID(messageID)
+	if err != nil {
+		if err != repository.ErrNotFound {
+			c.logger.Error("failed to GetMessageByID", zap.Error(err), zap.Stringer("messageID", messageID))
+		}
+		return
+	}
+	if err := c.repo.UpdateStampUsage(stampID, m.ChannelID, userID, date, delta); err != nil {
+		c.logger.Error("failed to UpdateStampUsage", zap.Error(err), zap.Stringer("stampID", stampID), zap.Int("delta", delta))
+	}
+}
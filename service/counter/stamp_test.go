@@ -0,0 +1,77 @@
+package counter
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type stampUsageRepository struct {
+	repository.Repository
+	channelID uuid.UUID
+	updates   chan int
+}
+
+func (r *stampUsageRepository) GetMessageByID(messageID uuid.UUID) (*model.Message, error) {
+	return &model.Message{ID: messageID, ChannelID: r.channelID}, nil
+}
+
+func (r *stampUsageRepository) UpdateStampUsage(stampID, channelID, userID uuid.UUID, date time.Time, delta int) error {
+	r.updates <- delta
+	return nil
+}
+
+func TestStampUsageCounter(t *testing.T) {
+	t.Parallel()
+
+	h := hub.New()
+	repo := &stampUsageRepository{channelID: uuid.Must(uuid.NewV4()), updates: make(chan int, 10)}
+	NewStampUsageCounter(repo, h, zap.NewNop())
+
+	stamped := func(remote bool) hub.Message {
+		return hub.Message{
+			Name: event.MessageStamped,
+			Fields: hub.Fields{
+				"message_id":      uuid.Must(uuid.NewV4()),
+				"stamp_id":        uuid.Must(uuid.NewV4()),
+				"user_id":         uuid.Must(uuid.NewV4()),
+				"created_at":      time.Now(),
+				"new":             true,
+				event.FieldRemote: remote,
+			},
+		}
+	}
+	receive := func() int {
+		select {
+		case delta := <-repo.updates:
+			return delta
+		case <-time.After(time.Second):
+			t.Fatal("stamp usage was not updated")
+			return 0
+		}
+	}
+
+	// 他のノードから複製されたイベントでは集計値を更新しない
+	h.Publish(stamped(true))
+	h.Publish(stamped(false))
+	assert.Equal(t, 1, receive())
+	h.Publish(hub.Message{
+		Name: event.MessageUnstamped,
+		Fields: hub.Fields{
+			"message_id":      uuid.Must(uuid.NewV4()),
+			"stamp_id":        uuid.Must(uuid.NewV4()),
+			"user_id":         uuid.Must(uuid.NewV4()),
+			"created_at":      time.Now(),
+			event.FieldRemote: true,
+		},
+	})
+	h.Publish(stamped(false))
+	assert.Equal(t, 1, receive())
+	assert.Len(t, repo.updates, 0)
+}
@@ -16,7 +16,8 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidImage = errors.New("invalid image")
 )
 
 type SaveArgs struct {
@@ -3,34 +3,62 @@ package file
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/media"
+	imaging2 "github.com/traPtitech/traQ/utils/imaging"
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/storage"
 	"go.uber.org/zap"
+	"image"
 	"image/png"
 	"io"
 	"io/ioutil"
+	"strings"
+)
+
+const (
+	// mediaQueueSize 処理待ちの動画・音声ファイルの最大数
+	mediaQueueSize = 256
+	// mediaWorkers 動画・音声ファイルを処理するワーカー数 (実際の処理並列数はmedia.Processorで制限されます)
+	mediaWorkers = 4
 )
 
 type managerImpl struct {
 	repo repository.FileRepository
 	fs   storage.FileStorage
 	ip   imaging.Processor
+	mp   media.Processor
+	mq   chan *model.FileMeta
+	sc   SanitizeConfig
 	l    *zap.Logger
 }
 
-func InitFileManager(repo repository.FileRepository, fs storage.FileStorage, ip imaging.Processor, l *zap.Logger) (Manager, error) {
-	return &managerImpl{
+func InitFileManager(repo repository.FileRepository, fs storage.FileStorage, ip imaging.Processor, mp media.Processor, sc SanitizeConfig, l *zap.Logger) (Manager, error) {
+	m := &managerImpl{
 		repo: repo,
 		fs:   fs,
 		ip:   ip,
+		mp:   mp,
+		sc:   sc,
 		l:    l.Named("file_manager"),
-	}, nil
+	}
+	if mp != nil {
+		m.mq = make(chan *model.FileMeta, mediaQueueSize)
+		for i := 0; i < mediaWorkers; i++ {
+			go func() {
+				for f := range m.mq {
+					m.processMedia(f)
+				}
+			}()
+		}
+	}
+	return m, nil
 }
 
 func (m *managerImpl) Save(args SaveArgs) (model.File, error) {
@@ -48,24 +76,30 @@ func (m *managerImpl) Save(args SaveArgs) (model.File, error) {
 		ChannelID: args.ChannelID,
 	}
 
+	// ハッシュ計算・サムネイル生成のためにSeek出来る必要があるので、出来ない場合は全読み込み
+	src, ok := args.Src.(io.ReadSeeker)
+	if !ok {
+		b, err := ioutil.ReadAll(args.Src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read whole src stream: %w", err)
+		}
+		src = bytes.NewReader(b)
+	}
+
+	// 画像のメタデータ除去・向きの正規化
+	src, orientation, err := m.sanitize(f, src)
+	if err != nil {
+		return nil, err
+	}
+
 	if args.Thumbnail == nil && !args.SkipThumbnailGeneration {
 		// サムネイル画像生成
 		switch args.MimeType {
 		case "image/jpeg", "image/png", "image/gif":
-			src, ok := args.Src.(io.ReadSeeker)
-			if !ok {
-				// Seek出来ないと困るので全読み込み
-				b, err := ioutil.ReadAll(args.Src)
-				if err != nil {
-					return nil, fmt.Errorf("failed to read whole src stream: %w", err)
-				}
-				src = bytes.NewReader(b)
-				args.Src = src
-			}
-
 			thumb, err := m.ip.Thumbnail(src)
 			if err == nil {
-				args.Thumbnail = thumb
+				// 原本に向き情報が残っている場合はサムネイルにも適用する
+				args.Thumbnail = imaging2.ApplyOrientation(thumb, orientation)
 			} else {
 				m.l.Warn("failed to generate thumbnail", zap.Error(err), zap.Stringer("fid", f.ID))
 			}
@@ -77,30 +111,24 @@ func (m *managerImpl) Save(args SaveArgs) (model.File, error) {
 		}
 	}
 
+	// ハッシュ計算
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), src); err != nil {
+		return nil, fmt.Errorf("failed to read src stream: %w", err)
+	}
+	f.Hash = hex.EncodeToString(md5Hash.Sum(nil))
+	contentHash := hex.EncodeToString(sha256Hash.Sum(nil))
+
 	if args.Thumbnail != nil {
 		f.HasThumbnail = true
 		f.ThumbnailMime = optional.StringFrom("image/png")
 		f.ThumbnailWidth = args.Thumbnail.Bounds().Size().X
 		f.ThumbnailHeight = args.Thumbnail.Bounds().Size().Y
-
-		r, w := io.Pipe()
-		go func() {
-			defer w.Close()
-			_ = png.Encode(w, args.Thumbnail)
-		}()
-
-		key := f.ID.String() + "-thumb"
-		if err := m.fs.SaveByKey(r, key, key+".png", "image/png", model.FileTypeThumbnail); err != nil {
-			return nil, fmt.Errorf("failed to save thumbnail to storage: %w", err)
+		if err := m.saveThumbnail(f.ID, args.Thumbnail); err != nil {
+			return nil, err
 		}
 	}
 
-	hash := md5.New()
-	if err := m.fs.SaveByKey(io.TeeReader(args.Src, hash), f.ID.String(), f.Name, f.Mime, f.Type); err != nil {
-		return nil, fmt.Errorf("failed to save file to storage: %w", err)
-	}
-	f.Hash = hex.EncodeToString(hash.Sum(nil))
-
 	var acl []*model.FileACLEntry
 	for uid, allow := range args.ACL {
 		acl = append(acl, &model.FileACLEntry{
@@ -109,21 +137,209 @@ func (m *managerImpl) Save(args SaveArgs) (model.File, error) {
 		})
 	}
 
-	err := m.repo.SaveFileMeta(f, acl)
-	if err != nil {
-		if err := m.fs.DeleteByKey(f.ID.String(), f.Type); err != nil {
-			m.l.Warn("failed to delete file from storage during rollback", zap.Error(err), zap.Stringer("fid", f.ID))
-		}
+	if err := m.saveMetaWithObject(f, acl, contentHash, src); err != nil {
 		if f.HasThumbnail {
 			if err := m.fs.DeleteByKey(f.ID.String()+"-thumb", model.FileTypeThumbnail); err != nil {
 				m.l.Warn("failed to delete thumbnail from storage during rollback", zap.Error(err), zap.Stringer("fid", f.ID))
 			}
 		}
-		return nil, fmt.Errorf("failed to SaveFileMeta: %w", err)
+		return nil, err
+	}
+
+	// 動画・音声ファイルのメディア情報の取得・サムネイル生成は非同期に行う
+	if m.mq != nil && isMediaMIMEType(f.Mime) {
+		meta := *f
+		select {
+		case m.mq <- &meta:
+		default:
+			m.l.Warn("media processing queue is full", zap.Stringer("fid", f.ID))
+		}
 	}
 	return m.makeFileMeta(f), nil
 }
 
+// sanitize 画像ファイルのメタデータを除去し、向きを正規化したストリームを返します
+//
+// MIMEタイプが画像で、サイズがSanitizeConfig.MaxSize以下のファイルのみが対象です。
+// 除去を行った場合はfのサイズを更新します。画像が不正で除去できない場合はErrInvalidImageを返します。
+// 返り値のorientationは、サムネイル生成時に追加で適用すべき向き情報です。
+func (m *managerImpl) sanitize(f *model.FileMeta, src io.ReadSeeker) (_ io.ReadSeeker, orientation int, err error) {
+	// 画像以外や巨大なファイルを全てメモリに読み込まないようにする
+	if !strings.HasPrefix(f.Mime, "image/") || (m.sc.MaxSize > 0 && f.Size > m.sc.MaxSize) {
+		return src, 1, nil
+	}
+
+	head := make([]byte, 12)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, 0, fmt.Errorf("failed to read src stream: %w", err)
+	}
+	if _, err := src.Seek(0, 0); err != nil {
+		return nil, 0, fmt.Errorf("failed to seek src stream: %w", err)
+	}
+	format := m.sc.sanitizeTarget(head[:n])
+	if len(format) == 0 {
+		return src, 1, nil
+	}
+
+	r := io.Reader(src)
+	if m.sc.MaxSize > 0 {
+		r = io.LimitReader(src, m.sc.MaxSize+1)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read src stream: %w", err)
+	}
+	if m.sc.MaxSize > 0 && int64(len(b)) > m.sc.MaxSize {
+		// 宣言されたサイズより実際のデータが大きい
+		if _, err := src.Seek(0, 0); err != nil {
+			return nil, 0, fmt.Errorf("failed to seek src stream: %w", err)
+		}
+		return src, 1, nil
+	}
+	// メタデータを除去できない画像はそのまま保存せずに拒否する
+	res, err := sanitizeImage(m.sc, format, b)
+	if err != nil {
+		if err == imaging2.ErrInvalidImageSrc {
+			return nil, 0, ErrInvalidImage
+		}
+		return nil, 0, fmt.Errorf("failed to sanitize image: %w", err)
+	}
+	f.Size = int64(len(res.Data))
+
+	// JPEGのサムネイル生成ではEXIFの向き情報が適用されるので、それ以外の場合のみ返す
+	if format == "jpeg" {
+		return bytes.NewReader(res.Data), 1, nil
+	}
+	return bytes.NewReader(res.Data), res.Orientation, nil
+}
+
+// saveThumbnail サムネイル画像をPNGでストレージに保存します
+func (m *managerImpl) saveThumbnail(fileID uuid.UUID, img image.Image) error {
+	r, w := io.Pipe()
+	go func() {
+		defer w.Close()
+		_ = png.Encode(w, img)
+	}()
+
+	key := fileID.String() + "-thumb"
+	if err := m.fs.SaveByKey(r, key, key+".png", "image/png", model.FileTypeThumbnail); err != nil {
+		return fmt.Errorf("failed to save thumbnail to storage: %w", err)
+	}
+	return nil
+}
+
+// processMedia 動画・音声ファイルのメディア情報を取得し、サムネイルが無い場合は生成して保存します
+func (m *managerImpl) processMedia(f *model.FileMeta) {
+	src, err := m.fs.OpenFileByKey(f.StorageKey(), f.Type)
+	if err != nil {
+		m.l.Warn("failed to open file for media processing", zap.Error(err), zap.Stringer("fid", f.ID))
+		return
+	}
+	res, err := m.mp.Process(src)
+	src.Close()
+	if err != nil {
+		if err != media.ErrUnavailable {
+			m.l.Warn("failed to process media", zap.Error(err), zap.Stringer("fid", f.ID))
+		}
+		return
+	}
+
+	args := repository.UpdateFileMediaArgs{
+		Width:    res.Info.Width,
+		Height:   res.Info.Height,
+		Duration: res.Info.Duration,
+		Codec:    res.Info.Codec,
+	}
+	if !f.HasThumbnail && res.Thumbnail != nil {
+		if err := m.saveThumbnail(f.ID, res.Thumbnail); err != nil {
+			m.l.Warn("failed to save media thumbnail", zap.Error(err), zap.Stringer("fid", f.ID))
+		} else {
+			args.ThumbnailMime = optional.StringFrom("image/png")
+			args.ThumbnailWidth = res.Thumbnail.Bounds().Size().X
+			args.ThumbnailHeight = res.Thumbnail.Bounds().Size().Y
+		}
+	}
+	if err := m.repo.UpdateFileMediaInfo(f.ID, args); err != nil {
+		m.l.Warn("failed to UpdateFileMediaInfo", zap.Error(err), zap.Stringer("fid", f.ID))
+		if args.ThumbnailMime.Valid {
+			if err := m.fs.DeleteByKey(f.ID.String()+"-thumb", model.FileTypeThumbnail); err != nil {
+				m.l.Warn("failed to delete thumbnail from storage during rollback", zap.Error(err), zap.Stringer("fid", f.ID))
+			}
+		}
+	}
+}
+
+func isMediaMIMEType(mime string) bool {
+	return strings.HasPrefix(mime, "video/") || strings.HasPrefix(mime, "audio/")
+}
+
+// saveMetaWithObject ファイルメタを保存します
+//
+// 同一内容・同一タイプのファイル実体が既に存在する場合はそれを共有し、存在しない場合はsrcをストレージに保存します。
+// ストレージには中立なファイル名・MIMEタイプで保存し、配信時にファイルメタのものを設定します。
+func (m *managerImpl) saveMetaWithObject(f *model.FileMeta, acl []*model.FileACLEntry, hash string, src io.ReadSeeker) error {
+	for retried := false; ; retried = true {
+		obj, err := m.repo.GetFileObjectByHash(f.Type, hash)
+		switch err {
+		case nil:
+			// 既存の実体を共有
+			f.ObjectID = obj.ID
+			err := m.repo.SaveFileMeta(f, acl)
+			if err == nil {
+				return nil
+			}
+			if err != repository.ErrNotFound {
+				return fmt.Errorf("failed to SaveFileMeta: %w", err)
+			}
+			// 実体が直前に削除されたので新たに保存する
+		case repository.ErrNotFound:
+		default:
+			return fmt.Errorf("failed to GetFileObjectByHash: %w", err)
+		}
+
+		// 実体を新たに保存
+		if _, err := src.Seek(0, 0); err != nil {
+			return fmt.Errorf("failed to seek src stream: %w", err)
+		}
+		obj = &model.FileObject{
+			ID:   f.ID,
+			Hash: optional.StringFrom(hash),
+			Type: f.Type,
+			Size: f.Size,
+		}
+		// 実体は複数のファイルメタで共有されるので、ファイル名・MIMEタイプはアップロード者のものを使わない
+		if err := m.fs.SaveByKey(src, obj.ID.String(), obj.ID.String(), "application/octet-stream", f.Type); err != nil {
+			return fmt.Errorf("failed to save file to storage: %w", err)
+		}
+		if err := m.repo.CreateFileObject(obj); err != nil {
+			m.deleteObjectFromStorage(obj.ID, obj.Type)
+			if err == repository.ErrAlreadyExists && !retried {
+				// 同一内容のファイルが同時に保存されたので、そちらを共有する
+				continue
+			}
+			return fmt.Errorf("failed to CreateFileObject: %w", err)
+		}
+
+		f.ObjectID = obj.ID
+		if err := m.repo.SaveFileMeta(f, acl); err != nil {
+			if err := m.repo.DeleteFileObject(obj.ID); err != nil {
+				m.l.Warn("failed to delete file object during rollback", zap.Error(err), zap.Stringer("oid", obj.ID))
+			}
+			m.deleteObjectFromStorage(obj.ID, obj.Type)
+			return fmt.Errorf("failed to SaveFileMeta: %w", err)
+		}
+		return nil
+	}
+}
+
+// deleteObjectFromStorage ストレージからファイル実体を削除します
+func (m *managerImpl) deleteObjectFromStorage(objectID uuid.UUID, fileType model.FileType) {
+	if err := m.fs.DeleteByKey(objectID.String(), fileType); err != nil {
+		m.l.Warn("failed to delete file from storage", zap.Error(err), zap.Stringer("oid", objectID))
+	}
+}
+
 func (m *managerImpl) Get(id uuid.UUID) (model.File, error) {
 	meta, err := m.repo.GetFileMeta(id)
 	if err != nil {
@@ -155,8 +371,16 @@ func (m *managerImpl) Delete(id uuid.UUID) error {
 	if err := m.repo.DeleteFileMeta(id); err != nil {
 		return fmt.Errorf("failed to DeleteFileMeta: %w", err)
 	}
-	if err := m.fs.DeleteByKey(meta.ID.String(), meta.Type); err != nil {
-		m.l.Warn("failed to delete file from storage", zap.Error(err), zap.Stringer("fid", meta.ID))
+
+	// 他のファイルから参照されなくなった実体をストレージから削除
+	objectID := meta.ObjectID
+	if objectID == uuid.Nil {
+		objectID = meta.ID
+	}
+	if _, err := m.repo.GetFileObject(objectID); err == repository.ErrNotFound {
+		m.deleteObjectFromStorage(objectID, meta.Type)
+	} else if err != nil {
+		m.l.Warn("failed to GetFileObject", zap.Error(err), zap.Stringer("oid", objectID))
 	}
 	if meta.HasThumbnail {
 		if err := m.fs.DeleteByKey(meta.ID.String()+"-thumb", model.FileTypeThumbnail); err != nil {
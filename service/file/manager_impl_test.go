@@ -11,11 +11,15 @@ import (
 	"github.com/traPtitech/traQ/repository/mock_repository"
 	"github.com/traPtitech/traQ/service/imaging"
 	"github.com/traPtitech/traQ/service/imaging/mock_imaging"
+	"github.com/traPtitech/traQ/service/media"
+	"github.com/traPtitech/traQ/service/media/mock_media"
 	imaging2 "github.com/traPtitech/traQ/utils/imaging"
+	media2 "github.com/traPtitech/traQ/utils/media"
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/storage"
 	"github.com/traPtitech/traQ/utils/storage/mock_storage"
 	"go.uber.org/zap"
+	"image"
 	"image/png"
 	"io"
 	"io/ioutil"
@@ -56,12 +60,22 @@ func TestManagerImpl_Save(t *testing.T) {
 		}
 
 		fs.EXPECT().
-			SaveByKey(gomock.Any(), gomock.Any(), args.FileName, args.MimeType, args.FileType).
+			SaveByKey(gomock.Any(), gomock.Any(), gomock.Any(), "application/octet-stream", args.FileType).
 			DoAndReturn(func(src io.Reader, key, name, contentType string, fileType model.FileType) error {
+				// アップロード者のファイル名は実体に保存されない
+				assert.Equal(t, key, name)
 				_, _ = io.Copy(ioutil.Discard, src)
 				return nil
 			}).
 			Times(1)
+		repo.EXPECT().
+			GetFileObjectByHash(args.FileType, gomock.Any()).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+		repo.EXPECT().
+			CreateFileObject(gomock.Any()).
+			Return(nil).
+			Times(1)
 		repo.EXPECT().
 			SaveFileMeta(gomock.Any(), []*model.FileACLEntry{{UserID: optional.UUIDFrom(uuid.Nil), Allow: optional.BoolFrom(true)}}).
 			DoAndReturn(func(meta *model.FileMeta, acl []*model.FileACLEntry) error {
@@ -85,6 +99,57 @@ func TestManagerImpl_Save(t *testing.T) {
 		}
 	})
 
+	t.Run("media file", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := mock_storage.NewMockFileStorage(ctrl)
+		fm := initFM(t, repo, fs, nil)
+		fm.mq = make(chan *model.FileMeta, 1)
+
+		data := []byte("test video file")
+		save := func() (model.File, error) {
+			return fm.Save(SaveArgs{
+				FileName: "test.mp4",
+				FileSize: int64(len(data)),
+				MimeType: "video/mp4",
+				FileType: model.FileTypeUserFile,
+				Src:      bytes.NewReader(data),
+			})
+		}
+
+		fs.EXPECT().
+			SaveByKey(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), model.FileTypeUserFile).
+			DoAndReturn(func(src io.Reader, key, name, contentType string, fileType model.FileType) error {
+				_, _ = io.Copy(ioutil.Discard, src)
+				return nil
+			}).
+			Times(2)
+		repo.EXPECT().
+			GetFileObjectByHash(model.FileTypeUserFile, gomock.Any()).
+			Return(nil, repository.ErrNotFound).
+			Times(2)
+		repo.EXPECT().
+			CreateFileObject(gomock.Any()).
+			Return(nil).
+			Times(2)
+		repo.EXPECT().
+			SaveFileMeta(gomock.Any(), gomock.Any()).
+			Return(nil).
+			Times(2)
+
+		result, err := save()
+		if assert.NoError(t, err) && assert.Len(t, fm.mq, 1) {
+			assert.Equal(t, result.GetID(), (<-fm.mq).ID)
+		}
+
+		// キューが一杯の場合は処理を諦め、アップロード自体は成功する
+		fm.mq <- &model.FileMeta{}
+		_, err = save()
+		assert.NoError(t, err)
+		assert.Len(t, fm.mq, 1)
+	})
+
 	t.Run("file with thumbnail", func(t *testing.T) {
 		t.Parallel()
 		ctrl := gomock.NewController(t)
@@ -106,7 +171,7 @@ func TestManagerImpl_Save(t *testing.T) {
 		}
 
 		fs.EXPECT().
-			SaveByKey(gomock.Any(), gomock.Any(), args.FileName, args.MimeType, args.FileType).
+			SaveByKey(gomock.Any(), gomock.Any(), gomock.Any(), "application/octet-stream", args.FileType).
 			DoAndReturn(func(src io.Reader, key, name, contentType string, fileType model.FileType) error {
 				_, _ = io.Copy(ioutil.Discard, src)
 				return nil
@@ -119,6 +184,14 @@ func TestManagerImpl_Save(t *testing.T) {
 				return err
 			}).
 			Times(1)
+		repo.EXPECT().
+			GetFileObjectByHash(args.FileType, gomock.Any()).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+		repo.EXPECT().
+			CreateFileObject(gomock.Any()).
+			Return(nil).
+			Times(1)
 		repo.EXPECT().
 			SaveFileMeta(gomock.Any(), []*model.FileACLEntry{{UserID: optional.UUIDFrom(uuid.Nil), Allow: optional.BoolFrom(true)}}).
 			DoAndReturn(func(meta *model.FileMeta, acl []*model.FileACLEntry) error {
@@ -166,7 +239,7 @@ func TestManagerImpl_Save(t *testing.T) {
 		}
 
 		fs.EXPECT().
-			SaveByKey(gomock.Any(), gomock.Any(), args.FileName, args.MimeType, args.FileType).
+			SaveByKey(gomock.Any(), gomock.Any(), gomock.Any(), "application/octet-stream", args.FileType).
 			Do(func(src io.Reader, key, name, contentType string, fileType model.FileType) {
 				_, _ = io.Copy(ioutil.Discard, src)
 			}).
@@ -179,6 +252,14 @@ func TestManagerImpl_Save(t *testing.T) {
 				return err
 			}).
 			Times(1)
+		repo.EXPECT().
+			GetFileObjectByHash(args.FileType, gomock.Any()).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+		repo.EXPECT().
+			CreateFileObject(gomock.Any()).
+			Return(nil).
+			Times(1)
 		repo.EXPECT().
 			SaveFileMeta(gomock.Any(), []*model.FileACLEntry{{UserID: optional.UUIDFrom(uuid.Nil), Allow: optional.BoolFrom(true)}}).
 			Do(func(meta *model.FileMeta, acl []*model.FileACLEntry) { meta.CreatedAt = time.Now() }).
@@ -229,7 +310,7 @@ func TestManagerImpl_Save(t *testing.T) {
 		}
 
 		fs.EXPECT().
-			SaveByKey(gomock.Any(), gomock.Any(), args.FileName, args.MimeType, args.FileType).
+			SaveByKey(gomock.Any(), gomock.Any(), gomock.Any(), "application/octet-stream", args.FileType).
 			Do(func(src io.Reader, key, name, contentType string, fileType model.FileType) {
 				_, _ = io.Copy(ioutil.Discard, src)
 			}).
@@ -242,6 +323,14 @@ func TestManagerImpl_Save(t *testing.T) {
 				return err
 			}).
 			Times(1)
+		repo.EXPECT().
+			GetFileObjectByHash(args.FileType, gomock.Any()).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+		repo.EXPECT().
+			CreateFileObject(gomock.Any()).
+			Return(nil).
+			Times(1)
 		repo.EXPECT().
 			SaveFileMeta(gomock.Any(), []*model.FileACLEntry{{UserID: optional.UUIDFrom(uuid.Nil), Allow: optional.BoolFrom(true)}}).
 			Do(func(meta *model.FileMeta, acl []*model.FileACLEntry) { meta.CreatedAt = time.Now() }).
@@ -270,6 +359,247 @@ func TestManagerImpl_Save(t *testing.T) {
 			assert.EqualValues(t, thumb.Bounds().Size().Y, result.GetThumbnailHeight())
 		}
 	})
+
+	t.Run("same content", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := mock_storage.NewMockFileStorage(ctrl)
+		fm := initFM(t, repo, fs, nil)
+
+		data := []byte("test text file")
+		sha256Hash := "02cbbe1fb31609fc4928de008c1710212d41c1fb688e3c3b19071cd9fc10df70"
+		obj := &model.FileObject{
+			ID:       uuid.NewV3(uuid.Nil, "o1"),
+			Hash:     optional.StringFrom(sha256Hash),
+			Type:     model.FileTypeUserFile,
+			Size:     int64(len(data)),
+			RefCount: 1,
+		}
+		args := SaveArgs{
+			FileName:  "test.txt",
+			FileSize:  int64(len(data)),
+			MimeType:  "text/plain",
+			FileType:  model.FileTypeUserFile,
+			ChannelID: optional.UUIDFrom(uuid.NewV3(uuid.Nil, "c")),
+			Src:       bytes.NewReader(data),
+		}
+
+		repo.EXPECT().
+			GetFileObjectByHash(args.FileType, sha256Hash).
+			Return(obj, nil).
+			Times(1)
+		repo.EXPECT().
+			SaveFileMeta(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(meta *model.FileMeta, acl []*model.FileACLEntry) error {
+				assert.Equal(t, obj.ID, meta.ObjectID)
+				meta.CreatedAt = time.Now()
+				return nil
+			}).
+			Times(1)
+		fs.EXPECT().
+			OpenFileByKey(obj.ID.String(), args.FileType).
+			Return(nil, nil).
+			Times(1)
+
+		result, err := fm.Save(args)
+		if assert.NoError(t, err) {
+			assert.NotEqual(t, obj.ID, result.GetID())
+			_, err := result.Open()
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("same content (object deleted)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := mock_storage.NewMockFileStorage(ctrl)
+		fm := initFM(t, repo, fs, nil)
+
+		data := []byte("test text file")
+		obj := &model.FileObject{ID: uuid.NewV3(uuid.Nil, "o1"), Type: model.FileTypeUserFile}
+		args := SaveArgs{
+			FileName:  "test.txt",
+			FileSize:  int64(len(data)),
+			MimeType:  "text/plain",
+			FileType:  model.FileTypeUserFile,
+			ChannelID: optional.UUIDFrom(uuid.NewV3(uuid.Nil, "c")),
+			Src:       bytes.NewReader(data),
+		}
+
+		repo.EXPECT().
+			GetFileObjectByHash(args.FileType, gomock.Any()).
+			Return(obj, nil).
+			Times(1)
+		gomock.InOrder(
+			repo.EXPECT().
+				SaveFileMeta(gomock.Any(), gomock.Any()).
+				Return(repository.ErrNotFound).
+				Times(1),
+			repo.EXPECT().
+				SaveFileMeta(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(meta *model.FileMeta, acl []*model.FileACLEntry) error {
+					assert.Equal(t, meta.ID, meta.ObjectID)
+					return nil
+				}).
+				Times(1),
+		)
+		repo.EXPECT().
+			CreateFileObject(gomock.Any()).
+			Return(nil).
+			Times(1)
+		fs.EXPECT().
+			SaveByKey(gomock.Any(), gomock.Any(), gomock.Any(), "application/octet-stream", args.FileType).
+			DoAndReturn(func(src io.Reader, key, name, contentType string, fileType model.FileType) error {
+				b, _ := ioutil.ReadAll(src)
+				assert.Equal(t, data, b)
+				return nil
+			}).
+			Times(1)
+
+		_, err := fm.Save(args)
+		assert.NoError(t, err)
+	})
+
+	t.Run("sanitize image", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := storage.NewInMemoryFileStorage()
+		fm := initFM(t, repo, fs, imaging.NewProcessor(imaging.Config{
+			MaxPixels:        1000 * 1000,
+			Concurrency:      1,
+			ThumbnailMaxSize: image.Pt(360, 480),
+		}))
+		fm.sc = SanitizeConfig{PNG: true, MaxPixels: 1000 * 1000}
+
+		var buf bytes.Buffer
+		_ = png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 20)))
+		data := imaging2.InsertPNGOrientation(buf.Bytes(), 6)
+		args := SaveArgs{
+			FileName:  "photo.png",
+			FileSize:  int64(len(data)),
+			MimeType:  "image/png",
+			FileType:  model.FileTypeUserFile,
+			ChannelID: optional.UUIDFrom(uuid.NewV3(uuid.Nil, "c")),
+			Src:       bytes.NewReader(data),
+		}
+
+		repo.EXPECT().
+			GetFileObjectByHash(args.FileType, gomock.Any()).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+		repo.EXPECT().
+			CreateFileObject(gomock.Any()).
+			Return(nil).
+			Times(1)
+		repo.EXPECT().
+			SaveFileMeta(gomock.Any(), gomock.Any()).
+			Return(nil).
+			Times(1)
+
+		result, err := fm.Save(args)
+		if assert.NoError(t, err) {
+			r, err := result.Open()
+			if assert.NoError(t, err) {
+				b, _ := ioutil.ReadAll(r)
+				r.Close()
+				assert.NotContains(t, string(b), "eXIf")
+				assert.EqualValues(t, len(b), result.GetFileSize())
+				img, err := png.Decode(bytes.NewReader(b))
+				if assert.NoError(t, err) {
+					assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+				}
+			}
+			assert.True(t, result.HasThumbnail())
+			assert.Equal(t, 20, result.GetThumbnailWidth())
+			assert.Equal(t, 40, result.GetThumbnailHeight())
+		}
+	})
+
+	t.Run("not sanitized", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		_ = png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 20)))
+		data := imaging2.InsertPNGOrientation(buf.Bytes(), 6)
+
+		cases := []struct {
+			name    string
+			mime    string
+			maxSize int64
+		}{
+			{"not image mime type", "application/octet-stream", 0},
+			{"too large", "image/png", int64(len(data) - 1)},
+		}
+		for _, c := range cases {
+			c := c
+			t.Run(c.name, func(t *testing.T) {
+				t.Parallel()
+				ctrl := gomock.NewController(t)
+				repo := mock_repository.NewMockFileRepository(ctrl)
+				fs := storage.NewInMemoryFileStorage()
+				fm := initFM(t, repo, fs, nil)
+				fm.sc = SanitizeConfig{PNG: true, MaxSize: c.maxSize}
+
+				repo.EXPECT().
+					GetFileObjectByHash(model.FileTypeUserFile, gomock.Any()).
+					Return(nil, repository.ErrNotFound).
+					Times(1)
+				repo.EXPECT().
+					CreateFileObject(gomock.Any()).
+					Return(nil).
+					Times(1)
+				repo.EXPECT().
+					SaveFileMeta(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				result, err := fm.Save(SaveArgs{
+					FileName:                "photo.png",
+					FileSize:                int64(len(data)),
+					MimeType:                c.mime,
+					FileType:                model.FileTypeUserFile,
+					Src:                     bytes.NewReader(data),
+					SkipThumbnailGeneration: true,
+				})
+				if assert.NoError(t, err) {
+					r, err := result.Open()
+					if assert.NoError(t, err) {
+						b, _ := ioutil.ReadAll(r)
+						r.Close()
+						assert.Equal(t, data, b)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("broken image", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := mock_storage.NewMockFileStorage(ctrl)
+		fm := initFM(t, repo, fs, nil)
+		fm.sc = SanitizeConfig{JPEG: true}
+
+		// APP1(EXIF)セグメントの長さがデータ長を超えているJPEG
+		data := []byte{0xff, 0xd8, 0xff, 0xe1, 0x10, 0x00}
+		data = append(data, "Exif\x00\x00GPS"...)
+		args := SaveArgs{
+			FileName:  "photo.jpg",
+			FileSize:  int64(len(data)),
+			MimeType:  "image/jpeg",
+			FileType:  model.FileTypeUserFile,
+			ChannelID: optional.UUIDFrom(uuid.NewV3(uuid.Nil, "c")),
+			Src:       bytes.NewReader(data),
+		}
+
+		// ストレージ・リポジトリには何も保存されない
+		_, err := fm.Save(args)
+		assert.Equal(t, ErrInvalidImage, err)
+	})
 }
 
 func TestManagerImpl_Get(t *testing.T) {
@@ -419,6 +749,10 @@ func TestManagerImpl_Delete(t *testing.T) {
 			DeleteFileMeta(meta.ID).
 			Return(nil).
 			Times(1)
+		repo.EXPECT().
+			GetFileObject(meta.ID).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
 		fs.EXPECT().
 			DeleteByKey(meta.ID.String(), meta.Type).
 			Return(nil).
@@ -457,6 +791,10 @@ func TestManagerImpl_Delete(t *testing.T) {
 			DeleteFileMeta(meta.ID).
 			Return(nil).
 			Times(1)
+		repo.EXPECT().
+			GetFileObject(meta.ID).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
 		fs.EXPECT().
 			DeleteByKey(meta.ID.String(), meta.Type).
 			Return(nil).
@@ -465,6 +803,40 @@ func TestManagerImpl_Delete(t *testing.T) {
 		assert.NoError(t, fm.Delete(meta.ID))
 	})
 
+	t.Run("success (shared object)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := mock_storage.NewMockFileStorage(ctrl)
+		fm := initFM(t, repo, fs, nil)
+
+		meta := &model.FileMeta{
+			ID:        uuid.NewV3(uuid.Nil, "f1"),
+			Name:      "file",
+			Mime:      "text/plain",
+			Size:      10,
+			Hash:      "d41d8cd98f00b204e9800998ecf8427e",
+			Type:      model.FileTypeUserFile,
+			ObjectID:  uuid.NewV3(uuid.Nil, "o1"),
+			CreatedAt: time.Now(),
+		}
+
+		repo.EXPECT().
+			GetFileMeta(meta.ID).
+			Return(meta, nil).
+			Times(1)
+		repo.EXPECT().
+			DeleteFileMeta(meta.ID).
+			Return(nil).
+			Times(1)
+		repo.EXPECT().
+			GetFileObject(meta.ObjectID).
+			Return(&model.FileObject{ID: meta.ObjectID, RefCount: 1}, nil).
+			Times(1)
+
+		assert.NoError(t, fm.Delete(meta.ID))
+	})
+
 	t.Run("not found", func(t *testing.T) {
 		t.Parallel()
 		ctrl := gomock.NewController(t)
@@ -529,6 +901,141 @@ func TestManagerImpl_Delete(t *testing.T) {
 	})
 }
 
+func TestManagerImpl_processMedia(t *testing.T) {
+	t.Parallel()
+
+	newFile := func(t *testing.T, fs storage.FileStorage, mime string) *model.FileMeta {
+		t.Helper()
+		f := &model.FileMeta{
+			ID:       uuid.Must(uuid.NewV4()),
+			Name:     "test",
+			Mime:     mime,
+			Type:     model.FileTypeUserFile,
+			ObjectID: uuid.Must(uuid.NewV4()),
+		}
+		if err := fs.SaveByKey(bytes.NewBufferString("media"), f.StorageKey(), f.Name, f.Mime, f.Type); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	t.Run("video", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := storage.NewInMemoryFileStorage()
+		mp := mock_media.NewMockProcessor(ctrl)
+		fm := initFM(t, repo, fs, nil)
+		fm.mp = mp
+
+		f := newFile(t, fs, "video/mp4")
+		thumb := imaging2.GenerateIcon("test")
+		info := media2.Info{Duration: 1500 * time.Millisecond, Width: 1920, Height: 1080, Codec: "h264", HasVideo: true}
+
+		mp.EXPECT().
+			Process(gomock.Any()).
+			DoAndReturn(func(src io.Reader) (*media.Result, error) {
+				b, _ := ioutil.ReadAll(src)
+				assert.Equal(t, "media", string(b))
+				return &media.Result{Info: info, Thumbnail: thumb}, nil
+			}).
+			Times(1)
+		repo.EXPECT().
+			UpdateFileMediaInfo(f.ID, repository.UpdateFileMediaArgs{
+				Width:           1920,
+				Height:          1080,
+				Duration:        1500 * time.Millisecond,
+				Codec:           "h264",
+				ThumbnailMime:   optional.StringFrom("image/png"),
+				ThumbnailWidth:  thumb.Bounds().Size().X,
+				ThumbnailHeight: thumb.Bounds().Size().Y,
+			}).
+			Return(nil).
+			Times(1)
+
+		fm.processMedia(f)
+
+		r, err := fs.OpenFileByKey(f.ID.String()+"-thumb", model.FileTypeThumbnail)
+		if assert.NoError(t, err) {
+			_, err := png.Decode(r)
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("audio (already has thumbnail)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := storage.NewInMemoryFileStorage()
+		mp := mock_media.NewMockProcessor(ctrl)
+		fm := initFM(t, repo, fs, nil)
+		fm.mp = mp
+
+		f := newFile(t, fs, "audio/mpeg")
+		f.HasThumbnail = true
+
+		mp.EXPECT().
+			Process(gomock.Any()).
+			Return(&media.Result{Info: media2.Info{Duration: time.Minute, Codec: "mp3", HasAudio: true}, Thumbnail: imaging2.GenerateIcon("test")}, nil).
+			Times(1)
+		repo.EXPECT().
+			UpdateFileMediaInfo(f.ID, repository.UpdateFileMediaArgs{Duration: time.Minute, Codec: "mp3"}).
+			Return(nil).
+			Times(1)
+
+		fm.processMedia(f)
+
+		_, err := fs.OpenFileByKey(f.ID.String()+"-thumb", model.FileTypeThumbnail)
+		assert.Equal(t, storage.ErrFileNotFound, err)
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := storage.NewInMemoryFileStorage()
+		mp := mock_media.NewMockProcessor(ctrl)
+		fm := initFM(t, repo, fs, nil)
+		fm.mp = mp
+
+		f := newFile(t, fs, "video/webm")
+
+		mp.EXPECT().
+			Process(gomock.Any()).
+			Return(nil, media.ErrUnavailable).
+			Times(1)
+
+		fm.processMedia(f)
+	})
+
+	t.Run("repo error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fs := storage.NewInMemoryFileStorage()
+		mp := mock_media.NewMockProcessor(ctrl)
+		fm := initFM(t, repo, fs, nil)
+		fm.mp = mp
+
+		f := newFile(t, fs, "video/mp4")
+
+		mp.EXPECT().
+			Process(gomock.Any()).
+			Return(&media.Result{Info: media2.Info{HasVideo: true}, Thumbnail: imaging2.GenerateIcon("test")}, nil).
+			Times(1)
+		repo.EXPECT().
+			UpdateFileMediaInfo(f.ID, gomock.Any()).
+			Return(errMock).
+			Times(1)
+
+		fm.processMedia(f)
+
+		// サムネイルは削除される
+		_, err := fs.OpenFileByKey(f.ID.String()+"-thumb", model.FileTypeThumbnail)
+		assert.Equal(t, storage.ErrFileNotFound, err)
+	})
+}
+
 func TestManagerImpl_Accessible(t *testing.T) {
 	t.Parallel()
 
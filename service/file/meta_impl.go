@@ -59,6 +59,22 @@ func (f *fileMetaImpl) GetThumbnailHeight() int {
 	return f.meta.ThumbnailHeight
 }
 
+func (f *fileMetaImpl) GetWidth() int {
+	return f.meta.Width
+}
+
+func (f *fileMetaImpl) GetHeight() int {
+	return f.meta.Height
+}
+
+func (f *fileMetaImpl) GetDuration() time.Duration {
+	return time.Duration(f.meta.DurationMS) * time.Millisecond
+}
+
+func (f *fileMetaImpl) GetCodec() string {
+	return f.meta.Codec
+}
+
 func (f *fileMetaImpl) GetUploadChannelID() optional.UUID {
 	return f.meta.ChannelID
 }
@@ -68,7 +84,7 @@ func (f *fileMetaImpl) GetCreatedAt() time.Time {
 }
 
 func (f *fileMetaImpl) Open() (ioext.ReadSeekCloser, error) {
-	return f.fs.OpenFileByKey(f.GetID().String(), f.GetFileType())
+	return f.fs.OpenFileByKey(f.meta.StorageKey(), f.GetFileType())
 }
 
 func (f *fileMetaImpl) OpenThumbnail() (ioext.ReadSeekCloser, error) {
@@ -79,6 +95,6 @@ func (f *fileMetaImpl) OpenThumbnail() (ioext.ReadSeekCloser, error) {
 }
 
 func (f *fileMetaImpl) GetAlternativeURL() string {
-	url, _ := f.fs.GenerateAccessURL(f.GetID().String(), f.GetFileType())
+	url, _ := f.fs.GenerateAccessURL(f.meta.StorageKey(), f.GetFileName(), f.GetFileType())
 	return url
 }
@@ -0,0 +1,171 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"github.com/traPtitech/traQ/utils/imaging"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// SanitizeConfig アップロードされた画像のメタデータ除去・向き補正設定
+type SanitizeConfig struct {
+	// JPEG JPEG画像のメタデータを除去するかどうか
+	JPEG bool
+	// PNG PNG画像のメタデータを除去するかどうか
+	PNG bool
+	// WebP WebP画像のメタデータを除去するかどうか
+	WebP bool
+	// JPEGQuality 向きの補正のためにJPEG画像を再エンコードする際の品質 (1-100)
+	JPEGQuality int
+	// MaxPixels 向きの補正を行う最大画素数
+	MaxPixels int
+	// MaxSize メタデータを除去する最大ファイルサイズ(byte)
+	//
+	// 0以下の場合は制限しません。これを超える画像はそのまま保存されます。
+	MaxSize int64
+	// ImageMagickPath WebP画像の向きの補正に用いるImageMagickの実行パス
+	ImageMagickPath string
+}
+
+// sanitizeResult 画像のメタデータ除去結果
+type sanitizeResult struct {
+	// Data メタデータ除去後の画像データ
+	Data []byte
+	// Orientation 画素を回転できなかったために残した向き情報 (1の場合は補正済み)
+	Orientation int
+}
+
+// sanitizeTarget 先頭12バイトから、メタデータ除去の対象となる画像フォーマットを判別します
+//
+// MIMEタイプではなく内容から判別します。対象外の場合は空文字列を返します。
+func (c SanitizeConfig) sanitizeTarget(head []byte) string {
+	switch {
+	case c.JPEG && bytes.HasPrefix(head, []byte{0xff, 0xd8, 0xff}):
+		return "jpeg"
+	case c.PNG && bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case c.WebP && len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return "webp"
+	}
+	return ""
+}
+
+// sanitizeImage 画像からメタデータを除去し、向きを正規化します
+//
+// 画素数の制限等で画素を回転できなかった場合は、向き情報のみを含むEXIFを残します。
+func sanitizeImage(c SanitizeConfig, format string, b []byte) (*sanitizeResult, error) {
+	switch format {
+	case "jpeg":
+		return sanitizeJPEG(c, b)
+	case "png":
+		return sanitizePNG(c, b)
+	case "webp":
+		return sanitizeWebP(c, b)
+	}
+	return nil, imaging.ErrInvalidImageSrc
+}
+
+func sanitizeJPEG(c SanitizeConfig, b []byte) (*sanitizeResult, error) {
+	stripped, orientation, err := imaging.StripJPEGMetadata(b)
+	if err != nil {
+		return nil, err
+	}
+	res := &sanitizeResult{Data: stripped, Orientation: 1}
+	if orientation == 1 {
+		return res, nil
+	}
+
+	img, ok := decodeForOrientation(c, stripped, jpeg.Decode)
+	if !ok {
+		res.Data = imaging.InsertJPEGOrientation(stripped, orientation)
+		res.Orientation = orientation
+		return res, nil
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, imaging.ApplyOrientation(img, orientation), &jpeg.Options{Quality: c.JPEGQuality}); err != nil {
+		return nil, err
+	}
+	// 色の再現に必要なICCプロファイルは引き継ぐ
+	res.Data = imaging.InsertJPEGSegments(buf.Bytes(), imaging.ExtractJPEGICCProfile(stripped))
+	return res, nil
+}
+
+func sanitizePNG(c SanitizeConfig, b []byte) (*sanitizeResult, error) {
+	stripped, orientation, err := imaging.StripPNGMetadata(b)
+	if err != nil {
+		return nil, err
+	}
+	res := &sanitizeResult{Data: stripped, Orientation: 1}
+	if orientation == 1 {
+		return res, nil
+	}
+
+	// APNGは再エンコードするとアニメーションが失われるので回転しない
+	var img image.Image
+	ok := !bytes.Contains(stripped, []byte("acTL"))
+	if ok {
+		img, ok = decodeForOrientation(c, stripped, png.Decode)
+	}
+	if !ok {
+		res.Data = imaging.InsertPNGOrientation(stripped, orientation)
+		res.Orientation = orientation
+		return res, nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, imaging.ApplyOrientation(img, orientation)); err != nil {
+		return nil, err
+	}
+	res.Data = buf.Bytes()
+	return res, nil
+}
+
+func sanitizeWebP(c SanitizeConfig, b []byte) (*sanitizeResult, error) {
+	stripped, orientation, err := imaging.StripWebPMetadata(b)
+	if err != nil {
+		return nil, err
+	}
+	res := &sanitizeResult{Data: stripped, Orientation: 1}
+	if orientation == 1 {
+		return res, nil
+	}
+
+	// GoではWebPをエンコードできないので、回転はImageMagickで行う
+	info, err := imaging.ProbeWebP(bytes.NewReader(stripped))
+	if err == nil && info.Frames == 1 && (c.MaxPixels <= 0 || info.Width*info.Height <= c.MaxPixels) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		lossless := bytes.Contains(stripped, []byte("VP8L"))
+		r, err := imaging.AutoOrientWebP(ctx, c.ImageMagickPath, bytes.NewReader(imaging.InsertWebPOrientation(stripped, orientation)), lossless)
+		if err == nil {
+			b, _ := ioutil.ReadAll(r)
+			if rotated, _, err := imaging.StripWebPMetadata(b); err == nil {
+				res.Data = rotated
+				return res, nil
+			}
+		}
+	}
+	res.Data = imaging.InsertWebPOrientation(stripped, orientation)
+	res.Orientation = orientation
+	return res, nil
+}
+
+// decodeForOrientation 向きの補正のために画像をデコードします
+//
+// 画素数が制限を超えている場合やデコードに失敗した場合はfalseを返します。
+func decodeForOrientation(c SanitizeConfig, b []byte, decode func(r io.Reader) (image.Image, error)) (image.Image, bool) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil || (c.MaxPixels > 0 && cfg.Width*cfg.Height > c.MaxPixels) {
+		return nil, false
+	}
+	img, err := decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, false
+	}
+	return img, true
+}
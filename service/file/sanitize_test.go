@@ -0,0 +1,75 @@
+package file
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/utils/imaging"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestSanitizeConfig_sanitizeTarget(t *testing.T) {
+	t.Parallel()
+
+	c := SanitizeConfig{JPEG: true, WebP: true}
+	assert.Equal(t, "jpeg", c.sanitizeTarget([]byte{0xff, 0xd8, 0xff, 0xe0}))
+	assert.Equal(t, "", c.sanitizeTarget([]byte("\x89PNG\r\n\x1a\n")))
+	assert.Equal(t, "webp", c.sanitizeTarget([]byte("RIFF\x00\x00\x00\x00WEBP")))
+	assert.Equal(t, "", c.sanitizeTarget([]byte("RIFF\x00\x00\x00\x00WAVE")))
+	assert.Equal(t, "", c.sanitizeTarget([]byte("text")))
+}
+
+func TestSanitizeImage(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 32, 16)), nil))
+	rawJPEG := buf.Bytes()
+	buf = bytes.Buffer{}
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 32, 16))))
+	rawPNG := buf.Bytes()
+
+	t.Run("jpeg rotate", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assert.New(t), require.New(t)
+
+		res, err := sanitizeImage(SanitizeConfig{JPEG: true, JPEGQuality: 90}, "jpeg", imaging.InsertJPEGOrientation(rawJPEG, 8))
+		require.NoError(err)
+		assert.Equal(1, res.Orientation)
+		assert.NotContains(string(res.Data), "Exif")
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(res.Data))
+		require.NoError(err)
+		assert.Equal(16, cfg.Width)
+		assert.Equal(32, cfg.Height)
+	})
+
+	t.Run("jpeg exceeds max pixels", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assert.New(t), require.New(t)
+
+		res, err := sanitizeImage(SanitizeConfig{JPEG: true, MaxPixels: 100}, "jpeg", imaging.InsertJPEGOrientation(rawJPEG, 6))
+		require.NoError(err)
+		assert.Equal(6, res.Orientation)
+		assert.Equal(imaging.InsertJPEGOrientation(rawJPEG, 6), res.Data)
+	})
+
+	t.Run("png without metadata", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := sanitizeImage(SanitizeConfig{PNG: true}, "png", rawPNG)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, res.Orientation)
+			assert.Equal(t, rawPNG, res.Data)
+		}
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := sanitizeImage(SanitizeConfig{PNG: true}, "png", rawPNG[:20])
+		assert.Error(t, err)
+	})
+}
@@ -8,6 +8,7 @@ import (
 var (
 	ErrPixelLimitExceeded = errors.New("the image exceeds max pixels limit")
 	ErrInvalidImageSrc    = errors.New("invalid image src")
+	ErrFrameLimitExceeded = errors.New("the image exceeds max frames limit")
 	ErrTimeout            = errors.New("processing timeout")
 )
 
@@ -15,6 +16,9 @@ type Config struct {
 	// MaxPixels 処理可能な最大画素数
 	// この値を超える画素数の画像を処理しようとした場合、全てエラーになります
 	MaxPixels int
+	// MaxAnimationFrames 処理可能なアニメーション画像(APNG, WebP)の最大フレーム数
+	// 0の場合は無制限です
+	MaxAnimationFrames int
 	// Concurrency 処理並列数
 	Concurrency int
 	// ThumbnailMaxSize サムネイル画像サイズ
@@ -79,3 +79,48 @@ func (mr *MockProcessorMockRecorder) FitAnimationGIF(src, width, height interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FitAnimationGIF", reflect.TypeOf((*MockProcessor)(nil).FitAnimationGIF), src, width, height)
 }
+
+// FitAnimationPNG mocks base method
+func (m *MockProcessor) FitAnimationPNG(src io.Reader, width, height int) (*bytes.Reader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FitAnimationPNG", src, width, height)
+	ret0, _ := ret[0].(*bytes.Reader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FitAnimationPNG indicates an expected call of FitAnimationPNG
+func (mr *MockProcessorMockRecorder) FitAnimationPNG(src, width, height interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FitAnimationPNG", reflect.TypeOf((*MockProcessor)(nil).FitAnimationPNG), src, width, height)
+}
+
+// FitAnimationWebP mocks base method
+func (m *MockProcessor) FitAnimationWebP(src io.Reader, width, height int) (*bytes.Reader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FitAnimationWebP", src, width, height)
+	ret0, _ := ret[0].(*bytes.Reader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FitAnimationWebP indicates an expected call of FitAnimationWebP
+func (mr *MockProcessorMockRecorder) FitAnimationWebP(src, width, height interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FitAnimationWebP", reflect.TypeOf((*MockProcessor)(nil).FitAnimationWebP), src, width, height)
+}
+
+// AnimationThumbnail mocks base method
+func (m *MockProcessor) AnimationThumbnail(src io.Reader) (image.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnimationThumbnail", src)
+	ret0, _ := ret[0].(image.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnimationThumbnail indicates an expected call of AnimationThumbnail
+func (mr *MockProcessorMockRecorder) AnimationThumbnail(src interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnimationThumbnail", reflect.TypeOf((*MockProcessor)(nil).AnimationThumbnail), src)
+}
@@ -11,4 +11,7 @@ type Processor interface {
 	Thumbnail(src io.ReadSeeker) (image.Image, error)
 	Fit(src io.ReadSeeker, width, height int) (image.Image, error)
 	FitAnimationGIF(src io.Reader, width, height int) (*bytes.Reader, error)
+	FitAnimationPNG(src io.Reader, width, height int) (*bytes.Reader, error)
+	FitAnimationWebP(src io.Reader, width, height int) (*bytes.Reader, error)
+	AnimationThumbnail(src io.Reader) (image.Image, error)
 }
@@ -7,7 +7,10 @@ import (
 	imaging2 "github.com/traPtitech/traQ/utils/imaging"
 	"golang.org/x/sync/semaphore"
 	"image"
+	"image/gif"
+	"image/png"
 	"io"
+	"io/ioutil"
 	"time"
 )
 
@@ -78,3 +81,134 @@ func (p *defaultProcessor) FitAnimationGIF(src io.Reader, width, height int) (*b
 	}
 	return b, nil
 }
+
+func (p *defaultProcessor) FitAnimationPNG(src io.Reader, width, height int) (*bytes.Reader, error) {
+	_ = p.sp.Acquire(context.Background(), 1)
+	defer p.sp.Release(1)
+
+	b, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	info, err := imaging2.ProbeAPNG(bytes.NewReader(b))
+	if err != nil {
+		return nil, ErrInvalidImageSrc
+	}
+	if err := p.checkAnimation(info); err != nil {
+		return nil, err
+	}
+
+	r, err := imaging2.ResizeAPNG(bytes.NewReader(b), width, height)
+	if err != nil {
+		if err == imaging2.ErrInvalidImageSrc {
+			return nil, ErrInvalidImageSrc
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func (p *defaultProcessor) FitAnimationWebP(src io.Reader, width, height int) (*bytes.Reader, error) {
+	_ = p.sp.Acquire(context.Background(), 1)
+	defer p.sp.Release(1)
+
+	b, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	info, err := imaging2.ProbeWebP(bytes.NewReader(b))
+	if err != nil {
+		return nil, ErrInvalidImageSrc
+	}
+	if err := p.checkAnimation(info); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // 10秒以内に終わらないファイルは無効
+	defer cancel()
+
+	r, err := imaging2.ResizeAnimationWebP(ctx, p.c.ImageMagickPath, bytes.NewReader(b), width, height, false)
+	if err != nil {
+		switch err {
+		case context.DeadlineExceeded:
+			return nil, ErrTimeout
+		case imaging2.ErrInvalidImageSrc:
+			return nil, ErrInvalidImageSrc
+		default:
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (p *defaultProcessor) AnimationThumbnail(src io.Reader) (image.Image, error) {
+	_ = p.sp.Acquire(context.Background(), 1)
+	defer p.sp.Release(1)
+
+	b, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先頭フレームを静止画として取り出す
+	var first image.Image
+	switch {
+	case bytes.HasPrefix(b, []byte("\x89PNG")):
+		if info, err := imaging2.ProbeAPNG(bytes.NewReader(b)); err != nil {
+			return nil, ErrInvalidImageSrc
+		} else if info.Width*info.Height > p.c.MaxPixels {
+			return nil, ErrPixelLimitExceeded
+		}
+		first, err = imaging2.DecodeAPNGFirstFrame(bytes.NewReader(b))
+	case bytes.HasPrefix(b, []byte("GIF")):
+		if cfg, err := gif.DecodeConfig(bytes.NewReader(b)); err != nil {
+			return nil, ErrInvalidImageSrc
+		} else if cfg.Width*cfg.Height > p.c.MaxPixels {
+			return nil, ErrPixelLimitExceeded
+		}
+		first, err = gif.Decode(bytes.NewReader(b))
+	case len(b) >= 12 && bytes.Equal(b[0:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP")):
+		if info, err := imaging2.ProbeWebP(bytes.NewReader(b)); err != nil {
+			return nil, ErrInvalidImageSrc
+		} else if info.Width*info.Height > p.c.MaxPixels {
+			return nil, ErrPixelLimitExceeded
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var r *bytes.Reader
+		r, err = imaging2.ExtractFirstFrame(ctx, p.c.ImageMagickPath, bytes.NewReader(b))
+		if err == nil {
+			first, err = png.Decode(r)
+		}
+	default:
+		return nil, ErrInvalidImageSrc
+	}
+	if err != nil {
+		switch err {
+		case context.DeadlineExceeded:
+			return nil, ErrTimeout
+		case imaging2.ErrImageMagickUnavailable:
+			return nil, err
+		default:
+			return nil, ErrInvalidImageSrc
+		}
+	}
+
+	if first.Bounds().Dx() > p.c.ThumbnailMaxSize.X || first.Bounds().Dy() > p.c.ThumbnailMaxSize.Y {
+		return imaging.Fit(first, p.c.ThumbnailMaxSize.X, p.c.ThumbnailMaxSize.Y, imaging.Linear), nil
+	}
+	return first, nil
+}
+
+// checkAnimation アニメーション画像の画素数とフレーム数が制限内かどうかを確認します
+func (p *defaultProcessor) checkAnimation(info imaging2.AnimationInfo) error {
+	if info.Width*info.Height > p.c.MaxPixels {
+		return ErrPixelLimitExceeded
+	}
+	if p.c.MaxAnimationFrames > 0 && info.Frames > p.c.MaxAnimationFrames {
+		return ErrFrameLimitExceeded
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	ldapv3 "github.com/go-ldap/ldap/v3"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSyncInterval = time.Hour
+
+type serviceImpl struct {
+	repo   repository.Repository
+	fm     file.Manager
+	logger *zap.Logger
+	config Config
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewService LDAPサービスを生成します
+//
+// 設定が無効な場合は何もしないサービスを返します。
+func NewService(repo repository.Repository, fm file.Manager, logger *zap.Logger, config Config) Service {
+	if !config.Enabled() {
+		return NewNullService()
+	}
+	if config.SyncInterval <= 0 {
+		config.SyncInterval = defaultSyncInterval
+	}
+	return &serviceImpl{
+		repo:   repo,
+		fm:     fm,
+		logger: logger.Named("ldap"),
+		config: config,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Login implements Service interface.
+func (s *serviceImpl) Login(name, password string) (model.UserInfo, error) {
+	entry, err := s.authenticate(name, password)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveOrCreateUser(entry, name)
+}
+
+// Link implements Service interface.
+func (s *serviceImpl) Link(userID uuid.UUID, name, password string) error {
+	entry, err := s.authenticate(name, password)
+	if err != nil {
+		return err
+	}
+
+	externalID := s.externalID(entry, name)
+	if err := s.repo.LinkExternalUserAccount(userID, repository.LinkExternalUserAccountArgs{
+		ProviderName: ProviderName,
+		ExternalID:   externalID,
+		Extra:        model.JSON{"externalName": entry.DN},
+	}); err != nil {
+		return err
+	}
+	s.logger.Info("an ldap account has been linked to traQ user", zap.Stringer("id", userID), zap.String("name", externalID))
+	return nil
+}
+
+// SyncGroups implements Service interface.
+func (s *serviceImpl) SyncGroups() error {
+	if !s.config.GroupSyncEnabled() {
+		return nil
+	}
+
+	conn, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	filter := s.config.GroupFilter
+	if len(filter) == 0 {
+		filter = "(objectClass=*)"
+	}
+	result, err := conn.Search(ldapv3.NewSearchRequest(
+		s.config.GroupBaseDN,
+		ldapv3.ScopeWholeSubtree, ldapv3.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{s.config.GroupNameAttribute, s.config.GroupMemberAttribute},
+		nil,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to search ldap groups: %w", err)
+	}
+
+	admin, err := s.repo.GetUserByName(s.groupAdminName(), false)
+	if err != nil {
+		return fmt.Errorf("failed to get group admin user: %w", err)
+	}
+
+	groups, err := s.repo.GetAllUserGroups()
+	if err != nil {
+		return err
+	}
+	groupsByName := make(map[string]*model.UserGroup, len(groups))
+	for _, g := range groups {
+		groupsByName[g.Name] = g
+	}
+
+	memberCache := map[string]uuid.UUID{}
+	synced := map[uuid.UUID]bool{}
+	for _, entry := range result.Entries {
+		name := entry.GetAttributeValue(s.config.GroupNameAttribute)
+		if len(name) == 0 {
+			continue
+		}
+
+		g, ok := groupsByName[name]
+		if ok && g.Source != GroupSource {
+			s.logger.Warn("ldap group was skipped: a local group with the same name exists", zap.String("name", name))
+			continue
+		}
+		if !ok {
+			g, err = s.repo.CreateExternalUserGroup(name, GroupSource, admin.GetID())
+			if err != nil {
+				s.logger.Warn("failed to create ldap group", zap.String("name", name), zap.Error(err))
+				continue
+			}
+			s.logger.Info("ldap group was created", zap.String("name", name), zap.Stringer("id", g.ID))
+		}
+		synced[g.ID] = true
+
+		desired := map[uuid.UUID]bool{}
+		for _, v := range entry.GetAttributeValues(s.config.GroupMemberAttribute) {
+			uid, err := s.resolveMember(conn, v, memberCache)
+			if err != nil {
+				return err
+			}
+			if uid != uuid.Nil {
+				desired[uid] = true
+			}
+		}
+		if err := s.syncGroupMembers(g, desired); err != nil {
+			return err
+		}
+	}
+
+	// ディレクトリから消えたグループはメンバーを空にする
+	for _, g := range groups {
+		if g.Source == GroupSource && !synced[g.ID] {
+			if err := s.syncGroupMembers(g, map[uuid.UUID]bool{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Start implements Service interface.
+func (s *serviceImpl) Start() {
+	if !s.config.GroupSyncEnabled() {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		t := time.NewTicker(s.config.SyncInterval)
+		defer t.Stop()
+		for {
+			if err := s.SyncGroups(); err != nil {
+				s.logger.Error("failed to sync ldap groups", zap.Error(err))
+			}
+			select {
+			case <-t.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown implements Service interface.
+func (s *serviceImpl) Shutdown() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.wg.Wait()
+}
+
+func (s *serviceImpl) connect() (*ldapv3.Conn, error) {
+	conn, err := ldapv3.DialURL(s.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect ldap server: %w", err)
+	}
+	conn.SetTimeout(10 * time.Second)
+	if len(s.config.BindDN) > 0 {
+		if err := conn.Bind(s.config.BindDN, s.config.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to bind ldap server: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// authenticate ディレクトリのユーザーをパスワードで認証し、そのエントリを返します
+func (s *serviceImpl) authenticate(name, password string) (*ldapv3.Entry, error) {
+	// 空パスワードでのバインドは匿名バインドとして成功してしまうため弾く
+	if len(password) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := s.searchUser(conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldapv3.IsErrorWithCode(err, ldapv3.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind ldap user: %w", err)
+	}
+	return entry, nil
+}
+
+func (s *serviceImpl) searchUser(conn *ldapv3.Conn, name string) (*ldapv3.Entry, error) {
+	result, err := conn.Search(ldapv3.NewSearchRequest(
+		s.config.UserBaseDN,
+		ldapv3.ScopeWholeSubtree, ldapv3.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(s.config.UserFilter, ldapv3.EscapeFilter(name)),
+		[]string{s.userNameAttribute(), s.config.DisplayNameAttribute},
+		nil,
+	))
+	if err != nil {
+		if ldapv3.IsErrorWithCode(err, ldapv3.LDAPResultNoSuchObject) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to search ldap user: %w", err)
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, ErrUserNotFound
+	case 1:
+		return result.Entries[0], nil
+	default:
+		return nil, errors.New("ldap user filter matched multiple entries")
+	}
+}
+
+// externalID エントリのユーザー名属性値を返します。属性が無い場合はログイン名を返します
+func (s *serviceImpl) externalID(entry *ldapv3.Entry, loginName string) string {
+	if name := entry.GetAttributeValue(s.userNameAttribute()); len(name) > 0 {
+		return name
+	}
+	return loginName
+}
+
+func (s *serviceImpl) resolveOrCreateUser(entry *ldapv3.Entry, loginName string) (model.UserInfo, error) {
+	name := s.externalID(entry, loginName)
+
+	user, err := s.repo.GetUserByExternalID(ProviderName, name, false)
+	if err == nil {
+		return user, nil
+	} else if err != repository.ErrNotFound {
+		return nil, err
+	}
+
+	// 同名のtraQユーザーが居ても自動では関連付けない (ディレクトリ側の名前によるアカウント乗っ取りを防ぐ)
+	if _, err := s.repo.GetUserByName(name, false); err == nil {
+		return nil, ErrNotLinked
+	} else if err != repository.ErrNotFound {
+		return nil, err
+	}
+
+	if !s.config.RegisterUserIfNotFound {
+		return nil, ErrNotRegistered
+	}
+
+	iconID, err := file.GenerateIconFile(s.fm, name)
+	if err != nil {
+		return nil, err
+	}
+	user, err = s.repo.CreateUser(repository.CreateUserArgs{
+		Name:        name,
+		DisplayName: entry.GetAttributeValue(s.config.DisplayNameAttribute),
+		Role:        role.User,
+		IconFileID:  iconID,
+		ExternalLogin: &model.ExternalProviderUser{
+			ProviderName: ProviderName,
+			ExternalID:   name,
+			Extra:        model.JSON{"externalName": entry.DN},
+		},
+	})
+	if err != nil {
+		if err == repository.ErrAlreadyExists {
+			return nil, ErrNotLinked
+		}
+		return nil, err
+	}
+	s.logger.Info("New user was created by ldap", zap.Stringer("id", user.GetID()), zap.String("name", name))
+	return user, nil
+}
+
+// resolveMember グループメンバー属性値(DNまたはユーザー名)をtraQユーザーIDに解決します
+//
+// 対応するtraQユーザーが居ない場合はuuid.Nilを返します。
+func (s *serviceImpl) resolveMember(conn *ldapv3.Conn, value string, cache map[string]uuid.UUID) (uuid.UUID, error) {
+	if id, ok := cache[value]; ok {
+		return id, nil
+	}
+
+	name := value
+	if strings.Contains(value, "=") {
+		dn, err := ldapv3.ParseDN(value)
+		if err != nil || len(dn.RDNs) == 0 {
+			cache[value] = uuid.Nil
+			return uuid.Nil, nil
+		}
+		if attr := dn.RDNs[0].Attributes[0]; strings.EqualFold(attr.Type, s.userNameAttribute()) {
+			name = attr.Value
+		} else {
+			result, err := conn.Search(ldapv3.NewSearchRequest(
+				value,
+				ldapv3.ScopeBaseObject, ldapv3.NeverDerefAliases, 1, 0, false,
+				"(objectClass=*)",
+				[]string{s.userNameAttribute()},
+				nil,
+			))
+			if err != nil {
+				if ldapv3.IsErrorWithCode(err, ldapv3.LDAPResultNoSuchObject) {
+					cache[value] = uuid.Nil
+					return uuid.Nil, nil
+				}
+				return uuid.Nil, fmt.Errorf("failed to search ldap group member: %w", err)
+			}
+			if len(result.Entries) == 0 {
+				cache[value] = uuid.Nil
+				return uuid.Nil, nil
+			}
+			name = result.Entries[0].GetAttributeValue(s.userNameAttribute())
+		}
+	}
+
+	// LDAPアカウントが関連付けられたtraQユーザーのみをメンバーにする
+	var id uuid.UUID
+	user, err := s.repo.GetUserByExternalID(ProviderName, name, false)
+	switch {
+	case err == nil:
+		if !user.IsBot() {
+			id = user.GetID()
+		}
+	case err != repository.ErrNotFound:
+		return uuid.Nil, err
+	}
+	cache[value] = id
+	return id, nil
+}
+
+func (s *serviceImpl) syncGroupMembers(g *model.UserGroup, desired map[uuid.UUID]bool) error {
+	current := map[uuid.UUID]bool{}
+	for _, m := range g.Members {
+		current[m.UserID] = true
+	}
+	for uid := range desired {
+		if !current[uid] {
+			if err := s.repo.AddUserToGroup(uid, g.ID, ""); err != nil {
+				return err
+			}
+		}
+	}
+	for uid := range current {
+		if !desired[uid] {
+			if err := s.repo.RemoveUserFromGroup(uid, g.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *serviceImpl) userNameAttribute() string {
+	if len(s.config.UserNameAttribute) == 0 {
+		return "uid"
+	}
+	return s.config.UserNameAttribute
+}
+
+func (s *serviceImpl) groupAdminName() string {
+	if len(s.config.GroupAdminName) == 0 {
+		return "traq"
+	}
+	return s.config.GroupAdminName
+}
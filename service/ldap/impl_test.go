@@ -0,0 +1,269 @@
+package ldap
+
+import (
+	"errors"
+	ldapv3 "github.com/go-ldap/ldap/v3"
+	"github.com/gofrs/uuid"
+	goldap "github.com/lor00x/goldap/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/random"
+	ldapserver "github.com/vjeantet/ldapserver"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testBindDN       = "cn=admin,dc=example,dc=com"
+	testBindPassword = "admin"
+	testUserBaseDN   = "ou=people,dc=example,dc=com"
+)
+
+// testDirectory テスト用ディレクトリのユーザー (uid -> password)
+var testDirectory = map[string]string{
+	"alice": "alice-password",
+	"bob":   "bob-password",
+	"carol": "carol-password",
+}
+
+type testRepository struct {
+	repository.Repository
+	users    map[string]*model.User
+	external map[string]*model.User
+
+	linksLock sync.Mutex
+	links     map[uuid.UUID]string
+}
+
+func (r *testRepository) GetUserByName(name string, _ bool) (model.UserInfo, error) {
+	u, ok := r.users[name]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *testRepository) GetUserByExternalID(providerName, externalID string, _ bool) (model.UserInfo, error) {
+	u, ok := r.external[externalID]
+	if !ok || providerName != ProviderName {
+		return nil, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *testRepository) LinkExternalUserAccount(userID uuid.UUID, args repository.LinkExternalUserAccountArgs) error {
+	r.linksLock.Lock()
+	defer r.linksLock.Unlock()
+	if r.links == nil {
+		r.links = map[uuid.UUID]string{}
+	}
+	if _, ok := r.links[userID]; ok {
+		return repository.ErrAlreadyExists
+	}
+	r.links[userID] = args.ExternalID
+	return nil
+}
+
+var testServerURL string
+
+func TestMain(m *testing.M) {
+	url, err := startTestServer()
+	if err != nil {
+		panic(err)
+	}
+	testServerURL = url
+	os.Exit(m.Run())
+}
+
+func startTestServer() (string, error) {
+	ldapserver.Logger = log.New(ioutil.Discard, "", 0)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	addr := l.Addr().String()
+	if err := l.Close(); err != nil {
+		return "", err
+	}
+
+	routes := ldapserver.NewRouteMux()
+	routes.Bind(func(w ldapserver.ResponseWriter, m *ldapserver.Message) {
+		r := m.GetBindRequest()
+		name, pass := string(r.Name()), string(r.AuthenticationSimple())
+		res := ldapserver.NewBindResponse(ldapserver.LDAPResultSuccess)
+		if name == testBindDN && pass == testBindPassword {
+			w.Write(res)
+			return
+		}
+		if strings.HasSuffix(name, ","+testUserBaseDN) {
+			uid := strings.TrimPrefix(strings.TrimSuffix(name, ","+testUserBaseDN), "uid=")
+			if p, ok := testDirectory[uid]; ok && p == pass {
+				w.Write(res)
+				return
+			}
+		}
+		res.SetResultCode(ldapserver.LDAPResultInvalidCredentials)
+		w.Write(res)
+	})
+	routes.Search(func(w ldapserver.ResponseWriter, m *ldapserver.Message) {
+		r := m.GetSearchRequest()
+		for uid := range testDirectory {
+			if r.FilterString() == "(uid="+uid+")" {
+				e := ldapserver.NewSearchResultEntry("uid=" + uid + "," + testUserBaseDN)
+				e.AddAttribute("uid", goldap.AttributeValue(uid))
+				e.AddAttribute("displayName", goldap.AttributeValue(strings.ToUpper(uid)))
+				w.Write(e)
+			}
+		}
+		w.Write(ldapserver.NewSearchResultDoneResponse(ldapserver.LDAPResultSuccess))
+	})
+
+	server := ldapserver.NewServer()
+	server.Handle(routes)
+	started := make(chan struct{})
+	go server.ListenAndServe(addr, func(*ldapserver.Server) { close(started) })
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		return "", errors.New("ldap test server did not start")
+	}
+	// ldapserverのStopはAcceptを中断できないため、テストプロセス終了までサーバーは停止しない
+
+	return "ldap://" + addr, nil
+}
+
+func newTestService(t *testing.T, repo *testRepository) *serviceImpl {
+	t.Helper()
+	return NewService(repo, nil, zap.NewNop(), Config{
+		URL:                  testServerURL,
+		BindDN:               testBindDN,
+		BindPassword:         testBindPassword,
+		UserBaseDN:           testUserBaseDN,
+		UserFilter:           "(uid=%s)",
+		UserNameAttribute:    "uid",
+		DisplayNameAttribute: "displayName",
+	}).(*serviceImpl)
+}
+
+func TestNewService(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, NewNullService(), NewService(nil, nil, zap.NewNop(), Config{}))
+	s := NewService(nil, nil, zap.NewNop(), Config{URL: "ldap://localhost", UserBaseDN: testUserBaseDN, UserFilter: "(uid=%s)"})
+	if assert.IsType(t, &serviceImpl{}, s) {
+		assert.Equal(t, defaultSyncInterval, s.(*serviceImpl).config.SyncInterval)
+	}
+}
+
+func TestServiceImpl_Login(t *testing.T) {
+	t.Parallel()
+
+	alice := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "alice", Status: model.UserAccountStatusActive}
+	bob := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "bob", Status: model.UserAccountStatusActive}
+	repo := &testRepository{
+		users:    map[string]*model.User{"alice": alice, "bob": bob},
+		external: map[string]*model.User{"alice": alice},
+	}
+	s := newTestService(t, repo)
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user, err := s.Login("alice", "alice-password")
+		if assert.NoError(t, err) {
+			assert.Equal(t, alice.ID, user.GetID())
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		t.Parallel()
+		_, err := s.Login("alice", random.SecureAlphaNumeric(20))
+		assert.Equal(t, ErrInvalidCredentials, err)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		t.Parallel()
+		_, err := s.Login("alice", "")
+		assert.Equal(t, ErrInvalidCredentials, err)
+	})
+
+	t.Run("not in directory", func(t *testing.T) {
+		t.Parallel()
+		_, err := s.Login("charlie", "charlie-password")
+		assert.Equal(t, ErrUserNotFound, err)
+	})
+
+	t.Run("same name user is not linked", func(t *testing.T) {
+		t.Parallel()
+		_, err := s.Login("bob", "bob-password")
+		assert.Equal(t, ErrNotLinked, err)
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		t.Parallel()
+		_, err := s.Login("carol", "carol-password")
+		assert.Equal(t, ErrNotRegistered, err)
+	})
+}
+
+func TestServiceImpl_Link(t *testing.T) {
+	t.Parallel()
+
+	bob := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "bob", Status: model.UserAccountStatusActive}
+	repo := &testRepository{users: map[string]*model.User{"bob": bob}}
+	s := newTestService(t, repo)
+
+	assert.Equal(t, ErrInvalidCredentials, s.Link(bob.ID, "bob", random.SecureAlphaNumeric(20)))
+	assert.Equal(t, ErrUserNotFound, s.Link(bob.ID, "charlie", "charlie-password"))
+	assert.Empty(t, repo.links)
+
+	if assert.NoError(t, s.Link(bob.ID, "bob", "bob-password")) {
+		assert.Equal(t, "bob", repo.links[bob.ID])
+	}
+	assert.Equal(t, repository.ErrAlreadyExists, s.Link(bob.ID, "bob", "bob-password"))
+}
+
+func TestServiceImpl_resolveMember(t *testing.T) {
+	t.Parallel()
+
+	alice := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "alice", Status: model.UserAccountStatusActive}
+	bob := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "bob", Status: model.UserAccountStatusActive}
+	bot := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "BOT_test", Status: model.UserAccountStatusActive, Bot: true}
+	s := newTestService(t, &testRepository{
+		users:    map[string]*model.User{"alice": alice, "bob": bob, "BOT_test": bot},
+		external: map[string]*model.User{"alice": alice, "BOT_test": bot},
+	})
+	conn, err := ldapv3.DialURL(s.config.URL)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	cache := map[string]uuid.UUID{}
+	tt := []struct {
+		value  string
+		expect uuid.UUID
+	}{
+		{"alice", alice.ID},
+		{"uid=alice," + testUserBaseDN, alice.ID},
+		{"UID=alice," + testUserBaseDN, alice.ID},
+		{"bob", uuid.Nil}, // 同名のtraQユーザーは居るが関連付けられていない
+		{"carol", uuid.Nil},
+		{"BOT_test", uuid.Nil},
+		{"uid=,=invalid", uuid.Nil},
+	}
+	for _, v := range tt {
+		id, err := s.resolveMember(conn, v.value, cache)
+		if assert.NoError(t, err, v.value) {
+			assert.Equal(t, v.expect, id, v.value)
+		}
+	}
+	assert.Len(t, cache, len(tt))
+}
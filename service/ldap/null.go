@@ -0,0 +1,33 @@
+package ldap
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+)
+
+var nullS = &nullService{}
+
+type nullService struct{}
+
+// NewNullService 何もしないLDAPサービスを返します
+func NewNullService() Service {
+	return nullS
+}
+
+func (n *nullService) Login(string, string) (model.UserInfo, error) {
+	return nil, ErrUserNotFound
+}
+
+func (n *nullService) Link(uuid.UUID, string, string) error {
+	return ErrUserNotFound
+}
+
+func (n *nullService) SyncGroups() error {
+	return nil
+}
+
+func (n *nullService) Start() {
+}
+
+func (n *nullService) Shutdown() {
+}
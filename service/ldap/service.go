@@ -0,0 +1,95 @@
+package ldap
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
+
+const (
+	// ProviderName 外部ログインアカウントのプロバイダ名
+	ProviderName = "ldap"
+	// GroupSource LDAPと同期されたユーザーグループのソース名
+	GroupSource = "ldap"
+)
+
+var (
+	// ErrUserNotFound ディレクトリにユーザーが存在しません
+	ErrUserNotFound = errors.New("ldap user not found")
+	// ErrInvalidCredentials パスワードが間違っています
+	ErrInvalidCredentials = errors.New("invalid ldap credentials")
+	// ErrNotRegistered ディレクトリにユーザーは存在するが、traQユーザーが存在せず登録も許可されていません
+	ErrNotRegistered = errors.New("ldap user is not registered to traQ")
+	// ErrNotLinked ディレクトリのユーザーと同名のtraQユーザーが存在するが、LDAPアカウントが関連付けられていません
+	ErrNotLinked = errors.New("ldap user is not linked to traQ user")
+)
+
+// Service LDAP認証・グループ同期サービス
+type Service interface {
+	// Login LDAPディレクトリに対してパスワード認証を行い、対応するtraQユーザーを返します
+	//
+	// 成功した場合、traQユーザーとnilを返します。
+	// ディレクトリにユーザーが存在しない場合、ErrUserNotFoundを返します。
+	// パスワードが間違っている場合、ErrInvalidCredentialsを返します。
+	// 対応するtraQユーザーが存在せず、自動登録が無効な場合、ErrNotRegisteredを返します。
+	// 同名のtraQユーザーが存在するが、LDAPアカウントが関連付けられていない場合、ErrNotLinkedを返します。
+	Login(name, password string) (model.UserInfo, error)
+	// Link LDAPディレクトリに対してパスワード認証を行い、指定したtraQユーザーにLDAPアカウントを関連付けます
+	//
+	// 成功した場合、nilを返します。
+	// ディレクトリにユーザーが存在しない場合、ErrUserNotFoundを返します。
+	// パスワードが間違っている場合、ErrInvalidCredentialsを返します。
+	// 既に関連付けられている場合、repository.ErrAlreadyExistsを返します。
+	Link(userID uuid.UUID, name, password string) error
+	// SyncGroups LDAPディレクトリのグループをtraQのユーザーグループに同期します
+	SyncGroups() error
+	// Start 定期グループ同期を開始します
+	Start()
+	// Shutdown 定期グループ同期を停止します
+	Shutdown()
+}
+
+// Config LDAP設定
+type Config struct {
+	// URL LDAPサーバーURL (ldap://host:389, ldaps://host:636)
+	URL string
+	// BindDN 検索用のバインドDN (空の場合は匿名バインド)
+	BindDN string
+	// BindPassword 検索用のバインドパスワード
+	BindPassword string
+
+	// UserBaseDN ユーザー検索ベースDN
+	UserBaseDN string
+	// UserFilter ユーザー検索フィルタ。%sはエスケープされたユーザー名に置換されます (例: (uid=%s))
+	UserFilter string
+	// UserNameAttribute traQユーザー名にマッピングする属性名 (例: uid)
+	UserNameAttribute string
+	// DisplayNameAttribute traQ表示名にマッピングする属性名 (例: displayName)
+	DisplayNameAttribute string
+	// RegisterUserIfNotFound traQユーザーが見つからなかった場合に登録するかどうか
+	RegisterUserIfNotFound bool
+
+	// GroupBaseDN グループ検索ベースDN (空の場合はグループ同期無効)
+	GroupBaseDN string
+	// GroupFilter グループ検索フィルタ (例: (objectClass=groupOfNames))
+	GroupFilter string
+	// GroupNameAttribute traQグループ名にマッピングする属性名 (例: cn)
+	GroupNameAttribute string
+	// GroupMemberAttribute グループメンバー属性名。値はユーザーDNまたはユーザー名 (例: member, memberUid)
+	GroupMemberAttribute string
+	// GroupAdminName 同期で作成されるグループの管理者にするtraQユーザー名
+	GroupAdminName string
+	// SyncInterval グループ同期間隔
+	SyncInterval time.Duration
+}
+
+// Enabled LDAPが有効かどうか
+func (c Config) Enabled() bool {
+	return len(c.URL) > 0 && len(c.UserBaseDN) > 0 && len(c.UserFilter) > 0
+}
+
+// GroupSyncEnabled グループ同期が有効かどうか
+func (c Config) GroupSyncEnabled() bool {
+	return c.Enabled() && len(c.GroupBaseDN) > 0 && len(c.GroupMemberAttribute) > 0 && len(c.GroupNameAttribute) > 0
+}
@@ -0,0 +1,24 @@
+package media
+
+import (
+	"errors"
+	"image"
+)
+
+var (
+	ErrUnavailable     = errors.New("media processing is unavailable")
+	ErrInvalidMediaSrc = errors.New("invalid media src")
+	ErrTimeout         = errors.New("processing timeout")
+)
+
+type Config struct {
+	// FFmpegPath ffmpegの実行パス
+	FFmpegPath string
+	// FFprobePath ffprobeの実行パス
+	// 空の場合は動画・音声の処理は全て無効になります
+	FFprobePath string
+	// Concurrency 処理並列数
+	Concurrency int
+	// ThumbnailMaxSize サムネイル画像サイズ
+	ThumbnailMaxSize image.Point
+}
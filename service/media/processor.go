@@ -0,0 +1,24 @@
+//go:generate mockgen -source=$GOFILE -destination=mock_$GOPACKAGE/mock_$GOFILE
+package media
+
+import (
+	"github.com/traPtitech/traQ/utils/media"
+	"image"
+	"io"
+)
+
+// Result 動画・音声ファイルの処理結果
+type Result struct {
+	// Info メディア情報
+	Info media.Info
+	// Thumbnail サムネイル画像 (動画の場合はポスターフレーム、音声の場合は波形画像)
+	// 生成できなかった場合はnil
+	Thumbnail image.Image
+}
+
+type Processor interface {
+	// Process 動画・音声ファイルのメディア情報を取得し、サムネイル画像を生成します
+	//
+	// 処理が無効な場合はErrUnavailableを返します
+	Process(src io.Reader) (*Result, error)
+}
@@ -0,0 +1,91 @@
+package media
+
+import (
+	"context"
+	"github.com/traPtitech/traQ/utils/media"
+	"golang.org/x/sync/semaphore"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// posterFrameMaxOffset ポスターフレームを取り出す最大の再生位置
+const posterFrameMaxOffset = time.Second
+
+type defaultProcessor struct {
+	c  Config
+	sp *semaphore.Weighted
+}
+
+func NewProcessor(c Config) Processor {
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	return &defaultProcessor{
+		c:  c,
+		sp: semaphore.NewWeighted(int64(c.Concurrency)),
+	}
+}
+
+func (p *defaultProcessor) Process(src io.Reader) (*Result, error) {
+	if len(p.c.FFprobePath) == 0 {
+		return nil, ErrUnavailable
+	}
+
+	_ = p.sp.Acquire(context.Background(), 1)
+	defer p.sp.Release(1)
+
+	// ffmpegはシーク可能な入力の方が高速に処理できるため一時ファイルに書き出す
+	tmp, err := ioutil.TempFile("", "traq-media-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, src)
+	tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // 30秒以内に終わらないファイルは無効
+	defer cancel()
+
+	info, err := media.Probe(ctx, p.c.FFprobePath, tmp.Name())
+	if err != nil {
+		return nil, convertError(ctx, err)
+	}
+	res := &Result{Info: *info}
+
+	// サムネイルの生成に失敗してもメディア情報は返す
+	switch {
+	case len(p.c.FFmpegPath) == 0:
+	case info.HasVideo:
+		at := info.Duration / 2
+		if at > posterFrameMaxOffset {
+			at = posterFrameMaxOffset
+		}
+		if img, err := media.ExtractFrame(ctx, p.c.FFmpegPath, tmp.Name(), at, p.c.ThumbnailMaxSize.X, p.c.ThumbnailMaxSize.Y); err == nil {
+			res.Thumbnail = img
+		}
+	case info.HasAudio:
+		width := p.c.ThumbnailMaxSize.X
+		if img, err := media.Waveform(ctx, p.c.FFmpegPath, tmp.Name(), info.Duration, width, width/4); err == nil {
+			res.Thumbnail = img
+		}
+	}
+	return res, nil
+}
+
+func convertError(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return ErrTimeout
+	case err == media.ErrInvalidMediaSrc:
+		return ErrInvalidMediaSrc
+	case err == media.ErrFFprobeUnavailable:
+		return ErrUnavailable
+	default:
+		return err
+	}
+}
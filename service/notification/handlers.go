@@ -33,9 +33,12 @@ var handlerMap = map[string]eventHandler{
 	event.ChannelCreated:            channelCreatedHandler,
 	event.ChannelUpdated:            channelUpdatedHandler,
 	event.ChannelDeleted:            channelDeletedHandler,
+	event.ChannelMembersChanged:     channelMembersChangedHandler,
+	event.ChannelMerged:             channelMergedHandler,
 	event.ChannelStared:             channelStaredHandler,
 	event.ChannelUnstared:           channelUnstaredHandler,
 	event.ChannelRead:               channelReadHandler,
+	event.ChannelReadStateUpdated:   channelReadStateUpdatedHandler,
 	event.ChannelViewersChanged:     channelViewersChangedHandler,
 	event.ChannelSubscribersChanged: channelSubscribersChangedHandler,
 	event.UserCreated:               userCreatedHandler,
@@ -43,6 +46,8 @@ var handlerMap = map[string]eventHandler{
 	event.UserIconUpdated:           userIconUpdatedHandler,
 	event.UserOnline:                userOnlineHandler,
 	event.UserOffline:               userOfflineHandler,
+	event.UserTyping:                userTypingHandler,
+	event.UserTypingStopped:         userTypingStoppedHandler,
 	event.UserTagAdded:              userTagUpdatedHandler,
 	event.UserTagRemoved:            userTagUpdatedHandler,
 	event.UserTagUpdated:            userTagUpdatedHandler,
@@ -50,6 +55,8 @@ var handlerMap = map[string]eventHandler{
 	event.UserGroupDeleted:          userGroupDeletedHandler,
 	event.UserGroupMemberAdded:      userGroupUpdatedHandler,
 	event.UserGroupMemberRemoved:    userGroupUpdatedHandler,
+	event.UserGroupChildAdded:       userGroupUpdatedHandler,
+	event.UserGroupChildRemoved:     userGroupUpdatedHandler,
 	event.StampCreated:              stampCreatedHandler,
 	event.StampUpdated:              stampUpdatedHandler,
 	event.StampDeleted:              stampDeletedHandler,
@@ -81,6 +88,13 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 		return
 	}
 
+	// 投稿チャンネル情報を取得
+	ch, err := ns.cm.GetChannel(chID)
+	if err != nil {
+		logger.Error("failed to GetChannel", zap.Error(err), zap.Stringer("channelId", chID)) // 失敗
+		return
+	}
+
 	fcmPayload := &fcm.Payload{
 		Type: "new_message",
 		Icon: fmt.Sprintf("%s/api/v3/public/icon/%s", ns.origin, strings.ReplaceAll(mUser.GetName(), "#", "%23")),
@@ -105,10 +119,26 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 		fcmPayload.Title = "#" + path
 		fcmPayload.Path = "/channels/" + path
 		fcmPayload.SetBodyWithEllipsis(mUser.GetResponseDisplayName() + ": " + parsed.OneLine())
+	} else if ch.IsPrivateChannel() {
+		// プライベートチャンネル
+		fcmPayload.Title = ch.Name
+		fcmPayload.Path = "/channels/" + ch.ID.String()
+		fcmPayload.SetBodyWithEllipsis(mUser.GetResponseDisplayName() + ": " + parsed.OneLine())
 	} else {
-		// DM
-		fcmPayload.Title = "@" + mUser.GetResponseDisplayName()
-		fcmPayload.Path = "/users/" + mUser.GetName()
+		members, err := ns.cm.GetDMChannelMembers(chID)
+		if err != nil {
+			logger.Error("failed to GetDMChannelMembers", zap.Error(err), zap.Stringer("channelId", chID)) // 失敗
+			return
+		}
+		if len(members) > 2 {
+			// グループDM
+			fcmPayload.Title = "@" + mUser.GetResponseDisplayName() + " (グループDM)"
+			fcmPayload.Path = "/channels/" + ch.ID.String()
+		} else {
+			// DM
+			fcmPayload.Title = "@" + mUser.GetResponseDisplayName()
+			fcmPayload.Path = "/users/" + mUser.GetName()
+		}
 		fcmPayload.SetBodyWithEllipsis(parsed.OneLine())
 	}
 
@@ -192,12 +222,15 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 		}
 	}
 
-	// 未読追加
+	// 未読追加 (他のノードから複製されたイベントの場合は、元のノードで追加済み)
+	remote := event.IsRemote(ev)
 	markedUsers.Remove(m.UserID)
-	for id := range markedUsers {
-		err := ns.repo.SetMessageUnread(id, m.ID, noticeable.Contains(id))
-		if err != nil {
-			logger.Error("failed to SetMessageUnread", zap.Error(err), zap.Stringer("user_id", id)) // 失敗
+	if !remote {
+		for id := range markedUsers {
+			err := ns.repo.SetMessageUnread(id, m.ID, noticeable.Contains(id))
+			if err != nil {
+				logger.Error("failed to SetMessageUnread", zap.Error(err), zap.Stringer("user_id", id)) // 失敗
+			}
 		}
 	}
 
@@ -213,7 +246,17 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 	}
 	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, targetFunc)
 
+	// SSE送信
+	if isDM {
+		sseMulticast(ns, notifiedUsers, ssePayload)
+	} else {
+		sseMulticast(ns, set.UnionUUIDSets(notifiedUsers, viewers), ssePayload)
+	}
+
 	// FCM送信
+	if remote {
+		return
+	}
 	targets := notifiedUsers.Clone()
 	targets.Remove(m.UserID)
 	ns.fcm.Send(targets, fcmPayload, true)
@@ -241,6 +284,7 @@ func messageUpdatedHandler(ns *Service, ev hub.Message) {
 	}
 
 	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, targetFunc)
+	sseMulticast(ns, channelViewers(ns, cid), ssePayload)
 }
 
 func messageDeletedHandler(ns *Service, ev hub.Message) {
@@ -265,6 +309,7 @@ func messageDeletedHandler(ns *Service, ev hub.Message) {
 	}
 
 	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, targetFunc)
+	sseMulticast(ns, channelViewers(ns, cid), ssePayload)
 }
 
 func messagePinnedHandler(ns *Service, ev hub.Message) {
@@ -338,6 +383,33 @@ func channelDeletedHandler(ns *Service, ev hub.Message) {
 	})
 }
 
+func channelMergedHandler(ns *Service, ev hub.Message) {
+	broadcast(ns, &sse.EventData{
+		EventType: "CHANNEL_MERGED",
+		Payload: map[string]interface{}{
+			"id": ev.Fields["channel_id"].(uuid.UUID),
+			"to": ev.Fields["to_channel_id"].(uuid.UUID),
+		},
+	})
+}
+
+func channelMembersChangedHandler(ns *Service, ev hub.Message) {
+	cid := ev.Fields["channel_id"].(uuid.UUID)
+	members, err := ns.cm.GetDMChannelMembers(cid)
+	if err != nil {
+		ns.logger.Error("failed to GetDMChannelMembers", zap.Error(err), zap.Stringer("channelId", cid))
+		return
+	}
+	// 削除されたメンバーにも通知する
+	members = append(members, ev.Fields["removed"].([]uuid.UUID)...)
+	usersMulticast(ns, members, &sse.EventData{
+		EventType: "CHANNEL_MEMBERS_CHANGED",
+		Payload: map[string]interface{}{
+			"id": cid,
+		},
+	})
+}
+
 func channelStaredHandler(ns *Service, ev hub.Message) {
 	userMulticast(ns, ev.Fields["user_id"].(uuid.UUID), &sse.EventData{
 		EventType: "CHANNEL_STARED",
@@ -365,6 +437,43 @@ func channelReadHandler(ns *Service, ev hub.Message) {
 	})
 }
 
+func channelReadStateUpdatedHandler(ns *Service, ev hub.Message) {
+	cid := ev.Fields["channel_id"].(uuid.UUID)
+	uid := ev.Fields["user_id"].(uuid.UUID)
+
+	// 既読位置を公開していないユーザーの既読は通知しない
+	settings, err := ns.repo.GetUserSettings(uid)
+	if err != nil {
+		ns.logger.Error("failed to GetUserSettings", zap.Error(err), zap.Stringer("userId", uid))
+		return
+	}
+	if !settings.ReadReceipts {
+		return
+	}
+
+	members, err := ns.cm.GetDMChannelMembers(cid)
+	if err != nil {
+		ns.logger.Error("failed to GetDMChannelMembers", zap.Error(err), zap.Stringer("channelId", cid))
+		return
+	}
+	targets := make([]uuid.UUID, 0, len(members))
+	for _, id := range members {
+		if id != uid {
+			targets = append(targets, id)
+		}
+	}
+
+	usersMulticast(ns, targets, &sse.EventData{
+		EventType: "CHANNEL_READ_STATE_UPDATED",
+		Payload: map[string]interface{}{
+			"channel_id": cid,
+			"user_id":    uid,
+			"message_id": ev.Fields["message_id"].(uuid.UUID),
+			"read_at":    ev.Fields["read_at"].(time.Time),
+		},
+	})
+}
+
 func channelViewersChangedHandler(ns *Service, ev hub.Message) {
 	cid := ev.Fields["channel_id"].(uuid.UUID)
 	channelViewerMulticast(ns, cid, &sse.EventData{
@@ -431,6 +540,31 @@ func userOfflineHandler(ns *Service, ev hub.Message) {
 	})
 }
 
+func userTypingHandler(ns *Service, ev hub.Message) {
+	cid := ev.Fields["channel_id"].(uuid.UUID)
+	uid := ev.Fields["user_id"].(uuid.UUID)
+	typingMulticast(ns, cid, uid, &sse.EventData{
+		EventType: "USER_TYPING",
+		Payload: map[string]interface{}{
+			"user_id":    uid,
+			"channel_id": cid,
+			"expires_at": ev.Fields["expires_at"].(time.Time),
+		},
+	})
+}
+
+func userTypingStoppedHandler(ns *Service, ev hub.Message) {
+	cid := ev.Fields["channel_id"].(uuid.UUID)
+	uid := ev.Fields["user_id"].(uuid.UUID)
+	typingMulticast(ns, cid, uid, &sse.EventData{
+		EventType: "USER_TYPING_STOPPED",
+		Payload: map[string]interface{}{
+			"user_id":    uid,
+			"channel_id": cid,
+		},
+	})
+}
+
 func userTagUpdatedHandler(ns *Service, ev hub.Message) {
 	broadcast(ns, &sse.EventData{
 		EventType: "USER_TAGS_UPDATED",
@@ -531,11 +665,14 @@ func userWebRTCv3StateChangedHandler(ns *Service, ev hub.Message) {
 		sessions = append(sessions, StateSession{State: state, SessionID: session})
 	}
 
-	go ns.ws.WriteMessage("USER_WEBRTC_STATE_CHANGED", map[string]interface{}{
-		"user_id":    ev.Fields["user_id"].(uuid.UUID),
-		"channel_id": ev.Fields["channel_id"].(uuid.UUID),
-		"sessions":   sessions,
-	}, ws.TargetAll())
+	broadcast(ns, &sse.EventData{
+		EventType: "USER_WEBRTC_STATE_CHANGED",
+		Payload: map[string]interface{}{
+			"user_id":    ev.Fields["user_id"].(uuid.UUID),
+			"channel_id": ev.Fields["channel_id"].(uuid.UUID),
+			"sessions":   sessions,
+		},
+	})
 }
 
 func clipFolderCreatedHandler(ns *Service, ev hub.Message) {
@@ -594,16 +731,35 @@ func channelHandler(ns *Service, ev hub.Message, ssePayload *sse.EventData) {
 			ns.logger.Error("failed to GetDMChannelMembers", zap.Error(err), zap.Stringer("channelId", cid))
 			return
 		}
-		go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, ws.TargetUsers(members...))
+		usersMulticast(ns, members, ssePayload)
 	} else {
-		go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, ws.TargetAll())
+		broadcast(ns, ssePayload)
 	}
 }
 
 func channelViewerMulticast(ns *Service, cid uuid.UUID, ssePayload *sse.EventData) {
+	sseMulticast(ns, channelViewers(ns, cid), ssePayload)
 	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, ws.TargetChannelViewers(cid))
 }
 
+// typingMulticast 入力中のユーザー以外のチャンネル閲覧者と、DMの相手に送信します
+func typingMulticast(ns *Service, cid, typingUserID uuid.UUID, ssePayload *sse.EventData) {
+	targetFunc := ws.TargetChannelViewers(cid)
+	targets := channelViewers(ns, cid)
+	if !ns.cm.PublicChannelTree().IsChannelPresent(cid) {
+		members, err := ns.cm.GetDMChannelMembers(cid)
+		if err != nil {
+			ns.logger.Error("failed to GetDMChannelMembers", zap.Error(err), zap.Stringer("channelId", cid))
+			return
+		}
+		targetFunc = ws.Or(targetFunc, ws.TargetUsers(members...))
+		targets.Add(members...)
+	}
+	targets.Remove(typingUserID)
+	sseMulticast(ns, targets, ssePayload)
+	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, ws.And(targetFunc, ws.Not(ws.TargetUsers(typingUserID))))
+}
+
 func messageViewerMulticast(ns *Service, mid uuid.UUID, ssePayload *sse.EventData) {
 	m, err := ns.repo.GetMessageByID(mid)
 	if err != nil {
@@ -614,9 +770,32 @@ func messageViewerMulticast(ns *Service, mid uuid.UUID, ssePayload *sse.EventDat
 }
 
 func broadcast(ns *Service, ssePayload *sse.EventData) {
+	go ns.sse.Broadcast(ssePayload)
 	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, ws.TargetAll())
 }
 
 func userMulticast(ns *Service, userID uuid.UUID, ssePayload *sse.EventData) {
+	go ns.sse.Multicast(userID, ssePayload)
 	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, ws.TargetUsers(userID))
 }
+
+func usersMulticast(ns *Service, userIDs []uuid.UUID, ssePayload *sse.EventData) {
+	sseMulticast(ns, set.UUIDSetFromArray(userIDs), ssePayload)
+	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, ws.TargetUsers(userIDs...))
+}
+
+// sseMulticast 指定したユーザーのSSEコネクションに送信します
+func sseMulticast(ns *Service, targets set.UUID, ssePayload *sse.EventData) {
+	for uid := range targets {
+		go ns.sse.Multicast(uid, ssePayload)
+	}
+}
+
+// channelViewers チャンネルを閲覧しているユーザーのIDを返します
+func channelViewers(ns *Service, cid uuid.UUID) set.UUID {
+	viewers := set.UUID{}
+	for uid := range ns.vm.GetChannelViewers(cid) {
+		viewers.Add(uid)
+	}
+	return viewers
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/fcm"
 	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/sse"
 	"github.com/traPtitech/traQ/service/variable"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/ws"
@@ -21,12 +22,13 @@ type Service struct {
 	logger *zap.Logger
 	fcm    fcm.Client
 	ws     *ws.Streamer
+	sse    *sse.Streamer
 	vm     *viewer.Manager
 	origin string
 }
 
 // NewService 通知サービスを作成して起動します
-func NewService(repo repository.Repository, cm channel.Manager, fm file.Manager, hub *hub.Hub, logger *zap.Logger, fcm fcm.Client, ws *ws.Streamer, vm *viewer.Manager, origin variable.ServerOriginString) *Service {
+func NewService(repo repository.Repository, cm channel.Manager, fm file.Manager, hub *hub.Hub, logger *zap.Logger, fcm fcm.Client, ws *ws.Streamer, sse *sse.Streamer, vm *viewer.Manager, origin variable.ServerOriginString) *Service {
 	service := &Service{
 		repo:   repo,
 		cm:     cm,
@@ -35,6 +37,7 @@ func NewService(repo repository.Repository, cm channel.Manager, fm file.Manager,
 		logger: logger.Named("notification"),
 		fcm:    fcm,
 		ws:     ws,
+		sse:    sse,
 		vm:     vm,
 		origin: string(origin),
 	}
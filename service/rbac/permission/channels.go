@@ -11,6 +11,16 @@ const (
 	DeleteChannel = Permission("delete_channel")
 	// ChangeParentChannel 親チャンネル変更権限
 	ChangeParentChannel = Permission("change_parent_channel")
+	// ArchiveChannel チャンネルアーカイブ権限
+	ArchiveChannel = Permission("archive_channel")
+	// EditPrivateChannelMembers プライベートチャンネルメンバー編集権限
+	EditPrivateChannelMembers = Permission("edit_private_channel_members")
+	// ManageOthersPrivateChannelMembers 他人のプライベートチャンネルのメンバー管理権限
+	ManageOthersPrivateChannelMembers = Permission("manage_others_private_channel_members")
+	// GetChannelTemplate チャンネルテンプレート取得権限
+	GetChannelTemplate = Permission("get_channel_template")
+	// ManageChannelTemplate チャンネルテンプレート管理・インスタンス化権限
+	ManageChannelTemplate = Permission("manage_channel_template")
 	// EditChannelTopic チャンネルトピック変更権限
 	EditChannelTopic = Permission("edit_channel_topic")
 	// GetChannelStar チャンネルスター取得権限
@@ -7,4 +7,6 @@ const (
 	DownloadFile = Permission("download_file")
 	// DeleteFile ファイル削除権限
 	DeleteFile = Permission("delete_file")
+	// GetFileUsage ファイル使用量取得権限
+	GetFileUsage = Permission("get_file_usage")
 )
@@ -65,6 +65,11 @@ var List = []Permission{
 	EditChannel,
 	DeleteChannel,
 	ChangeParentChannel,
+	ArchiveChannel,
+	EditPrivateChannelMembers,
+	ManageOthersPrivateChannelMembers,
+	GetChannelTemplate,
+	ManageChannelTemplate,
 	EditChannelTopic,
 
 	GetMyTokens,
@@ -78,6 +83,7 @@ var List = []Permission{
 	UploadFile,
 	DownloadFile,
 	DeleteFile,
+	GetFileUsage,
 
 	GetMessage,
 	PostMessage,
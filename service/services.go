@@ -2,13 +2,19 @@ package service
 
 import (
 	"github.com/traPtitech/traQ/service/bot"
+	"github.com/traPtitech/traQ/service/bus"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
 	"github.com/traPtitech/traQ/service/fcm"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ldap"
+	"github.com/traPtitech/traQ/service/media"
 	"github.com/traPtitech/traQ/service/notification"
 	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/sse"
+	"github.com/traPtitech/traQ/service/typing"
+	"github.com/traPtitech/traQ/service/upload"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
@@ -17,15 +23,26 @@ import (
 type Services struct {
 	BOT                  bot.Service
 	ChannelManager       channel.Manager
+	ChannelTreeSyncer    *channel.TreeSyncer
+	ChannelReadState     *channel.ReadStateRecorder
+	GroupSubscription    *channel.GroupSubscriptionSyncer
+	EventBus             *bus.Bus
+	HubReplicator        *bus.HubReplicator
 	OnlineCounter        *counter.OnlineCounter
 	UnreadMessageCounter counter.UnreadMessageCounter
 	MessageCounter       counter.MessageCounter
 	ChannelCounter       counter.ChannelCounter
+	StampUsageCounter    *counter.StampUsageCounter
 	FCM                  fcm.Client
 	FileManager          file.Manager
 	Imaging              imaging.Processor
+	LDAP                 ldap.Service
+	Media                media.Processor
 	Notification         *notification.Service
 	RBAC                 rbac.RBAC
+	SSE                  *sse.Streamer
+	Typing               *typing.Manager
+	Upload               upload.Manager
 	ViewerManager        *viewer.Manager
 	WebRTCv3             *webrtcv3.Manager
 	WS                   *ws.Streamer
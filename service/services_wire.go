@@ -9,6 +9,7 @@ import (
 var ProviderSet = wire.NewSet(wire.FieldsOf(new(*Services),
 	"BOT",
 	"ChannelManager",
+	"EventBus",
 	"OnlineCounter",
 	"UnreadMessageCounter",
 	"MessageCounter",
@@ -16,8 +17,12 @@ var ProviderSet = wire.NewSet(wire.FieldsOf(new(*Services),
 	"FCM",
 	"FileManager",
 	"Imaging",
+	"LDAP",
+	"Media",
 	"Notification",
 	"RBAC",
+	"SSE",
+	"Upload",
 	"ViewerManager",
 	"WebRTCv3",
 	"WS",
@@ -30,14 +30,7 @@ func (m *sseClientMap) rangeClients(f func(key uuid.UUID, value map[uuid.UUID]*s
 func (m *sseClientMap) broadcast(data *EventData) {
 	m.rangeClients(func(_ uuid.UUID, u map[uuid.UUID]*sseClient) bool {
 		for _, c := range u {
-			c.RLock()
-			skip := c.disconnected
-			c.RUnlock()
-			if skip {
-				continue
-			}
-
-			c.send <- data
+			c.write(data)
 		}
 		return true
 	})
@@ -46,14 +39,7 @@ func (m *sseClientMap) broadcast(data *EventData) {
 func (m *sseClientMap) multicast(user uuid.UUID, data *EventData) {
 	if u, ok := m.loadClients(user); ok {
 		for _, c := range u {
-			c.RLock()
-			skip := c.disconnected
-			c.RUnlock()
-			if skip {
-				continue
-			}
-
-			c.send <- data
+			c.write(data)
 		}
 	}
 }
@@ -64,13 +50,31 @@ type sseClient struct {
 	connectionID uuid.UUID
 	send         chan *EventData
 	disconnected bool
+	// connectedSeq 接続時点のシーケンス番号
+	connectedSeq int64
+	registered   chan struct{}
+}
+
+// write イベントデータを送信バッファに書き込みます
+//
+// 切断済みの場合や、送信バッファが一杯の場合は破棄します。
+func (c *sseClient) write(data *EventData) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.disconnected {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
 }
 
 func (c *sseClient) dispose() {
 	c.Lock()
 	c.disconnected = true
-	c.Unlock()
 	close(c.send)
+	c.Unlock()
 	// flush buffer
 	for range c.send {
 	}
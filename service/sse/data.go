@@ -3,16 +3,24 @@ package sse
 import (
 	jsoniter "github.com/json-iterator/go"
 	"net/http"
+	"strconv"
 )
 
 // EventData SSEイベントデータ
 type EventData struct {
+	// ID イベントのシーケンス番号 配信時に付与されます
+	ID        int64
 	EventType string
 	Payload   interface{}
 }
 
 func (d *EventData) write(rw http.ResponseWriter) {
 	stream := jsoniter.ConfigFastest.BorrowStream(rw)
+	if d.ID > 0 {
+		_, _ = rw.Write([]byte("id: "))
+		_, _ = rw.Write([]byte(strconv.FormatInt(d.ID, 10)))
+		_, _ = rw.Write([]byte("\n"))
+	}
 	_, _ = rw.Write([]byte("event: "))
 	_, _ = rw.Write([]byte(d.EventType))
 	_, _ = rw.Write([]byte("\ndata: "))
@@ -7,10 +7,19 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/utils/replay"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
+const (
+	replayBufferSize = 128
+	replayRetention  = 5 * time.Minute
+	replayGCInterval = time.Minute
+)
+
 var sseConnectionsCounter = promauto.NewGauge(prometheus.GaugeOpts{
 	Namespace: "traq",
 	Name:      "sse_connections",
@@ -20,20 +29,27 @@ var sseConnectionsCounter = promauto.NewGauge(prometheus.GaugeOpts{
 type Streamer struct {
 	sseClientMap
 	hub        *hub.Hub
+	replay     *replay.Store
 	connect    chan *sseClient
 	disconnect chan *sseClient
 	stop       chan struct{}
+	// mu シーケンス番号の付与と配信を直列化します
+	mu sync.Mutex
 }
 
 // NewStreamer SSEストリーマーを作成します
 func NewStreamer(hub *hub.Hub) *Streamer {
 	s := &Streamer{
 		hub:        hub,
+		replay:     replay.NewStore(replayBufferSize, replayRetention),
 		connect:    make(chan *sseClient),
 		disconnect: make(chan *sseClient, 10),
 		stop:       make(chan struct{}),
 	}
 	go func() {
+		gc := time.NewTicker(replayGCInterval)
+		defer gc.Stop()
+
 		for {
 			select {
 			case <-s.stop:
@@ -42,16 +58,26 @@ func NewStreamer(hub *hub.Hub) *Streamer {
 				return
 
 			case c := <-s.connect:
+				s.mu.Lock()
+				c.connectedSeq = s.replay.Acquire(c.userID)
 				arr, ok := s.loadClients(c.userID)
 				if !ok {
 					arr = make(map[uuid.UUID]*sseClient)
 					s.storeClients(c.userID, arr)
 				}
 				arr[c.connectionID] = c
+				s.mu.Unlock()
+				close(c.registered)
 
 			case c := <-s.disconnect:
+				s.mu.Lock()
 				arr, _ := s.loadClients(c.userID)
 				delete(arr, c.connectionID)
+				s.replay.Release(c.userID)
+				s.mu.Unlock()
+
+			case now := <-gc.C:
+				s.replay.GC(now)
 			}
 		}
 	}()
@@ -64,13 +90,62 @@ func (s *Streamer) Dispose() {
 }
 
 // Broadcast イベントデータを全コネクションに配信します
+//
+// 再送バッファを持つ全ユーザーのシーケンス番号を進めます。
 func (s *Streamer) Broadcast(data *EventData) {
-	s.broadcast(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, userID := range s.replay.Users() {
+		s.multicast(userID, s.sequence(userID, data))
+	}
 }
 
 // Multicast イベントデータを指定ユーザーの全コネクションに配信します
 func (s *Streamer) Multicast(userID uuid.UUID, data *EventData) {
-	s.multicast(userID, data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.multicast(userID, s.sequence(userID, data))
+}
+
+// sequence イベントデータにユーザーのシーケンス番号を付与して再送バッファに記録します
+func (s *Streamer) sequence(userID uuid.UUID, data *EventData) *EventData {
+	d, ok := s.replay.Append(userID, func(seq int64) interface{} {
+		d := *data
+		d.ID = seq
+		return &d
+	})
+	if !ok {
+		return data
+	}
+	return d.(*EventData)
+}
+
+// seqBody RESYNCイベントのペイロード
+type seqBody struct {
+	Seq int64 `json:"seq"`
+}
+
+// replayEvents Last-Event-IDより後、接続時点までのイベントを書き込みます
+//
+// 再送できない場合はRESYNCイベントを書き込み、クライアントに全体の再取得を求めます。
+func (s *Streamer) replayEvents(rw http.ResponseWriter, c *sseClient, lastEventID string) {
+	after, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil {
+		return
+	}
+
+	events, ok := s.replay.Range(c.userID, after, c.connectedSeq)
+	if !ok {
+		latest, _ := s.replay.Latest(c.userID)
+		(&EventData{
+			EventType: "RESYNC",
+			Payload:   &seqBody{Seq: latest},
+		}).write(rw)
+		return
+	}
+	for _, e := range events {
+		e.(*EventData).write(rw)
+	}
 }
 
 // ServeHTTP http.Handlerインターフェイスの実装
@@ -86,8 +161,10 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		userID:       ctx.Value(extension.CtxUserIDKey).(uuid.UUID),
 		connectionID: uuid.Must(uuid.NewV4()),
 		send:         make(chan *EventData, 100),
+		registered:   make(chan struct{}),
 	}
 	s.connect <- client
+	<-client.registered
 
 	sseConnectionsCounter.Inc()
 	defer sseConnectionsCounter.Dec()
@@ -110,6 +187,11 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	t := time.NewTicker(10 * time.Second)
 	defer t.Stop()
 
+	// 切断中のイベントを再送
+	if id := r.Header.Get("Last-Event-ID"); len(id) > 0 {
+		s.replayEvents(rw, client, id)
+	}
+
 	fl := rw.(http.Flusher)
 	fl.Flush()
 StreamFor:
@@ -0,0 +1,198 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/router/extension"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	ID        int64
+	EventType string
+	Data      string
+}
+
+type testConn struct {
+	t      *testing.T
+	cancel context.CancelFunc
+	events chan testEvent
+}
+
+func setupStreamer(t *testing.T) (*Streamer, func(userID uuid.UUID, lastEventID string) *testConn) {
+	t.Helper()
+
+	s := NewStreamer(hub.New())
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		userID := uuid.FromStringOrNil(r.URL.Query().Get("user"))
+		s.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), extension.CtxUserIDKey, userID)))
+	}))
+	t.Cleanup(func() {
+		s.Dispose()
+		srv.Close()
+	})
+
+	connect := func(userID uuid.UUID, lastEventID string) *testConn {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?user="+userID.String(), nil)
+		require.NoError(t, err)
+		if len(lastEventID) > 0 {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		c := &testConn{t: t, cancel: cancel, events: make(chan testEvent, 100)}
+		go func() {
+			defer res.Body.Close()
+			defer close(c.events)
+			var e testEvent
+			sc := bufio.NewScanner(res.Body)
+			for sc.Scan() {
+				line := sc.Text()
+				switch {
+				case len(line) == 0:
+					if len(e.EventType) > 0 {
+						c.events <- e
+					}
+					e = testEvent{}
+				case strings.HasPrefix(line, "id: "):
+					e.ID, _ = strconv.ParseInt(strings.TrimPrefix(line, "id: "), 10, 64)
+				case strings.HasPrefix(line, "event: "):
+					e.EventType = strings.TrimPrefix(line, "event: ")
+				case strings.HasPrefix(line, "data: "):
+					e.Data = strings.TrimPrefix(line, "data: ")
+				}
+			}
+		}()
+		t.Cleanup(cancel)
+		return c
+	}
+	return s, connect
+}
+
+func waitForConnections(t *testing.T, s *Streamer, userID uuid.UUID, n int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		s.mu.Lock()
+		arr, _ := s.loadClients(userID)
+		ok := len(arr) == n
+		s.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition was not satisfied")
+}
+
+func (c *testConn) read() testEvent {
+	c.t.Helper()
+	select {
+	case e, ok := <-c.events:
+		require.True(c.t, ok, "stream was closed")
+		return e
+	case <-time.After(3 * time.Second):
+		c.t.Fatal("timed out")
+		return testEvent{}
+	}
+}
+
+func TestStreamer_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	t.Run("replay on reconnect", func(t *testing.T) {
+		t.Parallel()
+		s, connect := setupStreamer(t)
+		user := uuid.Must(uuid.NewV4())
+		other := uuid.Must(uuid.NewV4())
+
+		conn1 := connect(user, "")
+		waitForConnections(t, s, user, 1)
+		s.Multicast(user, &EventData{EventType: "A", Payload: "a"})
+		a := conn1.read()
+		assert.Equal(t, "A", a.EventType)
+		assert.Equal(t, `"a"`, a.Data)
+		assert.NotZero(t, a.ID)
+
+		conn1.cancel()
+		waitForConnections(t, s, user, 0)
+
+		// 切断中のイベント
+		s.Multicast(user, &EventData{EventType: "B", Payload: "b"})
+		s.Broadcast(&EventData{EventType: "C", Payload: "c"})
+		s.Multicast(other, &EventData{EventType: "X", Payload: "x"})
+
+		conn2 := connect(user, strconv.FormatInt(a.ID, 10))
+		waitForConnections(t, s, user, 1)
+		// 接続後のイベントは再送の後に届く
+		s.Multicast(user, &EventData{EventType: "D", Payload: "d"})
+
+		b := conn2.read()
+		assert.Equal(t, "B", b.EventType)
+		assert.Equal(t, `"b"`, b.Data)
+		assert.Equal(t, a.ID+1, b.ID)
+		c := conn2.read()
+		assert.Equal(t, "C", c.EventType)
+		assert.Equal(t, b.ID+1, c.ID)
+		d := conn2.read()
+		assert.Equal(t, "D", d.EventType)
+		assert.Equal(t, c.ID+1, d.ID)
+	})
+
+	t.Run("resync", func(t *testing.T) {
+		t.Parallel()
+		s, connect := setupStreamer(t)
+		user := uuid.Must(uuid.NewV4())
+
+		conn1 := connect(user, "")
+		waitForConnections(t, s, user, 1)
+		for i := 0; i < replayBufferSize+1; i++ {
+			s.Multicast(user, &EventData{EventType: "A", Payload: i})
+		}
+		first := conn1.read()
+		conn1.cancel()
+		waitForConnections(t, s, user, 0)
+
+		// 再送バッファから失われたイベント以降の再送は要求できない
+		conn2 := connect(user, strconv.FormatInt(first.ID-1, 10))
+		e := conn2.read()
+		assert.Equal(t, "RESYNC", e.EventType)
+		assert.Zero(t, e.ID)
+		assert.Equal(t, `{"seq":`+strconv.FormatInt(first.ID+replayBufferSize, 10)+`}`, e.Data)
+	})
+
+	t.Run("no Last-Event-ID", func(t *testing.T) {
+		t.Parallel()
+		s, connect := setupStreamer(t)
+		user := uuid.Must(uuid.NewV4())
+
+		conn1 := connect(user, "")
+		waitForConnections(t, s, user, 1)
+		s.Multicast(user, &EventData{EventType: "A", Payload: "a"})
+		a := conn1.read()
+		conn1.cancel()
+		waitForConnections(t, s, user, 0)
+
+		s.Multicast(user, &EventData{EventType: "B", Payload: "b"})
+
+		// 再送を要求しない場合は接続後のイベントのみ届く
+		conn2 := connect(user, "")
+		waitForConnections(t, s, user, 1)
+		s.Multicast(user, &EventData{EventType: "C", Payload: "c"})
+		c := conn2.read()
+		assert.Equal(t, "C", c.EventType)
+		assert.Equal(t, a.ID+2, c.ID)
+	})
+}
@@ -0,0 +1,139 @@
+package typing
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"sync"
+	"time"
+)
+
+const (
+	// throttleInterval 同じユーザー・チャンネルの入力中イベントを発行する最短間隔
+	throttleInterval = 3 * time.Second
+	// expiration 入力中状態が更新されずに自動で解除されるまでの時間
+	expiration = 6 * time.Second
+	// checkInterval 入力中状態の期限を確認する間隔
+	checkInterval = time.Second
+)
+
+// Manager メッセージ入力中状態マネージャ
+//
+// 入力中状態はこのノードのコネクションからの通知だけを保持します。
+// イベントはHubを通じて他のノードに複製されます。
+type Manager struct {
+	hub    *hub.Hub
+	states map[key]*state
+	mu     sync.Mutex
+}
+
+type key struct {
+	userID    uuid.UUID
+	channelID uuid.UUID
+}
+
+type state struct {
+	published time.Time
+	expiresAt time.Time
+}
+
+// NewManager メッセージ入力中状態マネージャを生成します
+func NewManager(hub *hub.Hub) *Manager {
+	m := &Manager{
+		hub:    hub,
+		states: map[key]*state{},
+	}
+
+	// メッセージを投稿したら入力中状態を解除する
+	sub := hub.Subscribe(100, event.MessageCreated)
+	go func() {
+		t := time.NewTicker(checkInterval)
+		defer t.Stop()
+		for {
+			select {
+			case ev, ok := <-sub.Receiver:
+				if !ok {
+					return
+				}
+				msg := ev.Fields["message"].(*model.Message)
+				m.Stop(msg.UserID, msg.ChannelID)
+			case now := <-t.C:
+				m.expire(now)
+			}
+		}
+	}()
+	return m
+}
+
+// Typing ユーザーがチャンネルでメッセージを入力中であることを記録します
+//
+// 入力中になった時と、前回の発行からthrottleIntervalが経過した時にだけイベントを発行します。
+func (m *Manager) Typing(userID, channelID uuid.UUID) {
+	now := time.Now()
+	k := key{userID: userID, channelID: channelID}
+
+	m.mu.Lock()
+	s, ok := m.states[k]
+	if !ok {
+		s = &state{}
+		m.states[k] = s
+	}
+	s.expiresAt = now.Add(expiration)
+	publish := now.Sub(s.published) >= throttleInterval
+	if publish {
+		s.published = now
+	}
+	m.mu.Unlock()
+
+	if publish {
+		m.hub.Publish(hub.Message{
+			Name: event.UserTyping,
+			Fields: hub.Fields{
+				"user_id":    userID,
+				"channel_id": channelID,
+				"expires_at": now.Add(expiration),
+			},
+		})
+	}
+}
+
+// Stop ユーザーのチャンネルでのメッセージ入力中状態を解除します
+func (m *Manager) Stop(userID, channelID uuid.UUID) {
+	k := key{userID: userID, channelID: channelID}
+
+	m.mu.Lock()
+	_, ok := m.states[k]
+	delete(m.states, k)
+	m.mu.Unlock()
+
+	if ok {
+		m.publishStopped(k)
+	}
+}
+
+func (m *Manager) expire(now time.Time) {
+	var expired []key
+	m.mu.Lock()
+	for k, s := range m.states {
+		if now.After(s.expiresAt) {
+			expired = append(expired, k)
+			delete(m.states, k)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, k := range expired {
+		m.publishStopped(k)
+	}
+}
+
+func (m *Manager) publishStopped(k key) {
+	m.hub.Publish(hub.Message{
+		Name: event.UserTypingStopped,
+		Fields: hub.Fields{
+			"user_id":    k.userID,
+			"channel_id": k.channelID,
+		},
+	})
+}
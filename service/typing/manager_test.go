@@ -0,0 +1,64 @@
+package typing
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	h := hub.New()
+	sub := h.Subscribe(10, event.UserTyping, event.UserTypingStopped)
+	m := NewManager(h)
+	user := uuid.NewV3(uuid.Nil, "u")
+	channel := uuid.NewV3(uuid.Nil, "c")
+
+	receive := func() hub.Message {
+		select {
+		case ev := <-sub.Receiver:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("event was not published")
+			return hub.Message{}
+		}
+	}
+
+	m.Typing(user, channel)
+	ev := receive()
+	assert.Equal(t, event.UserTyping, ev.Name)
+	assert.Equal(t, user, ev.Fields["user_id"])
+	assert.Equal(t, channel, ev.Fields["channel_id"])
+
+	// throttleInterval以内の通知ではイベントを発行しない
+	m.Typing(user, channel)
+	assert.Len(t, sub.Receiver, 0)
+
+	// 期限が来ていなければ解除されない
+	m.expire(time.Now())
+	assert.Len(t, sub.Receiver, 0)
+
+	// 期限切れで解除される
+	m.expire(time.Now().Add(expiration + time.Second))
+	assert.Equal(t, event.UserTypingStopped, receive().Name)
+	m.expire(time.Now().Add(expiration + time.Second))
+	assert.Len(t, sub.Receiver, 0)
+
+	// メッセージを投稿すると解除される
+	m.Typing(user, channel)
+	assert.Equal(t, event.UserTyping, receive().Name)
+	h.Publish(hub.Message{
+		Name: event.MessageCreated,
+		Fields: hub.Fields{
+			"message": &model.Message{UserID: user, ChannelID: channel},
+		},
+	})
+	ev = receive()
+	assert.Equal(t, event.UserTypingStopped, ev.Name)
+	assert.Equal(t, user, ev.Fields["user_id"])
+}
@@ -0,0 +1,119 @@
+package upload
+
+import (
+	"errors"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/utils/validator"
+	"io"
+	"mime"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// ErrNotFound ファイルアップロードが存在しません
+	ErrNotFound = errors.New("not found")
+	// ErrTooLarge ファイルサイズが上限を超えています
+	ErrTooLarge = errors.New("file size exceeds the limit")
+	// ErrOutstandingLimitExceeded ユーザーの受信中のファイルアップロードの合計サイズが上限を超えています
+	ErrOutstandingLimitExceeded = errors.New("total size of outstanding uploads exceeds the limit")
+	// ErrOffsetMismatch 指定したオフセットが受信済みバイト数と一致しません
+	ErrOffsetMismatch = errors.New("offset mismatch")
+	// ErrSizeExceeded 受信したデータが宣言されたファイルサイズを超えています
+	ErrSizeExceeded = errors.New("received data exceeds the declared file size")
+	// ErrIncomplete 全てのデータを受信していません
+	ErrIncomplete = errors.New("upload is incomplete")
+)
+
+// CreateArgs ファイルアップロード作成引数
+type CreateArgs struct {
+	FileName  string
+	FileSize  int64
+	MimeType  string
+	CreatorID uuid.UUID
+	ChannelID uuid.UUID
+}
+
+func (args *CreateArgs) Validate() error {
+	if len(args.MimeType) == 0 {
+		args.MimeType = mime.TypeByExtension(filepath.Ext(args.FileName))
+		if len(args.MimeType) == 0 {
+			args.MimeType = "application/octet-stream"
+		}
+	}
+	return vd.ValidateStruct(args,
+		vd.Field(&args.FileName, vd.Required),
+		vd.Field(&args.FileSize, vd.Required, vd.Min(int64(1))),
+		vd.Field(&args.MimeType, vd.Required, is.PrintableASCII),
+		vd.Field(&args.CreatorID, validator.NotNilUUID),
+		vd.Field(&args.ChannelID, validator.NotNilUUID),
+	)
+}
+
+// Manager 再開可能なファイルアップロードマネージャー
+//
+// アップロードされたデータはステージングディレクトリに一時保存され、
+// 全て受信した後にfile.Managerによってファイルとして保存されます。
+//
+// ステージングディレクトリと同一アップロードへの書き込みの排他制御はノードローカルです。
+// 複数ノードで動作させる場合は、同じファイルアップロードへのリクエストが常に同じノードに届くように
+// ロードバランサーを設定(スティッキールーティング)する必要があります。
+type Manager interface {
+	// Create ファイルアップロードを作成します
+	//
+	// 成功した場合、ファイルアップロードとnilを返します。
+	// ファイルサイズが上限を超えている場合、ErrTooLargeを返します。
+	// 作成者の受信中のファイルアップロードの合計サイズが上限を超える場合、ErrOutstandingLimitExceededを返します。
+	Create(args CreateArgs) (*model.FileUpload, error)
+	// GetOutstandingSize 指定したユーザーの受信中(有効期限内)のファイルアップロードのファイルサイズの合計を返します
+	//
+	// 容量制限の計算に使用します。
+	GetOutstandingSize(userID uuid.UUID) (int64, error)
+	// Get 指定したファイルアップロードを取得します
+	//
+	// 成功した場合、ファイルアップロードとnilを返します。
+	// 存在しないか有効期限が切れている場合、ErrNotFoundを返します。
+	Get(uploadID uuid.UUID) (*model.FileUpload, error)
+	// Append ファイルアップロードにoffsetの位置からsrcのデータを追記します
+	//
+	// 成功した場合、更新後のファイルアップロードとnilを返します。
+	// srcの読み込み中にエラーが発生した場合は、それまでに受信したデータを保存した上でエラーを返します。
+	// 存在しないか有効期限が切れている場合、ErrNotFoundを返します。
+	// offsetが受信済みバイト数と一致しない場合、ErrOffsetMismatchを返します。
+	// データが宣言されたファイルサイズを超えた場合、ErrSizeExceededを返します。
+	Append(uploadID uuid.UUID, offset int64, src io.Reader) (*model.FileUpload, error)
+	// Finalize 全て受信したファイルアップロードをファイルとして保存します
+	//
+	// 成功した場合、保存したファイルとnilを返します。ファイルアップロードは削除されます。
+	// 存在しないか有効期限が切れている場合、ErrNotFoundを返します。
+	// 全てのデータを受信していない場合、ErrIncompleteを返します。
+	Finalize(uploadID uuid.UUID, acl file.ACL) (model.File, error)
+	// Abort ファイルアップロードを中止し、受信したデータを破棄します
+	//
+	// 成功した、或いは既に存在しない場合、nilを返します。
+	Abort(uploadID uuid.UUID) error
+	// Start 期限切れのファイルアップロードの定期削除を開始します
+	Start()
+	// Shutdown 期限切れのファイルアップロードの定期削除を停止します
+	Shutdown()
+}
+
+// Config 再開可能なファイルアップロード設定
+type Config struct {
+	// Dir 受信したデータのステージングディレクトリ
+	//
+	// ノード毎に独立したディレクトリです。複数ノードで動作させる場合はスティッキールーティングが必要です。
+	Dir string
+	// MaxSize アップロード可能な最大ファイルサイズ(byte)
+	MaxSize int64
+	// MaxOutstandingSize ユーザー毎の受信中のファイルアップロードの合計サイズの上限(byte)
+	MaxOutstandingSize int64
+	// Expiry 最後にデータを受信してからファイルアップロードが破棄されるまでの期間
+	Expiry time.Duration
+	// GCInterval 期限切れのファイルアップロードの削除間隔
+	GCInterval time.Duration
+}
@@ -0,0 +1,294 @@
+package upload
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/utils/optional"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxSize            = 1 << 30
+	defaultMaxOutstandingSize = 2 << 30
+	defaultExpiry             = 24 * time.Hour
+	defaultGCInterval         = time.Hour
+)
+
+type managerImpl struct {
+	repo   repository.FileRepository
+	fm     file.Manager
+	logger *zap.Logger
+	config Config
+
+	// locks ファイルアップロード毎のロック
+	locks sync.Map
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewManager 再開可能なファイルアップロードマネージャーを生成します
+func NewManager(repo repository.FileRepository, fm file.Manager, logger *zap.Logger, config Config) (Manager, error) {
+	if len(config.Dir) == 0 {
+		config.Dir = filepath.Join(os.TempDir(), "traq-uploads")
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultMaxSize
+	}
+	if config.MaxOutstandingSize <= 0 {
+		config.MaxOutstandingSize = defaultMaxOutstandingSize
+	}
+	if config.Expiry <= 0 {
+		config.Expiry = defaultExpiry
+	}
+	if config.GCInterval <= 0 {
+		config.GCInterval = defaultGCInterval
+	}
+	if err := os.MkdirAll(config.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create upload staging directory: %w", err)
+	}
+	return &managerImpl{
+		repo:   repo,
+		fm:     fm,
+		logger: logger.Named("upload_manager"),
+		config: config,
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+// Create implements Manager interface.
+func (m *managerImpl) Create(args CreateArgs) (*model.FileUpload, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	if args.FileSize > m.config.MaxSize {
+		return nil, ErrTooLarge
+	}
+	outstanding, err := m.GetOutstandingSize(args.CreatorID)
+	if err != nil {
+		return nil, err
+	}
+	if outstanding+args.FileSize > m.config.MaxOutstandingSize {
+		return nil, ErrOutstandingLimitExceeded
+	}
+
+	u := &model.FileUpload{
+		ID:        uuid.Must(uuid.NewV4()),
+		Name:      args.FileName,
+		Mime:      args.MimeType,
+		Size:      args.FileSize,
+		CreatorID: args.CreatorID,
+		ChannelID: args.ChannelID,
+		ExpiresAt: time.Now().Add(m.config.Expiry),
+	}
+	f, err := os.OpenFile(m.stagingPath(u.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	f.Close()
+
+	if err := m.repo.CreateFileUpload(u); err != nil {
+		m.removeStagingFile(u.ID)
+		return nil, fmt.Errorf("failed to CreateFileUpload: %w", err)
+	}
+	return u, nil
+}
+
+// GetOutstandingSize implements Manager interface.
+func (m *managerImpl) GetOutstandingSize(userID uuid.UUID) (int64, error) {
+	size, err := m.repo.GetUserFileUploadsSize(userID, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to GetUserFileUploadsSize: %w", err)
+	}
+	return size, nil
+}
+
+// Get implements Manager interface.
+func (m *managerImpl) Get(uploadID uuid.UUID) (*model.FileUpload, error) {
+	u, err := m.repo.GetFileUpload(uploadID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to GetFileUpload: %w", err)
+	}
+	if u.ExpiresAt.Before(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+// Append implements Manager interface.
+func (m *managerImpl) Append(uploadID uuid.UUID, offset int64, src io.Reader) (*model.FileUpload, error) {
+	unlock := m.lock(uploadID)
+	defer unlock()
+
+	u, err := m.Get(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if offset != u.Offset {
+		return nil, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(m.stagingPath(u.ID), os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open staging file: %w", err)
+	}
+	defer f.Close()
+
+	// 前回の途中で失敗した書き込みを捨てる
+	if err := f.Truncate(u.Offset); err != nil {
+		return nil, fmt.Errorf("failed to truncate staging file: %w", err)
+	}
+	if _, err := f.Seek(u.Offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek staging file: %w", err)
+	}
+
+	remaining := u.Size - u.Offset
+	n, copyErr := io.Copy(f, io.LimitReader(src, remaining+1))
+	if n > remaining {
+		return nil, ErrSizeExceeded
+	}
+	if copyErr != nil {
+		// 接続が切れた場合でも、それまでに受信したデータは保持して再開できるようにする
+		if err := f.Sync(); err != nil {
+			return nil, fmt.Errorf("failed to sync staging file: %w", err)
+		}
+	}
+	if n > 0 {
+		u.Offset += n
+		u.ExpiresAt = time.Now().Add(m.config.Expiry)
+		if err := m.repo.UpdateFileUploadOffset(u.ID, u.Offset, u.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to UpdateFileUploadOffset: %w", err)
+		}
+	}
+	if copyErr != nil {
+		return nil, fmt.Errorf("failed to read src stream: %w", copyErr)
+	}
+	return u, nil
+}
+
+// Finalize implements Manager interface.
+func (m *managerImpl) Finalize(uploadID uuid.UUID, acl file.ACL) (model.File, error) {
+	unlock := m.lock(uploadID)
+	defer unlock()
+
+	u, err := m.Get(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsCompleted() {
+		return nil, ErrIncomplete
+	}
+
+	src, err := os.Open(m.stagingPath(u.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open staging file: %w", err)
+	}
+	f, err := m.fm.Save(file.SaveArgs{
+		FileName:  u.Name,
+		FileSize:  u.Size,
+		MimeType:  u.Mime,
+		FileType:  model.FileTypeUserFile,
+		CreatorID: optional.UUIDFrom(u.CreatorID),
+		ChannelID: optional.UUIDFrom(u.ChannelID),
+		ACL:       acl,
+		Src:       src,
+	})
+	src.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	m.delete(u.ID)
+	return f, nil
+}
+
+// Abort implements Manager interface.
+func (m *managerImpl) Abort(uploadID uuid.UUID) error {
+	unlock := m.lock(uploadID)
+	defer unlock()
+
+	if err := m.repo.DeleteFileUpload(uploadID); err != nil {
+		return fmt.Errorf("failed to DeleteFileUpload: %w", err)
+	}
+	m.removeStagingFile(uploadID)
+	m.locks.Delete(uploadID)
+	return nil
+}
+
+// Start implements Manager interface.
+func (m *managerImpl) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		t := time.NewTicker(m.config.GCInterval)
+		defer t.Stop()
+		for {
+			m.gc()
+			select {
+			case <-t.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown implements Manager interface.
+func (m *managerImpl) Shutdown() {
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.wg.Wait()
+}
+
+// gc 期限切れのファイルアップロードを削除します
+func (m *managerImpl) gc() {
+	uploads, err := m.repo.GetExpiredFileUploads(time.Now())
+	if err != nil {
+		m.logger.Error("failed to GetExpiredFileUploads", zap.Error(err))
+		return
+	}
+	for _, u := range uploads {
+		unlock := m.lock(u.ID)
+		m.delete(u.ID)
+		unlock()
+	}
+}
+
+// delete ファイルアップロードとそのステージングファイルを削除します
+func (m *managerImpl) delete(uploadID uuid.UUID) {
+	if err := m.repo.DeleteFileUpload(uploadID); err != nil {
+		m.logger.Warn("failed to DeleteFileUpload", zap.Error(err), zap.Stringer("uploadID", uploadID))
+		return
+	}
+	m.removeStagingFile(uploadID)
+	m.locks.Delete(uploadID)
+}
+
+func (m *managerImpl) removeStagingFile(uploadID uuid.UUID) {
+	if err := os.Remove(m.stagingPath(uploadID)); err != nil && !os.IsNotExist(err) {
+		m.logger.Warn("failed to remove staging file", zap.Error(err), zap.Stringer("uploadID", uploadID))
+	}
+}
+
+func (m *managerImpl) stagingPath(uploadID uuid.UUID) string {
+	return filepath.Join(m.config.Dir, uploadID.String())
+}
+
+// lock ファイルアップロードのロックを取得し、解放する関数を返します
+func (m *managerImpl) lock(uploadID uuid.UUID) func() {
+	v, _ := m.locks.LoadOrStore(uploadID, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,414 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/repository/mock_repository"
+	"github.com/traPtitech/traQ/service/file"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+var errMock = errors.New("mock error")
+
+// fakeFileManager Saveに渡されたデータを保持するfile.Manager
+type fakeFileManager struct {
+	file.Manager
+	args file.SaveArgs
+	data []byte
+	err  error
+}
+
+func (fm *fakeFileManager) Save(args file.SaveArgs) (model.File, error) {
+	if fm.err != nil {
+		return nil, fm.err
+	}
+	fm.args = args
+	fm.data, _ = ioutil.ReadAll(args.Src)
+	return nil, nil
+}
+
+// errReader 常にエラーを返すio.Reader
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errMock
+}
+
+func initUM(t *testing.T, repo repository.FileRepository, fm file.Manager) *managerImpl {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "traq-upload-test")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	m, err := NewManager(repo, fm, zap.NewNop(), Config{Dir: dir, MaxSize: 100, MaxOutstandingSize: 150})
+	require.NoError(t, err)
+	return m.(*managerImpl)
+}
+
+func writeStaging(t *testing.T, m *managerImpl, u *model.FileUpload, data string) {
+	t.Helper()
+	require.NoError(t, ioutil.WriteFile(m.stagingPath(u.ID), []byte(data), 0600))
+}
+
+func readStaging(t *testing.T, m *managerImpl, u *model.FileUpload) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(m.stagingPath(u.ID))
+	require.NoError(t, err)
+	return string(b)
+}
+
+func newUpload(size, offset int64) *model.FileUpload {
+	return &model.FileUpload{
+		ID:        uuid.Must(uuid.NewV4()),
+		Name:      "test.txt",
+		Mime:      "text/plain",
+		Size:      size,
+		Offset:    offset,
+		CreatorID: uuid.Must(uuid.NewV4()),
+		ChannelID: uuid.Must(uuid.NewV4()),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+}
+
+func TestManagerImpl_Create(t *testing.T) {
+	t.Parallel()
+
+	args := func() CreateArgs {
+		return CreateArgs{
+			FileName:  "test.txt",
+			FileSize:  10,
+			CreatorID: uuid.Must(uuid.NewV4()),
+			ChannelID: uuid.Must(uuid.NewV4()),
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		repo.EXPECT().GetUserFileUploadsSize(gomock.Any(), gomock.Any()).Return(int64(140), nil).Times(1)
+		repo.EXPECT().CreateFileUpload(gomock.Any()).Return(nil).Times(1)
+
+		u, err := m.Create(args())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "text/plain; charset=utf-8", u.Mime)
+			assert.EqualValues(t, 10, u.Size)
+			assert.EqualValues(t, 0, u.Offset)
+			assert.True(t, u.ExpiresAt.After(time.Now()))
+			assert.Equal(t, "", readStaging(t, m, u))
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		t.Parallel()
+		m := initUM(t, nil, nil)
+
+		a := args()
+		a.FileSize = 101
+		_, err := m.Create(a)
+		assert.Equal(t, ErrTooLarge, err)
+	})
+
+	t.Run("outstanding limit exceeded", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		a := args()
+		repo.EXPECT().GetUserFileUploadsSize(a.CreatorID, gomock.Any()).Return(int64(141), nil).Times(1)
+
+		_, err := m.Create(a)
+		assert.Equal(t, ErrOutstandingLimitExceeded, err)
+	})
+
+	t.Run("invalid args", func(t *testing.T) {
+		t.Parallel()
+		m := initUM(t, nil, nil)
+
+		a := args()
+		a.ChannelID = uuid.Nil
+		_, err := m.Create(a)
+		assert.Error(t, err)
+	})
+
+	t.Run("repo error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		var id uuid.UUID
+		repo.EXPECT().GetUserFileUploadsSize(gomock.Any(), gomock.Any()).Return(int64(0), nil).Times(1)
+		repo.EXPECT().
+			CreateFileUpload(gomock.Any()).
+			DoAndReturn(func(u *model.FileUpload) error {
+				id = u.ID
+				return errMock
+			}).
+			Times(1)
+
+		_, err := m.Create(args())
+		if assert.Error(t, err) {
+			assert.Equal(t, errMock, errors.Unwrap(err))
+			_, err := os.Stat(m.stagingPath(id))
+			assert.True(t, os.IsNotExist(err))
+		}
+	})
+}
+
+func TestManagerImpl_Get(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		u := newUpload(10, 0)
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+
+		result, err := m.Get(u.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, u, result)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		u := newUpload(10, 0)
+		u.ExpiresAt = time.Now().Add(-time.Second)
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+
+		_, err := m.Get(u.ID)
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		id := uuid.Must(uuid.NewV4())
+		repo.EXPECT().GetFileUpload(id).Return(nil, repository.ErrNotFound).Times(1)
+
+		_, err := m.Get(id)
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+func TestManagerImpl_Append(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		u := newUpload(10, 3)
+		writeStaging(t, m, u, "abc")
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+		repo.EXPECT().UpdateFileUploadOffset(u.ID, int64(7), gomock.Any()).Return(nil).Times(1)
+
+		result, err := m.Append(u.ID, 3, bytes.NewBufferString("defg"))
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, 7, result.Offset)
+			assert.Equal(t, "abcdefg", readStaging(t, m, u))
+		}
+	})
+
+	t.Run("success (discard partial write)", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		u := newUpload(10, 3)
+		writeStaging(t, m, u, "abcXX")
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+		repo.EXPECT().UpdateFileUploadOffset(u.ID, int64(10), gomock.Any()).Return(nil).Times(1)
+
+		result, err := m.Append(u.ID, 3, bytes.NewBufferString("defghij"))
+		if assert.NoError(t, err) {
+			assert.True(t, result.IsCompleted())
+			assert.Equal(t, "abcdefghij", readStaging(t, m, u))
+		}
+	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		u := newUpload(10, 0)
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+		repo.EXPECT().UpdateFileUploadOffset(u.ID, int64(4), gomock.Any()).Return(nil).Times(1)
+
+		_, err := m.Append(u.ID, 0, io.MultiReader(bytes.NewBufferString("abcd"), errReader{}))
+		if assert.Error(t, err) {
+			assert.Equal(t, "abcd", readStaging(t, m, u))
+		}
+	})
+
+	t.Run("offset mismatch", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		u := newUpload(10, 3)
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+
+		_, err := m.Append(u.ID, 0, bytes.NewBufferString("abc"))
+		assert.Equal(t, ErrOffsetMismatch, err)
+	})
+
+	t.Run("size exceeded", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		u := newUpload(5, 0)
+		writeStaging(t, m, u, "")
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+
+		_, err := m.Append(u.ID, 0, bytes.NewBufferString("abcdef"))
+		assert.Equal(t, ErrSizeExceeded, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, nil)
+
+		id := uuid.Must(uuid.NewV4())
+		repo.EXPECT().GetFileUpload(id).Return(nil, repository.ErrNotFound).Times(1)
+
+		_, err := m.Append(id, 0, bytes.NewBufferString("abc"))
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+func TestManagerImpl_Finalize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		fm := &fakeFileManager{}
+		m := initUM(t, repo, fm)
+
+		u := newUpload(5, 5)
+		writeStaging(t, m, u, "abcde")
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+		repo.EXPECT().DeleteFileUpload(u.ID).Return(nil).Times(1)
+
+		acl := file.ACL{u.CreatorID: true}
+		_, err := m.Finalize(u.ID, acl)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "abcde", string(fm.data))
+			assert.Equal(t, u.Name, fm.args.FileName)
+			assert.Equal(t, u.Size, fm.args.FileSize)
+			assert.Equal(t, u.Mime, fm.args.MimeType)
+			assert.Equal(t, model.FileTypeUserFile, fm.args.FileType)
+			assert.Equal(t, u.CreatorID, fm.args.CreatorID.UUID)
+			assert.Equal(t, u.ChannelID, fm.args.ChannelID.UUID)
+			assert.Equal(t, acl, fm.args.ACL)
+
+			_, err := os.Stat(m.stagingPath(u.ID))
+			assert.True(t, os.IsNotExist(err))
+		}
+	})
+
+	t.Run("incomplete", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, &fakeFileManager{})
+
+		u := newUpload(5, 4)
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+
+		_, err := m.Finalize(u.ID, nil)
+		assert.Equal(t, ErrIncomplete, err)
+	})
+
+	t.Run("save error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockFileRepository(ctrl)
+		m := initUM(t, repo, &fakeFileManager{err: errMock})
+
+		u := newUpload(5, 5)
+		writeStaging(t, m, u, "abcde")
+		repo.EXPECT().GetFileUpload(u.ID).Return(u, nil).Times(1)
+
+		_, err := m.Finalize(u.ID, nil)
+		if assert.Equal(t, errMock, err) {
+			// 再試行できるように残っている
+			assert.Equal(t, "abcde", readStaging(t, m, u))
+		}
+	})
+}
+
+func TestManagerImpl_Abort(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	repo := mock_repository.NewMockFileRepository(ctrl)
+	m := initUM(t, repo, nil)
+
+	u := newUpload(5, 2)
+	writeStaging(t, m, u, "ab")
+	repo.EXPECT().DeleteFileUpload(u.ID).Return(nil).Times(1)
+
+	if assert.NoError(t, m.Abort(u.ID)) {
+		_, err := os.Stat(m.stagingPath(u.ID))
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestManagerImpl_gc(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	repo := mock_repository.NewMockFileRepository(ctrl)
+	m := initUM(t, repo, nil)
+
+	u1 := newUpload(5, 2)
+	u2 := newUpload(5, 0)
+	writeStaging(t, m, u1, "ab")
+	writeStaging(t, m, u2, "")
+	repo.EXPECT().GetExpiredFileUploads(gomock.Any()).Return([]*model.FileUpload{u1, u2}, nil).Times(1)
+	repo.EXPECT().DeleteFileUpload(u1.ID).Return(nil).Times(1)
+	repo.EXPECT().DeleteFileUpload(u2.ID).Return(nil).Times(1)
+
+	m.gc()
+
+	for _, u := range []*model.FileUpload{u1, u2} {
+		_, err := os.Stat(m.stagingPath(u.ID))
+		assert.True(t, os.IsNotExist(err))
+	}
+}
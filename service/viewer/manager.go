@@ -1,16 +1,24 @@
 package viewer
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/gofrs/uuid"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/service/bus"
 	"sync"
 	"time"
 )
 
+const busTopic = "viewer"
+
 // Manager チャンネル閲覧者マネージャ
+//
+// 複数ノードで動作している場合、閲覧者状態は全ノードで共有されます。
 type Manager struct {
 	hub      *hub.Hub
+	bus      *bus.Bus
 	channels map[uuid.UUID]map[*viewer]struct{}
 	viewers  map[interface{}]*viewer
 	mu       sync.RWMutex
@@ -18,18 +26,36 @@ type Manager struct {
 
 type viewer struct {
 	key       interface{}
+	node      string
 	userID    uuid.UUID
 	channelID uuid.UUID
 	state     StateWithTime
 }
 
+// remoteKey 他のノードの閲覧者のキー
+type remoteKey struct {
+	node string
+	key  string
+}
+
+type viewerDelta struct {
+	Key       string    `json:"key"`
+	Removed   bool      `json:"removed,omitempty"`
+	UserID    uuid.UUID `json:"user_id,omitempty"`
+	ChannelID uuid.UUID `json:"channel_id,omitempty"`
+	State     int       `json:"state,omitempty"`
+	Time      time.Time `json:"time,omitempty"`
+}
+
 // NewManager チャンネル閲覧者マネージャーを生成します
-func NewManager(hub *hub.Hub) *Manager {
+func NewManager(hub *hub.Hub, b *bus.Bus) *Manager {
 	vm := &Manager{
 		hub:      hub,
+		bus:      b,
 		channels: map[uuid.UUID]map[*viewer]struct{}{},
 		viewers:  map[interface{}]*viewer{},
 	}
+	b.Share(busTopic, vm)
 
 	go func() {
 		for range time.NewTicker(5 * time.Minute).C {
@@ -51,8 +77,31 @@ func (vm *Manager) GetChannelViewers(channelID uuid.UUID) map[uuid.UUID]StateWit
 // SetViewer 指定したキーのチャンネル閲覧者状態を設定します
 func (vm *Manager) SetViewer(key interface{}, userID uuid.UUID, channelID uuid.UUID, state State) {
 	vm.mu.Lock()
-	defer vm.mu.Unlock()
+	v, changed := vm.set(key, "", userID, channelID, state, time.Now())
+	var d viewerDelta
+	if changed {
+		d = viewerDelta{Key: keyString(key), UserID: userID, ChannelID: channelID, State: int(state), Time: v.state.Time}
+	}
+	vm.mu.Unlock()
+
+	if changed {
+		vm.publishDelta(&d)
+	}
+}
+
+// RemoveViewer 指定したキーのチャンネル閲覧者状態を削除します
+func (vm *Manager) RemoveViewer(key interface{}) {
+	vm.mu.Lock()
+	removed := vm.remove(key)
+	vm.mu.Unlock()
+
+	if removed {
+		vm.publishDelta(&viewerDelta{Key: keyString(key), Removed: true})
+	}
+}
 
+// set 閲覧者状態を設定します。vm.muをロックしてから呼び出してください
+func (vm *Manager) set(key interface{}, node string, userID uuid.UUID, channelID uuid.UUID, state State, t time.Time) (*viewer, bool) {
 	cv, ok := vm.channels[channelID]
 	if !ok {
 		cv = map[*viewer]struct{}{}
@@ -64,7 +113,7 @@ func (vm *Manager) SetViewer(key interface{}, userID uuid.UUID, channelID uuid.U
 		if v.channelID == channelID {
 			if v.state.State == state {
 				// 何も変わってない
-				return
+				return v, false
 			}
 			// stateだけ変更
 			v.state.State = state
@@ -77,7 +126,7 @@ func (vm *Manager) SetViewer(key interface{}, userID uuid.UUID, channelID uuid.U
 			v.channelID = channelID
 			v.state = StateWithTime{
 				State: state,
-				Time:  time.Now(),
+				Time:  t,
 			}
 
 			vm.hub.Publish(hub.Message{
@@ -91,11 +140,12 @@ func (vm *Manager) SetViewer(key interface{}, userID uuid.UUID, channelID uuid.U
 	} else {
 		v = &viewer{
 			key:       key,
+			node:      node,
 			userID:    userID,
 			channelID: channelID,
 			state: StateWithTime{
 				State: state,
-				Time:  time.Now(),
+				Time:  t,
 			},
 		}
 		vm.viewers[key] = v
@@ -109,16 +159,14 @@ func (vm *Manager) SetViewer(key interface{}, userID uuid.UUID, channelID uuid.U
 			"viewers":    calculateChannelViewers(cv),
 		},
 	})
+	return v, true
 }
 
-// RemoveViewer 指定したキーのチャンネル閲覧者状態を削除します
-func (vm *Manager) RemoveViewer(key interface{}) {
-	vm.mu.Lock()
-	defer vm.mu.Unlock()
-
+// remove 閲覧者状態を削除します。vm.muをロックしてから呼び出してください
+func (vm *Manager) remove(key interface{}) bool {
 	v, ok := vm.viewers[key]
 	if !ok {
-		return
+		return false
 	}
 
 	cv := vm.channels[v.channelID]
@@ -132,6 +180,84 @@ func (vm *Manager) RemoveViewer(key interface{}) {
 			"viewers":    calculateChannelViewers(cv),
 		},
 	})
+	return true
+}
+
+func (vm *Manager) publishDelta(d *viewerDelta) {
+	b, _ := json.Marshal(d)
+	_ = vm.bus.Publish(busTopic, b)
+}
+
+// Snapshot implements bus.Shared interface.
+func (vm *Manager) Snapshot() []byte {
+	vm.mu.RLock()
+	deltas := make([]*viewerDelta, 0, len(vm.viewers))
+	for _, v := range vm.viewers {
+		if len(v.node) > 0 {
+			continue
+		}
+		deltas = append(deltas, &viewerDelta{
+			Key:       keyString(v.key),
+			UserID:    v.userID,
+			ChannelID: v.channelID,
+			State:     int(v.state.State),
+			Time:      v.state.Time,
+		})
+	}
+	vm.mu.RUnlock()
+	b, _ := json.Marshal(deltas)
+	return b
+}
+
+// ApplySnapshot implements bus.Shared interface.
+func (vm *Manager) ApplySnapshot(node string, data []byte) {
+	var deltas []*viewerDelta
+	if err := json.Unmarshal(data, &deltas); err != nil {
+		return
+	}
+	keys := make(map[remoteKey]bool, len(deltas))
+	for _, d := range deltas {
+		keys[remoteKey{node: node, key: d.Key}] = true
+	}
+
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	for key, v := range vm.viewers {
+		if v.node == node && !keys[key.(remoteKey)] {
+			vm.remove(key)
+		}
+	}
+	for _, d := range deltas {
+		vm.set(remoteKey{node: node, key: d.Key}, node, d.UserID, d.ChannelID, State(d.State), d.Time)
+	}
+}
+
+// Apply implements bus.Shared interface.
+func (vm *Manager) Apply(node string, data []byte) {
+	var d viewerDelta
+	if err := json.Unmarshal(data, &d); err != nil {
+		return
+	}
+
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	key := remoteKey{node: node, key: d.Key}
+	if d.Removed {
+		vm.remove(key)
+	} else {
+		vm.set(key, node, d.UserID, d.ChannelID, State(d.State), d.Time)
+	}
+}
+
+// Forget implements bus.Shared interface.
+func (vm *Manager) Forget(node string) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	for key, v := range vm.viewers {
+		if v.node == node {
+			vm.remove(key)
+		}
+	}
 }
 
 // 5分に１回呼び出される。チャンネルマップのお掃除
@@ -143,6 +269,14 @@ func (vm *Manager) gc() {
 	}
 }
 
+// keyString 他のノードと共有するための自ノードの閲覧者のキーの文字列表現を返します
+func keyString(key interface{}) string {
+	if k, ok := key.(interface{ Key() string }); ok {
+		return k.Key()
+	}
+	return fmt.Sprint(key)
+}
+
 func calculateChannelViewers(vs map[*viewer]struct{}) map[uuid.UUID]StateWithTime {
 	result := make(map[uuid.UUID]StateWithTime, len(vs))
 	for v := range vs {
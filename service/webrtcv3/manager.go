@@ -1,15 +1,19 @@
 package webrtcv3
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/gofrs/uuid"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/service/bus"
 	"sync"
 )
 
+const busTopic = "webrtc_v3"
+
 var (
 	ErrOccupied             = errors.New("connection has already existed")
 	webrtcUsingUsersCounter = promauto.NewGauge(prometheus.GaugeOpts{
@@ -23,20 +27,32 @@ var (
 )
 
 // Manager WebRTCマネージャー
+//
+// 複数ノードで動作している場合、ユーザーの状態はそのユーザーが接続しているノードが所有し、全ノードで共有されます。
 type Manager struct {
 	eventbus      *hub.Hub
+	bus           *bus.Bus
 	userStates    map[uuid.UUID]*userState
 	channelStates map[uuid.UUID]*channelState
 	statesLock    sync.RWMutex
 }
 
+type stateDelta struct {
+	ConnKey   string            `json:"conn_key"`
+	UserID    uuid.UUID         `json:"user_id"`
+	ChannelID uuid.UUID         `json:"channel_id,omitempty"`
+	Sessions  map[string]string `json:"sessions,omitempty"`
+}
+
 // NewManager WebRTCマネージャーを生成します
-func NewManager(eventbus *hub.Hub) *Manager {
+func NewManager(eventbus *hub.Hub, b *bus.Bus) *Manager {
 	manager := &Manager{
 		eventbus:      eventbus,
+		bus:           b,
 		userStates:    map[uuid.UUID]*userState{},
 		channelStates: map[uuid.UUID]*channelState{},
 	}
+	b.Share(busTopic, manager)
 	return manager
 }
 
@@ -56,11 +72,44 @@ func (m *Manager) SetState(connKey string, user, channel uuid.UUID, sessions map
 	}
 
 	m.statesLock.Lock()
-	defer m.statesLock.Unlock()
+	if us, ok := m.userStates[user]; ok && len(us.node) > 0 {
+		// 他のノードのコネクションでロック中
+		m.statesLock.Unlock()
+		return ErrOccupied
+	}
+	us := m.set("", connKey, user, channel, sessions)
+	d := &stateDelta{ConnKey: us.connKey, UserID: user, ChannelID: channel, Sessions: sessions}
+	m.statesLock.Unlock()
+
+	m.publishDelta(d)
+	return nil
+}
+
+// ResetState 指定したユーザーの状態を削除します
+func (m *Manager) ResetState(connKey string, user uuid.UUID) error {
+	m.statesLock.Lock()
+	us, ok := m.userStates[user]
+	if !ok {
+		m.statesLock.Unlock()
+		return nil
+	}
+	if len(us.node) > 0 || us.connKey != connKey {
+		m.statesLock.Unlock()
+		return ErrOccupied
+	}
+	m.reset(user)
+	m.statesLock.Unlock()
+
+	m.publishDelta(&stateDelta{ConnKey: connKey, UserID: user})
+	return nil
+}
 
+// set 指定した状態をセットします。m.statesLockをロックしてから呼び出してください
+func (m *Manager) set(node, connKey string, user, channel uuid.UUID, sessions map[string]string) *userState {
 	us, ok := m.userStates[user]
 	if !ok {
 		us = &userState{
+			node:    node,
 			connKey: connKey,
 			userID:  user,
 		}
@@ -94,21 +143,14 @@ func (m *Manager) SetState(connKey string, user, channel uuid.UUID, sessions map
 			"sessions":   us.sessions,
 		},
 	})
-	return nil
+	return us
 }
 
-// ResetState 指定したユーザーの状態を削除します
-func (m *Manager) ResetState(connKey string, user uuid.UUID) error {
-	m.statesLock.Lock()
-	defer m.statesLock.Unlock()
-
+// reset 指定したユーザーの状態を削除します。m.statesLockをロックしてから呼び出してください
+func (m *Manager) reset(user uuid.UUID) {
 	us, ok := m.userStates[user]
 	if !ok {
-		return nil
-	}
-
-	if us.connKey != connKey {
-		return ErrOccupied
+		return
 	}
 
 	delete(m.userStates, user)
@@ -128,5 +170,86 @@ func (m *Manager) ResetState(connKey string, user uuid.UUID) error {
 			"sessions":   map[string]string{},
 		},
 	})
-	return nil
+}
+
+func (m *Manager) publishDelta(d *stateDelta) {
+	b, _ := json.Marshal(d)
+	_ = m.bus.Publish(busTopic, b)
+}
+
+// applyRemote 他のノードが所有する状態を適用します。m.statesLockをロックしてから呼び出してください
+func (m *Manager) applyRemote(node string, d *stateDelta) {
+	us, ok := m.userStates[d.UserID]
+	if ok && us.node != node {
+		// 別のノードが所有している状態は上書きしない
+		return
+	}
+	if len(d.Sessions) == 0 {
+		m.reset(d.UserID)
+		return
+	}
+	if ok && us.connKey != d.ConnKey {
+		m.reset(d.UserID)
+	}
+	m.set(node, d.ConnKey, d.UserID, d.ChannelID, d.Sessions)
+}
+
+// Snapshot implements bus.Shared interface.
+func (m *Manager) Snapshot() []byte {
+	m.statesLock.RLock()
+	deltas := make([]*stateDelta, 0, len(m.userStates))
+	for _, us := range m.userStates {
+		if len(us.node) == 0 {
+			deltas = append(deltas, &stateDelta{ConnKey: us.connKey, UserID: us.userID, ChannelID: us.channelID, Sessions: us.sessions})
+		}
+	}
+	m.statesLock.RUnlock()
+	b, _ := json.Marshal(deltas)
+	return b
+}
+
+// ApplySnapshot implements bus.Shared interface.
+func (m *Manager) ApplySnapshot(node string, data []byte) {
+	var deltas []*stateDelta
+	if err := json.Unmarshal(data, &deltas); err != nil {
+		return
+	}
+	users := make(map[uuid.UUID]bool, len(deltas))
+	for _, d := range deltas {
+		users[d.UserID] = true
+	}
+
+	m.statesLock.Lock()
+	defer m.statesLock.Unlock()
+	for user, us := range m.userStates {
+		if us.node == node && !users[user] {
+			m.reset(user)
+		}
+	}
+	for _, d := range deltas {
+		m.applyRemote(node, d)
+	}
+}
+
+// Apply implements bus.Shared interface.
+func (m *Manager) Apply(node string, data []byte) {
+	var d stateDelta
+	if err := json.Unmarshal(data, &d); err != nil {
+		return
+	}
+
+	m.statesLock.Lock()
+	defer m.statesLock.Unlock()
+	m.applyRemote(node, &d)
+}
+
+// Forget implements bus.Shared interface.
+func (m *Manager) Forget(node string) {
+	m.statesLock.Lock()
+	defer m.statesLock.Unlock()
+	for user, us := range m.userStates {
+		if us.node == node {
+			m.reset(user)
+		}
+	}
 }
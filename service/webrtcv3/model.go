@@ -12,6 +12,8 @@ type UserState interface {
 }
 
 type userState struct {
+	// node 状態を所有するノード (自ノードの場合は空文字列)
+	node      string
 	connKey   string
 	userID    uuid.UUID
 	channelID uuid.UUID
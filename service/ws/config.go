@@ -13,6 +13,10 @@ const (
 	pingPeriod         = (pongWait * 9) / 10
 	maxReadMessageSize = 1 << 9 // 512B
 	messageBufferSize  = 256
+	replayBufferSize   = 128
+	replayRetention    = 5 * time.Minute
+	replayGCInterval   = time.Minute
+	resumeWait         = time.Second
 )
 
 var (
@@ -21,5 +25,6 @@ var (
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
 		CheckOrigin:     func(r *http.Request) bool { return true },
+		Subprotocols:    []string{protocolJSONv1},
 	}
 )
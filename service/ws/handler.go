@@ -5,89 +5,98 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"go.uber.org/zap"
+	"strconv"
 	"strings"
 )
 
+// コマンドのエラーコード
+const (
+	errCodeInvalidMessage = "invalid_message"
+	errCodeUnknownCommand = "unknown_command"
+	errCodeInvalidArgs    = "invalid_args"
+	errCodeForbidden      = "forbidden"
+	errCodeLocked         = "locked"
+	errCodeInternal       = "internal_error"
+)
+
+// commandError コマンドの実行エラー
+type commandError struct {
+	code    string
+	message string
+}
+
+func errInvalidArgs(format string, a ...interface{}) *commandError {
+	return &commandError{code: errCodeInvalidArgs, message: fmt.Sprintf(format, a...)}
+}
+
 func (s *session) commandHandler(cmd string) {
 	args := strings.Split(strings.TrimSpace(cmd), ":")
 
-Command:
+	var err *commandError
 	switch strings.ToLower(args[0]) {
 	case "viewstate":
 		// viewstate:{チャンネルID}(:{状態})
 		if len(args) < 2 {
-			// 引数が不正
-			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			err = errInvalidArgs("invalid args: %s", cmd)
 			break
 		}
 
 		if str := strings.ToLower(args[1]); str == "null" || str == "" {
 			// viewstate:null
-			s.setViewState(uuid.Nil, 0)
-			s.streamer.vm.RemoveViewer(s)
+			err = s.execViewState(uuid.Nil, 0)
 			break
 		}
 
-		cid, err := uuid.FromString(args[1])
-		if err != nil {
+		cid, e := uuid.FromString(args[1])
+		if e != nil {
 			// チャンネルIDが不正
-			s.sendErrorMessage(fmt.Sprintf("invalid id: %s", args[1]))
+			err = errInvalidArgs("invalid id: %s", args[1])
 			break
 		}
 
 		if len(args) < 3 {
-			// 引数が不正
-			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			err = errInvalidArgs("invalid args: %s", cmd)
 			break
 		}
 
-		// TODO channelのアクセスチェック
-
-		s.setViewState(cid, viewer.StateFromString(args[2]))
-		s.streamer.vm.SetViewer(s, s.userID, s.viewState.channelID, s.viewState.state)
+		err = s.execViewState(cid, viewer.StateFromString(args[2]))
 
 	case "rtcstate":
 		// rtcstate:{チャンネルID}:({状態}:{セッションID})*
 		if len(args) < 2 {
-			// 引数が不正
-			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			err = errInvalidArgs("invalid args: %s", cmd)
 			break
 		}
 
 		// {チャンネルID} or null
 		if str := strings.ToLower(args[1]); str == "null" || str == "" {
 			// リセット
-			if s.streamer.webrtc.ResetState(s.Key(), s.UserID()) != nil {
-				// 別のコネクションでロック中
-				s.sendErrorMessage("your webrtc state is locked by another ws connection")
-			}
+			err = s.execRTCState(uuid.Nil, nil)
 			break
 		}
-		cid, err := uuid.FromString(args[1])
-		if err != nil {
+		cid, e := uuid.FromString(args[1])
+		if e != nil {
 			// チャンネルIDが不正
-			s.sendErrorMessage(fmt.Sprintf("invalid id: %s", args[1]))
+			err = errInvalidArgs("invalid id: %s", args[1])
 			break
 		}
 
 		// ({状態}:{セッションID})*
 		if len(args) < 3 {
-			// 引数が不正
-			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			err = errInvalidArgs("invalid args: %s", cmd)
 			break
 		}
 		if str := strings.ToLower(args[2]); str == "null" || str == "" {
 			// リセット
-			if s.streamer.webrtc.ResetState(s.Key(), s.UserID()) != nil {
-				// 別のコネクションでロック中
-				s.sendErrorMessage("your webrtc state is locked by another ws connection")
-			}
+			err = s.execRTCState(uuid.Nil, nil)
 			break
 		}
 
 		if (len(args)-2)%2 == 0 {
 			// 状態+セッションのペアが出来ていない
-			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			err = errInvalidArgs("invalid args: %s", cmd)
 			break
 		}
 
@@ -96,19 +105,51 @@ Command:
 			state, session := args[2*i], args[2*i+1]
 			if len(state) == 0 || len(session) == 0 {
 				// 状態+セッションのペアが出来ていない
-				s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
-				break Command
+				err = errInvalidArgs("invalid args: %s", cmd)
+				break
 			}
 			sessions[session] = state
 		}
+		if err != nil {
+			break
+		}
+
+		err = s.execRTCState(cid, sessions)
+
+	case "typing":
+		// typing:{チャンネルID}
+		if len(args) != 2 {
+			err = errInvalidArgs("invalid args: %s", cmd)
+			break
+		}
 
-		_ = s.streamer.webrtc.SetState(s.Key(), s.UserID(), cid, sessions)
+		cid, e := uuid.FromString(args[1])
+		if e != nil {
+			// チャンネルIDが不正
+			err = errInvalidArgs("invalid id: %s", args[1])
+			break
+		}
+		err = s.execTyping(cid)
+
+	case "resume":
+		// resume:{シーケンス番号}
+		if len(args) != 2 {
+			err = errInvalidArgs("invalid args: %s", cmd)
+			break
+		}
+
+		seq, e := strconv.ParseInt(args[1], 10, 64)
+		if e != nil {
+			// シーケンス番号が不正
+			err = errInvalidArgs("invalid seq: %s", args[1])
+			break
+		}
+		err = s.streamer.resume(s, seq)
 
 	case "timeline_streaming":
 		// timeline_streaming:(on|off|true|false)
 		if len(args) != 2 {
-			// 引数が不正
-			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			err = errInvalidArgs("invalid args: %s", cmd)
 			break
 		}
 
@@ -120,14 +161,75 @@ Command:
 			s.setTimelineStreaming(false)
 
 		default:
-			// 引数が不正
-			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			err = errInvalidArgs("invalid args: %s", cmd)
 		}
 
 	default:
 		// 不明なコマンド
-		s.sendErrorMessage(fmt.Sprintf("unknown command: %s", cmd))
+		err = &commandError{code: errCodeUnknownCommand, message: fmt.Sprintf("unknown command: %s", cmd)}
+	}
+
+	if err != nil {
+		s.sendErrorMessage(err.message)
+	}
+}
+
+// execViewState セッションのチャンネル閲覧状態を設定します
+//
+// cidがuuid.Nilの場合は閲覧状態を解除します。
+func (s *session) execViewState(cid uuid.UUID, state viewer.State) *commandError {
+	if cid == uuid.Nil {
+		s.setViewState(uuid.Nil, 0)
+		s.streamer.vm.RemoveViewer(s)
+		return nil
+	}
+
+	if err := s.checkChannelAccess(cid); err != nil {
+		return err
+	}
+
+	s.setViewState(cid, state)
+	s.streamer.vm.SetViewer(s, s.userID, cid, state)
+	return nil
+}
+
+// execTyping チャンネルでメッセージを入力中であることを通知します
+func (s *session) execTyping(cid uuid.UUID) *commandError {
+	if err := s.checkChannelAccess(cid); err != nil {
+		return err
+	}
+
+	s.streamer.typing.Typing(s.userID, cid)
+	return nil
+}
+
+func (s *session) checkChannelAccess(cid uuid.UUID) *commandError {
+	ok, err := s.streamer.cm.IsChannelAccessibleToUser(s.userID, cid)
+	if err != nil {
+		s.streamer.logger.Error("failed to IsChannelAccessibleToUser", zap.Error(err), zap.Stringer("userID", s.userID), zap.Stringer("channelID", cid))
+		return &commandError{code: errCodeInternal, message: "internal error"}
+	}
+	if !ok {
+		return &commandError{code: errCodeForbidden, message: fmt.Sprintf("you are not allowed to access the channel: %s", cid)}
+	}
+	return nil
+}
+
+// execRTCState 自分のWebRTC状態を設定します
+//
+// cidがuuid.Nilの場合は状態をリセットします。
+func (s *session) execRTCState(cid uuid.UUID, sessions map[string]string) *commandError {
+	var err error
+	if cid == uuid.Nil {
+		err = s.streamer.webrtc.ResetState(s.Key(), s.UserID())
+	} else {
+		err = s.streamer.webrtc.SetState(s.Key(), s.UserID(), cid, sessions)
+	}
+	if err == webrtcv3.ErrOccupied {
+		// 別のコネクションでロック中
+		return &commandError{code: errCodeLocked, message: "your webrtc state is locked by another ws connection"}
 	}
+	return nil
 }
 
 func (s *session) sendErrorMessage(error string) {
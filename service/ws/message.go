@@ -1,5 +1,7 @@
 package ws
 
+import jsoniter "github.com/json-iterator/go"
+
 type rawMessage struct {
 	t    int
 	data []byte
@@ -7,6 +9,7 @@ type rawMessage struct {
 
 type message struct {
 	Type string      `json:"type"`
+	Seq  int64       `json:"seq,omitempty"`
 	Body interface{} `json:"body"`
 }
 
@@ -17,6 +20,15 @@ func makeMessage(t string, b interface{}) (m *message) {
 	}
 }
 
+// makeSeqMessage シーケンス番号付きのメッセージを生成します
+func makeSeqMessage(t string, seq int64, b jsoniter.RawMessage) (m *message) {
+	return &message{
+		Type: t,
+		Seq:  seq,
+		Body: b,
+	}
+}
+
 func (m *message) toJSON() (b []byte) {
 	b, _ = json.Marshal(m)
 	return
@@ -0,0 +1,172 @@
+package ws
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/traPtitech/traQ/service/viewer"
+	"strings"
+)
+
+const (
+	// protocolJSONv1 JSONコマンドプロトコル(v1)のWebSocketサブプロトコル名
+	//
+	// サブプロトコルを指定せずに接続した場合は、従来のテキストコマンドプロトコルになります。
+	protocolJSONv1 = "traq.json.v1"
+)
+
+// jsonCommand JSONプロトコルのコマンド
+type jsonCommand struct {
+	// ID リクエストID 応答にそのまま含まれます
+	ID   string              `json:"id"`
+	Type string              `json:"type"`
+	Args jsoniter.RawMessage `json:"args"`
+}
+
+type viewStateArgs struct {
+	ChannelID *uuid.UUID `json:"channelId"`
+	State     string     `json:"state"`
+}
+
+type rtcStateArgs struct {
+	ChannelID *uuid.UUID `json:"channelId"`
+	Sessions  []struct {
+		State     string `json:"state"`
+		SessionID string `json:"sessionId"`
+	} `json:"sessions"`
+}
+
+type typingArgs struct {
+	ChannelID uuid.UUID `json:"channelId"`
+}
+
+type resumeArgs struct {
+	Seq *int64 `json:"seq"`
+}
+
+type timelineStreamingArgs struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ackBody ACKメッセージのボディ
+type ackBody struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// errorBody JSONプロトコルのERRORメッセージのボディ
+type errorBody struct {
+	ID      string `json:"id"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *session) jsonCommandHandler(data []byte) {
+	var cmd jsonCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		s.sendJSONError("", &commandError{code: errCodeInvalidMessage, message: "malformed command"})
+		return
+	}
+	parseArgs := func(v interface{}) *commandError {
+		if len(cmd.Args) == 0 {
+			return errInvalidArgs("args is required")
+		}
+		if err := json.Unmarshal(cmd.Args, v); err != nil {
+			return errInvalidArgs("invalid args: %s", err)
+		}
+		return nil
+	}
+
+	var err *commandError
+	switch strings.ToLower(cmd.Type) {
+	case "viewstate":
+		var args viewStateArgs
+		if err = parseArgs(&args); err != nil {
+			break
+		}
+		if args.ChannelID == nil || *args.ChannelID == uuid.Nil {
+			err = s.execViewState(uuid.Nil, 0)
+			break
+		}
+		if len(args.State) == 0 {
+			err = errInvalidArgs("state is required")
+			break
+		}
+		err = s.execViewState(*args.ChannelID, viewer.StateFromString(args.State))
+
+	case "rtcstate":
+		var args rtcStateArgs
+		if err = parseArgs(&args); err != nil {
+			break
+		}
+		if args.ChannelID == nil || *args.ChannelID == uuid.Nil || len(args.Sessions) == 0 {
+			err = s.execRTCState(uuid.Nil, nil)
+			break
+		}
+		sessions := make(map[string]string, len(args.Sessions))
+		for _, v := range args.Sessions {
+			if len(v.State) == 0 || len(v.SessionID) == 0 {
+				err = errInvalidArgs("state and sessionId are required")
+				break
+			}
+			sessions[v.SessionID] = v.State
+		}
+		if err != nil {
+			break
+		}
+		err = s.execRTCState(*args.ChannelID, sessions)
+
+	case "typing":
+		var args typingArgs
+		if err = parseArgs(&args); err != nil {
+			break
+		}
+		if args.ChannelID == uuid.Nil {
+			err = errInvalidArgs("channelId is required")
+			break
+		}
+		err = s.execTyping(args.ChannelID)
+
+	case "resume":
+		var args resumeArgs
+		if err = parseArgs(&args); err != nil {
+			break
+		}
+		if args.Seq == nil {
+			err = errInvalidArgs("seq is required")
+			break
+		}
+		err = s.streamer.resume(s, *args.Seq)
+
+	case "timeline_streaming":
+		var args timelineStreamingArgs
+		if err = parseArgs(&args); err != nil {
+			break
+		}
+		if args.Enabled == nil {
+			err = errInvalidArgs("enabled is required")
+			break
+		}
+		s.setTimelineStreaming(*args.Enabled)
+
+	default:
+		err = &commandError{code: errCodeUnknownCommand, message: fmt.Sprintf("unknown command: %s", cmd.Type)}
+	}
+
+	if err != nil {
+		s.sendJSONError(cmd.ID, err)
+		return
+	}
+	_ = s.writeMessage(&rawMessage{
+		t:    websocket.TextMessage,
+		data: makeMessage("ACK", &ackBody{ID: cmd.ID, Type: cmd.Type}).toJSON(),
+	})
+}
+
+func (s *session) sendJSONError(id string, err *commandError) {
+	_ = s.writeMessage(&rawMessage{
+		t:    websocket.TextMessage,
+		data: makeMessage("ERROR", &errorBody{ID: id, Code: err.code, Message: err.message}).toJSON(),
+	})
+}
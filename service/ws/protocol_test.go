@@ -0,0 +1,140 @@
+package ws
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/service/bus"
+	"github.com/traPtitech/traQ/service/channel/mock_channel"
+	"github.com/traPtitech/traQ/service/typing"
+	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func setupSession(t *testing.T, cm *mock_channel.MockManager) *session {
+	t.Helper()
+
+	h := hub.New()
+	b := bus.New(bus.NewLocalTransport(), zap.NewNop())
+	s := NewStreamer(h, viewer.NewManager(h, b), webrtcv3.NewManager(h, b), cm, typing.NewManager(h), zap.NewNop())
+	t.Cleanup(func() { _ = s.Close() })
+	return &session{
+		key:      "test",
+		userID:   uuid.Must(uuid.NewV4()),
+		protocol: protocolJSONv1,
+		open:     true,
+		streamer: s,
+		send:     make(chan *rawMessage, messageBufferSize),
+	}
+}
+
+func receiveMessage(t *testing.T, s *session) testMessage {
+	t.Helper()
+	select {
+	case raw := <-s.send:
+		var m testMessage
+		require.NoError(t, json.Unmarshal(raw.data, &m))
+		return m
+	default:
+		t.Fatal("no message was sent")
+		return testMessage{}
+	}
+}
+
+func TestSession_JSONCommandHandler(t *testing.T) {
+	t.Parallel()
+
+	accessible := uuid.NewV3(uuid.Nil, "accessible")
+	forbidden := uuid.NewV3(uuid.Nil, "forbidden")
+	broken := uuid.NewV3(uuid.Nil, "broken")
+
+	tests := []struct {
+		name string
+		data string
+		// id 応答のリクエストID
+		id string
+		// code 空文字の場合はACKを期待する
+		code string
+	}{
+		{name: "malformed json", data: `{"id":`, id: "", code: errCodeInvalidMessage},
+		{name: "unknown command", data: `{"id":"1","type":"unknown"}`, id: "1", code: errCodeUnknownCommand},
+		{name: "case insensitive", data: `{"id":"1","type":"ViewState","args":{"channelId":null}}`, id: "1"},
+		{name: "viewstate without args", data: `{"id":"1","type":"viewstate"}`, id: "1", code: errCodeInvalidArgs},
+		{name: "viewstate invalid args", data: `{"id":"1","type":"viewstate","args":{"channelId":1}}`, id: "1", code: errCodeInvalidArgs},
+		{name: "viewstate without state", data: `{"id":"1","type":"viewstate","args":{"channelId":"` + accessible.String() + `"}}`, id: "1", code: errCodeInvalidArgs},
+		{name: "viewstate reset", data: `{"id":"1","type":"viewstate","args":{"channelId":null}}`, id: "1"},
+		{name: "viewstate", data: `{"id":"1","type":"viewstate","args":{"channelId":"` + accessible.String() + `","state":"monitoring"}}`, id: "1"},
+		{name: "viewstate forbidden", data: `{"id":"1","type":"viewstate","args":{"channelId":"` + forbidden.String() + `","state":"monitoring"}}`, id: "1", code: errCodeForbidden},
+		{name: "viewstate internal error", data: `{"id":"1","type":"viewstate","args":{"channelId":"` + broken.String() + `","state":"monitoring"}}`, id: "1", code: errCodeInternal},
+		{name: "rtcstate without sessionId", data: `{"id":"1","type":"rtcstate","args":{"channelId":"` + accessible.String() + `","sessions":[{"state":"joined"}]}}`, id: "1", code: errCodeInvalidArgs},
+		{name: "rtcstate reset", data: `{"id":"1","type":"rtcstate","args":{"channelId":null}}`, id: "1"},
+		{name: "typing without channelId", data: `{"id":"1","type":"typing","args":{}}`, id: "1", code: errCodeInvalidArgs},
+		{name: "typing forbidden", data: `{"id":"1","type":"typing","args":{"channelId":"` + forbidden.String() + `"}}`, id: "1", code: errCodeForbidden},
+		{name: "typing", data: `{"id":"1","type":"typing","args":{"channelId":"` + accessible.String() + `"}}`, id: "1"},
+		{name: "resume without seq", data: `{"id":"1","type":"resume","args":{}}`, id: "1", code: errCodeInvalidArgs},
+		{name: "timeline_streaming without enabled", data: `{"id":"1","type":"timeline_streaming","args":{}}`, id: "1", code: errCodeInvalidArgs},
+		{name: "timeline_streaming", data: `{"id":"1","type":"timeline_streaming","args":{"enabled":true}}`, id: "1"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			cm := mock_channel.NewMockManager(ctrl)
+			cm.EXPECT().IsChannelAccessibleToUser(gomock.Any(), accessible).Return(true, nil).AnyTimes()
+			cm.EXPECT().IsChannelAccessibleToUser(gomock.Any(), forbidden).Return(false, nil).AnyTimes()
+			cm.EXPECT().IsChannelAccessibleToUser(gomock.Any(), broken).Return(false, errors.New("error")).AnyTimes()
+			s := setupSession(t, cm)
+
+			s.jsonCommandHandler([]byte(tt.data))
+			m := receiveMessage(t, s)
+			if tt.code == "" {
+				if assert.Equal(t, "ACK", m.Type) {
+					var body ackBody
+					require.NoError(t, json.Unmarshal(m.Body, &body))
+					assert.Equal(t, tt.id, body.ID)
+				}
+			} else {
+				if assert.Equal(t, "ERROR", m.Type) {
+					var body errorBody
+					require.NoError(t, json.Unmarshal(m.Body, &body))
+					assert.Equal(t, tt.id, body.ID)
+					assert.Equal(t, tt.code, body.Code)
+					assert.NotEmpty(t, body.Message)
+				}
+			}
+			assert.Len(t, s.send, 0)
+		})
+	}
+}
+
+func TestSession_CommandHandler_ViewStateForbidden(t *testing.T) {
+	t.Parallel()
+
+	channelID := uuid.NewV3(uuid.Nil, "forbidden")
+	ctrl := gomock.NewController(t)
+	cm := mock_channel.NewMockManager(ctrl)
+	cm.EXPECT().IsChannelAccessibleToUser(gomock.Any(), channelID).Return(false, nil)
+	s := setupSession(t, cm)
+	s.protocol = ""
+
+	s.commandHandler("viewstate:" + channelID.String() + ":monitoring")
+	m := receiveMessage(t, s)
+	if assert.Equal(t, "ERROR", m.Type) {
+		var body string
+		require.NoError(t, json.Unmarshal(m.Body, &body))
+		assert.Contains(t, body, channelID.String())
+	}
+
+	// 閲覧状態は変更されない
+	cid, state := s.ViewState()
+	assert.Equal(t, uuid.Nil, cid)
+	assert.Equal(t, viewer.StateNone, state)
+	assert.Empty(t, s.streamer.vm.GetChannelViewers(channelID))
+}
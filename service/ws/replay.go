@@ -0,0 +1,166 @@
+package ws
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/traPtitech/traQ/service/viewer"
+	"go.uber.org/zap"
+	"time"
+)
+
+// replayEntry 再送バッファに記録するイベント
+type replayEntry struct {
+	msg *rawMessage
+	// targetFunc イベントの送信先の判定関数
+	//
+	// 再送バッファはユーザー毎なので、再送時に切断されたセッションが送信先だったかどうかを判定するのに使います。
+	targetFunc TargetFunc
+}
+
+// seqBody RESUMED, RESYNCメッセージのボディ
+type seqBody struct {
+	Seq int64 `json:"seq"`
+}
+
+// ghostSession 切断されたセッションの状態
+//
+// 切断中にそのセッションに送られるはずだったイベントを再送バッファに記録するために使います。
+type ghostSession struct {
+	key               string
+	userID            uuid.UUID
+	channelID         uuid.UUID
+	state             viewer.State
+	timelineStreaming bool
+	// connectedSeq 接続時点のシーケンス番号
+	connectedSeq int64
+	// disconnectedSeq 切断時点のシーケンス番号
+	disconnectedSeq int64
+	expiresAt       time.Time
+}
+
+func makeGhostSession(s *session, disconnectedSeq int64, expiresAt time.Time) *ghostSession {
+	cid, state := s.ViewState()
+	return &ghostSession{
+		key:               s.Key(),
+		userID:            s.UserID(),
+		channelID:         cid,
+		state:             state,
+		timelineStreaming: s.TimelineStreaming(),
+		connectedSeq:      s.connectedSeq,
+		disconnectedSeq:   disconnectedSeq,
+		expiresAt:         expiresAt,
+	}
+}
+
+// Key implements Session interface.
+func (g *ghostSession) Key() string {
+	return g.key
+}
+
+// UserID implements Session interface.
+func (g *ghostSession) UserID() uuid.UUID {
+	return g.userID
+}
+
+// ViewState implements Session interface.
+func (g *ghostSession) ViewState() (uuid.UUID, viewer.State) {
+	return g.channelID, g.state
+}
+
+// TimelineStreaming implements Session interface.
+func (g *ghostSession) TimelineStreaming() bool {
+	return g.timelineStreaming
+}
+
+// resume 指定したシーケンス番号より後、セッションの接続時点までのイベントを再送し、ライブ配信を開始します
+//
+// resumeはライブ配信の開始前(最初のコマンド)でなければなりません。
+// 再送するのは、切断されたセッションのうちシーケンス番号afterのイベントを受け取り得たものが送信先だったイベントのみです。
+// 再送できない場合はRESYNCメッセージを送信し、クライアントに全体の再取得を求めます。
+func (s *Streamer) resume(session *session, after int64) *commandError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session.active {
+		return errInvalidArgs("resume must be the first command")
+	}
+
+	if s.replayEvents(session, after) {
+		_ = session.writeMessage(&rawMessage{
+			t:    websocket.TextMessage,
+			data: makeMessage("RESUMED", &seqBody{Seq: session.connectedSeq}).toJSON(),
+		})
+	}
+	s.flushPending(session)
+	return nil
+}
+
+// activate セッションへのライブ配信を開始します 既に開始されている場合は何もしません
+func (s *Streamer) activate(session *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session.active {
+		return
+	}
+	s.flushPending(session)
+}
+
+// replayEvents (after, connectedSeq]のイベントを再送します s.muのロックを取ってから呼び出してください
+//
+// 再送できなかった場合はfalseを返します。
+func (s *Streamer) replayEvents(session *session, after int64) bool {
+	events, ok := s.replay.Range(session.userID, after, session.connectedSeq)
+	if !ok {
+		latest, _ := s.replay.Latest(session.userID)
+		_ = session.writeMessage(&rawMessage{
+			t:    websocket.TextMessage,
+			data: makeMessage("RESYNC", &seqBody{Seq: latest}).toJSON(),
+		})
+		return false
+	}
+
+	// afterのイベントを受け取った後に切断されたセッションが、再開元のセッションの候補
+	var ghosts []*ghostSession
+	for g := range s.ghosts {
+		if g.userID == session.userID && g.connectedSeq <= after && after <= g.disconnectedSeq {
+			ghosts = append(ghosts, g)
+		}
+	}
+
+	for _, e := range events {
+		entry := e.(*replayEntry)
+		if !entry.targetsAny(ghosts) {
+			continue
+		}
+		if err := session.writeMessage(entry.msg); err != nil {
+			// 再送しきれなかったので、クライアントに再接続させる
+			s.logger.Warn("failed to replay events", zap.Error(err), zap.Stringer("userID", session.userID))
+			session.close()
+			return false
+		}
+	}
+	return true
+}
+
+// flushPending 保留していたイベントを送信し、ライブ配信を開始します s.muのロックを取ってから呼び出してください
+func (s *Streamer) flushPending(session *session) {
+	session.active = true
+	for _, m := range session.pending {
+		if err := session.writeMessage(m); err != nil {
+			if err == ErrBufferIsFull {
+				s.logger.Warn("Discard a pending message because the session's buffer is full.", zap.Stringer("userID", session.userID))
+				continue
+			}
+			break
+		}
+	}
+	session.pending = nil
+}
+
+func (e *replayEntry) targetsAny(sessions []*ghostSession) bool {
+	for _, g := range sessions {
+		if e.targetFunc(g) {
+			return true
+		}
+	}
+	return false
+}
@@ -23,6 +23,14 @@ type Session interface {
 type session struct {
 	key    string
 	userID uuid.UUID
+	// connectedSeq 接続時点のシーケンス番号
+	connectedSeq int64
+	// active ライブ配信が開始されたかどうか
+	//
+	// 再送するイベントが接続後のイベントより先に届くように、開始されるまでイベントはpendingに保留されます。
+	// active, pendingはStreamer.muで保護されます。
+	active  bool
+	pending []*rawMessage
 
 	viewState struct {
 		channelID uuid.UUID
@@ -31,8 +39,10 @@ type session struct {
 	enabledTimelineStreaming bool
 	sync.RWMutex
 
-	req      *http.Request
-	conn     *websocket.Conn
+	req  *http.Request
+	conn *websocket.Conn
+	// protocol ネゴシエートされたサブプロトコル 空文字の場合はテキストコマンドプロトコル
+	protocol string
 	open     bool
 	streamer *Streamer
 	send     chan *rawMessage
@@ -53,7 +63,13 @@ func (s *session) readLoop() {
 		}
 
 		if t == websocket.TextMessage {
-			s.commandHandler(string(m))
+			if s.protocol == protocolJSONv1 {
+				s.jsonCommandHandler(m)
+			} else {
+				s.commandHandler(string(m))
+			}
+			// 最初のコマンドがresume以外の場合は、ここでライブ配信を開始する
+			s.streamer.activate(s)
 		}
 
 		if t == websocket.BinaryMessage {
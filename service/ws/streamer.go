@@ -4,17 +4,22 @@ import (
 	"errors"
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/typing"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/replay"
 	"go.uber.org/zap"
 	"net/http"
 	"sync"
+	"time"
 )
 
 var (
@@ -34,23 +39,32 @@ type Streamer struct {
 	hub        *hub.Hub
 	vm         *viewer.Manager
 	webrtc     *webrtcv3.Manager
+	cm         channel.Manager
+	typing     *typing.Manager
 	logger     *zap.Logger
 	sessions   map[*session]struct{}
+	ghosts     map[*ghostSession]struct{}
+	replay     *replay.Store
 	register   chan *session
 	unregister chan *session
 	stop       chan struct{}
 	open       bool
 	mu         sync.RWMutex
+	writeMu    sync.Mutex
 }
 
 // NewStreamer WebSocketストリーマーを生成し起動します
-func NewStreamer(hub *hub.Hub, vm *viewer.Manager, webrtc *webrtcv3.Manager, logger *zap.Logger) *Streamer {
+func NewStreamer(hub *hub.Hub, vm *viewer.Manager, webrtc *webrtcv3.Manager, cm channel.Manager, typing *typing.Manager, logger *zap.Logger) *Streamer {
 	h := &Streamer{
 		hub:        hub,
 		vm:         vm,
 		webrtc:     webrtc,
+		cm:         cm,
+		typing:     typing,
 		logger:     logger.Named("ws"),
 		sessions:   make(map[*session]struct{}),
+		ghosts:     make(map[*ghostSession]struct{}),
+		replay:     replay.NewStore(replayBufferSize, replayRetention),
 		register:   make(chan *session),
 		unregister: make(chan *session),
 		stop:       make(chan struct{}),
@@ -62,10 +76,14 @@ func NewStreamer(hub *hub.Hub, vm *viewer.Manager, webrtc *webrtcv3.Manager, log
 }
 
 func (s *Streamer) run() {
+	gc := time.NewTicker(replayGCInterval)
+	defer gc.Stop()
+
 	for {
 		select {
 		case session := <-s.register:
 			s.mu.Lock()
+			session.connectedSeq = s.replay.Acquire(session.userID)
 			s.sessions[session] = struct{}{}
 			s.mu.Unlock()
 
@@ -73,9 +91,22 @@ func (s *Streamer) run() {
 			if _, ok := s.sessions[session]; ok {
 				s.mu.Lock()
 				delete(s.sessions, session)
+				disconnectedSeq, _ := s.replay.Latest(session.userID)
+				s.ghosts[makeGhostSession(session, disconnectedSeq, time.Now().Add(replayRetention))] = struct{}{}
+				s.replay.Release(session.userID)
 				s.mu.Unlock()
 			}
 
+		case now := <-gc.C:
+			s.mu.Lock()
+			for g := range s.ghosts {
+				if now.After(g.expiresAt) {
+					delete(s.ghosts, g)
+				}
+			}
+			s.replay.GC(now)
+			s.mu.Unlock()
+
 		case <-s.stop:
 			s.mu.Lock()
 			m := &rawMessage{
@@ -95,14 +126,51 @@ func (s *Streamer) run() {
 }
 
 // WriteMessage 指定したセッションにメッセージを書き込みます
+//
+// メッセージにはユーザー毎のシーケンス番号が付与され、送信先の判定関数と共に再送バッファに記録されます。
+// ライブ配信が開始されていないセッションへのメッセージは、開始されるまで保留されます。
 func (s *Streamer) WriteMessage(t string, body interface{}, targetFunc TargetFunc) {
-	m := &rawMessage{
-		t:    websocket.TextMessage,
-		data: makeMessage(t, body).toJSON(),
+	b, _ := json.Marshal(body)
+	msgs := map[uuid.UUID]*rawMessage{}
+	messageFor := func(userID uuid.UUID) *rawMessage {
+		if m, ok := msgs[userID]; ok {
+			return m
+		}
+		m, ok := s.replay.Append(userID, func(seq int64) interface{} {
+			return &replayEntry{
+				msg: &rawMessage{
+					t:    websocket.TextMessage,
+					data: makeSeqMessage(t, seq, jsoniter.RawMessage(b)).toJSON(),
+				},
+				targetFunc: targetFunc,
+			}
+		})
+		if !ok {
+			msgs[userID] = &rawMessage{t: websocket.TextMessage, data: makeMessage(t, jsoniter.RawMessage(b)).toJSON()}
+		} else {
+			msgs[userID] = m.(*replayEntry).msg
+		}
+		return msgs[userID]
 	}
+
+	// シーケンス番号の順に送信されるように、書き込みは直列化する
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	s.mu.RLock()
+	defer s.mu.RUnlock()
 	for session := range s.sessions {
 		if targetFunc(session) {
+			m := messageFor(session.userID)
+			if !session.active {
+				if len(session.pending) >= messageBufferSize {
+					s.logger.Warn("Discard a message because the session's pending buffer is full.",
+						zap.String("type", t), zap.Any("body", body),
+						zap.Stringer("userID", session.userID))
+					continue
+				}
+				session.pending = append(session.pending, m)
+				continue
+			}
 			if err := session.writeMessage(m); err != nil {
 				if err == ErrBufferIsFull {
 					s.logger.Warn("Discard a message because the session's buffer is full.",
@@ -113,7 +181,13 @@ func (s *Streamer) WriteMessage(t string, body interface{}, targetFunc TargetFun
 			}
 		}
 	}
-	s.mu.RUnlock()
+
+	// 切断中のセッションに送られるはずだったメッセージを記録する
+	for g := range s.ghosts {
+		if _, ok := msgs[g.userID]; !ok && targetFunc(g) {
+			messageFor(g.userID)
+		}
+	}
 }
 
 // ServeHTTP http.Handlerインターフェイスの実装
@@ -132,6 +206,7 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		key:      random.AlphaNumeric(20),
 		req:      r,
 		conn:     conn,
+		protocol: conn.Subprotocol(),
 		open:     true,
 		streamer: s,
 		send:     make(chan *rawMessage, messageBufferSize),
@@ -148,8 +223,11 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		},
 	})
 
+	// resumeコマンドが送られなければ、一定時間後にライブ配信を開始する
+	activateTimer := time.AfterFunc(resumeWait, func() { s.activate(session) })
 	go session.writeLoop()
 	session.readLoop()
+	activateTimer.Stop()
 
 	s.vm.RemoveViewer(session)
 	_ = s.webrtc.ResetState(session.Key(), session.UserID())
@@ -0,0 +1,187 @@
+package ws
+
+import (
+	"context"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/service/bus"
+	"github.com/traPtitech/traQ/service/typing"
+	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testMessage struct {
+	Type string              `json:"type"`
+	Seq  int64               `json:"seq"`
+	Body jsoniter.RawMessage `json:"body"`
+}
+
+func setupStreamer(t *testing.T) (*Streamer, func(userID uuid.UUID, protocols ...string) *websocket.Conn) {
+	t.Helper()
+
+	h := hub.New()
+	b := bus.New(bus.NewLocalTransport(), zap.NewNop())
+	s := NewStreamer(h, viewer.NewManager(h, b), webrtcv3.NewManager(h, b), nil, typing.NewManager(h), zap.NewNop())
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		userID := uuid.FromStringOrNil(r.URL.Query().Get("user"))
+		s.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), extension.CtxUserIDKey, userID)))
+	}))
+	t.Cleanup(func() {
+		_ = s.Close()
+		srv.Close()
+	})
+
+	dial := func(userID uuid.UUID, protocols ...string) *websocket.Conn {
+		t.Helper()
+		d := websocket.Dialer{Subprotocols: protocols}
+		conn, _, err := d.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?user="+userID.String(), nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+	return s, dial
+}
+
+func waitFor(t *testing.T, s *Streamer, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		s.mu.RLock()
+		ok := cond()
+		s.mu.RUnlock()
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition was not satisfied")
+}
+
+func activeSessions(s *Streamer) (n int) {
+	for session := range s.sessions {
+		if session.active {
+			n++
+		}
+	}
+	return
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) testMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	_, b, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var m testMessage
+	require.NoError(t, json.Unmarshal(b, &m))
+	return m
+}
+
+func writeText(t *testing.T, conn *websocket.Conn, text string) {
+	t.Helper()
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(text)))
+}
+
+func TestStreamer_Resume(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		s, dial := setupStreamer(t)
+		user := uuid.Must(uuid.NewV4())
+
+		conn1 := dial(user)
+		writeText(t, conn1, "timeline_streaming:off")
+		waitFor(t, s, func() bool { return activeSessions(s) == 1 })
+		s.WriteMessage("A", nil, TargetUsers(user))
+		a := readMessage(t, conn1)
+		assert.Equal(t, "A", a.Type)
+
+		// 同じユーザーの別のタブ
+		conn2 := dial(user)
+		writeText(t, conn2, "timeline_streaming:on")
+		waitFor(t, s, func() bool { return activeSessions(s) == 2 })
+
+		require.NoError(t, conn1.Close())
+		waitFor(t, s, func() bool { return len(s.sessions) == 1 && len(s.ghosts) == 1 })
+
+		s.WriteMessage("B", nil, TargetUsers(user))
+		// 別のタブのみが送信先のイベントは再送しない
+		s.WriteMessage("C", nil, TargetTimelineStreamingEnabled())
+		assert.Equal(t, "B", readMessage(t, conn2).Type)
+		assert.Equal(t, "C", readMessage(t, conn2).Type)
+
+		conn3 := dial(user)
+		waitFor(t, s, func() bool { return len(s.sessions) == 2 })
+		// 接続後のイベントは再送の後に届く
+		s.WriteMessage("D", nil, TargetUsers(user))
+		assert.Equal(t, "D", readMessage(t, conn2).Type)
+		writeText(t, conn3, "resume:"+strconv.FormatInt(a.Seq, 10))
+
+		b := readMessage(t, conn3)
+		assert.Equal(t, "B", b.Type)
+		assert.Greater(t, b.Seq, a.Seq)
+		resumed := readMessage(t, conn3)
+		if assert.Equal(t, "RESUMED", resumed.Type) {
+			var body seqBody
+			require.NoError(t, json.Unmarshal(resumed.Body, &body))
+			assert.Greater(t, body.Seq, b.Seq)
+		}
+		d := readMessage(t, conn3)
+		assert.Equal(t, "D", d.Type)
+		assert.Greater(t, d.Seq, b.Seq)
+	})
+
+	t.Run("resync", func(t *testing.T) {
+		t.Parallel()
+		_, dial := setupStreamer(t)
+		user := uuid.Must(uuid.NewV4())
+
+		conn := dial(user)
+		writeText(t, conn, "resume:1")
+		assert.Equal(t, "RESYNC", readMessage(t, conn).Type)
+	})
+
+	t.Run("json protocol", func(t *testing.T) {
+		t.Parallel()
+		_, dial := setupStreamer(t)
+		user := uuid.Must(uuid.NewV4())
+
+		conn := dial(user, protocolJSONv1)
+		writeText(t, conn, `{"id":"1","type":"resume","args":{"seq":1}}`)
+		assert.Equal(t, "RESYNC", readMessage(t, conn).Type)
+		assert.Equal(t, "ACK", readMessage(t, conn).Type)
+
+		// ライブ配信の開始後はresumeできない
+		writeText(t, conn, `{"id":"2","type":"resume","args":{"seq":1}}`)
+		m := readMessage(t, conn)
+		if assert.Equal(t, "ERROR", m.Type) {
+			var body errorBody
+			require.NoError(t, json.Unmarshal(m.Body, &body))
+			assert.Equal(t, "2", body.ID)
+			assert.Equal(t, errCodeInvalidArgs, body.Code)
+		}
+	})
+
+	t.Run("without resume", func(t *testing.T) {
+		t.Parallel()
+		s, dial := setupStreamer(t)
+		user := uuid.Must(uuid.NewV4())
+
+		conn := dial(user)
+		waitFor(t, s, func() bool { return len(s.sessions) == 1 })
+		s.WriteMessage("A", nil, TargetUsers(user))
+		// resumeWaitが経過するとライブ配信が開始される
+		assert.Equal(t, "A", readMessage(t, conn).Type)
+	})
+}
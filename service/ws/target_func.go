@@ -54,6 +54,25 @@ func TargetTimelineStreamingEnabled() TargetFunc {
 	}
 }
 
+// Not TargetFuncの条件に該当しない対象に送信します
+func Not(f TargetFunc) TargetFunc {
+	return func(s Session) bool {
+		return !f(s)
+	}
+}
+
+// And 全てのTargetFuncの条件に該当する対象に送信します
+func And(funcs ...TargetFunc) TargetFunc {
+	return func(s Session) bool {
+		for _, f := range funcs {
+			if !f(s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 // Or いずれかのTargetFuncの条件に該当する対象に送信します
 func Or(funcs ...TargetFunc) TargetFunc {
 	return func(s Session) bool {
@@ -9,6 +9,7 @@ type EmptyTestRepository struct {
 	repository.UserGroupRepository
 	repository.TagRepository
 	repository.ChannelRepository
+	repository.ChannelTemplateRepository
 	repository.MessageRepository
 	repository.MessageReportRepository
 	repository.StampRepository
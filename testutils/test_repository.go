@@ -49,6 +49,8 @@ type TestRepository struct {
 	FilesLock                 sync.RWMutex
 	FilesACL                  map[uuid.UUID]map[uuid.UUID]bool
 	FilesACLLock              sync.RWMutex
+	FileObjects               map[uuid.UUID]model.FileObject
+	FileObjectsLock           sync.RWMutex
 	Webhooks                  map[uuid.UUID]model.WebhookBot
 	WebhooksLock              sync.RWMutex
 }
@@ -82,6 +84,42 @@ func (repo *TestRepository) UnlinkExternalUserAccount(uuid.UUID, string) error {
 	panic("implement me")
 }
 
+func (repo *TestRepository) GetUserSettings(userID uuid.UUID) (*model.UserSettings, error) {
+	return &model.UserSettings{UserID: userID}, nil
+}
+
+func (repo *TestRepository) UpdateUserSettings(uuid.UUID, repository.UpdateUserSettingsArgs) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateChannelReadState(uuid.UUID, uuid.UUID, time.Time) (*model.ChannelReadState, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetChannelReadStates(uuid.UUID) ([]*model.ChannelReadState, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) MergeChannel(uuid.UUID, uuid.UUID, uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetChannelRedirect(uuid.UUID) (*model.ChannelRedirect, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetPrivateChannels(uuid.UUID) ([]*model.Channel, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) AddPrivateChannelMembers(uuid.UUID, set.UUID, uuid.UUID) ([]uuid.UUID, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) RemovePrivateChannelMembers(uuid.UUID, set.UUID, uuid.UUID) ([]uuid.UUID, error) {
+	panic("implement me")
+}
+
 func (repo *TestRepository) GetChannelStats(uuid.UUID) (*repository.ChannelStats, error) {
 	panic("implement me")
 }
@@ -134,6 +172,26 @@ func (repo *TestRepository) GetStampPalettes(uuid.UUID) (sps []*model.StampPalet
 	panic("implement me")
 }
 
+func (repo *TestRepository) CreateChannelTemplate(string, string, model.ChannelTemplateNode, uuid.UUID) (*model.ChannelTemplate, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateChannelTemplate(uuid.UUID, repository.UpdateChannelTemplateArgs) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetChannelTemplate(uuid.UUID) (*model.ChannelTemplate, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetChannelTemplates() ([]*model.ChannelTemplate, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteChannelTemplate(uuid.UUID) error {
+	panic("implement me")
+}
+
 func (repo *TestRepository) ExistStamps([]uuid.UUID) (err error) {
 	panic("implement me")
 }
@@ -154,6 +212,7 @@ func NewTestRepository() *TestRepository {
 		Stars:                 map[uuid.UUID]map[uuid.UUID]bool{},
 		Files:                 map[uuid.UUID]model.FileMeta{},
 		FilesACL:              map[uuid.UUID]map[uuid.UUID]bool{},
+		FileObjects:           map[uuid.UUID]model.FileObject{},
 		Webhooks:              map[uuid.UUID]model.WebhookBot{},
 	}
 	_, _ = r.CreateUser(repository.CreateUserArgs{Name: "traq", Password: "traq", Role: role.Admin})
@@ -410,6 +469,10 @@ func (repo *TestRepository) CreateUserGroup(name, description, gType string, adm
 	return &g, nil
 }
 
+func (repo *TestRepository) CreateExternalUserGroup(string, string, uuid.UUID) (*model.UserGroup, error) {
+	panic("implement me")
+}
+
 func (repo *TestRepository) UpdateUserGroup(id uuid.UUID, args repository.UpdateUserGroupNameArgs) error {
 	if id == uuid.Nil {
 		return repository.ErrNilID
@@ -656,6 +719,14 @@ func (repo *TestRepository) RemoveUserFromGroupAdmin(userID, groupID uuid.UUID)
 	return nil
 }
 
+func (repo *TestRepository) AddChildToGroup(childID, groupID uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) RemoveChildFromGroup(childID, groupID uuid.UUID) error {
+	panic("implement me")
+}
+
 func (repo *TestRepository) GetTagByID(id uuid.UUID) (*model.Tag, error) {
 	repo.TagsLock.RLock()
 	t, ok := repo.Tags[id]
@@ -799,6 +870,10 @@ func (repo *TestRepository) CreateChannel(ch model.Channel, privateMembers set.U
 	return &ch, nil
 }
 
+func (repo *TestRepository) CreateChannelTree(uuid.UUID, string, model.ChannelTemplateNode, uuid.UUID) ([]*model.Channel, error) {
+	panic("implement me")
+}
+
 func (repo *TestRepository) UpdateChannel(channelID uuid.UUID, args repository.UpdateChannelArgs) (*model.Channel, error) {
 	if channelID == uuid.Nil {
 		return nil, repository.ErrNilID
@@ -851,6 +926,18 @@ func (repo *TestRepository) GetDirectMessageChannelMapping(uuid.UUID) ([]*model.
 	panic("implement me")
 }
 
+func (repo *TestRepository) GetGroupDirectMessageChannel(set.UUID) (*model.Channel, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetGroupDirectMessageChannelMapping(uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) ConvertGroupDirectMessageChannel(uuid.UUID, string, uuid.UUID) (*model.Channel, error) {
+	panic("implement me")
+}
+
 func (repo *TestRepository) GetPrivateChannelMemberIDs(channelID uuid.UUID) ([]uuid.UUID, error) {
 	result := make([]uuid.UUID, 0)
 	repo.PrivateChannelMembersLock.RLock()
@@ -924,6 +1011,18 @@ func (repo *TestRepository) ChangeChannelSubscription(channelID uuid.UUID, args
 	return on, off, nil
 }
 
+func (repo *TestRepository) SetChannelGroupSubscription(channelID, groupID uuid.UUID, level model.ChannelSubscribeLevel) (on []uuid.UUID, off []uuid.UUID, err error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetChannelGroupSubscriptions(channelID uuid.UUID) ([]*model.ChannelGroupSubscription, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SyncGroupChannelSubscriptions(userID, groupID uuid.UUID) error {
+	panic("implement me")
+}
+
 func (repo *TestRepository) GetChannelSubscriptions(query repository.ChannelSubscriptionQuery) ([]*model.UserSubscribeChannel, error) {
 	repo.ChannelSubscribesLock.Lock()
 	result := make([]*model.UserSubscribeChannel, 0)
@@ -1228,6 +1327,22 @@ func (repo *TestRepository) RemoveStampFromMessage(uuid.UUID, uuid.UUID, uuid.UU
 	panic("implement me")
 }
 
+func (repo *TestRepository) SearchStamps(query string, userID uuid.UUID, limit int) ([]*model.Stamp, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateStampUsage(stampID, channelID, userID uuid.UUID, date time.Time, delta int) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetStampStats(stampID uuid.UUID, since time.Time) (*repository.StampStats, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetStampRanking(since time.Time, channelID uuid.UUID, limit int) ([]*repository.StampRankingItem, error) {
+	panic("implement me")
+}
+
 func (repo *TestRepository) GetUserStampHistory(uuid.UUID, int) (h []*repository.UserStampHistory, err error) {
 	panic("implement me")
 }
@@ -1349,11 +1464,62 @@ func (repo *TestRepository) DeleteFileMeta(fileID uuid.UUID) error {
 	}
 	repo.FilesLock.Lock()
 	defer repo.FilesLock.Unlock()
+	meta, ok := repo.Files[fileID]
+	if !ok {
+		return nil
+	}
 	delete(repo.Files, fileID)
+	if meta.ObjectID != uuid.Nil {
+		repo.FileObjectsLock.Lock()
+		if obj, ok := repo.FileObjects[meta.ObjectID]; ok {
+			obj.RefCount--
+			if obj.RefCount <= 0 {
+				delete(repo.FileObjects, obj.ID)
+			} else {
+				repo.FileObjects[obj.ID] = obj
+			}
+		}
+		repo.FileObjectsLock.Unlock()
+	}
+	return nil
+}
+
+func (repo *TestRepository) UpdateFileMediaInfo(fileID uuid.UUID, args repository.UpdateFileMediaArgs) error {
+	if fileID == uuid.Nil {
+		return repository.ErrNilID
+	}
+	repo.FilesLock.Lock()
+	defer repo.FilesLock.Unlock()
+	meta, ok := repo.Files[fileID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	meta.Width = args.Width
+	meta.Height = args.Height
+	meta.DurationMS = args.Duration.Milliseconds()
+	meta.Codec = args.Codec
+	if args.ThumbnailMime.Valid {
+		meta.HasThumbnail = true
+		meta.ThumbnailMime = args.ThumbnailMime
+		meta.ThumbnailWidth = args.ThumbnailWidth
+		meta.ThumbnailHeight = args.ThumbnailHeight
+	}
+	repo.Files[fileID] = meta
 	return nil
 }
 
 func (repo *TestRepository) SaveFileMeta(meta *model.FileMeta, acl []*model.FileACLEntry) error {
+	if meta.ObjectID != uuid.Nil {
+		repo.FileObjectsLock.Lock()
+		obj, ok := repo.FileObjects[meta.ObjectID]
+		if !ok {
+			repo.FileObjectsLock.Unlock()
+			return repository.ErrNotFound
+		}
+		obj.RefCount++
+		repo.FileObjects[obj.ID] = obj
+		repo.FileObjectsLock.Unlock()
+	}
 	repo.FilesLock.Lock()
 	repo.FilesACLLock.Lock()
 	meta.CreatedAt = time.Now()
@@ -1387,6 +1553,134 @@ func (repo *TestRepository) IsFileAccessible(fileID, userID uuid.UUID) (bool, er
 	return allow, nil
 }
 
+func (repo *TestRepository) GetUserFileUsage(userID uuid.UUID) (*model.UserFileUsage, error) {
+	usage := &model.UserFileUsage{UserID: userID}
+	repo.FilesLock.RLock()
+	defer repo.FilesLock.RUnlock()
+	for _, meta := range repo.Files {
+		if meta.CreatorID.Valid && meta.CreatorID.UUID == userID {
+			usage.Size += meta.Size
+			usage.Count++
+		}
+	}
+	return usage, nil
+}
+
+func (repo *TestRepository) GetChannelFileUsage(channelID uuid.UUID) (*model.ChannelFileUsage, error) {
+	usage := &model.ChannelFileUsage{ChannelID: channelID}
+	repo.FilesLock.RLock()
+	defer repo.FilesLock.RUnlock()
+	for _, meta := range repo.Files {
+		if meta.ChannelID.Valid && meta.ChannelID.UUID == channelID {
+			usage.Size += meta.Size
+			usage.Count++
+		}
+	}
+	return usage, nil
+}
+
+func (repo *TestRepository) GetTotalFileUsage() (size int64, count int64, err error) {
+	repo.FilesLock.RLock()
+	defer repo.FilesLock.RUnlock()
+	for _, meta := range repo.Files {
+		size += meta.Size
+		count++
+	}
+	return size, count, nil
+}
+
+func (repo *TestRepository) GetFileObject(objectID uuid.UUID) (*model.FileObject, error) {
+	repo.FileObjectsLock.RLock()
+	defer repo.FileObjectsLock.RUnlock()
+	obj, ok := repo.FileObjects[objectID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &obj, nil
+}
+
+func (repo *TestRepository) GetFileObjectByHash(fileType model.FileType, hash string) (*model.FileObject, error) {
+	repo.FileObjectsLock.RLock()
+	defer repo.FileObjectsLock.RUnlock()
+	for _, obj := range repo.FileObjects {
+		if obj.Type == fileType && obj.Hash.Valid && obj.Hash.String == hash {
+			obj := obj
+			return &obj, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (repo *TestRepository) CreateFileObject(object *model.FileObject) error {
+	if object == nil || object.ID == uuid.Nil {
+		return repository.ErrNilID
+	}
+	repo.FileObjectsLock.Lock()
+	defer repo.FileObjectsLock.Unlock()
+	for _, obj := range repo.FileObjects {
+		if obj.Type == object.Type && obj.Hash.Valid && object.Hash.Valid && obj.Hash.String == object.Hash.String {
+			return repository.ErrAlreadyExists
+		}
+	}
+	object.RefCount = 0
+	object.CreatedAt = time.Now()
+	repo.FileObjects[object.ID] = *object
+	return nil
+}
+
+func (repo *TestRepository) DeleteFileObject(objectID uuid.UUID) error {
+	repo.FileObjectsLock.Lock()
+	defer repo.FileObjectsLock.Unlock()
+	if obj, ok := repo.FileObjects[objectID]; ok && obj.RefCount <= 0 {
+		delete(repo.FileObjects, objectID)
+	}
+	return nil
+}
+
+func (repo *TestRepository) GetUnhashedFileObjects() ([]*model.FileObject, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetFileObjectHash(uuid.UUID, string) (*model.FileObject, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) CreateFileUpload(*model.FileUpload) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetFileUpload(uuid.UUID) (*model.FileUpload, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateFileUploadOffset(uuid.UUID, int64, time.Time) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteFileUpload(uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetExpiredFileUploads(time.Time) ([]*model.FileUpload, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetUserFileUploadsSize(uuid.UUID, time.Time) (int64, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetUserFileUsageRanking(int) ([]*model.UserFileUsage, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetChannelFileUsageRanking(int) ([]*model.ChannelFileUsage, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) RecalculateFileUsages() error {
+	panic("implement me")
+}
+
 func (repo *TestRepository) CreateWebhook(name, description string, channelID, iconFileID, creatorID uuid.UUID, secret string) (model.Webhook, error) {
 	if len(name) == 0 || utf8.RuneCountInString(name) > 32 {
 		return nil, repository.ArgError("name", "Name must be non-empty and shorter than 33 characters")
@@ -1618,6 +1912,30 @@ func (repo *TestRepository) DeleteAuthorize(string) error {
 	panic("implement me")
 }
 
+func (repo *TestRepository) SaveDeviceAuthorize(*model.OAuth2DeviceAuthorize) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetDeviceAuthorize(string) (*model.OAuth2DeviceAuthorize, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetDeviceAuthorizeByUserCode(string) (*model.OAuth2DeviceAuthorize, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DecideDeviceAuthorize(string, uuid.UUID, bool) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateDeviceAuthorizePolling(string, time.Time, int) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteDeviceAuthorize(string) error {
+	panic("implement me")
+}
+
 func (repo *TestRepository) IssueToken(*model.OAuth2Client, uuid.UUID, string, model.AccessScopes, int, bool) (*model.OAuth2Token, error) {
 	panic("implement me")
 }
@@ -0,0 +1,470 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"github.com/disintegration/imaging"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	pngSignature = "\x89PNG\r\n\x1a\n"
+	// maxPNGChunkSize 読み込むチャンクの最大サイズ
+	maxPNGChunkSize = 1 << 26
+)
+
+// APNGのフレーム破棄方法
+const (
+	apngDisposeOpNone = iota
+	apngDisposeOpBackground
+	apngDisposeOpPrevious
+)
+
+// APNGのフレーム合成方法
+const (
+	apngBlendOpSource = iota
+	apngBlendOpOver
+)
+
+// AnimationInfo アニメーション画像の情報
+type AnimationInfo struct {
+	// Width キャンバスの幅
+	Width int
+	// Height キャンバスの高さ
+	Height int
+	// Frames フレーム数 (アニメーションでない場合は1)
+	Frames int
+}
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// apngFrameControl fcTLチャンクの内容
+type apngFrameControl struct {
+	width     int
+	height    int
+	xOffset   int
+	yOffset   int
+	delayNum  uint16
+	delayDen  uint16
+	disposeOp byte
+	blendOp   byte
+}
+
+// apngFrame リサイズ済みのフレーム
+type apngFrame struct {
+	data     []byte
+	delayNum uint16
+	delayDen uint16
+}
+
+// ProbeAPNG PNG画像のキャンバスサイズとフレーム数を取得します
+//
+// アニメーションでないPNG画像の場合、フレーム数は1になります。
+func ProbeAPNG(src io.Reader) (AnimationInfo, error) {
+	ihdr, err := readPNGHeader(src)
+	if err != nil {
+		return AnimationInfo{}, err
+	}
+
+	info := AnimationInfo{
+		Width:  int(binary.BigEndian.Uint32(ihdr[0:4])),
+		Height: int(binary.BigEndian.Uint32(ihdr[4:8])),
+		Frames: 1,
+	}
+	for {
+		c, err := readPNGChunk(src)
+		if err != nil {
+			return AnimationInfo{}, err
+		}
+		switch c.typ {
+		case "acTL":
+			if len(c.data) != 8 {
+				return AnimationInfo{}, ErrInvalidImageSrc
+			}
+			info.Frames = int(binary.BigEndian.Uint32(c.data[0:4]))
+			if info.Frames == 0 {
+				return AnimationInfo{}, ErrInvalidImageSrc
+			}
+		case "IDAT", "IEND":
+			// acTLはIDATより前に存在する
+			return info, nil
+		}
+	}
+}
+
+// DecodeAPNGFirstFrame APNG画像のアニメーションの先頭フレームをデコードします
+//
+// アニメーションでないPNG画像の場合は、その画像を返します。
+func DecodeAPNGFirstFrame(src io.Reader) (image.Image, error) {
+	var first image.Image
+	_, err := decodeAPNG(src, func(canvas *image.NRGBA, _ *apngFrameControl) (bool, error) {
+		first = imaging.Clone(canvas)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return nil, ErrInvalidImageSrc
+	}
+	return first, nil
+}
+
+// ResizeAPNG APNG画像をアニメーションを保ったままリサイズします
+// 縮小は行いますが拡大は行いません。リサイズの必要がない場合は元の画像をそのまま返します
+func ResizeAPNG(src io.Reader, maxWidth, maxHeight int) (*bytes.Reader, error) {
+	if maxHeight <= 0 || maxWidth <= 0 {
+		return nil, ErrInvalidImageSrc
+	}
+
+	b, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ProbeAPNG(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if info.Width <= maxWidth && info.Height <= maxHeight {
+		return bytes.NewReader(b), nil
+	}
+
+	var (
+		frames []apngFrame
+		bounds image.Rectangle
+	)
+	numPlays, err := decodeAPNG(bytes.NewReader(b), func(canvas *image.NRGBA, fc *apngFrameControl) (bool, error) {
+		resized := imaging.Fit(canvas, maxWidth, maxHeight, imaging.Linear)
+		data, err := encodeAPNGFrameData(resized)
+		if err != nil {
+			return false, err
+		}
+		bounds = resized.Bounds()
+		frames = append(frames, apngFrame{data: data, delayNum: fc.delayNum, delayDen: fc.delayDen})
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, ErrInvalidImageSrc
+	}
+
+	var out bytes.Buffer
+	out.WriteString(pngSignature)
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(bounds.Dy()))
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // truecolor with alpha
+	writePNGChunk(&out, "IHDR", ihdr)
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:4], uint32(len(frames)))
+	binary.BigEndian.PutUint32(actl[4:8], numPlays)
+	writePNGChunk(&out, "acTL", actl)
+
+	var seq uint32
+	for i, f := range frames {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], seq)
+		binary.BigEndian.PutUint32(fctl[4:8], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:12], uint32(bounds.Dy()))
+		binary.BigEndian.PutUint16(fctl[20:22], f.delayNum)
+		binary.BigEndian.PutUint16(fctl[22:24], f.delayDen)
+		fctl[24] = apngDisposeOpNone
+		fctl[25] = apngBlendOpSource
+		writePNGChunk(&out, "fcTL", fctl)
+		seq++
+
+		if i == 0 {
+			writePNGChunk(&out, "IDAT", f.data)
+		} else {
+			fdat := make([]byte, 4+len(f.data))
+			binary.BigEndian.PutUint32(fdat[0:4], seq)
+			copy(fdat[4:], f.data)
+			writePNGChunk(&out, "fdAT", fdat)
+			seq++
+		}
+	}
+	writePNGChunk(&out, "IEND", nil)
+
+	return bytes.NewReader(out.Bytes()), nil
+}
+
+// decodeAPNG APNG画像の各フレームを順に合成し、合成後のキャンバスをfnに渡します
+//
+// fnがfalseを返した場合はそこでデコードを終了します。アニメーションの繰り返し回数を返します。
+func decodeAPNG(src io.Reader, fn func(canvas *image.NRGBA, fc *apngFrameControl) (bool, error)) (uint32, error) {
+	ihdr, err := readPNGHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	width := int(binary.BigEndian.Uint32(ihdr[0:4]))
+	height := int(binary.BigEndian.Uint32(ihdr[4:8]))
+
+	var (
+		canvas    = image.NewNRGBA(image.Rect(0, 0, width, height))
+		common    []pngChunk // PLTE, tRNSなど全フレームのデコードに必要なチャンク
+		animated  bool
+		seenIDAT  bool
+		numFrames int
+		numPlays  uint32
+		numFCTL   int
+		index     int
+		cur       *apngFrameControl
+		data      [][]byte
+	)
+
+	// flush 溜めたフレームデータをデコードしてキャンバスに合成します
+	flush := func() (bool, error) {
+		defer func() {
+			cur = nil
+			data = nil
+		}()
+		if cur == nil {
+			// アニメーションに含まれないデフォルト画像
+			return true, nil
+		}
+
+		// フレームがキャンバスに収まることはparseAPNGFrameControlで確認済み
+		frame, err := decodePNGFrame(ihdr, common, cur, data)
+		if err != nil {
+			return false, err
+		}
+		rect := image.Rect(cur.xOffset, cur.yOffset, cur.xOffset+cur.width, cur.yOffset+cur.height)
+
+		dispose := cur.disposeOp
+		if dispose == apngDisposeOpPrevious && index == 0 {
+			dispose = apngDisposeOpBackground
+		}
+		var prev *image.NRGBA
+		if dispose == apngDisposeOpPrevious {
+			prev = imaging.Clone(canvas.SubImage(rect))
+		}
+
+		op := draw.Src
+		if cur.blendOp == apngBlendOpOver {
+			op = draw.Over
+		}
+		draw.Draw(canvas, rect, frame, frame.Bounds().Min, op)
+
+		cont, err := fn(canvas, cur)
+		if err != nil || !cont {
+			return false, err
+		}
+		index++
+
+		switch dispose {
+		case apngDisposeOpBackground:
+			draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposeOpPrevious:
+			draw.Draw(canvas, rect, prev, prev.Bounds().Min, draw.Src)
+		}
+		return true, nil
+	}
+
+	for {
+		c, err := readPNGChunk(src)
+		if err != nil {
+			return 0, err
+		}
+
+		switch c.typ {
+		case "acTL":
+			if len(c.data) != 8 || seenIDAT {
+				return 0, ErrInvalidImageSrc
+			}
+			animated = true
+			numFrames = int(binary.BigEndian.Uint32(c.data[0:4]))
+			numPlays = binary.BigEndian.Uint32(c.data[4:8])
+			if numFrames == 0 {
+				return 0, ErrInvalidImageSrc
+			}
+		case "PLTE", "tRNS":
+			if !seenIDAT {
+				common = append(common, c)
+			}
+		case "fcTL":
+			if !animated {
+				return 0, ErrInvalidImageSrc
+			}
+			// acTLで宣言されたフレーム数を超えるフレームは受け付けない
+			numFCTL++
+			if numFCTL > numFrames {
+				return 0, ErrInvalidImageSrc
+			}
+			if cont, err := flush(); err != nil || !cont {
+				return numPlays, err
+			}
+			fc, err := parseAPNGFrameControl(c.data, width, height)
+			if err != nil {
+				return 0, err
+			}
+			cur = fc
+		case "IDAT":
+			if !animated && !seenIDAT {
+				cur = &apngFrameControl{width: width, height: height}
+			}
+			seenIDAT = true
+			data = append(data, c.data)
+		case "fdAT":
+			if len(c.data) < 4 || cur == nil {
+				return 0, ErrInvalidImageSrc
+			}
+			data = append(data, c.data[4:])
+		case "IEND":
+			if _, err := flush(); err != nil {
+				return 0, err
+			}
+			return numPlays, nil
+		}
+	}
+}
+
+// decodePNGFrame 1フレーム分の画像データを単体のPNG画像としてデコードします
+func decodePNGFrame(ihdr []byte, common []pngChunk, fc *apngFrameControl, data [][]byte) (image.Image, error) {
+	var buf bytes.Buffer
+	buf.WriteString(pngSignature)
+
+	h := make([]byte, len(ihdr))
+	copy(h, ihdr)
+	binary.BigEndian.PutUint32(h[0:4], uint32(fc.width))
+	binary.BigEndian.PutUint32(h[4:8], uint32(fc.height))
+	writePNGChunk(&buf, "IHDR", h)
+	for _, c := range common {
+		writePNGChunk(&buf, c.typ, c.data)
+	}
+	writePNGChunk(&buf, "IDAT", bytes.Join(data, nil))
+	writePNGChunk(&buf, "IEND", nil)
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		return nil, ErrInvalidImageSrc
+	}
+	return img, nil
+}
+
+// encodeAPNGFrameData 画像をRGBA 8bitのIDAT(fdAT)データにエンコードします
+func encodeAPNGFrameData(img *image.NRGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	stride := 4 * b.Dx()
+	row := make([]byte, 1+stride)
+	row[0] = 1 // Subフィルタ
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		pix := img.Pix[img.PixOffset(b.Min.X, y) : img.PixOffset(b.Min.X, y)+stride]
+		for i := range pix {
+			if i < 4 {
+				row[1+i] = pix[i]
+			} else {
+				row[1+i] = pix[i] - pix[i-4]
+			}
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseAPNGFrameControl fcTLチャンクをパースします
+//
+// フレームがwidth x heightのキャンバスからはみ出す場合はエラーを返します。
+// フレームの画素数はキャンバスの画素数以下になるため、デコード前にキャンバスの画素数を確認すれば十分です。
+func parseAPNGFrameControl(b []byte, width, height int) (*apngFrameControl, error) {
+	if len(b) != 26 {
+		return nil, ErrInvalidImageSrc
+	}
+	fc := &apngFrameControl{
+		width:     int(binary.BigEndian.Uint32(b[4:8])),
+		height:    int(binary.BigEndian.Uint32(b[8:12])),
+		xOffset:   int(binary.BigEndian.Uint32(b[12:16])),
+		yOffset:   int(binary.BigEndian.Uint32(b[16:20])),
+		delayNum:  binary.BigEndian.Uint16(b[20:22]),
+		delayDen:  binary.BigEndian.Uint16(b[22:24]),
+		disposeOp: b[24],
+		blendOp:   b[25],
+	}
+	if fc.width <= 0 || fc.height <= 0 || fc.disposeOp > apngDisposeOpPrevious || fc.blendOp > apngBlendOpOver {
+		return nil, ErrInvalidImageSrc
+	}
+	if fc.xOffset < 0 || fc.yOffset < 0 || fc.width > width-fc.xOffset || fc.height > height-fc.yOffset {
+		return nil, ErrInvalidImageSrc
+	}
+	return fc, nil
+}
+
+// readPNGHeader PNGシグネチャを確認し、IHDRチャンクのデータを返します
+func readPNGHeader(r io.Reader) ([]byte, error) {
+	sig := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, sig); err != nil || string(sig) != pngSignature {
+		return nil, ErrInvalidImageSrc
+	}
+	c, err := readPNGChunk(r)
+	if err != nil {
+		return nil, err
+	}
+	if c.typ != "IHDR" || len(c.data) != 13 {
+		return nil, ErrInvalidImageSrc
+	}
+	if binary.BigEndian.Uint32(c.data[0:4]) == 0 || binary.BigEndian.Uint32(c.data[4:8]) == 0 {
+		return nil, ErrInvalidImageSrc
+	}
+	return c.data, nil
+}
+
+func readPNGChunk(r io.Reader) (pngChunk, error) {
+	var h [8]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return pngChunk{}, ErrInvalidImageSrc
+	}
+	n := binary.BigEndian.Uint32(h[0:4])
+	if n > maxPNGChunkSize {
+		return pngChunk{}, ErrInvalidImageSrc
+	}
+	b := make([]byte, n+4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return pngChunk{}, ErrInvalidImageSrc
+	}
+
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write(h[4:8])
+	_, _ = crc.Write(b[:n])
+	if crc.Sum32() != binary.BigEndian.Uint32(b[n:]) {
+		return pngChunk{}, ErrInvalidImageSrc
+	}
+	return pngChunk{typ: string(h[4:8]), data: b[:n]}, nil
+}
+
+func writePNGChunk(w *bytes.Buffer, typ string, data []byte) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(len(data)))
+	w.Write(b[:])
+	w.WriteString(typ)
+	w.Write(data)
+
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write([]byte(typ))
+	_, _ = crc.Write(data)
+	binary.BigEndian.PutUint32(b[:], crc.Sum32())
+	w.Write(b[:])
+}
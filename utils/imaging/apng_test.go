@@ -0,0 +1,251 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"testing"
+)
+
+// makeAPNG 単色フレームからなるAPNG画像を生成します
+func makeAPNG(t *testing.T, width, height int, colors ...color.NRGBA) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	out.WriteString(pngSignature)
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	ihdr[8] = 8
+	ihdr[9] = 6
+	writePNGChunk(&out, "IHDR", ihdr)
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:4], uint32(len(colors)))
+	writePNGChunk(&out, "acTL", actl)
+
+	var seq uint32
+	for i, c := range colors {
+		img := image.NewNRGBA(image.Rect(0, 0, width, height))
+		for p := 0; p < len(img.Pix); p += 4 {
+			img.Pix[p], img.Pix[p+1], img.Pix[p+2], img.Pix[p+3] = c.R, c.G, c.B, c.A
+		}
+		data, err := encodeAPNGFrameData(img)
+		require.NoError(t, err)
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], seq)
+		binary.BigEndian.PutUint32(fctl[4:8], uint32(width))
+		binary.BigEndian.PutUint32(fctl[8:12], uint32(height))
+		binary.BigEndian.PutUint16(fctl[20:22], 1)
+		binary.BigEndian.PutUint16(fctl[22:24], 10)
+		writePNGChunk(&out, "fcTL", fctl)
+		seq++
+
+		if i == 0 {
+			writePNGChunk(&out, "IDAT", data)
+		} else {
+			fdat := make([]byte, 4+len(data))
+			binary.BigEndian.PutUint32(fdat[0:4], seq)
+			copy(fdat[4:], data)
+			writePNGChunk(&out, "fdAT", fdat)
+			seq++
+		}
+	}
+	writePNGChunk(&out, "IEND", nil)
+	return out.Bytes()
+}
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+func TestProbeAPNG(t *testing.T) {
+	t.Parallel()
+
+	t.Run("apng", func(t *testing.T) {
+		t.Parallel()
+
+		info, err := ProbeAPNG(bytes.NewReader(makeAPNG(t, 20, 10, red, blue, red)))
+		if assert.NoError(t, err) {
+			assert.Equal(t, AnimationInfo{Width: 20, Height: 10, Frames: 3}, info)
+		}
+	})
+
+	t.Run("static png", func(t *testing.T) {
+		t.Parallel()
+
+		var b bytes.Buffer
+		require.NoError(t, png.Encode(&b, image.NewNRGBA(image.Rect(0, 0, 30, 40))))
+		info, err := ProbeAPNG(&b)
+		if assert.NoError(t, err) {
+			assert.Equal(t, AnimationInfo{Width: 30, Height: 40, Frames: 1}, info)
+		}
+	})
+
+	t.Run("broken crc", func(t *testing.T) {
+		t.Parallel()
+
+		b := makeAPNG(t, 20, 10, red, blue)
+		b[len(pngSignature)+8] ^= 0xff // IHDRのデータを破壊
+		_, err := ProbeAPNG(bytes.NewReader(b))
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+
+	t.Run("not png", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ProbeAPNG(bytes.NewBufferString("GIF89a"))
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+}
+
+func TestResizeAPNG(t *testing.T) {
+	t.Parallel()
+
+	t.Run("resize", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assert.New(t), require.New(t)
+
+		r, err := ResizeAPNG(bytes.NewReader(makeAPNG(t, 200, 100, red, blue)), 50, 50)
+		require.NoError(err)
+		b, err := ioutil.ReadAll(r)
+		require.NoError(err)
+
+		info, err := ProbeAPNG(bytes.NewReader(b))
+		require.NoError(err)
+		assert.Equal(AnimationInfo{Width: 50, Height: 25, Frames: 2}, info)
+
+		// APNG非対応のデコーダーでは先頭フレームとして読める
+		img, err := png.Decode(bytes.NewReader(b))
+		require.NoError(err)
+		assert.Equal(image.Rect(0, 0, 50, 25), img.Bounds())
+		assert.Equal(red, color.NRGBAModel.Convert(img.At(10, 10)))
+
+		// 全フレームがデコードできる
+		var frames []color.Color
+		_, err = decodeAPNG(bytes.NewReader(b), func(canvas *image.NRGBA, fc *apngFrameControl) (bool, error) {
+			assert.EqualValues(1, fc.delayNum)
+			assert.EqualValues(10, fc.delayDen)
+			frames = append(frames, canvas.At(10, 10))
+			return true, nil
+		})
+		require.NoError(err)
+		assert.Equal([]color.Color{red, blue}, frames)
+	})
+
+	t.Run("no resize", func(t *testing.T) {
+		t.Parallel()
+
+		src := makeAPNG(t, 20, 10, red, blue)
+		r, err := ResizeAPNG(bytes.NewReader(src), 50, 50)
+		if assert.NoError(t, err) {
+			b, _ := ioutil.ReadAll(r)
+			assert.Equal(t, src, b)
+		}
+	})
+
+	t.Run("invalid args", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ResizeAPNG(bytes.NewReader(makeAPNG(t, 20, 10, red)), -1, 50)
+		assert.Error(t, err)
+	})
+
+	t.Run("not png", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ResizeAPNG(bytes.NewBufferString("not png"), 50, 50)
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+}
+
+func TestDecodeAPNGFirstFrame(t *testing.T) {
+	t.Parallel()
+
+	img, err := DecodeAPNGFirstFrame(bytes.NewReader(makeAPNG(t, 20, 10, blue, red)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, image.Rect(0, 0, 20, 10), img.Bounds())
+		assert.Equal(t, blue, img.At(5, 5))
+	}
+}
+
+func TestDecodeAPNG_InvalidFrame(t *testing.T) {
+	t.Parallel()
+
+	// setFrameControl index番目のfcTLチャンクの内容を書き換えます
+	setFrameControl := func(t *testing.T, b []byte, index int, width, height, x, y uint32) []byte {
+		t.Helper()
+		var out bytes.Buffer
+		r := bytes.NewReader(b[len(pngSignature):])
+		out.WriteString(pngSignature)
+		n := 0
+		for r.Len() > 0 {
+			c, err := readPNGChunk(r)
+			require.NoError(t, err)
+			if c.typ == "fcTL" {
+				if n == index {
+					binary.BigEndian.PutUint32(c.data[4:8], width)
+					binary.BigEndian.PutUint32(c.data[8:12], height)
+					binary.BigEndian.PutUint32(c.data[12:16], x)
+					binary.BigEndian.PutUint32(c.data[16:20], y)
+				}
+				n++
+			}
+			writePNGChunk(&out, c.typ, c.data)
+		}
+		return out.Bytes()
+	}
+
+	t.Run("frame larger than canvas", func(t *testing.T) {
+		t.Parallel()
+
+		b := setFrameControl(t, makeAPNG(t, 10, 10, red, blue), 1, 20000, 20000, 0, 0)
+		_, err := decodeAPNG(bytes.NewReader(b), func(*image.NRGBA, *apngFrameControl) (bool, error) { return true, nil })
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+
+	t.Run("frame outside of canvas", func(t *testing.T) {
+		t.Parallel()
+
+		b := setFrameControl(t, makeAPNG(t, 10, 10, red, blue), 1, 10, 10, 5, 0)
+		_, err := decodeAPNG(bytes.NewReader(b), func(*image.NRGBA, *apngFrameControl) (bool, error) { return true, nil })
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+
+	t.Run("offset overflow", func(t *testing.T) {
+		t.Parallel()
+
+		b := setFrameControl(t, makeAPNG(t, 10, 10, red), 0, 10, 10, 0xffffffff, 0xffffffff)
+		_, err := DecodeAPNGFirstFrame(bytes.NewReader(b))
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+
+	t.Run("more frames than acTL", func(t *testing.T) {
+		t.Parallel()
+
+		b := makeAPNG(t, 10, 10, red, blue, red)
+		// acTLのフレーム数を1に書き換える
+		var out bytes.Buffer
+		r := bytes.NewReader(b[len(pngSignature):])
+		out.WriteString(pngSignature)
+		for r.Len() > 0 {
+			c, err := readPNGChunk(r)
+			require.NoError(t, err)
+			if c.typ == "acTL" {
+				binary.BigEndian.PutUint32(c.data[0:4], 1)
+			}
+			writePNGChunk(&out, c.typ, c.data)
+		}
+
+		_, err := decodeAPNG(bytes.NewReader(out.Bytes()), func(*image.NRGBA, *apngFrameControl) (bool, error) { return true, nil })
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+}
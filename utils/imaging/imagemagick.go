@@ -46,6 +46,65 @@ func ConvertToPNG(ctx context.Context, execPath string, src io.Reader, maxWidth,
 // ResizeAnimationGIF Animation GIF画像をimagemagickでリサイズします
 // expandがfalseの場合、縮小は行いますが拡大は行いません
 func ResizeAnimationGIF(ctx context.Context, execPath string, src io.Reader, maxWidth, maxHeight int, expand bool) (*bytes.Reader, error) {
+	return resizeAnimation(ctx, execPath, src, maxWidth, maxHeight, expand, "gif:-", "-layers", "Optimize")
+}
+
+// ResizeAnimationWebP Animation WebP画像をimagemagickでリサイズします
+// expandがfalseの場合、縮小は行いますが拡大は行いません
+func ResizeAnimationWebP(ctx context.Context, execPath string, src io.Reader, maxWidth, maxHeight int, expand bool) (*bytes.Reader, error) {
+	return resizeAnimation(ctx, execPath, src, maxWidth, maxHeight, expand, "webp:-")
+}
+
+// ExtractFirstFrame アニメーション画像の先頭フレームをimagemagickでPNGとして取り出します
+func ExtractFirstFrame(ctx context.Context, execPath string, src io.Reader) (*bytes.Reader, error) {
+	if len(execPath) == 0 {
+		return nil, ErrImageMagickUnavailable
+	}
+
+	cmd := exec.CommandContext(ctx, execPath, "-[0]", "png:-")
+
+	b, err := cmdPipe(cmd, src)
+	if err != nil {
+		switch err.(type) {
+		case *exec.ExitError:
+			return nil, ErrInvalidImageSrc
+		default:
+			return nil, err
+		}
+	}
+
+	return bytes.NewReader(b), nil
+}
+
+// AutoOrientWebP WebP画像をEXIFの向き情報に従ってimagemagickで回転させ、メタデータを除去します
+// losslessがtrueの場合、可逆圧縮で出力します
+func AutoOrientWebP(ctx context.Context, execPath string, src io.Reader, lossless bool) (*bytes.Reader, error) {
+	if len(execPath) == 0 {
+		return nil, ErrImageMagickUnavailable
+	}
+
+	args := []string{"-", "-auto-orient", "-strip"}
+	if lossless {
+		args = append(args, "-define", "webp:lossless=true")
+	} else {
+		args = append(args, "-quality", "95")
+	}
+	cmd := exec.CommandContext(ctx, execPath, append(args, "webp:-")...)
+
+	b, err := cmdPipe(cmd, src)
+	if err != nil {
+		switch err.(type) {
+		case *exec.ExitError:
+			return nil, ErrInvalidImageSrc
+		default:
+			return nil, err
+		}
+	}
+
+	return bytes.NewReader(b), nil
+}
+
+func resizeAnimation(ctx context.Context, execPath string, src io.Reader, maxWidth, maxHeight int, expand bool, output string, options ...string) (*bytes.Reader, error) {
 	if len(execPath) == 0 {
 		return nil, ErrImageMagickUnavailable
 	}
@@ -58,7 +117,8 @@ func ResizeAnimationGIF(ctx context.Context, execPath string, src io.Reader, max
 	if !expand {
 		sizer += ">"
 	}
-	cmd := exec.CommandContext(ctx, execPath, "-", "-coalesce", "-repage", "0x0", "-resize", sizer, "-layers", "Optimize", "gif:-")
+	args := append([]string{"-", "-coalesce", "-repage", "0x0", "-resize", sizer}, options...)
+	cmd := exec.CommandContext(ctx, execPath, append(args, output)...)
 
 	b, err := cmdPipe(cmd, src)
 	if err != nil {
@@ -0,0 +1,354 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/disintegration/imaging"
+	"image"
+)
+
+const (
+	exifHeader         = "Exif\x00\x00"
+	exifTagOrientation = 0x0112
+)
+
+// StripJPEGMetadata JPEG画像からEXIF・XMP・IPTC・コメント等のメタデータを除去します
+//
+// ICCプロファイル(APP2)・JFIF(APP0)・Adobe(APP14)セグメントは表示に必要なため残します。
+// EOI以降のデータ(MPFの付随画像等)も除去されます。
+// 返り値のorientationは除去したEXIFに含まれていた向き情報(1-8)で、含まれていなかった場合は1です。
+func StripJPEGMetadata(b []byte) (out []byte, orientation int, err error) {
+	if len(b) < 2 || b[0] != 0xff || b[1] != 0xd8 {
+		return nil, 0, ErrInvalidImageSrc
+	}
+	out = make([]byte, 0, len(b))
+	out = append(out, 0xff, 0xd8)
+	orientation = 1
+
+	scanned := false
+	i := 2
+	for i < len(b) {
+		if b[i] != 0xff {
+			return nil, 0, ErrInvalidImageSrc
+		}
+		// フィルバイトを読み飛ばす
+		for i+1 < len(b) && b[i+1] == 0xff {
+			i++
+		}
+		if i+1 >= len(b) {
+			break
+		}
+		marker := b[i+1]
+		switch {
+		case marker == 0xd9: // EOI
+			return append(out, 0xff, 0xd9), orientation, nil
+		case marker >= 0xd0 && marker <= 0xd7, marker == 0x01: // RSTn, TEM (長さなし)
+			out = append(out, 0xff, marker)
+			i += 2
+			continue
+		}
+
+		if i+4 > len(b) {
+			return nil, 0, ErrInvalidImageSrc
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(b[i+2:i+4]))
+		if end < i+4 || end > len(b) {
+			return nil, 0, ErrInvalidImageSrc
+		}
+		data := b[i+4 : end]
+		if keepJPEGSegment(marker, data) {
+			out = append(out, b[i:end]...)
+		} else if marker == 0xe1 && bytes.HasPrefix(data, []byte(exifHeader)) {
+			if o := parseEXIFOrientation(data[len(exifHeader):]); o != 0 {
+				orientation = o
+			}
+		}
+		i = end
+
+		if marker == 0xda { // SOS
+			// 続くエントロピー符号化データを次のマーカーまでそのままコピー
+			scanned = true
+			j := i
+			for j < len(b) && !isJPEGMarkerAt(b, j) {
+				j++
+			}
+			out = append(out, b[i:j]...)
+			i = j
+		}
+	}
+
+	// EOIが無いまま終端に達した
+	if !scanned {
+		return nil, 0, ErrInvalidImageSrc
+	}
+	return out, orientation, nil
+}
+
+// keepJPEGSegment 除去せずに残すJPEGセグメントかどうか
+func keepJPEGSegment(marker byte, data []byte) bool {
+	switch {
+	case marker == 0xe0: // APP0
+		return bytes.HasPrefix(data, []byte("JFIF\x00"))
+	case marker == 0xe2: // APP2
+		return bytes.HasPrefix(data, []byte("ICC_PROFILE\x00"))
+	case marker == 0xee: // APP14
+		return bytes.HasPrefix(data, []byte("Adobe"))
+	case marker >= 0xe1 && marker <= 0xef, marker == 0xfe: // その他のAPPn, COM
+		return false
+	}
+	return true
+}
+
+// isJPEGMarkerAt エントロピー符号化データ中のb[i]がマーカーの開始かどうか
+func isJPEGMarkerAt(b []byte, i int) bool {
+	if b[i] != 0xff || i+1 >= len(b) {
+		return false
+	}
+	next := b[i+1]
+	return next != 0x00 && (next < 0xd0 || next > 0xd7)
+}
+
+// InsertJPEGOrientation 向き情報のみを含むEXIFセグメントをJPEG画像に挿入します
+func InsertJPEGOrientation(b []byte, orientation int) []byte {
+	data := append([]byte(exifHeader), buildOrientationEXIF(orientation)...)
+	seg := make([]byte, 4, 4+len(data))
+	seg[0], seg[1] = 0xff, 0xe1
+	binary.BigEndian.PutUint16(seg[2:4], uint16(2+len(data)))
+	seg = append(seg, data...)
+
+	out := make([]byte, 0, len(b)+len(seg))
+	out = append(out, b[:2]...)
+	out = append(out, seg...)
+	return append(out, b[2:]...)
+}
+
+// ExtractJPEGICCProfile JPEG画像のICCプロファイル(APP2)セグメントを取り出します
+func ExtractJPEGICCProfile(b []byte) [][]byte {
+	var segs [][]byte
+	for i := 2; i+4 <= len(b) && b[i] == 0xff; {
+		marker := b[i+1]
+		if marker == 0xda || marker == 0xd9 {
+			break
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(b[i+2:i+4]))
+		if end < i+4 || end > len(b) {
+			break
+		}
+		if marker == 0xe2 && bytes.HasPrefix(b[i+4:end], []byte("ICC_PROFILE\x00")) {
+			segs = append(segs, b[i:end])
+		}
+		i = end
+	}
+	return segs
+}
+
+// InsertJPEGSegments JPEG画像のSOIの直後にセグメントを挿入します
+func InsertJPEGSegments(b []byte, segs [][]byte) []byte {
+	out := make([]byte, 0, len(b))
+	out = append(out, b[:2]...)
+	for _, seg := range segs {
+		out = append(out, seg...)
+	}
+	return append(out, b[2:]...)
+}
+
+// StripPNGMetadata PNG画像からEXIF(eXIf)・テキスト(tEXt, zTXt, iTXt)・更新日時(tIME)チャンクを除去します
+//
+// IEND以降のデータも除去されます。
+// 返り値のorientationは除去したEXIFに含まれていた向き情報(1-8)で、含まれていなかった場合は1です。
+func StripPNGMetadata(b []byte) (out []byte, orientation int, err error) {
+	if !bytes.HasPrefix(b, []byte(pngSignature)) {
+		return nil, 0, ErrInvalidImageSrc
+	}
+	out = make([]byte, 0, len(b))
+	out = append(out, pngSignature...)
+	orientation = 1
+
+	for i := len(pngSignature); ; {
+		if i+8 > len(b) {
+			return nil, 0, ErrInvalidImageSrc
+		}
+		length := int64(binary.BigEndian.Uint32(b[i : i+4]))
+		typ := string(b[i+4 : i+8])
+		end := int64(i) + 12 + length
+		if end > int64(len(b)) {
+			return nil, 0, ErrInvalidImageSrc
+		}
+
+		switch typ {
+		case "eXIf":
+			if o := parseEXIFOrientation(b[i+8 : end-4]); o != 0 {
+				orientation = o
+			}
+		case "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, b[i:end]...)
+		}
+		if typ == "IEND" {
+			return out, orientation, nil
+		}
+		i = int(end)
+	}
+}
+
+// InsertPNGOrientation 向き情報のみを含むeXIfチャンクをPNG画像のIHDRの直後に挿入します
+func InsertPNGOrientation(b []byte, orientation int) []byte {
+	var chunk bytes.Buffer
+	writePNGChunk(&chunk, "eXIf", buildOrientationEXIF(orientation))
+
+	pos := len(pngSignature) + 12 + 13 // IHDRチャンクの終端
+	out := make([]byte, 0, len(b)+chunk.Len())
+	out = append(out, b[:pos]...)
+	out = append(out, chunk.Bytes()...)
+	return append(out, b[pos:]...)
+}
+
+// StripWebPMetadata WebP画像からEXIF・XMPチャンクを除去します
+//
+// 返り値のorientationは除去したEXIFに含まれていた向き情報(1-8)で、含まれていなかった場合は1です。
+func StripWebPMetadata(b []byte) (out []byte, orientation int, err error) {
+	if len(b) < 12 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return nil, 0, ErrInvalidImageSrc
+	}
+	out = make([]byte, 0, len(b))
+	out = append(out, b[0:12]...)
+	orientation = 1
+
+	riffEnd := int64(8) + int64(binary.LittleEndian.Uint32(b[4:8]))
+	if riffEnd > int64(len(b)) {
+		return nil, 0, ErrInvalidImageSrc
+	}
+	vp8x := -1
+	for i := int64(12); i < riffEnd; {
+		if i+8 > riffEnd {
+			return nil, 0, ErrInvalidImageSrc
+		}
+		typ := string(b[i : i+4])
+		size := int64(binary.LittleEndian.Uint32(b[i+4 : i+8]))
+		end := i + 8 + size + size%2
+		if i+8+size > riffEnd {
+			return nil, 0, ErrInvalidImageSrc
+		}
+		if end > riffEnd {
+			end = riffEnd
+		}
+
+		switch typ {
+		case "EXIF":
+			data := bytes.TrimPrefix(b[i+8:i+8+size], []byte(exifHeader))
+			if o := parseEXIFOrientation(data); o != 0 {
+				orientation = o
+			}
+		case "XMP ":
+		default:
+			if typ == "VP8X" && size >= 10 {
+				vp8x = len(out)
+			}
+			out = append(out, b[i:end]...)
+		}
+		i = end
+	}
+
+	if vp8x >= 0 {
+		out[vp8x+8] &^= 0x08 | 0x04 // EXIF, XMPフラグを下ろす
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, orientation, nil
+}
+
+// InsertWebPOrientation 向き情報のみを含むEXIFチャンクをWebP画像に追加します
+//
+// VP8Xチャンクを持たない(拡張フォーマットでない)画像はそのまま返します。
+func InsertWebPOrientation(b []byte, orientation int) []byte {
+	if len(b) < 30 || string(b[12:16]) != "VP8X" {
+		return b
+	}
+	data := buildOrientationEXIF(orientation)
+	out := make([]byte, 0, len(b)+8+len(data))
+	out = append(out, b...)
+	out = append(out, "EXIF"...)
+	out = append(out, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(out[len(out)-4:], uint32(len(data)))
+	out = append(out, data...) // 偶数長なのでパディング不要
+	out[20] |= 0x08
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out
+}
+
+// ApplyOrientation EXIFの向き情報(1-8)に従って画像を回転・反転させます
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	}
+	return img
+}
+
+// parseEXIFOrientation TIFF形式のEXIFデータのIFD0から向き情報を読み取ります
+//
+// 読み取れなかった場合は0を返します。
+func parseEXIFOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return 0
+	}
+
+	ifd := int64(order.Uint32(tiff[4:8]))
+	if ifd+2 > int64(len(tiff)) {
+		return 0
+	}
+	n := int64(order.Uint16(tiff[ifd : ifd+2]))
+	for k := int64(0); k < n; k++ {
+		e := ifd + 2 + k*12
+		if e+12 > int64(len(tiff)) {
+			return 0
+		}
+		if order.Uint16(tiff[e:e+2]) != exifTagOrientation {
+			continue
+		}
+		if order.Uint16(tiff[e+2:e+4]) != 3 { // SHORT
+			return 0
+		}
+		if o := int(order.Uint16(tiff[e+8 : e+10])); o >= 1 && o <= 8 {
+			return o
+		}
+		return 0
+	}
+	return 0
+}
+
+// buildOrientationEXIF 向き情報のみを含むTIFF形式のEXIFデータを生成します
+func buildOrientationEXIF(orientation int) []byte {
+	b := make([]byte, 26)
+	copy(b[0:4], "MM\x00\x2a")
+	binary.BigEndian.PutUint32(b[4:8], 8)
+	binary.BigEndian.PutUint16(b[8:10], 1)
+	binary.BigEndian.PutUint16(b[10:12], exifTagOrientation)
+	binary.BigEndian.PutUint16(b[12:14], 3)
+	binary.BigEndian.PutUint32(b[14:18], 1)
+	binary.BigEndian.PutUint16(b[18:20], uint16(orientation))
+	return b
+}
@@ -0,0 +1,213 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func jpegSegment(marker byte, data []byte) []byte {
+	seg := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:4], uint16(2+len(data)))
+	return append(seg, data...)
+}
+
+func exifWithGPS(orientation int) []byte {
+	tiff := buildOrientationEXIF(orientation)
+	return append(tiff, "GPS 35.6N 139.7E"...)
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	t.Parallel()
+
+	var enc bytes.Buffer
+	require.NoError(t, jpeg.Encode(&enc, image.NewGray(image.Rect(0, 0, 16, 8)), nil))
+	base := enc.Bytes()
+
+	t.Run("strip", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assert.New(t), require.New(t)
+
+		icc := jpegSegment(0xe2, []byte("ICC_PROFILE\x00\x01\x01dummy"))
+		b := InsertJPEGSegments(base, [][]byte{
+			jpegSegment(0xe1, append([]byte(exifHeader), exifWithGPS(6)...)),
+			jpegSegment(0xe1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")),
+			icc,
+			jpegSegment(0xed, []byte("Photoshop 3.0\x00")),
+			jpegSegment(0xfe, []byte("comment")),
+		})
+		b = append(b, "trailing secondary image"...)
+
+		out, orientation, err := StripJPEGMetadata(b)
+		require.NoError(err)
+		assert.Equal(6, orientation)
+		assert.NotContains(string(out), "GPS")
+		assert.NotContains(string(out), "xmpmeta")
+		assert.NotContains(string(out), "Photoshop")
+		assert.NotContains(string(out), "comment")
+		assert.NotContains(string(out), "trailing")
+		assert.Equal([][]byte{icc}, ExtractJPEGICCProfile(out))
+
+		img, err := jpeg.Decode(bytes.NewReader(out))
+		require.NoError(err)
+		assert.Equal(image.Rect(0, 0, 16, 8), img.Bounds())
+	})
+
+	t.Run("no metadata", func(t *testing.T) {
+		t.Parallel()
+
+		out, orientation, err := StripJPEGMetadata(base)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, orientation)
+			assert.Equal(t, base, out)
+		}
+	})
+
+	t.Run("insert orientation", func(t *testing.T) {
+		t.Parallel()
+
+		_, orientation, err := StripJPEGMetadata(InsertJPEGOrientation(base, 8))
+		if assert.NoError(t, err) {
+			assert.Equal(t, 8, orientation)
+		}
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := StripJPEGMetadata(base[:20])
+		assert.Equal(t, ErrInvalidImageSrc, err)
+		_, _, err = StripJPEGMetadata([]byte("GIF89a"))
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	t.Parallel()
+
+	var enc bytes.Buffer
+	require.NoError(t, png.Encode(&enc, image.NewNRGBA(image.Rect(0, 0, 4, 2))))
+	base := enc.Bytes()
+
+	t.Run("strip", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assert.New(t), require.New(t)
+
+		var text bytes.Buffer
+		writePNGChunk(&text, "tEXt", []byte("Comment\x00secret"))
+		b := InsertPNGOrientation(base, 3)
+		pos := len(pngSignature) + 12 + 13
+		b = append(b[:pos:pos], append(text.Bytes(), b[pos:]...)...)
+		b = append(b, "trailing"...)
+
+		out, orientation, err := StripPNGMetadata(b)
+		require.NoError(err)
+		assert.Equal(3, orientation)
+		assert.NotContains(string(out), "eXIf")
+		assert.NotContains(string(out), "secret")
+		assert.NotContains(string(out), "trailing")
+		assert.Equal(base, out)
+
+		_, err = png.Decode(bytes.NewReader(out))
+		assert.NoError(err)
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := StripPNGMetadata(base[:len(base)-4])
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	t.Parallel()
+
+	vp8x := []byte{0x08 | 0x04, 0, 0, 0, 199, 0, 0, 99, 0, 0}
+	vp8 := []byte{0, 0, 0, 0x9d, 0x01, 0x2a, 200, 0, 100, 0, 0}
+
+	t.Run("strip", func(t *testing.T) {
+		t.Parallel()
+		assert, require := assert.New(t), require.New(t)
+
+		b := makeWebP(
+			webpChunk("VP8X", vp8x),
+			webpChunk("VP8 ", vp8),
+			webpChunk("EXIF", append([]byte(exifHeader), exifWithGPS(5)...)),
+			webpChunk("XMP ", []byte("<x:xmpmeta/>")),
+		)
+		out, orientation, err := StripWebPMetadata(b)
+		require.NoError(err)
+		assert.Equal(5, orientation)
+		assert.NotContains(string(out), "GPS")
+		assert.NotContains(string(out), "xmpmeta")
+		assert.Equal(makeWebP(webpChunk("VP8X", append([]byte{0}, vp8x[1:]...)), webpChunk("VP8 ", vp8)), out)
+
+		info, err := ProbeWebP(bytes.NewReader(out))
+		require.NoError(err)
+		assert.Equal(AnimationInfo{Width: 200, Height: 100, Frames: 1}, info)
+	})
+
+	t.Run("insert orientation", func(t *testing.T) {
+		t.Parallel()
+
+		b := makeWebP(webpChunk("VP8X", make([]byte, 10)), webpChunk("VP8 ", vp8))
+		inserted := InsertWebPOrientation(b, 7)
+		assert.EqualValues(t, 0x08, inserted[20])
+		out, orientation, err := StripWebPMetadata(inserted)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 7, orientation)
+			assert.Equal(t, b, out)
+		}
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		t.Parallel()
+
+		b := makeWebP(webpChunk("VP8 ", vp8))
+		_, _, err := StripWebPMetadata(b[:len(b)-4])
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+}
+
+func TestApplyOrientation(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	cases := []struct {
+		orientation int
+		bounds      image.Rectangle
+		at          image.Point
+	}{
+		{1, image.Rect(0, 0, 2, 1), image.Pt(0, 0)},
+		{2, image.Rect(0, 0, 2, 1), image.Pt(1, 0)},
+		{3, image.Rect(0, 0, 2, 1), image.Pt(1, 0)},
+		{6, image.Rect(0, 0, 1, 2), image.Pt(0, 0)},
+		{8, image.Rect(0, 0, 1, 2), image.Pt(0, 1)},
+	}
+	for _, c := range cases {
+		out := ApplyOrientation(img, c.orientation)
+		assert.Equal(t, c.bounds, out.Bounds(), "orientation %d", c.orientation)
+		assert.Equal(t, red, color.NRGBAModel.Convert(out.At(c.at.X, c.at.Y)), "orientation %d", c.orientation)
+	}
+}
+
+func TestParseEXIFOrientation(t *testing.T) {
+	t.Parallel()
+
+	le := []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 1, 0, 0x12, 0x01, 3, 0, 1, 0, 0, 0, 6, 0, 0, 0, 0, 0, 0, 0}
+	assert.Equal(t, 6, parseEXIFOrientation(le))
+	assert.Equal(t, 2, parseEXIFOrientation(buildOrientationEXIF(2)))
+	assert.Equal(t, 0, parseEXIFOrientation(buildOrientationEXIF(9)))
+	assert.Equal(t, 0, parseEXIFOrientation([]byte("MM\x00\x2a\x00\x00\xff\xff")))
+	assert.Equal(t, 0, parseEXIFOrientation(nil))
+}
@@ -0,0 +1,94 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+)
+
+// ProbeWebP WebP画像のキャンバスサイズとフレーム数を取得します
+//
+// アニメーションでないWebP画像の場合、フレーム数は1になります。
+func ProbeWebP(src io.Reader) (AnimationInfo, error) {
+	var h [12]byte
+	if _, err := io.ReadFull(src, h[:]); err != nil || string(h[0:4]) != "RIFF" || string(h[8:12]) != "WEBP" {
+		return AnimationInfo{}, ErrInvalidImageSrc
+	}
+
+	var (
+		info   AnimationInfo
+		frames int
+	)
+	for {
+		var ch [8]byte
+		if _, err := io.ReadFull(src, ch[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return AnimationInfo{}, ErrInvalidImageSrc
+		}
+		typ := string(ch[0:4])
+		size := int64(binary.LittleEndian.Uint32(ch[4:8]))
+
+		var head []byte
+		switch typ {
+		case "VP8X":
+			head = make([]byte, 10)
+		case "VP8 ":
+			head = make([]byte, 10)
+		case "VP8L":
+			head = make([]byte, 5)
+		case "ANMF":
+			frames++
+		}
+		if int64(len(head)) > size {
+			return AnimationInfo{}, ErrInvalidImageSrc
+		}
+		if _, err := io.ReadFull(src, head); err != nil {
+			return AnimationInfo{}, ErrInvalidImageSrc
+		}
+
+		// VP8X(拡張フォーマット)が存在する場合はそのキャンバスサイズを優先
+		switch {
+		case typ == "VP8X":
+			info.Width = 1 + int(uint24LE(head[4:7]))
+			info.Height = 1 + int(uint24LE(head[7:10]))
+		case typ == "VP8 " && info.Width == 0:
+			if head[3] != 0x9d || head[4] != 0x01 || head[5] != 0x2a {
+				return AnimationInfo{}, ErrInvalidImageSrc
+			}
+			info.Width = int(binary.LittleEndian.Uint16(head[6:8]) & 0x3fff)
+			info.Height = int(binary.LittleEndian.Uint16(head[8:10]) & 0x3fff)
+		case typ == "VP8L" && info.Width == 0:
+			if head[0] != 0x2f {
+				return AnimationInfo{}, ErrInvalidImageSrc
+			}
+			bits := binary.LittleEndian.Uint32(head[1:5])
+			info.Width = 1 + int(bits&0x3fff)
+			info.Height = 1 + int((bits>>14)&0x3fff)
+		}
+
+		// 残りのデータを読み飛ばす (チャンクは偶数バイトにパディングされる)
+		if _, err := io.CopyN(ioutil.Discard, src, size-int64(len(head))); err != nil {
+			return AnimationInfo{}, ErrInvalidImageSrc
+		}
+		if size%2 == 1 {
+			if _, err := io.CopyN(ioutil.Discard, src, 1); err != nil && err != io.EOF {
+				return AnimationInfo{}, ErrInvalidImageSrc
+			}
+		}
+	}
+
+	if info.Width <= 0 || info.Height <= 0 {
+		return AnimationInfo{}, ErrInvalidImageSrc
+	}
+	info.Frames = frames
+	if info.Frames == 0 {
+		info.Frames = 1
+	}
+	return info, nil
+}
+
+func uint24LE(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
@@ -0,0 +1,91 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// makeWebP 指定したチャンクからなるWebPコンテナを生成します
+func makeWebP(chunks ...[]byte) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, c := range chunks {
+		body.Write(c)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	_ = binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func webpChunk(typ string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(typ)
+	_ = binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	if len(data)%2 == 1 {
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+func TestProbeWebP(t *testing.T) {
+	t.Parallel()
+
+	t.Run("animated", func(t *testing.T) {
+		t.Parallel()
+
+		vp8x := []byte{0x02, 0, 0, 0, 199, 0, 0, 99, 0, 0} // 200x100, アニメーション
+		b := makeWebP(
+			webpChunk("VP8X", vp8x),
+			webpChunk("ANIM", make([]byte, 6)),
+			webpChunk("ANMF", make([]byte, 17)),
+			webpChunk("ANMF", make([]byte, 17)),
+			webpChunk("ANMF", make([]byte, 17)),
+		)
+		info, err := ProbeWebP(bytes.NewReader(b))
+		if assert.NoError(t, err) {
+			assert.Equal(t, AnimationInfo{Width: 200, Height: 100, Frames: 3}, info)
+		}
+	})
+
+	t.Run("lossless", func(t *testing.T) {
+		t.Parallel()
+
+		bits := uint32(63) | uint32(31)<<14 // 64x32
+		vp8l := []byte{0x2f, byte(bits), byte(bits >> 8), byte(bits >> 16), byte(bits >> 24), 0, 0}
+		info, err := ProbeWebP(bytes.NewReader(makeWebP(webpChunk("VP8L", vp8l))))
+		if assert.NoError(t, err) {
+			assert.Equal(t, AnimationInfo{Width: 64, Height: 32, Frames: 1}, info)
+		}
+	})
+
+	t.Run("lossy", func(t *testing.T) {
+		t.Parallel()
+
+		vp8 := []byte{0, 0, 0, 0x9d, 0x01, 0x2a, 48, 0, 16, 0, 0}
+		info, err := ProbeWebP(bytes.NewReader(makeWebP(webpChunk("VP8 ", vp8))))
+		if assert.NoError(t, err) {
+			assert.Equal(t, AnimationInfo{Width: 48, Height: 16, Frames: 1}, info)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		t.Parallel()
+
+		b := makeWebP(webpChunk("VP8X", make([]byte, 10)), webpChunk("ANMF", make([]byte, 20)))
+		_, err := ProbeWebP(bytes.NewReader(b[:len(b)-5]))
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+
+	t.Run("not webp", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ProbeWebP(bytes.NewBufferString("RIFF\x00\x00\x00\x00WAVE"))
+		assert.Equal(t, ErrInvalidImageSrc, err)
+	})
+}
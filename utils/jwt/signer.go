@@ -3,15 +3,29 @@ package jwt
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 )
 
 var (
-	pub  *ecdsa.PublicKey
-	priv *ecdsa.PrivateKey
+	pub   *ecdsa.PublicKey
+	priv  *ecdsa.PrivateKey
+	keyID string
 )
 
+// JWK JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
 // SetupSigner JWTを発行・検証するためのSignerのセットアップ
 func SetupSigner(privRaw []byte) error {
 	_priv, err := jwt.ParseECPrivateKeyFromPEM(bytes.TrimSpace(privRaw))
@@ -21,12 +35,15 @@ func SetupSigner(privRaw []byte) error {
 
 	pub = &_priv.PublicKey
 	priv = _priv
+	keyID = thumbprint(pub)
 	return nil
 }
 
 // Sign JWTの発行を行う
 func Sign(claims jwt.Claims) (string, error) {
-	return jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(priv)
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(priv)
 }
 
 // Verify JWTの検証を行う
@@ -45,3 +62,40 @@ func Verify(tokenString string, claims jwt.Claims) error {
 	}
 	return nil
 }
+
+// PublicJWK 検証用の公開鍵をJWK形式で返します
+func PublicJWK() JWK {
+	x, y := coordinates(pub)
+	return JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   x,
+		Y:   y,
+		Use: "sig",
+		Alg: jwt.SigningMethodES256.Alg(),
+		Kid: keyID,
+	}
+}
+
+// coordinates 公開鍵の座標をbase64url文字列で返します
+func coordinates(key *ecdsa.PublicKey) (x, y string) {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return base64.RawURLEncoding.EncodeToString(padLeft(key.X.Bytes(), size)), base64.RawURLEncoding.EncodeToString(padLeft(key.Y.Bytes(), size))
+}
+
+// padLeft bを長さsizeになるように先頭を0で埋めます
+func padLeft(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	r := make([]byte, size)
+	copy(r[size-len(b):], b)
+	return r
+}
+
+// thumbprint 公開鍵のJWK Thumbprint (RFC 7638) を返します
+func thumbprint(key *ecdsa.PublicKey) string {
+	x, y := coordinates(key)
+	h := sha256.Sum256([]byte(`{"crv":"P-256","kty":"EC","x":"` + x + `","y":"` + y + `"}`))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
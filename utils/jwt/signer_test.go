@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/utils/random"
+	"math/big"
+	"testing"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	privRaw, _ := random.GenerateECDSAKey()
+	require.NoError(t, SetupSigner(privRaw))
+
+	token, err := Sign(&jwt.StandardClaims{Subject: "test"})
+	require.NoError(t, err)
+
+	var claims jwt.StandardClaims
+	if assert.NoError(t, Verify(token, &claims)) {
+		assert.Equal(t, "test", claims.Subject)
+	}
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &jwt.StandardClaims{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, PublicJWK().Kid, parsed.Header["kid"])
+	}
+
+	assert.Error(t, Verify(token+"a", &claims))
+}
+
+func TestPublicJWK(t *testing.T) {
+	privRaw, _ := random.GenerateECDSAKey()
+	require.NoError(t, SetupSigner(privRaw))
+
+	jwk := PublicJWK()
+	assert.Equal(t, "EC", jwk.Kty)
+	assert.Equal(t, "P-256", jwk.Crv)
+	assert.Equal(t, "ES256", jwk.Alg)
+	assert.Equal(t, "sig", jwk.Use)
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	require.NoError(t, err)
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	require.NoError(t, err)
+	assert.Len(t, x, 32)
+	assert.Len(t, y, 32)
+	assert.True(t, elliptic.P256().IsOnCurve(new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)))
+}
+
+func TestThumbprint(t *testing.T) {
+	t.Parallel()
+
+	// RFC 7515 A.3 の公開鍵
+	key := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     mustDecodeInt(t, "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU"),
+		Y:     mustDecodeInt(t, "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"),
+	}
+	assert.Equal(t, "oKIywvGUpTVTyxMQ3bwIIeQUudfr_CkLMjCE19ECD-U", thumbprint(key))
+}
+
+func mustDecodeInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	require.NoError(t, err)
+	return new(big.Int).SetBytes(b)
+}
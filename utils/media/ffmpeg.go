@@ -0,0 +1,240 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	jsoniter "github.com/json-iterator/go"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrFFmpegUnavailable ffmpegが使用できません
+	ErrFFmpegUnavailable = errors.New("ffmpeg is unavailable")
+	// ErrFFprobeUnavailable ffprobeが使用できません
+	ErrFFprobeUnavailable = errors.New("ffprobe is unavailable")
+	// ErrInvalidMediaSrc 不正なメディアファイルです
+	ErrInvalidMediaSrc = errors.New("invalid media src")
+)
+
+// Info メディア情報
+type Info struct {
+	// Duration 再生時間
+	Duration time.Duration
+	// Width 映像の幅 (映像が無い場合は0)
+	Width int
+	// Height 映像の高さ (映像が無い場合は0)
+	Height int
+	// Codec 映像(映像が無い場合は音声)のコーデック名
+	Codec string
+	// HasVideo 映像ストリームを持っているかどうか
+	HasVideo bool
+	// HasAudio 音声ストリームを持っているかどうか
+	HasAudio bool
+}
+
+type probeResult struct {
+	Streams []struct {
+		CodecType   string `json:"codec_type"`
+		CodecName   string `json:"codec_name"`
+		Width       int    `json:"width"`
+		Height      int    `json:"height"`
+		Duration    string `json:"duration"`
+		Disposition struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+		Tags struct {
+			Rotate string `json:"rotate"`
+		} `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// Probe ffprobeでpathのメディアファイルを解析します
+func Probe(ctx context.Context, execPath, path string) (*Info, error) {
+	if len(execPath) == 0 {
+		return nil, ErrFFprobeUnavailable
+	}
+
+	cmd := exec.CommandContext(ctx, execPath, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	b, err := cmd.Output()
+	if err != nil {
+		switch err.(type) {
+		case *exec.ExitError:
+			return nil, ErrInvalidMediaSrc
+		default:
+			return nil, err
+		}
+	}
+	return ParseProbeResult(b)
+}
+
+// ParseProbeResult ffprobeのJSON出力を解析します
+func ParseProbeResult(b []byte) (*Info, error) {
+	var r probeResult
+	if err := jsoniter.ConfigFastest.Unmarshal(b, &r); err != nil {
+		return nil, ErrInvalidMediaSrc
+	}
+
+	info := &Info{}
+	var audioCodec string
+	for _, s := range r.Streams {
+		switch s.CodecType {
+		case "video":
+			// 音声ファイルのカバー画像は映像として扱わない
+			if s.Disposition.AttachedPic != 0 || info.HasVideo {
+				continue
+			}
+			info.HasVideo = true
+			info.Codec = s.CodecName
+			info.Width, info.Height = s.Width, s.Height
+			if s.Tags.Rotate == "90" || s.Tags.Rotate == "270" || s.Tags.Rotate == "-90" {
+				info.Width, info.Height = info.Height, info.Width
+			}
+		case "audio":
+			if !info.HasAudio {
+				info.HasAudio = true
+				audioCodec = s.CodecName
+			}
+		default:
+			continue
+		}
+		if d := parseSeconds(s.Duration); d > info.Duration {
+			info.Duration = d
+		}
+	}
+	if !info.HasVideo && !info.HasAudio {
+		return nil, ErrInvalidMediaSrc
+	}
+	if !info.HasVideo {
+		info.Codec = audioCodec
+	}
+	if d := parseSeconds(r.Format.Duration); d > 0 {
+		info.Duration = d
+	}
+	return info, nil
+}
+
+func parseSeconds(s string) time.Duration {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return 0
+	}
+	return time.Duration(f * float64(time.Second))
+}
+
+// ExtractFrame ffmpegでpathの動画のat時点のフレームを取り出します
+//
+// フレームはffmpeg内でmaxWidth x maxHeightに収まるように縮小されます。
+func ExtractFrame(ctx context.Context, execPath, path string, at time.Duration, maxWidth, maxHeight int) (image.Image, error) {
+	if len(execPath) == 0 {
+		return nil, ErrFFmpegUnavailable
+	}
+	if maxWidth <= 0 || maxHeight <= 0 {
+		return nil, errors.New("maxWidth or maxHeight is wrong")
+	}
+
+	// 巨大な解像度の動画でもデコード後の画像が大きくならないように、ffmpeg内で縮小する (拡大はしない)
+	scale := fmt.Sprintf("scale='min(iw,%d)':'min(ih,%d)':force_original_aspect_ratio=decrease", maxWidth, maxHeight)
+	cmd := exec.CommandContext(ctx, execPath, "-v", "error", "-ss", fmt.Sprintf("%.3f", at.Seconds()), "-i", path, "-frames:v", "1", "-vf", scale, "-f", "image2pipe", "-vcodec", "png", "-")
+	b, err := cmd.Output()
+	if err != nil {
+		switch err.(type) {
+		case *exec.ExitError:
+			return nil, ErrInvalidMediaSrc
+		default:
+			return nil, err
+		}
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, ErrInvalidMediaSrc
+	}
+	return img, nil
+}
+
+// Waveform ffmpegでpathの音声をデコードし、width x heightの波形画像を生成します
+//
+// durationには音声の再生時間を指定してください。
+func Waveform(ctx context.Context, execPath, path string, duration time.Duration, width, height int) (image.Image, error) {
+	if len(execPath) == 0 {
+		return nil, ErrFFmpegUnavailable
+	}
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("width or height is wrong")
+	}
+
+	const sampleRate = 8000
+	cmd := exec.CommandContext(ctx, execPath, "-v", "error", "-i", path, "-vn", "-ac", "1", "-ar", strconv.Itoa(sampleRate), "-f", "s16le", "-")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	peaks, err := peaksFromPCM(stdout, int(duration.Seconds()*sampleRate), width)
+	_, _ = io.Copy(ioutil.Discard, stdout)
+	if werr := cmd.Wait(); werr != nil {
+		switch werr.(type) {
+		case *exec.ExitError:
+			return nil, ErrInvalidMediaSrc
+		default:
+			return nil, werr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return RenderWaveform(peaks, height), nil
+}
+
+// peaksFromPCM モノラル16bitリトルエンディアンPCMをbuckets個の区間に分け、各区間の振幅の最大値(0~1)を求めます
+//
+// totalSamplesにはおおよそのサンプル数を指定してください。それを超えたサンプルは最後の区間に含まれます。
+func peaksFromPCM(r io.Reader, totalSamples, buckets int) ([]float64, error) {
+	if totalSamples <= 0 {
+		totalSamples = 1
+	}
+	peaks := make([]float64, buckets)
+	var buf [4096]byte
+	var i, rest int
+	for {
+		n, err := r.Read(buf[rest:])
+		n += rest
+		samples := n / 2
+		for j := 0; j < samples; j++ {
+			v := float64(int16(binary.LittleEndian.Uint16(buf[j*2:]))) / 32768
+			if v < 0 {
+				v = -v
+			}
+			b := int(int64(i) * int64(buckets) / int64(totalSamples))
+			if b >= buckets {
+				b = buckets - 1
+			}
+			if v > peaks[b] {
+				peaks[b] = v
+			}
+			i++
+		}
+		rest = n - samples*2
+		copy(buf[:rest], buf[samples*2:n])
+		if err == io.EOF {
+			return peaks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseProbeResult(t *testing.T) {
+	t.Parallel()
+
+	t.Run("video", func(t *testing.T) {
+		t.Parallel()
+
+		info, err := ParseProbeResult([]byte(`{
+			"streams": [
+				{"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080, "duration": "10.000000"},
+				{"codec_type": "audio", "codec_name": "aac", "duration": "10.020000"}
+			],
+			"format": {"duration": "10.023000"}
+		}`))
+		if assert.NoError(t, err) {
+			assert.Equal(t, &Info{
+				Duration: 10023 * time.Millisecond,
+				Width:    1920,
+				Height:   1080,
+				Codec:    "h264",
+				HasVideo: true,
+				HasAudio: true,
+			}, info)
+		}
+	})
+
+	t.Run("rotated video", func(t *testing.T) {
+		t.Parallel()
+
+		info, err := ParseProbeResult([]byte(`{
+			"streams": [{"codec_type": "video", "codec_name": "hevc", "width": 1920, "height": 1080, "tags": {"rotate": "90"}}],
+			"format": {"duration": "1.5"}
+		}`))
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1080, info.Width)
+			assert.Equal(t, 1920, info.Height)
+			assert.Equal(t, 1500*time.Millisecond, info.Duration)
+		}
+	})
+
+	t.Run("audio with cover art", func(t *testing.T) {
+		t.Parallel()
+
+		info, err := ParseProbeResult([]byte(`{
+			"streams": [
+				{"codec_type": "audio", "codec_name": "mp3", "duration": "180.5"},
+				{"codec_type": "video", "codec_name": "mjpeg", "width": 500, "height": 500, "disposition": {"attached_pic": 1}}
+			],
+			"format": {}
+		}`))
+		if assert.NoError(t, err) {
+			assert.Equal(t, &Info{
+				Duration: 180500 * time.Millisecond,
+				Codec:    "mp3",
+				HasAudio: true,
+			}, info)
+		}
+	})
+
+	t.Run("no media streams", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseProbeResult([]byte(`{"streams": [{"codec_type": "subtitle"}], "format": {}}`))
+		assert.Equal(t, ErrInvalidMediaSrc, err)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseProbeResult([]byte(`not json`))
+		assert.Equal(t, ErrInvalidMediaSrc, err)
+	})
+}
+
+func TestPeaksFromPCM(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	for _, v := range []int16{0, 16384, -32768, 8192, 0, -16384, 100, 0} {
+		_ = binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteByte(0xff) // 半端なバイトは無視される
+
+	peaks, err := peaksFromPCM(&b, 8, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 1, 0.5, 100.0 / 32768}, peaks)
+}
+
+func TestRenderWaveform(t *testing.T) {
+	t.Parallel()
+
+	img := RenderWaveform([]float64{0, 1, 0.5}, 10)
+	assert.Equal(t, 3, img.Bounds().Dx())
+	assert.Equal(t, 10, img.Bounds().Dy())
+
+	assert.Equal(t, WaveformColor, img.NRGBAAt(0, 5))
+	assert.Zero(t, img.NRGBAAt(0, 1).A)
+	assert.Equal(t, WaveformColor, img.NRGBAAt(1, 0))
+	assert.Equal(t, WaveformColor, img.NRGBAAt(1, 9))
+	assert.Equal(t, WaveformColor, img.NRGBAAt(2, 3))
+	assert.Zero(t, img.NRGBAAt(2, 1).A)
+}
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Probe(context.Background(), "", "dummy")
+		assert.Equal(t, ErrFFprobeUnavailable, err)
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		t.Parallel()
+
+		ffprobe := os.Getenv("TRAQ_MEDIA_FFPROBE")
+		if len(ffprobe) == 0 {
+			t.SkipNow()
+		}
+		_, err := Probe(context.Background(), ffprobe, "ffmpeg_test.go")
+		assert.Equal(t, ErrInvalidMediaSrc, err)
+	})
+}
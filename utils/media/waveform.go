@@ -0,0 +1,27 @@
+package media
+
+import (
+	"image"
+	"image/color"
+)
+
+// WaveformColor 波形画像の波形の色
+var WaveformColor = color.NRGBA{R: 0x00, G: 0x5b, B: 0xac, A: 0xff}
+
+// RenderWaveform 各区間の振幅(0~1)から、幅len(peaks)・高さheightの透過背景の波形画像を生成します
+func RenderWaveform(peaks []float64, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, len(peaks), height))
+	center := height / 2
+	for x, p := range peaks {
+		if p > 1 {
+			p = 1
+		}
+		h := int(p * float64(height) / 2)
+		for y := center - h; y <= center+h && y < height; y++ {
+			if y >= 0 {
+				img.SetNRGBA(x, y, WaveformColor)
+			}
+		}
+	}
+	return img
+}
@@ -11,6 +11,7 @@ import (
 var (
 	mentionRegex = regexp.MustCompile(`[@＠]([\S]+)`)
 	channelRegex = regexp.MustCompile(`[#＃]([a-zA-Z0-9_/-]+)`)
+	stampRegex   = regexp.MustCompile(`:([a-zA-Z0-9_-]{1,32})((?:\.[a-zA-Z0-9_-]+)*):`)
 )
 
 const (
@@ -27,6 +28,8 @@ type ReplaceMapper interface {
 	Group(name string) (uuid.UUID, bool)
 	// User ユーザーID(lower-case) -> ユーザーUUID
 	User(name string) (uuid.UUID, bool)
+	// Stamp スタンプ名・エイリアス -> スタンプ名
+	Stamp(name string) (string, bool)
 }
 
 // Replacer メッセージ埋め込み置換機
@@ -118,7 +121,7 @@ func (re *Replacer) Replace(m string) string {
 }
 
 func (re *Replacer) replaceAll(m string) string {
-	return re.replaceMention(re.replaceChannel(m))
+	return re.replaceMention(re.replaceChannel(re.replaceStamp(m)))
 }
 
 func (re *Replacer) replaceMention(m string) string {
@@ -144,6 +147,17 @@ func (re *Replacer) replaceChannel(m string) string {
 	})
 }
 
+// replaceStamp スタンプのエイリアスをスタンプ名に置換します
+func (re *Replacer) replaceStamp(m string) string {
+	return stampRegex.ReplaceAllStringFunc(m, func(s string) string {
+		match := stampRegex.FindStringSubmatch(s)
+		if name, ok := re.mapper.Stamp(match[1]); ok && name != match[1] {
+			return ":" + name + match[2] + ":"
+		}
+		return s
+	})
+}
+
 func indexOf(slice []rune, target rune) int {
 	for k, v := range slice {
 		if v == target {
@@ -10,6 +10,7 @@ type TestReplaceMapper struct {
 	ChannelMap map[string]uuid.UUID
 	UserMap    map[string]uuid.UUID
 	GroupMap   map[string]uuid.UUID
+	StampMap   map[string]string
 }
 
 func (t *TestReplaceMapper) Channel(path string) (uuid.UUID, bool) {
@@ -27,6 +28,11 @@ func (t *TestReplaceMapper) User(name string) (uuid.UUID, bool) {
 	return v, ok
 }
 
+func (t *TestReplaceMapper) Stamp(name string) (string, bool) {
+	v, ok := t.StampMap[name]
+	return v, ok
+}
+
 func TestReplacer_Replace(t *testing.T) {
 	t.Parallel()
 
@@ -40,6 +46,10 @@ func TestReplacer_Replace(t *testing.T) {
 		GroupMap: map[string]uuid.UUID{
 			"okあok": uuid.Must(uuid.FromString("dfabf0c9-5de0-46ee-9721-2525e8bb3d45")),
 		},
+		StampMap: map[string]string{
+			"thumbs_up": "thumbs_up",
+			"good":      "thumbs_up",
+		},
 	})
 
 	tt := [][]string{
@@ -79,6 +89,14 @@ func TestReplacer_Replace(t *testing.T) {
 			"`$okあok$`",
 			"`$okあok$`",
 		},
+		{
+			":good: :thumbs_up: :good.large.rotate: :unknown: `:good:`\n```\n:good:\n```",
+			":thumbs_up: :thumbs_up: :thumbs_up.large.rotate: :unknown: `:good:`\n```\n:good:\n```",
+		},
+		{
+			"@takashi_trap:good:",
+			"@takashi_trap:thumbs_up:",
+		},
 		{
 			"````\n```\n@takashi_trap\n```\n````\n\n```\n@takashi_trap\n```",
 			"````\n```\n@takashi_trap\n```\n````\n\n```\n@takashi_trap\n```",
@@ -0,0 +1,161 @@
+package replay
+
+import (
+	"github.com/gofrs/uuid"
+	"sync"
+	"time"
+)
+
+// Store ユーザー毎のイベント再送バッファ
+//
+// ユーザー毎に単調増加するシーケンス番号をイベントに割り当て、直近のイベントを保持します。
+// シーケンス番号はバッファの作成時刻(マイクロ秒)から始まるため、
+// サーバーの再起動等でバッファが失われた後に古い番号で再開要求されても、誤ったイベントを再送しません。
+//
+// バッファはノード毎のメモリ上に保持され、ノード間では共有されません。
+// 別のノードで割り当てられたシーケンス番号は、通常このノードのバッファの範囲外(作成前または最新より後)になるためRangeはfalseを返し、
+// 別のノードに再接続したクライアントには全体の再取得を求めることになります。
+// ただし、同じユーザーのバッファが複数のノードでほぼ同時に作成された場合は番号の範囲が重なり得るため、
+// 複数ノードで動作させる場合は、同じユーザーのコネクションが同じノードに振り分けられるようにしてください。
+type Store struct {
+	size      int
+	retention time.Duration
+	users     map[uuid.UUID]*buffer
+	mu        sync.Mutex
+}
+
+type buffer struct {
+	// seq 最後に割り当てたシーケンス番号
+	seq int64
+	// events 直近のイベント (リングバッファ)
+	events []event
+	head   int
+	// refs 接続中のコネクション数
+	refs     int
+	released time.Time
+}
+
+type event struct {
+	seq  int64
+	data interface{}
+}
+
+// NewStore 再送バッファを生成します
+//
+// sizeはユーザー毎に保持するイベント数、retentionは全てのコネクションが切断されてからバッファを保持する期間です。
+func NewStore(size int, retention time.Duration) *Store {
+	return &Store{
+		size:      size,
+		retention: retention,
+		users:     map[uuid.UUID]*buffer{},
+	}
+}
+
+// Acquire 指定したユーザーのコネクションが接続したことを記録し、その時点のシーケンス番号を返します
+func (s *Store) Acquire(userID uuid.UUID) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.users[userID]
+	if !ok {
+		b = &buffer{
+			seq:    time.Now().UnixNano() / int64(time.Microsecond),
+			events: make([]event, 0, s.size),
+		}
+		s.users[userID] = b
+	}
+	b.refs++
+	return b.seq
+}
+
+// Release 指定したユーザーのコネクションが切断したことを記録します
+func (s *Store) Release(userID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.users[userID]; ok && b.refs > 0 {
+		b.refs--
+		if b.refs == 0 {
+			b.released = time.Now()
+		}
+	}
+}
+
+// Append 指定したユーザーのイベントを追加します
+//
+// buildには割り当てたシーケンス番号が渡され、その戻り値が保持されます。
+// ユーザーのバッファが存在しない場合は何もせずfalseを返します。
+func (s *Store) Append(userID uuid.UUID, build func(seq int64) interface{}) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.users[userID]
+	if !ok {
+		return nil, false
+	}
+	b.seq++
+	e := event{seq: b.seq, data: build(b.seq)}
+	if len(b.events) < s.size {
+		b.events = append(b.events, e)
+	} else {
+		b.events[b.head] = e
+		b.head = (b.head + 1) % s.size
+	}
+	return e.data, true
+}
+
+// Range 指定したユーザーのシーケンス番号がafterより大きくuntil以下のイベントを古い順に返します
+//
+// afterより後のイベントが既にバッファから失われている場合や、
+// afterが未知のシーケンス番号の場合はfalseを返します。
+func (s *Store) Range(userID uuid.UUID, after, until int64) ([]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.users[userID]
+	if !ok || after > b.seq {
+		return nil, false
+	}
+
+	oldest := b.seq - int64(len(b.events)) + 1
+	if after+1 < oldest {
+		return nil, false
+	}
+	result := make([]interface{}, 0)
+	for i := 0; i < len(b.events); i++ {
+		e := b.events[(b.head+i)%len(b.events)]
+		if e.seq > after && e.seq <= until {
+			result = append(result, e.data)
+		}
+	}
+	return result, true
+}
+
+// Latest 指定したユーザーの最後に割り当てたシーケンス番号を返します
+func (s *Store) Latest(userID uuid.UUID) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.users[userID]
+	if !ok {
+		return 0, false
+	}
+	return b.seq, true
+}
+
+// Users バッファが存在するユーザーのIDを返します
+func (s *Store) Users() []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]uuid.UUID, 0, len(s.users))
+	for u := range s.users {
+		users = append(users, u)
+	}
+	return users
+}
+
+// GC 保持期間が過ぎたバッファを破棄します
+func (s *Store) GC(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for u, b := range s.users {
+		if b.refs == 0 && now.Sub(b.released) > s.retention {
+			delete(s.users, u)
+		}
+	}
+}
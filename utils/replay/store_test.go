@@ -0,0 +1,108 @@
+package replay
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	s := NewStore(3, time.Minute)
+	user := uuid.Must(uuid.NewV4())
+	build := func(seq int64) interface{} { return seq }
+
+	_, ok := s.Append(user, build)
+	assert.False(t, ok, "no buffer before acquire")
+
+	base := s.Acquire(user)
+	assert.NotZero(t, base)
+	for i := int64(1); i <= 2; i++ {
+		v, ok := s.Append(user, build)
+		assert.True(t, ok)
+		assert.Equal(t, base+i, v)
+	}
+
+	events, ok := s.Range(user, base, base+2)
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{base + 1, base + 2}, events)
+	events, ok = s.Range(user, base+1, base+1)
+	assert.True(t, ok)
+	assert.Empty(t, events)
+
+	// 未知のシーケンス番号
+	_, ok = s.Range(user, base+3, base+3)
+	assert.False(t, ok)
+	_, ok = s.Range(user, base-1, base+2)
+	assert.False(t, ok)
+
+	// バッファから溢れたイベントは再送できない
+	s.Append(user, build)
+	s.Append(user, build)
+	_, ok = s.Range(user, base, base+4)
+	assert.False(t, ok)
+	events, ok = s.Range(user, base+1, base+4)
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{base + 2, base + 3, base + 4}, events)
+
+	latest, ok := s.Latest(user)
+	assert.True(t, ok)
+	assert.Equal(t, base+4, latest)
+	assert.Equal(t, []uuid.UUID{user}, s.Users())
+}
+
+func TestStore_GC(t *testing.T) {
+	t.Parallel()
+
+	s := NewStore(3, time.Minute)
+	user1 := uuid.Must(uuid.NewV4())
+	user2 := uuid.Must(uuid.NewV4())
+	s.Acquire(user1)
+	s.Acquire(user2)
+	s.Release(user2)
+
+	s.GC(time.Now())
+	assert.ElementsMatch(t, []uuid.UUID{user1, user2}, s.Users())
+
+	s.GC(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, []uuid.UUID{user1}, s.Users())
+}
+
+func TestStore_OtherNode(t *testing.T) {
+	t.Parallel()
+
+	// 再送バッファはノード間で共有されない
+	node1 := NewStore(3, time.Minute)
+	node2 := NewStore(3, time.Minute)
+	user := uuid.Must(uuid.NewV4())
+	build := func(seq int64) interface{} { return seq }
+
+	base1 := node1.Acquire(user)
+	v, _ := node1.Append(user, build)
+	seq1 := v.(int64)
+
+	// バッファの無いノード
+	_, ok := node2.Range(user, seq1, seq1)
+	assert.False(t, ok)
+
+	time.Sleep(time.Millisecond)
+	base2 := node2.Acquire(user)
+	v, _ = node2.Append(user, build)
+	seq2 := v.(int64)
+	node1.Append(user, build)
+	node2.Append(user, build)
+	assert.Greater(t, base2, seq1)
+
+	// 別のノードで割り当てられたシーケンス番号では再送できない
+	_, ok = node2.Range(user, seq1, base2+2)
+	assert.False(t, ok)
+	_, ok = node1.Range(user, seq2, base1+2)
+	assert.False(t, ok)
+
+	// 同じノードであれば再送できる
+	events, ok := node2.Range(user, seq2, base2+2)
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{base2 + 2}, events)
+}
@@ -53,9 +53,9 @@ func (fs *CompositeFileStorage) DeleteByKey(key string, fileType model.FileType)
 }
 
 // GenerateAccessURL keyで指定されたファイルの直接アクセスURLを発行する。発行機能がない場合は空文字列を返します(エラーはありません)。
-func (fs *CompositeFileStorage) GenerateAccessURL(key string, fileType model.FileType) (string, error) {
+func (fs *CompositeFileStorage) GenerateAccessURL(key, name string, fileType model.FileType) (string, error) {
 	if _, err := os.Stat(fs.local.getFilePath(key)); os.IsNotExist(err) {
-		return fs.swift.GenerateAccessURL(key, fileType)
+		return fs.swift.GenerateAccessURL(key, name, fileType)
 	}
-	return fs.local.GenerateAccessURL(key, fileType)
+	return fs.local.GenerateAccessURL(key, name, fileType)
 }
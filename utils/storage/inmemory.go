@@ -57,7 +57,7 @@ func (fs *InMemoryFileStorage) DeleteByKey(key string, fileType model.FileType)
 }
 
 // GenerateAccessURL "",nilを返します
-func (fs *InMemoryFileStorage) GenerateAccessURL(key string, fileType model.FileType) (string, error) {
+func (fs *InMemoryFileStorage) GenerateAccessURL(key, name string, fileType model.FileType) (string, error) {
 	return "", nil
 }
 
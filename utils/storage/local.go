@@ -57,7 +57,7 @@ func (fs *LocalFileStorage) DeleteByKey(key string, fileType model.FileType) err
 }
 
 // GenerateAccessURL "",nilを返します
-func (fs *LocalFileStorage) GenerateAccessURL(key string, fileType model.FileType) (string, error) {
+func (fs *LocalFileStorage) GenerateAccessURL(key, name string, fileType model.FileType) (string, error) {
 	return "", nil
 }
 
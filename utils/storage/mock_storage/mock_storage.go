@@ -79,16 +79,16 @@ func (mr *MockFileStorageMockRecorder) DeleteByKey(key, fileType interface{}) *g
 }
 
 // GenerateAccessURL mocks base method
-func (m *MockFileStorage) GenerateAccessURL(key string, fileType model.FileType) (string, error) {
+func (m *MockFileStorage) GenerateAccessURL(key, name string, fileType model.FileType) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateAccessURL", key, fileType)
+	ret := m.ctrl.Call(m, "GenerateAccessURL", key, name, fileType)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateAccessURL indicates an expected call of GenerateAccessURL
-func (mr *MockFileStorageMockRecorder) GenerateAccessURL(key, fileType interface{}) *gomock.Call {
+func (mr *MockFileStorageMockRecorder) GenerateAccessURL(key, name, fileType interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAccessURL", reflect.TypeOf((*MockFileStorage)(nil).GenerateAccessURL), key, fileType)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAccessURL", reflect.TypeOf((*MockFileStorage)(nil).GenerateAccessURL), key, name, fileType)
 }
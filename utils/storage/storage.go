@@ -22,5 +22,7 @@ type FileStorage interface {
 	// DeleteByKey keyで指定されたファイルを削除する
 	DeleteByKey(key string, fileType model.FileType) error
 	// GenerateAccessURL keyで指定されたファイルの直接アクセスURLを発行する。発行機能がない場合は空文字列を返します(エラーはありません)。
-	GenerateAccessURL(key string, fileType model.FileType) (string, error)
+	//
+	// nameはURLからダウンロードする際のファイル名です。保存時に指定したものより優先されます。
+	GenerateAccessURL(key, name string, fileType model.FileType) (string, error)
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/traPtitech/traQ/utils"
 	"github.com/traPtitech/traQ/utils/ioext"
 	"io"
+	"net/url"
 	"os"
 	"time"
 )
@@ -146,10 +147,13 @@ func (fs *SwiftFileStorage) DeleteByKey(key string, fileType model.FileType) (er
 }
 
 // GenerateAccessURL keyで指定されたファイルの直接アクセスURLを発行する。
-func (fs *SwiftFileStorage) GenerateAccessURL(key string, fileType model.FileType) (string, error) {
+//
+// ダウンロード時のファイル名はTempURLのfilenameパラメータで指定します。
+func (fs *SwiftFileStorage) GenerateAccessURL(key, name string, fileType model.FileType) (string, error) {
 	if !fs.cacheable(fileType) && len(fs.tempURLKey) > 0 {
 		if _, err := os.Stat(fs.getCacheFilePath(key)); os.IsNotExist(err) {
-			return fs.connection.ObjectTempUrl(fs.container, key, fs.tempURLKey, "GET", time.Now().Add(5*time.Minute)), nil
+			u := fs.connection.ObjectTempUrl(fs.container, key, fs.tempURLKey, "GET", time.Now().Add(5*time.Minute))
+			return u + "&filename=" + url.QueryEscape(name), nil
 		}
 	}
 	return "", nil
@@ -62,6 +62,21 @@ var StampNameRuleRequired = append([]vd.Rule{
 	vd.Required,
 }, StampNameRule...)
 
+// StampCategoryRule スタンプカテゴリーバリデーションルール
+var StampCategoryRule = []vd.Rule{
+	vd.RuneLength(0, 30),
+}
+
+// StampTagRule スタンプタグバリデーションルール
+var StampTagRule = []vd.Rule{
+	vd.RuneLength(1, 30),
+}
+
+// StampTagRuleRequired スタンプタグバリデーションルール with Required
+var StampTagRuleRequired = append([]vd.Rule{
+	vd.Required,
+}, StampTagRule...)
+
 // StampPaletteNameRule スタンプパレット名バリデーションルール
 var StampPaletteNameRule = []vd.Rule{
 	vd.RuneLength(1, 30),
@@ -112,3 +127,18 @@ var ClipFolderNameRuleRequired = append([]vd.Rule{
 var ClipFolderDescriptionRule = []vd.Rule{
 	vd.RuneLength(0, 1000),
 }
+
+// ChannelTemplateNameRule チャンネルテンプレート名バリデーションルール
+var ChannelTemplateNameRule = []vd.Rule{
+	vd.RuneLength(1, 30),
+}
+
+// ChannelTemplateNameRuleRequired チャンネルテンプレート名バリデーションルール with Required
+var ChannelTemplateNameRuleRequired = append([]vd.Rule{
+	vd.Required,
+}, ChannelTemplateNameRule...)
+
+// ChannelTemplateDescriptionRule チャンネルテンプレートの説明バリデーションルール
+var ChannelTemplateDescriptionRule = []vd.Rule{
+	vd.RuneLength(0, 1000),
+}